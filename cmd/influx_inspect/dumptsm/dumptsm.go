@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"text/tabwriter"
@@ -77,6 +78,12 @@ func (cmd *Command) dump() error {
 	}
 	b := make([]byte, 8)
 
+	dictID, err := tsm1.LoadZstdDict(filepath.Dir(cmd.path))
+	if err != nil {
+		return err
+	}
+	defer tsm1.UnloadZstdDict(dictID)
+
 	r, err := tsm1.NewTSMReader(f)
 	if err != nil {
 		return fmt.Errorf("Error opening TSM files: %s", err.Error())
@@ -291,7 +298,7 @@ var (
 		"none", "s8b", "rle",
 	}
 	floatEnc = []string{
-		"none", "gor",
+		"none", "gor", "zstd",
 	}
 	intEnc = []string{
 		"none", "s8b", "rle",
@@ -300,7 +307,7 @@ var (
 		"none", "bp",
 	}
 	stringEnc = []string{
-		"none", "snpy",
+		"none", "snpy", "zstd",
 	}
 	unsignedEnc = []string{
 		"none", "s8b", "rle",
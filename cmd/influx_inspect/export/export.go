@@ -269,6 +269,13 @@ func (cmd *Command) exportTSMFile(tsmFilePath string, w io.Writer) error {
 	}
 	defer f.Close()
 
+	dictID, err := tsm1.LoadZstdDict(filepath.Dir(tsmFilePath))
+	if err != nil {
+		fmt.Fprintf(cmd.Stderr, "unable to read %s, skipping: %s\n", tsmFilePath, err.Error())
+		return nil
+	}
+	defer tsm1.UnloadZstdDict(dictID)
+
 	r, err := tsm1.NewTSMReader(f)
 	if err != nil {
 		fmt.Fprintf(cmd.Stderr, "unable to read %s, skipping: %s\n", tsmFilePath, err.Error())
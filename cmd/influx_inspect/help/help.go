@@ -37,6 +37,7 @@ The commands are:
     export               exports raw data from a shard to line protocol
     buildtsi             generates tsi1 indexes from tsm1 data
     help                 display this help message
+    repair               removes corrupt blocks from TSM files
     report               displays a shard level report
     verify               verifies integrity of TSM files
     verify-seriesfile    verifies integrity of the Series file
@@ -15,6 +15,7 @@ import (
 	"github.com/influxdata/influxdb/cmd/influx_inspect/dumptsmwal"
 	"github.com/influxdata/influxdb/cmd/influx_inspect/export"
 	"github.com/influxdata/influxdb/cmd/influx_inspect/help"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/repair"
 	"github.com/influxdata/influxdb/cmd/influx_inspect/report"
 	"github.com/influxdata/influxdb/cmd/influx_inspect/reporttsi"
 	"github.com/influxdata/influxdb/cmd/influx_inspect/verify/seriesfile"
@@ -92,6 +93,11 @@ func (m *Main) Run(args ...string) error {
 		if err := name.Run(args...); err != nil {
 			return fmt.Errorf("buildtsi: %s", err)
 		}
+	case "repair":
+		name := repair.NewCommand()
+		if err := name.Run(args...); err != nil {
+			return fmt.Errorf("repair: %s", err)
+		}
 	case "report":
 		name := report.NewCommand()
 		if err := name.Run(args...); err != nil {
@@ -0,0 +1,149 @@
+// Package repair removes corrupt blocks from TSM files.
+package repair
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
+)
+
+// Command represents the program execution for "influx_inspect repair".
+type Command struct {
+	Stderr io.Writer
+	Stdout io.Writer
+}
+
+// NewCommand returns a new instance of Command.
+func NewCommand() *Command {
+	return &Command{
+		Stderr: os.Stderr,
+		Stdout: os.Stdout,
+	}
+}
+
+// Run executes the command.
+func (cmd *Command) Run(args ...string) error {
+	fs := flag.NewFlagSet("repair", flag.ExitOnError)
+	fs.SetOutput(cmd.Stdout)
+	fs.Usage = cmd.printUsage
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() == 0 {
+		fmt.Fprintf(cmd.Stdout, "TSM file not specified\n\n")
+		fs.Usage()
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(cmd.Stdout, 8, 8, 1, '\t', 0)
+	fmt.Fprintln(tw, strings.Join([]string{"File", "Series", "Field", "Min Time", "Max Time"}, "\t"))
+
+	var removed, repaired int
+	for _, path := range fs.Args() {
+		blocks, err := cmd.process(path)
+		if err != nil {
+			tw.Flush()
+			return err
+		}
+
+		for _, b := range blocks {
+			fmt.Fprintln(tw, strings.Join([]string{
+				b.Path,
+				string(b.Series),
+				string(b.Field),
+				time.Unix(0, b.MinTime).UTC().Format(time.RFC3339Nano),
+				time.Unix(0, b.MaxTime).UTC().Format(time.RFC3339Nano),
+			}, "\t"))
+		}
+		if len(blocks) > 0 {
+			removed += len(blocks)
+			repaired++
+		}
+	}
+	tw.Flush()
+
+	fmt.Fprintf(cmd.Stdout, "Removed %d corrupt blocks from %d of %d files\n", removed, repaired, fs.NArg())
+	return nil
+}
+
+// process rewrites the TSM file at path without its corrupt blocks and returns
+// them.  The file is removed if every block is corrupt.
+func (cmd *Command) process(path string) ([]tsdb.CorruptBlock, error) {
+	dictID, err := tsm1.LoadZstdDict(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	defer tsm1.UnloadZstdDict(dictID)
+
+	input, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+
+	r, err := tsm1.NewTSMReader(input)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %s", path, err)
+	}
+	defer r.Close()
+
+	corrupt, err := tsm1.VerifyTSM(r)
+	if err != nil || len(corrupt) == 0 {
+		return nil, err
+	}
+
+	// Remove previous temporary files.
+	outputPath := path + ".rewriting.tmp"
+	if err := os.RemoveAll(outputPath); err != nil {
+		return nil, err
+	}
+
+	output, err := os.Create(outputPath)
+	if err != nil {
+		return nil, err
+	}
+	defer output.Close()
+
+	w, err := tsm1.NewTSMWriter(output)
+	if err != nil {
+		return nil, err
+	}
+	defer w.Close()
+
+	if err := tsm1.RepairTSM(r, w, corrupt); err == tsm1.ErrNoValues {
+		// Nothing is left of the file, so remove it along with its tombstones.
+		if err := os.Remove(outputPath); err != nil {
+			return nil, err
+		}
+		for _, f := range r.TombstoneFiles() {
+			if err := os.Remove(f.Path); err != nil {
+				return nil, err
+			}
+		}
+		return corrupt, os.Remove(path)
+	} else if err != nil {
+		return nil, err
+	} else if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	// Replace original file with new file.
+	return corrupt, os.Rename(outputPath, path)
+}
+
+func (cmd *Command) printUsage() {
+	fmt.Fprint(cmd.Stdout, `Removes the blocks whose checksum does not match their content from TSM files
+and reports the series and time ranges that were lost.  The files are rewritten
+in place, so influxd must not be running.
+
+Usage: influx_inspect repair path...
+`)
+}
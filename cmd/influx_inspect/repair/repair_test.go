@@ -0,0 +1,191 @@
+package repair_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/repair"
+	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
+)
+
+// Ensure the command removes a corrupt block from a TSM file and reports it.
+func TestCommand_Run(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	path := MustWriteTSM(dir, map[string][]tsm1.Value{
+		"cpu,host=A#!~#value": {tsm1.NewValue(1, 1.1), tsm1.NewValue(2, 1.2)},
+		"cpu,host=B#!~#value": {tsm1.NewValue(3, 2.1)},
+	})
+
+	// The block of the first key follows the header and its checksum.
+	MustCorruptFile(path, 5+4+1)
+
+	var stdout bytes.Buffer
+	cmd := repair.NewCommand()
+	cmd.Stdout = &stdout
+	if err := cmd.Run(path); err != nil {
+		t.Fatal(err)
+	}
+
+	out := stdout.String()
+	if !regexp.MustCompile(regexp.QuoteMeta(path) + `\s+cpu,host=A\s+value\s+1970-01-01T00:00:00\.000000001Z\s+1970-01-01T00:00:00\.000000002Z\n`).MatchString(out) {
+		t.Fatalf("corrupt block not reported: %s", out)
+	} else if !bytes.HasSuffix(stdout.Bytes(), []byte("Removed 1 corrupt blocks from 1 of 1 files\n")) {
+		t.Fatalf("unexpected summary: %s", out)
+	}
+
+	if _, err := os.Stat(path + ".rewriting.tmp"); !os.IsNotExist(err) {
+		t.Fatalf("temporary file not removed: %v", err)
+	}
+
+	// Only the block of the second key is left in the rewritten file.
+	r := MustOpenTSMReader(path)
+	defer r.Close()
+
+	if corrupt, err := tsm1.VerifyTSM(r); err != nil {
+		t.Fatal(err)
+	} else if len(corrupt) != 0 {
+		t.Fatalf("unexpected corrupt blocks: %v", corrupt)
+	}
+	if r.KeyCount() != 1 {
+		t.Fatalf("unexpected key count: %d", r.KeyCount())
+	}
+	values, err := r.ReadAll([]byte("cpu,host=B#!~#value"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(values) != 1 || values[0].UnixNano() != 3 || values[0].Value() != 2.1 {
+		t.Fatalf("unexpected values: %v", values)
+	}
+}
+
+// Ensure the command removes a TSM file whose blocks are all corrupt.
+func TestCommand_Run_AllBlocksCorrupt(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	path := MustWriteTSM(dir, map[string][]tsm1.Value{
+		"cpu,host=A#!~#value": {tsm1.NewValue(1, 1.1)},
+	})
+	MustCorruptFile(path, 5+4+1)
+
+	var stdout bytes.Buffer
+	cmd := repair.NewCommand()
+	cmd.Stdout = &stdout
+	if err := cmd.Run(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasSuffix(stdout.Bytes(), []byte("Removed 1 corrupt blocks from 1 of 1 files\n")) {
+		t.Fatalf("unexpected summary: %s", stdout.String())
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected file to be removed: %v", err)
+	}
+}
+
+// Ensure the command leaves a TSM file without corrupt blocks untouched.
+func TestCommand_Run_NoCorruptBlocks(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	path := MustWriteTSM(dir, map[string][]tsm1.Value{
+		"cpu,host=A#!~#value": {tsm1.NewValue(1, 1.1)},
+	})
+	before, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	cmd := repair.NewCommand()
+	cmd.Stdout = &stdout
+	if err := cmd.Run(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasSuffix(stdout.Bytes(), []byte("Removed 0 corrupt blocks from 0 of 1 files\n")) {
+		t.Fatalf("unexpected summary: %s", stdout.String())
+	}
+	if after, err := ioutil.ReadFile(path); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(before, after) {
+		t.Fatal("file was rewritten")
+	}
+}
+
+func MustTempDir() string {
+	dir, err := ioutil.TempDir("", "influx_inspect-repair-")
+	if err != nil {
+		panic(err)
+	}
+	return dir
+}
+
+// MustWriteTSM writes values to a TSM file in dir and returns its path.
+func MustWriteTSM(dir string, values map[string][]tsm1.Value) string {
+	path := filepath.Join(dir, "000000001-000000001."+tsm1.TSMFileExtension)
+	f, err := os.Create(path)
+	if err != nil {
+		panic(err)
+	}
+
+	w, err := tsm1.NewTSMWriter(f)
+	if err != nil {
+		panic(err)
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if err := w.Write([]byte(k), values[k]); err != nil {
+			panic(err)
+		}
+	}
+	if err := w.WriteIndex(); err != nil {
+		panic(err)
+	} else if err := w.Close(); err != nil {
+		panic(err)
+	}
+	return path
+}
+
+// MustCorruptFile flips the bits of the byte at offset in the file at path.
+func MustCorruptFile(path string, offset int64) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0666)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	b := make([]byte, 1)
+	if _, err := f.ReadAt(b, offset); err != nil {
+		panic(err)
+	}
+	b[0] = ^b[0]
+	if _, err := f.WriteAt(b, offset); err != nil {
+		panic(err)
+	}
+}
+
+func MustOpenTSMReader(path string) *tsm1.TSMReader {
+	f, err := os.Open(path)
+	if err != nil {
+		panic(err)
+	}
+
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
@@ -72,6 +72,10 @@ func (cmd *Command) Run(args ...string) error {
 
 	dbCardinalities := map[string]counter{}
 
+	// The number of float and string blocks per codec of each shard.
+	var shardIDs []string
+	shardCodecs := map[string]map[string]int{}
+
 	start := time.Now()
 
 	tw := tabwriter.NewWriter(cmd.Stdout, 8, 2, 1, ' ', 0)
@@ -142,6 +146,16 @@ func (cmd *Command) Run(args ...string) error {
 				}
 			}
 		}
+		codecs := shardCodecs[id]
+		if codecs == nil {
+			codecs = map[string]int{}
+			shardCodecs[id] = codecs
+			shardIDs = append(shardIDs, id)
+		}
+		if err := countCodecs(reader, codecs); err != nil {
+			fmt.Fprintf(cmd.Stderr, "error: %s: %v. Skipping codecs.\n", file.Name(), err)
+		}
+
 		minT, maxT := reader.TimeRange()
 		if minT < minTime {
 			minTime = minT
@@ -187,6 +201,11 @@ func (cmd *Command) Run(args ...string) error {
 	}
 	fmt.Printf("  Total%s: %d\n", estTitle, totalSeries.Count())
 
+	fmt.Printf("  Codecs:\n")
+	for _, id := range shardIDs {
+		fmt.Printf("    - %s: %s\n", id, formatCodecs(shardCodecs[id]))
+	}
+
 	if cmd.detailed {
 		fmt.Printf("\n  Measurements (est):\n")
 		for _, t := range sortKeys(measCardinalities) {
@@ -208,6 +227,54 @@ func (cmd *Command) Run(args ...string) error {
 	return nil
 }
 
+// countCodecs adds the number of float and string blocks of r per block type
+// and codec to counts.
+func countCodecs(r *tsm1.TSMReader, counts map[string]int) error {
+	iter := r.BlockIterator()
+	for iter.Next() {
+		_, _, _, typ, _, buf, err := iter.Read()
+		if err != nil {
+			return err
+		}
+		codec, err := tsm1.BlockCodec(buf)
+		if err != nil {
+			return err
+		} else if codec == "" {
+			continue
+		}
+
+		name := "float"
+		if typ == tsm1.BlockString {
+			name = "string"
+		}
+		counts[name+"/"+codec]++
+	}
+	return iter.Err()
+}
+
+// formatCodecs formats the number of blocks per codec along with the share of
+// the blocks of the same type.
+func formatCodecs(counts map[string]int) string {
+	if len(counts) == 0 {
+		return "none"
+	}
+
+	totals := map[string]int{}
+	keys := make([]string, 0, len(counts))
+	for k, n := range counts {
+		totals[strings.Split(k, "/")[0]] += n
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		n := counts[k]
+		parts[i] = fmt.Sprintf("%s: %d (%d%%)", k, n, int(float64(n)/float64(totals[strings.Split(k, "/")[0]])*100))
+	}
+	return strings.Join(parts, ", ")
+}
+
 // sortKeys is a quick helper to return the sorted set of a map's keys
 func sortKeys(vals map[string]counter) (keys []string) {
 	for k := range vals {
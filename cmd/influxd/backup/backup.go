@@ -56,6 +56,7 @@ type Command struct {
 	end      time.Time
 
 	portable         bool
+	blob             bool
 	manifest         backup_util.Manifest
 	portableFileBase string
 	continueOnError  bool
@@ -83,6 +84,10 @@ func (cmd *Command) Run(args ...string) error {
 		return err
 	}
 
+	if cmd.blob {
+		return cmd.backupBlob()
+	}
+
 	if cmd.shardID != "" {
 		// always backup the metastore
 		if err := cmd.backupMetastore(); err != nil {
@@ -158,6 +163,7 @@ func (cmd *Command) parseFlags(args []string) (err error) {
 	fs.StringVar(&startArg, "start", "", "")
 	fs.StringVar(&endArg, "end", "", "")
 	fs.BoolVar(&cmd.portable, "portable", false, "")
+	fs.BoolVar(&cmd.blob, "blob", false, "")
 	fs.BoolVar(&cmd.continueOnError, "skip-errors", false, "")
 
 	fs.SetOutput(cmd.Stderr)
@@ -207,6 +213,16 @@ func (cmd *Command) parseFlags(args []string) (err error) {
 		}
 	}
 
+	// Blob store backups are written by the server and take no path.
+	if cmd.blob {
+		if !cmd.isBackup || sinceArg != "" || cmd.shardID != "" || cmd.portable {
+			return errors.New("-blob is not compatible with -since, -start, -end, -shard or -portable")
+		} else if fs.NArg() != 0 {
+			return errors.New("-blob does not take a backup path")
+		}
+		return nil
+	}
+
 	// Ensure that only one arg is specified.
 	if fs.NArg() != 1 {
 		return errors.New("Exactly one backup path is required.")
@@ -327,6 +343,33 @@ func (cmd *Command) backupShard(db, rp, sid string) error {
 
 }
 
+// backupBlob requests the server to back up the database and retention policy,
+// or everything when none is given, to the blob store configured on the server.
+func (cmd *Command) backupBlob() error {
+	cmd.StdoutLogger.Printf("backing up db=%s rp=%s to the server blob store", cmd.database, cmd.retentionPolicy)
+
+	req := &snapshotter.Request{
+		Type:                  snapshotter.RequestBlobBackup,
+		BackupDatabase:        cmd.database,
+		BackupRetentionPolicy: cmd.retentionPolicy,
+	}
+
+	response, err := cmd.requestInfo(req)
+	if err != nil {
+		cmd.StderrLogger.Printf("backup failed: %v", err)
+		return err
+	} else if response.Err != "" {
+		cmd.StderrLogger.Printf("backup failed: %s", response.Err)
+		return errors.New(response.Err)
+	}
+
+	cmd.StdoutLogger.Println("backup complete:")
+	for _, v := range response.Paths {
+		cmd.StdoutLogger.Println("\t" + v)
+	}
+	return nil
+}
+
 // backupDatabase will request the database information from the server and then backup
 // every shard in every retention policy in the database. Each shard will be written to a separate file.
 func (cmd *Command) backupDatabase() error {
@@ -595,10 +638,15 @@ Creates a backup copy of specified InfluxDB OSS database(s) and saves the files
 format to PATH (directory where backups are saved). 
 
 Usage: influxd backup [options] PATH
+       influxd backup -blob [options]
 
     -portable
             Required to generate backup files in a portable format that can be restored to InfluxDB OSS or InfluxDB 
             Enterprise. Use unless the legacy backup is required.
+    -blob
+            Requests the server to back up to the blob store configured in its [backup] section instead of
+            streaming the backup to PATH. Files unchanged since a previous backup are not copied again.
+            Not compatible with '-portable', '-shard', '-since', '-start' or '-end'.
     -host <host:port>
             InfluxDB OSS host to back up from. Optional. Defaults to 127.0.0.1:8088.
     -db <name>
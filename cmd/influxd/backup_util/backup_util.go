@@ -86,7 +86,12 @@ func GetMetaBytes(fname string) ([]byte, error) {
 // Manifest lists the meta and shard file information contained in the backup.
 // If Limited is false, the manifest contains a full backup, otherwise
 // it is a partial backup.
+//
+// Manifests written to a BlobStore have a non-zero Version and list every
+// file of each shard as a separate content-addressed blob.
 type Manifest struct {
+	Version int       `json:"version,omitempty"`
+	Created int64     `json:"created,omitempty"`
 	Meta    MetaEntry `json:"meta"`
 	Limited bool      `json:"limited"`
 	Files   []Entry   `json:"files"`
@@ -106,6 +111,12 @@ type Entry struct {
 	FileName     string `json:"fileName"`
 	Size         int64  `json:"size"`
 	LastModified int64  `json:"lastModified"`
+
+	// Path is the path of the file relative to the shard directory and
+	// Digest the hex encoded SHA-256 of its content. Both are only set in
+	// manifests written to a BlobStore.
+	Path   string `json:"path,omitempty"`
+	Digest string `json:"digest,omitempty"`
 }
 
 func (e *Entry) SizeOrZero() int64 {
@@ -0,0 +1,303 @@
+package backup_util
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/pkg/file"
+)
+
+const (
+	// BlobManifestVersion is the version of the manifests written to a BlobStore.
+	BlobManifestVersion = 1
+
+	// BlobPrefix is the key prefix of the content-addressed data blobs.
+	BlobPrefix = "blobs/"
+
+	// ManifestPrefix is the key prefix of the backup manifests.
+	ManifestPrefix = "manifests/"
+)
+
+// ErrBlobNotFound is returned when a blob doesn't exist in a BlobStore.
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore is a flat key/value store holding backup blobs and manifests.
+// Keys are slash separated paths.
+type BlobStore interface {
+	// Put stores the content read from r under key, replacing any existing blob.
+	Put(key string, r io.Reader) error
+
+	// Get returns the content of the blob stored under key, or
+	// ErrBlobNotFound if it doesn't exist.
+	Get(key string) (io.ReadCloser, error)
+
+	// Exists returns true if a blob is stored under key.
+	Exists(key string) (bool, error)
+
+	// List returns the sorted keys starting with prefix.
+	List(prefix string) ([]string, error)
+
+	// Delete removes the blob stored under key. Deleting a missing blob is not an error.
+	Delete(key string) error
+}
+
+// OpenBlobStore returns the BlobStore identified by rawurl. Supported schemes
+// are file:///path/to/dir and s3://bucket/prefix. S3 options are passed as
+// query parameters, see NewS3BlobStore.
+func OpenBlobStore(rawurl string) (BlobStore, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "file":
+		if u.Path == "" {
+			return nil, fmt.Errorf("blob store path required: %s", rawurl)
+		}
+		return NewFileBlobStore(u.Path), nil
+	case "s3":
+		return NewS3BlobStore(S3Config{
+			Endpoint:        u.Query().Get("endpoint"),
+			Region:          u.Query().Get("region"),
+			Bucket:          u.Host,
+			Prefix:          strings.TrimPrefix(u.Path, "/"),
+			Insecure:        u.Query().Get("insecure") == "true",
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		})
+	default:
+		return nil, fmt.Errorf("unsupported blob store scheme: %q", u.Scheme)
+	}
+}
+
+// FileBlobStore is a BlobStore storing blobs as files under a directory.
+type FileBlobStore struct {
+	dir string
+}
+
+// NewFileBlobStore returns a BlobStore storing blobs under dir.
+func NewFileBlobStore(dir string) *FileBlobStore {
+	return &FileBlobStore{dir: dir}
+}
+
+// Put writes the blob to a temporary file which is renamed into place once synced.
+func (s *FileBlobStore) Put(key string, r io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+Suffix)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	} else if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	} else if err := f.Close(); err != nil {
+		return err
+	}
+	return file.RenameFile(f.Name(), path)
+}
+
+// Get opens the file holding the blob.
+func (s *FileBlobStore) Get(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, ErrBlobNotFound
+	}
+	return f, err
+}
+
+// Exists returns true if the file holding the blob exists.
+func (s *FileBlobStore) Exists(key string) (bool, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// List walks the directory for the files whose key starts with prefix. Only
+// the directory named by the part of prefix up to its last slash is walked,
+// so listing the manifests doesn't walk the data blobs.
+func (s *FileBlobStore) List(prefix string) ([]string, error) {
+	root := s.dir
+	if i := strings.LastIndex(prefix, "/"); i > 0 {
+		dir, err := s.path(prefix[:i])
+		if err != nil {
+			return nil, err
+		}
+		root = dir
+	}
+
+	var keys []string
+	err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		} else if fi.IsDir() || strings.Contains(fi.Name(), Suffix) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.dir, path)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Delete removes the file holding the blob.
+func (s *FileBlobStore) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// path returns the path of the file holding the blob stored under key.
+func (s *FileBlobStore) path(key string) (string, error) {
+	if key == "" || path.IsAbs(key) || path.Clean(key) != key || strings.HasPrefix(key, "../") {
+		return "", fmt.Errorf("invalid blob key: %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+// BlobKey returns the key of the content-addressed blob with the given
+// SHA-256 digest.
+func BlobKey(digest string) string {
+	return BlobPrefix + digest[:2] + "/" + digest
+}
+
+// NextManifestKey returns the first unused key for the manifest of a backup
+// taken at t. Keys sort in the order backups were taken. They follow the
+// scheme manifests/<timestamp>.<increment>.manifest.
+func NextManifestKey(store BlobStore, t time.Time) (string, error) {
+	base := ManifestPrefix + t.UTC().Format(PortableFileNamePattern)
+	for i := 0; ; i++ {
+		key := fmt.Sprintf("%s.%02d.manifest", base, i)
+		if ok, err := store.Exists(key); err != nil {
+			return "", err
+		} else if !ok {
+			return key, nil
+		}
+	}
+}
+
+// PutContentBlob stores the content of r as a content-addressed blob, unless a
+// blob with the same content is already stored. It returns the key and the
+// digest of the blob, and whether it was uploaded.
+func PutContentBlob(store BlobStore, r io.ReadSeeker) (key, digest string, uploaded bool, err error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", "", false, err
+	}
+	digest = hex.EncodeToString(h.Sum(nil))
+	key = BlobKey(digest)
+
+	if ok, err := store.Exists(key); err != nil {
+		return "", "", false, err
+	} else if ok {
+		return key, digest, false, nil
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return "", "", false, err
+	}
+	if err := store.Put(key, r); err != nil {
+		return "", "", false, err
+	}
+	return key, digest, true, nil
+}
+
+// SaveBlobManifest writes the manifest to the store under key.
+func SaveBlobManifest(store BlobStore, key string, manifest *Manifest) error {
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("create manifest: %v", err)
+	}
+	return store.Put(key, bytes.NewReader(b))
+}
+
+// LoadBlobManifest reads the manifest stored under key.
+func LoadBlobManifest(store BlobStore, key string) (*Manifest, error) {
+	rc, err := store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var manifest Manifest
+	if err := json.NewDecoder(rc).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("read manifest: %v", err)
+	}
+	if manifest.Version > BlobManifestVersion {
+		return nil, fmt.Errorf("unsupported manifest version %d", manifest.Version)
+	}
+	return &manifest, nil
+}
+
+// BlobManifests returns the keys of the manifests in the store, oldest first.
+func BlobManifests(store BlobStore) ([]string, error) {
+	keys, err := store.List(ManifestPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	manifests := keys[:0]
+	for _, k := range keys {
+		if strings.HasSuffix(k, ".manifest") {
+			manifests = append(manifests, k)
+		}
+	}
+	return manifests, nil
+}
@@ -0,0 +1,336 @@
+package backup_util_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/cmd/influxd/backup_util"
+)
+
+func TestFileBlobStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backup-blob-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	testBlobStore(t, backup_util.NewFileBlobStore(dir))
+
+	if err := backup_util.NewFileBlobStore(dir).Put("../escape", strings.NewReader("x")); err == nil {
+		t.Fatal("expected error for key outside of the store")
+	}
+
+	// Listing a prefix whose directory doesn't exist returns no keys.
+	if keys, err := backup_util.NewFileBlobStore(dir).List("missing/dir/"); err != nil {
+		t.Fatal(err)
+	} else if len(keys) != 0 {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+}
+
+func TestS3BlobStore(t *testing.T) {
+	stub := NewS3Stub("bucket0")
+	defer stub.Close()
+
+	store, err := backup_util.NewS3BlobStore(backup_util.S3Config{
+		Endpoint:        strings.TrimPrefix(stub.URL, "http://"),
+		Bucket:          "bucket0",
+		Prefix:          "/influxdb/",
+		Insecure:        true,
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testBlobStore(t, store)
+
+	// Objects are stored under the prefix.
+	for key := range stub.Objects() {
+		if !strings.HasPrefix(key, "influxdb/") {
+			t.Fatalf("object stored outside of prefix: %s", key)
+		}
+	}
+}
+
+func TestS3BlobStore_Error(t *testing.T) {
+	stub := NewS3Stub("bucket0")
+	defer stub.Close()
+
+	store, err := backup_util.NewS3BlobStore(backup_util.S3Config{
+		Endpoint: strings.TrimPrefix(stub.URL, "http://"),
+		Bucket:   "missing",
+		Insecure: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Put("a", strings.NewReader("x")); err == nil || !strings.Contains(err.Error(), "NoSuchBucket") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestPutContentBlob(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backup-blob-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := backup_util.NewFileBlobStore(dir)
+
+	key, digest, uploaded, err := backup_util.PutContentBlob(store, strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	} else if !uploaded {
+		t.Fatal("expected blob to be uploaded")
+	} else if exp := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"; digest != exp {
+		t.Fatalf("unexpected digest: %s", digest)
+	} else if exp := "blobs/2c/" + digest; key != exp {
+		t.Fatalf("unexpected key: got %s, exp %s", key, exp)
+	}
+
+	// The same content is only stored once.
+	if k, _, uploaded, err := backup_util.PutContentBlob(store, strings.NewReader("hello")); err != nil {
+		t.Fatal(err)
+	} else if uploaded || k != key {
+		t.Fatalf("unexpected upload of existing blob: key=%s uploaded=%v", k, uploaded)
+	}
+}
+
+func TestBlobManifests(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backup-blob-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := backup_util.NewFileBlobStore(dir)
+
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	var keys []string
+	for i := 0; i < 2; i++ {
+		key, err := backup_util.NextManifestKey(store, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := &backup_util.Manifest{
+			Version: backup_util.BlobManifestVersion,
+			Created: now.UnixNano(),
+			Meta:    backup_util.MetaEntry{FileName: "blobs/00/00", Size: 1},
+			Files:   []backup_util.Entry{{Database: "db0", Policy: "rp0", ShardID: uint64(i), FileName: "blobs/01/01", Path: "000000001-000000001.tsm", Digest: "01"}},
+		}
+		if err := backup_util.SaveBlobManifest(store, key, m); err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+	}
+
+	if exp := []string{"manifests/20190101T000000Z.00.manifest", "manifests/20190101T000000Z.01.manifest"}; !reflect.DeepEqual(keys, exp) {
+		t.Fatalf("unexpected keys: got %v, exp %v", keys, exp)
+	}
+
+	if got, err := backup_util.BlobManifests(store); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(got, keys) {
+		t.Fatalf("unexpected manifests: got %v, exp %v", got, keys)
+	}
+
+	m, err := backup_util.LoadBlobManifest(store, keys[1])
+	if err != nil {
+		t.Fatal(err)
+	} else if len(m.Files) != 1 || m.Files[0].ShardID != 1 || m.Files[0].Path != "000000001-000000001.tsm" {
+		t.Fatalf("unexpected manifest: %+v", m)
+	}
+}
+
+func TestOpenBlobStore(t *testing.T) {
+	if store, err := backup_util.OpenBlobStore("file:///var/lib/influxdb/backups"); err != nil {
+		t.Fatal(err)
+	} else if _, ok := store.(*backup_util.FileBlobStore); !ok {
+		t.Fatalf("unexpected store: %T", store)
+	}
+
+	if store, err := backup_util.OpenBlobStore("s3://bucket/prefix?endpoint=localhost:9000&insecure=true"); err != nil {
+		t.Fatal(err)
+	} else if _, ok := store.(*backup_util.S3BlobStore); !ok {
+		t.Fatalf("unexpected store: %T", store)
+	}
+
+	for _, u := range []string{"ftp://host/dir", "s3:///prefix", "file://"} {
+		if _, err := backup_util.OpenBlobStore(u); err == nil {
+			t.Fatalf("expected error opening %s", u)
+		}
+	}
+}
+
+// testBlobStore runs the operations common to every BlobStore against store.
+func testBlobStore(t *testing.T, store backup_util.BlobStore) {
+	t.Helper()
+
+	for _, key := range []string{"a/1", "a/2", "a/3", "b/1"} {
+		if err := store.Put(key, strings.NewReader("value-"+key)); err != nil {
+			t.Fatalf("put %s: %s", key, err)
+		}
+	}
+
+	rc, err := store.Get("a/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	} else if string(b) != "value-a/2" {
+		t.Fatalf("unexpected value: %q", b)
+	}
+
+	if ok, err := store.Exists("a/1"); err != nil || !ok {
+		t.Fatalf("expected a/1 to exist: ok=%v err=%v", ok, err)
+	}
+	if ok, err := store.Exists("c/1"); err != nil || ok {
+		t.Fatalf("expected c/1 not to exist: ok=%v err=%v", ok, err)
+	}
+
+	if keys, err := store.List("a/"); err != nil {
+		t.Fatal(err)
+	} else if exp := []string{"a/1", "a/2", "a/3"}; !reflect.DeepEqual(keys, exp) {
+		t.Fatalf("unexpected keys: got %v, exp %v", keys, exp)
+	}
+
+	if err := store.Delete("a/1"); err != nil {
+		t.Fatal(err)
+	} else if err := store.Delete("a/1"); err != nil {
+		t.Fatalf("unexpected error deleting missing blob: %s", err)
+	}
+	if _, err := store.Get("a/1"); err != backup_util.ErrBlobNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// S3Stub is an in-memory stub of the subset of the S3 API used by S3BlobStore.
+// It returns at most two keys per list request to exercise pagination.
+type S3Stub struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	bucket  string
+	objects map[string][]byte
+}
+
+// NewS3Stub returns a running S3 stub serving a single bucket.
+func NewS3Stub(bucket string) *S3Stub {
+	s := &S3Stub{bucket: bucket, objects: make(map[string][]byte)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Objects returns a copy of the stored objects.
+func (s *S3Stub) Objects() map[string][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := make(map[string][]byte, len(s.objects))
+	for k, v := range s.objects {
+		m[k] = v
+	}
+	return m
+}
+
+func (s *S3Stub) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if auth := r.Header.Get("Authorization"); auth != "" && !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") {
+		s.error(w, http.StatusForbidden, "AccessDenied")
+		return
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	if parts[0] != s.bucket {
+		s.error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	if len(parts) == 1 {
+		s.list(w, r.URL.Query())
+		return
+	}
+
+	key := parts[1]
+	switch r.Method {
+	case "PUT":
+		b, _ := ioutil.ReadAll(r.Body)
+		if r.ContentLength != int64(len(b)) {
+			s.error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		s.objects[key] = b
+	case "GET", "HEAD":
+		b, ok := s.objects[key]
+		if !ok {
+			s.error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+		if r.Method == "GET" {
+			w.Write(b)
+		}
+	case "DELETE":
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *S3Stub) list(w http.ResponseWriter, query url.Values) {
+	var keys []string
+	for k := range s.objects {
+		if strings.HasPrefix(k, query.Get("prefix")) && k > query.Get("continuation-token") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	type content struct {
+		Key string
+	}
+	var result struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		Contents              []content
+		IsTruncated           bool
+		NextContinuationToken string `xml:",omitempty"`
+	}
+	for i, k := range keys {
+		if i == 2 {
+			result.IsTruncated = true
+			result.NextContinuationToken = keys[i-1]
+			break
+		}
+		result.Contents = append(result.Contents, content{Key: k})
+	}
+
+	var buf bytes.Buffer
+	xml.NewEncoder(&buf).Encode(result)
+	w.Write(buf.Bytes())
+}
+
+func (s *S3Stub) error(w http.ResponseWriter, code int, errCode string) {
+	w.WriteHeader(code)
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string
+		Message string
+	}{Code: errCode, Message: http.StatusText(code)})
+}
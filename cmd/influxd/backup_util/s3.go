@@ -0,0 +1,357 @@
+package backup_util
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultS3Endpoint is the endpoint used when S3Config.Endpoint is empty.
+	DefaultS3Endpoint = "s3.amazonaws.com"
+
+	// DefaultS3Region is the region used when S3Config.Region is empty.
+	DefaultS3Region = "us-east-1"
+
+	// s3UnsignedPayload is the payload hash sent for request bodies that are not hashed.
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+	// s3TimeFormat is the format of the x-amz-date header.
+	s3TimeFormat = "20060102T150405Z"
+)
+
+// S3Config configures a BlobStore backed by an S3-compatible object store.
+type S3Config struct {
+	// Endpoint is the host[:port] of the object store.
+	Endpoint string
+
+	// Region is the region used to sign requests.
+	Region string
+
+	// Bucket holds the blobs, under the optional Prefix.
+	Bucket string
+	Prefix string
+
+	// Insecure uses plain HTTP rather than HTTPS.
+	Insecure bool
+
+	// Credentials used to sign requests. Requests are sent unsigned when
+	// AccessKeyID is empty.
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// S3BlobStore is a BlobStore backed by an S3-compatible object store. Objects
+// are addressed path-style and requests are signed with AWS Signature Version 4.
+type S3BlobStore struct {
+	config S3Config
+	client *http.Client
+}
+
+// NewS3BlobStore returns a BlobStore storing blobs in an S3 bucket.
+func NewS3BlobStore(c S3Config) (*S3BlobStore, error) {
+	if c.Bucket == "" {
+		return nil, errors.New("s3 bucket required")
+	}
+	if c.Endpoint == "" {
+		c.Endpoint = DefaultS3Endpoint
+	}
+	if c.Region == "" {
+		c.Region = DefaultS3Region
+	}
+	c.Prefix = strings.Trim(c.Prefix, "/")
+
+	return &S3BlobStore{
+		config: c,
+		client: &http.Client{},
+	}, nil
+}
+
+// Put uploads the blob as a single object.
+func (s *S3BlobStore) Put(key string, r io.Reader) error {
+	// S3 requires the length of the object up front, so buffer readers that
+	// can't report it.
+	var size int64
+	if rs, ok := r.(io.ReadSeeker); ok {
+		cur, err := rs.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		end, err := rs.Seek(0, io.SeekEnd)
+		if err != nil {
+			return err
+		}
+		if _, err := rs.Seek(cur, io.SeekStart); err != nil {
+			return err
+		}
+		size = end - cur
+	} else {
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		r, size = bytes.NewReader(b), int64(len(b))
+	}
+
+	resp, err := s.do("PUT", s.objectPath(key), nil, ioutil.NopCloser(r), size)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return s3ResponseError(resp)
+	}
+	return nil
+}
+
+// Get downloads the object holding the blob.
+func (s *S3BlobStore) Get(key string) (io.ReadCloser, error) {
+	resp, err := s.do("GET", s.objectPath(key), nil, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrBlobNotFound
+	default:
+		defer resp.Body.Close()
+		return nil, s3ResponseError(resp)
+	}
+}
+
+// Exists sends a HEAD request for the object holding the blob.
+func (s *S3BlobStore) Exists(key string) (bool, error) {
+	resp, err := s.do("HEAD", s.objectPath(key), nil, nil, 0)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("s3: unexpected status %s", resp.Status)
+	}
+}
+
+// List pages through the objects of the bucket starting with prefix.
+func (s *S3BlobStore) List(prefix string) ([]string, error) {
+	var keys []string
+	var token string
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", s.objectKey(prefix))
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := s.do("GET", "/"+s.config.Bucket, query, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Contents []struct {
+				Key string
+			}
+			IsTruncated           bool
+			NextContinuationToken string
+		}
+		if resp.StatusCode != http.StatusOK {
+			err = s3ResponseError(resp)
+		} else {
+			err = xml.NewDecoder(resp.Body).Decode(&result)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range result.Contents {
+			keys = append(keys, strings.TrimPrefix(c.Key, s.objectKey("")))
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Delete removes the object holding the blob.
+func (s *S3BlobStore) Delete(key string) error {
+	resp, err := s.do("DELETE", s.objectPath(key), nil, nil, 0)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return s3ResponseError(resp)
+	}
+}
+
+// objectKey returns the object key of a blob key.
+func (s *S3BlobStore) objectKey(key string) string {
+	if s.config.Prefix == "" {
+		return key
+	}
+	return s.config.Prefix + "/" + key
+}
+
+// objectPath returns the path-style request path of a blob key.
+func (s *S3BlobStore) objectPath(key string) string {
+	return "/" + s.config.Bucket + "/" + s.objectKey(key)
+}
+
+// do sends a signed request to the object store.
+func (s *S3BlobStore) do(method, path string, query url.Values, body io.ReadCloser, size int64) (*http.Response, error) {
+	scheme := "https"
+	if s.config.Insecure {
+		scheme = "http"
+	}
+
+	u := &url.URL{Scheme: scheme, Host: s.config.Endpoint, Path: path, RawPath: s3EncodePath(path), RawQuery: s3EncodeQuery(query)}
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Body = body
+		req.ContentLength = size
+	}
+
+	s.sign(req, path, query)
+	return s.client.Do(req)
+}
+
+// sign adds the AWS Signature Version 4 headers to req.
+func (s *S3BlobStore) sign(req *http.Request, path string, query url.Values) {
+	now := time.Now().UTC()
+	amzDate := now.Format(s3TimeFormat)
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", s3UnsignedPayload)
+
+	if s.config.AccessKeyID == "" {
+		return
+	}
+
+	// Build the canonical request from the signed headers.
+	signed := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	var headers bytes.Buffer
+	for _, h := range signed {
+		v := req.Header.Get(h)
+		if h == "host" {
+			v = req.URL.Host
+		}
+		fmt.Fprintf(&headers, "%s:%s\n", h, strings.TrimSpace(v))
+	}
+
+	canonical := strings.Join([]string{
+		req.Method,
+		s3EncodePath(path),
+		s3EncodeQuery(query),
+		headers.String(),
+		strings.Join(signed, ";"),
+		s3UnsignedPayload,
+	}, "\n")
+
+	day := now.Format("20060102")
+	scope := day + "/" + s.config.Region + "/s3/aws4_request"
+	digest := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(digest[:])
+
+	key := s3HMAC([]byte("AWS4"+s.config.SecretAccessKey), day)
+	key = s3HMAC(key, s.config.Region)
+	key = s3HMAC(key, "s3")
+	key = s3HMAC(key, "aws4_request")
+	signature := hex.EncodeToString(s3HMAC(key, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKeyID, scope, strings.Join(signed, ";"), signature))
+}
+
+func s3HMAC(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3Escape percent-encodes every byte of s except the unreserved characters,
+// as required by Signature Version 4.
+func s3Escape(s string) string {
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			buf.WriteByte(c)
+		} else {
+			fmt.Fprintf(&buf, "%%%02X", c)
+		}
+	}
+	return buf.String()
+}
+
+// s3EncodePath encodes each segment of path.
+func s3EncodePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i := range segments {
+		segments[i] = s3Escape(segments[i])
+	}
+	return strings.Join(segments, "/")
+}
+
+// s3EncodeQuery encodes query sorted by key.
+func s3EncodeQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range query[k] {
+			pairs = append(pairs, s3Escape(k)+"="+s3Escape(v))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// s3ResponseError returns the error described by an error response.
+func s3ResponseError(resp *http.Response) error {
+	var e struct {
+		Code    string
+		Message string
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&e); err != nil || e.Code == "" {
+		return fmt.Errorf("s3: unexpected status %s", resp.Status)
+	}
+	return fmt.Errorf("s3: %s: %s", e.Code, e.Message)
+}
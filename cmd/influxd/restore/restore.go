@@ -5,23 +5,30 @@ package restore
 import (
 	"archive/tar"
 	"bytes"
+	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/golang/snappy"
 	gzip "github.com/klauspost/pgzip"
 
 	"github.com/influxdata/influxdb/cmd/influxd/backup_util"
+	"github.com/influxdata/influxdb/pkg/bytesutil"
 	tarstream "github.com/influxdata/influxdb/pkg/tar"
 	"github.com/influxdata/influxdb/services/meta"
 	"github.com/influxdata/influxdb/services/snapshotter"
+	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
 )
 
 // Command represents the program execution for "influxd restore".
@@ -49,6 +56,17 @@ type Command struct {
 	online              bool
 	manifestMeta        *backup_util.MetaEntry
 	manifestFiles       map[uint64]*backup_util.Entry
+	blobStoreURL        string
+	blobManifestKey     string
+	blobStore           backup_util.BlobStore
+	blobManifest        *backup_util.Manifest
+	waldir              string
+	walArchiveDir       string
+	until               time.Time
+
+	// restoredShards holds the paths of the shards restored offline,
+	// relative to the data directory.
+	restoredShards []string
 
 	// TODO: when the new meta stuff is done this should not be exported or be gone
 	MetaConfig *meta.Config
@@ -74,13 +92,25 @@ func (cmd *Command) Run(args ...string) error {
 		return err
 	}
 
-	if cmd.portable {
+	var err error
+	if cmd.blobManifest != nil {
+		if cmd.metadir != "" || cmd.datadir != "" {
+			err = cmd.runOfflineBlob()
+		} else {
+			return cmd.runOnlineBlob()
+		}
+	} else if cmd.portable {
 		return cmd.runOnlinePortable()
 	} else if cmd.online {
 		return cmd.runOnlineLegacy()
 	} else {
-		return cmd.runOffline()
+		err = cmd.runOffline()
+	}
+
+	if err != nil || cmd.until.IsZero() {
+		return err
 	}
+	return cmd.replayWALArchive()
 }
 
 func (cmd *Command) runOffline() error {
@@ -146,16 +176,52 @@ func (cmd *Command) parseFlags(args []string) error {
 	fs.Uint64Var(&cmd.shard, "shard", 0, "")
 	fs.BoolVar(&cmd.online, "online", false, "")
 	fs.BoolVar(&cmd.portable, "portable", false, "")
+	fs.StringVar(&cmd.blobStoreURL, "blob-store", "", "")
+	fs.StringVar(&cmd.blobManifestKey, "manifest", "", "")
+	fs.StringVar(&cmd.waldir, "waldir", "", "")
+	fs.StringVar(&cmd.walArchiveDir, "wal-archive", "", "")
+	until := fs.String("until", "", "")
 	fs.SetOutput(cmd.Stdout)
 	fs.Usage = cmd.printUsage
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
 
+	if *until != "" {
+		t, err := time.Parse(time.RFC3339Nano, *until)
+		if err != nil {
+			return fmt.Errorf("invalid -until time: %s", err)
+		} else if cmd.online || cmd.portable {
+			return fmt.Errorf("-until is not compatible with -online or -portable")
+		} else if cmd.datadir == "" || cmd.waldir == "" || cmd.walArchiveDir == "" {
+			return fmt.Errorf("-datadir, -waldir and -wal-archive are required with -until")
+		}
+		cmd.until = t
+	} else if cmd.waldir != "" || cmd.walArchiveDir != "" {
+		return fmt.Errorf("-waldir and -wal-archive require -until")
+	}
+
 	cmd.MetaConfig = meta.NewConfig()
 	cmd.MetaConfig.Dir = cmd.metadir
 	cmd.client = snapshotter.NewClient(cmd.host)
 
+	if cmd.blobStoreURL != "" {
+		if cmd.portable || cmd.online {
+			return fmt.Errorf("-blob-store is not compatible with -portable or -online")
+		} else if fs.NArg() != 0 {
+			return fmt.Errorf("-blob-store does not take a backup path")
+		} else if cmd.shard != 0 && (cmd.sourceDatabase == "" || cmd.backupRetention == "") {
+			return fmt.Errorf("-db and -rp are required to restore shard")
+		}
+
+		if cmd.restoreRetention == "" {
+			cmd.restoreRetention = cmd.backupRetention
+		}
+		return cmd.loadBlobManifest()
+	} else if cmd.blobManifestKey != "" {
+		return fmt.Errorf("-manifest requires -blob-store")
+	}
+
 	// Require output path.
 	cmd.backupFilesPath = fs.Arg(0)
 	if cmd.backupFilesPath == "" {
@@ -556,12 +622,411 @@ func (cmd *Command) unpackTar(tarFile string) error {
 		return fmt.Errorf("backup tarfile name incorrect format")
 	}
 
-	shardPath := filepath.Join(cmd.datadir, pathParts[0], pathParts[1], strings.Trim(pathParts[2], "0"))
+	shard := filepath.Join(pathParts[0], pathParts[1], strings.Trim(pathParts[2], "0"))
+	shardPath := filepath.Join(cmd.datadir, shard)
 	os.MkdirAll(shardPath, 0755)
+	cmd.restoredShards = append(cmd.restoredShards, shard)
 
 	return tarstream.Restore(f, shardPath)
 }
 
+// loadBlobManifest opens the blob store and reads the manifest to restore
+// from, the most recent one unless -manifest is given.
+func (cmd *Command) loadBlobManifest() error {
+	store, err := backup_util.OpenBlobStore(cmd.blobStoreURL)
+	if err != nil {
+		return err
+	}
+
+	key := cmd.blobManifestKey
+	var manifest *backup_util.Manifest
+	if key == "" {
+		keys, err := backup_util.BlobManifests(store)
+		if err != nil {
+			return err
+		}
+
+		// Use the most recent backup, taken before -until if given.
+		for i := len(keys) - 1; i >= 0; i-- {
+			m, err := backup_util.LoadBlobManifest(store, keys[i])
+			if err != nil {
+				return fmt.Errorf("load manifest %s: %s", keys[i], err)
+			} else if !cmd.until.IsZero() && time.Unix(0, m.Created).After(cmd.until) {
+				continue
+			}
+			key, manifest = keys[i], m
+			break
+		}
+
+		if manifest == nil {
+			return fmt.Errorf("no manifests found in: %s", cmd.blobStoreURL)
+		}
+	} else {
+		m, err := backup_util.LoadBlobManifest(store, key)
+		if err != nil {
+			return fmt.Errorf("load manifest %s: %s", key, err)
+		}
+		manifest = m
+	}
+	cmd.StdoutLogger.Printf("Using manifest: %s\n", key)
+
+	cmd.blobStore, cmd.blobManifest = store, manifest
+	return nil
+}
+
+// runOfflineBlob restores the meta store and shards of a blob store backup to
+// the meta and data directories of a stopped server.
+func (cmd *Command) runOfflineBlob() error {
+	if cmd.metadir != "" {
+		if err := cmd.unpackMetaBlob(); err != nil {
+			return err
+		}
+	}
+
+	if cmd.datadir != "" {
+		for _, sh := range cmd.blobShards() {
+			if err := cmd.unpackShardBlob(sh); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// runOnlineBlob imports the meta store and shards of a blob store backup into
+// a running server.
+func (cmd *Command) runOnlineBlob() error {
+	metaBytes, err := cmd.readMetaBlob()
+	if err != nil {
+		cmd.StderrLogger.Printf("error updating meta: %v", err)
+		return err
+	}
+
+	req := &snapshotter.Request{
+		Type:                   snapshotter.RequestMetaStoreUpdate,
+		BackupDatabase:         cmd.sourceDatabase,
+		RestoreDatabase:        cmd.destinationDatabase,
+		BackupRetentionPolicy:  cmd.backupRetention,
+		RestoreRetentionPolicy: cmd.restoreRetention,
+		UploadSize:             int64(len(metaBytes)),
+	}
+
+	cmd.shardIDMap, err = cmd.client.UpdateMeta(req, bytes.NewReader(metaBytes))
+	if err != nil {
+		cmd.StderrLogger.Printf("error updating meta: %v", err)
+		return err
+	}
+
+	for _, sh := range cmd.blobShards() {
+		// if newID not found then this shard's metadata was NOT imported
+		// and should be skipped
+		newID, ok := cmd.shardIDMap[sh.id]
+		if !ok {
+			cmd.StdoutLogger.Printf("Meta info not found for shard %d on database %s. Skipping shard", sh.id, sh.database)
+			continue
+		}
+
+		targetDB := cmd.destinationDatabase
+		if targetDB == "" {
+			targetDB = sh.database
+		}
+
+		cmd.StdoutLogger.Printf("Restoring shard %d live from blob store\n", sh.id)
+		pr, pw := io.Pipe()
+		go func(sh *blobShard) { pw.CloseWithError(cmd.writeShardTar(pw, sh)) }(sh)
+
+		err := cmd.client.UploadShard(sh.id, newID, targetDB, cmd.restoreRetention, tar.NewReader(pr))
+		pr.Close()
+		if err != nil {
+			cmd.StderrLogger.Printf("error updating shards: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// readMetaBlob returns the meta store of a blob store backup.
+func (cmd *Command) readMetaBlob() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := cmd.copyBlob(&buf, cmd.blobManifest.Meta.FileName, ""); err != nil {
+		return nil, err
+	}
+
+	var ep backup_util.PortablePacker
+	if err := ep.UnmarshalBinary(buf.Bytes()); err != nil {
+		return nil, err
+	}
+	return ep.Data, nil
+}
+
+// unpackMetaBlob replaces the meta store in the meta directory with the meta
+// store of a blob store backup.
+func (cmd *Command) unpackMetaBlob() error {
+	metaBytes, err := cmd.readMetaBlob()
+	if err != nil {
+		return err
+	}
+
+	var data meta.Data
+	if err := data.UnmarshalBinary(metaBytes); err != nil {
+		return fmt.Errorf("unmarshal: %s", err)
+	}
+
+	if err := os.MkdirAll(cmd.MetaConfig.Dir, 0700); err != nil {
+		return err
+	}
+
+	client := meta.NewClient(cmd.MetaConfig)
+	if err := client.Open(); err != nil {
+		return err
+	}
+	defer client.Close()
+
+	// Force set the full metadata.
+	if err := client.SetData(&data); err != nil {
+		return fmt.Errorf("set data: %s", err)
+	}
+	return nil
+}
+
+// blobShard lists the files of a shard in a blob store backup.
+type blobShard struct {
+	database string
+	policy   string
+	id       uint64
+	files    []backup_util.Entry
+}
+
+// blobShards returns the shards of the blob store backup selected with -db,
+// -rp and -shard, in manifest order.
+func (cmd *Command) blobShards() []*blobShard {
+	var shards []*blobShard
+	byID := make(map[uint64]*blobShard)
+	for _, f := range cmd.blobManifest.Files {
+		if (cmd.sourceDatabase != "" && cmd.sourceDatabase != f.Database) ||
+			(cmd.backupRetention != "" && cmd.backupRetention != f.Policy) ||
+			(cmd.shard != 0 && cmd.shard != f.ShardID) {
+			continue
+		}
+
+		sh := byID[f.ShardID]
+		if sh == nil {
+			sh = &blobShard{database: f.Database, policy: f.Policy, id: f.ShardID}
+			byID[f.ShardID] = sh
+			shards = append(shards, sh)
+		}
+		sh.files = append(sh.files, f)
+	}
+	return shards
+}
+
+// unpackShardBlob restores the files of a shard to the data dir.
+func (cmd *Command) unpackShardBlob(sh *blobShard) error {
+	// make sure the shard isn't already there so we don't clobber anything
+	shard := filepath.Join(sh.database, sh.policy, strconv.FormatUint(sh.id, 10))
+	shardPath := filepath.Join(cmd.datadir, shard)
+	if _, err := os.Stat(shardPath); err == nil {
+		return fmt.Errorf("shard already present: %s", shardPath)
+	}
+	cmd.StdoutLogger.Printf("Restoring offline shard %d to %s\n", sh.id, shardPath)
+	cmd.restoredShards = append(cmd.restoredShards, shard)
+
+	for _, f := range sh.files {
+		path := filepath.Join(shardPath, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+
+		out, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		if err := cmd.copyBlob(out, f.FileName, f.Digest); err != nil {
+			out.Close()
+			return err
+		} else if err := out.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeShardTar writes the files of a shard to w as a tar archive in the
+// layout expected by the snapshotter service.
+func (cmd *Command) writeShardTar(w io.Writer, sh *blobShard) error {
+	tw := tar.NewWriter(w)
+	for _, f := range sh.files {
+		hdr := &tar.Header{
+			Name:    filepath.ToSlash(filepath.Join(sh.database, sh.policy, strconv.FormatUint(sh.id, 10), filepath.FromSlash(f.Path))),
+			Mode:    0666,
+			Size:    f.Size,
+			ModTime: time.Unix(0, f.LastModified),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if err := cmd.copyBlob(tw, f.FileName, f.Digest); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// copyBlob copies the blob stored under key to w. If digest is not empty, the
+// content of the blob is checked against it.
+func (cmd *Command) copyBlob(w io.Writer, key, digest string) error {
+	rc, err := cmd.blobStore.Get(key)
+	if err != nil {
+		return fmt.Errorf("get blob %s: %s", key, err)
+	}
+	defer rc.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(w, h), rc); err != nil {
+		return fmt.Errorf("copy blob %s: %s", key, err)
+	}
+
+	if digest != "" && hex.EncodeToString(h.Sum(nil)) != digest {
+		return fmt.Errorf("blob %s is corrupt: digest mismatch", key)
+	}
+	return nil
+}
+
+// replayWALArchive writes the archived WAL entries of the restored shards,
+// written after the backup was taken and up to -until, to the WAL directory.
+// The server loads them into the cache of the shards when it starts.
+func (cmd *Command) replayWALArchive() error {
+	// Entries written before a blob store backup are part of it. Legacy
+	// backups don't record when they were taken, so the whole archive is
+	// replayed; replaying an entry twice yields the same data.
+	var since time.Time
+	if cmd.blobManifest != nil {
+		since = time.Unix(0, cmd.blobManifest.Created)
+	}
+
+	for _, shard := range cmd.restoredShards {
+		if err := cmd.replayShardWAL(shard, since); err != nil {
+			return fmt.Errorf("replay WAL of shard %s: %s", shard, err)
+		}
+	}
+	return nil
+}
+
+// replayShardWAL replays the archived WAL entries of a restored shard.
+func (cmd *Command) replayShardWAL(shard string, since time.Time) error {
+	archivePath := filepath.Join(cmd.walArchiveDir, shard)
+	if _, err := os.Stat(archivePath); os.IsNotExist(err) {
+		cmd.StdoutLogger.Printf("No archived WAL for shard %s\n", shard)
+		return nil
+	}
+
+	// make sure the WAL is empty so we don't clobber anything
+	walPath := filepath.Join(cmd.waldir, shard)
+	if segments, err := filepath.Glob(filepath.Join(walPath, "*."+tsm1.WALFileExtension)); err != nil {
+		return err
+	} else if len(segments) > 0 {
+		return fmt.Errorf("WAL already present: %s", walPath)
+	}
+
+	if err := os.MkdirAll(walPath, 0700); err != nil {
+		return err
+	}
+
+	segment := filepath.Join(walPath, fmt.Sprintf("%s%05d.%s", tsm1.WALFilePrefix, 1, tsm1.WALFileExtension))
+	f, err := os.OpenFile(segment, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := tsm1.NewWALSegmentWriter(f)
+	var deletes []*tsm1.DeleteRangeWALEntry
+	var n int
+	if err := tsm1.ReplayWALArchive(archivePath, since, cmd.until, func(entry tsm1.WALEntry, _ time.Time) error {
+		switch e := entry.(type) {
+		case *tsm1.DeleteRangeWALEntry:
+			deletes = append(deletes, e)
+		case *tsm1.DeleteWALEntry:
+			deletes = append(deletes, &tsm1.DeleteRangeWALEntry{Keys: e.Keys, Min: math.MinInt64, Max: math.MaxInt64})
+		}
+
+		b, err := entry.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		n++
+		return w.Write(entry.Type(), snappy.Encode(nil, b))
+	}); err != nil {
+		return err
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	} else if err := f.Sync(); err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return os.Remove(segment)
+	}
+
+	// Deletes also apply to the data of the backup.
+	shardPath := filepath.Join(cmd.datadir, shard)
+	if err := tombstoneShard(shardPath, deletes); err != nil {
+		return err
+	}
+
+	// Drop the field index of the backup so that the series created by the
+	// replayed writes are indexed when the shard is opened.
+	if err := os.Remove(filepath.Join(shardPath, "fields.idx")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	cmd.StdoutLogger.Printf("Replayed %d WAL entries of shard %s up to %s\n", n, shard, cmd.until.Format(time.RFC3339Nano))
+	return nil
+}
+
+// tombstoneShard applies deletes to the TSM files of the shard at path.
+func tombstoneShard(path string, deletes []*tsm1.DeleteRangeWALEntry) error {
+	if len(deletes) == 0 {
+		return nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(path, "*."+tsm1.TSMFileExtension))
+	if err != nil {
+		return err
+	}
+
+	for _, d := range deletes {
+		bytesutil.Sort(d.Keys)
+	}
+
+	for _, fn := range files {
+		f, err := os.Open(fn)
+		if err != nil {
+			return err
+		}
+
+		r, err := tsm1.NewTSMReader(f)
+		if err != nil {
+			f.Close()
+			return err
+		}
+
+		for _, d := range deletes {
+			if err := r.DeleteRange(d.Keys, d.Min, d.Max); err != nil {
+				r.Close()
+				return err
+			}
+		}
+
+		if err := r.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // printUsage prints the usage message to STDERR.
 func (cmd *Command) printUsage() {
 	fmt.Fprintf(cmd.Stdout, `
@@ -569,6 +1034,8 @@ Uses backup copies from the specified PATH to restore databases or specific shar
   or InfluxDB Enterprise to an InfluxDB OSS instance.
 
 Usage: influxd restore -portable [options] PATH
+       influxd restore -blob-store <url> [options]
+       influxd restore -until <time> -datadir <dir> -waldir <dir> -wal-archive <dir> [options] [PATH]
 
 Note: Restore using the '-portable' option consumes files in an improved Enterprise-compatible 
   format that includes a file manifest.
@@ -593,6 +1060,23 @@ Options:
     -shard <id>
             Identifier of the shard to be restored. Optional. If specified, then '-db <db_name>' and '-rp <rp_name>' are
             required.
+    -blob-store <url>
+            Restores from a blob store backup written by the server instead of PATH, either file:///path/to/dir
+            or s3://bucket/prefix?endpoint=host:port&region=name. S3 credentials are read from AWS_ACCESS_KEY_ID
+            and AWS_SECRET_ACCESS_KEY. The backup is imported into the server at '-host', or written to
+            '-metadir' and '-datadir' when either is given.
+    -manifest <key>
+            Key of the manifest of the blob store backup to restore, such as
+            manifests/20190101T000000Z.00.manifest. Optional. Defaults to the most recent backup, taken
+            before '-until' if given.
+    -until <time>
+            Restores the data as it was at the given RFC3339 time. The backup is restored offline to '-datadir',
+            then the entries of the WAL archive written after the backup and up to the given time are written to
+            '-waldir', to be loaded when the server starts. Requires WAL archiving to be enabled on the server.
+    -waldir <dir>
+            WAL directory of the server the backup is restored to. Required by '-until'.
+    -wal-archive <dir>
+            WAL archive directory of the server the backup was taken from. Required by '-until'.
     PATH
             Path to directory containing the backup files.
 
@@ -0,0 +1,340 @@
+package restore_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/influxdb/cmd/influxd/backup_util"
+	"github.com/influxdata/influxdb/cmd/influxd/restore"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
+)
+
+// Ensure a blob store backup can be restored to the meta and data directories.
+func TestRestore_Blob(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	store := backup_util.NewFileBlobStore(filepath.Join(dir, "backup"))
+	manifest := MustWriteBlobBackup(store, time.Unix(0, 0))
+
+	cmd := NewCommand()
+	metadir, datadir := filepath.Join(dir, "meta"), filepath.Join(dir, "data")
+	if err := cmd.Run("-blob-store", "file://"+filepath.Join(dir, "backup"), "-metadir", metadir, "-datadir", datadir); err != nil {
+		t.Fatal(err)
+	}
+
+	if out := cmd.Stdout.(*bytes.Buffer).String(); !strings.Contains(out, "Restoring offline shard 1") {
+		t.Fatalf("unexpected output: %s", out)
+	}
+
+	// The shard files must be restored with the content of the backup.
+	path := filepath.Join(datadir, "db0", "rp0", "1", manifest.Files[0].Path)
+	if b, err := ioutil.ReadFile(path); err != nil {
+		t.Fatal(err)
+	} else if int64(len(b)) != manifest.Files[0].Size {
+		t.Fatalf("unexpected file size: %d", len(b))
+	}
+	if values := MustReadTSMFile(t, path, "cpu,host=A#!~#value"); len(values) != 2 {
+		t.Fatalf("unexpected values: %v", values)
+	}
+
+	// The meta store must be restored.
+	config := meta.NewConfig()
+	config.Dir = metadir
+	client := meta.NewClient(config)
+	if err := client.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if db := client.Database("db0"); db == nil {
+		t.Fatal("expected database db0 to be restored")
+	}
+}
+
+// Ensure a blob store backup with a corrupt shard file is not restored.
+func TestRestore_Blob_CorruptObject(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	store := backup_util.NewFileBlobStore(filepath.Join(dir, "backup"))
+	manifest := MustWriteBlobBackup(store, time.Unix(0, 0))
+
+	key := manifest.Files[0].FileName
+	if err := store.Put(key, strings.NewReader("corrupt")); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := NewCommand()
+	err := cmd.Run("-blob-store", "file://"+filepath.Join(dir, "backup"), "-datadir", filepath.Join(dir, "data"))
+	if err == nil || err.Error() != "blob "+key+" is corrupt: digest mismatch" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure a blob store backup with a missing shard file is not restored.
+func TestRestore_Blob_MissingObject(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	store := backup_util.NewFileBlobStore(filepath.Join(dir, "backup"))
+	manifest := MustWriteBlobBackup(store, time.Unix(0, 0))
+
+	key := manifest.Files[0].FileName
+	if err := store.Delete(key); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := NewCommand()
+	err := cmd.Run("-blob-store", "file://"+filepath.Join(dir, "backup"), "-datadir", filepath.Join(dir, "data"))
+	if err == nil || !strings.HasPrefix(err.Error(), "get blob "+key+": ") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure the archived WAL entries written after a blob store backup and up to
+// -until are replayed into the WAL of the restored shard.
+func TestRestore_Blob_Until(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	created := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := backup_util.NewFileBlobStore(filepath.Join(dir, "backup"))
+	MustWriteBlobBackup(store, created)
+
+	// Entries archived before the backup and after -until are not replayed.
+	// The replayed delete also applies to the data of the backup.
+	key := []byte("cpu,host=A#!~#value")
+	archivedir := filepath.Join(dir, "wal-archive")
+	MustWriteArchivedSegment(filepath.Join(archivedir, "db0", "rp0", "1"), []ArchivedEntry{
+		{Time: created.Add(-time.Hour), Entry: &tsm1.WriteWALEntry{Values: map[string][]tsm1.Value{string(key): {tsm1.NewValue(0, 1.0)}}}},
+		{Time: created.Add(time.Hour), Entry: &tsm1.WriteWALEntry{Values: map[string][]tsm1.Value{string(key): {tsm1.NewValue(2, 3.0)}}}},
+		{Time: created.Add(90 * time.Minute), Entry: &tsm1.DeleteRangeWALEntry{Keys: [][]byte{key}, Min: 1, Max: 1}},
+		{Time: created.Add(3 * time.Hour), Entry: &tsm1.WriteWALEntry{Values: map[string][]tsm1.Value{string(key): {tsm1.NewValue(3, 4.0)}}}},
+	})
+
+	cmd := NewCommand()
+	datadir, waldir := filepath.Join(dir, "data"), filepath.Join(dir, "wal")
+	if err := cmd.Run(
+		"-blob-store", "file://"+filepath.Join(dir, "backup"),
+		"-datadir", datadir,
+		"-waldir", waldir,
+		"-wal-archive", archivedir,
+		"-until", created.Add(2*time.Hour).Format(time.RFC3339Nano),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if out := cmd.Stdout.(*bytes.Buffer).String(); !strings.Contains(out, "Replayed 2 WAL entries of shard "+filepath.Join("db0", "rp0", "1")+" up to 2019-01-01T02:00:00Z") {
+		t.Fatalf("unexpected output: %s", out)
+	}
+
+	entries := MustReadWALSegment(filepath.Join(waldir, "db0", "rp0", "1", "_00001.wal"))
+	if len(entries) != 2 {
+		t.Fatalf("unexpected entry count: %d", len(entries))
+	}
+	if e, ok := entries[0].(*tsm1.WriteWALEntry); !ok {
+		t.Fatalf("unexpected entry: %#v", entries[0])
+	} else if values := e.Values[string(key)]; len(values) != 1 || values[0].UnixNano() != 2 {
+		t.Fatalf("unexpected values: %v", values)
+	}
+	if e, ok := entries[1].(*tsm1.DeleteRangeWALEntry); !ok {
+		t.Fatalf("unexpected entry: %#v", entries[1])
+	} else if e.Min != 1 || e.Max != 1 {
+		t.Fatalf("unexpected delete range: %d-%d", e.Min, e.Max)
+	}
+
+	values := MustReadTSMFile(t, filepath.Join(datadir, "db0", "rp0", "1", "000000001-000000001.tsm"), string(key))
+	if len(values) != 1 || values[0].UnixNano() != 0 {
+		t.Fatalf("unexpected values: %v", values)
+	}
+}
+
+// Ensure -until requires the data, WAL and WAL archive directories.
+func TestRestore_Until_RequiresDirs(t *testing.T) {
+	cmd := NewCommand()
+	err := cmd.Run("-blob-store", "file:///tmp/backup", "-datadir", "/tmp/data", "-until", "2019-01-01T00:00:00Z")
+	if err == nil || err.Error() != "-datadir, -waldir and -wal-archive are required with -until" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// NewCommand returns a restore command writing its output to buffers.
+func NewCommand() *restore.Command {
+	cmd := restore.NewCommand()
+	cmd.Stdout = &bytes.Buffer{}
+	cmd.Stderr = &bytes.Buffer{}
+	return cmd
+}
+
+// MustWriteBlobBackup writes a backup of database db0, with a single TSM file
+// in shard 1 of retention policy rp0, to store and returns its manifest.
+func MustWriteBlobBackup(store backup_util.BlobStore, created time.Time) *backup_util.Manifest {
+	data := &meta.Data{}
+	if err := data.CreateDatabase("db0"); err != nil {
+		panic(err)
+	}
+	metaBytes, err := data.MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+	packed, err := backup_util.PortablePacker{Data: metaBytes}.MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+	metaKey, _, _, err := backup_util.PutContentBlob(store, bytes.NewReader(packed))
+	if err != nil {
+		panic(err)
+	}
+
+	tsm := MustWriteTSMFile(map[string][]tsm1.Value{
+		"cpu,host=A#!~#value": {tsm1.NewValue(0, 1.0), tsm1.NewValue(1, 2.0)},
+	})
+	key, digest, _, err := backup_util.PutContentBlob(store, bytes.NewReader(tsm))
+	if err != nil {
+		panic(err)
+	}
+
+	manifest := &backup_util.Manifest{
+		Version: backup_util.BlobManifestVersion,
+		Created: created.UnixNano(),
+		Meta:    backup_util.MetaEntry{FileName: metaKey, Size: int64(len(packed))},
+		Files: []backup_util.Entry{{
+			Database:     "db0",
+			Policy:       "rp0",
+			ShardID:      1,
+			FileName:     key,
+			Size:         int64(len(tsm)),
+			LastModified: created.UnixNano(),
+			Path:         "000000001-000000001.tsm",
+			Digest:       digest,
+		}},
+	}
+
+	manifestKey, err := backup_util.NextManifestKey(store, created)
+	if err != nil {
+		panic(err)
+	} else if err := backup_util.SaveBlobManifest(store, manifestKey, manifest); err != nil {
+		panic(err)
+	}
+	return manifest
+}
+
+// MustWriteTSMFile returns the content of a TSM file holding values.
+func MustWriteTSMFile(values map[string][]tsm1.Value) []byte {
+	var buf bytes.Buffer
+	w, err := tsm1.NewTSMWriter(&buf)
+	if err != nil {
+		panic(err)
+	}
+	for key, v := range values {
+		if err := w.Write([]byte(key), v); err != nil {
+			panic(err)
+		}
+	}
+	if err := w.WriteIndex(); err != nil {
+		panic(err)
+	} else if err := w.Close(); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+// MustReadTSMFile returns the values of key in the TSM file at path.
+func MustReadTSMFile(t *testing.T, path, key string) []tsm1.Value {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		f.Close()
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	values, err := r.ReadAll([]byte(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return values
+}
+
+// ArchivedEntry is a WAL entry archived at Time.
+type ArchivedEntry struct {
+	Time  time.Time
+	Entry tsm1.WALEntry
+}
+
+// MustWriteArchivedSegment writes entries to an archived WAL segment in dir,
+// along with the times they were written at.
+func MustWriteArchivedSegment(dir string, entries []ArchivedEntry) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		panic(err)
+	}
+
+	segment := filepath.Join(dir, "_00001.wal")
+	f, err := os.Create(segment)
+	if err != nil {
+		panic(err)
+	}
+
+	w := tsm1.NewWALSegmentWriter(f)
+	times := make([]byte, 8*len(entries))
+	for i, e := range entries {
+		b, err := e.Entry.MarshalBinary()
+		if err != nil {
+			panic(err)
+		} else if err := w.Write(e.Entry.Type(), snappy.Encode(nil, b)); err != nil {
+			panic(err)
+		}
+		binary.BigEndian.PutUint64(times[i*8:], uint64(e.Time.UnixNano()))
+	}
+	if err := w.Flush(); err != nil {
+		panic(err)
+	} else if err := f.Close(); err != nil {
+		panic(err)
+	}
+
+	if err := ioutil.WriteFile(segment+"."+tsm1.WALTimesFileExtension, times, 0666); err != nil {
+		panic(err)
+	}
+}
+
+// MustReadWALSegment returns the entries of the WAL segment at path.
+func MustReadWALSegment(path string) []tsm1.WALEntry {
+	f, err := os.Open(path)
+	if err != nil {
+		panic(err)
+	}
+
+	r := tsm1.NewWALSegmentReader(f)
+	defer r.Close()
+
+	var entries []tsm1.WALEntry
+	for r.Next() {
+		entry, err := r.Read()
+		if err != nil {
+			panic(err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// MustTempDir returns a temporary directory.
+func MustTempDir() string {
+	dir, err := ioutil.TempDir("", "influxd-restore-")
+	if err != nil {
+		panic(err)
+	}
+	return dir
+}
@@ -16,8 +16,11 @@ import (
 	"github.com/influxdata/influxdb/monitor"
 	"github.com/influxdata/influxdb/monitor/diagnostics"
 	"github.com/influxdata/influxdb/pkg/tlsconfig"
+	"github.com/influxdata/influxdb/services/audit"
+	"github.com/influxdata/influxdb/services/backup"
 	"github.com/influxdata/influxdb/services/collectd"
 	"github.com/influxdata/influxdb/services/continuous_querier"
+	"github.com/influxdata/influxdb/services/downsample"
 	"github.com/influxdata/influxdb/services/graphite"
 	"github.com/influxdata/influxdb/services/httpd"
 	"github.com/influxdata/influxdb/services/meta"
@@ -39,11 +42,15 @@ const (
 
 // Config represents the configuration format for the influxd binary.
 type Config struct {
-	Meta        *meta.Config       `toml:"meta"`
-	Data        tsdb.Config        `toml:"data"`
-	Coordinator coordinator.Config `toml:"coordinator"`
-	Retention   retention.Config   `toml:"retention"`
-	Precreator  precreator.Config  `toml:"shard-precreation"`
+	Meta        *meta.Config                 `toml:"meta"`
+	Data        tsdb.Config                  `toml:"data"`
+	Coordinator coordinator.Config           `toml:"coordinator"`
+	QueryCache  coordinator.QueryCacheConfig `toml:"query-cache"`
+	Retention   retention.Config             `toml:"retention"`
+	Downsample  downsample.Config            `toml:"downsample"`
+	Precreator  precreator.Config            `toml:"shard-precreation"`
+	Backup      backup.Config                `toml:"backup"`
+	Audit       audit.Config                 `toml:"audit"`
 
 	Monitor        monitor.Config    `toml:"monitor"`
 	Subscriber     subscriber.Config `toml:"subscriber"`
@@ -72,6 +79,7 @@ func NewConfig() *Config {
 	c.Meta = meta.NewConfig()
 	c.Data = tsdb.NewConfig()
 	c.Coordinator = coordinator.NewConfig()
+	c.QueryCache = coordinator.NewQueryCacheConfig()
 	c.Precreator = precreator.NewConfig()
 
 	c.Monitor = monitor.NewConfig()
@@ -86,6 +94,9 @@ func NewConfig() *Config {
 
 	c.ContinuousQuery = continuous_querier.NewConfig()
 	c.Retention = retention.NewConfig()
+	c.Downsample = downsample.NewConfig()
+	c.Backup = backup.NewConfig()
+	c.Audit = audit.NewConfig()
 	c.BindAddress = DefaultBindAddress
 
 	return c
@@ -169,10 +180,30 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	if err := c.Coordinator.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.QueryCache.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Downsample.Validate(); err != nil {
+		return err
+	}
+
 	if err := c.Precreator.Validate(); err != nil {
 		return err
 	}
 
+	if err := c.Backup.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Audit.Validate(); err != nil {
+		return err
+	}
+
 	if err := c.Subscriber.Validate(); err != nil {
 		return err
 	}
@@ -217,8 +248,12 @@ func (c *Config) diagnosticsClients() map[string]diagnostics.Client {
 		"config-data":        c.Data,
 		"config-meta":        c.Meta,
 		"config-coordinator": c.Coordinator,
+		"config-query-cache": c.QueryCache,
 		"config-retention":   c.Retention,
+		"config-downsample":  c.Downsample,
 		"config-precreator":  c.Precreator,
+		"config-backup":      c.Backup,
+		"config-audit":       c.Audit,
 
 		"config-monitor":    c.Monitor,
 		"config-subscriber": c.Subscriber,
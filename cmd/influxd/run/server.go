@@ -19,8 +19,11 @@ import (
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/monitor"
 	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/services/audit"
+	"github.com/influxdata/influxdb/services/backup"
 	"github.com/influxdata/influxdb/services/collectd"
 	"github.com/influxdata/influxdb/services/continuous_querier"
+	"github.com/influxdata/influxdb/services/downsample"
 	"github.com/influxdata/influxdb/services/graphite"
 	"github.com/influxdata/influxdb/services/httpd"
 	"github.com/influxdata/influxdb/services/meta"
@@ -75,6 +78,8 @@ type Server struct {
 
 	TSDBStore     *tsdb.Store
 	QueryExecutor *query.Executor
+	QueryCache    *coordinator.QueryCache
+	slowQueryLog  *os.File
 	PointsWriter  *coordinator.PointsWriter
 	Subscriber    *subscriber.Service
 
@@ -82,6 +87,7 @@ type Server struct {
 
 	// These references are required for the tcp muxer.
 	SnapshotterService *snapshotter.Service
+	AuditService       *audit.Service
 
 	Monitor *monitor.Monitor
 
@@ -195,12 +201,20 @@ func NewServer(c *Config, buildInfo *BuildInfo) (*Server, error) {
 	s.TSDBStore.EngineOptions.EngineVersion = c.Data.Engine
 	s.TSDBStore.EngineOptions.IndexVersion = c.Data.Index
 
+	// Cache the results of queries, invalidated by writes and deletes.
+	if c.QueryCache.Enabled {
+		s.QueryCache = coordinator.NewQueryCache(int64(c.QueryCache.MaxMemorySize), c.QueryCache.MaxEntries)
+		s.TSDBStore.EngineOptions.ShardObserver = s.QueryCache
+	}
+
 	// Create the Subscriber service
 	s.Subscriber = subscriber.NewService(c.Subscriber)
 
 	// Initialize points writer.
 	s.PointsWriter = coordinator.NewPointsWriter()
 	s.PointsWriter.WriteTimeout = time.Duration(c.Coordinator.WriteTimeout)
+	s.PointsWriter.Limiter = coordinator.NewWriteLimiter(c.Coordinator.UserWriteLimits(), c.Coordinator.DatabaseWriteLimits())
+	s.PointsWriter.Limiter.Databases = c.Coordinator.WriteLimitOverrides()
 	s.PointsWriter.TSDBStore = s.TSDBStore
 
 	// Initialize query executor.
@@ -215,13 +229,23 @@ func NewServer(c *Config, buildInfo *BuildInfo) (*Server, error) {
 		},
 		Monitor:           s.Monitor,
 		PointsWriter:      s.PointsWriter,
+		QueryCache:        s.QueryCache,
 		MaxSelectPointN:   c.Coordinator.MaxSelectPointN,
 		MaxSelectSeriesN:  c.Coordinator.MaxSelectSeriesN,
 		MaxSelectBucketsN: c.Coordinator.MaxSelectBucketsN,
+		MaxFillLookback:   time.Duration(c.Coordinator.MaxFillLookback),
 	}
 	s.QueryExecutor.TaskManager.QueryTimeout = time.Duration(c.Coordinator.QueryTimeout)
 	s.QueryExecutor.TaskManager.LogQueriesAfter = time.Duration(c.Coordinator.LogQueriesAfter)
+	s.QueryExecutor.TaskManager.ProfileQueriesAfter = time.Duration(c.Coordinator.ProfileQueriesAfter)
 	s.QueryExecutor.TaskManager.MaxConcurrentQueries = c.Coordinator.MaxConcurrentQueries
+	s.QueryExecutor.TaskManager.Quotas = query.NewQuotas()
+	s.QueryExecutor.TaskManager.Quotas.UserLimits = c.Coordinator.UserQuotaLimits()
+	s.QueryExecutor.TaskManager.Quotas.DatabaseLimits = c.Coordinator.DatabaseQuotaLimits()
+	s.QueryExecutor.TaskManager.Quotas.Overrides = s.MetaClient
+	if c.Coordinator.MaxQueryFingerprints > 0 {
+		s.QueryExecutor.TaskManager.QueryStats = query.NewQueryStats(c.Coordinator.MaxQueryFingerprints)
+	}
 
 	// Initialize the monitor
 	s.Monitor.Version = s.buildInfo.Version
@@ -239,6 +263,9 @@ func (s *Server) Statistics(tags map[string]string) []models.Statistic {
 	statistics = append(statistics, s.TSDBStore.Statistics(tags)...)
 	statistics = append(statistics, s.PointsWriter.Statistics(tags)...)
 	statistics = append(statistics, s.Subscriber.Statistics(tags)...)
+	if s.QueryCache != nil {
+		statistics = append(statistics, s.QueryCache.Statistics(tags)...)
+	}
 	for _, srv := range s.Services {
 		if m, ok := srv.(monitor.Reporter); ok {
 			statistics = append(statistics, m.Statistics(tags)...)
@@ -255,6 +282,32 @@ func (s *Server) appendSnapshotterService() {
 	s.SnapshotterService = srv
 }
 
+func (s *Server) appendBackupService(c backup.Config) {
+	if !c.Enabled {
+		return
+	}
+	srv := backup.NewService(c)
+	srv.MetaClient = s.MetaClient
+	srv.TSDBStore = s.TSDBStore
+	s.Services = append(s.Services, srv)
+	s.SnapshotterService.BackupService = srv
+}
+
+func (s *Server) appendAuditService(c audit.Config) {
+	if !c.Enabled {
+		return
+	}
+	srv := audit.NewService(c)
+	srv.MetaClient = s.MetaClient
+	srv.PointsWriter = (*monitorPointsWriter)(s.PointsWriter)
+	s.Services = append(s.Services, srv)
+	s.AuditService = srv
+
+	if e, ok := s.QueryExecutor.StatementExecutor.(*coordinator.StatementExecutor); ok {
+		e.Auditor = srv
+	}
+}
+
 // SetLogOutput sets the logger used for all messages. It must not be called
 // after the Open method has been called.
 func (s *Server) SetLogOutput(w io.Writer) {
@@ -272,6 +325,20 @@ func (s *Server) appendRetentionPolicyService(c retention.Config) {
 	srv := retention.NewService(c)
 	srv.MetaClient = s.MetaClient
 	srv.TSDBStore = s.TSDBStore
+	if s.config.Data.ColdDir != "" {
+		srv.ColdShardAge = time.Duration(s.config.Data.ColdShardAge)
+	}
+	s.Services = append(s.Services, srv)
+}
+
+func (s *Server) appendDownsampleService(c downsample.Config) {
+	if !c.Enabled {
+		return
+	}
+	srv := downsample.NewService(c)
+	srv.MetaClient = s.MetaClient
+	srv.TSDBStore = s.TSDBStore
+	srv.QueryExecutor = s.QueryExecutor
 	s.Services = append(s.Services, srv)
 }
 
@@ -285,6 +352,9 @@ func (s *Server) appendHTTPDService(c httpd.Config) {
 	srv.Handler.QueryAuthorizer = authorizer
 	srv.Handler.WriteAuthorizer = meta.NewWriteAuthorizer(s.MetaClient)
 	srv.Handler.QueryExecutor = s.QueryExecutor
+	if s.AuditService != nil {
+		srv.Handler.Auditor = s.AuditService
+	}
 	srv.Handler.Monitor = s.Monitor
 	srv.Handler.PointsWriter = s.PointsWriter
 	srv.Handler.Version = s.buildInfo.Version
@@ -390,9 +460,12 @@ func (s *Server) Open() error {
 	s.appendMonitorService()
 	s.appendPrecreatorService(s.config.Precreator)
 	s.appendSnapshotterService()
+	s.appendBackupService(s.config.Backup)
+	s.appendAuditService(s.config.Audit)
 	s.appendContinuousQueryService(s.config.ContinuousQuery)
 	s.appendHTTPDService(s.config.HTTPD)
 	s.appendRetentionPolicyService(s.config.Retention)
+	s.appendDownsampleService(s.config.Downsample)
 	for _, i := range s.config.GraphiteInputs {
 		if err := s.appendGraphiteService(i); err != nil {
 			return err
@@ -424,6 +497,14 @@ func (s *Server) Open() error {
 	if s.config.Data.QueryLogEnabled {
 		s.QueryExecutor.WithLogger(s.Logger)
 	}
+	if path := s.config.Coordinator.SlowQueryLogPath; path != "" {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+		if err != nil {
+			return fmt.Errorf("open slow query log: %s", err)
+		}
+		s.slowQueryLog = f
+		s.QueryExecutor.TaskManager.SlowQueryLogger = logger.New(f)
+	}
 	s.PointsWriter.WithLogger(s.Logger)
 	s.Subscriber.WithLogger(s.Logger)
 	for _, svc := range s.Services {
@@ -488,6 +569,10 @@ func (s *Server) Close() error {
 		s.QueryExecutor.Close()
 	}
 
+	if s.slowQueryLog != nil {
+		s.slowQueryLog.Close()
+	}
+
 	// Close the TSDBStore, no more reads or writes at this point
 	if s.TSDBStore != nil {
 		s.TSDBStore.Close()
@@ -629,7 +714,7 @@ func stopProfile() {
 type monitorPointsWriter coordinator.PointsWriter
 
 func (pw *monitorPointsWriter) WritePoints(database, retentionPolicy string, points models.Points) error {
-	return (*coordinator.PointsWriter)(pw).WritePointsPrivileged(database, retentionPolicy, models.ConsistencyLevelAny, points)
+	return (*coordinator.PointsWriter)(pw).WritePointsInternal(database, retentionPolicy, models.ConsistencyLevelAny, points)
 }
 
 func raftDBExists(dir string) error {
@@ -3,6 +3,8 @@
 package coordinator
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/influxdata/influxdb/monitor/diagnostics"
@@ -25,6 +27,19 @@ const (
 	// DefaultMaxSelectSeriesN is the maximum number of series a SELECT can run.
 	// A value of zero will make the maximum series count unlimited.
 	DefaultMaxSelectSeriesN = 0
+
+	// DefaultMaxFillLookback is how far outside of the time range of a SELECT
+	// to look for the nearest points to fill the windows at its edges.
+	// A value of zero only fills between points within the time range.
+	DefaultMaxFillLookback = 0
+
+	// DefaultQueryCacheMaxMemorySize is the default maximum size of the
+	// results held by the query cache.
+	DefaultQueryCacheMaxMemorySize = 64 * 1024 * 1024
+
+	// DefaultQueryCacheMaxEntries is the default maximum number of statements
+	// held by the query cache.
+	DefaultQueryCacheMaxEntries = 1000
 )
 
 // Config represents the configuration for the coordinator service.
@@ -33,9 +48,41 @@ type Config struct {
 	MaxConcurrentQueries int           `toml:"max-concurrent-queries"`
 	QueryTimeout         toml.Duration `toml:"query-timeout"`
 	LogQueriesAfter      toml.Duration `toml:"log-queries-after"`
+	ProfileQueriesAfter  toml.Duration `toml:"profile-queries-after"`
+	SlowQueryLogPath     string        `toml:"slow-query-log-path"`
+	MaxQueryFingerprints int           `toml:"max-query-fingerprints"`
 	MaxSelectPointN      int           `toml:"max-select-point"`
 	MaxSelectSeriesN     int           `toml:"max-select-series"`
 	MaxSelectBucketsN    int           `toml:"max-select-buckets"`
+	MaxFillLookback      toml.Duration `toml:"max-fill-lookback"`
+
+	// Default query quotas of each user and database. Quotas set in the meta
+	// store override them.
+	UserMaxConcurrentQueries     int       `toml:"user-max-concurrent-queries"`
+	UserMaxQueriesPerMinute      int       `toml:"user-max-queries-per-minute"`
+	UserMaxPointsScanned         int64     `toml:"user-max-points-scanned"`
+	UserMaxBytesReturned         toml.Size `toml:"user-max-bytes-returned"`
+	DatabaseMaxConcurrentQueries int       `toml:"database-max-concurrent-queries"`
+	DatabaseMaxQueriesPerMinute  int       `toml:"database-max-queries-per-minute"`
+	DatabaseMaxPointsScanned     int64     `toml:"database-max-points-scanned"`
+	DatabaseMaxBytesReturned     toml.Size `toml:"database-max-bytes-returned"`
+
+	// Write rate limits of each user and database.
+	UserWritePointsPerSecond     int64     `toml:"user-write-points-per-second"`
+	UserWriteBytesPerSecond      toml.Size `toml:"user-write-bytes-per-second"`
+	DatabaseWritePointsPerSecond int64     `toml:"database-write-points-per-second"`
+	DatabaseWriteBytesPerSecond  toml.Size `toml:"database-write-bytes-per-second"`
+
+	// Write rate limits of individual databases replacing the default
+	// database limits.
+	DatabaseWriteLimitOverrides []DatabaseWriteLimitConfig `toml:"database-write-limit"`
+}
+
+// DatabaseWriteLimitConfig represents the write rate limits of a database.
+type DatabaseWriteLimitConfig struct {
+	Database        string    `toml:"database"`
+	PointsPerSecond int64     `toml:"points-per-second"`
+	BytesPerSecond  toml.Size `toml:"bytes-per-second"`
 }
 
 // NewConfig returns an instance of Config with defaults.
@@ -46,7 +93,77 @@ func NewConfig() Config {
 		MaxConcurrentQueries: DefaultMaxConcurrentQueries,
 		MaxSelectPointN:      DefaultMaxSelectPointN,
 		MaxSelectSeriesN:     DefaultMaxSelectSeriesN,
+		MaxFillLookback:      toml.Duration(DefaultMaxFillLookback),
+		MaxQueryFingerprints: query.DefaultMaxQueryFingerprints,
+	}
+}
+
+// UserQuotaLimits returns the default query quota of each user.
+func (c Config) UserQuotaLimits() query.QuotaLimits {
+	return query.QuotaLimits{
+		MaxConcurrentQueries: c.UserMaxConcurrentQueries,
+		MaxQueriesPerMinute:  c.UserMaxQueriesPerMinute,
+		MaxPointsScanned:     c.UserMaxPointsScanned,
+		MaxBytesReturned:     int64(c.UserMaxBytesReturned),
+	}
+}
+
+// DatabaseQuotaLimits returns the default query quota of each database.
+func (c Config) DatabaseQuotaLimits() query.QuotaLimits {
+	return query.QuotaLimits{
+		MaxConcurrentQueries: c.DatabaseMaxConcurrentQueries,
+		MaxQueriesPerMinute:  c.DatabaseMaxQueriesPerMinute,
+		MaxPointsScanned:     c.DatabaseMaxPointsScanned,
+		MaxBytesReturned:     int64(c.DatabaseMaxBytesReturned),
+	}
+}
+
+// UserWriteLimits returns the write rate limits of each user.
+func (c Config) UserWriteLimits() WriteLimits {
+	return WriteLimits{
+		PointsPerSecond: c.UserWritePointsPerSecond,
+		BytesPerSecond:  int64(c.UserWriteBytesPerSecond),
+	}
+}
+
+// DatabaseWriteLimits returns the write rate limits of each database.
+func (c Config) DatabaseWriteLimits() WriteLimits {
+	return WriteLimits{
+		PointsPerSecond: c.DatabaseWritePointsPerSecond,
+		BytesPerSecond:  int64(c.DatabaseWriteBytesPerSecond),
+	}
+}
+
+// WriteLimitOverrides returns the write rate limits of individual databases,
+// keyed by database.
+func (c Config) WriteLimitOverrides() map[string]WriteLimits {
+	if len(c.DatabaseWriteLimitOverrides) == 0 {
+		return nil
 	}
+	limits := make(map[string]WriteLimits, len(c.DatabaseWriteLimitOverrides))
+	for _, l := range c.DatabaseWriteLimitOverrides {
+		limits[l.Database] = WriteLimits{
+			PointsPerSecond: l.PointsPerSecond,
+			BytesPerSecond:  int64(l.BytesPerSecond),
+		}
+	}
+	return limits
+}
+
+// Validate returns an error if the config is invalid.
+func (c Config) Validate() error {
+	databases := make(map[string]struct{}, len(c.DatabaseWriteLimitOverrides))
+	for _, l := range c.DatabaseWriteLimitOverrides {
+		if l.Database == "" {
+			return errors.New("database-write-limit database must not be empty")
+		} else if _, ok := databases[l.Database]; ok {
+			return fmt.Errorf("duplicate database-write-limit for database %q", l.Database)
+		} else if l.PointsPerSecond < 0 || l.BytesPerSecond < 0 {
+			return fmt.Errorf("database-write-limit of database %q must not be negative", l.Database)
+		}
+		databases[l.Database] = struct{}{}
+	}
+	return nil
 }
 
 // Diagnostics returns a diagnostics representation of a subset of the Config.
@@ -56,8 +173,71 @@ func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
 		"max-concurrent-queries": c.MaxConcurrentQueries,
 		"query-timeout":          c.QueryTimeout,
 		"log-queries-after":      c.LogQueriesAfter,
+		"profile-queries-after":  c.ProfileQueriesAfter,
+		"slow-query-log-path":    c.SlowQueryLogPath,
+		"max-query-fingerprints": c.MaxQueryFingerprints,
 		"max-select-point":       c.MaxSelectPointN,
 		"max-select-series":      c.MaxSelectSeriesN,
 		"max-select-buckets":     c.MaxSelectBucketsN,
+		"max-fill-lookback":      c.MaxFillLookback,
+
+		"user-max-concurrent-queries":     c.UserMaxConcurrentQueries,
+		"user-max-queries-per-minute":     c.UserMaxQueriesPerMinute,
+		"user-max-points-scanned":         c.UserMaxPointsScanned,
+		"user-max-bytes-returned":         c.UserMaxBytesReturned,
+		"database-max-concurrent-queries": c.DatabaseMaxConcurrentQueries,
+		"database-max-queries-per-minute": c.DatabaseMaxQueriesPerMinute,
+		"database-max-points-scanned":     c.DatabaseMaxPointsScanned,
+		"database-max-bytes-returned":     c.DatabaseMaxBytesReturned,
+
+		"user-write-points-per-second":     c.UserWritePointsPerSecond,
+		"user-write-bytes-per-second":      c.UserWriteBytesPerSecond,
+		"database-write-points-per-second": c.DatabaseWritePointsPerSecond,
+		"database-write-bytes-per-second":  c.DatabaseWriteBytesPerSecond,
+		"database-write-limits":            len(c.DatabaseWriteLimitOverrides),
+	}), nil
+}
+
+// QueryCacheConfig represents the configuration for the query result cache.
+type QueryCacheConfig struct {
+	Enabled       bool      `toml:"enabled"`
+	MaxMemorySize toml.Size `toml:"max-memory-size"`
+	MaxEntries    int       `toml:"max-entries"`
+}
+
+// NewQueryCacheConfig returns an instance of QueryCacheConfig with defaults.
+func NewQueryCacheConfig() QueryCacheConfig {
+	return QueryCacheConfig{
+		MaxMemorySize: DefaultQueryCacheMaxMemorySize,
+		MaxEntries:    DefaultQueryCacheMaxEntries,
+	}
+}
+
+// Validate returns an error if the config is invalid.
+func (c QueryCacheConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.MaxMemorySize <= 0 {
+		return errors.New("query-cache max-memory-size must be positive")
+	}
+	if c.MaxEntries < 0 {
+		return errors.New("query-cache max-entries must be non-negative")
+	}
+	return nil
+}
+
+// Diagnostics returns a diagnostics representation of a subset of the Config.
+func (c QueryCacheConfig) Diagnostics() (*diagnostics.Diagnostics, error) {
+	if !c.Enabled {
+		return diagnostics.RowFromMap(map[string]interface{}{
+			"enabled": false,
+		}), nil
+	}
+
+	return diagnostics.RowFromMap(map[string]interface{}{
+		"enabled":         true,
+		"max-memory-size": c.MaxMemorySize,
+		"max-entries":     c.MaxEntries,
 	}), nil
 }
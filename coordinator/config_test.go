@@ -22,3 +22,31 @@ write-timeout = "20s"
 		t.Fatalf("unexpected write timeout s: %s", c.WriteTimeout)
 	}
 }
+
+func TestConfig_Parse_DatabaseWriteLimits(t *testing.T) {
+	var c coordinator.Config
+	if _, err := toml.Decode(`
+database-write-points-per-second = 100
+
+[[database-write-limit]]
+database = "db0"
+points-per-second = 10
+bytes-per-second = "1k"
+`, &c); err != nil {
+		t.Fatal(err)
+	} else if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	limits := c.WriteLimitOverrides()
+	if got, exp := limits["db0"], (coordinator.WriteLimits{PointsPerSecond: 10, BytesPerSecond: 1024}); got != exp {
+		t.Fatalf("unexpected limits: got %+v, exp %+v", got, exp)
+	} else if len(limits) != 1 {
+		t.Fatalf("unexpected number of limits: %d", len(limits))
+	}
+
+	c.DatabaseWriteLimitOverrides = append(c.DatabaseWriteLimitOverrides, coordinator.DatabaseWriteLimitConfig{Database: "db0"})
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for duplicate database")
+	}
+}
@@ -12,21 +12,41 @@ type MetaClient interface {
 	CreateContinuousQuery(database, name, query string) error
 	CreateDatabase(name string) (*meta.DatabaseInfo, error)
 	CreateDatabaseWithRetentionPolicy(name string, spec *meta.RetentionPolicySpec) (*meta.DatabaseInfo, error)
+	CreateDownsample(database, policy string, ds *meta.DownsampleInfo) error
 	CreateRetentionPolicy(database string, spec *meta.RetentionPolicySpec, makeDefault bool) (*meta.RetentionPolicyInfo, error)
+	CreateRetentionRule(database, policy string, rule *meta.RetentionRuleInfo) error
+	CreateRole(name string) error
 	CreateSubscription(database, rp, name, mode string, destinations []string) error
+	CreateToken(name, username string) (string, error)
 	CreateUser(name, password string, admin bool) (meta.User, error)
 	Database(name string) *meta.DatabaseInfo
 	Databases() []meta.DatabaseInfo
 	DropShard(id uint64) error
 	DropContinuousQuery(database, name string) error
 	DropDatabase(name string) error
+	DropDownsample(database, policy, name string) error
+	DropQuota(kind, name string) error
 	DropRetentionPolicy(database, name string) error
+	DropRetentionRule(database, policy, name string) error
+	DropRole(name string) error
 	DropSubscription(database, rp, name string) error
+	DropToken(name string) error
 	DropUser(name string) error
+	GrantRole(role, username string) error
+	GrantRoleMeasurement(role string, gi *meta.GrantInfo) error
+	GrantUserMeasurement(username string, gi *meta.GrantInfo) error
 	RetentionPolicy(database, name string) (rpi *meta.RetentionPolicyInfo, err error)
+	RevokeRole(role, username string) error
+	RevokeRoleMeasurement(role string, gi *meta.GrantInfo) error
+	RevokeUserMeasurement(username string, gi *meta.GrantInfo) error
+	Role(name string) (*meta.RoleInfo, error)
+	Quotas() []meta.QuotaInfo
+	Roles() []meta.RoleInfo
 	SetAdminPrivilege(username string, admin bool) error
+	SetQuota(qi meta.QuotaInfo) error
 	SetPrivilege(username, database string, p influxql.Privilege) error
 	ShardGroupsByTimeRange(database, policy string, min, max time.Time) (a []meta.ShardGroupInfo, err error)
+	Tokens() []meta.TokenInfo
 	TruncateShardGroups(t time.Time) error
 	UpdateRetentionPolicy(database, name string, rpu *meta.RetentionPolicyUpdate, makeDefault bool) error
 	UpdateUser(name, password string) error
@@ -12,8 +12,12 @@ type MetaClient struct {
 	CreateContinuousQueryFn             func(database, name, query string) error
 	CreateDatabaseFn                    func(name string) (*meta.DatabaseInfo, error)
 	CreateDatabaseWithRetentionPolicyFn func(name string, spec *meta.RetentionPolicySpec) (*meta.DatabaseInfo, error)
+	CreateDownsampleFn                  func(database, policy string, ds *meta.DownsampleInfo) error
 	CreateRetentionPolicyFn             func(database string, spec *meta.RetentionPolicySpec, makeDefault bool) (*meta.RetentionPolicyInfo, error)
+	CreateRetentionRuleFn               func(database, policy string, rule *meta.RetentionRuleInfo) error
+	CreateRoleFn                        func(name string) error
 	CreateSubscriptionFn                func(database, rp, name, mode string, destinations []string) error
+	CreateTokenFn                       func(name, username string) (string, error)
 	CreateUserFn                        func(name, password string, admin bool) (meta.User, error)
 	DatabaseFn                          func(name string) *meta.DatabaseInfo
 	DatabasesFn                         func() []meta.DatabaseInfo
@@ -23,15 +27,31 @@ type MetaClient struct {
 	DeleteMetaNodeFn                    func(id uint64) error
 	DropContinuousQueryFn               func(database, name string) error
 	DropDatabaseFn                      func(name string) error
+	DropDownsampleFn                    func(database, policy, name string) error
+	DropQuotaFn                         func(kind, name string) error
 	DropRetentionPolicyFn               func(database, name string) error
+	DropRetentionRuleFn                 func(database, policy, name string) error
+	DropRoleFn                          func(name string) error
 	DropSubscriptionFn                  func(database, rp, name string) error
 	DropShardFn                         func(id uint64) error
+	DropTokenFn                         func(name string) error
 	DropUserFn                          func(name string) error
+	GrantRoleFn                         func(role, username string) error
+	GrantRoleMeasurementFn              func(role string, gi *meta.GrantInfo) error
+	GrantUserMeasurementFn              func(username string, gi *meta.GrantInfo) error
 	MetaNodesFn                         func() ([]meta.NodeInfo, error)
+	QuotasFn                            func() []meta.QuotaInfo
 	RetentionPolicyFn                   func(database, name string) (rpi *meta.RetentionPolicyInfo, err error)
+	RevokeRoleFn                        func(role, username string) error
+	RevokeRoleMeasurementFn             func(role string, gi *meta.GrantInfo) error
+	RevokeUserMeasurementFn             func(username string, gi *meta.GrantInfo) error
+	RoleFn                              func(name string) (*meta.RoleInfo, error)
+	RolesFn                             func() []meta.RoleInfo
 	SetAdminPrivilegeFn                 func(username string, admin bool) error
 	SetPrivilegeFn                      func(username, database string, p influxql.Privilege) error
+	SetQuotaFn                          func(qi meta.QuotaInfo) error
 	ShardGroupsByTimeRangeFn            func(database, policy string, min, max time.Time) (a []meta.ShardGroupInfo, err error)
+	TokensFn                            func() []meta.TokenInfo
 	TruncateShardGroupsFn               func(t time.Time) error
 	UpdateRetentionPolicyFn             func(database, name string, rpu *meta.RetentionPolicyUpdate, makeDefault bool) error
 	UpdateUserFn                        func(name, password string) error
@@ -56,6 +76,14 @@ func (c *MetaClient) CreateRetentionPolicy(database string, spec *meta.Retention
 	return c.CreateRetentionPolicyFn(database, spec, makeDefault)
 }
 
+func (c *MetaClient) CreateDownsample(database, policy string, ds *meta.DownsampleInfo) error {
+	return c.CreateDownsampleFn(database, policy, ds)
+}
+
+func (c *MetaClient) CreateRetentionRule(database, policy string, rule *meta.RetentionRuleInfo) error {
+	return c.CreateRetentionRuleFn(database, policy, rule)
+}
+
 func (c *MetaClient) DropShard(id uint64) error {
 	return c.DropShardFn(id)
 }
@@ -104,6 +132,18 @@ func (c *MetaClient) DropRetentionPolicy(database, name string) error {
 	return c.DropRetentionPolicyFn(database, name)
 }
 
+func (c *MetaClient) DropDownsample(database, policy, name string) error {
+	return c.DropDownsampleFn(database, policy, name)
+}
+
+func (c *MetaClient) DropQuota(kind, name string) error {
+	return c.DropQuotaFn(kind, name)
+}
+
+func (c *MetaClient) DropRetentionRule(database, policy, name string) error {
+	return c.DropRetentionRuleFn(database, policy, name)
+}
+
 func (c *MetaClient) DropSubscription(database, rp, name string) error {
 	return c.DropSubscriptionFn(database, rp, name)
 }
@@ -164,3 +204,63 @@ func DefaultMetaClientDatabaseFn(name string) *meta.DatabaseInfo {
 		DefaultRetentionPolicy: DefaultRetentionPolicy,
 	}
 }
+
+func (c *MetaClient) CreateRole(name string) error {
+	return c.CreateRoleFn(name)
+}
+
+func (c *MetaClient) CreateToken(name, username string) (string, error) {
+	return c.CreateTokenFn(name, username)
+}
+
+func (c *MetaClient) DropRole(name string) error {
+	return c.DropRoleFn(name)
+}
+
+func (c *MetaClient) DropToken(name string) error {
+	return c.DropTokenFn(name)
+}
+
+func (c *MetaClient) GrantRole(role, username string) error {
+	return c.GrantRoleFn(role, username)
+}
+
+func (c *MetaClient) GrantRoleMeasurement(role string, gi *meta.GrantInfo) error {
+	return c.GrantRoleMeasurementFn(role, gi)
+}
+
+func (c *MetaClient) GrantUserMeasurement(username string, gi *meta.GrantInfo) error {
+	return c.GrantUserMeasurementFn(username, gi)
+}
+
+func (c *MetaClient) RevokeRole(role, username string) error {
+	return c.RevokeRoleFn(role, username)
+}
+
+func (c *MetaClient) RevokeRoleMeasurement(role string, gi *meta.GrantInfo) error {
+	return c.RevokeRoleMeasurementFn(role, gi)
+}
+
+func (c *MetaClient) RevokeUserMeasurement(username string, gi *meta.GrantInfo) error {
+	return c.RevokeUserMeasurementFn(username, gi)
+}
+
+func (c *MetaClient) Role(name string) (*meta.RoleInfo, error) {
+	return c.RoleFn(name)
+}
+
+func (c *MetaClient) Roles() []meta.RoleInfo {
+	return c.RolesFn()
+}
+
+func (c *MetaClient) Tokens() []meta.TokenInfo {
+	return c.TokensFn()
+}
+
+func (c *MetaClient) Quotas() []meta.QuotaInfo {
+	return c.QuotasFn()
+}
+
+func (c *MetaClient) SetQuota(qi meta.QuotaInfo) error {
+	return c.SetQuotaFn(qi)
+}
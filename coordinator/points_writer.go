@@ -12,6 +12,7 @@ import (
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/services/meta"
 	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxql"
 	"go.uber.org/zap"
 )
 
@@ -26,6 +27,8 @@ const (
 	statWriteErr           = "writeError"
 	statSubWriteOK         = "subWriteOk"
 	statSubWriteDrop       = "subWriteDrop"
+	statWriteRateLimited   = "writeRateLimited"
+	statPointRateLimited   = "pointRateLimited"
 )
 
 var (
@@ -58,8 +61,12 @@ type PointsWriter struct {
 	TSDBStore interface {
 		CreateShard(database, retentionPolicy string, shardID uint64, enabled bool) error
 		WriteToShard(shardID uint64, points []models.Point) error
+		WriteToShardBulk(shardID uint64, points []models.Point) error
 	}
 
+	// Limiter limits the rate of writes of each user and database. Optional.
+	Limiter *WriteLimiter
+
 	subPoints []chan<- *WritePointsRequest
 
 	stats *WriteStatistics
@@ -163,6 +170,8 @@ type WriteStatistics struct {
 	WriteErr           int64
 	SubWriteOK         int64
 	SubWriteDrop       int64
+	WriteRateLimited   int64
+	PointRateLimited   int64
 }
 
 // Statistics returns statistics for periodic monitoring.
@@ -180,6 +189,8 @@ func (w *PointsWriter) Statistics(tags map[string]string) []models.Statistic {
 			statWriteErr:           atomic.LoadInt64(&w.stats.WriteErr),
 			statSubWriteOK:         atomic.LoadInt64(&w.stats.SubWriteOK),
 			statSubWriteDrop:       atomic.LoadInt64(&w.stats.SubWriteDrop),
+			statWriteRateLimited:   atomic.LoadInt64(&w.stats.WriteRateLimited),
+			statPointRateLimited:   atomic.LoadInt64(&w.stats.PointRateLimited),
 		},
 	}}
 }
@@ -287,16 +298,102 @@ func (l sgList) Append(sgi meta.ShardGroupInfo) sgList {
 // WritePointsInto is a copy of WritePoints that uses a tsdb structure instead of
 // a cluster structure for information. This is to avoid a circular dependency.
 func (w *PointsWriter) WritePointsInto(p *IntoWriteRequest) error {
-	return w.WritePointsPrivileged(p.Database, p.RetentionPolicy, models.ConsistencyLevelOne, p.Points)
+	return w.WritePointsInternal(p.Database, p.RetentionPolicy, models.ConsistencyLevelOne, p.Points)
 }
 
-// WritePoints writes the data to the underlying storage. consitencyLevel and user are only used for clustered scenarios
+// WritePoints writes the data to the underlying storage. consitencyLevel is only used for clustered scenarios.
+// No point is written unless user, if set, is authorized to write every one of them.
 func (w *PointsWriter) WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error {
-	return w.WritePointsPrivileged(database, retentionPolicy, consistencyLevel, points)
+	return w.writePointsAs(database, retentionPolicy, consistencyLevel, user, points, false)
+}
+
+// WritePointsBulk writes the data like WritePoints, except that the points are bulk loaded
+// by their shards.  The points are neither queryable nor durable until the bulk load of
+// their shard is committed.
+func (w *PointsWriter) WritePointsBulk(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error {
+	return w.writePointsAs(database, retentionPolicy, consistencyLevel, user, points, true)
+}
+
+// writePointsAs checks that user is authorized and within its rate limits to write the data
+// before writing it.
+func (w *PointsWriter) writePointsAs(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point, bulk bool) error {
+	var userID string
+	if user != nil {
+		userID = user.ID()
+	}
+
+	if user != nil && !user.AuthorizeUnrestricted() {
+		if retentionPolicy == "" {
+			db := w.MetaClient.Database(database)
+			if db == nil {
+				return influxdb.ErrDatabaseNotFound(database)
+			}
+			retentionPolicy = db.DefaultRetentionPolicy
+		}
+
+		if err := authorizeWrite(user, database, retentionPolicy, points); err != nil {
+			return err
+		}
+	}
+
+	if err := w.limit(userID, database, points); err != nil {
+		return err
+	}
+	return w.writePoints(database, retentionPolicy, consistencyLevel, points, bulk)
+}
+
+// limit returns an influxdb.RateLimitError if writing points exceeds the rate
+// limit of user or database.
+func (w *PointsWriter) limit(user, database string, points []models.Point) error {
+	if w.Limiter == nil {
+		return nil
+	}
+	if err := w.Limiter.Limit(user, database, points); err != nil {
+		atomic.AddInt64(&w.stats.WriteRateLimited, 1)
+		atomic.AddInt64(&w.stats.PointRateLimited, int64(len(points)))
+		return err
+	}
+	return nil
+}
+
+// authorizeWrite returns an error if user isn't authorized to write one of points.
+func authorizeWrite(user meta.User, database, retentionPolicy string, points []models.Point) error {
+	authorized := make(map[string]struct{})
+	for _, p := range points {
+		name := p.Name()
+		if _, ok := authorized[string(name)]; ok {
+			continue
+		}
+
+		if !user.AuthorizeMeasurement(influxql.WritePrivilege, database, retentionPolicy, name) {
+			return &meta.ErrAuthorize{
+				Database: database,
+				Message:  fmt.Sprintf("%s not authorized to write to measurement %q of %s.%s", user.ID(), name, database, retentionPolicy),
+			}
+		}
+		authorized[string(name)] = struct{}{}
+	}
+	return nil
 }
 
 // WritePointsPrivileged writes the data to the underlying storage, consitencyLevel is only used for clustered scenarios
+// The points are ingested from an external source, so they are limited by the rate limits of the database.
 func (w *PointsWriter) WritePointsPrivileged(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	if err := w.limit("", database, points); err != nil {
+		return err
+	}
+	return w.writePoints(database, retentionPolicy, consistencyLevel, points, false)
+}
+
+// WritePointsInternal writes points generated by the server itself, such as statistics, audit events and the
+// results of SELECT INTO statements. They aren't limited by the rate limits of the database.
+func (w *PointsWriter) WritePointsInternal(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	return w.writePoints(database, retentionPolicy, consistencyLevel, points, false)
+}
+
+// writePoints writes the data to the underlying storage without checking rate limits.
+// The points are bulk loaded if bulk is set.
+func (w *PointsWriter) writePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point, bulk bool) error {
 	atomic.AddInt64(&w.stats.WriteReq, 1)
 	atomic.AddInt64(&w.stats.PointWriteReq, int64(len(points)))
 
@@ -319,7 +416,7 @@ func (w *PointsWriter) WritePointsPrivileged(database, retentionPolicy string, c
 	for shardID, points := range shardMappings.Points {
 		go func(shard *meta.ShardInfo, database, retentionPolicy string, points []models.Point) {
 			// 写入shard
-			err := w.writeToShard(shard, database, retentionPolicy, points)
+			err := w.writeToShard(shard, database, retentionPolicy, points, bulk)
 			if err == tsdb.ErrShardDeletion {
 				err = tsdb.PartialWriteError{Reason: fmt.Sprintf("shard %d is pending deletion", shard.ID), Dropped: len(points)}
 			}
@@ -374,15 +471,20 @@ func (w *PointsWriter) WritePointsPrivileged(database, retentionPolicy string, c
 }
 
 // writeToShards writes points to a shard.
-func (w *PointsWriter) writeToShard(shard *meta.ShardInfo, database, retentionPolicy string, points []models.Point) error {
+func (w *PointsWriter) writeToShard(shard *meta.ShardInfo, database, retentionPolicy string, points []models.Point, bulk bool) error {
 	atomic.AddInt64(&w.stats.PointWriteReqLocal, int64(len(points)))
 
+	writeToShard := w.TSDBStore.WriteToShard
+	if bulk {
+		writeToShard = w.TSDBStore.WriteToShardBulk
+	}
+
 	/*
 	write to shard
 	store is the entry of writing
 	*/
 	// 写入shard
-	err := w.TSDBStore.WriteToShard(shard.ID, points)
+	err := writeToShard(shard.ID, points)
 	if err == nil {
 		atomic.AddInt64(&w.stats.WriteOK, 1)
 		return nil
@@ -408,7 +510,7 @@ func (w *PointsWriter) writeToShard(shard *meta.ShardInfo, database, retentionPo
 		}
 	}
 	// 创建shard后，再次重试
-	err = w.TSDBStore.WriteToShard(shard.ID, points)
+	err = writeToShard(shard.ID, points)
 	if err != nil {
 		w.Logger.Info("Write failed", zap.Uint64("shard", shard.ID), zap.Error(err))
 		atomic.AddInt64(&w.stats.WriteErr, 1)
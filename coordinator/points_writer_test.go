@@ -13,6 +13,7 @@ import (
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/services/meta"
 	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxql"
 )
 
 // TODO(benbjohnson): Rewrite tests to use cluster_test.MetaClient.
@@ -428,6 +429,159 @@ func TestPointsWriter_WritePoints_Dropped(t *testing.T) {
 	}
 }
 
+// Ensure that points are only written if the user may write all of them.
+func TestPointsWriter_WritePoints_Unauthorized(t *testing.T) {
+	pr := &coordinator.WritePointsRequest{
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+	}
+	pr.AddPoint("cpu", 1.0, time.Now(), nil)
+	pr.AddPoint("mem", 1.0, time.Now(), nil)
+
+	store := &fakeStore{
+		WriteFn: func(shardID uint64, points []models.Point) error {
+			t.Error("unexpected write")
+			return nil
+		},
+	}
+
+	c := coordinator.NewPointsWriter()
+	c.MetaClient = NewPointsWriterMetaClient()
+	c.TSDBStore = store
+	c.Node = &influxdb.Node{ID: 1}
+
+	c.Open()
+	defer c.Close()
+
+	// The user may only write to another retention policy.
+	user := &meta.UserInfo{
+		Name:   "fred",
+		Grants: []meta.GrantInfo{{Database: "mydb", RetentionPolicy: "otherrp", Privilege: influxql.WritePrivilege}},
+	}
+
+	err := c.WritePoints(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, user, pr.Points)
+	if !influxdb.IsAuthorizationError(err) {
+		t.Fatalf("PointsWriter.WritePoints(): got %v, exp authorization error", err)
+	}
+}
+
+func TestPointsWriter_WritePoints_RateLimited(t *testing.T) {
+	pr := &coordinator.WritePointsRequest{
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+	}
+
+	// Ensure that the test shard groups are created before the points
+	// are created.
+	ms := NewPointsWriterMetaClient()
+	pr.AddPoint("cpu", 1.0, time.Now(), nil)
+	pr.AddPoint("mem", 1.0, time.Now(), nil)
+
+	var writeN int64
+	store := &fakeStore{
+		WriteFn: func(shardID uint64, points []models.Point) error {
+			atomic.AddInt64(&writeN, int64(len(points)))
+			return nil
+		},
+		CreateShardfn: func(database, retentionPolicy string, shardID uint64, enabled bool) error {
+			return nil
+		},
+	}
+
+	c := coordinator.NewPointsWriter()
+	c.MetaClient = ms
+	c.TSDBStore = store
+	c.Node = &influxdb.Node{ID: 1}
+	c.Limiter = coordinator.NewWriteLimiter(coordinator.WriteLimits{PointsPerSecond: 2}, coordinator.WriteLimits{PointsPerSecond: 3})
+
+	c.Open()
+	defer c.Close()
+
+	user := &meta.UserInfo{Name: "fred", Admin: true}
+	if err := c.WritePoints(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, user, pr.Points); err != nil {
+		t.Fatal(err)
+	}
+
+	// The user limit is exhausted.
+	err := c.WritePoints(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, user, pr.Points[:1])
+	if _, ok := err.(influxdb.RateLimitError); !ok {
+		t.Fatalf("PointsWriter.WritePoints(): got %v, exp rate limit error", err)
+	}
+
+	// Privileged writes are only limited by the database.
+	if err := c.WritePointsPrivileged(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points[:1]); err != nil {
+		t.Fatal(err)
+	}
+	err = c.WritePointsPrivileged(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points[:1])
+	if _, ok := err.(influxdb.RateLimitError); !ok {
+		t.Fatalf("PointsWriter.WritePointsPrivileged(): got %v, exp rate limit error", err)
+	}
+
+	// Points written by the server itself aren't limited.
+	if err := c.WritePointsInternal(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := atomic.LoadInt64(&writeN); n != 5 {
+		t.Fatalf("unexpected number of points written: %d", n)
+	}
+}
+
+// Ensure that bulk writes are bulk loaded by the shards, after checking the user.
+func TestPointsWriter_WritePointsBulk(t *testing.T) {
+	pr := &coordinator.WritePointsRequest{
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+	}
+
+	// Ensure that the test shard groups are created before the points
+	// are created.
+	ms := NewPointsWriterMetaClient()
+	pr.AddPoint("cpu", 1.0, time.Now(), nil)
+	pr.AddPoint("mem", 1.0, time.Now(), nil)
+
+	var writeN int64
+	store := &fakeStore{
+		WriteFn: func(shardID uint64, points []models.Point) error {
+			t.Error("unexpected write")
+			return nil
+		},
+		WriteBulkFn: func(shardID uint64, points []models.Point) error {
+			atomic.AddInt64(&writeN, int64(len(points)))
+			return nil
+		},
+		CreateShardfn: func(database, retentionPolicy string, shardID uint64, enabled bool) error {
+			return nil
+		},
+	}
+
+	c := coordinator.NewPointsWriter()
+	c.MetaClient = ms
+	c.TSDBStore = store
+	c.Node = &influxdb.Node{ID: 1}
+
+	c.Open()
+	defer c.Close()
+
+	// The user may only write to another retention policy.
+	user := &meta.UserInfo{
+		Name:   "fred",
+		Grants: []meta.GrantInfo{{Database: "mydb", RetentionPolicy: "otherrp", Privilege: influxql.WritePrivilege}},
+	}
+	err := c.WritePointsBulk(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, user, pr.Points)
+	if !influxdb.IsAuthorizationError(err) {
+		t.Fatalf("PointsWriter.WritePointsBulk(): got %v, exp authorization error", err)
+	}
+
+	user.Grants[0].RetentionPolicy = "myrp"
+	if err := c.WritePointsBulk(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, user, pr.Points); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt64(&writeN); n != 2 {
+		t.Fatalf("unexpected number of points written: %d", n)
+	}
+}
+
 type fakePointsWriter struct {
 	WritePointsIntoFn func(*coordinator.IntoWriteRequest) error
 }
@@ -550,6 +704,7 @@ var shardID uint64
 
 type fakeStore struct {
 	WriteFn       func(shardID uint64, points []models.Point) error
+	WriteBulkFn   func(shardID uint64, points []models.Point) error
 	CreateShardfn func(database, retentionPolicy string, shardID uint64, enabled bool) error
 }
 
@@ -557,6 +712,10 @@ func (f *fakeStore) WriteToShard(shardID uint64, points []models.Point) error {
 	return f.WriteFn(shardID, points)
 }
 
+func (f *fakeStore) WriteToShardBulk(shardID uint64, points []models.Point) error {
+	return f.WriteBulkFn(shardID, points)
+}
+
 func (f *fakeStore) CreateShard(database, retentionPolicy string, shardID uint64, enabled bool) error {
 	return f.CreateShardfn(database, retentionPolicy, shardID, enabled)
 }
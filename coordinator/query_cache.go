@@ -0,0 +1,698 @@
+package coordinator
+
+import (
+	"container/list"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxql"
+)
+
+// The keys for statistics generated by the query cache.
+const (
+	statQueryCacheHits          = "hits"
+	statQueryCacheMisses        = "misses"
+	statQueryCacheInvalidations = "invalidations"
+	statQueryCacheEvictions     = "evictions"
+	statQueryCacheEntries       = "entries"
+	statQueryCacheMemoryBytes   = "memBytes"
+)
+
+// maxQueryCacheBuckets is the maximum number of GROUP BY time buckets of a
+// statement served through the query cache.
+const maxQueryCacheBuckets = 100000
+
+// queryCacheFunctions are the functions whose result in a GROUP BY time
+// bucket only depends on the points of the bucket.
+var queryCacheFunctions = map[string]struct{}{
+	"count":      {},
+	"first":      {},
+	"last":       {},
+	"max":        {},
+	"mean":       {},
+	"median":     {},
+	"min":        {},
+	"mode":       {},
+	"percentile": {},
+	"spread":     {},
+	"stddev":     {},
+	"sum":        {},
+}
+
+// QueryCache caches the results of SELECT statements grouped by time for each
+// completed GROUP BY time bucket, so repeated statements only compute the
+// buckets which are not cached. Buckets are invalidated when points within
+// them are written or deleted.
+//
+// Entries are indexed by the databases they read. Writes only look at the
+// entries of their database when they overlap the time range cached for it,
+// and never take the lock of the whole cache.
+type QueryCache struct {
+	// Maximum estimated size of the cached results and maximum number of
+	// cached statements. Least recently used statements are evicted first.
+	MaxMemorySize int64
+	MaxEntries    int
+
+	mu      sync.Mutex
+	entries map[string]*queryCacheEntry
+	lru     *list.List // most recently used entries at the front
+
+	databases sync.Map // map[string]*queryCacheDatabase
+	size      int64    // atomic
+
+	stats *QueryCacheStatistics
+}
+
+// NewQueryCache returns a new instance of QueryCache.
+func NewQueryCache(maxMemorySize int64, maxEntries int) *QueryCache {
+	return &QueryCache{
+		MaxMemorySize: maxMemorySize,
+		MaxEntries:    maxEntries,
+		entries:       make(map[string]*queryCacheEntry),
+		lru:           list.New(),
+		stats:         &QueryCacheStatistics{},
+	}
+}
+
+// QueryCacheStatistics keeps statistics related to the query cache.
+type QueryCacheStatistics struct {
+	Hits          int64
+	Misses        int64
+	Invalidations int64
+	Evictions     int64
+}
+
+// Statistics returns statistics for periodic monitoring.
+func (c *QueryCache) Statistics(tags map[string]string) []models.Statistic {
+	c.mu.Lock()
+	entries := len(c.entries)
+	c.mu.Unlock()
+
+	return []models.Statistic{{
+		Name: "queryCache",
+		Tags: tags,
+		Values: map[string]interface{}{
+			statQueryCacheHits:          atomic.LoadInt64(&c.stats.Hits),
+			statQueryCacheMisses:        atomic.LoadInt64(&c.stats.Misses),
+			statQueryCacheInvalidations: atomic.LoadInt64(&c.stats.Invalidations),
+			statQueryCacheEvictions:     atomic.LoadInt64(&c.stats.Evictions),
+			statQueryCacheEntries:       entries,
+			statQueryCacheMemoryBytes:   atomic.LoadInt64(&c.size),
+		},
+	}}
+}
+
+// PointsWritten invalidates the buckets of the database overlapping the time
+// range of written points. It implements tsdb.ShardObserver.
+func (c *QueryCache) PointsWritten(database string, min, max int64) {
+	c.invalidate(database, min, max)
+}
+
+// PointsDeleted invalidates the buckets of the database overlapping the time
+// range of deleted points. It implements tsdb.ShardObserver.
+func (c *QueryCache) PointsDeleted(database string, min, max int64) {
+	c.invalidate(database, min, max)
+}
+
+func (c *QueryCache) invalidate(database string, min, max int64) {
+	v, ok := c.databases.Load(database)
+	if !ok {
+		return
+	}
+	db := v.(*queryCacheDatabase)
+	if !db.overlaps(min, max) {
+		return
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var n int64
+	for ent := range db.entries {
+		invalidated, size := ent.invalidate(min, max)
+		n += invalidated
+		atomic.AddInt64(&c.size, -size)
+	}
+	atomic.AddInt64(&c.stats.Invalidations, n)
+}
+
+// database returns the index of the entries reading database.
+func (c *QueryCache) database(database string) *queryCacheDatabase {
+	if v, ok := c.databases.Load(database); ok {
+		return v.(*queryCacheDatabase)
+	}
+	v, _ := c.databases.LoadOrStore(database, &queryCacheDatabase{
+		entries: make(map[*queryCacheEntry]struct{}),
+		min:     math.MaxInt64,
+		max:     math.MinInt64,
+	})
+	return v.(*queryCacheDatabase)
+}
+
+// acquire returns the cached buckets of the complete windows of the plan and
+// reserves the other complete windows, which the caller computes and then
+// commits or aborts. If all is true, every complete window is reserved.
+func (c *QueryCache) acquire(p *queryCachePlan, all bool) (cached map[int64][]*models.Row, pending map[int64]*queryCacheBucket) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ent := c.entries[p.key]
+	if ent == nil {
+		ent = &queryCacheEntry{
+			key:     p.key,
+			buckets: make(map[int64]*queryCacheBucket),
+			min:     math.MaxInt64,
+			max:     math.MinInt64,
+		}
+		for _, name := range p.databases {
+			db := c.database(name)
+			db.add(ent)
+			ent.databases = append(ent.databases, db)
+		}
+		ent.elem = c.lru.PushFront(ent)
+		c.entries[p.key] = ent
+		c.evict()
+	} else {
+		c.lru.MoveToFront(ent.elem)
+	}
+	if p.columns == nil {
+		p.columns = ent.columns
+	}
+
+	// Writes are checked against the time range of the databases before
+	// the buckets are looked up, so the range must cover the windows before
+	// they are reserved.
+	min, max := int64(math.MaxInt64), int64(math.MinInt64)
+	for _, w := range p.windows {
+		if !p.complete(w) {
+			continue
+		}
+		if w.start < min {
+			min = w.start
+		}
+		if w.end > max {
+			max = w.end
+		}
+	}
+	for _, db := range ent.databases {
+		db.extend(min, max)
+	}
+
+	ent.mu.Lock()
+	defer ent.mu.Unlock()
+
+	cached = make(map[int64][]*models.Row)
+	pending = make(map[int64]*queryCacheBucket)
+	for _, w := range p.windows {
+		if !p.complete(w) {
+			continue
+		}
+
+		b := ent.buckets[w.start]
+		if b != nil && !b.pending && !all {
+			cached[w.start] = b.rows
+			continue
+		} else if b != nil {
+			ent.size -= b.size
+			atomic.AddInt64(&c.size, -b.size)
+		}
+
+		b = &queryCacheBucket{end: w.end, pending: true}
+		ent.buckets[w.start] = b
+		pending[w.start] = b
+		if w.start < ent.min {
+			ent.min = w.start
+		}
+		if w.end > ent.max {
+			ent.max = w.end
+		}
+	}
+
+	atomic.AddInt64(&c.stats.Hits, int64(len(cached)))
+	atomic.AddInt64(&c.stats.Misses, int64(len(p.windows)-len(cached)))
+	return cached, pending
+}
+
+// commit caches the computed rows of the reserved windows which were not
+// invalidated in the meantime.
+func (c *QueryCache) commit(p *queryCachePlan, pending map[int64]*queryCacheBucket, computed map[int64][]*models.Row) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ent := c.entries[p.key]
+	if ent == nil {
+		return
+	}
+	if ent.columns == nil {
+		ent.columns = p.columns
+	}
+
+	ent.mu.Lock()
+	for start, b := range pending {
+		if ent.buckets[start] != b {
+			continue
+		}
+		b.pending = false
+		b.rows = computed[start]
+		b.size = queryCacheRowsSize(b.rows)
+		ent.size += b.size
+		atomic.AddInt64(&c.size, b.size)
+	}
+	ent.mu.Unlock()
+	c.evict()
+}
+
+// abort releases the reserved windows.
+func (c *QueryCache) abort(p *queryCachePlan, pending map[int64]*queryCacheBucket) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ent := c.entries[p.key]
+	if ent == nil {
+		return
+	}
+
+	ent.mu.Lock()
+	defer ent.mu.Unlock()
+	for start, b := range pending {
+		if ent.buckets[start] == b {
+			delete(ent.buckets, start)
+		}
+	}
+}
+
+// evict removes the least recently used entries until the cache is within
+// its limits. c.mu must be held.
+func (c *QueryCache) evict() {
+	for (c.MaxMemorySize > 0 && atomic.LoadInt64(&c.size) > c.MaxMemorySize) || (c.MaxEntries > 0 && len(c.entries) > c.MaxEntries) {
+		elem := c.lru.Back()
+		if elem == nil {
+			return
+		}
+		ent := c.lru.Remove(elem).(*queryCacheEntry)
+		delete(c.entries, ent.key)
+		for _, db := range ent.databases {
+			db.remove(ent)
+		}
+
+		ent.mu.Lock()
+		atomic.AddInt64(&c.size, -ent.size)
+		ent.buckets, ent.size = nil, 0
+		ent.mu.Unlock()
+		atomic.AddInt64(&c.stats.Evictions, 1)
+	}
+}
+
+// queryCacheDatabase indexes the entries reading a database.
+type queryCacheDatabase struct {
+	// Time range spanned by all buckets ever reserved for the database, read
+	// and extended atomically. Writes outside of it invalidate nothing.
+	min, max int64
+
+	mu      sync.Mutex
+	entries map[*queryCacheEntry]struct{}
+}
+
+// overlaps returns true if the time range cached for the database overlaps
+// the inclusive time range of written points.
+func (d *queryCacheDatabase) overlaps(min, max int64) bool {
+	return atomic.LoadInt64(&d.max) > min && atomic.LoadInt64(&d.min) <= max
+}
+
+// extend extends the time range cached for the database to include the range
+// from min to max, exclusive of max.
+func (d *queryCacheDatabase) extend(min, max int64) {
+	for {
+		cur := atomic.LoadInt64(&d.min)
+		if min >= cur || atomic.CompareAndSwapInt64(&d.min, cur, min) {
+			break
+		}
+	}
+	for {
+		cur := atomic.LoadInt64(&d.max)
+		if max <= cur || atomic.CompareAndSwapInt64(&d.max, cur, max) {
+			break
+		}
+	}
+}
+
+func (d *queryCacheDatabase) add(ent *queryCacheEntry) {
+	d.mu.Lock()
+	d.entries[ent] = struct{}{}
+	d.mu.Unlock()
+}
+
+func (d *queryCacheDatabase) remove(ent *queryCacheEntry) {
+	d.mu.Lock()
+	delete(d.entries, ent)
+	d.mu.Unlock()
+}
+
+// queryCacheEntry holds the cached buckets of a statement.
+type queryCacheEntry struct {
+	key       string
+	databases []*queryCacheDatabase
+	elem      *list.Element
+	columns   []string
+
+	// Buckets by start time, and the time range spanned by all buckets ever
+	// cached. Guarded by mu, which is taken after the lock of the cache or
+	// of a database.
+	mu       sync.Mutex
+	buckets  map[int64]*queryCacheBucket
+	min, max int64
+
+	size int64
+}
+
+// invalidate removes the buckets overlapping the inclusive time range. It
+// returns the number of cached buckets removed and the size they released.
+func (e *queryCacheEntry) invalidate(min, max int64) (n, size int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.max <= min || e.min > max {
+		return 0, 0
+	}
+	for start, b := range e.buckets {
+		if start > max || b.end <= min {
+			continue
+		}
+		delete(e.buckets, start)
+		e.size -= b.size
+		size += b.size
+		if !b.pending {
+			n++
+		}
+	}
+	return n, size
+}
+
+// queryCacheBucket holds the rows of a GROUP BY time bucket. A pending bucket
+// is being computed.
+type queryCacheBucket struct {
+	end     int64
+	pending bool
+	rows    []*models.Row
+	size    int64
+}
+
+// queryCacheRowsSize returns an estimate of the memory used by rows.
+func queryCacheRowsSize(rows []*models.Row) int64 {
+	n := 64
+	for _, r := range rows {
+		n += len(r.Name) + 64
+		for k, v := range r.Tags {
+			n += len(k) + len(v) + 32
+		}
+		for _, values := range r.Values {
+			n += 24 + len(values)*16
+			for _, v := range values {
+				if s, ok := v.(string); ok {
+					n += len(s)
+				}
+			}
+		}
+	}
+	return int64(n)
+}
+
+// queryCacheWindow is a time range, exclusive of its end.
+type queryCacheWindow struct {
+	start, end int64
+}
+
+// queryCachePlan describes how a SELECT statement is served from the query
+// cache.
+type queryCachePlan struct {
+	key       string
+	databases []string
+	columns   []string
+
+	stmt *influxql.SelectStatement
+	cond influxql.Expr // condition without time
+	opt  query.IteratorOptions
+
+	// Time range of the statement, inclusive, and the current time.
+	min, max int64
+	now      int64
+
+	// GROUP BY time windows overlapping the time range.
+	windows []queryCacheWindow
+}
+
+// newQueryCachePlan returns a plan for serving a statement from the query
+// cache, or false if its results can't be cached.
+func newQueryCachePlan(stmt *influxql.SelectStatement, ctx *query.ExecutionContext, now time.Time) (*queryCachePlan, bool) {
+	if stmt.Target != nil || stmt.IsRawQuery || !stmt.TimeAscending() ||
+		stmt.Limit > 0 || stmt.Offset > 0 || stmt.SLimit > 0 || stmt.SOffset > 0 {
+		return nil, false
+	}
+
+	// Filling with previous or interpolated values depends on other buckets.
+	switch stmt.Fill {
+	case influxql.NullFill, influxql.NoFill, influxql.NumberFill:
+	default:
+		return nil, false
+	}
+	if !queryCacheFields(stmt.Fields) {
+		return nil, false
+	}
+
+	// Statements on measurements which aren't readable in full are not
+	// cached, so revoking access to them takes effect immediately.
+	p := &queryCachePlan{stmt: stmt}
+	for _, src := range stmt.Sources {
+		m, ok := src.(*influxql.Measurement)
+		if !ok {
+			return nil, false
+		} else if !query.AuthorizerIsOpen(ctx.Authorizer) {
+			if m.Regex != nil || !query.AuthorizerForRetentionPolicy(ctx.Authorizer, m.RetentionPolicy).AuthorizeSeriesRead(m.Database, []byte(m.Name), nil) {
+				return nil, false
+			}
+		}
+		p.addDatabase(m.Database)
+	}
+
+	interval, err := stmt.GroupByInterval()
+	if err != nil || interval <= 0 {
+		return nil, false
+	}
+	offset, err := stmt.GroupByOffset()
+	if err != nil {
+		return nil, false
+	}
+	p.opt = query.IteratorOptions{
+		Interval: query.Interval{Duration: interval, Offset: offset, Months: stmt.GroupByCalendarInterval()},
+		Location: stmt.Location,
+	}
+
+	// Statements without a lower time bound are not cached.
+	valuer := influxql.NowValuer{Now: now, Location: stmt.Location}
+	cond, tr, err := influxql.ConditionExpr(stmt.Condition, &valuer)
+	if err != nil || tr.Min.IsZero() {
+		return nil, false
+	}
+	p.cond = cond
+	p.min, p.now = tr.MinTimeNano(), now.UnixNano()
+	if tr.Max.IsZero() {
+		p.max = p.now
+	} else {
+		p.max = tr.MaxTimeNano()
+	}
+	if p.max < p.min || (p.max-p.min)/int64(interval) > maxQueryCacheBuckets {
+		return nil, false
+	}
+
+	for t := p.min; t <= p.max; {
+		start, end := p.opt.Window(t)
+		p.windows = append(p.windows, queryCacheWindow{start: start, end: end})
+		if end <= t {
+			break
+		}
+		t = end
+	}
+
+	// The statement without its time range identifies the cached results.
+	other := stmt.Clone()
+	other.Condition = cond
+	p.key = ctx.UserID + "\x00" + other.String()
+	return p, true
+}
+
+// queryCacheFields returns true if the fields only call functions whose
+// result in a bucket only depends on the points of the bucket.
+func queryCacheFields(fields influxql.Fields) bool {
+	calls, ok := 0, true
+	for _, f := range fields {
+		influxql.WalkFunc(f.Expr, func(n influxql.Node) {
+			if call, isCall := n.(*influxql.Call); isCall {
+				calls++
+				if _, found := queryCacheFunctions[call.Name]; !found {
+					ok = false
+				}
+			}
+		})
+	}
+	return ok && calls > 0
+}
+
+func (p *queryCachePlan) addDatabase(database string) {
+	for _, db := range p.databases {
+		if db == database {
+			return
+		}
+	}
+	p.databases = append(p.databases, database)
+}
+
+// complete returns true if the window is entirely within the time range of
+// the statement and in the past.
+func (p *queryCachePlan) complete(w queryCacheWindow) bool {
+	return w.start >= p.min && w.end-1 <= p.max && w.end <= p.now
+}
+
+// segments returns the time ranges of the runs of windows which are not
+// cached.
+func (p *queryCachePlan) segments(cached map[int64][]*models.Row) []queryCacheWindow {
+	var segments []queryCacheWindow
+	for _, w := range p.windows {
+		if _, ok := cached[w.start]; ok {
+			continue
+		}
+
+		start, end := w.start, w.end
+		if start < p.min {
+			start = p.min
+		}
+		if end > p.max+1 {
+			end = p.max + 1
+		}
+		if n := len(segments); n > 0 && segments[n-1].end == start {
+			segments[n-1].end = end
+		} else {
+			segments = append(segments, queryCacheWindow{start: start, end: end})
+		}
+	}
+	return segments
+}
+
+// segmentStatement returns the statement restricted to the time range of a
+// segment.
+func (p *queryCachePlan) segmentStatement(seg queryCacheWindow) *influxql.SelectStatement {
+	timeExpr := func(op influxql.Token, t int64) influxql.Expr {
+		return &influxql.BinaryExpr{
+			Op:  op,
+			LHS: &influxql.VarRef{Val: "time"},
+			RHS: &influxql.TimeLiteral{Val: time.Unix(0, t).UTC()},
+		}
+	}
+
+	other := p.stmt.Clone()
+	other.Condition = &influxql.BinaryExpr{
+		Op:  influxql.AND,
+		LHS: timeExpr(influxql.GTE, seg.start),
+		RHS: timeExpr(influxql.LT, seg.end),
+	}
+	if p.cond != nil {
+		other.Condition = &influxql.BinaryExpr{
+			Op:  influxql.AND,
+			LHS: &influxql.ParenExpr{Expr: influxql.CloneExpr(p.cond)},
+			RHS: other.Condition,
+		}
+	}
+	return other
+}
+
+// readBuckets reads the rows emitted by an emitter into the buckets of their
+// window.
+func (p *queryCachePlan) readBuckets(em *query.Emitter, buckets map[int64][]*models.Row) error {
+	for {
+		row, _, err := em.Emit()
+		if err != nil {
+			return err
+		} else if row == nil {
+			return nil
+		}
+		if p.columns == nil {
+			p.columns = row.Columns
+		}
+
+		var last *models.Row
+		var lastStart int64
+		for _, values := range row.Values {
+			t, ok := values[0].(time.Time)
+			if !ok {
+				continue
+			}
+			start, _ := p.opt.Window(t.UnixNano())
+			if last == nil || start != lastStart {
+				last = &models.Row{Name: row.Name, Tags: row.Tags}
+				lastStart = start
+				buckets[start] = append(buckets[start], last)
+			}
+			last.Values = append(last.Values, values)
+		}
+	}
+}
+
+// merge returns the rows of the statement from cached and computed buckets.
+// It returns false if filled buckets are missing some series, which means
+// they must be computed over the whole time range of the statement, as the
+// fill values of a series depend on the types of its fields.
+func (p *queryCachePlan) merge(cached, computed map[int64][]*models.Row) ([]*models.Row, bool) {
+	type series struct {
+		row *models.Row
+		id  string
+		n   int
+	}
+	m := make(map[string]*series)
+
+	for _, w := range p.windows {
+		rows, ok := cached[w.start]
+		if !ok {
+			rows = computed[w.start]
+		}
+
+		for _, r := range rows {
+			id := query.NewTags(r.Tags).ID()
+			s := m[r.Name+"\x00"+id]
+			if s == nil {
+				s = &series{row: &models.Row{Name: r.Name, Tags: r.Tags, Columns: p.columns}, id: id}
+				m[r.Name+"\x00"+id] = s
+			}
+
+			// Copy values, which may be modified once returned.
+			for _, values := range r.Values {
+				s.row.Values = append(s.row.Values, append([]interface{}(nil), values...))
+			}
+			s.n++
+		}
+	}
+
+	complete := true
+	a := make([]*series, 0, len(m))
+	for _, s := range m {
+		if p.stmt.Fill != influxql.NoFill && s.n != len(p.windows) {
+			complete = false
+		}
+		a = append(a, s)
+	}
+	sort.Slice(a, func(i, j int) bool {
+		if a[i].row.Name != a[j].row.Name {
+			return a[i].row.Name < a[j].row.Name
+		}
+		return a[i].id < a[j].id
+	})
+
+	rows := make([]*models.Row, len(a))
+	for i, s := range a {
+		rows[i] = s.row
+	}
+	return rows, complete
+}
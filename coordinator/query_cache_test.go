@@ -0,0 +1,55 @@
+package coordinator
+
+import (
+	"testing"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+// Ensure writes only invalidate the buckets of their database and time range.
+func TestQueryCache_Invalidate(t *testing.T) {
+	c := NewQueryCache(0, 0)
+	p := &queryCachePlan{
+		key:       "SELECT count(value) FROM db0..cpu GROUP BY time(10ns)",
+		databases: []string{"db0"},
+		min:       0,
+		max:       29,
+		now:       100,
+		windows:   []queryCacheWindow{{0, 10}, {10, 20}, {20, 30}},
+	}
+
+	_, pending := c.acquire(p, false)
+	computed := make(map[int64][]*models.Row)
+	for start := range pending {
+		computed[start] = []*models.Row{{Name: "cpu"}}
+	}
+	c.commit(p, pending, computed)
+
+	// Writes to other databases or after the cached buckets are ignored
+	// without looking at the entries.
+	c.PointsWritten("db1", 0, 30)
+	c.PointsWritten("db0", 30, 40)
+	if cached, _ := c.acquire(&queryCachePlan{key: p.key, databases: p.databases, min: 0, max: 29, now: 100, windows: p.windows}, false); len(cached) != 3 {
+		t.Fatalf("unexpected number of cached buckets: %d", len(cached))
+	}
+
+	// Writes within a bucket invalidate it.
+	c.PointsWritten("db0", 15, 15)
+	cached, pending := c.acquire(p, false)
+	if len(cached) != 2 {
+		t.Fatalf("unexpected number of cached buckets: %d", len(cached))
+	} else if _, ok := pending[10]; !ok || len(pending) != 1 {
+		t.Fatalf("unexpected pending buckets: %v", pending)
+	}
+	c.abort(p, pending)
+
+	// Evicted entries are removed from the index of their databases.
+	c.MaxEntries = 1
+	c.acquire(&queryCachePlan{key: "other", databases: []string{"db0"}}, false)
+	v, _ := c.databases.Load("db0")
+	if db := v.(*queryCacheDatabase); len(db.entries) != 1 {
+		t.Fatalf("unexpected number of database entries: %d", len(db.entries))
+	} else if _, ok := db.entries[c.entries["other"]]; !ok {
+		t.Fatal("expected the other entry to be indexed")
+	}
+}
@@ -78,6 +78,10 @@ func (e *LocalShardMapper) mapShards(a *LocalShardMapping, sources influxql.Sour
 			if err := e.mapShards(a, s.Statement.Sources, tmin, tmax); err != nil {
 				return err
 			}
+		case *influxql.Join:
+			if err := e.mapShards(a, influxql.Sources{s.LHS, s.RHS}, tmin, tmax); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
@@ -182,6 +186,9 @@ func (a *LocalShardMapping) CreateIterator(ctx context.Context, m *influxql.Meas
 		opt.EndTime = a.MaxTime.UnixNano()
 	}
 
+	// Series are authorized against the grants on their retention policy.
+	opt.Authorizer = query.AuthorizerForRetentionPolicy(opt.Authorizer, m.RetentionPolicy)
+
 	if m.Regex != nil {
 		measurements := sg.MeasurementsByRegex(m.Regex.Val)
 		inputs := make([]query.Iterator, 0, len(measurements))
@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"path/filepath"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -14,6 +16,7 @@ import (
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/monitor"
+	"github.com/influxdata/influxdb/pkg/estimator"
 	"github.com/influxdata/influxdb/pkg/tracing"
 	"github.com/influxdata/influxdb/pkg/tracing/fields"
 	"github.com/influxdata/influxdb/query"
@@ -51,10 +54,19 @@ type StatementExecutor struct {
 		WritePointsInto(*IntoWriteRequest) error
 	}
 
+	// Records the execution of administrative statements. It is optional.
+	Auditor interface {
+		AuditStatement(stmt influxql.Statement, user, database string, err error)
+	}
+
+	// Caches the results of SELECT statements grouped by time. It is optional.
+	QueryCache *QueryCache
+
 	// Select statement limits
 	MaxSelectPointN   int
 	MaxSelectSeriesN  int
 	MaxSelectBucketsN int
+	MaxFillLookback   time.Duration
 }
 
 // ExecuteStatement executes the given statement with the given execution context.
@@ -83,16 +95,36 @@ func (e *StatementExecutor) ExecuteStatement(stmt influxql.Statement, ctx *query
 			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
 		}
 		err = e.executeCreateDatabaseStatement(stmt)
+	case *influxql.CreateDownsampleStatement:
+		if ctx.ReadOnly {
+			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
+		}
+		err = e.executeCreateDownsampleStatement(stmt)
 	case *influxql.CreateRetentionPolicyStatement:
 		if ctx.ReadOnly {
 			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
 		}
 		err = e.executeCreateRetentionPolicyStatement(stmt)
+	case *influxql.CreateRetentionRuleStatement:
+		if ctx.ReadOnly {
+			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
+		}
+		err = e.executeCreateRetentionRuleStatement(stmt)
+	case *influxql.CreateRoleStatement:
+		if ctx.ReadOnly {
+			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
+		}
+		err = e.executeCreateRoleStatement(stmt)
 	case *influxql.CreateSubscriptionStatement:
 		if ctx.ReadOnly {
 			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
 		}
 		err = e.executeCreateSubscriptionStatement(stmt)
+	case *influxql.CreateTokenStatement:
+		if ctx.ReadOnly {
+			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
+		}
+		rows, err = e.executeCreateTokenStatement(stmt)
 	case *influxql.CreateUserStatement:
 		if ctx.ReadOnly {
 			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
@@ -110,6 +142,11 @@ func (e *StatementExecutor) ExecuteStatement(stmt influxql.Statement, ctx *query
 			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
 		}
 		err = e.executeDropDatabaseStatement(stmt)
+	case *influxql.DropDownsampleStatement:
+		if ctx.ReadOnly {
+			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
+		}
+		err = e.executeDropDownsampleStatement(stmt)
 	case *influxql.DropMeasurementStatement:
 		if ctx.ReadOnly {
 			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
@@ -120,11 +157,26 @@ func (e *StatementExecutor) ExecuteStatement(stmt influxql.Statement, ctx *query
 			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
 		}
 		err = e.executeDropSeriesStatement(stmt, ctx.Database)
+	case *influxql.DropQuotaStatement:
+		if ctx.ReadOnly {
+			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
+		}
+		err = e.executeDropQuotaStatement(stmt)
 	case *influxql.DropRetentionPolicyStatement:
 		if ctx.ReadOnly {
 			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
 		}
 		err = e.executeDropRetentionPolicyStatement(stmt)
+	case *influxql.DropRetentionRuleStatement:
+		if ctx.ReadOnly {
+			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
+		}
+		err = e.executeDropRetentionRuleStatement(stmt)
+	case *influxql.DropRoleStatement:
+		if ctx.ReadOnly {
+			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
+		}
+		err = e.executeDropRoleStatement(stmt)
 	case *influxql.DropShardStatement:
 		if ctx.ReadOnly {
 			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
@@ -135,6 +187,11 @@ func (e *StatementExecutor) ExecuteStatement(stmt influxql.Statement, ctx *query
 			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
 		}
 		err = e.executeDropSubscriptionStatement(stmt)
+	case *influxql.DropTokenStatement:
+		if ctx.ReadOnly {
+			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
+		}
+		err = e.executeDropTokenStatement(stmt)
 	case *influxql.DropUserStatement:
 		if ctx.ReadOnly {
 			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
@@ -156,6 +213,21 @@ func (e *StatementExecutor) ExecuteStatement(stmt influxql.Statement, ctx *query
 			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
 		}
 		err = e.executeGrantAdminStatement(stmt)
+	case *influxql.GrantMeasurementStatement:
+		if ctx.ReadOnly {
+			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
+		}
+		err = e.executeGrantMeasurementStatement(stmt)
+	case *influxql.GrantRoleStatement:
+		if ctx.ReadOnly {
+			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
+		}
+		err = e.executeGrantRoleStatement(stmt)
+	case *influxql.RepairShardStatement:
+		if ctx.ReadOnly {
+			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
+		}
+		rows, err = e.executeRepairShardStatement(stmt)
 	case *influxql.RevokeStatement:
 		if ctx.ReadOnly {
 			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
@@ -166,20 +238,42 @@ func (e *StatementExecutor) ExecuteStatement(stmt influxql.Statement, ctx *query
 			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
 		}
 		err = e.executeRevokeAdminStatement(stmt)
+	case *influxql.RevokeMeasurementStatement:
+		if ctx.ReadOnly {
+			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
+		}
+		err = e.executeRevokeMeasurementStatement(stmt)
+	case *influxql.RevokeRoleStatement:
+		if ctx.ReadOnly {
+			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
+		}
+		err = e.executeRevokeRoleStatement(stmt)
+	case *influxql.ShowCardinalityReportStatement:
+		rows, err = e.executeShowCardinalityReportStatement(stmt, ctx)
 	case *influxql.ShowContinuousQueriesStatement:
 		rows, err = e.executeShowContinuousQueriesStatement(stmt)
 	case *influxql.ShowDatabasesStatement:
 		rows, err = e.executeShowDatabasesStatement(stmt, ctx)
 	case *influxql.ShowDiagnosticsStatement:
 		rows, err = e.executeShowDiagnosticsStatement(stmt)
+	case *influxql.ShowDownsamplesStatement:
+		rows, err = e.executeShowDownsamplesStatement(stmt)
 	case *influxql.ShowGrantsForUserStatement:
 		rows, err = e.executeShowGrantsForUserStatement(stmt)
+	case *influxql.ShowGrantsForRoleStatement:
+		rows, err = e.executeShowGrantsForRoleStatement(stmt)
 	case *influxql.ShowMeasurementsStatement:
 		return e.executeShowMeasurementsStatement(stmt, ctx)
 	case *influxql.ShowMeasurementCardinalityStatement:
 		rows, err = e.executeShowMeasurementCardinalityStatement(stmt)
+	case *influxql.ShowQuotasStatement:
+		rows, err = e.executeShowQuotasStatement(stmt)
 	case *influxql.ShowRetentionPoliciesStatement:
 		rows, err = e.executeShowRetentionPoliciesStatement(stmt)
+	case *influxql.ShowRetentionRulesStatement:
+		rows, err = e.executeShowRetentionRulesStatement(stmt)
+	case *influxql.ShowRolesStatement:
+		rows, err = e.executeShowRolesStatement(stmt)
 	case *influxql.ShowSeriesCardinalityStatement:
 		rows, err = e.executeShowSeriesCardinalityStatement(stmt)
 	case *influxql.ShowShardsStatement:
@@ -194,20 +288,31 @@ func (e *StatementExecutor) ExecuteStatement(stmt influxql.Statement, ctx *query
 		return e.executeShowTagKeys(stmt, ctx)
 	case *influxql.ShowTagValuesStatement:
 		return e.executeShowTagValues(stmt, ctx)
+	case *influxql.ShowTokensStatement:
+		rows, err = e.executeShowTokensStatement(stmt)
 	case *influxql.ShowUsersStatement:
 		rows, err = e.executeShowUsersStatement(stmt)
+	case *influxql.SetQuotaStatement:
+		if ctx.ReadOnly {
+			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
+		}
+		err = e.executeSetQuotaStatement(stmt)
 	case *influxql.SetPasswordUserStatement:
 		if ctx.ReadOnly {
 			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
 		}
 		err = e.executeSetPasswordUserStatement(stmt)
-	case *influxql.ShowQueriesStatement, *influxql.KillQueryStatement:
+	case *influxql.ShowQueriesStatement, *influxql.ShowQueryStatsStatement, *influxql.KillQueryStatement:
 		// Send query related statements to the task manager.
 		return e.TaskManager.ExecuteStatement(stmt, ctx)
 	default:
 		return query.ErrInvalidQuery
 	}
 
+	if e.Auditor != nil {
+		e.Auditor.AuditStatement(stmt, ctx.UserID, ctx.Database, err)
+	}
+
 	if err != nil {
 		return err
 	}
@@ -288,6 +393,29 @@ func (e *StatementExecutor) executeCreateDatabaseStatement(stmt *influxql.Create
 	return err
 }
 
+func (e *StatementExecutor) executeCreateDownsampleStatement(stmt *influxql.CreateDownsampleStatement) error {
+	if !meta.ValidName(stmt.Name) {
+		return meta.ErrInvalidName
+	}
+
+	rp, err := e.retentionPolicyName(stmt.Database, stmt.RetentionPolicy)
+	if err != nil {
+		return err
+	}
+
+	ds := meta.DownsampleInfo{
+		Name:              stmt.Name,
+		TargetPolicy:      stmt.TargetPolicy,
+		Interval:          stmt.Interval,
+		FloatAggregate:    stmt.FloatAggregate,
+		IntegerAggregate:  stmt.IntegerAggregate,
+		UnsignedAggregate: stmt.UnsignedAggregate,
+		BooleanAggregate:  stmt.BooleanAggregate,
+		StringAggregate:   stmt.StringAggregate,
+	}
+	return e.MetaClient.CreateDownsample(stmt.Database, rp, &ds)
+}
+
 func (e *StatementExecutor) executeCreateRetentionPolicyStatement(stmt *influxql.CreateRetentionPolicyStatement) error {
 	if !meta.ValidName(stmt.Name) {
 		// TODO This should probably be in `(*meta.Data).CreateRetentionPolicy`
@@ -307,6 +435,26 @@ func (e *StatementExecutor) executeCreateRetentionPolicyStatement(stmt *influxql
 	return err
 }
 
+func (e *StatementExecutor) executeCreateRetentionRuleStatement(stmt *influxql.CreateRetentionRuleStatement) error {
+	if !meta.ValidName(stmt.Name) {
+		return meta.ErrInvalidName
+	}
+
+	rp, err := e.retentionPolicyName(stmt.Database, stmt.RetentionPolicy)
+	if err != nil {
+		return err
+	}
+
+	rule := meta.RetentionRuleInfo{
+		Name:        stmt.Name,
+		Measurement: stmt.Measurement,
+		TagKey:      stmt.TagKey,
+		TagPrefix:   stmt.TagPrefix,
+		Duration:    stmt.Duration,
+	}
+	return e.MetaClient.CreateRetentionRule(stmt.Database, rp, &rule)
+}
+
 func (e *StatementExecutor) executeCreateSubscriptionStatement(q *influxql.CreateSubscriptionStatement) error {
 	return e.MetaClient.CreateSubscription(q.Database, q.RetentionPolicy, q.Name, q.Mode, q.Destinations)
 }
@@ -349,6 +497,14 @@ func (e *StatementExecutor) executeDropDatabaseStatement(stmt *influxql.DropData
 	return e.MetaClient.DropDatabase(stmt.Name)
 }
 
+func (e *StatementExecutor) executeDropDownsampleStatement(stmt *influxql.DropDownsampleStatement) error {
+	rp, err := e.retentionPolicyName(stmt.Database, stmt.RetentionPolicy)
+	if err != nil {
+		return err
+	}
+	return e.MetaClient.DropDownsample(stmt.Database, rp, stmt.Name)
+}
+
 func (e *StatementExecutor) executeDropMeasurementStatement(stmt *influxql.DropMeasurementStatement, database string) error {
 	if dbi := e.MetaClient.Database(database); dbi == nil {
 		return query.ErrDatabaseNotFound(database)
@@ -382,6 +538,11 @@ func (e *StatementExecutor) executeDropShardStatement(stmt *influxql.DropShardSt
 	return e.MetaClient.DropShard(stmt.ID)
 }
 
+func (e *StatementExecutor) executeDropQuotaStatement(stmt *influxql.DropQuotaStatement) error {
+	kind, name := quotaTarget(stmt.User, stmt.Database)
+	return e.MetaClient.DropQuota(kind, name)
+}
+
 func (e *StatementExecutor) executeDropRetentionPolicyStatement(stmt *influxql.DropRetentionPolicyStatement) error {
 	dbi := e.MetaClient.Database(stmt.Database)
 	if dbi == nil {
@@ -400,6 +561,32 @@ func (e *StatementExecutor) executeDropRetentionPolicyStatement(stmt *influxql.D
 	return e.MetaClient.DropRetentionPolicy(stmt.Database, stmt.Name)
 }
 
+func (e *StatementExecutor) executeDropRetentionRuleStatement(stmt *influxql.DropRetentionRuleStatement) error {
+	rp, err := e.retentionPolicyName(stmt.Database, stmt.RetentionPolicy)
+	if err != nil {
+		return err
+	}
+	return e.MetaClient.DropRetentionRule(stmt.Database, rp, stmt.Name)
+}
+
+// retentionPolicyName returns name, or the default retention policy of the
+// database if name is blank.
+func (e *StatementExecutor) retentionPolicyName(database, name string) (string, error) {
+	if database == "" {
+		return "", ErrDatabaseNameRequired
+	} else if name != "" {
+		return name, nil
+	}
+
+	di := e.MetaClient.Database(database)
+	if di == nil {
+		return "", influxdb.ErrDatabaseNotFound(database)
+	} else if di.DefaultRetentionPolicy == "" {
+		return "", fmt.Errorf("default retention policy not set for: %s", di.Name)
+	}
+	return di.DefaultRetentionPolicy, nil
+}
+
 func (e *StatementExecutor) executeDropSubscriptionStatement(q *influxql.DropSubscriptionStatement) error {
 	return e.MetaClient.DropSubscription(q.Database, q.RetentionPolicy, q.Name)
 }
@@ -410,10 +597,11 @@ func (e *StatementExecutor) executeDropUserStatement(q *influxql.DropUserStateme
 
 func (e *StatementExecutor) executeExplainStatement(q *influxql.ExplainStatement, ctx *query.ExecutionContext) (models.Rows, error) {
 	opt := query.SelectOptions{
-		NodeID:      ctx.ExecutionOptions.NodeID,
-		MaxSeriesN:  e.MaxSelectSeriesN,
-		MaxBucketsN: e.MaxSelectBucketsN,
-		Authorizer:  ctx.Authorizer,
+		NodeID:          ctx.ExecutionOptions.NodeID,
+		MaxSeriesN:      e.MaxSelectSeriesN,
+		MaxBucketsN:     e.MaxSelectBucketsN,
+		MaxFillLookback: e.MaxFillLookback,
+		Authorizer:      ctx.Authorizer,
 	}
 
 	// Prepare the query for execution, but do not actually execute it.
@@ -514,6 +702,26 @@ func (e *StatementExecutor) executeGrantAdminStatement(stmt *influxql.GrantAdmin
 	return e.MetaClient.SetAdminPrivilege(stmt.User, true)
 }
 
+func (e *StatementExecutor) executeRepairShardStatement(stmt *influxql.RepairShardStatement) (models.Rows, error) {
+	blocks, err := e.TSDBStore.RepairShard(stmt.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each row is a range of points of a series that was lost.
+	row := &models.Row{Columns: []string{"series", "field", "min_time", "max_time", "file"}}
+	for _, b := range blocks {
+		row.Values = append(row.Values, []interface{}{
+			string(b.Series),
+			string(b.Field),
+			time.Unix(0, b.MinTime).UTC().Format(time.RFC3339Nano),
+			time.Unix(0, b.MaxTime).UTC().Format(time.RFC3339Nano),
+			filepath.Base(b.Path),
+		})
+	}
+	return []*models.Row{row}, nil
+}
+
 func (e *StatementExecutor) executeRevokeStatement(stmt *influxql.RevokeStatement) error {
 	priv := influxql.NoPrivileges
 
@@ -534,20 +742,121 @@ func (e *StatementExecutor) executeRevokeAdminStatement(stmt *influxql.RevokeAdm
 	return e.MetaClient.SetAdminPrivilege(stmt.User, false)
 }
 
+func (e *StatementExecutor) executeCreateRoleStatement(stmt *influxql.CreateRoleStatement) error {
+	return e.MetaClient.CreateRole(stmt.Name)
+}
+
+func (e *StatementExecutor) executeDropRoleStatement(stmt *influxql.DropRoleStatement) error {
+	return e.MetaClient.DropRole(stmt.Name)
+}
+
+func (e *StatementExecutor) executeGrantRoleStatement(stmt *influxql.GrantRoleStatement) error {
+	return e.MetaClient.GrantRole(stmt.Role, stmt.User)
+}
+
+func (e *StatementExecutor) executeRevokeRoleStatement(stmt *influxql.RevokeRoleStatement) error {
+	return e.MetaClient.RevokeRole(stmt.Role, stmt.User)
+}
+
+func (e *StatementExecutor) executeGrantMeasurementStatement(stmt *influxql.GrantMeasurementStatement) error {
+	gi := measurementGrant(stmt.Privilege, stmt.Database, stmt.RetentionPolicy, stmt.Name, stmt.Regex)
+	if stmt.Role != "" {
+		return e.MetaClient.GrantRoleMeasurement(stmt.Role, gi)
+	}
+	return e.MetaClient.GrantUserMeasurement(stmt.User, gi)
+}
+
+func (e *StatementExecutor) executeRevokeMeasurementStatement(stmt *influxql.RevokeMeasurementStatement) error {
+	gi := measurementGrant(stmt.Privilege, stmt.Database, stmt.RetentionPolicy, stmt.Name, stmt.Regex)
+	if stmt.Role != "" {
+		return e.MetaClient.RevokeRoleMeasurement(stmt.Role, gi)
+	}
+	return e.MetaClient.RevokeUserMeasurement(stmt.User, gi)
+}
+
+// measurementGrant returns the grant of a privilege on the measurements named
+// name or matching re.
+func measurementGrant(p influxql.Privilege, database, retentionPolicy, name string, re *influxql.RegexLiteral) *meta.GrantInfo {
+	gi := &meta.GrantInfo{
+		Database:        database,
+		RetentionPolicy: retentionPolicy,
+		Privilege:       p,
+	}
+	if re != nil {
+		gi.Measurement = re.Val.String()
+	} else {
+		gi.Measurement = "^" + regexp.QuoteMeta(name) + "$"
+	}
+	return gi
+}
+
+func (e *StatementExecutor) executeCreateTokenStatement(stmt *influxql.CreateTokenStatement) (models.Rows, error) {
+	token, err := e.MetaClient.CreateToken(stmt.Name, stmt.User)
+	if err != nil {
+		return nil, err
+	}
+
+	row := &models.Row{Columns: []string{"name", "token"}}
+	row.Values = append(row.Values, []interface{}{stmt.Name, token})
+	return []*models.Row{row}, nil
+}
+
+func (e *StatementExecutor) executeDropTokenStatement(stmt *influxql.DropTokenStatement) error {
+	return e.MetaClient.DropToken(stmt.Name)
+}
+
+func (e *StatementExecutor) executeSetQuotaStatement(stmt *influxql.SetQuotaStatement) error {
+	kind, name := quotaTarget(stmt.User, stmt.Database)
+	return e.MetaClient.SetQuota(meta.QuotaInfo{
+		Kind:                 kind,
+		Name:                 name,
+		MaxConcurrentQueries: stmt.MaxConcurrentQueries,
+		MaxQueriesPerMinute:  stmt.MaxQueriesPerMinute,
+		MaxPointsScanned:     stmt.MaxPointsScanned,
+		MaxBytesReturned:     stmt.MaxBytesReturned,
+	})
+}
+
+// quotaTarget returns the kind and the name of the target of a quota
+// statement.
+func quotaTarget(user, database string) (kind, name string) {
+	if user != "" {
+		return query.UserQuota, user
+	}
+	return query.DatabaseQuota, database
+}
+
 func (e *StatementExecutor) executeSetPasswordUserStatement(q *influxql.SetPasswordUserStatement) error {
 	return e.MetaClient.UpdateUser(q.Name, q.Password)
 }
 
 func (e *StatementExecutor) executeSelectStatement(stmt *influxql.SelectStatement, ctx *query.ExecutionContext) error {
-	cur, err := e.createIterators(ctx, stmt, ctx.ExecutionOptions)
+	if e.QueryCache != nil {
+		if p, ok := newQueryCachePlan(stmt, ctx, time.Now().UTC()); ok {
+			return e.executeCachedSelectStatement(p, ctx)
+		}
+	}
+
+	// Record the work done by the iterators in the profile of the statement.
+	tctx := ctx.TraceContext()
+	start := time.Now()
+
+	cur, err := e.createIterators(tctx, stmt, ctx.ExecutionOptions)
 	if err != nil {
 		return err
 	}
 
+	if span := tracing.SpanFromContext(tctx); span != nil {
+		span.MergeFields(fields.Duration("planning_time", time.Since(start)))
+	}
+
 	// Generate a row emitter from the iterator set.
 	em := query.NewEmitter(cur, ctx.ChunkSize)
 	defer em.Close()
 
+	// Count the series and points scanned by the statement.
+	defer func() { ctx.AddIteratorStats(cur.Stats()) }()
+
 	// Emit rows to the results channel.
 	var writeN int64
 	var emitted bool
@@ -625,13 +934,96 @@ func (e *StatementExecutor) executeSelectStatement(stmt *influxql.SelectStatemen
 	return nil
 }
 
+// executeCachedSelectStatement executes a SELECT statement, only computing the
+// buckets which are not held by the query cache.
+func (e *StatementExecutor) executeCachedSelectStatement(p *queryCachePlan, ctx *query.ExecutionContext) error {
+	cached, pending := e.QueryCache.acquire(p, false)
+	computed, err := e.selectSegments(p, ctx, p.segments(cached))
+	if err != nil {
+		e.QueryCache.abort(p, pending)
+		return err
+	}
+
+	rows, ok := p.merge(cached, computed)
+	if !ok {
+		// Some series are missing from filled buckets, so they must be
+		// filled over the whole time range of the statement.
+		e.QueryCache.abort(p, pending)
+		_, pending = e.QueryCache.acquire(p, true)
+		computed, err = e.selectSegments(p, ctx, []queryCacheWindow{{start: p.min, end: p.max + 1}})
+		if err != nil {
+			e.QueryCache.abort(p, pending)
+			return err
+		}
+		rows, _ = p.merge(nil, computed)
+	}
+	e.QueryCache.commit(p, pending, computed)
+
+	// Always emit at least one result.
+	if len(rows) == 0 {
+		return ctx.Send(&query.Result{
+			Series: make([]*models.Row, 0),
+		})
+	}
+
+	// Split rows into chunks like the emitter.
+	for i, row := range rows {
+		values := row.Values
+		for len(values) > 0 {
+			r := &models.Row{Name: row.Name, Tags: row.Tags, Columns: row.Columns, Values: values}
+			if ctx.ChunkSize > 0 && len(values) > ctx.ChunkSize {
+				r.Values, values = values[:ctx.ChunkSize], values[ctx.ChunkSize:]
+				r.Partial = true
+			} else {
+				values = nil
+			}
+
+			if err := ctx.Send(&query.Result{
+				Series:  []*models.Row{r},
+				Partial: r.Partial || i < len(rows)-1,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// selectSegments computes the buckets of a cached statement within segments.
+func (e *StatementExecutor) selectSegments(p *queryCachePlan, ctx *query.ExecutionContext, segments []queryCacheWindow) (map[int64][]*models.Row, error) {
+	buckets := make(map[int64][]*models.Row)
+	for _, seg := range segments {
+		cur, err := e.createIterators(ctx.TraceContext(), p.segmentStatement(seg), ctx.ExecutionOptions)
+		if err != nil {
+			return nil, err
+		}
+
+		em := query.NewEmitter(cur, 0)
+		err = p.readBuckets(em, buckets)
+		ctx.AddIteratorStats(cur.Stats())
+		em.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		// Check if the query was interrupted while emitting.
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+	}
+	return buckets, nil
+}
+
 func (e *StatementExecutor) createIterators(ctx context.Context, stmt *influxql.SelectStatement, opt query.ExecutionOptions) (query.Cursor, error) {
 	sopt := query.SelectOptions{
-		NodeID:      opt.NodeID,
-		MaxSeriesN:  e.MaxSelectSeriesN,
-		MaxPointN:   e.MaxSelectPointN,
-		MaxBucketsN: e.MaxSelectBucketsN,
-		Authorizer:  opt.Authorizer,
+		NodeID:          opt.NodeID,
+		MaxSeriesN:      e.MaxSelectSeriesN,
+		MaxPointN:       e.MaxSelectPointN,
+		MaxBucketsN:     e.MaxSelectBucketsN,
+		MaxFillLookback: e.MaxFillLookback,
+		Authorizer:      opt.Authorizer,
 	}
 
 	// Create a set of iterators from a selection.
@@ -662,14 +1054,78 @@ func (e *StatementExecutor) executeShowDatabasesStatement(q *influxql.ShowDataba
 
 	row := &models.Row{Name: "databases", Columns: []string{"name"}}
 	for _, di := range dis {
-		// Only include databases that the user is authorized to read or write.
-		if a.AuthorizeDatabase(influxql.ReadPrivilege, di.Name) || a.AuthorizeDatabase(influxql.WritePrivilege, di.Name) {
+		// Only include databases that the user is authorized to read or write,
+		// including through grants on some of their measurements.
+		if authorizeDatabaseAccess(a, di.Name) {
 			row.Values = append(row.Values, []interface{}{di.Name})
 		}
 	}
 	return []*models.Row{row}, nil
 }
 
+// authorizeDatabaseAccess returns true if a authorizes reading or writing the
+// database or some of its measurements.
+func authorizeDatabaseAccess(a query.Authorizer, database string) bool {
+	if u, ok := a.(meta.User); ok {
+		return u.AuthorizeMeasurement(influxql.ReadPrivilege, database, "", nil) ||
+			u.AuthorizeMeasurement(influxql.WritePrivilege, database, "", nil)
+	}
+	return a.AuthorizeDatabase(influxql.ReadPrivilege, database) || a.AuthorizeDatabase(influxql.WritePrivilege, database)
+}
+
+func (e *StatementExecutor) executeShowDownsamplesStatement(q *influxql.ShowDownsamplesStatement) (models.Rows, error) {
+	if q.Database == "" {
+		return nil, ErrDatabaseNameRequired
+	}
+
+	di := e.MetaClient.Database(q.Database)
+	if di == nil {
+		return nil, influxdb.ErrDatabaseNotFound(q.Database)
+	}
+
+	row := &models.Row{Columns: []string{
+		"retention_policy", "name", "target_policy", "interval",
+		"float", "integer", "unsigned", "boolean", "string",
+		"completed_shard_groups", "pending_shard_groups", "last_completed",
+	}}
+	for _, rpi := range di.RetentionPolicies {
+		for i := range rpi.Downsamples {
+			ds := &rpi.Downsamples[i]
+
+			// Shard groups without recorded progress are pending, whether
+			// they are still hot or waiting for the next downsample run.
+			var completed, pending int
+			var last time.Time
+			for _, sgi := range rpi.ShardGroups {
+				if sgi.Deleted() {
+					continue
+				}
+				p := ds.ShardGroupProgress(sgi.ID)
+				if p == nil {
+					pending++
+					continue
+				}
+				completed++
+				if p.CompletedAt.After(last) {
+					last = p.CompletedAt
+				}
+			}
+
+			var lastCompleted interface{}
+			if !last.IsZero() {
+				lastCompleted = last.UTC().Format(time.RFC3339)
+			}
+
+			row.Values = append(row.Values, []interface{}{
+				rpi.Name, ds.Name, ds.TargetPolicy, ds.Interval.String(),
+				ds.FloatAggregate, ds.IntegerAggregate, ds.UnsignedAggregate, ds.BooleanAggregate, ds.StringAggregate,
+				completed, pending, lastCompleted,
+			})
+		}
+	}
+	return []*models.Row{row}, nil
+}
+
 func (e *StatementExecutor) executeShowDiagnosticsStatement(stmt *influxql.ShowDiagnosticsStatement) (models.Rows, error) {
 	diags, err := e.Monitor.Diagnostics()
 	if err != nil {
@@ -708,6 +1164,67 @@ func (e *StatementExecutor) executeShowGrantsForUserStatement(q *influxql.ShowGr
 	for d, p := range priv {
 		row.Values = append(row.Values, []interface{}{d, p.String()})
 	}
+	rows := []*models.Row{row}
+
+	// Grants on measurements, directly or through roles, are listed
+	// separately.
+	for _, ui := range e.MetaClient.Users() {
+		if ui.Name != q.Name || (len(ui.Grants) == 0 && len(ui.Roles) == 0) {
+			continue
+		}
+
+		row := &models.Row{Name: "measurements", Columns: []string{"role", "database", "retention_policy", "measurement", "privilege"}}
+		for _, gi := range ui.Grants {
+			row.Values = append(row.Values, []interface{}{"", gi.Database, gi.RetentionPolicy, gi.Measurement, gi.Privilege.String()})
+		}
+		for _, role := range ui.Roles {
+			ri, err := e.MetaClient.Role(role)
+			if err != nil {
+				return nil, err
+			}
+			for _, gi := range ri.Grants {
+				row.Values = append(row.Values, []interface{}{role, gi.Database, gi.RetentionPolicy, gi.Measurement, gi.Privilege.String()})
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (e *StatementExecutor) executeShowGrantsForRoleStatement(q *influxql.ShowGrantsForRoleStatement) (models.Rows, error) {
+	ri, err := e.MetaClient.Role(q.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	row := &models.Row{Columns: []string{"database", "retention_policy", "measurement", "privilege"}}
+	for _, gi := range ri.Grants {
+		row.Values = append(row.Values, []interface{}{gi.Database, gi.RetentionPolicy, gi.Measurement, gi.Privilege.String()})
+	}
+	return []*models.Row{row}, nil
+}
+
+func (e *StatementExecutor) executeShowRolesStatement(q *influxql.ShowRolesStatement) (models.Rows, error) {
+	users := make(map[string][]string)
+	for _, ui := range e.MetaClient.Users() {
+		for _, role := range ui.Roles {
+			users[role] = append(users[role], ui.Name)
+		}
+	}
+
+	row := &models.Row{Columns: []string{"role", "users"}}
+	for _, ri := range e.MetaClient.Roles() {
+		sort.Strings(users[ri.Name])
+		row.Values = append(row.Values, []interface{}{ri.Name, strings.Join(users[ri.Name], ",")})
+	}
+	return []*models.Row{row}, nil
+}
+
+func (e *StatementExecutor) executeShowTokensStatement(q *influxql.ShowTokensStatement) (models.Rows, error) {
+	row := &models.Row{Columns: []string{"name", "user"}}
+	for _, ti := range e.MetaClient.Tokens() {
+		row.Values = append(row.Values, []interface{}{ti.Name, ti.User})
+	}
 	return []*models.Row{row}, nil
 }
 
@@ -771,6 +1288,14 @@ func (e *StatementExecutor) executeShowMeasurementCardinalityStatement(stmt *inf
 	}}, nil
 }
 
+func (e *StatementExecutor) executeShowQuotasStatement(q *influxql.ShowQuotasStatement) (models.Rows, error) {
+	row := &models.Row{Columns: []string{"kind", "name", "max_concurrent_queries", "max_queries_per_minute", "max_points_scanned", "max_bytes_returned"}}
+	for _, qi := range e.MetaClient.Quotas() {
+		row.Values = append(row.Values, []interface{}{qi.Kind, qi.Name, qi.MaxConcurrentQueries, qi.MaxQueriesPerMinute, qi.MaxPointsScanned, qi.MaxBytesReturned})
+	}
+	return []*models.Row{row}, nil
+}
+
 func (e *StatementExecutor) executeShowRetentionPoliciesStatement(q *influxql.ShowRetentionPoliciesStatement) (models.Rows, error) {
 	if q.Database == "" {
 		return nil, ErrDatabaseNameRequired
@@ -788,12 +1313,31 @@ func (e *StatementExecutor) executeShowRetentionPoliciesStatement(q *influxql.Sh
 	return []*models.Row{row}, nil
 }
 
+func (e *StatementExecutor) executeShowRetentionRulesStatement(q *influxql.ShowRetentionRulesStatement) (models.Rows, error) {
+	if q.Database == "" {
+		return nil, ErrDatabaseNameRequired
+	}
+
+	di := e.MetaClient.Database(q.Database)
+	if di == nil {
+		return nil, influxdb.ErrDatabaseNotFound(q.Database)
+	}
+
+	row := &models.Row{Columns: []string{"retention_policy", "name", "measurement", "tag_key", "tag_prefix", "duration"}}
+	for _, rpi := range di.RetentionPolicies {
+		for _, rule := range rpi.RetentionRules {
+			row.Values = append(row.Values, []interface{}{rpi.Name, rule.Name, rule.Measurement, rule.TagKey, rule.TagPrefix, rule.Duration.String()})
+		}
+	}
+	return []*models.Row{row}, nil
+}
+
 func (e *StatementExecutor) executeShowShardsStatement(stmt *influxql.ShowShardsStatement) (models.Rows, error) {
 	dis := e.MetaClient.Databases()
 
 	rows := []*models.Row{}
 	for _, di := range dis {
-		row := &models.Row{Columns: []string{"id", "database", "retention_policy", "shard_group", "start_time", "end_time", "expiry_time", "owners"}, Name: di.Name}
+		row := &models.Row{Columns: []string{"id", "database", "retention_policy", "shard_group", "start_time", "end_time", "expiry_time", "owners", "tier"}, Name: di.Name}
 		for _, rpi := range di.RetentionPolicies {
 			for _, sgi := range rpi.ShardGroups {
 				// Shards associated with deleted shard groups are effectively deleted.
@@ -817,6 +1361,7 @@ func (e *StatementExecutor) executeShowShardsStatement(stmt *influxql.ShowShards
 						sgi.EndTime.UTC().Format(time.RFC3339),
 						sgi.EndTime.Add(rpi.Duration).UTC().Format(time.RFC3339),
 						joinUint64(ownerIDs),
+						e.TSDBStore.ShardTier(si.ID),
 					})
 				}
 			}
@@ -842,6 +1387,177 @@ func (e *StatementExecutor) executeShowSeriesCardinalityStatement(stmt *influxql
 	}}, nil
 }
 
+// DefaultCardinalityReportLimit is the number of measurements listed by
+// SHOW CARDINALITY REPORT without a LIMIT.
+const DefaultCardinalityReportLimit = 10
+
+func (e *StatementExecutor) executeShowCardinalityReportStatement(stmt *influxql.ShowCardinalityReportStatement, ctx *query.ExecutionContext) (models.Rows, error) {
+	if stmt.Database == "" {
+		return nil, ErrDatabaseNameRequired
+	}
+	di := e.MetaClient.Database(stmt.Database)
+	if di == nil {
+		return nil, influxdb.ErrDatabaseNotFound(stmt.Database)
+	}
+	limit := stmt.Limit
+	if limit <= 0 {
+		limit = DefaultCardinalityReportLimit
+	}
+
+	// Series are attributed to the first shard group they were written to,
+	// which shows how fast each measurement grows.
+	type groupGrowth struct {
+		retentionPolicy string
+		group           meta.ShardGroupInfo
+		series          map[string]uint64
+		newSeries       map[string]uint64
+	}
+	var growth []groupGrowth
+	for _, rpi := range di.RetentionPolicies {
+		for _, sgi := range rpi.ShardGroups {
+			if !sgi.Deleted() {
+				growth = append(growth, groupGrowth{retentionPolicy: rpi.Name, group: sgi})
+			}
+		}
+	}
+	sort.Slice(growth, func(i, j int) bool {
+		if gi, gj := growth[i].group, growth[j].group; !gi.StartTime.Equal(gj.StartTime) {
+			return gi.StartTime.Before(gj.StartTime)
+		}
+		return growth[i].group.ID < growth[j].group.ID
+	})
+
+	var shardIDs []uint64
+	series := make(map[string]*tsdb.SeriesIDSet)
+	for i := range growth {
+		g := &growth[i]
+		ids := make([]uint64, 0, len(g.group.Shards))
+		for _, si := range g.group.Shards {
+			ids = append(ids, si.ID)
+		}
+		shardIDs = append(shardIDs, ids...)
+
+		sets, err := e.TSDBStore.MeasurementSeriesIDSets(ids)
+		if err != nil {
+			return nil, err
+		}
+
+		g.series, g.newSeries = make(map[string]uint64), make(map[string]uint64)
+		for name, set := range sets {
+			if !query.AuthorizerIsOpen(ctx.Authorizer) && !query.AuthorizerForRetentionPolicy(ctx.Authorizer, g.retentionPolicy).AuthorizeSeriesRead(stmt.Database, []byte(name), nil) {
+				continue
+			}
+
+			g.series[name] = set.Cardinality()
+			if seen := series[name]; seen != nil {
+				g.newSeries[name] = set.AndNot(seen).Cardinality()
+				seen.Merge(set)
+			} else {
+				g.newSeries[name] = set.Cardinality()
+				series[name] = set.Clone()
+			}
+		}
+	}
+
+	// Rank measurements by their number of series.
+	var total uint64
+	names := make([]string, 0, len(series))
+	for name, set := range series {
+		total += set.Cardinality()
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if ni, nj := series[names[i]].Cardinality(), series[names[j]].Cardinality(); ni != nj {
+			return ni > nj
+		}
+		return names[i] < names[j]
+	})
+	if len(names) > limit {
+		names = names[:limit]
+	}
+
+	// The series estimate comes from the HyperLogLog sketches of the indexes.
+	ss, ts, err := e.TSDBStore.SeriesSketches(stmt.Database)
+	if err != nil {
+		return nil, err
+	}
+	estimate := int64(ss.Count()) - int64(ts.Count())
+	if estimate < 0 {
+		estimate = 0
+	}
+
+	// The growth rate is measured in the most recent shard group which has started.
+	now := time.Now().UTC()
+	var current *groupGrowth
+	for i := len(growth) - 1; i >= 0; i-- {
+		if !growth[i].group.StartTime.After(now) {
+			current = &growth[i]
+			break
+		}
+	}
+
+	summary := &models.Row{
+		Name:    "cardinality",
+		Columns: []string{"database", "series", "series_estimate", "measurements", "shard_groups"},
+		Values:  [][]interface{}{{stmt.Database, total, estimate, len(series), len(growth)}},
+	}
+
+	measurements := &models.Row{
+		Name:    "measurements",
+		Columns: []string{"measurement", "series", "percent", "new_series", "new_series_per_hour"},
+	}
+	tagKeys := &models.Row{
+		Name:    "tag_keys",
+		Columns: []string{"measurement", "tag_key", "values", "series"},
+	}
+	for _, name := range names {
+		n := series[name].Cardinality()
+		var newN uint64
+		var rate float64
+		if current != nil {
+			newN = current.newSeries[name]
+			end := current.group.EndTime
+			if end.After(now) {
+				end = now
+			}
+			if hours := end.Sub(current.group.StartTime).Hours(); hours > 0 {
+				rate = float64(newN) / hours
+			}
+		}
+		// Measurements whose series were all deleted have no series left.
+		var percent float64
+		if total > 0 {
+			percent = float64(n) / float64(total) * 100
+		}
+		measurements.Values = append(measurements.Values, []interface{}{name, n, percent, newN, rate})
+
+		keys, err := e.TSDBStore.TagKeyCardinalities(shardIDs, []byte(name))
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) > limit {
+			keys = keys[:limit]
+		}
+		for _, k := range keys {
+			tagKeys.Values = append(tagKeys.Values, []interface{}{name, k.Key, k.ValuesN, k.SeriesN})
+		}
+	}
+
+	history := &models.Row{
+		Name:    "growth",
+		Columns: []string{"time", "retention_policy", "measurement", "series", "new_series"},
+	}
+	for _, g := range growth {
+		for _, name := range names {
+			if n, ok := g.series[name]; ok {
+				history.Values = append(history.Values, []interface{}{g.group.StartTime, g.retentionPolicy, name, n, g.newSeries[name]})
+			}
+		}
+	}
+
+	return []*models.Row{summary, measurements, tagKeys, history}, nil
+}
+
 func (e *StatementExecutor) executeShowShardGroupsStatement(stmt *influxql.ShowShardGroupsStatement) (models.Rows, error) {
 	dis := e.MetaClient.Databases()
 
@@ -1280,6 +1996,22 @@ func (e *StatementExecutor) NormalizeStatement(stmt influxql.Statement, defaultD
 			if node.Database == "" {
 				node.Database = defaultDatabase
 			}
+		case *influxql.ShowRetentionRulesStatement:
+			if node.Database == "" {
+				node.Database = defaultDatabase
+			}
+		case *influxql.ShowDownsamplesStatement:
+			if node.Database == "" {
+				node.Database = defaultDatabase
+			}
+		case *influxql.GrantMeasurementStatement:
+			if node.Database == "" {
+				node.Database = defaultDatabase
+			}
+		case *influxql.RevokeMeasurementStatement:
+			if node.Database == "" {
+				node.Database = defaultDatabase
+			}
 		case *influxql.ShowMeasurementsStatement:
 			if node.Database == "" {
 				node.Database = defaultDatabase
@@ -1300,6 +2032,10 @@ func (e *StatementExecutor) NormalizeStatement(stmt influxql.Statement, defaultD
 			if node.Database == "" {
 				node.Database = defaultDatabase
 			}
+		case *influxql.ShowCardinalityReportStatement:
+			if node.Database == "" {
+				node.Database = defaultDatabase
+			}
 		case *influxql.Measurement:
 			switch stmt.(type) {
 			case *influxql.DropSeriesStatement, *influxql.DeleteSeriesStatement:
@@ -1363,6 +2099,7 @@ type TSDBStore interface {
 
 	RestoreShard(id uint64, r io.Reader) error
 	BackupShard(id uint64, since time.Time, w io.Writer) error
+	RepairShard(id uint64) ([]tsdb.CorruptBlock, error)
 
 	DeleteDatabase(name string) error
 	DeleteMeasurement(database, name string) error
@@ -1376,6 +2113,12 @@ type TSDBStore interface {
 
 	SeriesCardinality(database string) (int64, error)
 	MeasurementsCardinality(database string) (int64, error)
+	SeriesSketches(database string) (estimator.Sketch, estimator.Sketch, error)
+
+	MeasurementSeriesIDSets(shardIDs []uint64) (map[string]*tsdb.SeriesIDSet, error)
+	TagKeyCardinalities(shardIDs []uint64, name []byte) ([]tsdb.TagKeyCardinality, error)
+
+	ShardTier(id uint64) string
 }
 
 var _ TSDBStore = LocalTSDBStore{}
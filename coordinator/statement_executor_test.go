@@ -17,6 +17,8 @@ import (
 	"github.com/influxdata/influxdb/internal"
 	"github.com/influxdata/influxdb/logger"
 	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/estimator"
+	"github.com/influxdata/influxdb/pkg/estimator/hll"
 	"github.com/influxdata/influxdb/query"
 	"github.com/influxdata/influxdb/services/meta"
 	"github.com/influxdata/influxdb/tsdb"
@@ -130,6 +132,89 @@ func TestQueryExecutor_ExecuteQuery_MaxSelectBucketsN(t *testing.T) {
 	}
 }
 
+// Ensure query executor only computes the buckets which are not held by the query cache.
+func TestQueryExecutor_ExecuteQuery_QueryCache(t *testing.T) {
+	e := DefaultQueryExecutor()
+	cache := coordinator.NewQueryCache(0, 0)
+	e.StatementExecutor.QueryCache = cache
+
+	e.MetaClient.ShardGroupsByTimeRangeFn = func(database, policy string, min, max time.Time) (a []meta.ShardGroupInfo, err error) {
+		return []meta.ShardGroupInfo{
+			{ID: 1, Shards: []meta.ShardInfo{
+				{ID: 100, Owners: []meta.ShardOwner{{NodeID: 0}}},
+			}},
+		}, nil
+	}
+
+	// One point every 10 seconds, recording the time ranges read.
+	var ranges [][2]time.Time
+	e.TSDBStore.ShardGroupFn = func(ids []uint64) tsdb.ShardGroup {
+		var sh MockShard
+		sh.CreateIteratorFn = func(_ context.Context, _ *influxql.Measurement, opt query.IteratorOptions) (query.Iterator, error) {
+			ranges = append(ranges, [2]time.Time{time.Unix(0, opt.StartTime).UTC(), time.Unix(0, opt.EndTime+1).UTC()})
+
+			var points []query.FloatPoint
+			for i := 0; i < 6; i++ {
+				if t := ts("2000-01-01T00:00:00Z").Add(time.Duration(i) * 10 * time.Second).UnixNano(); t >= opt.StartTime && t <= opt.EndTime {
+					points = append(points, query.FloatPoint{Name: "cpu", Time: t, Value: float64(i)})
+				}
+			}
+			return query.NewCallIterator(&FloatIterator{Points: points}, opt)
+		}
+		sh.FieldDimensionsFn = func(measurements []string) (fields map[string]influxql.DataType, dimensions map[string]struct{}, err error) {
+			return map[string]influxql.DataType{"value": influxql.Float}, nil, nil
+		}
+		return &sh
+	}
+
+	q := `SELECT sum(value) FROM cpu WHERE time >= '2000-01-01T00:00:00Z' AND time < '2000-01-01T00:01:00Z' GROUP BY time(20s)`
+	exp := []*query.Result{{
+		StatementID: 0,
+		Series: []*models.Row{{
+			Name:    "cpu",
+			Columns: []string{"time", "sum"},
+			Values: [][]interface{}{
+				{ts("2000-01-01T00:00:00Z"), float64(1)},
+				{ts("2000-01-01T00:00:20Z"), float64(5)},
+				{ts("2000-01-01T00:00:40Z"), float64(9)},
+			},
+		}},
+	}}
+
+	// The first execution computes every bucket, and the second none.
+	for i := 0; i < 2; i++ {
+		if a := ReadAllResults(e.ExecuteQuery(q, "db0", 0)); !reflect.DeepEqual(a, exp) {
+			t.Fatalf("unexpected results: %s", spew.Sdump(a))
+		}
+	}
+	if exp := [][2]time.Time{{ts("2000-01-01T00:00:00Z"), ts("2000-01-01T00:01:00Z")}}; !reflect.DeepEqual(ranges, exp) {
+		t.Fatalf("unexpected time ranges: %v", ranges)
+	}
+
+	// Writes only invalidate the buckets they overlap.
+	ranges = nil
+	cache.PointsWritten("db0", ts("2000-01-01T00:00:25Z").UnixNano(), ts("2000-01-01T00:00:25Z").UnixNano())
+	cache.PointsWritten("db1", ts("2000-01-01T00:00:00Z").UnixNano(), ts("2000-01-01T00:00:00Z").UnixNano())
+	if a := ReadAllResults(e.ExecuteQuery(q, "db0", 0)); !reflect.DeepEqual(a, exp) {
+		t.Fatalf("unexpected results: %s", spew.Sdump(a))
+	}
+	if exp := [][2]time.Time{{ts("2000-01-01T00:00:20Z"), ts("2000-01-01T00:00:40Z")}}; !reflect.DeepEqual(ranges, exp) {
+		t.Fatalf("unexpected time ranges: %v", ranges)
+	}
+
+	stats := cache.Statistics(nil)[0]
+	for k, exp := range map[string]interface{}{
+		"hits":          int64(5),
+		"misses":        int64(4),
+		"invalidations": int64(1),
+		"entries":       1,
+	} {
+		if got := stats.Values[k]; got != exp {
+			t.Errorf("unexpected %s: got %v, exp %v", k, got, exp)
+		}
+	}
+}
+
 func TestStatementExecutor_ExecuteQuery_WriteInto(t *testing.T) {
 	for _, tt := range []struct {
 		name    string
@@ -446,6 +531,222 @@ func TestQueryExecutor_ExecuteQuery_ShowDatabases(t *testing.T) {
 	}
 }
 
+// Ensure statements are passed to the auditor with the user running them.
+func TestQueryExecutor_ExecuteQuery_Auditor(t *testing.T) {
+	auditor := &StatementAuditor{}
+	qe := query.NewExecutor()
+	qe.StatementExecutor = &coordinator.StatementExecutor{
+		MetaClient: &internal.MetaClientMock{
+			CreateDatabaseFn: func(name string) (*meta.DatabaseInfo, error) {
+				if name == "db1" {
+					return nil, meta.ErrDatabaseExists
+				}
+				return &meta.DatabaseInfo{Name: name}, nil
+			},
+		},
+		Auditor: auditor,
+	}
+
+	q, err := influxql.ParseQuery("CREATE DATABASE db0; CREATE DATABASE db1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opt := query.ExecutionOptions{UserID: "admin", Authorizer: query.OpenAuthorizer}
+	ReadAllResults(qe.ExecuteQuery(q, opt, make(chan struct{})))
+
+	if exp := []string{
+		"admin: CREATE DATABASE db0: <nil>",
+		"admin: CREATE DATABASE db1: database already exists",
+	}; !reflect.DeepEqual(auditor.Statements, exp) {
+		t.Fatalf("unexpected statements: exp %v, got %v", exp, auditor.Statements)
+	}
+}
+
+// Ensure the cardinality report ranks measurements and attributes series to
+// the shard group they were first written to.
+func TestQueryExecutor_ExecuteQuery_ShowCardinalityReport(t *testing.T) {
+	day := func(n int) time.Time { return time.Date(2000, 1, n, 0, 0, 0, 0, time.UTC) }
+
+	qe := query.NewExecutor()
+	store := &internal.TSDBStoreMock{}
+	qe.StatementExecutor = &coordinator.StatementExecutor{
+		MetaClient: &internal.MetaClientMock{
+			DatabaseFn: func(name string) *meta.DatabaseInfo {
+				return &meta.DatabaseInfo{
+					Name: name,
+					RetentionPolicies: []meta.RetentionPolicyInfo{{
+						Name: "rp0",
+						ShardGroups: []meta.ShardGroupInfo{
+							{ID: 2, StartTime: day(2), EndTime: day(3), Shards: []meta.ShardInfo{{ID: 2}}},
+							{ID: 1, StartTime: day(1), EndTime: day(2), Shards: []meta.ShardInfo{{ID: 1}}},
+						},
+					}},
+				}
+			},
+		},
+		TSDBStore: store,
+	}
+
+	seriesIDSet := func(ids ...uint64) *tsdb.SeriesIDSet { return tsdb.NewSeriesIDSet(ids...) }
+	store.MeasurementSeriesIDSetsFn = func(shardIDs []uint64) (map[string]*tsdb.SeriesIDSet, error) {
+		switch shardIDs[0] {
+		case 1:
+			return map[string]*tsdb.SeriesIDSet{"cpu": seriesIDSet(1, 2), "mem": seriesIDSet(3)}, nil
+		default:
+			return map[string]*tsdb.SeriesIDSet{"cpu": seriesIDSet(2, 4, 5)}, nil
+		}
+	}
+	store.SeriesSketchesFn = func(database string) (estimator.Sketch, estimator.Sketch, error) {
+		ss, ts := hll.NewDefaultPlus(), hll.NewDefaultPlus()
+		for i := 0; i < 5; i++ {
+			ss.Add([]byte(fmt.Sprintf("series%d", i)))
+		}
+		return ss, ts, nil
+	}
+	store.TagKeyCardinalitiesFn = func(shardIDs []uint64, name []byte) ([]tsdb.TagKeyCardinality, error) {
+		if !reflect.DeepEqual(shardIDs, []uint64{1, 2}) {
+			t.Fatalf("unexpected shards: %v", shardIDs)
+		}
+		if string(name) == "cpu" {
+			return []tsdb.TagKeyCardinality{{Key: "host", ValuesN: 4, SeriesN: 4}}, nil
+		}
+		return []tsdb.TagKeyCardinality{{Key: "host", ValuesN: 1, SeriesN: 1}}, nil
+	}
+
+	q := MustParseQuery(`SHOW CARDINALITY REPORT ON db0`)
+	results := ReadAllResults(qe.ExecuteQuery(q, query.ExecutionOptions{}, make(chan struct{})))
+	if len(results) != 1 || results[0].Err != nil {
+		t.Fatalf("unexpected results: %s", spew.Sdump(results))
+	}
+
+	exp := []*models.Row{
+		{
+			Name:    "cardinality",
+			Columns: []string{"database", "series", "series_estimate", "measurements", "shard_groups"},
+			Values:  [][]interface{}{{"db0", uint64(5), int64(5), 2, 2}},
+		},
+		{
+			Name:    "measurements",
+			Columns: []string{"measurement", "series", "percent", "new_series", "new_series_per_hour"},
+			Values: [][]interface{}{
+				{"cpu", uint64(4), float64(80), uint64(2), float64(2) / 24},
+				{"mem", uint64(1), float64(20), uint64(0), float64(0)},
+			},
+		},
+		{
+			Name:    "tag_keys",
+			Columns: []string{"measurement", "tag_key", "values", "series"},
+			Values: [][]interface{}{
+				{"cpu", "host", int64(4), int64(4)},
+				{"mem", "host", int64(1), int64(1)},
+			},
+		},
+		{
+			Name:    "growth",
+			Columns: []string{"time", "retention_policy", "measurement", "series", "new_series"},
+			Values: [][]interface{}{
+				{day(1), "rp0", "cpu", uint64(2), uint64(2)},
+				{day(1), "rp0", "mem", uint64(1), uint64(1)},
+				{day(2), "rp0", "cpu", uint64(3), uint64(2)},
+			},
+		},
+	}
+	if got := results[0].Series; !reflect.DeepEqual(got, models.Rows(exp)) {
+		t.Fatalf("unexpected rows: exp %s, got %s", spew.Sdump(exp), spew.Sdump(got))
+	}
+}
+
+// Ensure the cardinality report doesn't divide by zero when the measurements
+// have no series left.
+func TestQueryExecutor_ExecuteQuery_ShowCardinalityReport_NoSeries(t *testing.T) {
+	qe := query.NewExecutor()
+	qe.StatementExecutor = &coordinator.StatementExecutor{
+		MetaClient: &internal.MetaClientMock{
+			DatabaseFn: func(name string) *meta.DatabaseInfo {
+				return &meta.DatabaseInfo{
+					Name: name,
+					RetentionPolicies: []meta.RetentionPolicyInfo{{
+						Name: "rp0",
+						ShardGroups: []meta.ShardGroupInfo{
+							{ID: 1, StartTime: time.Unix(0, 0), EndTime: time.Unix(3600, 0), Shards: []meta.ShardInfo{{ID: 1}}},
+						},
+					}},
+				}
+			},
+		},
+		TSDBStore: &internal.TSDBStoreMock{
+			MeasurementSeriesIDSetsFn: func(shardIDs []uint64) (map[string]*tsdb.SeriesIDSet, error) {
+				return map[string]*tsdb.SeriesIDSet{"cpu": tsdb.NewSeriesIDSet()}, nil
+			},
+			SeriesSketchesFn: func(database string) (estimator.Sketch, estimator.Sketch, error) {
+				return hll.NewDefaultPlus(), hll.NewDefaultPlus(), nil
+			},
+			TagKeyCardinalitiesFn: func(shardIDs []uint64, name []byte) ([]tsdb.TagKeyCardinality, error) {
+				return nil, nil
+			},
+		},
+	}
+
+	q := MustParseQuery(`SHOW CARDINALITY REPORT ON db0`)
+	results := ReadAllResults(qe.ExecuteQuery(q, query.ExecutionOptions{}, make(chan struct{})))
+	if len(results) != 1 || results[0].Err != nil {
+		t.Fatalf("unexpected results: %s", spew.Sdump(results))
+	}
+
+	if got, exp := results[0].Series[1].Values, [][]interface{}{{"cpu", uint64(0), float64(0), uint64(0), float64(0)}}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected measurements: exp %s, got %s", spew.Sdump(exp), spew.Sdump(got))
+	}
+}
+
+// Ensure REPAIR SHARD reports the series and time ranges that were lost.
+func TestQueryExecutor_ExecuteQuery_RepairShard(t *testing.T) {
+	qe := query.NewExecutor()
+	qe.StatementExecutor = &coordinator.StatementExecutor{
+		MetaClient: &internal.MetaClientMock{},
+		TSDBStore: &internal.TSDBStoreMock{
+			RepairShardFn: func(id uint64) ([]tsdb.CorruptBlock, error) {
+				if id != 3 {
+					t.Fatalf("unexpected shard: %d", id)
+				}
+				return []tsdb.CorruptBlock{{
+					Path:    "/var/lib/influxdb/data/db0/rp0/3/000000002-000000001.tsm",
+					Offset:  5,
+					Series:  []byte("cpu,host=A"),
+					Field:   []byte("value"),
+					MinTime: 1000000000,
+					MaxTime: 2000000000,
+				}}, nil
+			},
+		},
+	}
+
+	q := MustParseQuery(`REPAIR SHARD 3`)
+	results := ReadAllResults(qe.ExecuteQuery(q, query.ExecutionOptions{}, make(chan struct{})))
+	if len(results) != 1 || results[0].Err != nil {
+		t.Fatalf("unexpected results: %s", spew.Sdump(results))
+	}
+
+	exp := []*models.Row{{
+		Columns: []string{"series", "field", "min_time", "max_time", "file"},
+		Values: [][]interface{}{
+			{"cpu,host=A", "value", "1970-01-01T00:00:01Z", "1970-01-01T00:00:02Z", "000000002-000000001.tsm"},
+		},
+	}}
+	if !reflect.DeepEqual(results[0].Series, models.Rows(exp)) {
+		t.Fatalf("unexpected rows: %s", spew.Sdump(results[0].Series))
+	}
+}
+
+// StatementAuditor records the statements audited by a statement executor.
+type StatementAuditor struct {
+	Statements []string
+}
+
+func (a *StatementAuditor) AuditStatement(stmt influxql.Statement, user, database string, err error) {
+	a.Statements = append(a.Statements, fmt.Sprintf("%s: %s: %v", user, stmt, err))
+}
+
 // QueryExecutor is a test wrapper for coordinator.QueryExecutor.
 type QueryExecutor struct {
 	*query.Executor
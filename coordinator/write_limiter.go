@@ -0,0 +1,208 @@
+package coordinator
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+)
+
+// WriteLimits holds the rates at which points may be written. A zero rate is
+// unlimited.
+type WriteLimits struct {
+	PointsPerSecond int64
+	BytesPerSecond  int64
+}
+
+func (l WriteLimits) unlimited() bool {
+	return l.PointsPerSecond <= 0 && l.BytesPerSecond <= 0
+}
+
+// writeBucketsIdleTime is how long the buckets of a user or of a database are
+// kept after their last write. Buckets idle for more than a second are full,
+// so dropping them doesn't change the rates enforced.
+const writeBucketsIdleTime = time.Minute
+
+// WriteLimiter limits the rate at which each user and each database writes
+// points. Rates are enforced with token buckets holding up to one second of
+// their rate. A write larger than a bucket is admitted once the bucket is full.
+type WriteLimiter struct {
+	// Default limits of each user and of each database.
+	UserLimits     WriteLimits
+	DatabaseLimits WriteLimits
+
+	// Limits of individual databases replacing the default database limits.
+	// Optional. Must not be modified once the limiter is in use.
+	Databases map[string]WriteLimits
+
+	mu        sync.Mutex
+	buckets   map[writeLimitKey]*writeBuckets
+	lastEvict time.Time
+
+	// now returns the current time. Used for testing.
+	now func() time.Time
+}
+
+type writeLimitKey struct {
+	kind, name string
+}
+
+type writeBuckets struct {
+	points tokenBucket
+	bytes  tokenBucket
+
+	// Time of the last write limited by the buckets.
+	last time.Time
+}
+
+// NewWriteLimiter returns a new instance of WriteLimiter.
+func NewWriteLimiter(userLimits, databaseLimits WriteLimits) *WriteLimiter {
+	return &WriteLimiter{
+		UserLimits:     userLimits,
+		DatabaseLimits: databaseLimits,
+		buckets:        make(map[writeLimitKey]*writeBuckets),
+		now:            time.Now,
+	}
+}
+
+// databaseLimits returns the limits of database.
+func (l *WriteLimiter) databaseLimits(database string) WriteLimits {
+	if limits, ok := l.Databases[database]; ok {
+		return limits
+	}
+	return l.DatabaseLimits
+}
+
+// Limit returns an influxdb.RateLimitError if writing points would exceed the
+// rate of user or of database. No tokens are taken from any bucket unless all
+// of them admit the write. An empty user has no limits.
+func (l *WriteLimiter) Limit(user, database string, points []models.Point) error {
+	type limited struct {
+		key    writeLimitKey
+		limits WriteLimits
+	}
+	var targets []limited
+	if user != "" && !l.UserLimits.unlimited() {
+		targets = append(targets, limited{writeLimitKey{"user", user}, l.UserLimits})
+	}
+	if limits := l.databaseLimits(database); !limits.unlimited() {
+		targets = append(targets, limited{writeLimitKey{"database", database}, limits})
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	// Only compute the size of the points if it's limited.
+	pointN, byteN := float64(len(points)), float64(0)
+	for _, t := range targets {
+		if t.limits.BytesPerSecond > 0 {
+			for _, p := range points {
+				byteN += float64(p.StringSize() + 1)
+			}
+			break
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.evict(now)
+	for _, t := range targets {
+		b := l.buckets[t.key]
+		if b == nil {
+			b = &writeBuckets{}
+			l.buckets[t.key] = b
+		}
+		b.points.refill(t.limits.PointsPerSecond, now)
+		b.bytes.refill(t.limits.BytesPerSecond, now)
+		b.last = now
+
+		if d := b.points.wait(t.limits.PointsPerSecond, pointN); d > 0 {
+			return influxdb.RateLimitError{
+				Reason:     fmt.Sprintf("%s %q writes more than %d points per second", t.key.kind, t.key.name, t.limits.PointsPerSecond),
+				RetryAfter: d,
+			}
+		}
+		if d := b.bytes.wait(t.limits.BytesPerSecond, byteN); d > 0 {
+			return influxdb.RateLimitError{
+				Reason:     fmt.Sprintf("%s %q writes more than %d bytes per second", t.key.kind, t.key.name, t.limits.BytesPerSecond),
+				RetryAfter: d,
+			}
+		}
+	}
+
+	for _, t := range targets {
+		b := l.buckets[t.key]
+		b.points.take(t.limits.PointsPerSecond, pointN)
+		b.bytes.take(t.limits.BytesPerSecond, byteN)
+	}
+	return nil
+}
+
+// evict drops the buckets which haven't limited a write for
+// writeBucketsIdleTime. The buckets are swept at most once every
+// writeBucketsIdleTime. l.mu must be held.
+func (l *WriteLimiter) evict(now time.Time) {
+	if now.Sub(l.lastEvict) < writeBucketsIdleTime {
+		return
+	}
+	l.lastEvict = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= writeBucketsIdleTime {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// tokenBucket holds tokens accumulating at a rate per second up to the rate.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens accumulated since the last refill. A new bucket is
+// full.
+func (b *tokenBucket) refill(rate int64, now time.Time) {
+	if rate <= 0 {
+		return
+	}
+	if b.last.IsZero() {
+		b.tokens = float64(rate)
+	} else if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * float64(rate)
+		if b.tokens > float64(rate) {
+			b.tokens = float64(rate)
+		}
+	}
+	b.last = now
+}
+
+// wait returns how long until n tokens may be taken from the bucket.
+func (b *tokenBucket) wait(rate int64, n float64) time.Duration {
+	if rate <= 0 {
+		return 0
+	}
+	if n > float64(rate) {
+		n = float64(rate)
+	}
+	if b.tokens >= n {
+		return 0
+	}
+	return time.Duration((n - b.tokens) / float64(rate) * float64(time.Second))
+}
+
+// take removes n tokens from the bucket. A write larger than the bucket
+// empties it.
+func (b *tokenBucket) take(rate int64, n float64) {
+	if rate <= 0 {
+		return
+	}
+	if n > b.tokens {
+		n = b.tokens
+	}
+	b.tokens -= n
+}
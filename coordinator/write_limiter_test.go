@@ -0,0 +1,154 @@
+package coordinator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+)
+
+func TestWriteLimiter_Limit(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewWriteLimiter(WriteLimits{PointsPerSecond: 10}, WriteLimits{PointsPerSecond: 15})
+	l.now = func() time.Time { return now }
+
+	points := make([]models.Point, 5)
+
+	// The user bucket allows two writes of 5 points.
+	for i := 0; i < 2; i++ {
+		if err := l.Limit("bob", "db0", points); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := l.Limit("bob", "db0", points)
+	if e, ok := err.(influxdb.RateLimitError); !ok {
+		t.Fatalf("unexpected error: %v", err)
+	} else if e.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("unexpected retry after: %s", e.RetryAfter)
+	}
+
+	// The database bucket still has 5 points, which were not taken by the
+	// rejected write.
+	if err := l.Limit("alice", "db0", points); err != nil {
+		t.Fatal(err)
+	} else if err := l.Limit("alice", "db0", points); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// Writes without a user are only limited by the database.
+	if err := l.Limit("", "db1", make([]models.Point, 15)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The buckets refill over time.
+	now = now.Add(time.Second)
+	if err := l.Limit("bob", "db0", points); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteLimiter_Limit_LargeWrite(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewWriteLimiter(WriteLimits{}, WriteLimits{PointsPerSecond: 10})
+	l.now = func() time.Time { return now }
+
+	// A write larger than the bucket is admitted when the bucket is full.
+	if err := l.Limit("", "db0", make([]models.Point, 25)); err != nil {
+		t.Fatal(err)
+	} else if err := l.Limit("", "db0", make([]models.Point, 1)); err == nil {
+		t.Fatal("expected error")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if err := l.Limit("", "db0", make([]models.Point, 25)); err == nil {
+		t.Fatal("expected error")
+	}
+	now = now.Add(500 * time.Millisecond)
+	if err := l.Limit("", "db0", make([]models.Point, 25)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteLimiter_Limit_Bytes(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewWriteLimiter(WriteLimits{}, WriteLimits{BytesPerSecond: 148})
+	l.now = func() time.Time { return now }
+
+	// Each point is 37 bytes of line protocol including the newline.
+	pt := models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "server01"}), models.Fields{"value": 1.0}, time.Unix(1, 0))
+	if n := pt.StringSize() + 1; n != 37 {
+		t.Fatalf("unexpected point size: %d", n)
+	}
+
+	if err := l.Limit("", "db0", []models.Point{pt, pt, pt}); err != nil {
+		t.Fatal(err)
+	} else if err := l.Limit("", "db0", []models.Point{pt}); err != nil {
+		t.Fatal(err)
+	}
+	err := l.Limit("", "db0", []models.Point{pt})
+	if e, ok := err.(influxdb.RateLimitError); !ok {
+		t.Fatalf("unexpected error: %v", err)
+	} else if e.RetryAfter != 250*time.Millisecond {
+		t.Fatalf("unexpected retry after: %s", e.RetryAfter)
+	}
+}
+
+func TestWriteLimiter_Limit_Databases(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewWriteLimiter(WriteLimits{}, WriteLimits{PointsPerSecond: 10})
+	l.Databases = map[string]WriteLimits{
+		"db0": {PointsPerSecond: 2},
+		"db1": {},
+	}
+	l.now = func() time.Time { return now }
+
+	// The database has its own limit.
+	if err := l.Limit("", "db0", make([]models.Point, 2)); err != nil {
+		t.Fatal(err)
+	}
+	err := l.Limit("", "db0", make([]models.Point, 1))
+	if e, ok := err.(influxdb.RateLimitError); !ok || e.Reason != `database "db0" writes more than 2 points per second` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// An empty override makes the database unlimited.
+	if err := l.Limit("", "db1", make([]models.Point, 100)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Other databases have the default limit.
+	if err := l.Limit("", "db2", make([]models.Point, 10)); err != nil {
+		t.Fatal(err)
+	} else if err := l.Limit("", "db2", make([]models.Point, 1)); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestWriteLimiter_Evict(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewWriteLimiter(WriteLimits{PointsPerSecond: 10}, WriteLimits{PointsPerSecond: 10})
+	l.now = func() time.Time { return now }
+
+	for _, db := range []string{"db0", "db1"} {
+		if err := l.Limit("bob", db, make([]models.Point, 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(l.buckets); n != 3 {
+		t.Fatalf("unexpected number of buckets: %d", n)
+	}
+
+	// Buckets written to within the idle time are kept.
+	now = now.Add(writeBucketsIdleTime - time.Second)
+	if err := l.Limit("", "db0", make([]models.Point, 1)); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Second)
+	if err := l.Limit("", "db0", make([]models.Point, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := l.buckets[writeLimitKey{"database", "db0"}]; !ok || len(l.buckets) != 1 {
+		t.Fatalf("unexpected buckets: %v", l.buckets)
+	}
+}
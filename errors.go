@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 )
 
 // ErrFieldTypeConflict is returned when a new field already exists with a
@@ -28,6 +29,20 @@ func IsAuthorizationError(err error) bool {
 	return ok && e.AuthorizationFailed()
 }
 
+// RateLimitError is returned when a write is rejected because it exceeds a
+// rate limit.
+type RateLimitError struct {
+	Reason string
+
+	// How long until the write may succeed.
+	RetryAfter time.Duration
+}
+
+// Error returns the string representation of the error.
+func (e RateLimitError) Error() string {
+	return fmt.Sprintf("rate limit exceeded: %s, retry after %s", e.Reason, e.RetryAfter)
+}
+
 // IsClientError indicates whether an error is a known client error.
 func IsClientError(err error) bool {
 	if err == nil {
@@ -13,9 +13,13 @@ type MetaClientMock struct {
 	CreateContinuousQueryFn             func(database, name, query string) error
 	CreateDatabaseFn                    func(name string) (*meta.DatabaseInfo, error)
 	CreateDatabaseWithRetentionPolicyFn func(name string, spec *meta.RetentionPolicySpec) (*meta.DatabaseInfo, error)
+	CreateDownsampleFn                  func(database, policy string, ds *meta.DownsampleInfo) error
 	CreateRetentionPolicyFn             func(database string, spec *meta.RetentionPolicySpec, makeDefault bool) (*meta.RetentionPolicyInfo, error)
+	CreateRetentionRuleFn               func(database, policy string, rule *meta.RetentionRuleInfo) error
+	CreateRoleFn                        func(name string) error
 	CreateShardGroupFn                  func(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error)
 	CreateSubscriptionFn                func(database, rp, name, mode string, destinations []string) error
+	CreateTokenFn                       func(name, username string) (string, error)
 	CreateUserFn                        func(name, password string, admin bool) (meta.User, error)
 
 	DatabaseFn  func(name string) *meta.DatabaseInfo
@@ -25,11 +29,29 @@ type MetaClientMock struct {
 	DeleteShardGroupFn    func(database string, policy string, id uint64) error
 	DropContinuousQueryFn func(database, name string) error
 	DropDatabaseFn        func(name string) error
+	DropDownsampleFn      func(database, policy, name string) error
+	DropQuotaFn           func(kind, name string) error
 	DropRetentionPolicyFn func(database, name string) error
+	DropRetentionRuleFn   func(database, policy, name string) error
+	DropRoleFn            func(name string) error
 	DropSubscriptionFn    func(database, rp, name string) error
 	DropShardFn           func(id uint64) error
+	DropTokenFn           func(name string) error
 	DropUserFn            func(name string) error
 
+	GrantRoleFn             func(role, username string) error
+	GrantRoleMeasurementFn  func(role string, gi *meta.GrantInfo) error
+	GrantUserMeasurementFn  func(username string, gi *meta.GrantInfo) error
+	RevokeRoleFn            func(role, username string) error
+	RevokeRoleMeasurementFn func(role string, gi *meta.GrantInfo) error
+	RevokeUserMeasurementFn func(username string, gi *meta.GrantInfo) error
+	RoleFn                  func(name string) (*meta.RoleInfo, error)
+	RolesFn                 func() []meta.RoleInfo
+	TokensFn                func() []meta.TokenInfo
+	AuthenticateTokenFn     func(token string) (meta.User, error)
+	QuotasFn                func() []meta.QuotaInfo
+	SetQuotaFn              func(qi meta.QuotaInfo) error
+
 	OpenFn func() error
 
 	PrecreateShardGroupsFn func(from, to time.Time) error
@@ -41,6 +63,7 @@ type MetaClientMock struct {
 	AdminUserExistsFn        func() bool
 	SetAdminPrivilegeFn      func(username string, admin bool) error
 	SetDataFn                func(*meta.Data) error
+	SetDownsampleProgressFn  func(database, policy, name string, shardGroupID uint64, t time.Time) error
 	SetPrivilegeFn           func(username, database string, p influxql.Privilege) error
 	ShardGroupsByTimeRangeFn func(database, policy string, min, max time.Time) (a []meta.ShardGroupInfo, err error)
 	ShardOwnerFn             func(shardID uint64) (database, policy string, sgi *meta.ShardGroupInfo)
@@ -73,6 +96,18 @@ func (c *MetaClientMock) CreateRetentionPolicy(database string, spec *meta.Reten
 	return c.CreateRetentionPolicyFn(database, spec, makeDefault)
 }
 
+func (c *MetaClientMock) CreateDownsample(database, policy string, ds *meta.DownsampleInfo) error {
+	return c.CreateDownsampleFn(database, policy, ds)
+}
+
+func (c *MetaClientMock) CreateRetentionRule(database, policy string, rule *meta.RetentionRuleInfo) error {
+	return c.CreateRetentionRuleFn(database, policy, rule)
+}
+
+func (c *MetaClientMock) CreateRole(name string) error {
+	return c.CreateRoleFn(name)
+}
+
 func (c *MetaClientMock) CreateShardGroup(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error) {
 	return c.CreateShardGroupFn(database, policy, timestamp)
 }
@@ -81,6 +116,10 @@ func (c *MetaClientMock) CreateSubscription(database, rp, name, mode string, des
 	return c.CreateSubscriptionFn(database, rp, name, mode, destinations)
 }
 
+func (c *MetaClientMock) CreateToken(name, username string) (string, error) {
+	return c.CreateTokenFn(name, username)
+}
+
 func (c *MetaClientMock) CreateUser(name, password string, admin bool) (meta.User, error) {
 	return c.CreateUserFn(name, password, admin)
 }
@@ -109,6 +148,22 @@ func (c *MetaClientMock) DropRetentionPolicy(database, name string) error {
 	return c.DropRetentionPolicyFn(database, name)
 }
 
+func (c *MetaClientMock) DropDownsample(database, policy, name string) error {
+	return c.DropDownsampleFn(database, policy, name)
+}
+
+func (c *MetaClientMock) DropQuota(kind, name string) error {
+	return c.DropQuotaFn(kind, name)
+}
+
+func (c *MetaClientMock) DropRetentionRule(database, policy, name string) error {
+	return c.DropRetentionRuleFn(database, policy, name)
+}
+
+func (c *MetaClientMock) DropRole(name string) error {
+	return c.DropRoleFn(name)
+}
+
 func (c *MetaClientMock) DropShard(id uint64) error {
 	return c.DropShardFn(id)
 }
@@ -117,10 +172,58 @@ func (c *MetaClientMock) DropSubscription(database, rp, name string) error {
 	return c.DropSubscriptionFn(database, rp, name)
 }
 
+func (c *MetaClientMock) DropToken(name string) error {
+	return c.DropTokenFn(name)
+}
+
 func (c *MetaClientMock) DropUser(name string) error {
 	return c.DropUserFn(name)
 }
 
+func (c *MetaClientMock) GrantRole(role, username string) error {
+	return c.GrantRoleFn(role, username)
+}
+
+func (c *MetaClientMock) GrantRoleMeasurement(role string, gi *meta.GrantInfo) error {
+	return c.GrantRoleMeasurementFn(role, gi)
+}
+
+func (c *MetaClientMock) GrantUserMeasurement(username string, gi *meta.GrantInfo) error {
+	return c.GrantUserMeasurementFn(username, gi)
+}
+
+func (c *MetaClientMock) RevokeRole(role, username string) error {
+	return c.RevokeRoleFn(role, username)
+}
+
+func (c *MetaClientMock) RevokeRoleMeasurement(role string, gi *meta.GrantInfo) error {
+	return c.RevokeRoleMeasurementFn(role, gi)
+}
+
+func (c *MetaClientMock) RevokeUserMeasurement(username string, gi *meta.GrantInfo) error {
+	return c.RevokeUserMeasurementFn(username, gi)
+}
+
+func (c *MetaClientMock) Role(name string) (*meta.RoleInfo, error) {
+	return c.RoleFn(name)
+}
+
+func (c *MetaClientMock) Roles() []meta.RoleInfo {
+	return c.RolesFn()
+}
+
+func (c *MetaClientMock) Tokens() []meta.TokenInfo {
+	return c.TokensFn()
+}
+
+func (c *MetaClientMock) Quotas() []meta.QuotaInfo {
+	return c.QuotasFn()
+}
+
+func (c *MetaClientMock) SetQuota(qi meta.QuotaInfo) error {
+	return c.SetQuotaFn(qi)
+}
+
 func (c *MetaClientMock) RetentionPolicy(database, name string) (rpi *meta.RetentionPolicyInfo, err error) {
 	return c.RetentionPolicyFn(database, name)
 }
@@ -164,13 +267,21 @@ func (c *MetaClientMock) UserPrivileges(username string) (map[string]influxql.Pr
 func (c *MetaClientMock) Authenticate(username, password string) (meta.User, error) {
 	return c.AuthenticateFn(username, password)
 }
+
+func (c *MetaClientMock) AuthenticateToken(token string) (meta.User, error) {
+	return c.AuthenticateTokenFn(token)
+}
 func (c *MetaClientMock) AdminUserExists() bool { return c.AdminUserExistsFn() }
 
 func (c *MetaClientMock) User(username string) (meta.User, error) { return c.UserFn(username) }
 func (c *MetaClientMock) Users() []meta.UserInfo                  { return c.UsersFn() }
 
-func (c *MetaClientMock) Open() error                { return c.OpenFn() }
-func (c *MetaClientMock) Data() meta.Data            { return c.DataFn() }
+func (c *MetaClientMock) Open() error     { return c.OpenFn() }
+func (c *MetaClientMock) Data() meta.Data { return c.DataFn() }
+func (c *MetaClientMock) SetDownsampleProgress(database, policy, name string, shardGroupID uint64, t time.Time) error {
+	return c.SetDownsampleProgressFn(database, policy, name, shardGroupID, t)
+}
+
 func (c *MetaClientMock) SetData(d *meta.Data) error { return c.SetDataFn(d) }
 
 func (c *MetaClientMock) PrecreateShardGroups(from, to time.Time) error {
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/estimator"
 	"github.com/influxdata/influxdb/query"
 	"github.com/influxdata/influxdb/tsdb"
 	"github.com/influxdata/influxql"
@@ -24,29 +25,37 @@ type TSDBStoreMock struct {
 	DeleteMeasurementFn       func(database, name string) error
 	DeleteRetentionPolicyFn   func(database, name string) error
 	DeleteSeriesFn            func(database string, sources []influxql.Source, condition influxql.Expr) error
+	DeleteSeriesInShardsFn    func(database string, shardIDs []uint64, sources []influxql.Source, condition influxql.Expr) error
 	DeleteShardFn             func(id uint64) error
 	DiskSizeFn                func() (int64, error)
 	ExpandSourcesFn           func(sources influxql.Sources) (influxql.Sources, error)
 	ImportShardFn             func(id uint64, r io.Reader) error
 	MeasurementSeriesCountsFn func(database string) (measuments int, series int)
+	MeasurementSeriesIDSetsFn func(shardIDs []uint64) (map[string]*tsdb.SeriesIDSet, error)
 	MeasurementsCardinalityFn func(database string) (int64, error)
 	MeasurementNamesFn        func(auth query.Authorizer, database string, cond influxql.Expr) ([][]byte, error)
+	MoveShardToColdTierFn     func(id uint64) error
 	OpenFn                    func() error
 	PathFn                    func() string
+	RepairShardFn             func(id uint64) ([]tsdb.CorruptBlock, error)
 	RestoreShardFn            func(id uint64, r io.Reader) error
 	SeriesCardinalityFn       func(database string) (int64, error)
+	SeriesSketchesFn          func(database string) (estimator.Sketch, estimator.Sketch, error)
 	SetShardEnabledFn         func(shardID uint64, enabled bool) error
 	ShardFn                   func(id uint64) *tsdb.Shard
 	ShardGroupFn              func(ids []uint64) tsdb.ShardGroup
 	ShardIDsFn                func() []uint64
 	ShardNFn                  func() int
 	ShardRelativePathFn       func(id uint64) (string, error)
+	ShardTierFn               func(id uint64) string
 	ShardsFn                  func(ids []uint64) []*tsdb.Shard
 	StatisticsFn              func(tags map[string]string) []models.Statistic
+	TagKeyCardinalitiesFn     func(shardIDs []uint64, name []byte) ([]tsdb.TagKeyCardinality, error)
 	TagKeysFn                 func(auth query.Authorizer, shardIDs []uint64, cond influxql.Expr) ([]tsdb.TagKeys, error)
 	TagValuesFn               func(auth query.Authorizer, shardIDs []uint64, cond influxql.Expr) ([]tsdb.TagValues, error)
 	WithLoggerFn              func(log *zap.Logger)
 	WriteToShardFn            func(shardID uint64, points []models.Point) error
+	WriteToShardBulkFn        func(shardID uint64, points []models.Point) error
 }
 
 func (s *TSDBStoreMock) BackupShard(id uint64, since time.Time, w io.Writer) error {
@@ -80,6 +89,9 @@ func (s *TSDBStoreMock) DeleteRetentionPolicy(database string, name string) erro
 func (s *TSDBStoreMock) DeleteSeries(database string, sources []influxql.Source, condition influxql.Expr) error {
 	return s.DeleteSeriesFn(database, sources, condition)
 }
+func (s *TSDBStoreMock) DeleteSeriesInShards(database string, shardIDs []uint64, sources []influxql.Source, condition influxql.Expr) error {
+	return s.DeleteSeriesInShardsFn(database, shardIDs, sources, condition)
+}
 func (s *TSDBStoreMock) DeleteShard(shardID uint64) error {
 	return s.DeleteShardFn(shardID)
 }
@@ -95,9 +107,15 @@ func (s *TSDBStoreMock) ImportShard(id uint64, r io.Reader) error {
 func (s *TSDBStoreMock) MeasurementNames(auth query.Authorizer, database string, cond influxql.Expr) ([][]byte, error) {
 	return s.MeasurementNamesFn(auth, database, cond)
 }
+func (s *TSDBStoreMock) MoveShardToColdTier(id uint64) error {
+	return s.MoveShardToColdTierFn(id)
+}
 func (s *TSDBStoreMock) MeasurementSeriesCounts(database string) (measuments int, series int) {
 	return s.MeasurementSeriesCountsFn(database)
 }
+func (s *TSDBStoreMock) MeasurementSeriesIDSets(shardIDs []uint64) (map[string]*tsdb.SeriesIDSet, error) {
+	return s.MeasurementSeriesIDSetsFn(shardIDs)
+}
 func (s *TSDBStoreMock) MeasurementsCardinality(database string) (int64, error) {
 	return s.MeasurementsCardinalityFn(database)
 }
@@ -107,12 +125,18 @@ func (s *TSDBStoreMock) Open() error {
 func (s *TSDBStoreMock) Path() string {
 	return s.PathFn()
 }
+func (s *TSDBStoreMock) RepairShard(id uint64) ([]tsdb.CorruptBlock, error) {
+	return s.RepairShardFn(id)
+}
 func (s *TSDBStoreMock) RestoreShard(id uint64, r io.Reader) error {
 	return s.RestoreShardFn(id, r)
 }
 func (s *TSDBStoreMock) SeriesCardinality(database string) (int64, error) {
 	return s.SeriesCardinalityFn(database)
 }
+func (s *TSDBStoreMock) SeriesSketches(database string) (estimator.Sketch, estimator.Sketch, error) {
+	return s.SeriesSketchesFn(database)
+}
 func (s *TSDBStoreMock) SetShardEnabled(shardID uint64, enabled bool) error {
 	return s.SetShardEnabledFn(shardID, enabled)
 }
@@ -131,12 +155,18 @@ func (s *TSDBStoreMock) ShardN() int {
 func (s *TSDBStoreMock) ShardRelativePath(id uint64) (string, error) {
 	return s.ShardRelativePathFn(id)
 }
+func (s *TSDBStoreMock) ShardTier(id uint64) string {
+	return s.ShardTierFn(id)
+}
 func (s *TSDBStoreMock) Shards(ids []uint64) []*tsdb.Shard {
 	return s.ShardsFn(ids)
 }
 func (s *TSDBStoreMock) Statistics(tags map[string]string) []models.Statistic {
 	return s.StatisticsFn(tags)
 }
+func (s *TSDBStoreMock) TagKeyCardinalities(shardIDs []uint64, name []byte) ([]tsdb.TagKeyCardinality, error) {
+	return s.TagKeyCardinalitiesFn(shardIDs, name)
+}
 func (s *TSDBStoreMock) TagKeys(auth query.Authorizer, shardIDs []uint64, cond influxql.Expr) ([]tsdb.TagKeys, error) {
 	return s.TagKeysFn(auth, shardIDs, cond)
 }
@@ -149,3 +179,6 @@ func (s *TSDBStoreMock) WithLogger(log *zap.Logger) {
 func (s *TSDBStoreMock) WriteToShard(shardID uint64, points []models.Point) error {
 	return s.WriteToShardFn(shardID, points)
 }
+func (s *TSDBStoreMock) WriteToShardBulk(shardID uint64, points []models.Point) error {
+	return s.WriteToShardBulkFn(shardID, points)
+}
@@ -0,0 +1,164 @@
+package arrowipc
+
+import "encoding/binary"
+
+// builder builds a flatbuffer back to front, the same way as the reference
+// implementation. Objects must be built before the objects referencing them
+// and only one table can be built at a time.
+type builder struct {
+	buf      []byte
+	head     int
+	minAlign int
+
+	vtable    []int
+	objectEnd int
+}
+
+func newBuilder(size int) *builder {
+	return &builder{buf: make([]byte, size), head: size, minAlign: 1}
+}
+
+// offset returns the number of bytes written so far, which identifies the
+// last object written.
+func (b *builder) offset() int { return len(b.buf) - b.head }
+
+// grow doubles the size of the buffer, keeping the data at its end.
+func (b *builder) grow() {
+	size := 2 * len(b.buf)
+	if size == 0 {
+		size = 64
+	}
+	buf := make([]byte, size)
+	copy(buf[size-b.offset():], b.buf[b.head:])
+	b.head += size - len(b.buf)
+	b.buf = buf
+}
+
+// prep pads the buffer so that size bytes can be written aligned to size
+// after additional bytes are written.
+func (b *builder) prep(size, additional int) {
+	if size > b.minAlign {
+		b.minAlign = size
+	}
+	pad := (^(b.offset() + additional) + 1) & (size - 1)
+	for b.head < pad+size+additional {
+		b.grow()
+	}
+	for i := 0; i < pad; i++ {
+		b.head--
+		b.buf[b.head] = 0
+	}
+}
+
+func (b *builder) placeUint8(v uint8) {
+	b.head--
+	b.buf[b.head] = v
+}
+
+func (b *builder) placeUint16(v uint16) {
+	b.head -= 2
+	binary.LittleEndian.PutUint16(b.buf[b.head:], v)
+}
+
+func (b *builder) placeUint32(v uint32) {
+	b.head -= 4
+	binary.LittleEndian.PutUint32(b.buf[b.head:], v)
+}
+
+func (b *builder) placeUint64(v uint64) {
+	b.head -= 8
+	binary.LittleEndian.PutUint64(b.buf[b.head:], v)
+}
+
+func (b *builder) prependBool(v bool) {
+	b.prep(1, 0)
+	if v {
+		b.placeUint8(1)
+	} else {
+		b.placeUint8(0)
+	}
+}
+
+func (b *builder) prependUint8(v uint8)   { b.prep(1, 0); b.placeUint8(v) }
+func (b *builder) prependInt16(v int16)   { b.prep(2, 0); b.placeUint16(uint16(v)) }
+func (b *builder) prependUint16(v uint16) { b.prep(2, 0); b.placeUint16(v) }
+func (b *builder) prependInt32(v int32)   { b.prep(4, 0); b.placeUint32(uint32(v)) }
+func (b *builder) prependInt64(v int64)   { b.prep(8, 0); b.placeUint64(uint64(v)) }
+
+// prependOffset writes a reference to a previously written object.
+func (b *builder) prependOffset(off int) {
+	b.prep(4, 0)
+	b.placeUint32(uint32(b.offset() - off + 4))
+}
+
+// startVector prepares the buffer for n elements of elemSize bytes, which
+// must then be prepended in reverse order.
+func (b *builder) startVector(elemSize, n, align int) {
+	b.prep(4, elemSize*n)
+	b.prep(align, elemSize*n)
+}
+
+func (b *builder) endVector(n int) int {
+	b.placeUint32(uint32(n))
+	return b.offset()
+}
+
+func (b *builder) createString(s string) int {
+	b.prep(4, len(s)+1)
+	b.placeUint8(0)
+	b.head -= len(s)
+	copy(b.buf[b.head:], s)
+	return b.endVector(len(s))
+}
+
+// createOffsetVector writes a vector referencing previously written objects.
+func (b *builder) createOffsetVector(offs []int) int {
+	b.startVector(4, len(offs), 4)
+	for i := len(offs) - 1; i >= 0; i-- {
+		b.prependOffset(offs[i])
+	}
+	return b.endVector(len(offs))
+}
+
+func (b *builder) startTable(numFields int) {
+	b.vtable = make([]int, numFields)
+	b.objectEnd = b.offset()
+}
+
+// slot records that the last value written is the field i of the table.
+func (b *builder) slot(i int) { b.vtable[i] = b.offset() }
+
+func (b *builder) addBool(i int, v bool)    { b.prependBool(v); b.slot(i) }
+func (b *builder) addUint8(i int, v uint8)  { b.prependUint8(v); b.slot(i) }
+func (b *builder) addInt16(i int, v int16)  { b.prependInt16(v); b.slot(i) }
+func (b *builder) addInt32(i int, v int32)  { b.prependInt32(v); b.slot(i) }
+func (b *builder) addInt64(i int, v int64)  { b.prependInt64(v); b.slot(i) }
+func (b *builder) addOffset(i int, off int) { b.prependOffset(off); b.slot(i) }
+
+// endTable writes the vtable of the table and returns the table.
+func (b *builder) endTable() int {
+	b.prependInt32(0)
+	object := b.offset()
+
+	for i := len(b.vtable) - 1; i >= 0; i-- {
+		var off uint16
+		if b.vtable[i] != 0 {
+			off = uint16(object - b.vtable[i])
+		}
+		b.prependUint16(off)
+	}
+	b.prependUint16(uint16(object - b.objectEnd))
+	b.prependUint16(uint16((len(b.vtable) + 2) * 2))
+
+	// Point the table to its vtable, which precedes it.
+	binary.LittleEndian.PutUint32(b.buf[len(b.buf)-object:], uint32(b.offset()-object))
+	b.vtable = nil
+	return object
+}
+
+// finish writes the reference to the root table and returns the flatbuffer.
+func (b *builder) finish(root int) []byte {
+	b.prep(b.minAlign, 4)
+	b.prependOffset(root)
+	return b.buf[b.head:]
+}
@@ -0,0 +1,507 @@
+// Package arrowipc writes record batches in the Apache Arrow IPC streaming
+// format.
+//
+// Only the subset of the format needed to export query results is supported:
+// 64-bit integers, floats, booleans, UTF-8 strings, optionally dictionary
+// encoded, timestamps and null columns.
+package arrowipc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Type is the type of the values of a field.
+type Type int
+
+const (
+	// Null fields only contain nulls.
+	Null Type = iota
+	Int64
+	Uint64
+	Float64
+	Bool
+	String
+	// Timestamp fields contain int64 values in the unit of the field.
+	Timestamp
+)
+
+// String returns the name of the type.
+func (t Type) String() string {
+	switch t {
+	case Null:
+		return "null"
+	case Int64:
+		return "int64"
+	case Uint64:
+		return "uint64"
+	case Float64:
+		return "float64"
+	case Bool:
+		return "bool"
+	case String:
+		return "string"
+	case Timestamp:
+		return "timestamp"
+	}
+	return "unknown"
+}
+
+// TimeUnit is the unit of the values of a timestamp field.
+type TimeUnit int16
+
+const (
+	Second TimeUnit = iota
+	Millisecond
+	Microsecond
+	Nanosecond
+)
+
+// Field describes a column of the record batches of a stream.
+type Field struct {
+	Name string
+	Type Type
+
+	// Unit and time zone of Timestamp fields.
+	Unit     TimeUnit
+	Timezone string
+
+	// Dictionary encodes the values of a String field as indexes into a
+	// dictionary of the distinct values of the stream.
+	Dictionary bool
+}
+
+// Constants of the Arrow flatbuffer schema.
+const (
+	metadataVersionV5 = 4
+
+	messageHeaderSchema          = 1
+	messageHeaderDictionaryBatch = 2
+	messageHeaderRecordBatch     = 3
+
+	typeNull          = 1
+	typeInt           = 2
+	typeFloatingPoint = 3
+	typeUtf8          = 5
+	typeBool          = 6
+	typeTimestamp     = 10
+
+	precisionDouble = 2
+)
+
+// continuation precedes the length of every message of a stream.
+const continuation = 0xFFFFFFFF
+
+// StreamWriter writes record batches with a fixed schema to an Arrow IPC
+// stream. The schema is written before the first record batch.
+type StreamWriter struct {
+	w      io.Writer
+	fields []Field
+
+	// Dictionaries of the dictionary encoded fields, indexed by field.
+	dicts []*dictionary
+
+	started bool
+}
+
+// dictionary holds the distinct values of a dictionary encoded field.
+type dictionary struct {
+	id     int64
+	index  map[string]int32
+	values []string
+
+	// Number of values already written to the stream.
+	written int
+}
+
+// NewStreamWriter returns a writer of record batches with the given fields.
+func NewStreamWriter(w io.Writer, fields []Field) *StreamWriter {
+	sw := &StreamWriter{
+		w:      w,
+		fields: fields,
+		dicts:  make([]*dictionary, len(fields)),
+	}
+	var id int64
+	for i, f := range fields {
+		if f.Type == String && f.Dictionary {
+			sw.dicts[i] = &dictionary{id: id, index: make(map[string]int32)}
+			id++
+		}
+	}
+	return sw
+}
+
+// Fields returns the fields of the stream.
+func (w *StreamWriter) Fields() []Field { return w.fields }
+
+// WriteRecord writes a record batch with one slice of values per field. All
+// slices must have the same length. Values must be nil or match the type of
+// their field: int64 for Int64 and Timestamp fields, uint64, float64, bool
+// and string for the other types.
+func (w *StreamWriter) WriteRecord(columns [][]interface{}) error {
+	if len(columns) != len(w.fields) {
+		return fmt.Errorf("arrowipc: record has %d columns, schema has %d fields", len(columns), len(w.fields))
+	}
+	n := 0
+	if len(columns) > 0 {
+		n = len(columns[0])
+	}
+	for i, col := range columns {
+		if len(col) != n {
+			return fmt.Errorf("arrowipc: column %q has %d values, expected %d", w.fields[i].Name, len(col), n)
+		}
+	}
+
+	if !w.started {
+		if err := w.writeSchema(); err != nil {
+			return err
+		}
+	}
+
+	var rb recordBatch
+	rb.length = n
+	for i, col := range columns {
+		if err := rb.addColumn(w.fields[i], w.dicts[i], col); err != nil {
+			return err
+		}
+	}
+
+	// Dictionaries must be written before the first batch referencing their
+	// values. Values added since the previous batch are written as a delta.
+	for _, d := range w.dicts {
+		if d == nil || (w.started && d.written == len(d.values)) {
+			continue
+		}
+		if err := w.writeDictionary(d); err != nil {
+			return err
+		}
+	}
+	w.started = true
+
+	return w.writeMessage(messageHeaderRecordBatch, rb.build, rb.body)
+}
+
+// Close ends the stream. Another stream can be written to the underlying
+// writer afterwards.
+func (w *StreamWriter) Close() error {
+	if !w.started {
+		if err := w.writeSchema(); err != nil {
+			return err
+		}
+		w.started = true
+	}
+	var buf [8]byte
+	binary.LittleEndian.PutUint32(buf[:4], continuation)
+	_, err := w.w.Write(buf[:])
+	return err
+}
+
+func (w *StreamWriter) writeSchema() error {
+	return w.writeMessage(messageHeaderSchema, func(b *builder) int {
+		fields := make([]int, len(w.fields))
+		for i, f := range w.fields {
+			fields[i] = buildField(b, f, w.dicts[i])
+		}
+		vec := b.createOffsetVector(fields)
+
+		b.startTable(2)
+		b.addOffset(1, vec)
+		b.addInt16(0, 0) // little endian
+		return b.endTable()
+	}, nil)
+}
+
+func (w *StreamWriter) writeDictionary(d *dictionary) error {
+	var rb recordBatch
+	values := d.values[d.written:]
+	rb.length = len(values)
+	col := make([]interface{}, len(values))
+	for i, v := range values {
+		col[i] = v
+	}
+	if err := rb.addColumn(Field{Type: String}, nil, col); err != nil {
+		return err
+	}
+
+	isDelta := w.started
+	if err := w.writeMessage(messageHeaderDictionaryBatch, func(b *builder) int {
+		data := rb.build(b)
+		b.startTable(3)
+		b.addInt64(0, d.id)
+		b.addOffset(1, data)
+		b.addBool(2, isDelta)
+		return b.endTable()
+	}, rb.body); err != nil {
+		return err
+	}
+	d.written = len(d.values)
+	return nil
+}
+
+// writeMessage writes a message with the header built by fn followed by its
+// body.
+func (w *StreamWriter) writeMessage(headerType uint8, fn func(b *builder) int, body []byte) error {
+	b := newBuilder(256)
+	header := fn(b)
+	b.startTable(4)
+	b.addInt64(3, int64(len(body)))
+	b.addOffset(2, header)
+	b.addInt16(0, metadataVersionV5)
+	b.addUint8(1, headerType)
+	metadata := b.finish(b.endTable())
+
+	// The body must start on an 8 byte boundary.
+	size := len(metadata) + padding(len(metadata))
+
+	buf := make([]byte, 8+size, 8+size+len(body))
+	binary.LittleEndian.PutUint32(buf[0:4], continuation)
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(size))
+	copy(buf[8:], metadata)
+	buf = append(buf, body...)
+	_, err := w.w.Write(buf)
+	return err
+}
+
+func buildField(b *builder, f Field, d *dictionary) int {
+	name := b.createString(f.Name)
+
+	var typ uint8
+	var typeTable int
+	switch f.Type {
+	case Null:
+		typ = typeNull
+		b.startTable(0)
+		typeTable = b.endTable()
+	case Int64, Uint64:
+		typ = typeInt
+		typeTable = buildInt(b, 64, f.Type == Int64)
+	case Float64:
+		typ = typeFloatingPoint
+		b.startTable(1)
+		b.addInt16(0, precisionDouble)
+		typeTable = b.endTable()
+	case Bool:
+		typ = typeBool
+		b.startTable(0)
+		typeTable = b.endTable()
+	case String:
+		typ = typeUtf8
+		b.startTable(0)
+		typeTable = b.endTable()
+	case Timestamp:
+		typ = typeTimestamp
+		tz := -1
+		if f.Timezone != "" {
+			tz = b.createString(f.Timezone)
+		}
+		b.startTable(2)
+		if tz >= 0 {
+			b.addOffset(1, tz)
+		}
+		b.addInt16(0, int16(f.Unit))
+		typeTable = b.endTable()
+	}
+
+	encoding := -1
+	if d != nil {
+		indexType := buildInt(b, 32, true)
+		b.startTable(3)
+		b.addInt64(0, d.id)
+		b.addOffset(1, indexType)
+		b.addBool(2, false)
+		encoding = b.endTable()
+	}
+
+	// Some readers require the children of a field, even if there are none.
+	children := b.createOffsetVector(nil)
+
+	b.startTable(7)
+	b.addOffset(0, name)
+	b.addOffset(3, typeTable)
+	if encoding >= 0 {
+		b.addOffset(4, encoding)
+	}
+	b.addOffset(5, children)
+	b.addBool(1, true)
+	b.addUint8(2, typ)
+	return b.endTable()
+}
+
+func buildInt(b *builder, bitWidth int32, signed bool) int {
+	b.startTable(2)
+	b.addInt32(0, bitWidth)
+	b.addBool(1, signed)
+	return b.endTable()
+}
+
+// recordBatch accumulates the nodes and buffers of a record batch.
+type recordBatch struct {
+	length  int
+	nodes   []fieldNode
+	buffers []buffer
+	body    []byte
+}
+
+type fieldNode struct {
+	length    int64
+	nullCount int64
+}
+
+type buffer struct {
+	offset int64
+	length int64
+}
+
+// addBuffer appends p to the body of the batch, padded to 8 bytes.
+func (rb *recordBatch) addBuffer(p []byte) {
+	rb.buffers = append(rb.buffers, buffer{offset: int64(len(rb.body)), length: int64(len(p))})
+	rb.body = append(rb.body, p...)
+	for i := padding(len(rb.body)); i > 0; i-- {
+		rb.body = append(rb.body, 0)
+	}
+}
+
+func (rb *recordBatch) addColumn(f Field, d *dictionary, values []interface{}) error {
+	n := len(values)
+	if f.Type == Null {
+		rb.nodes = append(rb.nodes, fieldNode{length: int64(n), nullCount: int64(n)})
+		return nil
+	}
+
+	// The validity bitmap is omitted when there are no nulls.
+	var validity []byte
+	nullN := 0
+	for _, v := range values {
+		if v == nil {
+			nullN++
+		}
+	}
+	if nullN > 0 {
+		validity = make([]byte, (n+7)/8)
+		for i, v := range values {
+			if v != nil {
+				validity[i/8] |= 1 << uint(i%8)
+			}
+		}
+	}
+	rb.nodes = append(rb.nodes, fieldNode{length: int64(n), nullCount: int64(nullN)})
+	rb.addBuffer(validity)
+
+	typeError := func(v interface{}) error {
+		return fmt.Errorf("arrowipc: unexpected %T value for %s field %q", v, f.Type, f.Name)
+	}
+
+	switch {
+	case f.Type == String && d != nil:
+		data := make([]byte, 4*n)
+		for i, v := range values {
+			if v == nil {
+				continue
+			}
+			s, ok := v.(string)
+			if !ok {
+				return typeError(v)
+			}
+			idx, ok := d.index[s]
+			if !ok {
+				idx = int32(len(d.values))
+				d.index[s] = idx
+				d.values = append(d.values, s)
+			}
+			binary.LittleEndian.PutUint32(data[4*i:], uint32(idx))
+		}
+		rb.addBuffer(data)
+	case f.Type == String:
+		offsets := make([]byte, 4*(n+1))
+		var data []byte
+		for i, v := range values {
+			if v != nil {
+				s, ok := v.(string)
+				if !ok {
+					return typeError(v)
+				}
+				data = append(data, s...)
+			}
+			binary.LittleEndian.PutUint32(offsets[4*(i+1):], uint32(len(data)))
+		}
+		rb.addBuffer(offsets)
+		rb.addBuffer(data)
+	case f.Type == Bool:
+		data := make([]byte, (n+7)/8)
+		for i, v := range values {
+			if v == nil {
+				continue
+			}
+			b, ok := v.(bool)
+			if !ok {
+				return typeError(v)
+			}
+			if b {
+				data[i/8] |= 1 << uint(i%8)
+			}
+		}
+		rb.addBuffer(data)
+	default:
+		data := make([]byte, 8*n)
+		for i, v := range values {
+			if v == nil {
+				continue
+			}
+			var bits uint64
+			switch v := v.(type) {
+			case int64:
+				if f.Type != Int64 && f.Type != Timestamp {
+					return typeError(v)
+				}
+				bits = uint64(v)
+			case uint64:
+				if f.Type != Uint64 {
+					return typeError(v)
+				}
+				bits = v
+			case float64:
+				if f.Type != Float64 {
+					return typeError(v)
+				}
+				bits = math.Float64bits(v)
+			default:
+				return typeError(v)
+			}
+			binary.LittleEndian.PutUint64(data[8*i:], bits)
+		}
+		rb.addBuffer(data)
+	}
+	return nil
+}
+
+// build writes the RecordBatch table of the batch.
+func (rb *recordBatch) build(b *builder) int {
+	b.startVector(16, len(rb.buffers), 8)
+	for i := len(rb.buffers) - 1; i >= 0; i-- {
+		b.prep(8, 16)
+		b.prependInt64(rb.buffers[i].length)
+		b.prependInt64(rb.buffers[i].offset)
+	}
+	buffers := b.endVector(len(rb.buffers))
+
+	b.startVector(16, len(rb.nodes), 8)
+	for i := len(rb.nodes) - 1; i >= 0; i-- {
+		b.prep(8, 16)
+		b.prependInt64(rb.nodes[i].nullCount)
+		b.prependInt64(rb.nodes[i].length)
+	}
+	nodes := b.endVector(len(rb.nodes))
+
+	b.startTable(3)
+	b.addInt64(0, int64(rb.length))
+	b.addOffset(1, nodes)
+	b.addOffset(2, buffers)
+	return b.endTable()
+}
+
+// padding returns the number of bytes needed to align n to 8 bytes.
+func padding(n int) int {
+	return (8 - n%8) % 8
+}
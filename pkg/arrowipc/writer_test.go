@@ -0,0 +1,509 @@
+package arrowipc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestStreamWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewStreamWriter(&buf, []Field{
+		{Name: "name", Type: String, Dictionary: true},
+		{Name: "time", Type: Timestamp, Unit: Nanosecond, Timezone: "UTC"},
+		{Name: "value", Type: Float64},
+		{Name: "count", Type: Int64},
+		{Name: "ok", Type: Bool},
+		{Name: "msg", Type: String},
+		{Name: "empty", Type: Null},
+	})
+	if err := w.WriteRecord([][]interface{}{
+		{"cpu", "mem", "cpu"},
+		{int64(10), int64(20), int64(30)},
+		{1.5, nil, 3.5},
+		{int64(1), int64(2), int64(3)},
+		{true, false, nil},
+		{"a", nil, "bc"},
+		{nil, nil, nil},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRecord([][]interface{}{
+		{"cpu"}, {int64(40)}, {4.5}, {int64(4)}, {true}, {"d"}, {nil},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRecord([][]interface{}{
+		{"disk"}, {int64(50)}, {5.5}, {int64(5)}, {false}, {"e"}, {nil},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := readMessages(t, buf.Bytes())
+	var types []uint8
+	for _, m := range msgs {
+		types = append(types, m.headerType)
+	}
+	if got, want := types, []uint8{
+		messageHeaderSchema,
+		messageHeaderDictionaryBatch, messageHeaderRecordBatch,
+		messageHeaderRecordBatch,
+		messageHeaderDictionaryBatch, messageHeaderRecordBatch,
+		0, // end of stream
+	}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected messages: got=%v want=%v", got, want)
+	}
+
+	// Verify the schema.
+	fields := msgs[0].header.tables(1)
+	var names []string
+	var typeIDs []uint8
+	for _, f := range fields {
+		names = append(names, f.string(0))
+		typeIDs = append(typeIDs, f.uint8(2))
+	}
+	if got, want := names, []string{"name", "time", "value", "count", "ok", "msg", "empty"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected field names: got=%v want=%v", got, want)
+	}
+	if got, want := typeIDs, []uint8{typeUtf8, typeTimestamp, typeFloatingPoint, typeInt, typeBool, typeUtf8, typeNull}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected field types: got=%v want=%v", got, want)
+	}
+	if enc := fields[0].table(4); enc.pos == 0 {
+		t.Fatal("expected dictionary encoding for name")
+	} else if got, want := enc.table(1).int32(0), int32(32); got != want {
+		t.Fatalf("unexpected index bit width: got=%d want=%d", got, want)
+	}
+	if got, want := fields[1].table(3).string(1), "UTC"; got != want {
+		t.Fatalf("unexpected time zone: got=%q want=%q", got, want)
+	}
+
+	// The first dictionary holds every value, the next one only new values.
+	for _, tt := range []struct {
+		msg     message
+		isDelta bool
+		values  []string
+	}{
+		{msg: msgs[1], isDelta: false, values: []string{"cpu", "mem"}},
+		{msg: msgs[4], isDelta: true, values: []string{"disk"}},
+	} {
+		if got := tt.msg.header.bool(2); got != tt.isDelta {
+			t.Fatalf("unexpected isDelta: got=%v want=%v", got, tt.isDelta)
+		}
+		data := tt.msg.header.table(1)
+		if got, want := data.int64(0), int64(len(tt.values)); got != want {
+			t.Fatalf("unexpected dictionary length: got=%d want=%d", got, want)
+		}
+		offsets, values := tt.msg.buffer(data, 1), tt.msg.buffer(data, 2)
+		for i, want := range tt.values {
+			start, end := binary.LittleEndian.Uint32(offsets[4*i:]), binary.LittleEndian.Uint32(offsets[4*i+4:])
+			if got := string(values[start:end]); got != want {
+				t.Fatalf("unexpected dictionary value %d: got=%q want=%q", i, got, want)
+			}
+		}
+	}
+
+	// Verify the first record batch.
+	rb := msgs[2].header
+	if got, want := rb.int64(0), int64(3); got != want {
+		t.Fatalf("unexpected length: got=%d want=%d", got, want)
+	}
+	if got, want := msgs[2].nullCounts(), []int64{0, 0, 1, 0, 1, 1, 3}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected null counts: got=%v want=%v", got, want)
+	}
+	for i, n := range rb.structs(2, 16) {
+		if off := binary.LittleEndian.Uint64(n); off%8 != 0 {
+			t.Fatalf("buffer %d is not aligned: %d", i, off)
+		}
+	}
+
+	// Buffers: name (validity, indexes), time (validity, values), value
+	// (validity, values), ...
+	if got, want := msgs[2].int32s(rb, 1), []int32{0, 1, 0}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected name indexes: got=%v want=%v", got, want)
+	}
+	if got, want := msgs[2].int64s(rb, 3), []int64{10, 20, 30}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected times: got=%v want=%v", got, want)
+	}
+	if got, want := msgs[2].buffer(rb, 4), []byte{0x5}; !bytes.Equal(got, want) {
+		t.Fatalf("unexpected value validity: got=%v want=%v", got, want)
+	}
+	if got, want := msgs[2].int64s(rb, 5)[2], int64(math.Float64bits(3.5)); got != want {
+		t.Fatalf("unexpected value: got=%x want=%x", got, want)
+	}
+	if got, want := msgs[5].int32s(msgs[5].header, 1), []int32{2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected name indexes: got=%v want=%v", got, want)
+	}
+}
+
+// Ensure the writer encodes records like arrow-go. The fixture was written by
+// the ipc.Writer of github.com/apache/arrow/go/v15 v15.0.2, with dictionary
+// deltas enabled, for the records below.
+func TestStreamWriter_ArrowGo(t *testing.T) {
+	fields := []Field{
+		{Name: "name", Type: String, Dictionary: true},
+		{Name: "time", Type: Timestamp, Unit: Nanosecond, Timezone: "UTC"},
+		{Name: "value", Type: Float64},
+		{Name: "count", Type: Int64},
+		{Name: "total", Type: Uint64},
+		{Name: "ok", Type: Bool},
+		{Name: "msg", Type: String},
+		{Name: "empty", Type: Null},
+	}
+	records := [][][]interface{}{
+		{
+			{"cpu", "mem", "cpu"},
+			{int64(10), int64(20), int64(30)},
+			{1.5, nil, 3.5},
+			{int64(1), int64(2), int64(3)},
+			{uint64(1 << 63), nil, uint64(7)},
+			{true, false, nil},
+			{"a", nil, "bc"},
+			{nil, nil, nil},
+		},
+		{
+			{"disk"}, {int64(40)}, {4.5}, {int64(4)}, {uint64(8)}, {true}, {"d"}, {nil},
+		},
+	}
+
+	golden, err := ioutil.ReadFile("testdata/arrow-go.arrows")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	w := NewStreamWriter(&buf, fields)
+	for _, columns := range records {
+		if err := w.WriteRecord(columns); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := decodeStream(t, golden)
+	if !reflect.DeepEqual(want.records, records) {
+		t.Fatalf("unexpected fixture records: %v", want.records)
+	}
+	got := decodeStream(t, buf.Bytes())
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected stream:\ngot=%+v\nwant=%+v", got, want)
+	}
+}
+
+func TestStreamWriter_Errors(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		columns [][]interface{}
+		err     string
+	}{
+		{
+			name:    "ColumnN",
+			columns: [][]interface{}{{int64(1)}},
+			err:     "arrowipc: record has 1 columns, schema has 2 fields",
+		},
+		{
+			name:    "ValueN",
+			columns: [][]interface{}{{int64(1)}, {"a", "b"}},
+			err:     `arrowipc: column "tag" has 2 values, expected 1`,
+		},
+		{
+			name:    "Type",
+			columns: [][]interface{}{{1.5}, {"a"}},
+			err:     `arrowipc: unexpected float64 value for int64 field "value"`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewStreamWriter(&bytes.Buffer{}, []Field{
+				{Name: "value", Type: Int64},
+				{Name: "tag", Type: String, Dictionary: true},
+			})
+			if err := w.WriteRecord(tt.columns); err == nil || err.Error() != tt.err {
+				t.Fatalf("unexpected error: got=%v want=%s", err, tt.err)
+			}
+		})
+	}
+}
+
+// message is a message decoded from a stream.
+type message struct {
+	headerType uint8
+	header     table
+	body       []byte
+}
+
+func readMessages(t *testing.T, buf []byte) []message {
+	t.Helper()
+
+	var msgs []message
+	for len(buf) > 0 {
+		if len(buf) < 8 || binary.LittleEndian.Uint32(buf) != continuation {
+			t.Fatalf("invalid message prefix: %v", buf)
+		}
+		size := int(binary.LittleEndian.Uint32(buf[4:]))
+		if size == 0 {
+			msgs = append(msgs, message{})
+			buf = buf[8:]
+			continue
+		} else if size%8 != 0 {
+			t.Fatalf("metadata size is not aligned: %d", size)
+		}
+
+		metadata := buf[8 : 8+size]
+		root := table{buf: metadata, pos: int(binary.LittleEndian.Uint32(metadata))}
+		if got, want := root.int16(0), int16(metadataVersionV5); got != want {
+			t.Fatalf("unexpected version: got=%d want=%d", got, want)
+		}
+		bodyN := int(root.int64(3))
+		msgs = append(msgs, message{
+			headerType: root.uint8(1),
+			header:     root.table(2),
+			body:       buf[8+size : 8+size+bodyN],
+		})
+		buf = buf[8+size+bodyN:]
+	}
+	return msgs
+}
+
+// stream is the content of a stream decoded independently of its encoding.
+type stream struct {
+	messageTypes []uint8
+	fields       []streamField
+	deltas       []bool
+	records      [][][]interface{}
+}
+
+type streamField struct {
+	name     string
+	typeID   uint8
+	typ      string // parameters of the type
+	signed   bool
+	dictID   int64
+	dictBits int32 // bit width of the indexes, or 0
+}
+
+// decodeStream decodes the records of a stream, resolving the values of
+// dictionary encoded fields.
+func decodeStream(t *testing.T, buf []byte) stream {
+	t.Helper()
+
+	var s stream
+	msgs := readMessages(t, buf)
+	for _, m := range msgs {
+		s.messageTypes = append(s.messageTypes, m.headerType)
+	}
+
+	for _, f := range msgs[0].header.tables(1) {
+		sf := streamField{name: f.string(0), typeID: f.uint8(2)}
+		switch typ := f.table(3); sf.typeID {
+		case typeInt:
+			sf.typ, sf.signed = fmt.Sprintf("bitWidth=%d", typ.int32(0)), typ.bool(1)
+		case typeFloatingPoint:
+			sf.typ = fmt.Sprintf("precision=%d", typ.int16(0))
+		case typeTimestamp:
+			sf.typ = fmt.Sprintf("unit=%d timezone=%s", typ.int16(0), typ.string(1))
+		}
+		if enc := f.table(4); enc.pos != 0 {
+			sf.dictID, sf.dictBits = enc.int64(0), enc.table(1).int32(0)
+		}
+		s.fields = append(s.fields, sf)
+	}
+
+	dicts := make(map[int64][]interface{})
+	for _, m := range msgs[1:] {
+		switch m.headerType {
+		case messageHeaderDictionaryBatch:
+			id, isDelta := m.header.int64(0), m.header.bool(2)
+			values, _, _ := m.column(m.header.table(1), 0, 0, streamField{typeID: typeUtf8}, nil)
+			if !isDelta {
+				dicts[id] = nil
+			}
+			dicts[id] = append(dicts[id], values...)
+			s.deltas = append(s.deltas, isDelta)
+		case messageHeaderRecordBatch:
+			var columns [][]interface{}
+			var node, buf int
+			for _, f := range s.fields {
+				var values []interface{}
+				var dict []interface{}
+				if f.dictBits != 0 {
+					dict = dicts[f.dictID]
+				}
+				values, node, buf = m.column(m.header, node, buf, f, dict)
+				columns = append(columns, values)
+			}
+			s.records = append(s.records, columns)
+		}
+	}
+	return s
+}
+
+// column decodes the values of field f of a record batch from the node and
+// buffers at the given indexes. It returns the indexes of the next field.
+func (m message) column(rb table, node, buf int, f streamField, dict []interface{}) ([]interface{}, int, int) {
+	n := rb.structs(1, 16)[node]
+	length, nullN := int(binary.LittleEndian.Uint64(n)), int(binary.LittleEndian.Uint64(n[8:]))
+
+	values := make([]interface{}, length)
+	if f.typeID == typeNull {
+		return values, node + 1, buf
+	}
+
+	validity := m.buffer(rb, buf)
+	buf++
+	valid := func(i int) bool { return nullN == 0 || validity[i/8]&(1<<uint(i%8)) != 0 }
+
+	data := m.buffer(rb, buf)
+	buf++
+	for i := range values {
+		if !valid(i) {
+			continue
+		}
+		switch {
+		case dict != nil:
+			values[i] = dict[binary.LittleEndian.Uint32(data[4*i:])]
+		case f.typeID == typeUtf8:
+			start, end := binary.LittleEndian.Uint32(data[4*i:]), binary.LittleEndian.Uint32(data[4*i+4:])
+			values[i] = string(m.buffer(rb, buf)[start:end])
+		case f.typeID == typeBool:
+			values[i] = data[i/8]&(1<<uint(i%8)) != 0
+		case f.typeID == typeInt && !f.signed:
+			values[i] = binary.LittleEndian.Uint64(data[8*i:])
+		case f.typeID == typeFloatingPoint:
+			values[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[8*i:]))
+		default:
+			values[i] = int64(binary.LittleEndian.Uint64(data[8*i:]))
+		}
+	}
+	if f.typeID == typeUtf8 && dict == nil {
+		buf++
+	}
+	return values, node + 1, buf
+}
+
+// nullCounts returns the null counts of the nodes of a record batch.
+func (m message) nullCounts() []int64 {
+	var counts []int64
+	for _, n := range m.header.structs(1, 16) {
+		counts = append(counts, int64(binary.LittleEndian.Uint64(n[8:])))
+	}
+	return counts
+}
+
+// buffer returns the i-th buffer of a record batch.
+func (m message) buffer(rb table, i int) []byte {
+	b := rb.structs(2, 16)[i]
+	off, n := binary.LittleEndian.Uint64(b), binary.LittleEndian.Uint64(b[8:])
+	return m.body[off : off+n]
+}
+
+func (m message) int32s(rb table, i int) []int32 {
+	var values []int32
+	for b := m.buffer(rb, i); len(b) > 0; b = b[4:] {
+		values = append(values, int32(binary.LittleEndian.Uint32(b)))
+	}
+	return values
+}
+
+func (m message) int64s(rb table, i int) []int64 {
+	var values []int64
+	for b := m.buffer(rb, i); len(b) > 0; b = b[8:] {
+		values = append(values, int64(binary.LittleEndian.Uint64(b)))
+	}
+	return values
+}
+
+// table is a flatbuffer table.
+type table struct {
+	buf []byte
+	pos int
+}
+
+// field returns the position of a field of the table or zero if it is not
+// set.
+func (t table) field(slot int) int {
+	vtable := t.pos - int(int32(binary.LittleEndian.Uint32(t.buf[t.pos:])))
+	if o := 4 + 2*slot; o < int(binary.LittleEndian.Uint16(t.buf[vtable:])) {
+		if off := binary.LittleEndian.Uint16(t.buf[vtable+o:]); off != 0 {
+			return t.pos + int(off)
+		}
+	}
+	return 0
+}
+
+func (t table) uint8(slot int) uint8 {
+	if pos := t.field(slot); pos != 0 {
+		return t.buf[pos]
+	}
+	return 0
+}
+
+func (t table) bool(slot int) bool { return t.uint8(slot) != 0 }
+
+func (t table) int16(slot int) int16 {
+	if pos := t.field(slot); pos != 0 {
+		return int16(binary.LittleEndian.Uint16(t.buf[pos:]))
+	}
+	return 0
+}
+
+func (t table) int32(slot int) int32 {
+	if pos := t.field(slot); pos != 0 {
+		return int32(binary.LittleEndian.Uint32(t.buf[pos:]))
+	}
+	return 0
+}
+
+func (t table) int64(slot int) int64 {
+	if pos := t.field(slot); pos != 0 {
+		return int64(binary.LittleEndian.Uint64(t.buf[pos:]))
+	}
+	return 0
+}
+
+// deref returns the position of the object referenced by a field.
+func (t table) deref(slot int) int {
+	pos := t.field(slot)
+	if pos == 0 {
+		return 0
+	}
+	return pos + int(binary.LittleEndian.Uint32(t.buf[pos:]))
+}
+
+func (t table) table(slot int) table {
+	return table{buf: t.buf, pos: t.deref(slot)}
+}
+
+func (t table) string(slot int) string {
+	pos := t.deref(slot)
+	n := int(binary.LittleEndian.Uint32(t.buf[pos:]))
+	return string(t.buf[pos+4 : pos+4+n])
+}
+
+func (t table) tables(slot int) []table {
+	pos := t.deref(slot)
+	n := int(binary.LittleEndian.Uint32(t.buf[pos:]))
+	tables := make([]table, n)
+	for i := range tables {
+		elem := pos + 4 + 4*i
+		tables[i] = table{buf: t.buf, pos: elem + int(binary.LittleEndian.Uint32(t.buf[elem:]))}
+	}
+	return tables
+}
+
+func (t table) structs(slot, size int) [][]byte {
+	pos := t.deref(slot)
+	n := int(binary.LittleEndian.Uint32(t.buf[pos:]))
+	structs := make([][]byte, n)
+	for i := range structs {
+		structs[i] = t.buf[pos+4+size*i : pos+4+size*(i+1)]
+	}
+	return structs
+}
@@ -0,0 +1,462 @@
+// Package ddsketch implements the DDSketch quantile sketch described in the
+// following paper: https://arxiv.org/abs/1908.10693
+//
+// A DDSketch maps values onto logarithmically sized buckets so that every
+// quantile it returns is within a fixed relative error of the true value.
+// Sketches with the same relative accuracy can be merged losslessly, which
+// allows partial sketches to be built independently and combined later.
+package ddsketch
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Current version of the sketch encoding.
+const version uint8 = 1
+
+// DefaultRelativeAccuracy is the default relative accuracy of a sketch.
+const DefaultRelativeAccuracy = 0.01
+
+// DefaultMaxBins is the default number of buckets kept for each sign.
+// With the default accuracy this covers a dynamic range of about 10^17
+// before the lowest buckets start to be collapsed.
+const DefaultMaxBins = 2048
+
+// MaxBins is the maximum number of buckets of each sign of a decoded sketch.
+// It bounds the memory allocated when decoding untrusted data.
+const MaxBins = 8 * DefaultMaxBins
+
+// HeaderSize is the size of the version and relative accuracy starting every
+// encoded sketch.
+const HeaderSize = 1 + 8
+
+// MaxEncodedSize is the maximum size of an encoded sketch.
+const MaxEncodedSize = HeaderSize + 2*8 + 2*binary.MaxVarintLen64 + 2*(2+MaxBins)*binary.MaxVarintLen64
+
+var (
+	// ErrIncompatibleSketch is returned when merging sketches that were
+	// created with a different relative accuracy.
+	ErrIncompatibleSketch = errors.New("ddsketch: incompatible relative accuracy")
+
+	// ErrInvalidEncoding is returned when decoding a malformed sketch.
+	ErrInvalidEncoding = errors.New("ddsketch: invalid encoding")
+)
+
+// Sketch is a mergeable quantile sketch.
+type Sketch struct {
+	accuracy float64
+	gamma    float64
+	logGamma float64
+	maxBins  int
+
+	pos  store // positive values, keyed by log_gamma(x).
+	neg  store // negative values, keyed by log_gamma(-x).
+	zero uint64
+
+	min, max float64
+}
+
+// New returns a new sketch with the given relative accuracy. The accuracy
+// must be in the range (0, 1).
+func New(relativeAccuracy float64) (*Sketch, error) {
+	if !(relativeAccuracy > 0 && relativeAccuracy < 1) {
+		return nil, fmt.Errorf("ddsketch: relative accuracy must be between 0 and 1, got %v", relativeAccuracy)
+	}
+	return newSketch(relativeAccuracy, DefaultMaxBins), nil
+}
+
+// NewDefault returns a new sketch with the default relative accuracy.
+func NewDefault() *Sketch {
+	return newSketch(DefaultRelativeAccuracy, DefaultMaxBins)
+}
+
+func newSketch(accuracy float64, maxBins int) *Sketch {
+	gamma := (1 + accuracy) / (1 - accuracy)
+	return &Sketch{
+		accuracy: accuracy,
+		gamma:    gamma,
+		logGamma: math.Log(gamma),
+		maxBins:  maxBins,
+		min:      math.Inf(1),
+		max:      math.Inf(-1),
+	}
+}
+
+// RelativeAccuracy returns the relative accuracy of the sketch.
+func (s *Sketch) RelativeAccuracy() float64 { return s.accuracy }
+
+// Count returns the number of values added to the sketch.
+func (s *Sketch) Count() uint64 { return s.pos.count + s.neg.count + s.zero }
+
+// Min returns the smallest value added to the sketch.
+func (s *Sketch) Min() float64 { return s.min }
+
+// Max returns the largest value added to the sketch.
+func (s *Sketch) Max() float64 { return s.max }
+
+// Add adds a single value to the sketch. NaN and infinite values are ignored.
+func (s *Sketch) Add(v float64) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return
+	}
+
+	switch {
+	case v > 0:
+		s.pos.add(s.key(v), 1, s.maxBins)
+	case v < 0:
+		s.neg.add(s.key(-v), 1, s.maxBins)
+	default:
+		s.zero++
+	}
+
+	if v < s.min {
+		s.min = v
+	}
+	if v > s.max {
+		s.max = v
+	}
+}
+
+// Merge merges another sketch into this one.
+func (s *Sketch) Merge(other *Sketch) error {
+	if other.Count() == 0 {
+		return nil
+	} else if s.accuracy != other.accuracy {
+		return ErrIncompatibleSketch
+	}
+
+	other.pos.each(false, func(key int, n uint64) bool {
+		s.pos.add(key, n, s.maxBins)
+		return true
+	})
+	other.neg.each(false, func(key int, n uint64) bool {
+		s.neg.add(key, n, s.maxBins)
+		return true
+	})
+	s.zero += other.zero
+
+	if other.min < s.min {
+		s.min = other.min
+	}
+	if other.max > s.max {
+		s.max = other.max
+	}
+	return nil
+}
+
+// Quantile returns an estimate of the value at quantile q, where q is in the
+// range [0, 1]. NaN is returned if the sketch is empty or q is out of range.
+func (s *Sketch) Quantile(q float64) float64 {
+	n := s.Count()
+	if n == 0 || !(q >= 0 && q <= 1) {
+		return math.NaN()
+	} else if q == 0 {
+		return s.min
+	} else if q == 1 {
+		return s.max
+	}
+
+	// Find the bucket holding the value with the given zero-based rank,
+	// walking from the most negative value to the most positive one.
+	rank := q * float64(n-1)
+	var (
+		cum   float64
+		value float64
+		found bool
+	)
+	s.neg.each(true, func(key int, count uint64) bool {
+		cum += float64(count)
+		if cum > rank {
+			value, found = -s.value(key), true
+			return false
+		}
+		return true
+	})
+	if !found {
+		if cum += float64(s.zero); cum > rank {
+			value, found = 0, true
+		}
+	}
+	if !found {
+		s.pos.each(false, func(key int, count uint64) bool {
+			cum += float64(count)
+			if cum > rank {
+				value, found = s.value(key), true
+				return false
+			}
+			return true
+		})
+	}
+	if !found {
+		value = s.max
+	}
+
+	// The bucket representative may lie outside of the observed range.
+	if value < s.min {
+		value = s.min
+	} else if value > s.max {
+		value = s.max
+	}
+	return value
+}
+
+// CountLessOrEqual returns an estimate of the number of values in the sketch
+// that are less than or equal to v.
+func (s *Sketch) CountLessOrEqual(v float64) uint64 {
+	if s.Count() == 0 || v < s.min || math.IsNaN(v) {
+		return 0
+	} else if v >= s.max {
+		return s.Count()
+	}
+
+	var cum uint64
+	s.neg.each(true, func(key int, count uint64) bool {
+		if -s.value(key) > v {
+			return false
+		}
+		cum += count
+		return true
+	})
+	if v < 0 {
+		return cum
+	}
+	cum += s.zero
+	s.pos.each(false, func(key int, count uint64) bool {
+		if s.value(key) > v {
+			return false
+		}
+		cum += count
+		return true
+	})
+	return cum
+}
+
+// key returns the bucket key for the positive value v.
+func (s *Sketch) key(v float64) int {
+	return int(math.Ceil(math.Log(v) / s.logGamma))
+}
+
+// value returns the representative value of the bucket with the given key.
+// Every value in the bucket is within the relative accuracy of it.
+func (s *Sketch) value(key int) float64 {
+	return 2 * math.Pow(s.gamma, float64(key)) / (s.gamma + 1)
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+func (s *Sketch) MarshalBinary() ([]byte, error) {
+	// Header: version, accuracy, max bins, min, max & zero count.
+	buf := make([]byte, 0, 1+8+8+8+3*binary.MaxVarintLen64+len(s.pos.bins)+len(s.neg.bins))
+	buf = append(buf, version)
+	buf = appendFloat(buf, s.accuracy)
+	buf = appendUvarint(buf, uint64(s.maxBins))
+	buf = appendFloat(buf, s.min)
+	buf = appendFloat(buf, s.max)
+	buf = appendUvarint(buf, s.zero)
+	buf = s.pos.appendBinary(buf)
+	buf = s.neg.appendBinary(buf)
+	return buf, nil
+}
+
+// ValidHeader returns true if data starts with the header of an encoded
+// sketch. It tells sketches apart from other data without decoding them.
+func ValidHeader(data []byte) bool {
+	if len(data) < HeaderSize || data[0] != version {
+		return false
+	}
+	accuracy := math.Float64frombits(binary.BigEndian.Uint64(data[1:]))
+	return accuracy > 0 && accuracy < 1
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (s *Sketch) UnmarshalBinary(data []byte) error {
+	if !ValidHeader(data) || len(data) > MaxEncodedSize {
+		return ErrInvalidEncoding
+	}
+	r := reader{buf: data[1:]}
+
+	accuracy := r.float()
+	maxBins := r.uvarint()
+	if r.err != nil || maxBins == 0 || maxBins > MaxBins {
+		return ErrInvalidEncoding
+	}
+	other := newSketch(accuracy, int(maxBins))
+	other.min = r.float()
+	other.max = r.float()
+	other.zero = r.uvarint()
+	other.pos.readBinary(&r, other.maxBins)
+	other.neg.readBinary(&r, other.maxBins)
+	if r.err != nil || len(r.buf) != 0 {
+		return ErrInvalidEncoding
+	}
+	*s = *other
+	return nil
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	return append(buf, b[:binary.PutUvarint(b[:], v)]...)
+}
+
+func appendVarint(buf []byte, v int64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	return append(buf, b[:binary.PutVarint(b[:], v)]...)
+}
+
+func appendFloat(buf []byte, v float64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], math.Float64bits(v))
+	return append(buf, b[:]...)
+}
+
+// store is a dense range of bucket counts starting at offset.
+type store struct {
+	bins   []uint64
+	offset int
+	count  uint64
+}
+
+// add adds n to the bucket with the given key. When the store would exceed
+// maxBins buckets, the lowest buckets are collapsed into a single bucket.
+func (s *store) add(key int, n uint64, maxBins int) {
+	if n == 0 {
+		return
+	}
+
+	if len(s.bins) == 0 {
+		s.bins, s.offset = make([]uint64, 1), key
+	} else if key < s.offset {
+		s.growLeft(key, maxBins)
+		if key < s.offset {
+			key = s.offset
+		}
+	} else if key >= s.offset+len(s.bins) {
+		s.growRight(key, maxBins)
+	}
+	s.bins[key-s.offset] += n
+	s.count += n
+}
+
+func (s *store) growLeft(key, maxBins int) {
+	hi := s.offset + len(s.bins) - 1
+	lo := key
+	if hi-lo+1 > maxBins {
+		lo = hi - maxBins + 1
+	}
+	if lo >= s.offset {
+		return
+	}
+
+	bins := make([]uint64, hi-lo+1)
+	copy(bins[s.offset-lo:], s.bins)
+	s.bins, s.offset = bins, lo
+}
+
+func (s *store) growRight(key, maxBins int) {
+	lo := s.offset
+	if key-lo+1 > maxBins {
+		lo = key - maxBins + 1
+	}
+
+	// Sum the buckets that fall below the new range so they can be folded
+	// into the lowest remaining bucket.
+	var collapsed uint64
+	for i := s.offset; i < lo && i < s.offset+len(s.bins); i++ {
+		collapsed += s.bins[i-s.offset]
+	}
+
+	bins := make([]uint64, key-lo+1)
+	if lo < s.offset+len(s.bins) {
+		copy(bins, s.bins[lo-s.offset:])
+	}
+	bins[0] += collapsed
+	s.bins, s.offset = bins, lo
+}
+
+// each calls fn for every non-empty bucket in key order until fn returns false.
+func (s *store) each(reverse bool, fn func(key int, n uint64) bool) {
+	for i := range s.bins {
+		if reverse {
+			i = len(s.bins) - 1 - i
+		}
+		if s.bins[i] == 0 {
+			continue
+		} else if !fn(s.offset+i, s.bins[i]) {
+			return
+		}
+	}
+}
+
+func (s *store) appendBinary(buf []byte) []byte {
+	buf = appendVarint(buf, int64(s.offset))
+	buf = appendUvarint(buf, uint64(len(s.bins)))
+	for _, n := range s.bins {
+		buf = appendUvarint(buf, n)
+	}
+	return buf
+}
+
+func (s *store) readBinary(r *reader, maxBins int) {
+	offset := r.varint()
+	n := r.uvarint()
+	if r.err != nil {
+		return
+	} else if n > uint64(maxBins) || n > uint64(len(r.buf)) || offset < math.MinInt32 || offset > math.MaxInt32 {
+		r.err = ErrInvalidEncoding
+		return
+	} else if n == 0 {
+		return
+	}
+
+	s.bins, s.offset = make([]uint64, n), int(offset)
+	for i := range s.bins {
+		s.bins[i] = r.uvarint()
+		s.count += s.bins[i]
+	}
+}
+
+// reader decodes values from an encoded sketch, recording the first error.
+type reader struct {
+	buf []byte
+	err error
+}
+
+func (r *reader) float() float64 {
+	if r.err != nil {
+		return 0
+	} else if len(r.buf) < 8 {
+		r.err = ErrInvalidEncoding
+		return 0
+	}
+	v := math.Float64frombits(binary.BigEndian.Uint64(r.buf))
+	r.buf = r.buf[8:]
+	return v
+}
+
+func (r *reader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.err = ErrInvalidEncoding
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *reader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.buf)
+	if n <= 0 {
+		r.err = ErrInvalidEncoding
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
@@ -0,0 +1,203 @@
+package ddsketch_test
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/influxdata/influxdb/pkg/ddsketch"
+)
+
+func TestSketch_Quantile(t *testing.T) {
+	rnd := rand.New(rand.NewSource(0))
+	values := make([]float64, 10000)
+	s := ddsketch.NewDefault()
+	for i := range values {
+		values[i] = rnd.ExpFloat64()*100 - 20
+		s.Add(values[i])
+	}
+	sort.Float64s(values)
+
+	if got, exp := s.Count(), uint64(len(values)); got != exp {
+		t.Fatalf("unexpected count: got=%d exp=%d", got, exp)
+	}
+
+	for _, q := range []float64{0, 0.01, 0.1, 0.25, 0.5, 0.75, 0.9, 0.99, 0.999, 1} {
+		exp := values[int(q*float64(len(values)-1))]
+		got := s.Quantile(q)
+		if math.Abs(got-exp) > math.Abs(exp)*ddsketch.DefaultRelativeAccuracy+1e-9 {
+			t.Errorf("unexpected quantile %v: got=%v exp=%v", q, got, exp)
+		}
+	}
+}
+
+func TestSketch_Quantile_Empty(t *testing.T) {
+	s := ddsketch.NewDefault()
+	if v := s.Quantile(0.5); !math.IsNaN(v) {
+		t.Fatalf("expected NaN, got %v", v)
+	}
+
+	s.Add(1)
+	if v := s.Quantile(2); !math.IsNaN(v) {
+		t.Fatalf("expected NaN for invalid quantile, got %v", v)
+	}
+}
+
+func TestSketch_Merge(t *testing.T) {
+	a, b, all := ddsketch.NewDefault(), ddsketch.NewDefault(), ddsketch.NewDefault()
+	for i := -500; i < 1000; i++ {
+		v := float64(i)
+		if i%3 == 0 {
+			a.Add(v)
+		} else {
+			b.Add(v)
+		}
+		all.Add(v)
+	}
+
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := a.Count(), all.Count(); got != exp {
+		t.Fatalf("unexpected count: got=%d exp=%d", got, exp)
+	}
+	for _, q := range []float64{0, 0.1, 0.5, 0.9, 1} {
+		if got, exp := a.Quantile(q), all.Quantile(q); got != exp {
+			t.Errorf("unexpected quantile %v: got=%v exp=%v", q, got, exp)
+		}
+	}
+
+	other, err := ddsketch.New(0.05)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.Add(1)
+	if err := a.Merge(other); err != ddsketch.ErrIncompatibleSketch {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestSketch_CountLessOrEqual(t *testing.T) {
+	s := ddsketch.NewDefault()
+	for i := 1; i <= 100; i++ {
+		s.Add(float64(i))
+		s.Add(-float64(i))
+	}
+	s.Add(0)
+
+	for _, tt := range []struct {
+		v   float64
+		exp uint64
+	}{
+		{v: -1000, exp: 0},
+		{v: -50, exp: 51},
+		{v: 0, exp: 101},
+		{v: 10, exp: 111},
+		{v: 1000, exp: 201},
+	} {
+		got := s.CountLessOrEqual(tt.v)
+		if diff := int64(got) - int64(tt.exp); diff < -1 || diff > 1 {
+			t.Errorf("unexpected count <= %v: got=%d exp=%d", tt.v, got, tt.exp)
+		}
+	}
+}
+
+func TestSketch_MaxBins(t *testing.T) {
+	s, err := ddsketch.New(0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Span far more buckets than the sketch is allowed to hold so the lowest
+	// buckets are collapsed.
+	for i := -300; i <= 300; i++ {
+		s.Add(math.Pow(10, float64(i)))
+	}
+	if got, exp := s.Count(), uint64(601); got != exp {
+		t.Fatalf("unexpected count: got=%d exp=%d", got, exp)
+	}
+	if got, exp := s.Quantile(1), math.Pow(10, 300); got != exp {
+		t.Fatalf("unexpected max: got=%v exp=%v", got, exp)
+	}
+	if got, exp := s.Quantile(0.9), 1e240; math.Abs(got-exp) > exp*0.1 {
+		t.Fatalf("unexpected quantile: got=%v exp=%v", got, exp)
+	}
+}
+
+func TestSketch_MarshalBinary(t *testing.T) {
+	s := ddsketch.NewDefault()
+	for i := -100; i < 1000; i++ {
+		s.Add(float64(i) * 1.5)
+	}
+
+	data, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var other ddsketch.Sketch
+	if err := other.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := other.Count(), s.Count(); got != exp {
+		t.Fatalf("unexpected count: got=%d exp=%d", got, exp)
+	}
+	for _, q := range []float64{0, 0.25, 0.5, 0.75, 1} {
+		if got, exp := other.Quantile(q), s.Quantile(q); got != exp {
+			t.Errorf("unexpected quantile %v: got=%v exp=%v", q, got, exp)
+		}
+	}
+
+	if err := other.UnmarshalBinary(data[:len(data)-1]); err != ddsketch.ErrInvalidEncoding {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := other.UnmarshalBinary([]byte("foo")); err != ddsketch.ErrInvalidEncoding {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure malformed sketches are rejected before allocating their buckets.
+func TestSketch_UnmarshalBinary_Malformed(t *testing.T) {
+	header := func(maxBins uint64) []byte {
+		buf := []byte{1}
+		buf = appendFloat(buf, 0.01)
+		buf = appendUvarint(buf, maxBins)
+		buf = appendFloat(buf, 0)
+		buf = appendFloat(buf, 1)
+		return appendUvarint(buf, 0)
+	}
+
+	var s ddsketch.Sketch
+	if err := s.UnmarshalBinary(header(ddsketch.MaxBins + 1)); err != ddsketch.ErrInvalidEncoding {
+		t.Fatalf("unexpected error for too many bins: %v", err)
+	}
+
+	// The count of buckets is larger than the remaining data.
+	data := header(ddsketch.MaxBins)
+	data = appendVarint(data, 0)
+	data = appendUvarint(data, ddsketch.MaxBins)
+	if err := s.UnmarshalBinary(data); err != ddsketch.ErrInvalidEncoding {
+		t.Fatalf("unexpected error for truncated bins: %v", err)
+	}
+
+	if ddsketch.ValidHeader([]byte("not a sketch")) {
+		t.Fatal("expected invalid header")
+	}
+}
+
+func appendFloat(buf []byte, v float64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], math.Float64bits(v))
+	return append(buf, b[:]...)
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	return append(buf, b[:binary.PutUvarint(b[:], v)]...)
+}
+
+func appendVarint(buf []byte, v int64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	return append(buf, b[:binary.PutVarint(b[:], v)]...)
+}
@@ -53,6 +53,12 @@ func NewCallIterator(input Iterator, opt IteratorOptions) (Iterator, error) {
 		return newLastIterator(input, opt)
 	case "mean":
 		return newMeanIterator(input, opt)
+	case "sketch":
+		return newSketchIterator(input, opt)
+	case "hll":
+		return newHLLIterator(input, opt)
+	case "hll_merge":
+		return newHLLMergeIterator(input, opt)
 	default:
 		return nil, fmt.Errorf("unsupported function call: %s", name)
 	}
@@ -1046,6 +1052,153 @@ func newPercentileIterator(input Iterator, opt IteratorOptions, percentile float
 	}
 }
 
+// newSketchIterator returns an iterator for operating on a sketch() call.
+func newSketchIterator(input Iterator, opt IteratorOptions) (Iterator, error) {
+	switch input := input.(type) {
+	case FloatIterator:
+		createFn := func() (FloatPointAggregator, StringPointEmitter) {
+			fn := NewSketchReducer()
+			return fn, fn
+		}
+		return newFloatReduceStringIterator(input, opt, createFn), nil
+	case IntegerIterator:
+		createFn := func() (IntegerPointAggregator, StringPointEmitter) {
+			fn := NewSketchReducer()
+			return fn, fn
+		}
+		return newIntegerReduceStringIterator(input, opt, createFn), nil
+	case UnsignedIterator:
+		createFn := func() (UnsignedPointAggregator, StringPointEmitter) {
+			fn := NewSketchReducer()
+			return fn, fn
+		}
+		return newUnsignedReduceStringIterator(input, opt, createFn), nil
+	case StringIterator:
+		createFn := func() (StringPointAggregator, StringPointEmitter) {
+			fn := NewSketchReducer()
+			return fn, fn
+		}
+		return newStringReduceStringIterator(input, opt, createFn), nil
+	default:
+		return nil, fmt.Errorf("unsupported sketch iterator type: %T", input)
+	}
+}
+
+// newQuantileApproxIterator returns an iterator for operating on a
+// quantile_approx() call. The input is an iterator of encoded sketches.
+func newQuantileApproxIterator(input Iterator, opt IteratorOptions, quantile float64) (Iterator, error) {
+	switch input := input.(type) {
+	case StringIterator:
+		createFn := func() (StringPointAggregator, FloatPointEmitter) {
+			fn := NewQuantileApproxReducer(quantile)
+			return fn, fn
+		}
+		return newStringReduceFloatIterator(input, opt, createFn), nil
+	case *nilFloatIterator:
+		// No shards were mapped so there are no sketches to read.
+		return input, nil
+	default:
+		return nil, fmt.Errorf("unsupported quantile_approx iterator type: %T", input)
+	}
+}
+
+// newHistogramIterator returns an iterator for operating on a histogram()
+// call. The input is an iterator of encoded sketches.
+func newHistogramIterator(input Iterator, opt IteratorOptions, bounds []float64) (Iterator, error) {
+	switch input := input.(type) {
+	case StringIterator:
+		createFn := func() (StringPointAggregator, IntegerPointEmitter) {
+			fn := NewHistogramReducer(bounds)
+			return fn, fn
+		}
+		return newStringReduceIntegerIterator(input, opt, createFn), nil
+	case *nilFloatIterator:
+		// No shards were mapped so there are no sketches to read.
+		return input, nil
+	default:
+		return nil, fmt.Errorf("unsupported histogram iterator type: %T", input)
+	}
+}
+
+// newHLLIterator returns an iterator for operating on an hll() call. This call
+// is used internally by count_distinct_approx() to build a HyperLogLog++ sketch
+// of the distinct values in each interval.
+func newHLLIterator(input Iterator, opt IteratorOptions) (Iterator, error) {
+	call := opt.Expr.(*influxql.Call)
+	precision := uint8(call.Args[1].(*influxql.IntegerLiteral).Val)
+	if _, err := NewHLLReducer(precision); err != nil {
+		return nil, err
+	}
+
+	switch input := input.(type) {
+	case FloatIterator:
+		createFn := func() (FloatPointAggregator, StringPointEmitter) {
+			fn, _ := NewHLLReducer(precision)
+			return fn, fn
+		}
+		return newFloatReduceStringIterator(input, opt, createFn), nil
+	case IntegerIterator:
+		createFn := func() (IntegerPointAggregator, StringPointEmitter) {
+			fn, _ := NewHLLReducer(precision)
+			return fn, fn
+		}
+		return newIntegerReduceStringIterator(input, opt, createFn), nil
+	case UnsignedIterator:
+		createFn := func() (UnsignedPointAggregator, StringPointEmitter) {
+			fn, _ := NewHLLReducer(precision)
+			return fn, fn
+		}
+		return newUnsignedReduceStringIterator(input, opt, createFn), nil
+	case StringIterator:
+		createFn := func() (StringPointAggregator, StringPointEmitter) {
+			fn, _ := NewHLLReducer(precision)
+			return fn, fn
+		}
+		return newStringReduceStringIterator(input, opt, createFn), nil
+	case BooleanIterator:
+		createFn := func() (BooleanPointAggregator, StringPointEmitter) {
+			fn, _ := NewHLLReducer(precision)
+			return fn, fn
+		}
+		return newBooleanReduceStringIterator(input, opt, createFn), nil
+	default:
+		return nil, fmt.Errorf("unsupported hll iterator type: %T", input)
+	}
+}
+
+// newHLLMergeIterator returns an iterator that merges the sketches produced
+// by an hll() call.
+func newHLLMergeIterator(input Iterator, opt IteratorOptions) (Iterator, error) {
+	switch input := input.(type) {
+	case StringIterator:
+		createFn := func() (StringPointAggregator, StringPointEmitter) {
+			fn := NewHLLMergeReducer()
+			return fn, fn
+		}
+		return newStringReduceStringIterator(input, opt, createFn), nil
+	default:
+		return nil, fmt.Errorf("unsupported hll_merge iterator type: %T", input)
+	}
+}
+
+// newCountDistinctApproxIterator returns an iterator for operating on a
+// count_distinct_approx() call. The input is an iterator of hll() sketches.
+func newCountDistinctApproxIterator(input Iterator, opt IteratorOptions) (Iterator, error) {
+	switch input := input.(type) {
+	case StringIterator:
+		createFn := func() (StringPointAggregator, IntegerPointEmitter) {
+			fn := NewCountDistinctApproxReducer()
+			return fn, fn
+		}
+		return newStringReduceIntegerIterator(input, opt, createFn), nil
+	case *nilFloatIterator:
+		// No shards were mapped so there are no sketches to read.
+		return input, nil
+	default:
+		return nil, fmt.Errorf("unsupported count_distinct_approx iterator type: %T", input)
+	}
+}
+
 // NewFloatPercentileReduceSliceFunc returns the percentile value within a window.
 func NewFloatPercentileReduceSliceFunc(percentile float64) FloatReduceSliceFunc {
 	return func(a []FloatPoint) []FloatPoint {
@@ -3,6 +3,7 @@ package query
 import (
 	"errors"
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
@@ -62,6 +63,9 @@ type compiledStatement struct {
 	// HasDistinct is set when the distinct() function is encountered.
 	HasDistinct bool
 
+	// HasHistogram is set when the histogram() function is encountered.
+	HasHistogram bool
+
 	// FillOption contains the fill option for aggregates.
 	FillOption influxql.FillOption
 
@@ -188,7 +192,98 @@ func (c *compiledStatement) compile(stmt *influxql.SelectStatement) error {
 			if err := c.subquery(source.Statement); err != nil {
 				return err
 			}
+		case *influxql.Join:
+			if err := c.compileJoin(stmt, source); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// compileJoin validates a join and replaces each side of the join with a
+// subquery that reads the referenced fields grouped by the join tags.
+func (c *compiledStatement) compileJoin(stmt *influxql.SelectStatement, join *influxql.Join) error {
+	if len(stmt.Sources) > 1 {
+		return errors.New("JOIN cannot be combined with other sources")
+	} else if stmt.HasFieldWildcard() || stmt.HasDimensionWildcard() {
+		return errors.New("wildcards are not supported with JOIN")
+	}
+
+	sides := []*influxql.Source{&join.LHS, &join.RHS}
+	names := make([]string, len(sides))
+	for i, src := range sides {
+		if names[i] = joinSourceName(*src); names[i] == "" {
+			return fmt.Errorf("invalid JOIN source: %s", *src)
+		}
+	}
+	if names[0] == names[1] {
+		return fmt.Errorf("JOIN sources must have different names: %s", names[0])
+	}
+
+	// Joined rows only carry the join tags so only those can be grouped.
+	for _, d := range stmt.Dimensions {
+		switch expr := d.Expr.(type) {
+		case *influxql.VarRef:
+			if !isJoinTag(join, expr.Val) {
+				return fmt.Errorf("GROUP BY tag %s must be included in the JOIN ON clause", expr.Val)
+			}
+		case *influxql.RegexLiteral:
+			return errors.New("wildcards are not supported with JOIN")
+		}
+	}
+
+	// Find the fields referenced from each side of the join. Every reference
+	// must either be a join tag or qualified by the name of its source.
+	fields := make([][]string, len(sides))
+	var err error
+	visit := func(n influxql.Node) {
+		ref, ok := n.(*influxql.VarRef)
+		if !ok || err != nil || ref.Val == "time" || isJoinTag(join, ref.Val) {
+			return
+		}
+		for i, name := range names {
+			if strings.HasPrefix(ref.Val, name+".") {
+				field := strings.TrimPrefix(ref.Val, name+".")
+				for _, f := range fields[i] {
+					if f == field {
+						return
+					}
+				}
+				fields[i] = append(fields[i], field)
+				return
+			}
 		}
+		err = fmt.Errorf("unable to resolve %s in JOIN: fields must be qualified by their measurement", ref.Val)
+	}
+	for _, f := range stmt.Fields {
+		influxql.WalkFunc(f.Expr, visit)
+	}
+	influxql.WalkFunc(c.Condition, visit)
+	if err != nil {
+		return err
+	}
+
+	for i, src := range sides {
+		if len(fields[i]) == 0 {
+			return fmt.Errorf("JOIN requires at least one field from %s", names[i])
+		}
+
+		other := &influxql.SelectStatement{
+			Sources:    influxql.Sources{*src},
+			IsRawQuery: true,
+			OmitTime:   true,
+		}
+		for _, f := range fields[i] {
+			other.Fields = append(other.Fields, &influxql.Field{Expr: &influxql.VarRef{Val: f}})
+		}
+		for _, tag := range join.On {
+			other.Dimensions = append(other.Dimensions, &influxql.Dimension{Expr: &influxql.VarRef{Val: tag}})
+		}
+		if err := c.subquery(other); err != nil {
+			return err
+		}
+		*src = &influxql.SubQuery{Statement: other}
 	}
 	return nil
 }
@@ -269,6 +364,12 @@ func (c *compiledField) compileExpr(expr influxql.Expr) error {
 		switch expr.Name {
 		case "percentile":
 			return c.compilePercentile(expr.Args)
+		case "quantile_approx":
+			return c.compileQuantileApprox(expr.Args)
+		case "count_distinct_approx":
+			return c.compileCountDistinctApprox(expr.Args)
+		case "histogram":
+			return c.compileHistogram(expr.Args)
 		case "sample":
 			return c.compileSample(expr.Args)
 		case "distinct":
@@ -380,7 +481,7 @@ func (c *compiledField) compileFunction(expr *influxql.Call) error {
 	switch expr.Name {
 	case "max", "min", "first", "last":
 		// top/bottom are not included here since they are not typical functions.
-	case "count", "sum", "mean", "median", "mode", "stddev", "spread":
+	case "count", "sum", "mean", "median", "mode", "stddev", "spread", "sketch":
 		// These functions are not considered selectors.
 		c.global.OnlySelectors = false
 	default:
@@ -418,6 +519,72 @@ func (c *compiledField) compilePercentile(args []influxql.Expr) error {
 	return c.compileSymbol("percentile", args[0])
 }
 
+func (c *compiledField) compileQuantileApprox(args []influxql.Expr) error {
+	if exp, got := 2, len(args); got != exp {
+		return fmt.Errorf("invalid number of arguments for quantile_approx, expected %d, got %d", exp, got)
+	}
+
+	var quantile float64
+	switch arg1 := args[1].(type) {
+	case *influxql.IntegerLiteral:
+		quantile = float64(arg1.Val)
+	case *influxql.NumberLiteral:
+		quantile = arg1.Val
+	default:
+		return fmt.Errorf("expected float argument in quantile_approx()")
+	}
+	if quantile < 0 || quantile > 1 {
+		return fmt.Errorf("quantile must be between 0 and 1, got %v", quantile)
+	}
+	c.global.OnlySelectors = false
+	return c.compileSymbol("quantile_approx", args[0])
+}
+
+func (c *compiledField) compileCountDistinctApprox(args []influxql.Expr) error {
+	if min, max, got := 1, 2, len(args); got > max || got < min {
+		return fmt.Errorf("invalid number of arguments for count_distinct_approx, expected at least %d but no more than %d, got %d", min, max, got)
+	}
+
+	if len(args) == 2 {
+		switch arg1 := args[1].(type) {
+		case *influxql.IntegerLiteral:
+			if arg1.Val < 4 || arg1.Val > 18 {
+				return fmt.Errorf("count_distinct_approx precision must be between 4 and 18, got %d", arg1.Val)
+			}
+		default:
+			return fmt.Errorf("expected integer argument as second arg in count_distinct_approx()")
+		}
+	}
+	c.global.OnlySelectors = false
+	return c.compileSymbol("count_distinct_approx", args[0])
+}
+
+func (c *compiledField) compileHistogram(args []influxql.Expr) error {
+	if exp, got := 2, len(args); got < exp {
+		return fmt.Errorf("invalid number of arguments for histogram, expected at least %d, got %d", exp, got)
+	}
+
+	prev := math.Inf(-1)
+	for _, arg := range args[1:] {
+		var bound float64
+		switch arg := arg.(type) {
+		case *influxql.IntegerLiteral:
+			bound = float64(arg.Val)
+		case *influxql.NumberLiteral:
+			bound = arg.Val
+		default:
+			return fmt.Errorf("expected number argument in histogram()")
+		}
+		if bound <= prev {
+			return fmt.Errorf("histogram bucket bounds must be in increasing order")
+		}
+		prev = bound
+	}
+	c.global.HasHistogram = true
+	c.global.OnlySelectors = false
+	return c.compileSymbol("histogram", args[0])
+}
+
 func (c *compiledField) compileSample(args []influxql.Expr) error {
 	if exp, got := 2, len(args); got != exp {
 		return fmt.Errorf("invalid number of arguments for sample, expected %d, got %d", exp, got)
@@ -910,6 +1077,12 @@ func (c *compiledStatement) compileDimensions(stmt *influxql.SelectStatement) er
 				return errors.New("only time() calls allowed in dimensions")
 			} else if got := len(expr.Args); got < 1 || got > 2 {
 				return errors.New("time dimension expected 1 or 2 arguments")
+			} else if lit, ok := expr.Args[0].(*influxql.CalendarDurationLiteral); ok {
+				if c.Interval.Duration != 0 {
+					return errors.New("multiple time dimensions not allowed")
+				} else if err := c.compileCalendarInterval(expr, lit); err != nil {
+					return err
+				}
 			} else if lit, ok := expr.Args[0].(*influxql.DurationLiteral); !ok {
 				return errors.New("time dimension must have duration argument")
 			} else if c.Interval.Duration != 0 {
@@ -936,8 +1109,16 @@ func (c *compiledStatement) compileDimensions(stmt *influxql.SelectStatement) er
 						// to use the compiler information yet.
 						expr.Args[1] = &influxql.DurationLiteral{Val: c.Interval.Offset}
 					case *influxql.StringLiteral:
+						// A weekday starts the weeks of the interval on that day.
 						// If literal looks like a date time then parse it as a time literal.
-						if lit.IsTimeLiteral() {
+						if weekday, ok := parseWeekday(lit.Val); ok {
+							if c.Interval.Duration%(7*24*time.Hour) != 0 {
+								return errors.New("time dimension with a weekday offset must be a multiple of 1w")
+							}
+							// Windows are aligned to the epoch, which was a Thursday.
+							c.Interval.Offset = time.Duration((weekday-time.Thursday+7)%7) * 24 * time.Hour
+							expr.Args[1] = &influxql.DurationLiteral{Val: c.Interval.Offset}
+						} else if lit.IsTimeLiteral() {
 							t, err := lit.ToTimeLiteral(stmt.Location)
 							if err != nil {
 								return err
@@ -963,6 +1144,32 @@ func (c *compiledStatement) compileDimensions(stmt *influxql.SelectStatement) er
 	return nil
 }
 
+// compileCalendarInterval compiles a time dimension with a calendar duration.
+// The offset can only be a duration since calendar windows do not have a
+// fixed length to align a time to.
+func (c *compiledStatement) compileCalendarInterval(call *influxql.Call, lit *influxql.CalendarDurationLiteral) error {
+	c.Interval.Duration = lit.Duration()
+	c.Interval.Months = lit.Months
+	if len(call.Args) == 2 {
+		offset, ok := call.Args[1].(*influxql.DurationLiteral)
+		if !ok {
+			return errors.New("calendar time dimension offset must be a duration")
+		}
+		c.Interval.Offset = offset.Val
+	}
+	return nil
+}
+
+// parseWeekday returns the day of the week with the name s.
+func parseWeekday(s string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(s, d.String()) {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
 // validateFields validates that the fields are mutually compatible with each other.
 // This runs at the end of compilation but before linking.
 func (c *compiledStatement) validateFields() error {
@@ -979,6 +1186,8 @@ func (c *compiledStatement) validateFields() error {
 			return errors.New("fill(none) must be used with a function")
 		case influxql.LinearFill:
 			return errors.New("fill(linear) must be used with a function")
+		case influxql.InterpolateTimeFill:
+			return errors.New("fill(interpolate_time) must be used with a function")
 		}
 		if !c.Interval.IsZero() && !c.InheritedInterval {
 			return errors.New("GROUP BY requires at least one aggregate function")
@@ -988,6 +1197,10 @@ func (c *compiledStatement) validateFields() error {
 	if c.HasDistinct && (len(c.FunctionCalls) != 1 || c.HasAuxiliaryFields) {
 		return errors.New("aggregate function distinct() cannot be combined with other functions or fields")
 	}
+	// If a histogram() call is present, ensure there is exactly one function.
+	if c.HasHistogram && (len(c.FunctionCalls) != 1 || c.HasAuxiliaryFields) {
+		return errors.New("aggregate function histogram() cannot be combined with other functions or fields")
+	}
 	// Validate we are using a selector or raw query if auxiliary fields are required.
 	if c.HasAuxiliaryFields {
 		if !c.OnlySelectors {
@@ -1143,6 +1356,28 @@ func (c *compiledStatement) Prepare(shardMapper ShardMapper, sopt SelectOptions)
 		}
 	}
 
+	// Map the shards around the time range to find the nearest points of each
+	// series for filling the windows at the edges of the time range.
+	if !c.Interval.IsZero() && sopt.MaxFillLookback > 0 && hasFillEdgeCall(c.stmt.Fields) {
+		switch c.FillOption {
+		case influxql.PreviousFill, influxql.LinearFill, influxql.InterpolateTimeFill:
+			if !timeRange.Min.IsZero() {
+				if newTime := timeRange.Min.Add(-sopt.MaxFillLookback); newTime.After(time.Unix(0, influxql.MinTime).UTC()) {
+					timeRange.Min = newTime
+				} else {
+					timeRange.Min = time.Unix(0, influxql.MinTime).UTC()
+				}
+			}
+			if !timeRange.Max.IsZero() {
+				if newTime := timeRange.Max.Add(sopt.MaxFillLookback); newTime.Before(time.Unix(0, influxql.MaxTime).UTC()) {
+					timeRange.Max = newTime
+				} else {
+					timeRange.Max = time.Unix(0, influxql.MaxTime).UTC()
+				}
+			}
+		}
+	}
+
 	// Create an iterator creator based on the shards in the cluster.
 	shards, err := shardMapper.MapShards(c.stmt.Sources, timeRange, sopt)
 	if err != nil {
@@ -1157,6 +1392,12 @@ func (c *compiledStatement) Prepare(shardMapper ShardMapper, sopt SelectOptions)
 		return nil, err
 	}
 
+	// Assign types to the fields read through a JOIN.
+	if _, err := rewriteJoinFields(stmt, mapper); err != nil {
+		shards.Close()
+		return nil, err
+	}
+
 	// Validate if the types are correct now that they have been assigned.
 	if err := validateTypes(stmt); err != nil {
 		shards.Close()
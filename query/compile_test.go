@@ -65,6 +65,16 @@ func TestCompile_Success(t *testing.T) {
 		`SELECT max(bottom) FROM (SELECT bottom(value, host, 1) FROM cpu) GROUP BY region`,
 		`SELECT percentile(value, 75) FROM cpu`,
 		`SELECT percentile(value, 75.0) FROM cpu`,
+		`SELECT quantile_approx(value, 0.99) FROM cpu`,
+		`SELECT quantile_approx(value, 1), mean(value) FROM cpu`,
+		`SELECT quantile_approx(s, 0.5) FROM (SELECT sketch(value) AS s FROM cpu GROUP BY time(1m)) WHERE time > now() - 1h GROUP BY time(10m)`,
+		`SELECT histogram(value, 10, 20.5, 100) FROM cpu`,
+		`SELECT sketch(value) FROM cpu`,
+		`SELECT count_distinct_approx(value) FROM cpu`,
+		`SELECT count_distinct_approx(value, 12), count(value) FROM cpu`,
+		`SELECT cpu.usage / cpu_count.n FROM cpu INNER JOIN cpu_count ON host`,
+		`SELECT cpu.usage, cpu_count.n, host FROM cpu OUTER JOIN cpu_count ON host WHERE cpu.usage > 10 fill(0)`,
+		`SELECT mean(cpu.usage) FROM cpu INNER JOIN (SELECT max(n) AS n FROM cpu_count GROUP BY time(1m), host) ON host WHERE cpu_count.n > 0 GROUP BY time(1m), host`,
 		`SELECT sample(value, 2) FROM cpu`,
 		`SELECT sample(*, 2) FROM cpu`,
 		`SELECT sample(/val/, 2) FROM cpu`,
@@ -75,6 +85,9 @@ func TestCompile_Success(t *testing.T) {
 		`SELECT max(value) FROM cpu WHERE time >= now() - 1m GROUP BY time(10s, 5s)`,
 		`SELECT max(value) FROM cpu WHERE time >= now() - 1m GROUP BY time(10s, '2000-01-01T00:00:05Z')`,
 		`SELECT max(value) FROM cpu WHERE time >= now() - 1m GROUP BY time(10s, now())`,
+		`SELECT sum(value) FROM cpu WHERE time >= now() - 52w GROUP BY time(1mo)`,
+		`SELECT sum(value) FROM cpu WHERE time >= now() - 52w GROUP BY time(1q, 1d) tz('America/Los_Angeles')`,
+		`SELECT sum(value) FROM cpu WHERE time >= now() - 10w GROUP BY time(1w, 'monday')`,
 		`SELECT max(mean) FROM (SELECT mean(value) FROM cpu GROUP BY host)`,
 		`SELECT max(derivative) FROM (SELECT derivative(mean(value)) FROM cpu) WHERE time >= now() - 1m GROUP BY time(10s)`,
 		`SELECT max(value) FROM (SELECT value + total FROM cpu) WHERE time >= now() - 1m GROUP BY time(10s)`,
@@ -162,6 +175,10 @@ func TestCompile_Failures(t *testing.T) {
 		{s: `SELECT value FROM cpu GROUP BY time(5m, unexpected())`, err: `time dimension offset function must be now()`},
 		{s: `SELECT value FROM cpu GROUP BY time(5m, now(1m))`, err: `time dimension offset now() function requires no arguments`},
 		{s: `SELECT value FROM cpu GROUP BY time(5m, 'unexpected')`, err: `time dimension offset must be duration or now()`},
+		{s: `SELECT count(value) FROM cpu GROUP BY time(1mo, now())`, err: `calendar time dimension offset must be a duration`},
+		{s: `SELECT count(value) FROM cpu GROUP BY time(1y, '2000-01-01T00:00:00Z')`, err: `calendar time dimension offset must be a duration`},
+		{s: `SELECT count(value) FROM cpu GROUP BY time(1mo), time(1d)`, err: `multiple time dimensions not allowed`},
+		{s: `SELECT count(value) FROM cpu GROUP BY time(1d, 'monday')`, err: `time dimension with a weekday offset must be a multiple of 1w`},
 		{s: `SELECT value FROM cpu GROUP BY 'unexpected'`, err: `only time and tag dimensions allowed`},
 		{s: `SELECT top(value) FROM cpu`, err: `invalid number of arguments for top, expected at least 2, got 1`},
 		{s: `SELECT top('unexpected', 5) FROM cpu`, err: `expected first argument to be a field in top(), found 'unexpected'`},
@@ -223,9 +240,31 @@ func TestCompile_Failures(t *testing.T) {
 		{s: `SELECT percentile(field1) FROM myseries`, err: `invalid number of arguments for percentile, expected 2, got 1`},
 		{s: `SELECT percentile(field1, foo) FROM myseries`, err: `expected float argument in percentile()`},
 		{s: `SELECT percentile(max(field1), 75) FROM myseries`, err: `expected field argument in percentile()`},
+		{s: `SELECT quantile_approx(field1) FROM myseries`, err: `invalid number of arguments for quantile_approx, expected 2, got 1`},
+		{s: `SELECT quantile_approx(field1, foo) FROM myseries`, err: `expected float argument in quantile_approx()`},
+		{s: `SELECT quantile_approx(field1, 1.5) FROM myseries`, err: `quantile must be between 0 and 1, got 1.5`},
+		{s: `SELECT quantile_approx(field1, 0.5), field2 FROM myseries`, err: `mixing aggregate and non-aggregate queries is not supported`},
+		{s: `SELECT histogram(field1) FROM myseries`, err: `invalid number of arguments for histogram, expected at least 2, got 1`},
+		{s: `SELECT histogram(field1, foo) FROM myseries`, err: `expected number argument in histogram()`},
+		{s: `SELECT histogram(field1, 10, 5) FROM myseries`, err: `histogram bucket bounds must be in increasing order`},
+		{s: `SELECT histogram(field1, 10), mean(field1) FROM myseries`, err: `aggregate function histogram() cannot be combined with other functions or fields`},
+		{s: `SELECT sketch(field1, 2) FROM myseries`, err: `invalid number of arguments for sketch, expected 1, got 2`},
+		{s: `SELECT count_distinct_approx() FROM myseries`, err: `invalid number of arguments for count_distinct_approx, expected at least 1 but no more than 2, got 0`},
+		{s: `SELECT count_distinct_approx(field1, 3) FROM myseries`, err: `count_distinct_approx precision must be between 4 and 18, got 3`},
+		{s: `SELECT count_distinct_approx(field1, 1.5) FROM myseries`, err: `expected integer argument as second arg in count_distinct_approx()`},
+		{s: `SELECT count_distinct_approx(max(field1)) FROM myseries`, err: `expected field argument in count_distinct_approx()`},
+		{s: `SELECT cpu.usage FROM cpu INNER JOIN cpu_count ON host`, err: `JOIN requires at least one field from cpu_count`},
+		{s: `SELECT usage, cpu_count.n FROM cpu INNER JOIN cpu_count ON host`, err: `unable to resolve usage in JOIN: fields must be qualified by their measurement`},
+		{s: `SELECT * FROM cpu INNER JOIN cpu_count ON host`, err: `wildcards are not supported with JOIN`},
+		{s: `SELECT mean(cpu.usage) FROM cpu INNER JOIN cpu_count ON host WHERE cpu_count.n > 0 GROUP BY region`, err: `GROUP BY tag region must be included in the JOIN ON clause`},
+		{s: `SELECT cpu.usage FROM cpu INNER JOIN cpu ON host`, err: `JOIN sources must have different names: cpu`},
+		{s: `SELECT cpu.usage FROM /cpu/ INNER JOIN cpu_count ON host`, err: `invalid JOIN source: /cpu/`},
+		{s: `SELECT cpu.usage, mem.free FROM disk, cpu INNER JOIN mem ON host`, err: `JOIN cannot be combined with other sources`},
+		{s: `SELECT hll(field1, 16) FROM myseries`, err: `undefined function hll()`},
 		{s: `SELECT field1 FROM foo group by time(1s)`, err: `GROUP BY requires at least one aggregate function`},
 		{s: `SELECT field1 FROM foo fill(none)`, err: `fill(none) must be used with a function`},
 		{s: `SELECT field1 FROM foo fill(linear)`, err: `fill(linear) must be used with a function`},
+		{s: `SELECT field1 FROM foo fill(interpolate_time)`, err: `fill(interpolate_time) must be used with a function`},
 		{s: `SELECT count(value), value FROM foo`, err: `mixing aggregate and non-aggregate queries is not supported`},
 		{s: `SELECT count(value) FROM foo group by time`, err: `time() is a function and expects at least one argument`},
 		{s: `SELECT count(value) FROM foo group by 'time'`, err: `only time and tag dimensions allowed`},
@@ -3,6 +3,8 @@ package query
 import (
 	"context"
 	"sync"
+
+	"github.com/influxdata/influxdb/pkg/tracing"
 )
 
 // ExecutionContext contains state that the query is currently executing with.
@@ -24,6 +26,16 @@ type ExecutionContext struct {
 	// Options used to start this query.
 	ExecutionOptions
 
+	// The trace and root span of the executing statement if it is profiled.
+	trace *tracing.Trace
+	span  *tracing.Span
+
+	// Statistics of the executing statement.
+	stats StatementStats
+
+	// Warnings raised while executing the statement.
+	warnings Warnings
+
 	mu   sync.RWMutex
 	done chan struct{}
 	err  error
@@ -84,14 +96,41 @@ func (ctx *ExecutionContext) Value(key interface{}) interface{} {
 	switch key {
 	case monitorContextKey:
 		return ctx.task
+	case progressContextKey:
+		if ctx.task != nil {
+			return &ctx.task.progress
+		}
+		return nil
+	case warningsContextKey:
+		return &ctx.warnings
 	}
 	return ctx.Context.Value(key)
 }
 
+// TraceContext returns a context carrying the trace of the executing
+// statement if it is profiled so that the work done with it is recorded in
+// the profile. Otherwise it returns ctx.
+func (ctx *ExecutionContext) TraceContext() context.Context {
+	if ctx.span == nil {
+		return ctx
+	}
+	return tracing.NewContextWithSpan(tracing.NewContextWithTrace(ctx, ctx.trace), ctx.span)
+}
+
+// AddIteratorStats counts the series and points scanned by the executing
+// statement. Points are counted against the quotas of the query while they
+// are scanned, through the Progress of the query.
+func (ctx *ExecutionContext) AddIteratorStats(stats IteratorStats) {
+	ctx.stats.SeriesN += stats.SeriesN
+	ctx.stats.PointN += stats.PointN
+}
+
 // send sends a Result to the Results channel and will exit if the query has
 // been aborted.
 func (ctx *ExecutionContext) send(result *Result) error {
 	result.StatementID = ctx.statementID
+	result.Messages = append(result.Messages, ctx.warnings.take()...)
+	ctx.countRows(result)
 	select {
 	case <-ctx.AbortCh:
 		return ErrQueryAborted
@@ -101,9 +140,12 @@ func (ctx *ExecutionContext) send(result *Result) error {
 }
 
 // Send sends a Result to the Results channel and will exit if the query has
-// been interrupted or aborted.
+// been interrupted or aborted. The warnings raised since the previous result
+// are returned with it.
 func (ctx *ExecutionContext) Send(result *Result) error {
 	result.StatementID = ctx.statementID
+	result.Messages = append(result.Messages, ctx.warnings.take()...)
+	ctx.countRows(result)
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
@@ -111,3 +153,10 @@ func (ctx *ExecutionContext) Send(result *Result) error {
 	}
 	return nil
 }
+
+// countRows counts the rows returned by the executing statement.
+func (ctx *ExecutionContext) countRows(result *Result) {
+	for _, row := range result.Series {
+		ctx.stats.RowN += len(row.Values)
+	}
+}
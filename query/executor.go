@@ -11,7 +11,10 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/influxdata/influxdb/logger"
 	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/tracing"
+	"github.com/influxdata/influxdb/pkg/tracing/fields"
 	"github.com/influxdata/influxql"
 	"go.uber.org/zap"
 )
@@ -114,6 +117,19 @@ func AuthorizerIsOpen(a Authorizer) bool {
 	return a == nil || a == OpenAuthorizer
 }
 
+// AuthorizerForRetentionPolicy returns an Authorizer whose series
+// authorization applies to the series of the given retention policy.
+// Authorizers which don't authorize series per retention policy are returned
+// unchanged.
+func AuthorizerForRetentionPolicy(a Authorizer, retentionPolicy string) Authorizer {
+	if pa, ok := a.(interface {
+		ForRetentionPolicy(retentionPolicy string) Authorizer
+	}); ok && retentionPolicy != "" {
+		return pa.ForRetentionPolicy(retentionPolicy)
+	}
+	return a
+}
+
 // ExecutionOptions contains the options for executing a query.
 type ExecutionOptions struct {
 	// The database the query is running against.
@@ -122,6 +138,9 @@ type ExecutionOptions struct {
 	// The retention policy the query is running against.
 	RetentionPolicy string
 
+	// The name of the authenticated user running the query.
+	UserID string
+
 	// How to determine whether the query is allowed to execute,
 	// what resources can be returned in SHOW queries, etc.
 	Authorizer Authorizer
@@ -138,8 +157,17 @@ type ExecutionOptions struct {
 	// Quiet suppresses non-essential output from the query executor.
 	Quiet bool
 
+	// Profile returns the span tree recorded while executing each statement
+	// with the results of the statement.
+	Profile bool
+
 	// AbortCh is a channel that signals when results are no longer desired by the caller.
 	AbortCh <-chan struct{}
+
+	// Attached receives the id of the query once it is attached to the
+	// TaskManager and is closed afterwards. It is closed without a value if
+	// the query could not be attached. It must be buffered.
+	Attached chan<- uint64
 }
 
 type contextKey int
@@ -147,6 +175,8 @@ type contextKey int
 const (
 	iteratorsContextKey contextKey = iota
 	monitorContextKey
+	progressContextKey
+	warningsContextKey
 )
 
 // NewContextWithIterators returns a new context.Context with the *Iterators slice added.
@@ -205,7 +235,7 @@ type Statistics struct {
 
 // Statistics returns statistics for periodic monitoring.
 func (e *Executor) Statistics(tags map[string]string) []models.Statistic {
-	return []models.Statistic{{
+	statistics := []models.Statistic{{
 		Name: "queryExecutor",
 		Tags: tags,
 		Values: map[string]interface{}{
@@ -216,6 +246,10 @@ func (e *Executor) Statistics(tags map[string]string) []models.Statistic {
 			statRecoveredPanics:        atomic.LoadInt64(&e.stats.RecoveredPanics),
 		},
 	}}
+	if e.TaskManager.Quotas != nil {
+		statistics = append(statistics, e.TaskManager.Quotas.Statistics(tags)...)
+	}
+	return statistics
 }
 
 // Close kills all running queries and prevents new queries from being attached.
@@ -250,6 +284,12 @@ func (e *Executor) executeQuery(query *influxql.Query, opt ExecutionOptions, clo
 	}(time.Now())
 
 	ctx, detach, err := e.TaskManager.AttachQuery(query, opt, closing)
+	if opt.Attached != nil {
+		if err == nil {
+			opt.Attached <- ctx.QueryID
+		}
+		close(opt.Attached)
+	}
 	if err != nil {
 		select {
 		case results <- &Result{Err: err}:
@@ -266,6 +306,7 @@ func (e *Executor) executeQuery(query *influxql.Query, opt ExecutionOptions, clo
 LOOP:
 	for ; i < len(query.Statements); i++ {
 		ctx.statementID = i
+		ctx.warnings.reset()
 		stmt := query.Statements[i]
 
 		// If a default database wasn't passed in by the caller, check the statement.
@@ -330,8 +371,18 @@ LOOP:
 			e.Logger.Info("Executing query", zap.Stringer("query", stmt))
 		}
 
+		// Trace the statement if its profile was requested or if the profile
+		// of slow statements is logged.
+		if ctx.Profile || e.TaskManager.ProfileQueriesAfter > 0 {
+			ctx.trace, ctx.span = tracing.NewTrace("statement")
+		}
+		start := time.Now()
+
 		// Send any other statements to the underlying statement executor.
 		err = e.StatementExecutor.ExecuteStatement(stmt, ctx)
+		d := time.Since(start)
+		profile := e.finishProfile(ctx, stmt, d)
+		e.recordStatement(ctx, stmt, statementDatabase(stmt, defaultDB), d)
 		if err == ErrQueryInterrupted {
 			// Query was interrupted so retrieve the real interrupt error from
 			// the query task if there is one.
@@ -345,6 +396,7 @@ LOOP:
 			if err := ctx.send(&Result{
 				StatementID: i,
 				Err:         err,
+				Profile:     profile,
 			}); err == ErrQueryAborted {
 				return
 			}
@@ -352,6 +404,14 @@ LOOP:
 			break
 		}
 
+		// Send the profile and the warnings raised after the last result
+		// after all of the results of the statement.
+		if profile != nil || ctx.warnings.Pending() {
+			if err := ctx.send(&Result{Profile: profile}); err == ErrQueryAborted {
+				return
+			}
+		}
+
 		// Check if the query was interrupted during an uninterruptible statement.
 		interrupted := false
 		select {
@@ -377,6 +437,109 @@ LOOP:
 	}
 }
 
+// finishProfile finishes the trace of a profiled statement and logs it if the
+// statement was slower than the ProfileQueriesAfter threshold. It returns the
+// profile if it was requested by the caller.
+func (e *Executor) finishProfile(ctx *ExecutionContext, stmt influxql.Statement, d time.Duration) *Profile {
+	if ctx.span == nil {
+		return nil
+	}
+	trace, span := ctx.trace, ctx.span
+	ctx.trace, ctx.span = nil, nil
+
+	span.MergeFields(fields.Duration("total_time", d))
+	span.Finish()
+
+	tree := trace.Tree()
+	if threshold := e.TaskManager.ProfileQueriesAfter; threshold > 0 && d >= threshold {
+		e.Logger.Warn("Slow statement profile",
+			zap.Stringer("query", stmt),
+			zap.Uint64("qid", ctx.QueryID),
+			zap.Duration("duration", d),
+			zap.String("profile", tree.String()))
+	}
+
+	if !ctx.Profile {
+		return nil
+	}
+	return NewProfile(tree)
+}
+
+// recordStatement adds the statistics of an executed statement to the query
+// stats and writes them to the slow query log if the statement was slower
+// than the LogQueriesAfter threshold.
+func (e *Executor) recordStatement(ctx *ExecutionContext, stmt influxql.Statement, database string, d time.Duration) {
+	stats := ctx.stats
+	ctx.stats = StatementStats{}
+
+	threshold := e.TaskManager.LogQueriesAfter
+	slow := threshold > 0 && d >= threshold
+	if e.TaskManager.QueryStats == nil && !slow {
+		return
+	}
+
+	stats.Fingerprint = Fingerprint(stmt)
+	stats.Database = database
+	stats.User = ctx.UserID
+	stats.Duration = d
+	if e.TaskManager.QueryStats != nil {
+		e.TaskManager.QueryStats.Add(stats)
+	}
+
+	if slow {
+		log := e.TaskManager.SlowQueryLogger
+		if log == nil {
+			log = e.Logger
+		}
+		log.Warn("Slow query",
+			zap.String("fingerprint", stats.Fingerprint),
+			logger.Database(stats.Database),
+			zap.String("user", stats.User),
+			zap.Uint64("qid", ctx.QueryID),
+			zap.Duration("duration", stats.Duration),
+			zap.Int("series_scanned", stats.SeriesN),
+			zap.Int("points_scanned", stats.PointN),
+			zap.Int("rows_returned", stats.RowN),
+			zap.Stringer("query", stmt))
+	}
+}
+
+// statementDatabase returns the database used by a normalized statement. The
+// first database of the sources of a SELECT statement is used. defaultDB is
+// returned if the statement doesn't name a database.
+func statementDatabase(stmt influxql.Statement, defaultDB string) string {
+	switch stmt := stmt.(type) {
+	case *influxql.SelectStatement:
+		if db := sourcesDatabase(stmt.Sources); db != "" {
+			return db
+		}
+	case influxql.HasDefaultDatabase:
+		if db := stmt.DefaultDatabase(); db != "" {
+			return db
+		}
+	}
+	return defaultDB
+}
+
+// sourcesDatabase returns the first database named by the sources.
+func sourcesDatabase(sources influxql.Sources) string {
+	for _, src := range sources {
+		var db string
+		switch src := src.(type) {
+		case *influxql.Measurement:
+			db = src.Database
+		case *influxql.SubQuery:
+			db = sourcesDatabase(src.Statement.Sources)
+		case *influxql.Join:
+			db = sourcesDatabase(influxql.Sources{src.LHS, src.RHS})
+		}
+		if db != "" {
+			return db
+		}
+	}
+	return ""
+}
+
 // Determines if the Executor will recover any panics or let them crash
 // the server.
 var willCrash bool
@@ -411,14 +574,36 @@ func (e *Executor) recover(query *influxql.Query, results chan *Result) {
 type Task struct {
 	query     string
 	database  string
+	user      string
+	quotas    *Quotas
 	status    TaskStatus
 	startTime time.Time
 	closing   chan struct{}
 	monitorCh chan error
+	progress  Progress
 	err       error
 	mu        sync.Mutex
 }
 
+// info returns the information of the query.
+func (q *Task) info(qid uint64, now time.Time) QueryInfo {
+	q.mu.Lock()
+	status := q.status
+	q.mu.Unlock()
+
+	return QueryInfo{
+		ID:              qid,
+		Query:           q.query,
+		Database:        q.database,
+		User:            q.user,
+		Duration:        now.Sub(q.startTime),
+		Status:          status,
+		ShardN:          q.progress.ShardN(),
+		ShardsCompleted: q.progress.ShardsCompleted(),
+		PointN:          q.progress.PointN(),
+	}
+}
+
 // Monitor starts a new goroutine that will monitor a query. The function
 // will be passed in a channel to signal when the query has been finished
 // normally. If the function returns with an error and the query is still
@@ -441,6 +626,14 @@ func (q *Task) setError(err error) {
 	q.mu.Unlock()
 }
 
+// chargePoints counts points scanned by the query against its quotas and
+// kills the query once one of them is exceeded.
+func (q *Task) chargePoints(n int64) {
+	if err := q.quotas.chargePoints(q.user, q.database, n); err != nil {
+		q.monitor(func(<-chan struct{}) error { return err })
+	}
+}
+
 func (q *Task) monitor(fn MonitorFunc) {
 	if err := fn(q.closing); err != nil {
 		select {
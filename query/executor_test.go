@@ -1,12 +1,18 @@
 package query_test
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/influxdata/influxdb/logger"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/tracing"
+	"github.com/influxdata/influxdb/pkg/tracing/fields"
 	"github.com/influxdata/influxdb/query"
 	"github.com/influxdata/influxql"
 )
@@ -44,6 +50,102 @@ func TestQueryExecutor_AttachQuery(t *testing.T) {
 	discardOutput(e.ExecuteQuery(q, query.ExecutionOptions{}, nil))
 }
 
+func TestQueryExecutor_Progress(t *testing.T) {
+	q, err := influxql.ParseQuery(`SELECT count(value) FROM cpu`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := NewQueryExecutor()
+	e.StatementExecutor = &StatementExecutor{
+		ExecuteStatementFn: func(stmt influxql.Statement, ctx *query.ExecutionContext) error {
+			p := query.ProgressFromContext(ctx)
+			p.AddShards(3)
+			p.CompleteShard()
+			p.AddPointsScanned(10)
+
+			info, ok := e.TaskManager.Query(ctx.QueryID)
+			if !ok {
+				return fmt.Errorf("no such query id: %d", ctx.QueryID)
+			}
+			info.Duration = 0
+			if got, want := info, (query.QueryInfo{
+				ID:              ctx.QueryID,
+				Query:           "SELECT count(value) FROM cpu",
+				Database:        "db0",
+				User:            "user0",
+				Status:          query.RunningTask,
+				ShardN:          3,
+				ShardsCompleted: 1,
+				PointN:          10,
+			}); !reflect.DeepEqual(got, want) {
+				return fmt.Errorf("unexpected query info: got=%#v want=%#v", got, want)
+			}
+			return nil
+		},
+	}
+
+	attached := make(chan uint64, 1)
+	results := e.ExecuteQuery(q, query.ExecutionOptions{Database: "db0", UserID: "user0", Attached: attached}, nil)
+	if qid, ok := <-attached; !ok || qid != 1 {
+		t.Fatalf("unexpected query id: %d", qid)
+	}
+	for result := range results {
+		if result.Err != nil {
+			t.Fatal(result.Err)
+		}
+	}
+	if _, ok := e.TaskManager.Query(1); ok {
+		t.Fatal("expected the query to be detached")
+	}
+}
+
+func TestQueryExecutor_Warnings(t *testing.T) {
+	q, err := influxql.ParseQuery(`SELECT count(value) FROM cpu; SELECT count(value) FROM mem`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := NewQueryExecutor()
+	e.StatementExecutor = &StatementExecutor{
+		ExecuteStatementFn: func(stmt influxql.Statement, ctx *query.ExecutionContext) error {
+			w := query.WarningsFromContext(ctx)
+			w.Add("warning 1")
+			w.Add("warning 1")
+			if err := ctx.Send(&query.Result{}); err != nil {
+				return err
+			}
+
+			// Warnings raised after the last result are sent on their own.
+			w.Add("warning 2")
+			return nil
+		},
+	}
+
+	var got [][]string
+	for result := range e.ExecuteQuery(q, query.ExecutionOptions{}, nil) {
+		if result.Err != nil {
+			t.Fatal(result.Err)
+		}
+		var texts []string
+		for _, m := range result.Messages {
+			if m.Level != query.WarningLevel {
+				t.Fatalf("unexpected message level: %s", m.Level)
+			}
+			texts = append(texts, fmt.Sprintf("%d: %s", result.StatementID, m.Text))
+		}
+		got = append(got, texts)
+	}
+
+	// Each statement has its own warnings.
+	if want := [][]string{
+		{"0: warning 1"}, {"0: warning 2"},
+		{"1: warning 1"}, {"1: warning 2"},
+	}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected warnings: got=%v want=%v", got, want)
+	}
+}
+
 func TestQueryExecutor_KillQuery(t *testing.T) {
 	q, err := influxql.ParseQuery(`SELECT count(value) FROM cpu`)
 	if err != nil {
@@ -405,6 +507,220 @@ func TestQueryExecutor_Limit_ConcurrentQueries(t *testing.T) {
 	}
 }
 
+func TestQueryExecutor_Limit_Quota(t *testing.T) {
+	q, err := influxql.ParseQuery(`SELECT count(value) FROM cpu`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	qid := make(chan uint64)
+
+	e := NewQueryExecutor()
+	e.StatementExecutor = &StatementExecutor{
+		ExecuteStatementFn: func(stmt influxql.Statement, ctx *query.ExecutionContext) error {
+			qid <- ctx.QueryID
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+	e.TaskManager.Quotas = query.NewQuotas()
+	e.TaskManager.Quotas.UserLimits.MaxConcurrentQueries = 1
+	defer e.Close()
+
+	// Start a query as bob and wait for it to be executing.
+	go discardOutput(e.ExecuteQuery(q, query.ExecutionOptions{UserID: "bob"}, nil))
+	<-qid
+
+	// Queries of other users still run.
+	done := make(chan struct{})
+	go func() {
+		discardOutput(e.ExecuteQuery(q, query.ExecutionOptions{UserID: "alice"}, nil))
+		close(done)
+	}()
+	e.TaskManager.KillQuery(<-qid)
+	<-done
+
+	// A second query of bob fails.
+	results := e.ExecuteQuery(q, query.ExecutionOptions{UserID: "bob"}, nil)
+
+	select {
+	case result := <-results:
+		if _, ok := result.Err.(*query.ErrQuotaExceeded); !ok {
+			t.Errorf("unexpected error: %s", result.Err)
+		}
+	case <-qid:
+		t.Errorf("unexpected statement execution for the second query")
+	}
+}
+
+// Ensure a query is killed once the points it scans exceed a quota.
+func TestQueryExecutor_Quota_PointsScanned(t *testing.T) {
+	q, err := influxql.ParseQuery(`SELECT count(value) FROM cpu`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := NewQueryExecutor()
+	e.StatementExecutor = &StatementExecutor{
+		ExecuteStatementFn: func(stmt influxql.Statement, ctx *query.ExecutionContext) error {
+			p := query.ProgressFromContext(ctx)
+			p.AddPointsScanned(100)
+			p.AddPointsScanned(50)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+	e.TaskManager.Quotas = query.NewQuotas()
+	e.TaskManager.Quotas.DatabaseLimits.MaxPointsScanned = 100
+	defer e.Close()
+
+	results := e.ExecuteQuery(q, query.ExecutionOptions{Database: "db0"}, nil)
+	result := <-results
+	if e, ok := result.Err.(*query.ErrQuotaExceeded); !ok || e.Limit != "max-points-scanned" {
+		t.Fatalf("unexpected error: %v", result.Err)
+	}
+	discardOutput(results)
+}
+
+func TestQueryExecutor_Profile(t *testing.T) {
+	q, err := influxql.ParseQuery(`SELECT count(value) FROM cpu; SELECT count(value) FROM mem`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := NewQueryExecutor()
+	e.StatementExecutor = &StatementExecutor{
+		ExecuteStatementFn: func(stmt influxql.Statement, ctx *query.ExecutionContext) error {
+			span := tracing.SpanFromContext(ctx.TraceContext())
+			if span == nil {
+				t.Error("expected the statement to be traced")
+				return errUnexpected
+			}
+			child := span.StartSpan("create_iterator")
+			child.SetLabels("shard_id", "1")
+			child.MergeFields(fields.Int64("cursors_ref", 2))
+			child.Finish()
+			return ctx.Send(&query.Result{})
+		},
+	}
+
+	results := e.ExecuteQuery(q, query.ExecutionOptions{Profile: true}, nil)
+	for i := 0; i < 2; i++ {
+		if result := <-results; result.StatementID != i || result.Profile != nil {
+			t.Fatalf("%d. unexpected result: %#v", i, result)
+		}
+
+		result := <-results
+		if result.StatementID != i {
+			t.Fatalf("%d. unexpected statement id: %d", i, result.StatementID)
+		}
+		p := result.Profile
+		if p == nil {
+			t.Fatalf("%d. expected a profile", i)
+		} else if p.Name != "statement" || p.Fields["total_time"] == nil {
+			t.Fatalf("%d. unexpected profile: %#v", i, p)
+		} else if len(p.Children) != 1 {
+			t.Fatalf("%d. unexpected number of children: %d", i, len(p.Children))
+		}
+		if got, exp := p.Children[0], (&query.Profile{
+			Name:   "create_iterator",
+			Labels: map[string]string{"shard_id": "1"},
+			Fields: map[string]interface{}{"cursors_ref": int64(2)},
+		}); !reflect.DeepEqual(got, exp) {
+			t.Fatalf("%d. unexpected child:\n\ngot=%#v\n\nexp=%#v", i, got, exp)
+		}
+	}
+	if result, ok := <-results; ok {
+		t.Fatalf("unexpected result: %#v", result)
+	}
+}
+
+func TestQueryExecutor_Profile_Disabled(t *testing.T) {
+	q, err := influxql.ParseQuery(`SELECT count(value) FROM cpu`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := NewQueryExecutor()
+	e.StatementExecutor = &StatementExecutor{
+		ExecuteStatementFn: func(stmt influxql.Statement, ctx *query.ExecutionContext) error {
+			if span := tracing.SpanFromContext(ctx.TraceContext()); span != nil {
+				t.Error("unexpected trace of the statement")
+			}
+			return ctx.Send(&query.Result{})
+		},
+	}
+
+	for result := range e.ExecuteQuery(q, query.ExecutionOptions{}, nil) {
+		if result.Profile != nil {
+			t.Errorf("unexpected profile: %#v", result.Profile)
+		}
+	}
+}
+
+func TestQueryExecutor_QueryStats(t *testing.T) {
+	q, err := influxql.ParseQuery(`SELECT value FROM cpu WHERE host = 'server01'; SELECT value FROM cpu WHERE host = 'server02'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	e := NewQueryExecutor()
+	e.TaskManager.QueryStats = query.NewQueryStats(query.DefaultMaxQueryFingerprints)
+	e.TaskManager.LogQueriesAfter = time.Nanosecond
+	e.TaskManager.SlowQueryLogger = logger.New(&buf)
+	e.StatementExecutor = &StatementExecutor{
+		ExecuteStatementFn: func(stmt influxql.Statement, ctx *query.ExecutionContext) error {
+			switch stmt.(type) {
+			case *influxql.ShowQueryStatsStatement:
+				return e.TaskManager.ExecuteStatement(stmt, ctx)
+			}
+
+			ctx.AddIteratorStats(query.IteratorStats{SeriesN: 1, PointN: 3})
+			time.Sleep(time.Millisecond)
+			return ctx.Send(&query.Result{Series: models.Rows{{
+				Name:    "cpu",
+				Columns: []string{"time", "value"},
+				Values:  [][]interface{}{{int64(0), 1.0}, {int64(1), 2.0}},
+			}}})
+		},
+	}
+	discardOutput(e.ExecuteQuery(q, query.ExecutionOptions{Database: "db0", UserID: "admin"}, nil))
+
+	for _, s := range []string{
+		`fingerprint="SELECT value FROM cpu WHERE host = ?" db_instance=db0 user=admin`,
+		`series_scanned=1 points_scanned=3 rows_returned=2 query="SELECT value FROM cpu WHERE host = 'server02'"`,
+	} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("expected %q in the slow query log:\n%s", s, buf.String())
+		}
+	}
+
+	results := e.ExecuteQuery(&influxql.Query{Statements: influxql.Statements{&influxql.ShowQueryStatsStatement{}}}, query.ExecutionOptions{}, nil)
+	result := <-results
+	discardOutput(results)
+	if result.Err != nil {
+		t.Fatalf("unexpected error: %s", result.Err)
+	} else if len(result.Series) != 1 || len(result.Series[0].Values) != 1 {
+		t.Fatalf("unexpected series: %#v", result.Series)
+	}
+	row := result.Series[0].Values[0]
+	if got, exp := []interface{}{row[0], row[1], row[2], row[9], row[10], row[11]},
+		[]interface{}{"SELECT value FROM cpu WHERE host = ?", "db0", int64(2), int64(2), int64(6), int64(4)}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected stats: got=%v exp=%v", got, exp)
+	}
+
+	// Statements are recorded against the database they read from.
+	if q, err = influxql.ParseQuery(`SELECT value FROM db1.autogen.cpu`); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	discardOutput(e.ExecuteQuery(q, query.ExecutionOptions{Database: "db0", UserID: "admin"}, nil))
+	if s := `fingerprint="SELECT value FROM db1.autogen.cpu" db_instance=db1`; !strings.Contains(buf.String(), s) {
+		t.Errorf("expected %q in the slow query log:\n%s", s, buf.String())
+	}
+}
+
 func TestQueryExecutor_Close(t *testing.T) {
 	q, err := influxql.ParseQuery(`SELECT count(value) FROM cpu`)
 	if err != nil {
@@ -28,6 +28,10 @@ func (p *preparedStatement) Explain() (string, error) {
 			buf.WriteString("\n")
 		}
 
+		if node.Join != nil {
+			fmt.Fprintf(&buf, "JOIN: %s\n", node.Join.Type)
+		}
+
 		expr := "<nil>"
 		if node.Expr != nil {
 			expr = node.Expr.String()
@@ -40,6 +44,10 @@ func (p *preparedStatement) Explain() (string, error) {
 			}
 			fmt.Fprintf(&buf, "AUXILIARY FIELDS: %s\n", strings.Join(refs, ", "))
 		}
+		if node.Join != nil {
+			writeJoinPlan(&buf, node)
+			continue
+		}
 		fmt.Fprintf(&buf, "NUMBER OF SHARDS: %d\n", node.Cost.NumShards)
 		fmt.Fprintf(&buf, "NUMBER OF SERIES: %d\n", node.Cost.NumSeries)
 		fmt.Fprintf(&buf, "CACHED VALUES: %d\n", node.Cost.CachedValues)
@@ -50,10 +58,41 @@ func (p *preparedStatement) Explain() (string, error) {
 	return buf.String(), nil
 }
 
+// writeJoinPlan writes the plan for a join. The plans for reading each side
+// of the join follow it.
+func writeJoinPlan(buf *bytes.Buffer, node planNode) {
+	on := make([]string, len(node.Join.On))
+	for i, tag := range node.Join.On {
+		on[i] = influxql.QuoteIdent(tag)
+	}
+	fmt.Fprintf(buf, "JOIN ON: time, %s\n", strings.Join(on, ", "))
+	if node.Join.Type == influxql.OuterJoin {
+		var fill string
+		switch node.Fill {
+		case influxql.NullFill, influxql.LinearFill, influxql.InterpolateTimeFill:
+			fill = "null"
+		case influxql.NoFill:
+			fill = "none"
+		case influxql.NumberFill:
+			fill = fmt.Sprint(node.FillValue)
+		case influxql.PreviousFill:
+			fill = "previous"
+		}
+		fmt.Fprintf(buf, "MISSING SIDE FILL: %s\n", fill)
+	}
+	fmt.Fprintf(buf, "LEFT: %s\n", node.Join.LHS.(*influxql.SubQuery).Statement)
+	fmt.Fprintf(buf, "RIGHT: %s\n", node.Join.RHS.(*influxql.SubQuery).Statement)
+}
+
 type planNode struct {
 	Expr influxql.Expr
 	Aux  []influxql.VarRef
 	Cost IteratorCost
+
+	// Join is set for the node that joins the iterators which follow it.
+	Join      *influxql.Join
+	Fill      influxql.FillOption
+	FillValue interface{}
 }
 
 type explainIteratorCreator struct {
@@ -0,0 +1,183 @@
+package query
+
+import (
+	"context"
+
+	"github.com/influxdata/influxql"
+)
+
+// fillEdgeCalls are the selectors, whose result is a point of the series, so
+// the nearest point outside of the time range can stand in for a window.
+// Aggregates such as mean() are not filled from a single point.
+var fillEdgeCalls = map[string]struct{}{
+	"first":      {},
+	"last":       {},
+	"min":        {},
+	"max":        {},
+	"percentile": {},
+}
+
+// hasFillEdgeCall returns true if the fields call a function whose windows
+// at the edges of the time range can be filled from the nearest points.
+func hasFillEdgeCall(fields influxql.Fields) bool {
+	var found bool
+	for _, f := range fields {
+		influxql.WalkFunc(f.Expr, func(n influxql.Node) {
+			if call, ok := n.(*influxql.Call); ok {
+				if _, ok := fillEdgeCalls[call.Name]; ok {
+					found = true
+				}
+			}
+		})
+	}
+	return found
+}
+
+// fillEdges holds the nearest points outside of the time range of a query for
+// each series. The previous points come before the first window in the order
+// of the query and the next points come after the last window.
+type fillEdges struct {
+	prev, next map[string]fillEdge
+}
+
+// fillEdge is the nearest point of a series outside of the time range.
+type fillEdge struct {
+	time  int64
+	value interface{}
+}
+
+// fillEdgeKey returns the key of the series in fillEdges.
+func fillEdgeKey(name string, tags Tags) string {
+	return name + "\x00" + tags.ID()
+}
+
+// fillEdges finds the nearest points of each series outside of the time range
+// so fill(previous), fill(linear) and fill(interpolate_time) can fill the
+// windows at the edges of the time range. Points are only looked for within
+// the fill lookback of the time range.
+func (b *exprIteratorBuilder) fillEdges(ctx context.Context, expr *influxql.Call, opt IteratorOptions) (fillEdges, error) {
+	var edges fillEdges
+	switch opt.Fill {
+	case influxql.PreviousFill, influxql.LinearFill, influxql.InterpolateTimeFill:
+	default:
+		return edges, nil
+	}
+	if _, ok := fillEdgeCalls[expr.Name]; !ok || opt.FillLookback <= 0 {
+		return edges, nil
+	}
+	ref, ok := expr.Args[0].(*influxql.VarRef)
+	if !ok {
+		return edges, nil
+	}
+	lookback := int64(opt.FillLookback)
+
+	var before, after map[string]fillEdge
+	if opt.StartTime > influxql.MinTime {
+		start := influxql.MinTime
+		if opt.StartTime > influxql.MinTime+lookback {
+			start = opt.StartTime - lookback
+		}
+		points, err := b.nearestPoints(ctx, "last", ref, opt, start, opt.StartTime-1)
+		if err != nil {
+			return edges, err
+		}
+		before = points
+	}
+	if opt.EndTime < influxql.MaxTime {
+		end := influxql.MaxTime
+		if opt.EndTime < influxql.MaxTime-lookback {
+			end = opt.EndTime + lookback
+		}
+		points, err := b.nearestPoints(ctx, "first", ref, opt, opt.EndTime+1, end)
+		if err != nil {
+			return edges, err
+		}
+		after = points
+	}
+
+	if opt.Ascending {
+		edges.prev, edges.next = before, after
+	} else {
+		edges.prev, edges.next = after, before
+	}
+	return edges, nil
+}
+
+// nearestPoints returns the first or last point of each series between start
+// and end. The engine reads these with a cursor that stops after one point.
+func (b *exprIteratorBuilder) nearestPoints(ctx context.Context, name string, ref *influxql.VarRef, opt IteratorOptions, start, end int64) (map[string]fillEdge, error) {
+	opt.Expr = &influxql.Call{Name: name, Args: []influxql.Expr{ref}}
+	opt.Interval = Interval{}
+	opt.StartTime, opt.EndTime = start, end
+	opt.Limit, opt.Offset = 0, 0
+	opt.Fill = influxql.NoFill
+
+	points := make(map[string]fillEdge)
+	for _, source := range b.sources {
+		m, ok := source.(*influxql.Measurement)
+		if !ok {
+			continue
+		}
+
+		itr, err := b.ic.CreateIterator(ctx, m, opt)
+		if err != nil {
+			return nil, err
+		} else if itr == nil {
+			continue
+		}
+		err = readNearestPoints(itr, name == "first", points)
+		itr.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return points, nil
+}
+
+// readNearestPoints reads the points of itr into points, keeping the earliest
+// point of each series if first is true and the latest point otherwise.
+func readNearestPoints(itr Iterator, first bool, points map[string]fillEdge) error {
+	for {
+		p, err := nextPoint(itr)
+		if err != nil {
+			return err
+		} else if p == nil {
+			return nil
+		} else if p.value() == nil {
+			continue
+		}
+
+		key := fillEdgeKey(p.name(), p.tags())
+		if prev, ok := points[key]; ok && (prev.time < p.time()) == first {
+			continue
+		}
+		points[key] = fillEdge{time: p.time(), value: p.value()}
+	}
+}
+
+// nextPoint returns the next point of an iterator of any type.
+func nextPoint(itr Iterator) (Point, error) {
+	switch itr := itr.(type) {
+	case FloatIterator:
+		if p, err := itr.Next(); p != nil || err != nil {
+			return p, err
+		}
+	case IntegerIterator:
+		if p, err := itr.Next(); p != nil || err != nil {
+			return p, err
+		}
+	case UnsignedIterator:
+		if p, err := itr.Next(); p != nil || err != nil {
+			return p, err
+		}
+	case StringIterator:
+		if p, err := itr.Next(); p != nil || err != nil {
+			return p, err
+		}
+	case BooleanIterator:
+		if p, err := itr.Next(); p != nil || err != nil {
+			return p, err
+		}
+	}
+	return nil, nil
+}
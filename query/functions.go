@@ -2,10 +2,14 @@ package query
 
 import (
 	"container/heap"
+	"encoding/base64"
+	"encoding/binary"
 	"math"
 	"sort"
 	"time"
 
+	"github.com/influxdata/influxdb/pkg/ddsketch"
+	"github.com/influxdata/influxdb/pkg/estimator/hll"
 	"github.com/influxdata/influxdb/query/internal/gota"
 	"github.com/influxdata/influxdb/query/neldermead"
 	"github.com/influxdata/influxql"
@@ -52,6 +56,8 @@ func (CallTypeMapper) CallType(name string, args []influxql.DataType) (influxql.
 	case "min", "max", "sum", "first", "last":
 		// TODO(jsternberg): Verify the input type.
 		return args[0], nil
+	case "sketch":
+		return influxql.String, nil
 	}
 	return influxql.Unknown, nil
 }
@@ -84,9 +90,10 @@ func (m FunctionTypeMapper) CallType(name string, args []influxql.DataType) (inf
 		"kaufmans_efficiency_ratio",
 		"kaufmans_adaptive_moving_average",
 		"chande_momentum_oscillator",
-		"holt_winters", "holt_winters_with_fit":
+		"holt_winters", "holt_winters_with_fit",
+		"quantile_approx":
 		return influxql.Float, nil
-	case "elapsed":
+	case "elapsed", "histogram", "count_distinct_approx":
 		return influxql.Integer, nil
 	default:
 		// TODO(jsternberg): Do not use default for this.
@@ -2146,3 +2153,262 @@ func (r *UnsignedBottomReducer) Emit() []UnsignedPoint {
 	sort.Sort(sort.Reverse(&h))
 	return points
 }
+
+// SketchReducer builds a quantile sketch from the aggregated points and emits
+// it as an encoded string. String points are decoded as sketches and merged so
+// that sketches from subqueries or stored rollups can be combined.
+type SketchReducer struct {
+	sketch *ddsketch.Sketch
+}
+
+// NewSketchReducer creates a new SketchReducer.
+func NewSketchReducer() *SketchReducer {
+	return &SketchReducer{sketch: ddsketch.NewDefault()}
+}
+
+// AggregateFloat aggregates a point into the reducer.
+func (r *SketchReducer) AggregateFloat(p *FloatPoint) {
+	r.sketch.Add(p.Value)
+}
+
+// AggregateInteger aggregates a point into the reducer.
+func (r *SketchReducer) AggregateInteger(p *IntegerPoint) {
+	r.sketch.Add(float64(p.Value))
+}
+
+// AggregateUnsigned aggregates a point into the reducer.
+func (r *SketchReducer) AggregateUnsigned(p *UnsignedPoint) {
+	r.sketch.Add(float64(p.Value))
+}
+
+// AggregateString merges an encoded sketch into the reducer. Values that are
+// not valid sketches are ignored.
+func (r *SketchReducer) AggregateString(p *StringPoint) {
+	if sketch, err := DecodeSketch(p.Value); err == nil {
+		r.sketch.Merge(sketch)
+	}
+}
+
+// Emit emits the encoded sketch as a single point.
+func (r *SketchReducer) Emit() []StringPoint {
+	if r.sketch.Count() == 0 {
+		return nil
+	}
+	return []StringPoint{{
+		Time:  ZeroTime,
+		Value: EncodeSketch(r.sketch),
+	}}
+}
+
+// QuantileApproxReducer merges encoded sketches and emits the estimated value
+// at a quantile.
+type QuantileApproxReducer struct {
+	SketchReducer
+	quantile float64
+}
+
+// NewQuantileApproxReducer creates a new QuantileApproxReducer.
+func NewQuantileApproxReducer(quantile float64) *QuantileApproxReducer {
+	return &QuantileApproxReducer{
+		SketchReducer: *NewSketchReducer(),
+		quantile:      quantile,
+	}
+}
+
+// Emit emits the estimated quantile as a single point.
+func (r *QuantileApproxReducer) Emit() []FloatPoint {
+	if r.sketch.Count() == 0 {
+		return nil
+	}
+	return []FloatPoint{{
+		Time:  ZeroTime,
+		Value: r.sketch.Quantile(r.quantile),
+	}}
+}
+
+// HistogramReducer merges encoded sketches and emits the estimated number of
+// values in each bucket. Buckets are bounded by the upper bounds given to the
+// reducer, with a final bucket for values greater than the last bound.
+type HistogramReducer struct {
+	SketchReducer
+	bounds []float64
+}
+
+// NewHistogramReducer creates a new HistogramReducer.
+func NewHistogramReducer(bounds []float64) *HistogramReducer {
+	return &HistogramReducer{
+		SketchReducer: *NewSketchReducer(),
+		bounds:        bounds,
+	}
+}
+
+// Emit emits one point per bucket, in order of the bucket bounds.
+func (r *HistogramReducer) Emit() []IntegerPoint {
+	if r.sketch.Count() == 0 {
+		return nil
+	}
+
+	points := make([]IntegerPoint, 0, len(r.bounds)+1)
+	var prev uint64
+	for _, bound := range r.bounds {
+		n := r.sketch.CountLessOrEqual(bound)
+		points = append(points, IntegerPoint{Time: ZeroTime, Value: int64(n - prev)})
+		prev = n
+	}
+	points = append(points, IntegerPoint{Time: ZeroTime, Value: int64(r.sketch.Count() - prev)})
+	return points
+}
+
+// EncodeSketch encodes a sketch as a string so it can be stored in a string
+// field or returned from a subquery.
+func EncodeSketch(sketch *ddsketch.Sketch) string {
+	data, _ := sketch.MarshalBinary()
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// DecodeSketch decodes a sketch encoded with EncodeSketch. Strings which are
+// too large or don't start with the header of a sketch are rejected before
+// they are decoded.
+func DecodeSketch(s string) (*ddsketch.Sketch, error) {
+	if len(s) > base64.StdEncoding.EncodedLen(ddsketch.MaxEncodedSize) {
+		return nil, ddsketch.ErrInvalidEncoding
+	}
+
+	// Padded base64 encodes the header in whole blocks, so it's decoded
+	// alone before the whole string.
+	n := base64.StdEncoding.EncodedLen(ddsketch.HeaderSize)
+	if len(s) < n {
+		return nil, ddsketch.ErrInvalidEncoding
+	}
+	header, err := base64.StdEncoding.DecodeString(s[:n])
+	if err != nil || !ddsketch.ValidHeader(header) {
+		return nil, ddsketch.ErrInvalidEncoding
+	}
+
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var sketch ddsketch.Sketch
+	if err := sketch.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return &sketch, nil
+}
+
+// HLLReducer adds the aggregated values to a HyperLogLog++ sketch and emits
+// the encoded sketch so partial results can be merged by HLLMergeReducer.
+type HLLReducer struct {
+	sketch *hll.Plus
+}
+
+// NewHLLReducer creates a new HLLReducer with the given precision.
+func NewHLLReducer(precision uint8) (*HLLReducer, error) {
+	sketch, err := hll.NewPlus(precision)
+	if err != nil {
+		return nil, err
+	}
+	return &HLLReducer{sketch: sketch}, nil
+}
+
+// AggregateFloat aggregates a point into the reducer.
+func (r *HLLReducer) AggregateFloat(p *FloatPoint) {
+	r.aggregateUint64(math.Float64bits(p.Value))
+}
+
+// AggregateInteger aggregates a point into the reducer.
+func (r *HLLReducer) AggregateInteger(p *IntegerPoint) {
+	r.aggregateUint64(uint64(p.Value))
+}
+
+// AggregateUnsigned aggregates a point into the reducer.
+func (r *HLLReducer) AggregateUnsigned(p *UnsignedPoint) {
+	r.aggregateUint64(p.Value)
+}
+
+// AggregateString aggregates a point into the reducer.
+func (r *HLLReducer) AggregateString(p *StringPoint) {
+	r.sketch.Add([]byte(p.Value))
+}
+
+// AggregateBoolean aggregates a point into the reducer.
+func (r *HLLReducer) AggregateBoolean(p *BooleanPoint) {
+	if p.Value {
+		r.sketch.Add([]byte{1})
+	} else {
+		r.sketch.Add([]byte{0})
+	}
+}
+
+func (r *HLLReducer) aggregateUint64(v uint64) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	r.sketch.Add(buf[:])
+}
+
+// Emit emits the encoded sketch as a single point.
+func (r *HLLReducer) Emit() []StringPoint {
+	data, err := r.sketch.MarshalBinary()
+	if err != nil {
+		return nil
+	}
+	return []StringPoint{{Time: ZeroTime, Value: string(data)}}
+}
+
+// HLLMergeReducer merges the encoded sketches emitted by HLLReducer.
+type HLLMergeReducer struct {
+	sketch *hll.Plus
+}
+
+// NewHLLMergeReducer creates a new HLLMergeReducer.
+func NewHLLMergeReducer() *HLLMergeReducer {
+	return &HLLMergeReducer{}
+}
+
+// AggregateString merges an encoded sketch into the reducer. Sketches that
+// cannot be decoded or have a different precision are ignored.
+func (r *HLLMergeReducer) AggregateString(p *StringPoint) {
+	var sketch hll.Plus
+	if err := sketch.UnmarshalBinary([]byte(p.Value)); err != nil {
+		return
+	}
+
+	if r.sketch == nil {
+		r.sketch = &sketch
+		return
+	}
+	r.sketch.Merge(&sketch)
+}
+
+// Emit emits the encoded sketch as a single point.
+func (r *HLLMergeReducer) Emit() []StringPoint {
+	if r.sketch == nil {
+		return nil
+	}
+
+	data, err := r.sketch.MarshalBinary()
+	if err != nil {
+		return nil
+	}
+	return []StringPoint{{Time: ZeroTime, Value: string(data)}}
+}
+
+// CountDistinctApproxReducer merges the encoded sketches emitted by
+// HLLReducer and emits the estimated number of distinct values.
+type CountDistinctApproxReducer struct {
+	HLLMergeReducer
+}
+
+// NewCountDistinctApproxReducer creates a new CountDistinctApproxReducer.
+func NewCountDistinctApproxReducer() *CountDistinctApproxReducer {
+	return &CountDistinctApproxReducer{}
+}
+
+// Emit emits the estimated count as a single point.
+func (r *CountDistinctApproxReducer) Emit() []IntegerPoint {
+	if r.sketch == nil {
+		return nil
+	}
+	return []IntegerPoint{{Time: ZeroTime, Value: int64(r.sketch.Count())}}
+}
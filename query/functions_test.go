@@ -2,6 +2,7 @@ package query_test
 
 import (
 	"math"
+	"strings"
 	"testing"
 	"time"
 
@@ -497,3 +498,23 @@ func TestSample_SampleSizeGreaterThanNumPoints(t *testing.T) {
 		t.Fatalf("unexpected points: %s", spew.Sdump(points))
 	}
 }
+
+// Ensure strings which aren't sketches are not decoded as sketches.
+func TestDecodeSketch_Invalid(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"server01",
+		"AAAAAAAAAAAAAAAA",
+		strings.Repeat("A", 4*1024*1024),
+	} {
+		if _, err := query.DecodeSketch(s); err == nil {
+			t.Errorf("expected error decoding %.16q", s)
+		}
+	}
+
+	r := query.NewSketchReducer()
+	r.AggregateString(&query.StringPoint{Value: "server01"})
+	if points := r.Emit(); len(points) != 0 {
+		t.Fatalf("unexpected points: %v", points)
+	}
+}
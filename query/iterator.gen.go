@@ -710,6 +710,7 @@ type floatFillIterator struct {
 	startTime int64
 	endTime   int64
 	auxFields []interface{}
+	edges     fillEdges
 	init      bool
 	opt       IteratorOptions
 
@@ -721,9 +722,9 @@ type floatFillIterator struct {
 	}
 }
 
-func newFloatFillIterator(input FloatIterator, expr influxql.Expr, opt IteratorOptions) *floatFillIterator {
+func newFloatFillIterator(input FloatIterator, expr influxql.Expr, opt IteratorOptions, edges fillEdges) *floatFillIterator {
 	if opt.Fill == influxql.NullFill {
-		if expr, ok := expr.(*influxql.Call); ok && expr.Name == "count" {
+		if expr, ok := expr.(*influxql.Call); ok && (expr.Name == "count" || expr.Name == "count_distinct_approx") {
 			opt.Fill = influxql.NumberFill
 			opt.FillValue = float64(0)
 		}
@@ -749,6 +750,7 @@ func newFloatFillIterator(input FloatIterator, expr influxql.Expr, opt IteratorO
 		startTime: startTime,
 		endTime:   endTime,
 		auxFields: auxFields,
+		edges:     edges,
 		opt:       opt,
 	}
 }
@@ -770,6 +772,7 @@ func (itr *floatFillIterator) Next() (*FloatPoint, error) {
 		if itr.opt.Location != nil {
 			_, itr.window.offset = itr.opt.Zone(itr.window.time)
 		}
+		itr.prev = itr.edge(itr.edges.prev)
 		itr.init = true
 	}
 
@@ -807,7 +810,7 @@ func (itr *floatFillIterator) Next() (*FloatPoint, error) {
 		if itr.opt.Location != nil {
 			_, itr.window.offset = itr.opt.Zone(itr.window.time)
 		}
-		itr.prev = FloatPoint{Nil: true}
+		itr.prev = itr.edge(itr.edges.prev)
 	}
 
 	// Check if the point is our next expected point.
@@ -825,13 +828,26 @@ CONSTRUCT:
 		}
 
 		switch itr.opt.Fill {
-		case influxql.LinearFill:
+		case influxql.LinearFill, influxql.InterpolateTimeFill:
 			if !itr.prev.Nil {
 				next, err := itr.input.peek()
 				if err != nil {
 					return nil, err
-				} else if next != nil && next.Name == itr.window.name && next.Tags.ID() == itr.window.tags.ID() {
+				} else if next == nil || next.Name != itr.window.name || next.Tags.ID() != itr.window.tags.ID() {
+					// Interpolate to the nearest point after the time range.
+					next = nil
+					if edge := itr.edge(itr.edges.next); !edge.Nil {
+						next = &edge
+					}
+				}
+				if next != nil {
+					// Linear fill interpolates by the number of windows
+					// between the points. Calendar windows differ in length,
+					// so they are always interpolated by time.
 					interval := int64(itr.opt.Interval.Duration)
+					if itr.opt.Fill == influxql.InterpolateTimeFill || itr.opt.Interval.IsCalendar() {
+						interval = 1
+					}
 					start := itr.window.time / interval
 					p.Value = linearFloat(start, itr.prev.Time/interval, next.Time/interval, itr.prev.Value, next.Value)
 				} else {
@@ -859,7 +875,16 @@ CONSTRUCT:
 
 	// Advance the expected time. Do not advance to a new window here
 	// as there may be lingering points with the same timestamp in the previous
-	// window.
+	// window. Calendar windows differ in length and already account for
+	// offset changes, so the next window is found from the calendar.
+	if itr.opt.Interval.IsCalendar() {
+		if itr.opt.Ascending {
+			_, itr.window.time = itr.opt.Window(itr.window.time)
+		} else {
+			itr.window.time, _ = itr.opt.Window(itr.window.time - 1)
+		}
+		return p, nil
+	}
 	if itr.opt.Ascending {
 		itr.window.time += int64(itr.opt.Interval.Duration)
 	} else {
@@ -880,6 +905,17 @@ CONSTRUCT:
 	return p, nil
 }
 
+// edge returns the nearest point of the current series outside of the time
+// range from edges or a nil point if there is none.
+func (itr *floatFillIterator) edge(edges map[string]fillEdge) FloatPoint {
+	if e, ok := edges[fillEdgeKey(itr.window.name, itr.window.tags)]; ok {
+		if v, ok := castToFloat(e.value); ok {
+			return FloatPoint{Name: itr.window.name, Tags: itr.window.tags, Time: e.time, Value: v}
+		}
+	}
+	return FloatPoint{Nil: true}
+}
+
 // floatIntervalIterator represents a float implementation of IntervalIterator.
 type floatIntervalIterator struct {
 	input FloatIterator
@@ -993,6 +1029,25 @@ func (itr *floatCloseInterruptIterator) Next() (*FloatPoint, error) {
 	return p, nil
 }
 
+// floatProgressIterator reports the progress of reading a float iterator.
+type floatProgressIterator struct {
+	input    FloatIterator
+	progress iteratorProgress
+}
+
+func newFloatProgressIterator(input FloatIterator, p *Progress) *floatProgressIterator {
+	return &floatProgressIterator{input: input, progress: iteratorProgress{progress: p}}
+}
+
+func (itr *floatProgressIterator) Stats() IteratorStats { return itr.input.Stats() }
+func (itr *floatProgressIterator) Close() error { return itr.input.Close() }
+
+func (itr *floatProgressIterator) Next() (*FloatPoint, error) {
+	p, err := itr.input.Next()
+	itr.progress.next(itr.input, p == nil)
+	return p, err
+}
+
 // floatReduceFloatIterator executes a reducer for every interval and buffers the result.
 type floatReduceFloatIterator struct {
 	input    *bufFloatIterator
@@ -3374,6 +3429,7 @@ type integerFillIterator struct {
 	startTime int64
 	endTime   int64
 	auxFields []interface{}
+	edges     fillEdges
 	init      bool
 	opt       IteratorOptions
 
@@ -3385,9 +3441,9 @@ type integerFillIterator struct {
 	}
 }
 
-func newIntegerFillIterator(input IntegerIterator, expr influxql.Expr, opt IteratorOptions) *integerFillIterator {
+func newIntegerFillIterator(input IntegerIterator, expr influxql.Expr, opt IteratorOptions, edges fillEdges) *integerFillIterator {
 	if opt.Fill == influxql.NullFill {
-		if expr, ok := expr.(*influxql.Call); ok && expr.Name == "count" {
+		if expr, ok := expr.(*influxql.Call); ok && (expr.Name == "count" || expr.Name == "count_distinct_approx") {
 			opt.Fill = influxql.NumberFill
 			opt.FillValue = int64(0)
 		}
@@ -3413,6 +3469,7 @@ func newIntegerFillIterator(input IntegerIterator, expr influxql.Expr, opt Itera
 		startTime: startTime,
 		endTime:   endTime,
 		auxFields: auxFields,
+		edges:     edges,
 		opt:       opt,
 	}
 }
@@ -3434,6 +3491,7 @@ func (itr *integerFillIterator) Next() (*IntegerPoint, error) {
 		if itr.opt.Location != nil {
 			_, itr.window.offset = itr.opt.Zone(itr.window.time)
 		}
+		itr.prev = itr.edge(itr.edges.prev)
 		itr.init = true
 	}
 
@@ -3471,7 +3529,7 @@ func (itr *integerFillIterator) Next() (*IntegerPoint, error) {
 		if itr.opt.Location != nil {
 			_, itr.window.offset = itr.opt.Zone(itr.window.time)
 		}
-		itr.prev = IntegerPoint{Nil: true}
+		itr.prev = itr.edge(itr.edges.prev)
 	}
 
 	// Check if the point is our next expected point.
@@ -3489,13 +3547,26 @@ CONSTRUCT:
 		}
 
 		switch itr.opt.Fill {
-		case influxql.LinearFill:
+		case influxql.LinearFill, influxql.InterpolateTimeFill:
 			if !itr.prev.Nil {
 				next, err := itr.input.peek()
 				if err != nil {
 					return nil, err
-				} else if next != nil && next.Name == itr.window.name && next.Tags.ID() == itr.window.tags.ID() {
+				} else if next == nil || next.Name != itr.window.name || next.Tags.ID() != itr.window.tags.ID() {
+					// Interpolate to the nearest point after the time range.
+					next = nil
+					if edge := itr.edge(itr.edges.next); !edge.Nil {
+						next = &edge
+					}
+				}
+				if next != nil {
+					// Linear fill interpolates by the number of windows
+					// between the points. Calendar windows differ in length,
+					// so they are always interpolated by time.
 					interval := int64(itr.opt.Interval.Duration)
+					if itr.opt.Fill == influxql.InterpolateTimeFill || itr.opt.Interval.IsCalendar() {
+						interval = 1
+					}
 					start := itr.window.time / interval
 					p.Value = linearInteger(start, itr.prev.Time/interval, next.Time/interval, itr.prev.Value, next.Value)
 				} else {
@@ -3523,7 +3594,16 @@ CONSTRUCT:
 
 	// Advance the expected time. Do not advance to a new window here
 	// as there may be lingering points with the same timestamp in the previous
-	// window.
+	// window. Calendar windows differ in length and already account for
+	// offset changes, so the next window is found from the calendar.
+	if itr.opt.Interval.IsCalendar() {
+		if itr.opt.Ascending {
+			_, itr.window.time = itr.opt.Window(itr.window.time)
+		} else {
+			itr.window.time, _ = itr.opt.Window(itr.window.time - 1)
+		}
+		return p, nil
+	}
 	if itr.opt.Ascending {
 		itr.window.time += int64(itr.opt.Interval.Duration)
 	} else {
@@ -3544,6 +3624,17 @@ CONSTRUCT:
 	return p, nil
 }
 
+// edge returns the nearest point of the current series outside of the time
+// range from edges or a nil point if there is none.
+func (itr *integerFillIterator) edge(edges map[string]fillEdge) IntegerPoint {
+	if e, ok := edges[fillEdgeKey(itr.window.name, itr.window.tags)]; ok {
+		if v, ok := castToInteger(e.value); ok {
+			return IntegerPoint{Name: itr.window.name, Tags: itr.window.tags, Time: e.time, Value: v}
+		}
+	}
+	return IntegerPoint{Nil: true}
+}
+
 // integerIntervalIterator represents a integer implementation of IntervalIterator.
 type integerIntervalIterator struct {
 	input IntegerIterator
@@ -3657,6 +3748,25 @@ func (itr *integerCloseInterruptIterator) Next() (*IntegerPoint, error) {
 	return p, nil
 }
 
+// integerProgressIterator reports the progress of reading a integer iterator.
+type integerProgressIterator struct {
+	input    IntegerIterator
+	progress iteratorProgress
+}
+
+func newIntegerProgressIterator(input IntegerIterator, p *Progress) *integerProgressIterator {
+	return &integerProgressIterator{input: input, progress: iteratorProgress{progress: p}}
+}
+
+func (itr *integerProgressIterator) Stats() IteratorStats { return itr.input.Stats() }
+func (itr *integerProgressIterator) Close() error { return itr.input.Close() }
+
+func (itr *integerProgressIterator) Next() (*IntegerPoint, error) {
+	p, err := itr.input.Next()
+	itr.progress.next(itr.input, p == nil)
+	return p, err
+}
+
 // integerReduceFloatIterator executes a reducer for every interval and buffers the result.
 type integerReduceFloatIterator struct {
 	input    *bufIntegerIterator
@@ -6038,6 +6148,7 @@ type unsignedFillIterator struct {
 	startTime int64
 	endTime   int64
 	auxFields []interface{}
+	edges     fillEdges
 	init      bool
 	opt       IteratorOptions
 
@@ -6049,9 +6160,9 @@ type unsignedFillIterator struct {
 	}
 }
 
-func newUnsignedFillIterator(input UnsignedIterator, expr influxql.Expr, opt IteratorOptions) *unsignedFillIterator {
+func newUnsignedFillIterator(input UnsignedIterator, expr influxql.Expr, opt IteratorOptions, edges fillEdges) *unsignedFillIterator {
 	if opt.Fill == influxql.NullFill {
-		if expr, ok := expr.(*influxql.Call); ok && expr.Name == "count" {
+		if expr, ok := expr.(*influxql.Call); ok && (expr.Name == "count" || expr.Name == "count_distinct_approx") {
 			opt.Fill = influxql.NumberFill
 			opt.FillValue = uint64(0)
 		}
@@ -6077,6 +6188,7 @@ func newUnsignedFillIterator(input UnsignedIterator, expr influxql.Expr, opt Ite
 		startTime: startTime,
 		endTime:   endTime,
 		auxFields: auxFields,
+		edges:     edges,
 		opt:       opt,
 	}
 }
@@ -6098,6 +6210,7 @@ func (itr *unsignedFillIterator) Next() (*UnsignedPoint, error) {
 		if itr.opt.Location != nil {
 			_, itr.window.offset = itr.opt.Zone(itr.window.time)
 		}
+		itr.prev = itr.edge(itr.edges.prev)
 		itr.init = true
 	}
 
@@ -6135,7 +6248,7 @@ func (itr *unsignedFillIterator) Next() (*UnsignedPoint, error) {
 		if itr.opt.Location != nil {
 			_, itr.window.offset = itr.opt.Zone(itr.window.time)
 		}
-		itr.prev = UnsignedPoint{Nil: true}
+		itr.prev = itr.edge(itr.edges.prev)
 	}
 
 	// Check if the point is our next expected point.
@@ -6153,13 +6266,26 @@ CONSTRUCT:
 		}
 
 		switch itr.opt.Fill {
-		case influxql.LinearFill:
+		case influxql.LinearFill, influxql.InterpolateTimeFill:
 			if !itr.prev.Nil {
 				next, err := itr.input.peek()
 				if err != nil {
 					return nil, err
-				} else if next != nil && next.Name == itr.window.name && next.Tags.ID() == itr.window.tags.ID() {
+				} else if next == nil || next.Name != itr.window.name || next.Tags.ID() != itr.window.tags.ID() {
+					// Interpolate to the nearest point after the time range.
+					next = nil
+					if edge := itr.edge(itr.edges.next); !edge.Nil {
+						next = &edge
+					}
+				}
+				if next != nil {
+					// Linear fill interpolates by the number of windows
+					// between the points. Calendar windows differ in length,
+					// so they are always interpolated by time.
 					interval := int64(itr.opt.Interval.Duration)
+					if itr.opt.Fill == influxql.InterpolateTimeFill || itr.opt.Interval.IsCalendar() {
+						interval = 1
+					}
 					start := itr.window.time / interval
 					p.Value = linearUnsigned(start, itr.prev.Time/interval, next.Time/interval, itr.prev.Value, next.Value)
 				} else {
@@ -6187,7 +6313,16 @@ CONSTRUCT:
 
 	// Advance the expected time. Do not advance to a new window here
 	// as there may be lingering points with the same timestamp in the previous
-	// window.
+	// window. Calendar windows differ in length and already account for
+	// offset changes, so the next window is found from the calendar.
+	if itr.opt.Interval.IsCalendar() {
+		if itr.opt.Ascending {
+			_, itr.window.time = itr.opt.Window(itr.window.time)
+		} else {
+			itr.window.time, _ = itr.opt.Window(itr.window.time - 1)
+		}
+		return p, nil
+	}
 	if itr.opt.Ascending {
 		itr.window.time += int64(itr.opt.Interval.Duration)
 	} else {
@@ -6208,6 +6343,17 @@ CONSTRUCT:
 	return p, nil
 }
 
+// edge returns the nearest point of the current series outside of the time
+// range from edges or a nil point if there is none.
+func (itr *unsignedFillIterator) edge(edges map[string]fillEdge) UnsignedPoint {
+	if e, ok := edges[fillEdgeKey(itr.window.name, itr.window.tags)]; ok {
+		if v, ok := castToUnsigned(e.value); ok {
+			return UnsignedPoint{Name: itr.window.name, Tags: itr.window.tags, Time: e.time, Value: v}
+		}
+	}
+	return UnsignedPoint{Nil: true}
+}
+
 // unsignedIntervalIterator represents a unsigned implementation of IntervalIterator.
 type unsignedIntervalIterator struct {
 	input UnsignedIterator
@@ -6321,6 +6467,25 @@ func (itr *unsignedCloseInterruptIterator) Next() (*UnsignedPoint, error) {
 	return p, nil
 }
 
+// unsignedProgressIterator reports the progress of reading a unsigned iterator.
+type unsignedProgressIterator struct {
+	input    UnsignedIterator
+	progress iteratorProgress
+}
+
+func newUnsignedProgressIterator(input UnsignedIterator, p *Progress) *unsignedProgressIterator {
+	return &unsignedProgressIterator{input: input, progress: iteratorProgress{progress: p}}
+}
+
+func (itr *unsignedProgressIterator) Stats() IteratorStats { return itr.input.Stats() }
+func (itr *unsignedProgressIterator) Close() error { return itr.input.Close() }
+
+func (itr *unsignedProgressIterator) Next() (*UnsignedPoint, error) {
+	p, err := itr.input.Next()
+	itr.progress.next(itr.input, p == nil)
+	return p, err
+}
+
 // unsignedReduceFloatIterator executes a reducer for every interval and buffers the result.
 type unsignedReduceFloatIterator struct {
 	input    *bufUnsignedIterator
@@ -8702,6 +8867,7 @@ type stringFillIterator struct {
 	startTime int64
 	endTime   int64
 	auxFields []interface{}
+	edges     fillEdges
 	init      bool
 	opt       IteratorOptions
 
@@ -8713,9 +8879,9 @@ type stringFillIterator struct {
 	}
 }
 
-func newStringFillIterator(input StringIterator, expr influxql.Expr, opt IteratorOptions) *stringFillIterator {
+func newStringFillIterator(input StringIterator, expr influxql.Expr, opt IteratorOptions, edges fillEdges) *stringFillIterator {
 	if opt.Fill == influxql.NullFill {
-		if expr, ok := expr.(*influxql.Call); ok && expr.Name == "count" {
+		if expr, ok := expr.(*influxql.Call); ok && (expr.Name == "count" || expr.Name == "count_distinct_approx") {
 			opt.Fill = influxql.NumberFill
 			opt.FillValue = ""
 		}
@@ -8741,6 +8907,7 @@ func newStringFillIterator(input StringIterator, expr influxql.Expr, opt Iterato
 		startTime: startTime,
 		endTime:   endTime,
 		auxFields: auxFields,
+		edges:     edges,
 		opt:       opt,
 	}
 }
@@ -8762,6 +8929,7 @@ func (itr *stringFillIterator) Next() (*StringPoint, error) {
 		if itr.opt.Location != nil {
 			_, itr.window.offset = itr.opt.Zone(itr.window.time)
 		}
+		itr.prev = itr.edge(itr.edges.prev)
 		itr.init = true
 	}
 
@@ -8799,7 +8967,7 @@ func (itr *stringFillIterator) Next() (*StringPoint, error) {
 		if itr.opt.Location != nil {
 			_, itr.window.offset = itr.opt.Zone(itr.window.time)
 		}
-		itr.prev = StringPoint{Nil: true}
+		itr.prev = itr.edge(itr.edges.prev)
 	}
 
 	// Check if the point is our next expected point.
@@ -8817,7 +8985,7 @@ CONSTRUCT:
 		}
 
 		switch itr.opt.Fill {
-		case influxql.LinearFill:
+		case influxql.LinearFill, influxql.InterpolateTimeFill:
 			fallthrough
 		case influxql.NullFill:
 			p.Nil = true
@@ -8837,7 +9005,16 @@ CONSTRUCT:
 
 	// Advance the expected time. Do not advance to a new window here
 	// as there may be lingering points with the same timestamp in the previous
-	// window.
+	// window. Calendar windows differ in length and already account for
+	// offset changes, so the next window is found from the calendar.
+	if itr.opt.Interval.IsCalendar() {
+		if itr.opt.Ascending {
+			_, itr.window.time = itr.opt.Window(itr.window.time)
+		} else {
+			itr.window.time, _ = itr.opt.Window(itr.window.time - 1)
+		}
+		return p, nil
+	}
 	if itr.opt.Ascending {
 		itr.window.time += int64(itr.opt.Interval.Duration)
 	} else {
@@ -8858,6 +9035,17 @@ CONSTRUCT:
 	return p, nil
 }
 
+// edge returns the nearest point of the current series outside of the time
+// range from edges or a nil point if there is none.
+func (itr *stringFillIterator) edge(edges map[string]fillEdge) StringPoint {
+	if e, ok := edges[fillEdgeKey(itr.window.name, itr.window.tags)]; ok {
+		if v, ok := castToString(e.value); ok {
+			return StringPoint{Name: itr.window.name, Tags: itr.window.tags, Time: e.time, Value: v}
+		}
+	}
+	return StringPoint{Nil: true}
+}
+
 // stringIntervalIterator represents a string implementation of IntervalIterator.
 type stringIntervalIterator struct {
 	input StringIterator
@@ -8971,6 +9159,25 @@ func (itr *stringCloseInterruptIterator) Next() (*StringPoint, error) {
 	return p, nil
 }
 
+// stringProgressIterator reports the progress of reading a string iterator.
+type stringProgressIterator struct {
+	input    StringIterator
+	progress iteratorProgress
+}
+
+func newStringProgressIterator(input StringIterator, p *Progress) *stringProgressIterator {
+	return &stringProgressIterator{input: input, progress: iteratorProgress{progress: p}}
+}
+
+func (itr *stringProgressIterator) Stats() IteratorStats { return itr.input.Stats() }
+func (itr *stringProgressIterator) Close() error { return itr.input.Close() }
+
+func (itr *stringProgressIterator) Next() (*StringPoint, error) {
+	p, err := itr.input.Next()
+	itr.progress.next(itr.input, p == nil)
+	return p, err
+}
+
 // stringReduceFloatIterator executes a reducer for every interval and buffers the result.
 type stringReduceFloatIterator struct {
 	input    *bufStringIterator
@@ -11352,6 +11559,7 @@ type booleanFillIterator struct {
 	startTime int64
 	endTime   int64
 	auxFields []interface{}
+	edges     fillEdges
 	init      bool
 	opt       IteratorOptions
 
@@ -11363,9 +11571,9 @@ type booleanFillIterator struct {
 	}
 }
 
-func newBooleanFillIterator(input BooleanIterator, expr influxql.Expr, opt IteratorOptions) *booleanFillIterator {
+func newBooleanFillIterator(input BooleanIterator, expr influxql.Expr, opt IteratorOptions, edges fillEdges) *booleanFillIterator {
 	if opt.Fill == influxql.NullFill {
-		if expr, ok := expr.(*influxql.Call); ok && expr.Name == "count" {
+		if expr, ok := expr.(*influxql.Call); ok && (expr.Name == "count" || expr.Name == "count_distinct_approx") {
 			opt.Fill = influxql.NumberFill
 			opt.FillValue = false
 		}
@@ -11391,6 +11599,7 @@ func newBooleanFillIterator(input BooleanIterator, expr influxql.Expr, opt Itera
 		startTime: startTime,
 		endTime:   endTime,
 		auxFields: auxFields,
+		edges:     edges,
 		opt:       opt,
 	}
 }
@@ -11412,6 +11621,7 @@ func (itr *booleanFillIterator) Next() (*BooleanPoint, error) {
 		if itr.opt.Location != nil {
 			_, itr.window.offset = itr.opt.Zone(itr.window.time)
 		}
+		itr.prev = itr.edge(itr.edges.prev)
 		itr.init = true
 	}
 
@@ -11449,7 +11659,7 @@ func (itr *booleanFillIterator) Next() (*BooleanPoint, error) {
 		if itr.opt.Location != nil {
 			_, itr.window.offset = itr.opt.Zone(itr.window.time)
 		}
-		itr.prev = BooleanPoint{Nil: true}
+		itr.prev = itr.edge(itr.edges.prev)
 	}
 
 	// Check if the point is our next expected point.
@@ -11467,7 +11677,7 @@ CONSTRUCT:
 		}
 
 		switch itr.opt.Fill {
-		case influxql.LinearFill:
+		case influxql.LinearFill, influxql.InterpolateTimeFill:
 			fallthrough
 		case influxql.NullFill:
 			p.Nil = true
@@ -11487,7 +11697,16 @@ CONSTRUCT:
 
 	// Advance the expected time. Do not advance to a new window here
 	// as there may be lingering points with the same timestamp in the previous
-	// window.
+	// window. Calendar windows differ in length and already account for
+	// offset changes, so the next window is found from the calendar.
+	if itr.opt.Interval.IsCalendar() {
+		if itr.opt.Ascending {
+			_, itr.window.time = itr.opt.Window(itr.window.time)
+		} else {
+			itr.window.time, _ = itr.opt.Window(itr.window.time - 1)
+		}
+		return p, nil
+	}
 	if itr.opt.Ascending {
 		itr.window.time += int64(itr.opt.Interval.Duration)
 	} else {
@@ -11508,6 +11727,17 @@ CONSTRUCT:
 	return p, nil
 }
 
+// edge returns the nearest point of the current series outside of the time
+// range from edges or a nil point if there is none.
+func (itr *booleanFillIterator) edge(edges map[string]fillEdge) BooleanPoint {
+	if e, ok := edges[fillEdgeKey(itr.window.name, itr.window.tags)]; ok {
+		if v, ok := castToBoolean(e.value); ok {
+			return BooleanPoint{Name: itr.window.name, Tags: itr.window.tags, Time: e.time, Value: v}
+		}
+	}
+	return BooleanPoint{Nil: true}
+}
+
 // booleanIntervalIterator represents a boolean implementation of IntervalIterator.
 type booleanIntervalIterator struct {
 	input BooleanIterator
@@ -11621,6 +11851,25 @@ func (itr *booleanCloseInterruptIterator) Next() (*BooleanPoint, error) {
 	return p, nil
 }
 
+// booleanProgressIterator reports the progress of reading a boolean iterator.
+type booleanProgressIterator struct {
+	input    BooleanIterator
+	progress iteratorProgress
+}
+
+func newBooleanProgressIterator(input BooleanIterator, p *Progress) *booleanProgressIterator {
+	return &booleanProgressIterator{input: input, progress: iteratorProgress{progress: p}}
+}
+
+func (itr *booleanProgressIterator) Stats() IteratorStats { return itr.input.Stats() }
+func (itr *booleanProgressIterator) Close() error { return itr.input.Close() }
+
+func (itr *booleanProgressIterator) Next() (*BooleanPoint, error) {
+	p, err := itr.input.Next()
+	itr.progress.next(itr.input, p == nil)
+	return p, err
+}
+
 // booleanReduceFloatIterator executes a reducer for every interval and buffers the result.
 type booleanReduceFloatIterator struct {
 	input    *bufBooleanIterator
@@ -137,11 +137,19 @@ func (a Iterators) Merge(opt IteratorOptions) (Iterator, error) {
 	}
 
 	// When merging the count() function, use sum() to sum the counted points.
-	if call.Name == "count" {
+	// The sketches built by hll() are merged rather than rebuilt from the
+	// encoded sketches.
+	switch call.Name {
+	case "count":
 		opt.Expr = &influxql.Call{
 			Name: "sum",
 			Args: call.Args,
 		}
+	case "hll":
+		opt.Expr = &influxql.Call{
+			Name: "hll_merge",
+			Args: call.Args,
+		}
 	}
 	return NewCallIterator(itr, opt)
 }
@@ -354,17 +362,23 @@ func NewDedupeIterator(input Iterator) Iterator {
 
 // NewFillIterator returns an iterator that fills in missing points in an aggregate.
 func NewFillIterator(input Iterator, expr influxql.Expr, opt IteratorOptions) Iterator {
+	return newFillIterator(input, expr, opt, fillEdges{})
+}
+
+// newFillIterator returns a fill iterator that uses the nearest points outside
+// of the time range from edges to fill the windows at the edges.
+func newFillIterator(input Iterator, expr influxql.Expr, opt IteratorOptions, edges fillEdges) Iterator {
 	switch input := input.(type) {
 	case FloatIterator:
-		return newFloatFillIterator(input, expr, opt)
+		return newFloatFillIterator(input, expr, opt, edges)
 	case IntegerIterator:
-		return newIntegerFillIterator(input, expr, opt)
+		return newIntegerFillIterator(input, expr, opt, edges)
 	case UnsignedIterator:
-		return newUnsignedFillIterator(input, expr, opt)
+		return newUnsignedFillIterator(input, expr, opt, edges)
 	case StringIterator:
-		return newStringFillIterator(input, expr, opt)
+		return newStringFillIterator(input, expr, opt, edges)
 	case BooleanIterator:
-		return newBooleanFillIterator(input, expr, opt)
+		return newBooleanFillIterator(input, expr, opt, edges)
 	default:
 		panic(fmt.Sprintf("unsupported fill iterator type: %T", input))
 	}
@@ -580,6 +594,10 @@ type IteratorOptions struct {
 	Fill      influxql.FillOption
 	FillValue interface{}
 
+	// How far outside of the time range to look for the nearest points of
+	// each series for fill(previous), fill(linear) and fill(interpolate_time).
+	FillLookback time.Duration
+
 	// Condition to filter by.
 	Condition influxql.Expr
 
@@ -652,6 +670,9 @@ func newIteratorOptionsStmt(stmt *influxql.SelectStatement, sopt SelectOptions)
 		}
 	}
 	opt.Interval.Duration = interval
+	if interval > 0 {
+		opt.Interval.Months = stmt.GroupByCalendarInterval()
+	}
 
 	// Always request an ordered output for the top level iterators.
 	// The emitter will always emit points as ordered.
@@ -681,6 +702,7 @@ func newIteratorOptionsStmt(stmt *influxql.SelectStatement, sopt SelectOptions)
 	opt.Limit, opt.Offset = stmt.Limit, stmt.Offset
 	opt.SLimit, opt.SOffset = stmt.SLimit, stmt.SOffset
 	opt.MaxSeriesN = sopt.MaxSeriesN
+	opt.FillLookback = sopt.MaxFillLookback
 	opt.Authorizer = sopt.Authorizer
 
 	return opt, nil
@@ -789,6 +811,8 @@ func (opt IteratorOptions) StopTime() int64 {
 func (opt IteratorOptions) Window(t int64) (start, end int64) {
 	if opt.Interval.IsZero() {
 		return opt.StartTime, opt.EndTime + 1
+	} else if opt.Interval.IsCalendar() {
+		return opt.calendarWindow(t)
 	}
 
 	// Subtract the offset to the time so we calculate the correct base interval.
@@ -864,6 +888,71 @@ func (opt IteratorOptions) Window(t int64) (start, end int64) {
 	return
 }
 
+// extendWindows extends the time range by n windows before the start time for
+// ascending options or after the end time for descending options.
+func (opt *IteratorOptions) extendWindows(n int64) {
+	if !opt.Interval.IsCalendar() {
+		if opt.Ascending {
+			opt.StartTime -= int64(opt.Interval.Duration) * n
+		} else {
+			opt.EndTime += int64(opt.Interval.Duration) * n
+		}
+		return
+	}
+
+	// Calendar windows differ in length, so step through whole windows.
+	for ; n > 0; n-- {
+		if opt.Ascending {
+			if start, _ := opt.Window(opt.StartTime); start > influxql.MinTime {
+				opt.StartTime, _ = opt.Window(start - 1)
+			}
+		} else {
+			if _, end := opt.Window(opt.EndTime); end < influxql.MaxTime {
+				_, end = opt.Window(end)
+				opt.EndTime = end - 1
+			}
+		}
+	}
+}
+
+// calendarWindow returns the calendar window [start,end) that t falls within.
+// Windows start at midnight on the first day of a month in the location of the
+// options and are aligned to January 1970.
+func (opt IteratorOptions) calendarWindow(t int64) (start, end int64) {
+	loc := opt.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	// Subtract the offset to the time so we calculate the correct base interval.
+	t -= int64(opt.Interval.Offset)
+
+	// Truncate the months since January 1970 by the number of months in the interval.
+	ts := time.Unix(0, t).In(loc)
+	months := (ts.Year()-1970)*12 + int(ts.Month()) - 1
+	if dm := months % opt.Interval.Months; dm < 0 {
+		months -= dm + opt.Interval.Months
+	} else {
+		months -= dm
+	}
+
+	// Midnight may not exist on days with an offset change, in which case
+	// time.Date normalizes it to the first time of the day.
+	if st := time.Date(1970, time.Month(months+1), 1, 0, 0, 0, 0, loc); st.Before(time.Unix(0, influxql.MinTime)) {
+		start = influxql.MinTime
+	} else {
+		start = st.UnixNano()
+	}
+	if et := time.Date(1970, time.Month(months+opt.Interval.Months+1), 1, 0, 0, 0, 0, loc); et.After(time.Unix(0, influxql.MaxTime)) {
+		end = influxql.MaxTime
+	} else {
+		end = et.UnixNano()
+	}
+	start += int64(opt.Interval.Offset)
+	end += int64(opt.Interval.Offset)
+	return
+}
+
 // DerivativeInterval returns the time interval for the derivative function.
 func (opt IteratorOptions) DerivativeInterval() Interval {
 	// Use the interval on the derivative() call, if specified.
@@ -1138,15 +1227,23 @@ func decodeMeasurement(pb *internal.Measurement) (*influxql.Measurement, error)
 type Interval struct {
 	Duration time.Duration
 	Offset   time.Duration
+
+	// Months is the number of calendar months in each window of a calendar
+	// interval. Duration is then the longest duration of a window.
+	Months int
 }
 
 // IsZero returns true if the interval has no duration.
 func (i Interval) IsZero() bool { return i.Duration == 0 }
 
+// IsCalendar returns true if the windows of the interval are calendar months.
+func (i Interval) IsCalendar() bool { return i.Months > 0 }
+
 func encodeInterval(i Interval) *internal.Interval {
 	return &internal.Interval{
 		Duration: proto.Int64(i.Duration.Nanoseconds()),
 		Offset:   proto.Int64(i.Offset.Nanoseconds()),
+		Months:   proto.Int64(int64(i.Months)),
 	}
 }
 
@@ -1154,6 +1251,7 @@ func decodeInterval(pb *internal.Interval) Interval {
 	return Interval{
 		Duration: time.Duration(pb.GetDuration()),
 		Offset:   time.Duration(pb.GetOffset()),
+		Months:   int(pb.GetMonths()),
 	}
 }
 
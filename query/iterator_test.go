@@ -1258,6 +1258,111 @@ func TestIteratorOptions_Window_Location(t *testing.T) {
 	}
 }
 
+func TestIteratorOptions_Window_Calendar(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		now        time.Time
+		start, end time.Time
+		months     int
+		offset     time.Duration
+		location   *time.Location
+	}{
+		{
+			name:   "Month",
+			now:    mustParseTime("2000-02-15T12:14:15Z"),
+			start:  mustParseTime("2000-02-01T00:00:00Z"),
+			end:    mustParseTime("2000-03-01T00:00:00Z"),
+			months: 1,
+		},
+		{
+			name:   "Quarter",
+			now:    mustParseTime("2000-05-31T23:59:59Z"),
+			start:  mustParseTime("2000-04-01T00:00:00Z"),
+			end:    mustParseTime("2000-07-01T00:00:00Z"),
+			months: 3,
+		},
+		{
+			name:   "Year",
+			now:    mustParseTime("2000-12-31T23:59:59Z"),
+			start:  mustParseTime("2000-01-01T00:00:00Z"),
+			end:    mustParseTime("2001-01-01T00:00:00Z"),
+			months: 12,
+		},
+		{
+			name:   "BeforeEpoch",
+			now:    mustParseTime("1969-11-30T00:00:00Z"),
+			start:  mustParseTime("1969-10-01T00:00:00Z"),
+			end:    mustParseTime("1970-01-01T00:00:00Z"),
+			months: 3,
+		},
+		{
+			name:   "Offset",
+			now:    mustParseTime("2000-03-01T03:00:00Z"),
+			start:  mustParseTime("2000-02-01T06:00:00Z"),
+			end:    mustParseTime("2000-03-01T06:00:00Z"),
+			months: 1,
+			offset: 6 * time.Hour,
+		},
+		{
+			name:     "Location",
+			now:      mustParseTime("2000-03-01T03:00:00Z"),
+			start:    mustParseTime("2000-02-01T00:00:00-08:00"),
+			end:      mustParseTime("2000-03-01T00:00:00-08:00"),
+			months:   1,
+			location: LosAngeles,
+		},
+		{
+			name:     "DaylightSavingTime",
+			now:      mustParseTime("2000-04-15T00:00:00-07:00"),
+			start:    mustParseTime("2000-04-01T00:00:00-08:00"),
+			end:      mustParseTime("2000-05-01T00:00:00-07:00"),
+			months:   1,
+			location: LosAngeles,
+		},
+		{
+			name:     "StandardTime",
+			now:      mustParseTime("2000-12-31T23:59:59-08:00"),
+			start:    mustParseTime("2000-01-01T00:00:00-08:00"),
+			end:      mustParseTime("2001-01-01T00:00:00-08:00"),
+			months:   12,
+			location: LosAngeles,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			opt := query.IteratorOptions{
+				Location: tt.location,
+				Interval: query.Interval{
+					Duration: time.Duration(tt.months) * 31 * 24 * time.Hour,
+					Offset:   tt.offset,
+					Months:   tt.months,
+				},
+			}
+			start, end := opt.Window(tt.now.UnixNano())
+			if have, want := time.Unix(0, start), tt.start; !have.Equal(want) {
+				t.Errorf("unexpected start time: %s != %s", have, want)
+			}
+			if have, want := time.Unix(0, end), tt.end; !have.Equal(want) {
+				t.Errorf("unexpected end time: %s != %s", have, want)
+			}
+		})
+	}
+}
+
+func TestIteratorOptions_Window_Calendar_MinMaxTime(t *testing.T) {
+	opt := query.IteratorOptions{
+		Interval: query.Interval{
+			Duration: 31 * 24 * time.Hour,
+			Months:   1,
+		},
+	}
+	if start, _ := opt.Window(influxql.MinTime); start != influxql.MinTime {
+		t.Errorf("expected start to be %d, got %d", influxql.MinTime, start)
+	}
+	if _, end := opt.Window(influxql.MaxTime); end != influxql.MaxTime {
+		t.Errorf("expected end to be %d, got %d", influxql.MaxTime, end)
+	}
+}
+
 func TestIteratorOptions_Window_MinTime(t *testing.T) {
 	opt := query.IteratorOptions{
 		StartTime: influxql.MinTime,
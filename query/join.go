@@ -0,0 +1,509 @@
+package query
+
+import (
+	"context"
+	"strings"
+
+	"github.com/influxdata/influxql"
+)
+
+// joinSourceName returns the name used to qualify references to one side of
+// a join. It is empty if the source cannot be used within a join.
+func joinSourceName(src influxql.Source) string {
+	switch src := src.(type) {
+	case *influxql.Measurement:
+		if src.Regex != nil {
+			return ""
+		}
+		return src.Name
+	case *influxql.SubQuery:
+		if len(src.Statement.Sources) == 1 {
+			return joinSourceName(src.Statement.Sources[0])
+		}
+	}
+	return ""
+}
+
+// joinFieldName splits a qualified reference such as cpu.usage into the side
+// of the join it references and the name of the field within that side.
+func joinFieldName(join *influxql.Join, name string) (influxql.Source, string, bool) {
+	for _, src := range []influxql.Source{join.LHS, join.RHS} {
+		if prefix := joinSourceName(src) + "."; strings.HasPrefix(name, prefix) {
+			return src, strings.TrimPrefix(name, prefix), true
+		}
+	}
+	return nil, "", false
+}
+
+func isJoinTag(join *influxql.Join, name string) bool {
+	for _, tag := range join.On {
+		if tag == name {
+			return true
+		}
+	}
+	return false
+}
+
+// rewriteJoinFields rewrites the sides of any joins within the statement and
+// assigns types to the references that are read through them. RewriteFields
+// is unable to see through a join so these would otherwise remain untyped.
+// It returns true if the statement reads from a join.
+func rewriteJoinFields(stmt *influxql.SelectStatement, m influxql.FieldMapper) (bool, error) {
+	var (
+		join   *influxql.Join
+		joined bool
+	)
+	for _, src := range stmt.Sources {
+		switch src := src.(type) {
+		case *influxql.SubQuery:
+			ok, err := rewriteJoinFields(src.Statement, m)
+			if err != nil {
+				return false, err
+			}
+			joined = joined || ok
+		case *influxql.Join:
+			for _, side := range []influxql.Source{src.LHS, src.RHS} {
+				side := side.(*influxql.SubQuery)
+				other, err := side.Statement.RewriteFields(m)
+				if err != nil {
+					return false, err
+				} else if _, err := rewriteJoinFields(other, m); err != nil {
+					return false, err
+				}
+				side.Statement = other
+			}
+			join, joined = src, true
+		}
+	}
+
+	// Nothing was rewritten so the types assigned by RewriteFields are final.
+	if !joined {
+		return false, nil
+	}
+
+	rewrite := func(n influxql.Node) {
+		ref, ok := n.(*influxql.VarRef)
+		if !ok || (ref.Type != influxql.Unknown && ref.Type != influxql.AnyField) {
+			return
+		}
+
+		var typ influxql.DataType
+		if join == nil {
+			typ = influxql.EvalType(ref, stmt.Sources, m)
+		} else if isJoinTag(join, ref.Val) {
+			typ = influxql.Tag
+		} else if src, name, ok := joinFieldName(join, ref.Val); ok {
+			typ = influxql.EvalType(&influxql.VarRef{Val: name}, influxql.Sources{src}, m)
+		}
+		if typ == influxql.Tag && ref.Type == influxql.AnyField {
+			return
+		}
+		ref.Type = typ
+	}
+	influxql.WalkFunc(stmt.Fields, rewrite)
+	influxql.WalkFunc(stmt.Condition, rewrite)
+	return true, nil
+}
+
+type joinBuilder struct {
+	ic   IteratorCreator
+	join *influxql.Join
+}
+
+// buildAuxIterator constructs an auxiliary Iterator from a join.
+func (b *joinBuilder) buildAuxIterator(ctx context.Context, opt IteratorOptions) (Iterator, error) {
+	cur, joinOpt, err := b.buildCursor(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Construct the iterators for the join.
+	indexes := b.mapAuxFields(cur.Columns(), opt.Aux)
+	itr := NewIteratorMapper(cur, nil, indexes, joinOpt)
+	if len(opt.GetDimensions()) != len(joinOpt.GetDimensions()) {
+		itr = NewTagSubsetIterator(itr, opt)
+	}
+	return itr, nil
+}
+
+func (b *joinBuilder) buildVarRefIterator(ctx context.Context, expr *influxql.VarRef, opt IteratorOptions) (Iterator, error) {
+	cur, joinOpt, err := b.buildCursor(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Look for the field or tag that is driving this query.
+	columns := cur.Columns()
+	driver := b.mapAuxField(columns, expr)
+	if driver == nil {
+		// There are no results without a driver.
+		cur.Close()
+		return nil, nil
+	}
+
+	indexes := b.mapAuxFields(columns, opt.Aux)
+	itr := NewIteratorMapper(cur, driver, indexes, joinOpt)
+	if len(opt.GetDimensions()) != len(joinOpt.GetDimensions()) {
+		itr = NewTagSubsetIterator(itr, opt)
+	}
+	return itr, nil
+}
+
+func (b *joinBuilder) mapAuxFields(columns []influxql.VarRef, auxFields []influxql.VarRef) []IteratorMap {
+	indexes := make([]IteratorMap, len(auxFields))
+	for i, name := range auxFields {
+		m := b.mapAuxField(columns, &name)
+		if m == nil {
+			m = NullMap{}
+		}
+		indexes[i] = m
+	}
+	return indexes
+}
+
+func (b *joinBuilder) mapAuxField(columns []influxql.VarRef, name *influxql.VarRef) IteratorMap {
+	for i, col := range columns {
+		if col.Val == name.Val {
+			return FieldMap{
+				Index: i,
+				Type:  name.Type,
+			}
+		}
+	}
+	if isJoinTag(b.join, name.Val) {
+		return TagMap(name.Val)
+	}
+	return nil
+}
+
+// buildCursor reads both sides of the join and returns a cursor with the
+// joined rows. The options used for the joined rows are returned with the
+// cursor.
+func (b *joinBuilder) buildCursor(ctx context.Context, opt IteratorOptions) (Cursor, IteratorOptions, error) {
+	// Each side is grouped by the join tags so every row carries them.
+	joinOpt := opt
+	joinOpt.GroupBy = make(map[string]struct{}, len(b.join.On))
+	for _, tag := range b.join.On {
+		joinOpt.GroupBy[tag] = struct{}{}
+	}
+	// The merge join requires both sides to be sorted. Series limits cannot
+	// be applied to each side independently.
+	joinOpt.Ordered = true
+	joinOpt.SLimit, joinOpt.SOffset = 0, 0
+
+	// Each side is sorted by the dimensions of the query and then by the join
+	// tags before time, so the rows of each series of a side are read in
+	// time order and the sides are merged series by series.
+	sideOpt := joinOpt
+	sideOpt.Dimensions = joinDimensions(opt.Dimensions, b.join.On)
+
+	if e, ok := b.ic.(*explainIteratorCreator); ok {
+		e.nodes = append(e.nodes, planNode{
+			Expr:      opt.Expr,
+			Aux:       opt.Aux,
+			Join:      b.join,
+			Fill:      opt.Fill,
+			FillValue: opt.FillValue,
+		})
+	}
+
+	var sides [2]Cursor
+	for i, src := range []influxql.Source{b.join.LHS, b.join.RHS} {
+		stmt := src.(*influxql.SubQuery).Statement
+		subOpt, err := newIteratorOptionsSubstatement(ctx, stmt, sideOpt)
+		if err != nil {
+			closeCursors(sides[:i])
+			return nil, IteratorOptions{}, err
+		}
+
+		cur, err := buildCursor(ctx, stmt, b.ic, subOpt)
+		if err != nil {
+			closeCursors(sides[:i])
+			return nil, IteratorOptions{}, err
+		}
+		sides[i] = cur
+	}
+
+	var cur Cursor = newJoinCursor(b.join, sides, sideOpt)
+
+	// Filter the joined rows by a condition if one was given.
+	if opt.Condition != nil {
+		cur = newFilterCursor(cur, opt.Condition)
+	}
+	return cur, joinOpt, nil
+}
+
+// joinDimensions returns the dimensions of the query followed by the join tags
+// which are not already dimensions.
+func joinDimensions(dimensions, on []string) []string {
+	other := make([]string, len(dimensions), len(dimensions)+len(on))
+	copy(other, dimensions)
+	for _, tag := range on {
+		found := false
+		for _, dim := range dimensions {
+			if dim == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			other = append(other, tag)
+		}
+	}
+	return other
+}
+
+func closeCursors(curs []Cursor) {
+	for _, cur := range curs {
+		cur.Close()
+	}
+}
+
+// joinCursor merges the rows of two cursors with the same join tags and time.
+// Both cursors must be sorted by the dimensions of the query, then by the join
+// tags and then by time.
+//
+// Rows from one side without a match in the other are dropped for an inner
+// join. For an outer join, the values of the missing side are taken from the
+// fill option. Rows are dropped when there is no fill.
+type joinCursor struct {
+	sides      [2]*joinSide
+	outer      bool
+	fill       influxql.FillOption
+	fillValue  interface{}
+	dimensions []string
+	ascending  bool
+
+	name    string
+	series  Series
+	columns []influxql.VarRef
+	rows    []Row
+}
+
+type joinSide struct {
+	cur     Cursor
+	row     Row
+	ok      bool
+	columns []influxql.VarRef
+
+	// prev holds the last values read for each series for fill(previous).
+	prev map[string][]interface{}
+}
+
+func newJoinCursor(join *influxql.Join, curs [2]Cursor, opt IteratorOptions) *joinCursor {
+	cur := &joinCursor{
+		outer:      join.Type == influxql.OuterJoin,
+		fill:       opt.Fill,
+		fillValue:  opt.FillValue,
+		dimensions: opt.Dimensions,
+		ascending:  opt.Ascending,
+		name:       joinSourceName(join.LHS),
+	}
+	if v, ok := cur.fillValue.(int); ok {
+		cur.fillValue = int64(v)
+	}
+
+	for i, src := range []influxql.Source{join.LHS, join.RHS} {
+		side := &joinSide{
+			cur:     curs[i],
+			columns: curs[i].Columns(),
+			prev:    make(map[string][]interface{}),
+		}
+		name := joinSourceName(src)
+		for _, col := range side.columns {
+			cur.columns = append(cur.columns, influxql.VarRef{
+				Val:  name + "." + col.Val,
+				Type: col.Type,
+			})
+		}
+		side.next()
+		cur.sides[i] = side
+	}
+	return cur
+}
+
+func (s *joinSide) next() {
+	// Scan into a new row since the cursor reuses the memory of the row.
+	s.row = Row{}
+	s.ok = s.cur.Scan(&s.row)
+}
+
+func (cur *joinCursor) Scan(row *Row) bool {
+	for len(cur.rows) == 0 {
+		if !cur.advance() {
+			return false
+		}
+	}
+	*row = cur.rows[0]
+	cur.rows = cur.rows[1:]
+	return true
+}
+
+// advance reads the rows with the next window and time from each side and
+// joins them.
+func (cur *joinCursor) advance() bool {
+	lhs, rhs := cur.sides[0], cur.sides[1]
+	if !lhs.ok && !rhs.ok {
+		return false
+	} else if !cur.outer && (!lhs.ok || !rhs.ok) {
+		return false
+	}
+
+	var l, r []Row
+	switch {
+	case !rhs.ok:
+		l = cur.readRun(lhs)
+	case !lhs.ok:
+		r = cur.readRun(rhs)
+	default:
+		cmp := cur.compare(&lhs.row, &rhs.row)
+		if cmp <= 0 {
+			l = cur.readRun(lhs)
+		}
+		if cmp >= 0 {
+			r = cur.readRun(rhs)
+		}
+	}
+	cur.join(l, r)
+	return true
+}
+
+// compare compares rows by the dimensions and join tags of the query before
+// time, which is the order the sides are read in.
+func (cur *joinCursor) compare(a, b *Row) int {
+	aTags, bTags := a.Series.Tags.Subset(cur.dimensions), b.Series.Tags.Subset(cur.dimensions)
+	if aID, bID := aTags.ID(), bTags.ID(); aID != bID {
+		if (aID < bID) == cur.ascending {
+			return -1
+		}
+		return 1
+	}
+	if a.Time == b.Time {
+		return 0
+	} else if (a.Time < b.Time) == cur.ascending {
+		return -1
+	}
+	return 1
+}
+
+// readRun reads every row from the side with the same dimensions and time as
+// the current row.
+func (cur *joinCursor) readRun(s *joinSide) []Row {
+	rows := []Row{s.row}
+	for s.next(); s.ok && cur.compare(&s.row, &rows[0]) == 0; s.next() {
+		rows = append(rows, s.row)
+	}
+	return rows
+}
+
+// join emits the rows from each side of the join with the same join tags.
+func (cur *joinCursor) join(lhs, rhs []Row) {
+	matches := make(map[string][]Row, len(rhs))
+	for _, row := range rhs {
+		id := row.Series.Tags.ID()
+		matches[id] = append(matches[id], row)
+	}
+
+	matched := make(map[string]bool, len(rhs))
+	for i := range lhs {
+		row := &lhs[i]
+		id := row.Series.Tags.ID()
+		if rows, ok := matches[id]; ok {
+			matched[id] = true
+			for _, other := range rows {
+				cur.emit(row, row.Values, other.Values)
+			}
+		} else if values, ok := cur.fillValues(cur.sides[1], id); ok {
+			cur.emit(row, row.Values, values)
+		}
+	}
+	for i := range rhs {
+		row := &rhs[i]
+		id := row.Series.Tags.ID()
+		if matched[id] {
+			continue
+		} else if values, ok := cur.fillValues(cur.sides[0], id); ok {
+			cur.emit(row, values, row.Values)
+		}
+	}
+
+	// Remember the last values of each series for fill(previous).
+	for _, row := range lhs {
+		cur.sides[0].prev[row.Series.Tags.ID()] = row.Values
+	}
+	for _, row := range rhs {
+		cur.sides[1].prev[row.Series.Tags.ID()] = row.Values
+	}
+}
+
+// fillValues returns the values used for a side of the join that has no row
+// for the series. It returns false if the row should be dropped.
+func (cur *joinCursor) fillValues(s *joinSide, id string) ([]interface{}, bool) {
+	if !cur.outer {
+		return nil, false
+	}
+
+	values := make([]interface{}, len(s.columns))
+	switch cur.fill {
+	case influxql.NoFill:
+		return nil, false
+	case influxql.NumberFill:
+		for i, col := range s.columns {
+			switch col.Type {
+			case influxql.Float, influxql.Integer, influxql.Unsigned:
+				values[i] = castToType(cur.fillValue, col.Type)
+			}
+		}
+	case influxql.PreviousFill:
+		copy(values, s.prev[id])
+	}
+	return values, true
+}
+
+func (cur *joinCursor) emit(row *Row, lhs, rhs []interface{}) {
+	if cur.series.id == 0 || !row.Series.Tags.Equals(&cur.series.Tags) {
+		cur.series.Name = cur.name
+		cur.series.Tags = row.Series.Tags
+		cur.series.id++
+	}
+
+	values := make([]interface{}, len(cur.columns))
+	copy(values, lhs)
+	copy(values[len(cur.sides[0].columns):], rhs)
+	cur.rows = append(cur.rows, Row{
+		Time:   row.Time,
+		Series: cur.series,
+		Values: values,
+	})
+}
+
+func (cur *joinCursor) Stats() IteratorStats {
+	var stats IteratorStats
+	for _, s := range cur.sides {
+		stats.Add(s.cur.Stats())
+	}
+	return stats
+}
+
+func (cur *joinCursor) Err() error {
+	for _, s := range cur.sides {
+		if err := s.cur.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cur *joinCursor) Columns() []influxql.VarRef {
+	return cur.columns
+}
+
+func (cur *joinCursor) Close() error {
+	var err error
+	for _, s := range cur.sides {
+		if e := s.cur.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
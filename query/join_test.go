@@ -0,0 +1,234 @@
+package query_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxql"
+)
+
+func TestJoin(t *testing.T) {
+	// Raw points for each measurement sorted by the join tags and then by
+	// time, like the engine returns them.
+	cpu := func() query.Iterator {
+		return &FloatIterator{Points: []query.FloatPoint{
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 0 * Second, Aux: []interface{}{float64(10)}},
+			{Name: "cpu", Tags: ParseTags("host=A"), Time: 10 * Second, Aux: []interface{}{float64(20)}},
+			{Name: "cpu", Tags: ParseTags("host=B"), Time: 0 * Second, Aux: []interface{}{float64(30)}},
+		}}
+	}
+	cpuCount := func() query.Iterator {
+		return &FloatIterator{Points: []query.FloatPoint{
+			{Name: "cpu_count", Tags: ParseTags("host=A"), Time: 0 * Second, Aux: []interface{}{int64(2)}},
+			{Name: "cpu_count", Tags: ParseTags("host=A"), Time: 20 * Second, Aux: []interface{}{int64(4)}},
+			{Name: "cpu_count", Tags: ParseTags("host=B"), Time: 0 * Second, Aux: []interface{}{int64(3)}},
+		}}
+	}
+	raw := func(t *testing.T) CreateIteratorFn {
+		return func(ctx context.Context, m *influxql.Measurement, opt query.IteratorOptions) query.Iterator {
+			if got, want := opt.GetDimensions(), []string{"host"}; !cmp.Equal(got, want) {
+				t.Errorf("unexpected dimensions: got=%v want=%v", got, want)
+			} else if got, want := opt.Dimensions, []string{"host"}; !cmp.Equal(got, want) {
+				t.Errorf("unexpected sort dimensions: got=%v want=%v", got, want)
+			}
+			switch m.Name {
+			case "cpu":
+				return cpu()
+			case "cpu_count":
+				return cpuCount()
+			}
+			t.Errorf("unexpected source: %s", m.Name)
+			return nil
+		}
+	}
+
+	for _, test := range []struct {
+		Name        string
+		Statement   string
+		MapShardsFn func(t *testing.T) CreateIteratorFn
+		Rows        []query.Row
+	}{
+		{
+			Name:        "Inner",
+			Statement:   `SELECT cpu.usage / cpu_count.n FROM cpu INNER JOIN cpu_count ON host WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:30Z'`,
+			MapShardsFn: raw,
+			Rows: []query.Row{
+				{Time: 0 * Second, Series: query.Series{Name: "cpu"}, Values: []interface{}{float64(5)}},
+				{Time: 0 * Second, Series: query.Series{Name: "cpu"}, Values: []interface{}{float64(10)}},
+			},
+		},
+		{
+			Name:        "Outer_FillNull",
+			Statement:   `SELECT cpu.usage, cpu_count.n, host FROM cpu OUTER JOIN cpu_count ON host WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:30Z'`,
+			MapShardsFn: raw,
+			Rows: []query.Row{
+				{Time: 0 * Second, Series: query.Series{Name: "cpu"}, Values: []interface{}{float64(10), int64(2), "A"}},
+				{Time: 10 * Second, Series: query.Series{Name: "cpu"}, Values: []interface{}{float64(20), nil, "A"}},
+				{Time: 20 * Second, Series: query.Series{Name: "cpu"}, Values: []interface{}{nil, int64(4), "A"}},
+				{Time: 0 * Second, Series: query.Series{Name: "cpu"}, Values: []interface{}{float64(30), int64(3), "B"}},
+			},
+		},
+		{
+			Name:        "Outer_FillNumber",
+			Statement:   `SELECT cpu.usage, cpu_count.n FROM cpu OUTER JOIN cpu_count ON host WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:30Z' fill(1)`,
+			MapShardsFn: raw,
+			Rows: []query.Row{
+				{Time: 0 * Second, Series: query.Series{Name: "cpu"}, Values: []interface{}{float64(10), int64(2)}},
+				{Time: 10 * Second, Series: query.Series{Name: "cpu"}, Values: []interface{}{float64(20), int64(1)}},
+				{Time: 20 * Second, Series: query.Series{Name: "cpu"}, Values: []interface{}{float64(1), int64(4)}},
+				{Time: 0 * Second, Series: query.Series{Name: "cpu"}, Values: []interface{}{float64(30), int64(3)}},
+			},
+		},
+		{
+			Name:        "Outer_FillPrevious",
+			Statement:   `SELECT cpu.usage, cpu_count.n FROM cpu OUTER JOIN cpu_count ON host WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:30Z' fill(previous)`,
+			MapShardsFn: raw,
+			Rows: []query.Row{
+				{Time: 0 * Second, Series: query.Series{Name: "cpu"}, Values: []interface{}{float64(10), int64(2)}},
+				{Time: 10 * Second, Series: query.Series{Name: "cpu"}, Values: []interface{}{float64(20), int64(2)}},
+				{Time: 20 * Second, Series: query.Series{Name: "cpu"}, Values: []interface{}{float64(20), int64(4)}},
+				{Time: 0 * Second, Series: query.Series{Name: "cpu"}, Values: []interface{}{float64(30), int64(3)}},
+			},
+		},
+		{
+			Name:      "Aggregate_GroupByTag",
+			Statement: `SELECT sum(cpu.usage) FROM cpu OUTER JOIN cpu_count ON host WHERE cpu_count.n > 2 AND time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:30Z' GROUP BY time(30s), host`,
+			MapShardsFn: func(t *testing.T) CreateIteratorFn {
+				return func(ctx context.Context, m *influxql.Measurement, opt query.IteratorOptions) query.Iterator {
+					// Points are sorted by host since the query is grouped by host.
+					var points []query.FloatPoint
+					switch m.Name {
+					case "cpu":
+						points = []query.FloatPoint{
+							{Name: "cpu", Tags: ParseTags("host=A"), Time: 0 * Second, Aux: []interface{}{float64(10)}},
+							{Name: "cpu", Tags: ParseTags("host=A"), Time: 10 * Second, Aux: []interface{}{float64(20)}},
+							{Name: "cpu", Tags: ParseTags("host=B"), Time: 0 * Second, Aux: []interface{}{float64(30)}},
+						}
+					case "cpu_count":
+						points = []query.FloatPoint{
+							{Name: "cpu_count", Tags: ParseTags("host=A"), Time: 0 * Second, Aux: []interface{}{int64(2)}},
+							{Name: "cpu_count", Tags: ParseTags("host=A"), Time: 10 * Second, Aux: []interface{}{int64(3)}},
+							{Name: "cpu_count", Tags: ParseTags("host=B"), Time: 0 * Second, Aux: []interface{}{int64(3)}},
+						}
+					}
+					return &FloatIterator{Points: points}
+				}
+			},
+			Rows: []query.Row{
+				{Time: 0 * Second, Series: query.Series{Name: "cpu", Tags: ParseTags("host=A")}, Values: []interface{}{float64(20)}},
+				{Time: 0 * Second, Series: query.Series{Name: "cpu", Tags: ParseTags("host=B")}, Values: []interface{}{float64(30)}},
+			},
+		},
+		{
+			Name:      "SubQuery",
+			Statement: `SELECT cpu.usage - cpu_count.n FROM cpu INNER JOIN (SELECT max(n) AS n FROM cpu_count GROUP BY time(20s), host) ON host WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:30Z'`,
+			MapShardsFn: func(t *testing.T) CreateIteratorFn {
+				return func(ctx context.Context, m *influxql.Measurement, opt query.IteratorOptions) query.Iterator {
+					switch m.Name {
+					case "cpu":
+						return cpu()
+					case "cpu_count":
+						if got, want := opt.Expr.String(), "max(n::integer)"; got != want {
+							t.Errorf("unexpected expression: got=%s want=%s", got, want)
+						}
+						itr, err := query.NewCallIterator(&IntegerIterator{Points: []query.IntegerPoint{
+							{Name: "cpu_count", Tags: ParseTags("host=A"), Time: 0 * Second, Value: 2},
+							{Name: "cpu_count", Tags: ParseTags("host=A"), Time: 10 * Second, Value: 5},
+							{Name: "cpu_count", Tags: ParseTags("host=B"), Time: 0 * Second, Value: 3},
+						}}, opt)
+						if err != nil {
+							panic(err)
+						}
+						return itr
+					}
+					t.Errorf("unexpected source: %s", m.Name)
+					return nil
+				}
+			},
+			Rows: []query.Row{
+				{Time: 0 * Second, Series: query.Series{Name: "cpu"}, Values: []interface{}{float64(5)}},
+				{Time: 0 * Second, Series: query.Series{Name: "cpu"}, Values: []interface{}{float64(27)}},
+			},
+		},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			shardMapper := ShardMapper{
+				MapShardsFn: func(sources influxql.Sources, tr influxql.TimeRange) query.ShardGroup {
+					fn := test.MapShardsFn(t)
+					return &ShardGroup{
+						Fields: map[string]influxql.DataType{
+							"usage": influxql.Float,
+							"n":     influxql.Integer,
+						},
+						Dimensions: []string{"host"},
+						CreateIteratorFn: func(ctx context.Context, m *influxql.Measurement, opt query.IteratorOptions) (query.Iterator, error) {
+							return fn(ctx, m, opt), nil
+						},
+					}
+				},
+			}
+
+			stmt := MustParseSelectStatement(test.Statement)
+			stmt.OmitTime = true
+			cur, err := query.Select(context.Background(), stmt, &shardMapper, query.SelectOptions{})
+			if err != nil {
+				t.Fatalf("unexpected parse error: %s", err)
+			} else if a, err := ReadCursor(cur); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			} else if diff := cmp.Diff(test.Rows, a); diff != "" {
+				t.Fatalf("unexpected points:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestJoin_Explain(t *testing.T) {
+	shardMapper := ShardMapper{
+		MapShardsFn: func(sources influxql.Sources, tr influxql.TimeRange) query.ShardGroup {
+			return &ShardGroup{
+				Fields: map[string]influxql.DataType{
+					"usage": influxql.Float,
+					"n":     influxql.Integer,
+				},
+				Dimensions: []string{"host"},
+			}
+		},
+	}
+
+	stmt := MustParseSelectStatement(`SELECT mean(cpu.usage) / max(cpu_count.n) FROM cpu OUTER JOIN cpu_count ON host WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:30Z' GROUP BY time(10s) fill(0)`)
+	c, err := query.Compile(stmt, query.CompileOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := c.Prepare(&shardMapper, query.SelectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	plan, err := p.Explain()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The plan for each side follows the plan for the join. The calls are
+	// planned in no particular order, so the join may be planned for either.
+	const exp = `JOIN: OUTER JOIN
+EXPRESSION: %s
+JOIN ON: time, host
+MISSING SIDE FILL: 0
+LEFT: SELECT usage::float FROM cpu GROUP BY host
+RIGHT: SELECT n::integer FROM cpu_count GROUP BY host
+
+EXPRESSION: <nil>
+AUXILIARY FIELDS: usage::float
+`
+	if !strings.HasPrefix(plan, fmt.Sprintf(exp, `"cpu.usage"::float`)) &&
+		!strings.HasPrefix(plan, fmt.Sprintf(exp, `"cpu_count.n"::integer`)) {
+		t.Fatalf("unexpected plan:\n%s", plan)
+	}
+}
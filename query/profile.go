@@ -0,0 +1,46 @@
+package query
+
+import (
+	"time"
+
+	"github.com/influxdata/influxdb/pkg/tracing"
+)
+
+// Profile is a node of the span tree recorded while executing a statement
+// with profiling enabled.
+type Profile struct {
+	Name     string                 `json:"name"`
+	Labels   map[string]string      `json:"labels,omitempty"`
+	Fields   map[string]interface{} `json:"fields,omitempty"`
+	Children []*Profile             `json:"children,omitempty"`
+}
+
+// NewProfile converts a trace tree into a Profile. Durations are formatted
+// the same way as the output of EXPLAIN ANALYZE.
+func NewProfile(node *tracing.TreeNode) *Profile {
+	if node == nil {
+		return nil
+	}
+
+	p := &Profile{Name: node.Raw.Name}
+	if len(node.Raw.Labels) > 0 {
+		p.Labels = make(map[string]string, len(node.Raw.Labels))
+		for _, l := range node.Raw.Labels {
+			p.Labels[l.Key] = l.Value
+		}
+	}
+	if len(node.Raw.Fields) > 0 {
+		p.Fields = make(map[string]interface{}, len(node.Raw.Fields))
+		for _, f := range node.Raw.Fields {
+			v := f.Value()
+			if d, ok := v.(time.Duration); ok {
+				v = d.String()
+			}
+			p.Fields[f.Key()] = v
+		}
+	}
+	for _, c := range node.Children {
+		p.Children = append(p.Children, NewProfile(c))
+	}
+	return p
+}
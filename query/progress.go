@@ -0,0 +1,101 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// Progress counts the work done by a running query. Shards are counted once
+// for every iterator created on them. It is safe for concurrent use.
+type Progress struct {
+	shardN          int64
+	shardsCompleted int64
+	pointN          int64
+
+	// charge counts points scanned against the quotas of the query, if it
+	// has any.
+	charge func(n int64)
+}
+
+// ProgressFromContext returns the Progress of the query executing with the
+// Context if one exists.
+func ProgressFromContext(ctx context.Context) *Progress {
+	v, _ := ctx.Value(progressContextKey).(*Progress)
+	return v
+}
+
+// AddShards adds n shards to read.
+func (p *Progress) AddShards(n int) {
+	atomic.AddInt64(&p.shardN, int64(n))
+}
+
+// CompleteShard marks a shard as read.
+func (p *Progress) CompleteShard() {
+	atomic.AddInt64(&p.shardsCompleted, 1)
+}
+
+// AddPointsScanned adds n points scanned.
+func (p *Progress) AddPointsScanned(n int) {
+	atomic.AddInt64(&p.pointN, int64(n))
+	if p.charge != nil && n > 0 {
+		p.charge(int64(n))
+	}
+}
+
+// ShardN returns the number of shards to read.
+func (p *Progress) ShardN() int64 { return atomic.LoadInt64(&p.shardN) }
+
+// ShardsCompleted returns the number of shards read.
+func (p *Progress) ShardsCompleted() int64 { return atomic.LoadInt64(&p.shardsCompleted) }
+
+// PointN returns the number of points scanned.
+func (p *Progress) PointN() int64 { return atomic.LoadInt64(&p.pointN) }
+
+// NewProgressIterator returns an iterator that reports the progress of
+// reading the iterator of a shard. The shard is completed once the iterator
+// is exhausted.
+func NewProgressIterator(input Iterator, p *Progress) Iterator {
+	switch input := input.(type) {
+	case FloatIterator:
+		return newFloatProgressIterator(input, p)
+	case IntegerIterator:
+		return newIntegerProgressIterator(input, p)
+	case UnsignedIterator:
+		return newUnsignedProgressIterator(input, p)
+	case StringIterator:
+		return newStringProgressIterator(input, p)
+	case BooleanIterator:
+		return newBooleanProgressIterator(input, p)
+	default:
+		panic(fmt.Sprintf("unsupported progress iterator type: %T", input))
+	}
+}
+
+// iteratorProgress reports the progress of an iterator to a Progress.
+type iteratorProgress struct {
+	progress *Progress
+	count    int
+	pointN   int
+	done     bool
+}
+
+// next is called after reading a point from itr. The points scanned are only
+// updated every N points since reading the stats of an iterator may be
+// expensive.
+func (p *iteratorProgress) next(itr Iterator, eof bool) {
+	if p.done {
+		return
+	}
+
+	p.count++
+	if eof || p.count&0x3FF == 0 {
+		stats := itr.Stats()
+		p.progress.AddPointsScanned(stats.PointN - p.pointN)
+		p.pointN = stats.PointN
+	}
+	if eof {
+		p.done = true
+		p.progress.CompleteShard()
+	}
+}
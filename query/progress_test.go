@@ -0,0 +1,63 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/influxdb/query"
+)
+
+// progressIterator counts each point read as a point scanned.
+type progressIterator struct {
+	n     int
+	stats query.IteratorStats
+}
+
+func (itr *progressIterator) Stats() query.IteratorStats { return itr.stats }
+func (itr *progressIterator) Close() error               { return nil }
+
+func (itr *progressIterator) Next() (*query.FloatPoint, error) {
+	if itr.stats.PointN == itr.n {
+		return nil, nil
+	}
+	itr.stats.PointN++
+	return &query.FloatPoint{Time: int64(itr.stats.PointN)}, nil
+}
+
+func TestProgressIterator(t *testing.T) {
+	var p query.Progress
+	p.AddShards(2)
+
+	itr := query.NewProgressIterator(&progressIterator{n: 2000}, &p).(query.FloatIterator)
+	for i := 0; i < 1500; i++ {
+		if pt, err := itr.Next(); err != nil {
+			t.Fatal(err)
+		} else if pt == nil {
+			t.Fatalf("unexpected end of iterator after %d points", i)
+		}
+	}
+	if got, want := p.PointN(), int64(1024); got != want {
+		t.Fatalf("unexpected points scanned: got=%d want=%d", got, want)
+	} else if got, want := p.ShardsCompleted(), int64(0); got != want {
+		t.Fatalf("unexpected shards completed: got=%d want=%d", got, want)
+	}
+
+	for {
+		if pt, err := itr.Next(); err != nil {
+			t.Fatal(err)
+		} else if pt == nil {
+			break
+		}
+	}
+	// Reading past the end does not complete the shard again.
+	if _, err := itr.Next(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := p.PointN(), int64(2000); got != want {
+		t.Fatalf("unexpected points scanned: got=%d want=%d", got, want)
+	} else if got, want := p.ShardsCompleted(), int64(1); got != want {
+		t.Fatalf("unexpected shards completed: got=%d want=%d", got, want)
+	} else if got, want := p.ShardN(), int64(2); got != want {
+		t.Fatalf("unexpected shards: got=%d want=%d", got, want)
+	}
+}
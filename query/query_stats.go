@@ -0,0 +1,211 @@
+package query
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/ddsketch"
+	"github.com/influxdata/influxql"
+)
+
+// DefaultMaxQueryFingerprints is the default number of fingerprints tracked
+// by QueryStats.
+const DefaultMaxQueryFingerprints = 1000
+
+// fingerprintParam replaces the literals of a statement in its fingerprint.
+var fingerprintParam = &influxql.BoundParameter{Name: "?"}
+
+// Fingerprint returns the statement with every literal replaced by a "?" so
+// that statements which only differ by their time range, tag values or
+// thresholds share the same fingerprint.
+func Fingerprint(stmt influxql.Statement) string {
+	if s, ok := stmt.(*influxql.SelectStatement); ok {
+		var fn func(n influxql.Node) influxql.Node
+		fn = func(n influxql.Node) influxql.Node {
+			switch n := n.(type) {
+			case influxql.Sources:
+				// Sources are not traversed, so rewrite subqueries and joins here.
+				for i, src := range n {
+					n[i] = influxql.RewriteFunc(src, fn).(influxql.Source)
+				}
+			case *influxql.BooleanLiteral, *influxql.DurationLiteral, *influxql.IntegerLiteral,
+				*influxql.NumberLiteral, *influxql.RegexLiteral, *influxql.StringLiteral,
+				*influxql.TimeLiteral, *influxql.UnsignedLiteral:
+				return fingerprintParam
+			}
+			return n
+		}
+		stmt = influxql.RewriteFunc(s.Clone(), fn).(influxql.Statement)
+	}
+	// String literals were replaced, so the quoted parameter only appears
+	// where a literal was.
+	return strings.Replace(stmt.String(), fingerprintParam.String(), "?", -1)
+}
+
+// StatementStats holds the statistics of an executed statement.
+type StatementStats struct {
+	Fingerprint string
+	Database    string
+	User        string
+	Duration    time.Duration
+
+	// Number of series and points scanned by the statement.
+	SeriesN int
+	PointN  int
+
+	// Number of rows returned by the statement.
+	RowN int
+}
+
+// QueryStats aggregates the statistics of executed statements by database
+// and fingerprint.
+type QueryStats struct {
+	mu    sync.Mutex
+	maxN  int
+	stats map[queryStatsKey]*fingerprintStats
+
+	// Number of statements added, used to order fingerprints by last use.
+	seq uint64
+}
+
+type queryStatsKey struct {
+	database    string
+	fingerprint string
+}
+
+type fingerprintStats struct {
+	count   int64
+	total   time.Duration
+	max     time.Duration
+	seriesN int64
+	pointN  int64
+	rowN    int64
+
+	// Durations of the statements in nanoseconds.
+	latency *ddsketch.Sketch
+
+	// Sequence number of the last statement added.
+	lastSeen uint64
+}
+
+// NewQueryStats returns a QueryStats tracking up to maxN fingerprints. Once
+// maxN fingerprints are tracked, a new fingerprint replaces the least
+// recently seen one.
+func NewQueryStats(maxN int) *QueryStats {
+	return &QueryStats{
+		maxN:  maxN,
+		stats: make(map[queryStatsKey]*fingerprintStats),
+	}
+}
+
+// Add adds the statistics of an executed statement.
+func (s *QueryStats) Add(stats StatementStats) {
+	key := queryStatsKey{database: stats.Database, fingerprint: stats.Fingerprint}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fs := s.stats[key]
+	if fs == nil {
+		if s.maxN > 0 && len(s.stats) >= s.maxN {
+			s.evict()
+		}
+		fs = &fingerprintStats{latency: ddsketch.NewDefault()}
+		s.stats[key] = fs
+	}
+
+	s.seq++
+	fs.lastSeen = s.seq
+	fs.count++
+	fs.total += stats.Duration
+	if stats.Duration > fs.max {
+		fs.max = stats.Duration
+	}
+	fs.seriesN += int64(stats.SeriesN)
+	fs.pointN += int64(stats.PointN)
+	fs.rowN += int64(stats.RowN)
+	fs.latency.Add(float64(stats.Duration))
+}
+
+// evict removes the least recently seen fingerprint.
+func (s *QueryStats) evict() {
+	var oldest queryStatsKey
+	var oldestSeen uint64
+	for key, fs := range s.stats {
+		if oldestSeen == 0 || fs.lastSeen < oldestSeen {
+			oldest, oldestSeen = key, fs.lastSeen
+		}
+	}
+	delete(s.stats, oldest)
+}
+
+// Rows returns the statistics of each fingerprint sorted by descending total
+// duration. A limit of zero returns every fingerprint.
+func (s *QueryStats) Rows(limit int) models.Rows {
+	type entry struct {
+		key queryStatsKey
+		*fingerprintStats
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]entry, 0, len(s.stats))
+	for key, fs := range s.stats {
+		entries = append(entries, entry{key: key, fingerprintStats: fs})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].total != entries[j].total {
+			return entries[i].total > entries[j].total
+		}
+		if entries[i].key.database != entries[j].key.database {
+			return entries[i].key.database < entries[j].key.database
+		}
+		return entries[i].key.fingerprint < entries[j].key.fingerprint
+	})
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	values := make([][]interface{}, 0, len(entries))
+	for _, e := range entries {
+		quantile := func(q float64) string {
+			return roundDuration(time.Duration(e.latency.Quantile(q))).String()
+		}
+		values = append(values, []interface{}{
+			e.key.fingerprint,
+			e.key.database,
+			e.count,
+			roundDuration(e.total).String(),
+			roundDuration(e.total / time.Duration(e.count)).String(),
+			quantile(0.5),
+			quantile(0.9),
+			quantile(0.99),
+			roundDuration(e.max).String(),
+			e.seriesN,
+			e.pointN,
+			e.rowN,
+		})
+	}
+
+	return models.Rows{{
+		Columns: []string{"fingerprint", "database", "count", "total", "mean", "p50", "p90", "p99", "max", "series_scanned", "points_scanned", "rows_returned"},
+		Values:  values,
+	}}
+}
+
+// roundDuration truncates a duration to the precision of its largest unit.
+func roundDuration(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		d = d - (d % time.Second)
+	case d >= time.Millisecond:
+		d = d - (d % time.Millisecond)
+	case d >= time.Microsecond:
+		d = d - (d % time.Microsecond)
+	}
+	return d
+}
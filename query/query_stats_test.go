@@ -0,0 +1,107 @@
+package query_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxql"
+)
+
+func TestFingerprint(t *testing.T) {
+	for _, tt := range []struct {
+		s   string
+		exp string
+	}{
+		{
+			s:   `SELECT mean(value) FROM cpu WHERE host = 'server01' AND time > now() - 1h GROUP BY time(10s) fill(none)`,
+			exp: `SELECT mean(value) FROM cpu WHERE host = ? AND time > now() - ? GROUP BY time(?) fill(none)`,
+		},
+		{
+			s:   `SELECT value * 2 FROM cpu WHERE region =~ /us-.*/ AND value > 10.5 AND ok = true`,
+			exp: `SELECT value * ? FROM cpu WHERE region =~ ? AND value > ? AND ok = ?`,
+		},
+		{
+			s:   `SELECT max(mean) FROM (SELECT mean(value) FROM cpu WHERE host = 'a' GROUP BY time(1m)) WHERE time >= '2000-01-01T00:00:00Z'`,
+			exp: `SELECT max(mean) FROM (SELECT mean(value) FROM cpu WHERE host = ? GROUP BY time(?)) WHERE time >= ?`,
+		},
+		{
+			s:   `SELECT value FROM /cpu.*/ LIMIT 10`,
+			exp: `SELECT value FROM /cpu.*/ LIMIT 10`,
+		},
+		{
+			s:   `SHOW DATABASES`,
+			exp: `SHOW DATABASES`,
+		},
+	} {
+		stmt, err := influxql.ParseStatement(tt.s)
+		if err != nil {
+			t.Fatalf("unable to parse %q: %s", tt.s, err)
+		}
+		if got := query.Fingerprint(stmt); got != tt.exp {
+			t.Errorf("unexpected fingerprint of %q:\n\ngot=%s\n\nexp=%s", tt.s, got, tt.exp)
+		}
+	}
+
+	// The statement must not be modified.
+	s := `SELECT value FROM cpu WHERE host = 'server01'`
+	stmt := influxql.MustParseStatement(s)
+	query.Fingerprint(stmt)
+	if got := stmt.String(); got != s {
+		t.Fatalf("statement modified: %s", got)
+	}
+}
+
+func TestQueryStats(t *testing.T) {
+	s := query.NewQueryStats(2)
+	for _, d := range []time.Duration{10, 20, 30} {
+		s.Add(query.StatementStats{
+			Fingerprint: "SELECT a FROM cpu",
+			Database:    "db0",
+			Duration:    d * time.Millisecond,
+			SeriesN:     2,
+			PointN:      10,
+			RowN:        5,
+		})
+	}
+	s.Add(query.StatementStats{Fingerprint: "SELECT b FROM cpu", Database: "db0", Duration: 100 * time.Millisecond})
+
+	rows := s.Rows(0)
+	if got, exp := rows[0].Columns, []string{"fingerprint", "database", "count", "total", "mean", "p50", "p90", "p99", "max", "series_scanned", "points_scanned", "rows_returned"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected columns: %v", got)
+	}
+
+	// Percentiles are estimated within 1% and truncated to the millisecond.
+	for i, exp := range [][]interface{}{
+		{"SELECT b FROM cpu", "db0", int64(1), "100ms", "100ms", "100ms", "100ms", "100ms", "100ms", int64(0), int64(0), int64(0)},
+		{"SELECT a FROM cpu", "db0", int64(3), "60ms", "20ms", "19ms", "19ms", "19ms", "30ms", int64(6), int64(30), int64(15)},
+	} {
+		if got := rows[0].Values[i]; !reflect.DeepEqual(got, exp) {
+			t.Fatalf("%d. unexpected values:\n\ngot=%v\n\nexp=%v", i, got, exp)
+		}
+	}
+
+	// The limit returns the fingerprints with the highest total duration.
+	if got := s.Rows(1); len(got[0].Values) != 1 || got[0].Values[0][0] != "SELECT b FROM cpu" {
+		t.Fatalf("unexpected rows: %#v", got)
+	}
+
+	// A new fingerprint replaces the least recently seen one.
+	s.Add(query.StatementStats{Fingerprint: "SELECT a FROM cpu", Database: "db0", Duration: 10 * time.Millisecond})
+	s.Add(query.StatementStats{Fingerprint: "SELECT c FROM cpu", Database: "db0", Duration: time.Millisecond})
+	rows = s.Rows(0)
+	if len(rows[0].Values) != 2 {
+		t.Fatalf("unexpected number of fingerprints: %d", len(rows[0].Values))
+	} else if got := []interface{}{rows[0].Values[0][0], rows[0].Values[1][0]}; !reflect.DeepEqual(got, []interface{}{"SELECT a FROM cpu", "SELECT c FROM cpu"}) {
+		t.Fatalf("unexpected fingerprints: %v", got)
+	}
+
+	// A fingerprint seen again is kept, even if it has the lowest total.
+	s.Add(query.StatementStats{Fingerprint: "SELECT c FROM cpu", Database: "db0", Duration: time.Millisecond})
+	s.Add(query.StatementStats{Fingerprint: "SELECT d FROM cpu", Database: "db0", Duration: time.Second})
+	rows = s.Rows(0)
+	if got := []interface{}{rows[0].Values[0][0], rows[0].Values[1][0]}; !reflect.DeepEqual(got, []interface{}{"SELECT d FROM cpu", "SELECT c FROM cpu"}) {
+		t.Fatalf("unexpected fingerprints: %v", got)
+	}
+}
@@ -0,0 +1,330 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+// Kinds of quotas.
+const (
+	// UserQuota limits the queries of a user.
+	UserQuota = "user"
+
+	// DatabaseQuota limits the queries against a database.
+	DatabaseQuota = "database"
+)
+
+// QuotaWindow is the period over which queries, points scanned and bytes
+// returned are counted.
+const QuotaWindow = time.Minute
+
+// quotaUsageIdleTime is how long the usage of a user or of a database is kept
+// after its last query. The usage of a window which ended is reset anyway, so
+// dropping it only drops the cumulative statistics.
+const quotaUsageIdleTime = 10 * time.Minute
+
+// Statistics for the quotas.
+const (
+	statQuotaConcurrentQueries = "concurrentQueries" // Number of queries currently running.
+	statQuotaQueriesExecuted   = "queriesExecuted"   // Number of queries admitted.
+	statQuotaQueriesRejected   = "queriesRejected"   // Number of queries rejected because a quota was exceeded.
+	statQuotaPointsScanned     = "pointsScanned"     // Number of points scanned by queries.
+	statQuotaBytesReturned     = "bytesReturned"     // Number of bytes returned to clients.
+)
+
+// QuotaLimits holds the limits of a quota. A zero limit is unlimited.
+type QuotaLimits struct {
+	// Maximum number of queries running at once.
+	MaxConcurrentQueries int
+
+	// Maximum number of queries started per window.
+	MaxQueriesPerMinute int
+
+	// Maximum number of points scanned per window.
+	MaxPointsScanned int64
+
+	// Maximum number of bytes returned per window.
+	MaxBytesReturned int64
+}
+
+// QuotaOverrides looks up limits replacing the default limits of a user or a
+// database.
+type QuotaOverrides interface {
+	QueryQuota(kind, name string) (QuotaLimits, bool)
+}
+
+// ErrQuotaExceeded is returned when a query is rejected because a user or a
+// database exceeded one of its quotas.
+type ErrQuotaExceeded struct {
+	Kind  string
+	Name  string
+	Limit string
+
+	// How long until the query may succeed.
+	RetryAfter time.Duration
+}
+
+// Error returns the string representation of the error.
+func (e *ErrQuotaExceeded) Error() string {
+	return fmt.Sprintf("%s quota exceeded for %s %q, retry after %s", e.Limit, e.Kind, e.Name, e.RetryAfter)
+}
+
+// Quotas tracks the usage of users and databases and rejects queries which
+// exceed their limits. Usage is counted in fixed windows of QuotaWindow.
+// Queries are admitted as long as none of their quotas is exhausted. Points
+// scanned are charged while a query runs, and the query is killed as soon as
+// it exceeds a quota of points scanned. Bytes returned are counted once they
+// are written to the client.
+type Quotas struct {
+	// Default limits of users and databases.
+	UserLimits     QuotaLimits
+	DatabaseLimits QuotaLimits
+
+	// Overrides of the default limits. Optional.
+	Overrides QuotaOverrides
+
+	mu        sync.Mutex
+	usage     map[quotaKey]*quotaUsage
+	lastEvict time.Time
+
+	// now returns the current time. Used for testing.
+	now func() time.Time
+}
+
+type quotaKey struct {
+	kind, name string
+}
+
+type quotaUsage struct {
+	concurrentQueries int
+
+	// Usage in the current window.
+	window        time.Time
+	queries       int
+	pointsScanned int64
+	bytesReturned int64
+
+	// Cumulative usage reported in statistics.
+	queriesExecuted    int64
+	queriesRejected    int64
+	totalPointsScanned int64
+	totalBytesReturned int64
+}
+
+// NewQuotas returns a new instance of Quotas.
+func NewQuotas() *Quotas {
+	return &Quotas{
+		usage: make(map[quotaKey]*quotaUsage),
+		now:   time.Now,
+	}
+}
+
+// keys returns the quotas applying to a query run by user against database.
+// Anonymous queries have no user quota and queries without a default database
+// have no database quota.
+func (q *Quotas) keys(user, database string) []quotaKey {
+	keys := make([]quotaKey, 0, 2)
+	if user != "" {
+		keys = append(keys, quotaKey{UserQuota, user})
+	}
+	if database != "" {
+		keys = append(keys, quotaKey{DatabaseQuota, database})
+	}
+	return keys
+}
+
+func (q *Quotas) limits(key quotaKey) QuotaLimits {
+	if q.Overrides != nil {
+		if limits, ok := q.Overrides.QueryQuota(key.kind, key.name); ok {
+			return limits
+		}
+	}
+	if key.kind == UserQuota {
+		return q.UserLimits
+	}
+	return q.DatabaseLimits
+}
+
+// usageOf returns the usage of a quota, starting a new window if the current
+// one has ended. q.mu must be held.
+func (q *Quotas) usageOf(key quotaKey, now time.Time) *quotaUsage {
+	u := q.usage[key]
+	if u == nil {
+		u = &quotaUsage{}
+		q.usage[key] = u
+	}
+	if window := now.Truncate(QuotaWindow); !window.Equal(u.window) {
+		u.window = window
+		u.queries, u.pointsScanned, u.bytesReturned = 0, 0, 0
+	}
+	return u
+}
+
+// check returns an error if a new query would exceed the quota. q.mu must be
+// held.
+func (q *Quotas) check(key quotaKey, u *quotaUsage, now time.Time) error {
+	limits := q.limits(key)
+	err := &ErrQuotaExceeded{Kind: key.kind, Name: key.name}
+	switch {
+	case limits.MaxConcurrentQueries > 0 && u.concurrentQueries >= limits.MaxConcurrentQueries:
+		err.Limit, err.RetryAfter = "max-concurrent-queries", time.Second
+	case limits.MaxQueriesPerMinute > 0 && u.queries >= limits.MaxQueriesPerMinute:
+		err.Limit = "max-queries-per-minute"
+	case limits.MaxPointsScanned > 0 && u.pointsScanned >= limits.MaxPointsScanned:
+		err.Limit = "max-points-scanned"
+	case limits.MaxBytesReturned > 0 && u.bytesReturned >= limits.MaxBytesReturned:
+		err.Limit = "max-bytes-returned"
+	default:
+		return nil
+	}
+	if err.RetryAfter == 0 {
+		err.RetryAfter = u.window.Add(QuotaWindow).Sub(now)
+	}
+	return err
+}
+
+// checkAll returns an error if a new query would exceed one of the quotas of
+// keys, counting the rejected query. q.mu must be held.
+func (q *Quotas) checkAll(keys []quotaKey, now time.Time) error {
+	for _, key := range keys {
+		if err := q.check(key, q.usageOf(key, now), now); err != nil {
+			for _, key := range keys {
+				q.usageOf(key, now).queriesRejected++
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// acquire admits a query run by user against database, or returns an
+// *ErrQuotaExceeded error if one of the quotas is exceeded. The quotas are
+// checked and reserved at once, so concurrent queries can't be admitted past
+// a limit. Admitted queries must be released once they finish.
+func (q *Quotas) acquire(user, database string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	q.evict(now)
+
+	keys := q.keys(user, database)
+	if err := q.checkAll(keys, now); err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		u := q.usage[key]
+		u.concurrentQueries++
+		u.queries++
+		u.queriesExecuted++
+	}
+	return nil
+}
+
+// evict drops the usage of the users and databases which haven't run a query
+// for quotaUsageIdleTime. The usage is swept at most once every
+// quotaUsageIdleTime. q.mu must be held.
+func (q *Quotas) evict(now time.Time) {
+	if now.Sub(q.lastEvict) < quotaUsageIdleTime {
+		return
+	}
+	q.lastEvict = now
+
+	for key, u := range q.usage {
+		if u.concurrentQueries == 0 && now.Sub(u.window) >= quotaUsageIdleTime {
+			delete(q.usage, key)
+		}
+	}
+}
+
+// release marks a query admitted by acquire as finished.
+func (q *Quotas) release(user, database string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, key := range q.keys(user, database) {
+		if u := q.usage[key]; u != nil && u.concurrentQueries > 0 {
+			u.concurrentQueries--
+		}
+	}
+}
+
+// chargePoints counts n points scanned by a running query of user against
+// database. It returns an *ErrQuotaExceeded error if the points exceed a
+// quota of points scanned.
+func (q *Quotas) chargePoints(user, database string, n int64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	var err error
+	for _, key := range q.keys(user, database) {
+		u := q.usageOf(key, now)
+		u.pointsScanned += n
+		u.totalPointsScanned += n
+
+		if limit := q.limits(key).MaxPointsScanned; err == nil && limit > 0 && u.pointsScanned > limit {
+			err = &ErrQuotaExceeded{
+				Kind:       key.kind,
+				Name:       key.name,
+				Limit:      "max-points-scanned",
+				RetryAfter: u.window.Add(QuotaWindow).Sub(now),
+			}
+		}
+	}
+	return err
+}
+
+// AddBytesReturned counts bytes returned to a client for a query run by user
+// against database.
+func (q *Quotas) AddBytesReturned(user, database string, n int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	for _, key := range q.keys(user, database) {
+		u := q.usageOf(key, now)
+		u.bytesReturned += n
+		u.totalBytesReturned += n
+	}
+}
+
+// Statistics returns the usage of each user and database which ran queries
+// recently.
+func (q *Quotas) Statistics(tags map[string]string) []models.Statistic {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	keys := make([]quotaKey, 0, len(q.usage))
+	for key := range q.usage {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].kind != keys[j].kind {
+			return keys[i].kind < keys[j].kind
+		}
+		return keys[i].name < keys[j].name
+	})
+
+	statistics := make([]models.Statistic, 0, len(keys))
+	for _, key := range keys {
+		u := q.usage[key]
+		statistics = append(statistics, models.Statistic{
+			Name: "queryQuota",
+			Tags: models.StatisticTags{key.kind: key.name}.Merge(tags),
+			Values: map[string]interface{}{
+				statQuotaConcurrentQueries: u.concurrentQueries,
+				statQuotaQueriesExecuted:   u.queriesExecuted,
+				statQuotaQueriesRejected:   u.queriesRejected,
+				statQuotaPointsScanned:     u.totalPointsScanned,
+				statQuotaBytesReturned:     u.totalBytesReturned,
+			},
+		})
+	}
+	return statistics
+}
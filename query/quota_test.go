@@ -0,0 +1,191 @@
+package query
+
+import (
+	"testing"
+	"time"
+)
+
+// quotaOverrides is a static set of quota overrides.
+type quotaOverrides map[quotaKey]QuotaLimits
+
+func (o quotaOverrides) QueryQuota(kind, name string) (QuotaLimits, bool) {
+	limits, ok := o[quotaKey{kind, name}]
+	return limits, ok
+}
+
+func TestQuotas_ConcurrentQueries(t *testing.T) {
+	q := NewQuotas()
+	q.UserLimits.MaxConcurrentQueries = 1
+	q.DatabaseLimits.MaxConcurrentQueries = 2
+
+	if err := q.acquire("bob", "db0"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The user quota is exhausted.
+	err := q.acquire("bob", "db1")
+	if e, ok := err.(*ErrQuotaExceeded); !ok {
+		t.Fatalf("unexpected error: %v", err)
+	} else if e.Kind != UserQuota || e.Name != "bob" || e.Limit != "max-concurrent-queries" || e.RetryAfter != time.Second {
+		t.Fatalf("unexpected error: %#v", e)
+	}
+
+	// Other users have their own quota, but share the database quota.
+	if err := q.acquire("alice", "db0"); err != nil {
+		t.Fatal(err)
+	}
+	err = q.acquire("carol", "db0")
+	if e, ok := err.(*ErrQuotaExceeded); !ok || e.Kind != DatabaseQuota || e.Name != "db0" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	q.release("bob", "db0")
+	if err := q.acquire("bob", "db1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQuotas_Window(t *testing.T) {
+	now := time.Date(2000, 1, 1, 0, 0, 15, 0, time.UTC)
+	q := NewQuotas()
+	q.now = func() time.Time { return now }
+	q.UserLimits.MaxQueriesPerMinute = 2
+	q.DatabaseLimits.MaxPointsScanned = 100
+
+	for i := 0; i < 2; i++ {
+		if err := q.acquire("bob", ""); err != nil {
+			t.Fatal(err)
+		}
+		q.release("bob", "")
+	}
+
+	err := q.acquire("bob", "")
+	if e, ok := err.(*ErrQuotaExceeded); !ok || e.Limit != "max-queries-per-minute" {
+		t.Fatalf("unexpected error: %v", err)
+	} else if e.RetryAfter != 45*time.Second {
+		t.Fatalf("unexpected retry after: %s", e.RetryAfter)
+	}
+
+	// Points scanned are charged while the query runs and fail it once they
+	// exceed the quota.
+	if err := q.acquire("", "db0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.chargePoints("", "db0", 100); err != nil {
+		t.Fatal(err)
+	}
+	err = q.chargePoints("", "db0", 50)
+	if e, ok := err.(*ErrQuotaExceeded); !ok || e.Limit != "max-points-scanned" || e.RetryAfter != 45*time.Second {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	q.release("", "db0")
+	err = q.acquire("", "db0")
+	if e, ok := err.(*ErrQuotaExceeded); !ok || e.Limit != "max-points-scanned" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Usage is reset by the next window.
+	now = now.Add(time.Minute)
+	if err := q.acquire("bob", "db0"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQuotas_Overrides(t *testing.T) {
+	q := NewQuotas()
+	q.UserLimits.MaxBytesReturned = 10
+	q.Overrides = quotaOverrides{
+		{UserQuota, "admin"}: {},
+	}
+
+	q.AddBytesReturned("bob", "", 10)
+	q.AddBytesReturned("admin", "", 10)
+
+	if err, ok := q.acquire("bob", "").(*ErrQuotaExceeded); !ok || err.Limit != "max-bytes-returned" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := q.acquire("admin", ""); err != nil {
+		t.Fatalf("unexpected error for unlimited override: %v", err)
+	}
+}
+
+func TestQuotas_Statistics(t *testing.T) {
+	q := NewQuotas()
+	q.UserLimits.MaxConcurrentQueries = 1
+
+	if err := q.acquire("bob", "db0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.acquire("bob", "db0"); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := q.chargePoints("bob", "db0", 5); err != nil {
+		t.Fatal(err)
+	}
+	q.AddBytesReturned("bob", "db0", 7)
+
+	stats := q.Statistics(map[string]string{"host": "server0"})
+	if len(stats) != 2 {
+		t.Fatalf("unexpected number of statistics: %d", len(stats))
+	}
+
+	stat := stats[1]
+	if stat.Name != "queryQuota" || stat.Tags["user"] != "bob" || stat.Tags["host"] != "server0" {
+		t.Fatalf("unexpected statistic: %#v", stat)
+	}
+	for k, exp := range map[string]interface{}{
+		statQuotaConcurrentQueries: 1,
+		statQuotaQueriesExecuted:   int64(1),
+		statQuotaQueriesRejected:   int64(1),
+		statQuotaPointsScanned:     int64(5),
+		statQuotaBytesReturned:     int64(7),
+	} {
+		if got := stat.Values[k]; got != exp {
+			t.Errorf("unexpected %s: got %v, exp %v", k, got, exp)
+		}
+	}
+	if stats[0].Tags["database"] != "db0" {
+		t.Fatalf("unexpected statistic: %#v", stats[0])
+	}
+}
+
+func TestQuotas_Evict(t *testing.T) {
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	q := NewQuotas()
+	q.now = func() time.Time { return now }
+
+	// bob keeps running a query while alice finishes hers.
+	if err := q.acquire("bob", "db0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.acquire("alice", "db1"); err != nil {
+		t.Fatal(err)
+	}
+	q.release("alice", "db1")
+	if n := len(q.usage); n != 4 {
+		t.Fatalf("unexpected number of usages: %d", n)
+	}
+
+	// Usage queried within the idle time is kept.
+	now = now.Add(quotaUsageIdleTime - time.Minute)
+	if err := q.acquire("", "db2"); err != nil {
+		t.Fatal(err)
+	}
+	q.release("", "db2")
+
+	// Idle usage is dropped, but not the usage of running queries.
+	now = now.Add(time.Minute)
+	if err := q.acquire("carol", ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []quotaKey{{UserQuota, "alice"}, {DatabaseQuota, "db1"}} {
+		if _, ok := q.usage[key]; ok {
+			t.Fatalf("usage of %s %q not evicted", key.kind, key.name)
+		}
+	}
+	for _, key := range []quotaKey{{UserQuota, "bob"}, {DatabaseQuota, "db0"}, {DatabaseQuota, "db2"}, {UserQuota, "carol"}} {
+		if _, ok := q.usage[key]; !ok {
+			t.Fatalf("usage of %s %q evicted", key.kind, key.name)
+		}
+	}
+}
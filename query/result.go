@@ -91,6 +91,10 @@ type Result struct {
 	Messages    []*Message
 	Partial     bool
 	Err         error
+
+	// Profile is the span tree of the statement when it was executed with
+	// profiling enabled. It is sent with the last result of the statement.
+	Profile *Profile
 }
 
 // MarshalJSON encodes the result into JSON.
@@ -102,6 +106,7 @@ func (r *Result) MarshalJSON() ([]byte, error) {
 		Messages    []*Message    `json:"messages,omitempty"`
 		Partial     bool          `json:"partial,omitempty"`
 		Err         string        `json:"error,omitempty"`
+		Profile     *Profile      `json:"profile,omitempty"`
 	}
 
 	// Copy fields to output struct.
@@ -109,6 +114,7 @@ func (r *Result) MarshalJSON() ([]byte, error) {
 	o.Series = r.Series
 	o.Messages = r.Messages
 	o.Partial = r.Partial
+	o.Profile = r.Profile
 	if r.Err != nil {
 		o.Err = r.Err.Error()
 	}
@@ -124,6 +130,7 @@ func (r *Result) UnmarshalJSON(b []byte) error {
 		Messages    []*Message    `json:"messages,omitempty"`
 		Partial     bool          `json:"partial,omitempty"`
 		Err         string        `json:"error,omitempty"`
+		Profile     *Profile      `json:"profile,omitempty"`
 	}
 
 	err := json.Unmarshal(b, &o)
@@ -134,6 +141,7 @@ func (r *Result) UnmarshalJSON(b []byte) error {
 	r.Series = o.Series
 	r.Messages = o.Messages
 	r.Partial = o.Partial
+	r.Profile = o.Profile
 	if o.Err != "" {
 		r.Err = errors.New(o.Err)
 	}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/influxdata/influxdb/pkg/estimator/hll"
 	"github.com/influxdata/influxdb/pkg/tracing"
 	"github.com/influxdata/influxdb/query/internal/gota"
 	"github.com/influxdata/influxql"
@@ -37,6 +38,11 @@ type SelectOptions struct {
 
 	// Maximum number of buckets for a statement.
 	MaxBucketsN int
+
+	// How far outside of the time range to look for the nearest points of
+	// each series when filling windows with fill(previous), fill(linear) or
+	// fill(interpolate_time). If zero, the edges are not filled.
+	MaxFillLookback time.Duration
 }
 
 // ShardMapper retrieves and maps shards into an IteratorCreator that can later be
@@ -188,6 +194,18 @@ func (b *exprIteratorBuilder) buildVarRefIterator(ctx context.Context, expr *inf
 				} else if input != nil {
 					inputs = append(inputs, input)
 				}
+			case *influxql.Join:
+				join := joinBuilder{
+					ic:   b.ic,
+					join: source,
+				}
+
+				input, err := join.buildVarRefIterator(ctx, expr, b.opt)
+				if err != nil {
+					return err
+				} else if input != nil {
+					inputs = append(inputs, input)
+				}
 			}
 		}
 		return nil
@@ -226,6 +244,20 @@ func (b *exprIteratorBuilder) buildCallIterator(ctx context.Context, expr *influ
 			return nil, err
 		}
 		return NewIntervalIterator(input, opt), nil
+	case "histogram":
+		input, err := b.sketchIterator(ctx, expr, opt)
+		if err != nil {
+			return nil, err
+		}
+		bounds := make([]float64, 0, len(expr.Args)-1)
+		for _, arg := range expr.Args[1:] {
+			bounds = append(bounds, numberLiteral(arg))
+		}
+		input, err = newHistogramIterator(input, opt, bounds)
+		if err != nil {
+			return nil, err
+		}
+		return NewIntervalIterator(input, opt), nil
 	case "sample":
 		opt.Ordered = true
 		input, err := buildExprIterator(ctx, expr.Args[0], b.ic, b.sources, opt, b.selector, false)
@@ -255,11 +287,7 @@ func (b *exprIteratorBuilder) buildCallIterator(ctx context.Context, expr *influ
 		return newHoltWintersIterator(input, opt, int(h.Val), int(m.Val), includeFitData, interval)
 	case "derivative", "non_negative_derivative", "difference", "non_negative_difference", "moving_average", "exponential_moving_average", "double_exponential_moving_average", "triple_exponential_moving_average", "relative_strength_index", "triple_exponential_derivative", "kaufmans_efficiency_ratio", "kaufmans_adaptive_moving_average", "chande_momentum_oscillator", "elapsed":
 		if !opt.Interval.IsZero() {
-			if opt.Ascending {
-				opt.StartTime -= int64(opt.Interval.Duration)
-			} else {
-				opt.EndTime += int64(opt.Interval.Duration)
-			}
+			opt.extendWindows(1)
 		}
 		opt.Ordered = true
 
@@ -282,21 +310,13 @@ func (b *exprIteratorBuilder) buildCallIterator(ctx context.Context, expr *influ
 		case "moving_average":
 			n := expr.Args[1].(*influxql.IntegerLiteral)
 			if n.Val > 1 && !opt.Interval.IsZero() {
-				if opt.Ascending {
-					opt.StartTime -= int64(opt.Interval.Duration) * (n.Val - 1)
-				} else {
-					opt.EndTime += int64(opt.Interval.Duration) * (n.Val - 1)
-				}
+				opt.extendWindows(n.Val - 1)
 			}
 			return newMovingAverageIterator(input, int(n.Val), opt)
 		case "exponential_moving_average", "double_exponential_moving_average", "triple_exponential_moving_average", "relative_strength_index", "triple_exponential_derivative":
 			n := expr.Args[1].(*influxql.IntegerLiteral)
 			if n.Val > 1 && !opt.Interval.IsZero() {
-				if opt.Ascending {
-					opt.StartTime -= int64(opt.Interval.Duration) * (n.Val - 1)
-				} else {
-					opt.EndTime += int64(opt.Interval.Duration) * (n.Val - 1)
-				}
+				opt.extendWindows(n.Val - 1)
 			}
 
 			nHold := -1
@@ -326,11 +346,7 @@ func (b *exprIteratorBuilder) buildCallIterator(ctx context.Context, expr *influ
 		case "kaufmans_efficiency_ratio", "kaufmans_adaptive_moving_average":
 			n := expr.Args[1].(*influxql.IntegerLiteral)
 			if n.Val > 1 && !opt.Interval.IsZero() {
-				if opt.Ascending {
-					opt.StartTime -= int64(opt.Interval.Duration) * (n.Val - 1)
-				} else {
-					opt.EndTime += int64(opt.Interval.Duration) * (n.Val - 1)
-				}
+				opt.extendWindows(n.Val - 1)
 			}
 
 			nHold := -1
@@ -347,11 +363,7 @@ func (b *exprIteratorBuilder) buildCallIterator(ctx context.Context, expr *influ
 		case "chande_momentum_oscillator":
 			n := expr.Args[1].(*influxql.IntegerLiteral)
 			if n.Val > 1 && !opt.Interval.IsZero() {
-				if opt.Ascending {
-					opt.StartTime -= int64(opt.Interval.Duration) * (n.Val - 1)
-				} else {
-					opt.EndTime += int64(opt.Interval.Duration) * (n.Val - 1)
-				}
+				opt.extendWindows(n.Val - 1)
 			}
 
 			nHold := -1
@@ -509,8 +521,33 @@ func (b *exprIteratorBuilder) buildCallIterator(ctx context.Context, expr *influ
 				}
 			}
 			fallthrough
-		case "min", "max", "sum", "first", "last", "mean":
+		case "min", "max", "sum", "first", "last", "mean", "sketch":
 			return b.callIterator(ctx, expr, opt)
+		case "count_distinct_approx":
+			precision := int64(hll.DefaultPrecision)
+			if len(expr.Args) == 2 {
+				precision = expr.Args[1].(*influxql.IntegerLiteral).Val
+			}
+			call := &influxql.Call{
+				Name: "hll",
+				Args: []influxql.Expr{
+					expr.Args[0],
+					&influxql.IntegerLiteral{Val: precision},
+				},
+			}
+			callOpt := opt
+			callOpt.Expr = call
+			input, err := b.callIterator(ctx, call, callOpt)
+			if err != nil {
+				return nil, err
+			}
+			return newCountDistinctApproxIterator(input, opt)
+		case "quantile_approx":
+			input, err := b.sketchIterator(ctx, expr, opt)
+			if err != nil {
+				return nil, err
+			}
+			return newQuantileApproxIterator(input, opt, numberLiteral(expr.Args[1]))
 		case "median":
 			opt.Ordered = true
 			input, err := buildExprIterator(ctx, expr.Args[0].(*influxql.VarRef), b.ic, b.sources, opt, false, false)
@@ -563,7 +600,12 @@ func (b *exprIteratorBuilder) buildCallIterator(ctx context.Context, expr *influ
 	if !b.selector || !opt.Interval.IsZero() {
 		itr = NewIntervalIterator(itr, opt)
 		if !opt.Interval.IsZero() && opt.Fill != influxql.NoFill {
-			itr = NewFillIterator(itr, expr, opt)
+			edges, err := b.fillEdges(ctx, expr, opt)
+			if err != nil {
+				itr.Close()
+				return nil, err
+			}
+			itr = newFillIterator(itr, expr, opt, edges)
 		}
 	}
 	if opt.InterruptCh != nil {
@@ -583,7 +625,7 @@ func (b *exprIteratorBuilder) callIterator(ctx context.Context, expr *influxql.C
 					return err
 				}
 				inputs = append(inputs, input)
-			case *influxql.SubQuery:
+			case *influxql.SubQuery, *influxql.Join:
 				// Identify the name of the field we are using.
 				arg0 := expr.Args[0].(*influxql.VarRef)
 
@@ -618,6 +660,29 @@ func (b *exprIteratorBuilder) callIterator(ctx context.Context, expr *influxql.C
 	return itr, nil
 }
 
+// sketchIterator returns an iterator of merged sketches for the first argument
+// of expr. The sketch() call is pushed down to the IteratorCreator so each
+// shard only returns its partial sketch for every interval.
+func (b *exprIteratorBuilder) sketchIterator(ctx context.Context, expr *influxql.Call, opt IteratorOptions) (Iterator, error) {
+	call := &influxql.Call{
+		Name: "sketch",
+		Args: expr.Args[:1],
+	}
+	opt.Expr = call
+	return b.callIterator(ctx, call, opt)
+}
+
+// numberLiteral returns the value of an integer or number literal.
+func numberLiteral(expr influxql.Expr) float64 {
+	switch expr := expr.(type) {
+	case *influxql.NumberLiteral:
+		return expr.Val
+	case *influxql.IntegerLiteral:
+		return float64(expr.Val)
+	}
+	return 0
+}
+
 func buildCursor(ctx context.Context, stmt *influxql.SelectStatement, ic IteratorCreator, opt IteratorOptions) (Cursor, error) {
 	span := tracing.SpanFromContext(ctx)
 	if span != nil {
@@ -788,6 +853,18 @@ func buildAuxIterator(ctx context.Context, ic IteratorCreator, sources influxql.
 					stmt: source.Statement,
 				}
 
+				input, err := b.buildAuxIterator(ctx, opt)
+				if err != nil {
+					return err
+				} else if input != nil {
+					inputs = append(inputs, input)
+				}
+			case *influxql.Join:
+				b := joinBuilder{
+					ic:   ic,
+					join: source,
+				}
+
 				input, err := b.buildAuxIterator(ctx, opt)
 				if err != nil {
 					return err
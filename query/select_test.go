@@ -10,6 +10,7 @@ import (
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/influxdb/pkg/ddsketch"
 	"github.com/influxdata/influxdb/query"
 	"github.com/influxdata/influxql"
 )
@@ -1336,6 +1337,24 @@ func TestSelect(t *testing.T) {
 				{Time: 50 * Second, Series: query.Series{Name: "cpu", Tags: ParseTags("host=A")}, Values: []interface{}{nil}},
 			},
 		},
+		{
+			name: "Fill_Linear_Float_Calendar",
+			q:    `SELECT mean(value) FROM cpu WHERE time >= '2000-01-01T00:00:00Z' AND time < '2000-05-01T00:00:00Z' GROUP BY host, time(1mo) fill(linear)`,
+			typ:  influxql.Float,
+			expr: `mean(value::float)`,
+			itrs: []query.Iterator{
+				&FloatIterator{Points: []query.FloatPoint{
+					{Name: "cpu", Tags: ParseTags("host=A"), Time: mustParseTime("2000-01-15T00:00:00Z").UnixNano(), Value: 0},
+					{Name: "cpu", Tags: ParseTags("host=A"), Time: mustParseTime("2000-03-10T00:00:00Z").UnixNano(), Value: 60},
+				}},
+			},
+			rows: []query.Row{
+				{Time: mustParseTime("2000-01-01T00:00:00Z").UnixNano(), Series: query.Series{Name: "cpu", Tags: ParseTags("host=A")}, Values: []interface{}{float64(0)}},
+				{Time: mustParseTime("2000-02-01T00:00:00Z").UnixNano(), Series: query.Series{Name: "cpu", Tags: ParseTags("host=A")}, Values: []interface{}{float64(31)}},
+				{Time: mustParseTime("2000-03-01T00:00:00Z").UnixNano(), Series: query.Series{Name: "cpu", Tags: ParseTags("host=A")}, Values: []interface{}{float64(60)}},
+				{Time: mustParseTime("2000-04-01T00:00:00Z").UnixNano(), Series: query.Series{Name: "cpu", Tags: ParseTags("host=A")}, Values: []interface{}{nil}},
+			},
+		},
 		{
 			name: "Fill_Linear_Float_Many",
 			q:    `SELECT mean(value) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:01:00Z' GROUP BY host, time(10s) fill(linear)`,
@@ -1813,6 +1832,174 @@ func TestSelect(t *testing.T) {
 				{Time: 50 * Second, Series: query.Series{Name: "cpu", Tags: ParseTags("host=B")}, Values: []interface{}{uint64(9)}},
 			},
 		},
+		{
+			name: "QuantileApprox_Float",
+			q:    `SELECT quantile_approx(value, 0.9) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-02T00:00:00Z' GROUP BY time(10s), host fill(none)`,
+			typ:  influxql.Float,
+			expr: `sketch(value::float)`,
+			itrs: []query.Iterator{
+				&FloatIterator{Points: []query.FloatPoint{
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 0 * Second, Value: 20},
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 11 * Second, Value: 3},
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 31 * Second, Value: 100},
+				}},
+				&FloatIterator{Points: []query.FloatPoint{
+					{Name: "cpu", Tags: ParseTags("region=west,host=B"), Time: 5 * Second, Value: 10},
+					{Name: "cpu", Tags: ParseTags("region=west,host=B"), Time: 50 * Second, Value: 10},
+					{Name: "cpu", Tags: ParseTags("region=west,host=B"), Time: 51 * Second, Value: 9},
+					{Name: "cpu", Tags: ParseTags("region=west,host=B"), Time: 52 * Second, Value: 8},
+					{Name: "cpu", Tags: ParseTags("region=west,host=B"), Time: 53 * Second, Value: 7},
+					{Name: "cpu", Tags: ParseTags("region=west,host=B"), Time: 54 * Second, Value: 6},
+					{Name: "cpu", Tags: ParseTags("region=west,host=B"), Time: 55 * Second, Value: 5},
+					{Name: "cpu", Tags: ParseTags("region=west,host=B"), Time: 56 * Second, Value: 4},
+					{Name: "cpu", Tags: ParseTags("region=west,host=B"), Time: 57 * Second, Value: 3},
+					{Name: "cpu", Tags: ParseTags("region=west,host=B"), Time: 58 * Second, Value: 2},
+					{Name: "cpu", Tags: ParseTags("region=west,host=B"), Time: 59 * Second, Value: 1},
+				}},
+				&FloatIterator{Points: []query.FloatPoint{
+					{Name: "cpu", Tags: ParseTags("region=east,host=A"), Time: 9 * Second, Value: 19},
+					{Name: "cpu", Tags: ParseTags("region=east,host=A"), Time: 10 * Second, Value: 2},
+				}},
+			},
+			rows: []query.Row{
+				{Time: 0 * Second, Series: query.Series{Name: "cpu", Tags: ParseTags("host=A")}, Values: []interface{}{QuantileApprox(0.9, 20, 19)}},
+				{Time: 10 * Second, Series: query.Series{Name: "cpu", Tags: ParseTags("host=A")}, Values: []interface{}{QuantileApprox(0.9, 3, 2)}},
+				{Time: 30 * Second, Series: query.Series{Name: "cpu", Tags: ParseTags("host=A")}, Values: []interface{}{float64(100)}},
+				{Time: 0 * Second, Series: query.Series{Name: "cpu", Tags: ParseTags("host=B")}, Values: []interface{}{float64(10)}},
+				{Time: 50 * Second, Series: query.Series{Name: "cpu", Tags: ParseTags("host=B")}, Values: []interface{}{QuantileApprox(0.9, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1)}},
+			},
+		},
+		{
+			name: "QuantileApprox_Integer",
+			q:    `SELECT quantile_approx(value, 0.5) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-02T00:00:00Z' GROUP BY time(10s), host fill(none)`,
+			typ:  influxql.Integer,
+			expr: `sketch(value::integer)`,
+			itrs: []query.Iterator{
+				&IntegerIterator{Points: []query.IntegerPoint{
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 0 * Second, Value: 200},
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 1 * Second, Value: -40},
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 11 * Second, Value: 3},
+				}},
+				&IntegerIterator{Points: []query.IntegerPoint{
+					{Name: "cpu", Tags: ParseTags("region=east,host=A"), Time: 9 * Second, Value: 19},
+					{Name: "cpu", Tags: ParseTags("region=east,host=A"), Time: 10 * Second, Value: 0},
+				}},
+			},
+			rows: []query.Row{
+				{Time: 0 * Second, Series: query.Series{Name: "cpu", Tags: ParseTags("host=A")}, Values: []interface{}{QuantileApprox(0.5, 200, -40, 19)}},
+				{Time: 10 * Second, Series: query.Series{Name: "cpu", Tags: ParseTags("host=A")}, Values: []interface{}{QuantileApprox(0.5, 3, 0)}},
+			},
+		},
+		{
+			name: "QuantileApprox_Sketch",
+			q:    `SELECT quantile_approx(value, 0.5) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-02T00:00:00Z' GROUP BY time(20s), host fill(none)`,
+			typ:  influxql.String,
+			expr: `sketch(value::string)`,
+			itrs: []query.Iterator{
+				&StringIterator{Points: []query.StringPoint{
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 0 * Second, Value: EncodeSketch(1, 2, 3)},
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 10 * Second, Value: EncodeSketch(4, 5)},
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 20 * Second, Value: "not a sketch"},
+				}},
+				&StringIterator{Points: []query.StringPoint{
+					{Name: "cpu", Tags: ParseTags("region=east,host=A"), Time: 10 * Second, Value: EncodeSketch(100, 200, 300, 400)},
+				}},
+			},
+			rows: []query.Row{
+				{Time: 0 * Second, Series: query.Series{Name: "cpu", Tags: ParseTags("host=A")}, Values: []interface{}{QuantileApprox(0.5, 1, 2, 3, 4, 5, 100, 200, 300, 400)}},
+			},
+		},
+		{
+			name: "Sketch_Float",
+			q:    `SELECT sketch(value) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-02T00:00:00Z' GROUP BY time(10s), host fill(none)`,
+			typ:  influxql.Float,
+			expr: `sketch(value::float)`,
+			itrs: []query.Iterator{
+				&FloatIterator{Points: []query.FloatPoint{
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 0 * Second, Value: 20},
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 11 * Second, Value: 3},
+				}},
+				&FloatIterator{Points: []query.FloatPoint{
+					{Name: "cpu", Tags: ParseTags("region=east,host=A"), Time: 9 * Second, Value: 19},
+				}},
+			},
+			rows: []query.Row{
+				{Time: 0 * Second, Series: query.Series{Name: "cpu", Tags: ParseTags("host=A")}, Values: []interface{}{EncodeSketch(20, 19)}},
+				{Time: 10 * Second, Series: query.Series{Name: "cpu", Tags: ParseTags("host=A")}, Values: []interface{}{EncodeSketch(3)}},
+			},
+		},
+		{
+			name: "Histogram_Float",
+			q:    `SELECT histogram(value, 5, 50) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-02T00:00:00Z' GROUP BY time(10s), host fill(none)`,
+			typ:  influxql.Float,
+			expr: `sketch(value::float)`,
+			itrs: []query.Iterator{
+				&FloatIterator{Points: []query.FloatPoint{
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 0 * Second, Value: 20},
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 1 * Second, Value: 3},
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 11 * Second, Value: 100},
+				}},
+				&FloatIterator{Points: []query.FloatPoint{
+					{Name: "cpu", Tags: ParseTags("region=east,host=A"), Time: 9 * Second, Value: 19},
+					{Name: "cpu", Tags: ParseTags("region=east,host=A"), Time: 12 * Second, Value: 200},
+				}},
+			},
+			rows: []query.Row{
+				{Time: 0 * Second, Series: query.Series{Name: "cpu", Tags: ParseTags("host=A")}, Values: []interface{}{int64(1)}},
+				{Time: 0 * Second, Series: query.Series{Name: "cpu", Tags: ParseTags("host=A")}, Values: []interface{}{int64(2)}},
+				{Time: 0 * Second, Series: query.Series{Name: "cpu", Tags: ParseTags("host=A")}, Values: []interface{}{int64(0)}},
+				{Time: 10 * Second, Series: query.Series{Name: "cpu", Tags: ParseTags("host=A")}, Values: []interface{}{int64(0)}},
+				{Time: 10 * Second, Series: query.Series{Name: "cpu", Tags: ParseTags("host=A")}, Values: []interface{}{int64(0)}},
+				{Time: 10 * Second, Series: query.Series{Name: "cpu", Tags: ParseTags("host=A")}, Values: []interface{}{int64(2)}},
+			},
+		},
+		{
+			name: "CountDistinctApprox_String",
+			q:    `SELECT count_distinct_approx(value) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-02T00:00:00Z' GROUP BY time(10s), host fill(none)`,
+			typ:  influxql.String,
+			expr: `hll(value::string, 16)`,
+			itrs: []query.Iterator{
+				&StringIterator{Points: []query.StringPoint{
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 0 * Second, Value: "user1"},
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 1 * Second, Value: "user2"},
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 2 * Second, Value: "user1"},
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 11 * Second, Value: "user3"},
+				}},
+				&StringIterator{Points: []query.StringPoint{
+					{Name: "cpu", Tags: ParseTags("region=east,host=A"), Time: 9 * Second, Value: "user2"},
+					{Name: "cpu", Tags: ParseTags("region=east,host=A"), Time: 9 * Second, Value: "user4"},
+				}},
+				&StringIterator{Points: []query.StringPoint{
+					{Name: "cpu", Tags: ParseTags("region=west,host=B"), Time: 5 * Second, Value: "user1"},
+				}},
+			},
+			rows: []query.Row{
+				{Time: 0 * Second, Series: query.Series{Name: "cpu", Tags: ParseTags("host=A")}, Values: []interface{}{int64(3)}},
+				{Time: 10 * Second, Series: query.Series{Name: "cpu", Tags: ParseTags("host=A")}, Values: []interface{}{int64(1)}},
+				{Time: 0 * Second, Series: query.Series{Name: "cpu", Tags: ParseTags("host=B")}, Values: []interface{}{int64(1)}},
+			},
+		},
+		{
+			name: "CountDistinctApprox_Float_Precision",
+			q:    `SELECT count_distinct_approx(value, 10) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:30Z' GROUP BY time(10s)`,
+			typ:  influxql.Float,
+			expr: `hll(value::float, 10)`,
+			itrs: []query.Iterator{
+				&FloatIterator{Points: []query.FloatPoint{
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 0 * Second, Value: 1.5},
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 1 * Second, Value: 2.5},
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 21 * Second, Value: 2.5},
+				}},
+				&FloatIterator{Points: []query.FloatPoint{
+					{Name: "cpu", Tags: ParseTags("region=west,host=B"), Time: 5 * Second, Value: 1.5},
+				}},
+			},
+			rows: []query.Row{
+				{Time: 0 * Second, Series: query.Series{Name: "cpu"}, Values: []interface{}{int64(2)}},
+				{Time: 10 * Second, Series: query.Series{Name: "cpu"}, Values: []interface{}{int64(0)}},
+				{Time: 20 * Second, Series: query.Series{Name: "cpu"}, Values: []interface{}{int64(1)}},
+			},
+		},
 		{
 			name: "Sample_Float",
 			q:    `SELECT sample(value, 2) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-02T00:00:00Z' GROUP BY time(10s), host fill(none)`,
@@ -2976,7 +3163,117 @@ func TestSelect_Raw(t *testing.T) {
 	}
 }
 
-// Ensure a SELECT binary expr queries can be executed as floats.
+// Ensure the windows of selectors at the edges of the time range are filled
+// from the nearest points outside of it.
+func TestSelect_FillEdges(t *testing.T) {
+	shardMapper := ShardMapper{
+		MapShardsFn: func(sources influxql.Sources, _ influxql.TimeRange) query.ShardGroup {
+			return &ShardGroup{
+				Fields: map[string]influxql.DataType{
+					"value": influxql.Float,
+				},
+				CreateIteratorFn: func(ctx context.Context, m *influxql.Measurement, opt query.IteratorOptions) (query.Iterator, error) {
+					var points []query.FloatPoint
+					for _, p := range []query.FloatPoint{
+						{Name: "cpu", Tags: ParseTags("host=A"), Time: 5 * Second, Value: 0},
+						{Name: "cpu", Tags: ParseTags("host=A"), Time: 32 * Second, Value: 30},
+						{Name: "cpu", Tags: ParseTags("host=A"), Time: 80 * Second, Value: 80},
+					} {
+						if p.Time >= opt.StartTime && p.Time <= opt.EndTime {
+							points = append(points, p)
+						}
+					}
+					return query.NewCallIterator(&FloatIterator{Points: points}, opt)
+				},
+			}
+		},
+	}
+
+	for _, tt := range []struct {
+		name     string
+		call     string
+		fill     string
+		lookback time.Duration
+		values   []interface{}
+	}{
+		{
+			name:     "Previous",
+			fill:     "previous",
+			lookback: time.Minute,
+			values:   []interface{}{float64(0), float64(30), float64(30), float64(30)},
+		},
+		{
+			name:   "Previous_NoLookback",
+			fill:   "previous",
+			values: []interface{}{nil, float64(30), float64(30), float64(30)},
+		},
+		{
+			name:     "Previous_Bounded",
+			fill:     "previous",
+			lookback: 10 * time.Second,
+			values:   []interface{}{nil, float64(30), float64(30), float64(30)},
+		},
+		{
+			name:     "Linear",
+			fill:     "linear",
+			lookback: time.Minute,
+			values:   []interface{}{float64(20), float64(30), float64(40), float64(50)},
+		},
+		{
+			name:   "Linear_NoLookback",
+			fill:   "linear",
+			values: []interface{}{nil, float64(30), nil, nil},
+		},
+		{
+			name:     "InterpolateTime",
+			fill:     "interpolate_time",
+			lookback: time.Minute,
+			values:   []interface{}{float64(18), float64(30), float64(40), float64(50)},
+		},
+		{
+			name:     "Previous_Aggregate",
+			call:     "mean",
+			fill:     "previous",
+			lookback: time.Minute,
+			values:   []interface{}{nil, float64(30), float64(30), float64(30)},
+		},
+		{
+			name:     "Linear_Aggregate",
+			call:     "mean",
+			fill:     "linear",
+			lookback: time.Minute,
+			values:   []interface{}{nil, float64(30), nil, nil},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			call := tt.call
+			if call == "" {
+				call = "last"
+			}
+			stmt := MustParseSelectStatement(fmt.Sprintf(`SELECT %s(value) FROM cpu WHERE time >= '1970-01-01T00:00:20Z' AND time < '1970-01-01T00:01:00Z' GROUP BY host, time(10s) fill(%s)`, call, tt.fill))
+			cur, err := query.Select(context.Background(), stmt, &shardMapper, query.SelectOptions{MaxFillLookback: tt.lookback})
+			if err != nil {
+				t.Fatal(err)
+			}
+			rows, err := ReadCursor(cur)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			var values []interface{}
+			for i, row := range rows {
+				if got, want := row.Time, int64(20+10*i)*Second; got != want {
+					t.Fatalf("unexpected time for row %d: got=%d want=%d", i, got, want)
+				}
+				values = append(values, row.Values[1])
+			}
+			if diff := cmp.Diff(tt.values, values); diff != "" {
+				t.Fatalf("unexpected values:\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestSelect_BinaryExpr(t *testing.T) {
 	shardMapper := ShardMapper{
 		MapShardsFn: func(sources influxql.Sources, _ influxql.TimeRange) query.ShardGroup {
@@ -4207,6 +4504,24 @@ func benchmarkSelectTop(b *testing.B, seriesN, pointsPerSeries int) {
 
 func BenchmarkSelect_Top_1K(b *testing.B) { benchmarkSelectTop(b, 1000, 1000) }
 
+// QuantileApprox returns the estimated quantile of the values using a sketch.
+func QuantileApprox(q float64, values ...float64) float64 {
+	sketch := ddsketch.NewDefault()
+	for _, v := range values {
+		sketch.Add(v)
+	}
+	return sketch.Quantile(q)
+}
+
+// EncodeSketch returns the encoded sketch of the values.
+func EncodeSketch(values ...float64) string {
+	sketch := ddsketch.NewDefault()
+	for _, v := range values {
+		sketch.Add(v)
+	}
+	return query.EncodeSketch(sketch)
+}
+
 // ReadCursor reads a Cursor into an array of points.
 func ReadCursor(cur query.Cursor) ([]query.Row, error) {
 	defer cur.Close()
@@ -364,6 +364,35 @@ func TestSubquery(t *testing.T) {
 				{Time: mustParseTime("2019-06-25T22:36:15.144253616Z").UnixNano(), Series: query.Series{Name: "testing"}, Values: []interface{}{float64(2), "a"}},
 			},
 		},
+		{
+			Name:      "SketchRollup",
+			Statement: `SELECT quantile_approx(s, 0.5) FROM (SELECT sketch(value) AS s FROM cpu GROUP BY time(5s)) WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:20Z' GROUP BY time(10s)`,
+			Fields:    map[string]influxql.DataType{"value": influxql.Float},
+			MapShardsFn: func(t *testing.T, tr influxql.TimeRange) CreateIteratorFn {
+				return func(ctx context.Context, m *influxql.Measurement, opt query.IteratorOptions) query.Iterator {
+					if got, want := opt.Expr.String(), "sketch(value::float)"; got != want {
+						t.Errorf("unexpected expression: got=%s want=%s", got, want)
+					}
+					var itr query.Iterator = &FloatIterator{Points: []query.FloatPoint{
+						{Name: "cpu", Time: 0 * Second, Value: 1},
+						{Name: "cpu", Time: 1 * Second, Value: 2},
+						{Name: "cpu", Time: 6 * Second, Value: 30},
+						{Name: "cpu", Time: 7 * Second, Value: 40},
+						{Name: "cpu", Time: 8 * Second, Value: 50},
+						{Name: "cpu", Time: 12 * Second, Value: 7},
+					}}
+					i, err := query.NewCallIterator(itr, opt)
+					if err != nil {
+						panic(err)
+					}
+					return i
+				}
+			},
+			Rows: []query.Row{
+				{Time: 0 * Second, Series: query.Series{Name: "cpu"}, Values: []interface{}{QuantileApprox(0.5, 1, 2, 30, 40, 50)}},
+				{Time: 10 * Second, Series: query.Series{Name: "cpu"}, Values: []interface{}{float64(7)}},
+			},
+		},
 	} {
 		t.Run(test.Name, func(t *testing.T) {
 			shardMapper := ShardMapper{
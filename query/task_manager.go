@@ -1,7 +1,6 @@
 package query
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -47,13 +46,19 @@ func (t TaskStatus) MarshalJSON() ([]byte, error) {
 }
 
 func (t *TaskStatus) UnmarshalJSON(data []byte) error {
-	if bytes.Equal(data, []byte("running")) {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	switch s {
+	case "running":
 		*t = RunningTask
-	} else if bytes.Equal(data, []byte("killed")) {
+	case "killed":
 		*t = KilledTask
-	} else if bytes.Equal(data, []byte("unknown")) {
+	case "unknown":
 		*t = TaskStatus(0)
-	} else {
+	default:
 		return fmt.Errorf("unknown task status: %s", string(data))
 	}
 	return nil
@@ -64,13 +69,27 @@ type TaskManager struct {
 	// Query execution timeout.
 	QueryTimeout time.Duration
 
-	// Log queries if they are slower than this time.
+	// Log statements slower than this time once they finish.
 	// If zero, slow queries will never be logged.
 	LogQueriesAfter time.Duration
 
+	// Log the profile of statements if they are slower than this time.
+	// If zero, statements are only profiled when requested.
+	ProfileQueriesAfter time.Duration
+
 	// Maximum number of concurrent queries.
 	MaxConcurrentQueries int
 
+	// Per-user and per-database quotas. Optional.
+	Quotas *Quotas
+
+	// Statistics of the executed statements by fingerprint. Optional.
+	QueryStats *QueryStats
+
+	// Logger for the statements slower than LogQueriesAfter.
+	// Defaults to Logger.
+	SlowQueryLogger *zap.Logger
+
 	// Logger to use for all logging.
 	// Defaults to discarding all log output.
 	Logger *zap.Logger
@@ -101,6 +120,14 @@ func (t *TaskManager) ExecuteStatement(stmt influxql.Statement, ctx *ExecutionCo
 			return err
 		}
 
+		ctx.Send(&Result{
+			Series: rows,
+		})
+	case *influxql.ShowQueryStatsStatement:
+		var rows models.Rows
+		if t.QueryStats != nil {
+			rows = t.QueryStats.Rows(stmt.Limit)
+		}
 		ctx.Send(&Result{
 			Series: rows,
 		})
@@ -134,17 +161,7 @@ func (t *TaskManager) executeShowQueriesStatement(q *influxql.ShowQueriesStateme
 
 	values := make([][]interface{}, 0, len(t.queries))
 	for id, qi := range t.queries {
-		d := now.Sub(qi.startTime)
-
-		switch {
-		case d >= time.Second:
-			d = d - (d % time.Second)
-		case d >= time.Millisecond:
-			d = d - (d % time.Millisecond)
-		case d >= time.Microsecond:
-			d = d - (d % time.Microsecond)
-		}
-
+		d := roundDuration(now.Sub(qi.startTime))
 		values = append(values, []interface{}{id, qi.query, qi.database, d.String(), qi.status.String()})
 	}
 
@@ -182,32 +199,29 @@ func (t *TaskManager) AttachQuery(q *influxql.Query, opt ExecutionOptions, inter
 		return nil, nil, ErrMaxConcurrentQueriesLimitExceeded(len(t.queries), t.MaxConcurrentQueries)
 	}
 
+	if t.Quotas != nil {
+		if err := t.Quotas.acquire(opt.UserID, opt.Database); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	qid := t.nextID
 	query := &Task{
 		query:     q.String(),
 		database:  opt.Database,
+		user:      opt.UserID,
+		quotas:    t.Quotas,
 		status:    RunningTask,
 		startTime: time.Now(),
 		closing:   make(chan struct{}),
 		monitorCh: make(chan error),
 	}
+	if query.quotas != nil {
+		query.progress.charge = query.chargePoints
+	}
 	t.queries[qid] = query
 
 	go t.waitForQuery(qid, query.closing, interrupt, query.monitorCh)
-	if t.LogQueriesAfter != 0 {
-		go query.monitor(func(closing <-chan struct{}) error {
-			timer := time.NewTimer(t.LogQueriesAfter)
-			defer timer.Stop()
-
-			select {
-			case <-timer.C:
-				t.Logger.Warn(fmt.Sprintf("Detected slow query: %s (qid: %d, database: %s, threshold: %s)",
-					query.query, qid, query.database, t.LogQueriesAfter))
-			case <-closing:
-			}
-			return nil
-		})
-	}
 	t.nextID++
 
 	ctx := &ExecutionContext{
@@ -246,6 +260,9 @@ func (t *TaskManager) DetachQuery(qid uint64) error {
 	}
 
 	query.close()
+	if query.quotas != nil {
+		query.quotas.release(query.user, query.database)
+	}
 	delete(t.queries, qid)
 	return nil
 }
@@ -255,8 +272,14 @@ type QueryInfo struct {
 	ID       uint64        `json:"id"`
 	Query    string        `json:"query"`
 	Database string        `json:"database"`
+	User     string        `json:"user,omitempty"`
 	Duration time.Duration `json:"duration"`
 	Status   TaskStatus    `json:"status"`
+
+	// Progress of the query.
+	ShardN          int64 `json:"shards_total"`
+	ShardsCompleted int64 `json:"shards_completed"`
+	PointN          int64 `json:"points_scanned"`
 }
 
 // Queries returns a list of all running queries with information about them.
@@ -267,17 +290,24 @@ func (t *TaskManager) Queries() []QueryInfo {
 	now := time.Now()
 	queries := make([]QueryInfo, 0, len(t.queries))
 	for id, qi := range t.queries {
-		queries = append(queries, QueryInfo{
-			ID:       id,
-			Query:    qi.query,
-			Database: qi.database,
-			Duration: now.Sub(qi.startTime),
-			Status:   qi.status,
-		})
+		queries = append(queries, qi.info(id, now))
 	}
 	return queries
 }
 
+// Query returns information about a running query. Returns false if there is
+// no such query.
+func (t *TaskManager) Query(qid uint64) (QueryInfo, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	qi := t.queries[qid]
+	if qi == nil {
+		return QueryInfo{}, false
+	}
+	return qi.info(qid, time.Now()), true
+}
+
 func (t *TaskManager) waitForQuery(qid uint64, interrupt <-chan struct{}, closing <-chan struct{}, monitorCh <-chan error) {
 	var timerCh <-chan time.Time
 	if t.QueryTimeout != 0 {
@@ -0,0 +1,59 @@
+package query
+
+import (
+	"context"
+	"sync"
+)
+
+// Warnings collects the warnings raised while executing a statement so they
+// are returned with its results. It is safe for concurrent use.
+type Warnings struct {
+	mu      sync.Mutex
+	pending []*Message
+	seen    map[string]struct{}
+}
+
+// WarningsFromContext returns the Warnings of the statement executing with the
+// Context if one exists.
+func WarningsFromContext(ctx context.Context) *Warnings {
+	v, _ := ctx.Value(warningsContextKey).(*Warnings)
+	return v
+}
+
+// Add adds a warning unless the same warning was already added for the
+// statement.
+func (w *Warnings) Add(text string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.seen[text]; ok {
+		return
+	}
+	if w.seen == nil {
+		w.seen = make(map[string]struct{})
+	}
+	w.seen[text] = struct{}{}
+	w.pending = append(w.pending, &Message{Level: WarningLevel, Text: text})
+}
+
+// Pending returns true if warnings were added that were not returned yet.
+func (w *Warnings) Pending() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.pending) > 0
+}
+
+// take returns the warnings that were not returned yet.
+func (w *Warnings) take() []*Message {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	msgs := w.pending
+	w.pending = nil
+	return msgs
+}
+
+// reset forgets the warnings of the previous statement.
+func (w *Warnings) reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending, w.seen = nil, nil
+}
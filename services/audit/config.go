@@ -0,0 +1,109 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/toml"
+)
+
+const (
+	// DefaultMaxSize is the default size a log file may reach before it is
+	// rotated.
+	DefaultMaxSize = 100 * 1024 * 1024
+
+	// DefaultMaxBackups is the default number of rotated log files to keep.
+	DefaultMaxBackups = 5
+
+	// DefaultStoreDatabase is the default database events are written to.
+	DefaultStoreDatabase = "_audit"
+)
+
+// Event categories.
+const (
+	// CategoryDDL covers statements changing databases, retention policies,
+	// continuous queries, subscriptions and stored data.
+	CategoryDDL = "ddl"
+
+	// CategoryDCL covers statements changing users, roles, privileges,
+	// tokens and quotas.
+	CategoryDCL = "dcl"
+
+	// CategoryAuth covers authentication attempts.
+	CategoryAuth = "auth"
+)
+
+// Config represents the configuration for the audit service.
+type Config struct {
+	Enabled       bool      `toml:"enabled"`
+	Path          string    `toml:"path"`
+	MaxSize       toml.Size `toml:"max-size"`
+	MaxBackups    int       `toml:"max-backups"`
+	StoreEnabled  bool      `toml:"store-enabled"`
+	StoreDatabase string    `toml:"store-database"`
+	Categories    []string  `toml:"categories"`
+
+	// AuthSuccess enables recording successful authentication attempts, which
+	// happen on every authenticated request. Failed attempts are always
+	// recorded when the auth category is enabled.
+	AuthSuccess bool `toml:"auth-success"`
+}
+
+// NewConfig returns an instance of Config with defaults.
+func NewConfig() Config {
+	return Config{
+		Enabled:       false,
+		MaxSize:       DefaultMaxSize,
+		MaxBackups:    DefaultMaxBackups,
+		StoreDatabase: DefaultStoreDatabase,
+		Categories:    []string{CategoryDDL, CategoryDCL, CategoryAuth},
+	}
+}
+
+// Validate returns an error if the Config is invalid.
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if c.Path == "" && !c.StoreEnabled {
+		return errors.New("path must be specified when store-enabled is false")
+	}
+	if c.MaxBackups < 0 {
+		return errors.New("max-backups must not be negative")
+	}
+	if c.StoreEnabled && c.StoreDatabase == "" {
+		return errors.New("store-database must be specified when store-enabled is true")
+	}
+	for _, category := range c.Categories {
+		switch category {
+		case CategoryDDL, CategoryDCL, CategoryAuth:
+		default:
+			return fmt.Errorf("unknown category: %q", category)
+		}
+	}
+
+	return nil
+}
+
+// Diagnostics returns a diagnostics representation of a subset of the Config.
+func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
+	if !c.Enabled {
+		return diagnostics.RowFromMap(map[string]interface{}{
+			"enabled": false,
+		}), nil
+	}
+
+	return diagnostics.RowFromMap(map[string]interface{}{
+		"enabled":        true,
+		"path":           c.Path,
+		"max-size":       c.MaxSize,
+		"max-backups":    c.MaxBackups,
+		"store-enabled":  c.StoreEnabled,
+		"store-database": c.StoreDatabase,
+		"categories":     strings.Join(c.Categories, ","),
+		"auth-success":   c.AuthSuccess,
+	}), nil
+}
@@ -0,0 +1,81 @@
+package audit_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdata/influxdb/services/audit"
+)
+
+func TestConfig_Parse(t *testing.T) {
+	// Parse configuration.
+	var c audit.Config
+	if _, err := toml.Decode(`
+enabled = true
+path = "/var/log/influxdb/audit.log"
+max-size = "10m"
+max-backups = 3
+store-enabled = true
+store-database = "audit_db"
+categories = ["dcl", "auth"]
+auth-success = true
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate configuration.
+	if !c.Enabled {
+		t.Fatalf("unexpected enabled state: %v", c.Enabled)
+	} else if c.Path != "/var/log/influxdb/audit.log" {
+		t.Fatalf("unexpected path: %s", c.Path)
+	} else if c.MaxSize != 10*1024*1024 {
+		t.Fatalf("unexpected max size: %d", c.MaxSize)
+	} else if c.MaxBackups != 3 {
+		t.Fatalf("unexpected max backups: %d", c.MaxBackups)
+	} else if !c.StoreEnabled {
+		t.Fatalf("unexpected store enabled state: %v", c.StoreEnabled)
+	} else if c.StoreDatabase != "audit_db" {
+		t.Fatalf("unexpected store database: %s", c.StoreDatabase)
+	} else if !reflect.DeepEqual(c.Categories, []string{"dcl", "auth"}) {
+		t.Fatalf("unexpected categories: %v", c.Categories)
+	} else if !c.AuthSuccess {
+		t.Fatalf("unexpected auth success state: %v", c.AuthSuccess)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	c := audit.NewConfig()
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validation fail from NewConfig: %s", err)
+	}
+
+	c = audit.NewConfig()
+	c.Enabled = true
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for missing path, got nil")
+	}
+
+	c.StoreEnabled = true
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validation fail from store only config: %s", err)
+	}
+
+	c.StoreDatabase = ""
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for empty store-database, got nil")
+	}
+
+	c = audit.NewConfig()
+	c.Enabled = true
+	c.Path = "/var/log/influxdb/audit.log"
+	c.Categories = []string{"ddl", "dml"}
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for unknown category, got nil")
+	}
+
+	c.Enabled = false
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validation fail from disabled config: %s", err)
+	}
+}
@@ -0,0 +1,415 @@
+// Package audit provides the service that records administrative and
+// authentication events.
+package audit // import "github.com/influxdata/influxdb/services/audit"
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/logger"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxql"
+	"go.uber.org/zap"
+)
+
+const (
+	// Measurement is the measurement events are stored in.
+	Measurement = "audit"
+
+	// maxPendingEvents is the number of events which may be waiting to be
+	// stored before new events are dropped.
+	maxPendingEvents = 10000
+
+	// storeBatchSize is the maximum number of events written at once.
+	storeBatchSize = 1000
+
+	// storeInterval is how often pending events are written.
+	storeInterval = time.Second
+)
+
+// Event is a single audited event.
+type Event struct {
+	Time       time.Time `json:"time"`
+	Category   string    `json:"category"`
+	Action     string    `json:"action"`
+	User       string    `json:"user,omitempty"`
+	Database   string    `json:"database,omitempty"`
+	Statement  string    `json:"statement,omitempty"`
+	Method     string    `json:"method,omitempty"`
+	RemoteAddr string    `json:"remote_addr,omitempty"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// point returns the event as a point in the audit measurement.
+func (ev *Event) point() (models.Point, error) {
+	tags := map[string]string{
+		"category": ev.Category,
+		"action":   ev.Action,
+	}
+	fields := map[string]interface{}{
+		"success": ev.Success,
+	}
+	for k, v := range map[string]string{
+		"user":        ev.User,
+		"database":    ev.Database,
+		"statement":   ev.Statement,
+		"method":      ev.Method,
+		"remote_addr": ev.RemoteAddr,
+		"error":       ev.Error,
+	} {
+		if v != "" {
+			fields[k] = v
+		}
+	}
+	return models.NewPoint(Measurement, models.NewTags(tags), fields, ev.Time)
+}
+
+// Service records audit events as JSON lines in a rotating log file and,
+// optionally, as points in an internal database.
+type Service struct {
+	MetaClient interface {
+		Database(name string) *meta.DatabaseInfo
+		CreateDatabase(name string) (*meta.DatabaseInfo, error)
+	}
+	PointsWriter interface {
+		WritePoints(database, retentionPolicy string, points models.Points) error
+	}
+
+	config     Config
+	categories map[string]bool
+
+	mu      sync.Mutex
+	file    *logFile
+	pending chan *Event
+	dropped int64
+	wg      sync.WaitGroup
+	done    chan struct{}
+
+	storeCreated bool
+
+	logger *zap.Logger
+}
+
+// NewService returns a configured audit service.
+func NewService(c Config) *Service {
+	s := &Service{
+		config:     c,
+		categories: make(map[string]bool, len(c.Categories)),
+		logger:     zap.NewNop(),
+	}
+	for _, category := range c.Categories {
+		s.categories[category] = true
+	}
+	return s
+}
+
+// Open opens the log file and starts storing events.
+func (s *Service) Open() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.config.Enabled || s.done != nil {
+		return nil
+	}
+
+	s.logger.Info("Starting audit service",
+		logger.Database(s.config.StoreDatabase),
+		zap.String("path", s.config.Path),
+		zap.Bool("store", s.config.StoreEnabled))
+
+	if s.config.Path != "" {
+		f, err := openLogFile(s.config.Path, int64(s.config.MaxSize), s.config.MaxBackups)
+		if err != nil {
+			return fmt.Errorf("open audit log: %s", err)
+		}
+		s.file = f
+	}
+
+	done := make(chan struct{})
+	s.done = done
+	if s.config.StoreEnabled {
+		pending := make(chan *Event, maxPendingEvents)
+		s.pending = pending
+		s.wg.Add(1)
+		go func() { defer s.wg.Done(); s.store(pending, done) }()
+	}
+	return nil
+}
+
+// Close stops storing events, after writing the pending ones, and closes the
+// log file.
+func (s *Service) Close() error {
+	s.mu.Lock()
+	if s.done == nil {
+		s.mu.Unlock()
+		return nil
+	}
+	s.logger.Info("Closing audit service")
+	close(s.done)
+	s.done = nil
+	s.mu.Unlock()
+
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = nil
+	if s.file != nil {
+		err := s.file.Close()
+		s.file = nil
+		return err
+	}
+	return nil
+}
+
+// WithLogger sets the logger on the service.
+func (s *Service) WithLogger(log *zap.Logger) {
+	s.logger = log.With(zap.String("service", "audit"))
+}
+
+// AuditStatement records the execution of a statement by user against
+// database. Statements which are neither DDL nor DCL are ignored.
+func (s *Service) AuditStatement(stmt influxql.Statement, user, database string, err error) {
+	category, action := StatementAction(stmt)
+	if category == "" {
+		return
+	}
+
+	s.Record(&Event{
+		Category:  category,
+		Action:    action,
+		User:      user,
+		Database:  database,
+		Statement: stmt.String(),
+		Success:   err == nil,
+		Error:     errorString(err),
+	})
+}
+
+// AuditAuthentication records an attempt by username to authenticate with
+// method from remoteAddr. Successful attempts are only recorded when enabled
+// by the configuration.
+func (s *Service) AuditAuthentication(username, method, remoteAddr string, err error) {
+	if err == nil && !s.config.AuthSuccess {
+		return
+	}
+
+	s.Record(&Event{
+		Category:   CategoryAuth,
+		Action:     "authenticate",
+		User:       username,
+		Method:     method,
+		RemoteAddr: remoteAddr,
+		Success:    err == nil,
+		Error:      errorString(err),
+	})
+}
+
+// Record records ev if its category is enabled. The time of the event is
+// set to the current time when it is zero.
+func (s *Service) Record(ev *Event) {
+	if !s.categories[ev.Category] {
+		return
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now().UTC()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done == nil {
+		return
+	}
+
+	if s.file != nil {
+		buf, err := json.Marshal(ev)
+		if err == nil {
+			_, err = s.file.Write(append(buf, '\n'))
+		}
+		if err != nil {
+			s.logger.Info("Failed to write audit event", zap.Error(err))
+		}
+	}
+
+	if s.pending != nil {
+		select {
+		case s.pending <- ev:
+		default:
+			s.dropped++
+		}
+	}
+}
+
+// store writes pending events to the store database until done is closed.
+func (s *Service) store(pending <-chan *Event, done <-chan struct{}) {
+	ticker := time.NewTicker(storeInterval)
+	defer ticker.Stop()
+
+	var batch []*Event
+	for {
+		select {
+		case ev := <-pending:
+			batch = append(batch, ev)
+			if len(batch) >= storeBatchSize {
+				batch = s.writeEvents(batch)
+			}
+
+		case <-ticker.C:
+			batch = s.writeEvents(batch)
+
+		case <-done:
+			for {
+				select {
+				case ev := <-pending:
+					batch = append(batch, ev)
+				default:
+					s.writeEvents(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// writeEvents writes a batch of events to the store database. It returns the
+// events which must be written again.
+func (s *Service) writeEvents(batch []*Event) []*Event {
+	s.mu.Lock()
+	dropped := s.dropped
+	s.dropped = 0
+	s.mu.Unlock()
+	if dropped > 0 {
+		s.logger.Info("Dropped audit events, too many waiting to be stored", zap.Int64("n", dropped))
+	}
+
+	if len(batch) == 0 {
+		return batch
+	}
+
+	if !s.storeCreated {
+		if di := s.MetaClient.Database(s.config.StoreDatabase); di == nil {
+			if _, err := s.MetaClient.CreateDatabase(s.config.StoreDatabase); err != nil {
+				s.logger.Info("Failed to create storage", logger.Database(s.config.StoreDatabase), zap.Error(err))
+				return s.keepPending(batch)
+			}
+		}
+		s.storeCreated = true
+	}
+
+	points := make(models.Points, 0, len(batch))
+	for _, ev := range batch {
+		pt, err := ev.point()
+		if err != nil {
+			s.logger.Info("Dropping audit event", zap.Error(err))
+			continue
+		}
+		points = append(points, pt)
+	}
+
+	if err := s.PointsWriter.WritePoints(s.config.StoreDatabase, "", points); err != nil {
+		s.logger.Info("Failed to store audit events", logger.Database(s.config.StoreDatabase), zap.Error(err))
+		return s.keepPending(batch)
+	}
+	return batch[:0]
+}
+
+// keepPending returns the events of a batch which failed to be written,
+// dropping the oldest ones once the batch is full.
+func (s *Service) keepPending(batch []*Event) []*Event {
+	if len(batch) > maxPendingEvents {
+		s.logger.Info("Dropped audit events, too many waiting to be stored", zap.Int("n", len(batch)-maxPendingEvents))
+		batch = append(batch[:0], batch[len(batch)-maxPendingEvents:]...)
+	}
+	return batch
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// StatementAction returns the category and the name of the action of a
+// statement. It returns an empty category for statements which aren't
+// audited.
+func StatementAction(stmt influxql.Statement) (category, action string) {
+	switch stmt.(type) {
+	case *influxql.AlterRetentionPolicyStatement:
+		return CategoryDDL, "alter_retention_policy"
+	case *influxql.CreateContinuousQueryStatement:
+		return CategoryDDL, "create_continuous_query"
+	case *influxql.CreateDatabaseStatement:
+		return CategoryDDL, "create_database"
+	case *influxql.CreateDownsampleStatement:
+		return CategoryDDL, "create_downsample"
+	case *influxql.CreateRetentionPolicyStatement:
+		return CategoryDDL, "create_retention_policy"
+	case *influxql.CreateRetentionRuleStatement:
+		return CategoryDDL, "create_retention_rule"
+	case *influxql.CreateSubscriptionStatement:
+		return CategoryDDL, "create_subscription"
+	case *influxql.DeleteSeriesStatement:
+		return CategoryDDL, "delete"
+	case *influxql.DropContinuousQueryStatement:
+		return CategoryDDL, "drop_continuous_query"
+	case *influxql.DropDatabaseStatement:
+		return CategoryDDL, "drop_database"
+	case *influxql.DropDownsampleStatement:
+		return CategoryDDL, "drop_downsample"
+	case *influxql.DropMeasurementStatement:
+		return CategoryDDL, "drop_measurement"
+	case *influxql.DropRetentionPolicyStatement:
+		return CategoryDDL, "drop_retention_policy"
+	case *influxql.DropRetentionRuleStatement:
+		return CategoryDDL, "drop_retention_rule"
+	case *influxql.DropSeriesStatement:
+		return CategoryDDL, "drop_series"
+	case *influxql.DropShardStatement:
+		return CategoryDDL, "drop_shard"
+	case *influxql.DropSubscriptionStatement:
+		return CategoryDDL, "drop_subscription"
+	case *influxql.RepairShardStatement:
+		return CategoryDDL, "repair_shard"
+	case *influxql.CreateRoleStatement:
+		return CategoryDCL, "create_role"
+	case *influxql.CreateTokenStatement:
+		return CategoryDCL, "create_token"
+	case *influxql.CreateUserStatement:
+		return CategoryDCL, "create_user"
+	case *influxql.DropQuotaStatement:
+		return CategoryDCL, "drop_quota"
+	case *influxql.DropRoleStatement:
+		return CategoryDCL, "drop_role"
+	case *influxql.DropTokenStatement:
+		return CategoryDCL, "drop_token"
+	case *influxql.DropUserStatement:
+		return CategoryDCL, "drop_user"
+	case *influxql.GrantAdminStatement:
+		return CategoryDCL, "grant_admin"
+	case *influxql.GrantMeasurementStatement:
+		return CategoryDCL, "grant_measurement"
+	case *influxql.GrantRoleStatement:
+		return CategoryDCL, "grant_role"
+	case *influxql.GrantStatement:
+		return CategoryDCL, "grant"
+	case *influxql.RevokeAdminStatement:
+		return CategoryDCL, "revoke_admin"
+	case *influxql.RevokeMeasurementStatement:
+		return CategoryDCL, "revoke_measurement"
+	case *influxql.RevokeRoleStatement:
+		return CategoryDCL, "revoke_role"
+	case *influxql.RevokeStatement:
+		return CategoryDCL, "revoke"
+	case *influxql.SetPasswordUserStatement:
+		return CategoryDCL, "set_password"
+	case *influxql.SetQuotaStatement:
+		return CategoryDCL, "set_quota"
+	default:
+		return "", ""
+	}
+}
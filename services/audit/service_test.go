@@ -0,0 +1,250 @@
+package audit_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/services/audit"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxql"
+)
+
+func TestService_AuditStatement(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	c := audit.NewConfig()
+	c.Enabled = true
+	c.Path = filepath.Join(dir, "audit.log")
+	c.Categories = []string{audit.CategoryDDL, audit.CategoryDCL}
+	s := audit.NewService(c)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	s.AuditStatement(&influxql.CreateUserStatement{Name: "bob", Password: "secret"}, "admin", "", nil)
+	s.AuditStatement(&influxql.DropDatabaseStatement{Name: "db0"}, "admin", "db0", errors.New("marker"))
+	s.AuditStatement(&influxql.ShowDatabasesStatement{}, "admin", "", nil)
+	s.AuditAuthentication("admin", "password", "127.0.0.1:1234", nil)
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	events := MustReadEvents(c.Path)
+	if len(events) != 2 {
+		t.Fatalf("unexpected number of events: %d", len(events))
+	}
+
+	if ev := events[0]; ev.Category != audit.CategoryDCL || ev.Action != "create_user" || ev.User != "admin" || !ev.Success || ev.Time.IsZero() {
+		t.Fatalf("unexpected event: %+v", ev)
+	} else if strings.Contains(ev.Statement, "secret") {
+		t.Fatalf("password not redacted: %s", ev.Statement)
+	}
+
+	if ev := events[1]; ev.Category != audit.CategoryDDL || ev.Action != "drop_database" || ev.Database != "db0" || ev.Success || ev.Error != "marker" {
+		t.Fatalf("unexpected event: %+v", ev)
+	} else if ev.Statement != "DROP DATABASE db0" {
+		t.Fatalf("unexpected statement: %s", ev.Statement)
+	}
+}
+
+func TestService_AuditAuthentication(t *testing.T) {
+	for _, success := range []bool{false, true} {
+		t.Run(fmt.Sprintf("auth-success=%v", success), func(t *testing.T) {
+			dir := MustTempDir()
+			defer os.RemoveAll(dir)
+
+			c := audit.NewConfig()
+			c.Enabled = true
+			c.Path = filepath.Join(dir, "audit.log")
+			c.AuthSuccess = success
+			s := audit.NewService(c)
+			if err := s.Open(); err != nil {
+				t.Fatal(err)
+			}
+
+			s.AuditAuthentication("admin", "password", "127.0.0.1:1234", nil)
+			s.AuditAuthentication("bob", "password", "127.0.0.1:1234", errors.New("authorization failed"))
+			if err := s.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			var users []string
+			for _, ev := range MustReadEvents(c.Path) {
+				users = append(users, ev.User)
+			}
+			exp := []string{"bob"}
+			if success {
+				exp = []string{"admin", "bob"}
+			}
+			if !reflect.DeepEqual(users, exp) {
+				t.Fatalf("unexpected users: got %v, exp %v", users, exp)
+			}
+		})
+	}
+}
+
+func TestService_Rotate(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	c := audit.NewConfig()
+	c.Enabled = true
+	c.Path = filepath.Join(dir, "audit.log")
+	c.MaxSize = 512
+	c.MaxBackups = 2
+	c.AuthSuccess = true
+	s := audit.NewService(c)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 50; i++ {
+		s.AuditAuthentication(fmt.Sprintf("user%d", i), "password", "127.0.0.1:1234", nil)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"audit.log", "audit.log.1", "audit.log.2"} {
+		fi, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		} else if fi.Size() > int64(c.MaxSize) {
+			t.Fatalf("%s larger than max size: %d", name, fi.Size())
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "audit.log.3")); !os.IsNotExist(err) {
+		t.Fatalf("unexpected backup file: %v", err)
+	}
+
+	// The most recent event is at the end of the current file.
+	events := MustReadEvents(c.Path)
+	if ev := events[len(events)-1]; ev.User != "user49" {
+		t.Fatalf("unexpected last event: %+v", ev)
+	}
+}
+
+func TestService_Store(t *testing.T) {
+	c := audit.NewConfig()
+	c.Enabled = true
+	c.StoreEnabled = true
+	s := audit.NewService(c)
+
+	var created string
+	s.MetaClient = &MetaClient{
+		DatabaseFn: func(name string) *meta.DatabaseInfo { return nil },
+		CreateDatabaseFn: func(name string) (*meta.DatabaseInfo, error) {
+			created = name
+			return &meta.DatabaseInfo{Name: name}, nil
+		},
+	}
+
+	var mu sync.Mutex
+	var points models.Points
+	s.PointsWriter = PointsWriterFunc(func(database, retentionPolicy string, pts models.Points) error {
+		if database != audit.DefaultStoreDatabase {
+			t.Errorf("unexpected database: %s", database)
+		}
+		mu.Lock()
+		points = append(points, pts...)
+		mu.Unlock()
+		return nil
+	})
+
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	s.AuditAuthentication("bob", "token", "127.0.0.1:1234", errors.New("authorization failed"))
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if created != audit.DefaultStoreDatabase {
+		t.Fatalf("unexpected created database: %q", created)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(points) != 1 {
+		t.Fatalf("unexpected number of points: %d", len(points))
+	}
+	pt := points[0]
+	if string(pt.Name()) != audit.Measurement {
+		t.Fatalf("unexpected measurement: %s", pt.Name())
+	} else if got := pt.Tags().GetString("category"); got != audit.CategoryAuth {
+		t.Fatalf("unexpected category: %s", got)
+	}
+	fields, err := pt.Fields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields["user"] != "bob" || fields["success"] != false || fields["error"] != "authorization failed" {
+		t.Fatalf("unexpected fields: %v", fields)
+	}
+}
+
+// MetaClient is a mock of the meta client used by the audit service.
+type MetaClient struct {
+	DatabaseFn       func(name string) *meta.DatabaseInfo
+	CreateDatabaseFn func(name string) (*meta.DatabaseInfo, error)
+}
+
+func (c *MetaClient) Database(name string) *meta.DatabaseInfo {
+	return c.DatabaseFn(name)
+}
+
+func (c *MetaClient) CreateDatabase(name string) (*meta.DatabaseInfo, error) {
+	return c.CreateDatabaseFn(name)
+}
+
+// PointsWriterFunc is a function which implements the points writer of the
+// audit service.
+type PointsWriterFunc func(database, retentionPolicy string, points models.Points) error
+
+func (fn PointsWriterFunc) WritePoints(database, retentionPolicy string, points models.Points) error {
+	return fn(database, retentionPolicy, points)
+}
+
+// MustTempDir returns a temporary directory. Panic on error.
+func MustTempDir() string {
+	dir, err := ioutil.TempDir("", "influxdb-audit-")
+	if err != nil {
+		panic(err)
+	}
+	return dir
+}
+
+// MustReadEvents returns the events in the log file at path. Panic on error.
+func MustReadEvents(path string) []audit.Event {
+	f, err := os.Open(path)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	var events []audit.Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var ev audit.Event
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			panic(err)
+		}
+		events = append(events, ev)
+	}
+	if err := scanner.Err(); err != nil {
+		panic(err)
+	}
+	return events
+}
@@ -0,0 +1,58 @@
+package backup
+
+import (
+	"errors"
+
+	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/toml"
+)
+
+// Config represents the configuration for the backup service.
+type Config struct {
+	Enabled bool `toml:"enabled"`
+
+	// Store is the URL of the blob store backups are written to, either
+	// file:///path/to/dir or s3://bucket/prefix?endpoint=host:port&region=name.
+	// S3 credentials are read from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY.
+	Store string `toml:"store"`
+
+	// Interval is the interval at which full backups are taken. Backups are
+	// only taken on request when it is zero.
+	Interval toml.Duration `toml:"interval"`
+}
+
+// NewConfig returns an instance of Config with defaults.
+func NewConfig() Config {
+	return Config{Enabled: false}
+}
+
+// Validate returns an error if the Config is invalid.
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if c.Store == "" {
+		return errors.New("store must be specified")
+	}
+	if c.Interval < 0 {
+		return errors.New("interval must not be negative")
+	}
+
+	return nil
+}
+
+// Diagnostics returns a diagnostics representation of a subset of the Config.
+func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
+	if !c.Enabled {
+		return diagnostics.RowFromMap(map[string]interface{}{
+			"enabled": false,
+		}), nil
+	}
+
+	return diagnostics.RowFromMap(map[string]interface{}{
+		"enabled":  true,
+		"store":    c.Store,
+		"interval": c.Interval,
+	}), nil
+}
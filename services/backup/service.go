@@ -0,0 +1,275 @@
+// Package backup provides the service that backs up shards and the meta store
+// to a blob store.
+package backup // import "github.com/influxdata/influxdb/services/backup"
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influxd/backup_util"
+	"github.com/influxdata/influxdb/logger"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/tsdb"
+	"go.uber.org/zap"
+)
+
+// Service represents the backup service. Backups are made of content-addressed
+// blobs holding the meta store and the files of each shard, and of a manifest
+// listing the blobs of the backup. Files which didn't change since a previous
+// backup are not uploaded again.
+type Service struct {
+	MetaClient interface {
+		encoding.BinaryMarshaler
+		Databases() []meta.DatabaseInfo
+	}
+	TSDBStore interface {
+		Shard(id uint64) *tsdb.Shard
+		CreateShardSnapshot(id uint64) (string, error)
+	}
+
+	// BlobStore holds the backups. It is opened from the configured store
+	// URL when nil.
+	BlobStore backup_util.BlobStore
+
+	config Config
+	mu     sync.Mutex // serializes backups
+	wg     sync.WaitGroup
+	done   chan struct{}
+
+	logger *zap.Logger
+}
+
+// NewService returns a configured backup service.
+func NewService(c Config) *Service {
+	return &Service{
+		config: c,
+		logger: zap.NewNop(),
+	}
+}
+
+// Open opens the blob store and starts the periodic backups.
+func (s *Service) Open() error {
+	if !s.config.Enabled || s.done != nil {
+		return nil
+	}
+
+	if s.BlobStore == nil {
+		store, err := backup_util.OpenBlobStore(s.config.Store)
+		if err != nil {
+			return fmt.Errorf("open blob store: %s", err)
+		}
+		s.BlobStore = store
+	}
+
+	s.logger.Info("Starting backup service",
+		zap.String("store", s.config.Store),
+		logger.DurationLiteral("interval", time.Duration(s.config.Interval)))
+	s.done = make(chan struct{})
+
+	if s.config.Interval > 0 {
+		s.wg.Add(1)
+		go func() { defer s.wg.Done(); s.run() }()
+	}
+	return nil
+}
+
+// Close stops the periodic backups.
+func (s *Service) Close() error {
+	if !s.config.Enabled || s.done == nil {
+		return nil
+	}
+
+	s.logger.Info("Closing backup service")
+	close(s.done)
+
+	s.wg.Wait()
+	s.done = nil
+	return nil
+}
+
+// WithLogger sets the logger on the service.
+func (s *Service) WithLogger(log *zap.Logger) {
+	s.logger = log.With(zap.String("service", "backup"))
+}
+
+func (s *Service) run() {
+	ticker := time.NewTicker(time.Duration(s.config.Interval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+
+		case <-ticker.C:
+			if _, err := s.Backup("", ""); err != nil {
+				s.logger.Info("Backup failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Backup backs up the meta store and the local shards of the given database
+// and retention policy to the blob store. All databases are backed up when
+// database is empty, and all retention policies of the database when
+// retentionPolicy is empty. It returns the key of the manifest of the backup.
+func (s *Service) Backup(database, retentionPolicy string) (string, error) {
+	if s.BlobStore == nil {
+		return "", fmt.Errorf("backup service is not open")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log, logEnd := logger.NewOperation(s.logger, "Backup", "backup",
+		logger.Database(database), logger.RetentionPolicy(retentionPolicy))
+	defer logEnd()
+
+	created := time.Now().UTC()
+	manifest := &backup_util.Manifest{
+		Version:  backup_util.BlobManifestVersion,
+		Created:  created.UnixNano(),
+		Limited:  database != "",
+		Database: database,
+		Policy:   retentionPolicy,
+	}
+
+	if err := s.backupMetaStore(manifest); err != nil {
+		return "", fmt.Errorf("back up meta store: %s", err)
+	}
+
+	dbs := s.MetaClient.Databases()
+	if database != "" {
+		found := false
+		for _, d := range dbs {
+			if d.Name == database {
+				dbs, found = []meta.DatabaseInfo{d}, true
+				break
+			}
+		}
+		if !found {
+			return "", influxdb.ErrDatabaseNotFound(database)
+		}
+	}
+
+	var uploaded, reused int
+	for _, d := range dbs {
+		for _, r := range d.RetentionPolicies {
+			if retentionPolicy != "" && r.Name != retentionPolicy {
+				continue
+			}
+
+			for _, g := range r.ShardGroups {
+				if g.Deleted() {
+					continue
+				}
+
+				for _, sh := range g.Shards {
+					// Ignore shards which aren't on this server.
+					if s.TSDBStore.Shard(sh.ID) == nil {
+						continue
+					}
+
+					u, n, err := s.backupShard(manifest, d.Name, r.Name, sh.ID)
+					if err != nil {
+						return "", fmt.Errorf("back up shard %d: %s", sh.ID, err)
+					}
+					uploaded += u
+					reused += n
+				}
+			}
+		}
+	}
+
+	key, err := backup_util.NextManifestKey(s.BlobStore, created)
+	if err != nil {
+		return "", err
+	}
+	if err := backup_util.SaveBlobManifest(s.BlobStore, key, manifest); err != nil {
+		return "", err
+	}
+
+	log.Info("Backup complete",
+		zap.String("manifest", key),
+		zap.Int("uploaded", uploaded),
+		zap.Int("reused", reused),
+		zap.Int64("size", manifest.Size()))
+	return key, nil
+}
+
+// backupMetaStore uploads the meta store in the portable format.
+func (s *Service) backupMetaStore(manifest *backup_util.Manifest) error {
+	data, err := s.MetaClient.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	b, err := backup_util.PortablePacker{Data: data}.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	key, _, _, err := backup_util.PutContentBlob(s.BlobStore, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+
+	manifest.Meta = backup_util.MetaEntry{FileName: key, Size: int64(len(b))}
+	return nil
+}
+
+// backupShard uploads the files of a snapshot of the shard which aren't
+// already in the blob store. It returns the number of files uploaded and reused.
+// The snapshot flushes the WAL of the shard to TSM files first, so the files
+// of the snapshot hold every write made before the backup.
+func (s *Service) backupShard(manifest *backup_util.Manifest, database, policy string, id uint64) (uploaded, reused int, err error) {
+	dir, err := s.TSDBStore.CreateShardSnapshot(id)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer os.RemoveAll(dir)
+
+	err = filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		key, digest, ok, err := backup_util.PutContentBlob(s.BlobStore, f)
+		if err != nil {
+			return err
+		} else if ok {
+			uploaded++
+		} else {
+			reused++
+		}
+
+		manifest.Files = append(manifest.Files, backup_util.Entry{
+			Database:     database,
+			Policy:       policy,
+			ShardID:      id,
+			FileName:     key,
+			Size:         fi.Size(),
+			LastModified: fi.ModTime().UnixNano(),
+			Path:         filepath.ToSlash(rel),
+			Digest:       digest,
+		})
+		return nil
+	})
+	return uploaded, reused, err
+}
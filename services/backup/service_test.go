@@ -0,0 +1,261 @@
+package backup_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/cmd/influxd/backup_util"
+	"github.com/influxdata/influxdb/internal"
+	"github.com/influxdata/influxdb/logger"
+	"github.com/influxdata/influxdb/services/backup"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/tsdb"
+)
+
+func TestService_OpenClose(t *testing.T) {
+	c := backup.NewConfig()
+	c.Enabled = true
+	c.Store = "file:///var/lib/influxdb/backups"
+	s := NewService(c)
+
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.LogBuf.String() == "" {
+		t.Fatal("service didn't log anything on open")
+	}
+
+	// Reopening is a no-op
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Re-closing is a no-op
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestService_Backup(t *testing.T) {
+	s := NewService(backup.NewConfig())
+	defer s.Cleanup()
+
+	// Shard 1 and 2 are local, shard 3 isn't on this server.
+	s.ShardFiles = map[uint64]map[string]string{
+		1: {"000000001-000000001.tsm": "tsm-1", "000000002-000000001.tsm": "tsm-2"},
+		2: {"000000001-000000001.tsm": "tsm-1"},
+	}
+
+	key, err := s.Backup("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := backup_util.LoadBlobManifest(s.Store, key)
+	if err != nil {
+		t.Fatal(err)
+	} else if m.Version != backup_util.BlobManifestVersion || m.Limited {
+		t.Fatalf("unexpected manifest: %+v", m)
+	} else if got, exp := manifestFiles(m), []string{
+		"db0/rp0/1/000000001-000000001.tsm",
+		"db0/rp0/1/000000002-000000001.tsm",
+		"db0/rp1/2/000000001-000000001.tsm",
+	}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected files:\n\ngot=%v\n\nexp=%v", got, exp)
+	}
+
+	// Identical files are stored once: the meta store and two distinct TSM files.
+	if got, exp := s.Store.puts, 3; got != exp {
+		t.Fatalf("unexpected number of uploads: got %d, exp %d", got, exp)
+	}
+
+	if got := readBlob(t, s.Store, m.Files[0].FileName); got != "tsm-1" {
+		t.Fatalf("unexpected content: %q", got)
+	}
+
+	// An incremental backup only uploads the files that changed.
+	s.Store.puts = 0
+	s.ShardFiles[1]["000000003-000000001.tsm"] = "tsm-3"
+
+	key2, err := s.Backup("", "")
+	if err != nil {
+		t.Fatal(err)
+	} else if key2 <= key {
+		t.Fatalf("manifest keys out of order: %s <= %s", key2, key)
+	}
+	if got, exp := s.Store.puts, 1; got != exp {
+		t.Fatalf("unexpected number of uploads: got %d, exp %d", got, exp)
+	}
+
+	// Both backups can be restored from.
+	if keys, err := backup_util.BlobManifests(s.Store); err != nil {
+		t.Fatal(err)
+	} else if exp := []string{key, key2}; !reflect.DeepEqual(keys, exp) {
+		t.Fatalf("unexpected manifests: got %v, exp %v", keys, exp)
+	}
+}
+
+func TestService_Backup_RetentionPolicy(t *testing.T) {
+	s := NewService(backup.NewConfig())
+	defer s.Cleanup()
+
+	s.ShardFiles = map[uint64]map[string]string{
+		1: {"000000001-000000001.tsm": "tsm-1"},
+		2: {"000000001-000000001.tsm": "tsm-2"},
+	}
+
+	key, err := s.Backup("db0", "rp1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := backup_util.LoadBlobManifest(s.Store, key)
+	if err != nil {
+		t.Fatal(err)
+	} else if !m.Limited || m.Database != "db0" || m.Policy != "rp1" {
+		t.Fatalf("unexpected manifest: %+v", m)
+	} else if got, exp := manifestFiles(m), []string{"db0/rp1/2/000000001-000000001.tsm"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected files: got %v, exp %v", got, exp)
+	}
+
+	if _, err := s.Backup("db1", ""); err == nil || err.Error() != "database not found: db1" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+type Service struct {
+	MetaClient *MetaClient
+	TSDBStore  *internal.TSDBStoreMock
+	Store      *BlobStore
+
+	// ShardFiles holds the content of the files of each local shard.
+	ShardFiles map[uint64]map[string]string
+
+	LogBuf bytes.Buffer
+	*backup.Service
+
+	dir string
+}
+
+func NewService(c backup.Config) *Service {
+	dir, err := ioutil.TempDir("", "influxdb-backup-")
+	if err != nil {
+		panic(err)
+	}
+
+	s := &Service{
+		MetaClient: &MetaClient{},
+		TSDBStore:  &internal.TSDBStoreMock{},
+		Store:      &BlobStore{BlobStore: backup_util.NewFileBlobStore(filepath.Join(dir, "store"))},
+		Service:    backup.NewService(c),
+		dir:        dir,
+	}
+
+	l := logger.New(&s.LogBuf)
+	s.WithLogger(l)
+
+	s.TSDBStore.ShardFn = func(id uint64) *tsdb.Shard {
+		if _, ok := s.ShardFiles[id]; !ok {
+			return nil
+		}
+		return &tsdb.Shard{}
+	}
+	s.TSDBStore.CreateShardSnapshotFn = func(id uint64) (string, error) {
+		path, err := ioutil.TempDir(dir, "snapshot-")
+		if err != nil {
+			return "", err
+		}
+		for name, content := range s.ShardFiles[id] {
+			if err := ioutil.WriteFile(filepath.Join(path, name), []byte(content), 0666); err != nil {
+				return "", err
+			}
+		}
+		return path, nil
+	}
+
+	s.Service.MetaClient = s.MetaClient
+	s.Service.TSDBStore = s.TSDBStore
+	s.Service.BlobStore = s.Store
+	return s
+}
+
+// Cleanup removes the blob store and snapshots of the service.
+func (s *Service) Cleanup() {
+	os.RemoveAll(s.dir)
+}
+
+// MetaClient is a mockable meta client holding a single database.
+type MetaClient struct{}
+
+func (c *MetaClient) MarshalBinary() ([]byte, error) {
+	return (&meta.Data{Databases: c.Databases()}).MarshalBinary()
+}
+
+func (c *MetaClient) Databases() []meta.DatabaseInfo {
+	shardGroup := func(id uint64) meta.ShardGroupInfo {
+		return meta.ShardGroupInfo{
+			ID:        id,
+			StartTime: time.Unix(0, 0),
+			EndTime:   time.Unix(0, 0).Add(time.Hour),
+			Shards:    []meta.ShardInfo{{ID: id}},
+		}
+	}
+	return []meta.DatabaseInfo{{
+		Name: "db0",
+		RetentionPolicies: []meta.RetentionPolicyInfo{
+			{Name: "rp0", ShardGroups: []meta.ShardGroupInfo{shardGroup(1), shardGroup(3)}},
+			{Name: "rp1", ShardGroups: []meta.ShardGroupInfo{shardGroup(2)}},
+		},
+	}}
+}
+
+// BlobStore counts the blobs put into the underlying store.
+type BlobStore struct {
+	backup_util.BlobStore
+	puts int
+}
+
+func (s *BlobStore) Put(key string, r io.Reader) error {
+	if strings.HasPrefix(key, backup_util.BlobPrefix) {
+		s.puts++
+	}
+	return s.BlobStore.Put(key, r)
+}
+
+// manifestFiles returns the sorted paths of the files listed in m.
+func manifestFiles(m *backup_util.Manifest) []string {
+	var a []string
+	for _, f := range m.Files {
+		a = append(a, filepath.ToSlash(filepath.Join(f.Database, f.Policy, strconv.FormatUint(f.ShardID, 10), f.Path)))
+	}
+	sort.Strings(a)
+	return a
+}
+
+func readBlob(t *testing.T, store backup_util.BlobStore, key string) string {
+	t.Helper()
+	rc, err := store.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	b, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
@@ -15,6 +15,7 @@ import (
 
 	"collectd.org/api"
 	"collectd.org/network"
+	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/logger"
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/services/meta"
@@ -31,6 +32,7 @@ const (
 	statBatchesTransmitted   = "batchesTx"
 	statPointsTransmitted    = "pointsTx"
 	statBatchesTransmitFail  = "batchesTxFail"
+	statPointsRateLimited    = "pointsRateLimited"
 	statDroppedPointsInvalid = "droppedPointsInvalid"
 )
 
@@ -292,6 +294,7 @@ type Statistics struct {
 	BatchesTransmitted   int64
 	PointsTransmitted    int64
 	BatchesTransmitFail  int64
+	PointsRateLimited    int64
 	InvalidDroppedPoints int64
 }
 
@@ -308,6 +311,7 @@ func (s *Service) Statistics(tags map[string]string) []models.Statistic {
 			statBatchesTransmitted:   atomic.LoadInt64(&s.stats.BatchesTransmitted),
 			statPointsTransmitted:    atomic.LoadInt64(&s.stats.PointsTransmitted),
 			statBatchesTransmitFail:  atomic.LoadInt64(&s.stats.BatchesTransmitFail),
+			statPointsRateLimited:    atomic.LoadInt64(&s.stats.PointsRateLimited),
 			statDroppedPointsInvalid: atomic.LoadInt64(&s.stats.InvalidDroppedPoints),
 		},
 	}}
@@ -403,6 +407,10 @@ func (s *Service) writePoints() {
 			if err := s.PointsWriter.WritePointsPrivileged(s.Config.Database, s.Config.RetentionPolicy, models.ConsistencyLevelAny, batch); err == nil {
 				atomic.AddInt64(&s.stats.BatchesTransmitted, 1)
 				atomic.AddInt64(&s.stats.PointsTransmitted, int64(len(batch)))
+			} else if _, ok := err.(influxdb.RateLimitError); ok {
+				// Drop the batch rather than blocking the listener.
+				atomic.AddInt64(&s.stats.BatchesTransmitFail, 1)
+				atomic.AddInt64(&s.stats.PointsRateLimited, int64(len(batch)))
 			} else {
 				s.Logger.Info("Failed to write point batch to database",
 					logger.Database(s.Config.Database), zap.Error(err))
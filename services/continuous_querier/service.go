@@ -313,44 +313,69 @@ func (s *Service) ExecuteContinuousQuery(dbi *meta.DatabaseInfo, cqi *meta.Conti
 		return false, err
 	}
 
-	// See if this query needs to be run.
-	run, nextRun, err := cq.shouldRunContinuousQuery(now, interval)
-	if err != nil {
-		return false, err
-	} else if !run {
-		return false, nil
-	}
+	var startTime, endTime time.Time
+	if months := cq.q.GroupByCalendarInterval(); months > 0 {
+		// Calendar windows do not have a fixed length, so they are found from
+		// the calendar instead of truncating times by the interval.
+		opt := query.IteratorOptions{
+			Interval: query.Interval{Duration: interval, Offset: offset, Months: months},
+			Location: now.Location(),
+		}
+		run, nextRun, err := cq.shouldRunCalendarContinuousQuery(now, opt)
+		if err != nil {
+			return false, err
+		} else if !run {
+			return false, nil
+		}
 
-	resampleEvery := interval
-	if cq.Resample.Every != 0 {
-		resampleEvery = cq.Resample.Every
-	}
+		// Store the start of the current window as the last run and compute
+		// every window that ended since the next run.
+		start, _ := opt.Window(now.UnixNano())
+		cq.LastRun = time.Unix(0, start).In(now.Location())
+		s.lastRuns[id] = cq.LastRun
 
-	// We're about to run the query so store the current time closest to the nearest interval.
-	// If all is going well, this time should be the same as nextRun.
-	cq.LastRun = truncate(now.Add(-offset), resampleEvery).Add(offset)
-	s.lastRuns[id] = cq.LastRun
+		first, _ := opt.Window(nextRun.UnixNano() - 1)
+		startTime, endTime = time.Unix(0, first).In(now.Location()), cq.LastRun
+	} else {
+		// See if this query needs to be run.
+		run, nextRun, err := cq.shouldRunContinuousQuery(now, interval)
+		if err != nil {
+			return false, err
+		} else if !run {
+			return false, nil
+		}
 
-	// Retrieve the oldest interval we should calculate based on the next time
-	// interval. We do this instead of using the current time just in case any
-	// time intervals were missed. The start time of the oldest interval is what
-	// we use as the start time.
-	resampleFor := interval
-	if cq.Resample.For != 0 {
-		resampleFor = cq.Resample.For
-	} else if interval < resampleEvery {
-		resampleFor = resampleEvery
-	}
+		resampleEvery := interval
+		if cq.Resample.Every != 0 {
+			resampleEvery = cq.Resample.Every
+		}
 
-	// If the resample interval is greater than the interval of the query, use the
-	// query interval instead.
-	if interval < resampleEvery {
-		resampleEvery = interval
-	}
+		// We're about to run the query so store the current time closest to the nearest interval.
+		// If all is going well, this time should be the same as nextRun.
+		cq.LastRun = truncate(now.Add(-offset), resampleEvery).Add(offset)
+		s.lastRuns[id] = cq.LastRun
+
+		// Retrieve the oldest interval we should calculate based on the next time
+		// interval. We do this instead of using the current time just in case any
+		// time intervals were missed. The start time of the oldest interval is what
+		// we use as the start time.
+		resampleFor := interval
+		if cq.Resample.For != 0 {
+			resampleFor = cq.Resample.For
+		} else if interval < resampleEvery {
+			resampleFor = resampleEvery
+		}
+
+		// If the resample interval is greater than the interval of the query, use the
+		// query interval instead.
+		if interval < resampleEvery {
+			resampleEvery = interval
+		}
 
-	// Calculate and set the time range for the query.
-	startTime := truncate(nextRun.Add(interval-resampleFor-offset-1), interval).Add(offset)
-	endTime := truncate(now.Add(interval-resampleEvery-offset), interval).Add(offset)
+		// Calculate and set the time range for the query.
+		startTime = truncate(nextRun.Add(interval-resampleFor-offset-1), interval).Add(offset)
+		endTime = truncate(now.Add(interval-resampleEvery-offset), interval).Add(offset)
+	}
 	if !endTime.After(startTime) {
 		// Exit early since there is no time interval.
 		return false, nil
@@ -537,6 +562,26 @@ func (cq *ContinuousQuery) shouldRunContinuousQuery(now time.Time, interval time
 	return false, cq.LastRun, nil
 }
 
+// shouldRunCalendarContinuousQuery returns true if the calendar window the
+// continuous query last ran in has ended, and the time of the next run.
+func (cq *ContinuousQuery) shouldRunCalendarContinuousQuery(now time.Time, opt query.IteratorOptions) (bool, time.Time, error) {
+	// If it's not aggregated, do not run the query.
+	if cq.q.IsRawQuery {
+		return false, cq.LastRun, errors.New("continuous queries must be aggregate queries")
+	} else if cq.Resample.Every != 0 || cq.Resample.For != 0 {
+		return false, cq.LastRun, errors.New("continuous queries with calendar intervals do not support RESAMPLE")
+	}
+
+	// If the query never ran, execute it using the current time.
+	if !cq.HasRun {
+		return true, now, nil
+	}
+	if _, end := opt.Window(cq.LastRun.UnixNano()); end <= now.UnixNano() {
+		return true, time.Unix(0, end).In(now.Location()), nil
+	}
+	return false, cq.LastRun, nil
+}
+
 // assert will panic with a given formatted message if the given condition is false.
 func assert(condition bool, msg string, v ...interface{}) {
 	if !condition {
@@ -533,6 +533,32 @@ func TestExecuteContinuousQuery_TimeZone(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:    "Calendar/1mo",
+			d:       "1mo",
+			initial: mustParseTime(t, "2000-03-01T00:00:00-05:00"),
+			tests: []test{
+				{
+					start: mustParseTime(t, "2000-03-01T00:00:00-05:00"),
+					end:   mustParseTime(t, "2000-04-01T00:00:00-05:00"),
+				},
+				{
+					start: mustParseTime(t, "2000-04-01T00:00:00-05:00"),
+					end:   mustParseTime(t, "2000-05-01T00:00:00-04:00"),
+				},
+			},
+		},
+		{
+			name:    "Calendar/1y",
+			d:       "1y",
+			initial: mustParseTime(t, "2000-01-01T00:00:00-05:00"),
+			tests: []test{
+				{
+					start: mustParseTime(t, "2000-01-01T00:00:00-05:00"),
+					end:   mustParseTime(t, "2001-01-01T00:00:00-05:00"),
+				},
+			},
+		},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
 			s := NewTestService(t)
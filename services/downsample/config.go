@@ -0,0 +1,66 @@
+package downsample
+
+import (
+	"errors"
+	"time"
+
+	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/toml"
+)
+
+const (
+	// DefaultCheckInterval is the default interval at which the service looks
+	// for shard groups to downsample.
+	DefaultCheckInterval = 10 * time.Minute
+
+	// DefaultColdAfter is the default time a shard group must have ended
+	// before it is considered cold and can be downsampled.
+	DefaultColdAfter = time.Hour
+)
+
+// Config represents the configuration for the downsample service.
+type Config struct {
+	Enabled       bool          `toml:"enabled"`
+	CheckInterval toml.Duration `toml:"check-interval"`
+	ColdAfter     toml.Duration `toml:"cold-after"`
+}
+
+// NewConfig returns an instance of Config with defaults.
+func NewConfig() Config {
+	return Config{
+		Enabled:       true,
+		CheckInterval: toml.Duration(DefaultCheckInterval),
+		ColdAfter:     toml.Duration(DefaultColdAfter),
+	}
+}
+
+// Validate returns an error if the Config is invalid.
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if c.CheckInterval <= 0 {
+		return errors.New("check-interval must be positive")
+	}
+	if c.ColdAfter < 0 {
+		return errors.New("cold-after must not be negative")
+	}
+
+	return nil
+}
+
+// Diagnostics returns a diagnostics representation of a subset of the Config.
+func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
+	if !c.Enabled {
+		return diagnostics.RowFromMap(map[string]interface{}{
+			"enabled": false,
+		}), nil
+	}
+
+	return diagnostics.RowFromMap(map[string]interface{}{
+		"enabled":        true,
+		"check-interval": c.CheckInterval,
+		"cold-after":     c.ColdAfter,
+	}), nil
+}
@@ -0,0 +1,54 @@
+package downsample_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdata/influxdb/services/downsample"
+)
+
+func TestConfig_Parse(t *testing.T) {
+	// Parse configuration.
+	var c downsample.Config
+	if _, err := toml.Decode(`
+enabled = true
+check-interval = "1s"
+cold-after = "2h"
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate configuration.
+	if !c.Enabled {
+		t.Fatalf("unexpected enabled state: %v", c.Enabled)
+	} else if time.Duration(c.CheckInterval) != time.Second {
+		t.Fatalf("unexpected check interval: %v", c.CheckInterval)
+	} else if time.Duration(c.ColdAfter) != 2*time.Hour {
+		t.Fatalf("unexpected cold after: %v", c.ColdAfter)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	c := downsample.NewConfig()
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validation fail from NewConfig: %s", err)
+	}
+
+	c = downsample.NewConfig()
+	c.CheckInterval = 0
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for check-interval = 0, got nil")
+	}
+
+	c = downsample.NewConfig()
+	c.ColdAfter *= -1
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for negative cold-after, got nil")
+	}
+
+	c.Enabled = false
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validation fail from disabled config: %s", err)
+	}
+}
@@ -0,0 +1,283 @@
+// Package downsample provides the service that rolls cold shard groups up
+// into the downsampling tiers attached to retention policies.
+package downsample // import "github.com/influxdata/influxdb/services/downsample"
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/logger"
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxql"
+	"go.uber.org/zap"
+)
+
+// matchAll matches every measurement of a shard group.
+var matchAll = regexp.MustCompile(`.*`)
+
+// Service represents the downsampling service.
+type Service struct {
+	MetaClient interface {
+		Databases() []meta.DatabaseInfo
+		SetDownsampleProgress(database, policy, name string, shardGroupID uint64, t time.Time) error
+	}
+	TSDBStore interface {
+		Shard(id uint64) *tsdb.Shard
+		ShardGroup(ids []uint64) tsdb.ShardGroup
+	}
+	QueryExecutor interface {
+		ExecuteQuery(query *influxql.Query, opt query.ExecutionOptions, closing chan struct{}) <-chan *query.Result
+	}
+
+	config Config
+	wg     sync.WaitGroup
+	done   chan struct{}
+
+	logger *zap.Logger
+}
+
+// NewService returns a configured downsampling service.
+func NewService(c Config) *Service {
+	return &Service{
+		config: c,
+		logger: zap.NewNop(),
+	}
+}
+
+// Open starts downsampling.
+func (s *Service) Open() error {
+	if !s.config.Enabled || s.done != nil {
+		return nil
+	}
+
+	s.logger.Info("Starting downsample service",
+		logger.DurationLiteral("check_interval", time.Duration(s.config.CheckInterval)),
+		logger.DurationLiteral("cold_after", time.Duration(s.config.ColdAfter)))
+	s.done = make(chan struct{})
+
+	s.wg.Add(1)
+	go func() { defer s.wg.Done(); s.run() }()
+	return nil
+}
+
+// Close stops downsampling.
+func (s *Service) Close() error {
+	if !s.config.Enabled || s.done == nil {
+		return nil
+	}
+
+	s.logger.Info("Closing downsample service")
+	close(s.done)
+
+	s.wg.Wait()
+	s.done = nil
+	return nil
+}
+
+// WithLogger sets the logger on the service.
+func (s *Service) WithLogger(log *zap.Logger) {
+	s.logger = log.With(zap.String("service", "downsample"))
+}
+
+func (s *Service) run() {
+	ticker := time.NewTicker(time.Duration(s.config.CheckInterval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+
+		case <-ticker.C:
+			s.Run(time.Now().UTC())
+		}
+	}
+}
+
+// Run downsamples every shard group that is cold as of now and that has not
+// been downsampled since it was last written to. Progress is recorded in the
+// meta store so that shard groups are not processed again after a restart.
+func (s *Service) Run(now time.Time) {
+	log, logEnd := logger.NewOperation(s.logger, "Downsample check", "downsample_check")
+	defer logEnd()
+
+	var retryNeeded bool
+	for _, d := range s.MetaClient.Databases() {
+		for _, r := range d.RetentionPolicies {
+			for i := range r.Downsamples {
+				ds := &r.Downsamples[i]
+				for j := range r.ShardGroups {
+					g := &r.ShardGroups[j]
+					if !s.pending(ds, g, now) {
+						continue
+					}
+
+					select {
+					case <-s.done:
+						return
+					default:
+					}
+
+					// Take the completion time before reading so that writes
+					// made while downsampling cause the group to be processed again.
+					started := time.Now().UTC()
+					n, err := s.downsampleShardGroup(d.Name, r.Name, ds, g)
+					if err != nil {
+						log.Info("Failed to downsample shard group",
+							logger.Database(d.Name),
+							logger.RetentionPolicy(r.Name),
+							logger.ShardGroup(g.ID),
+							zap.String("downsample", ds.Name),
+							zap.Error(err))
+						retryNeeded = true
+						continue
+					}
+
+					if err := s.MetaClient.SetDownsampleProgress(d.Name, r.Name, ds.Name, g.ID, started); err != nil {
+						log.Info("Failed to record downsample progress",
+							logger.Database(d.Name),
+							logger.RetentionPolicy(r.Name),
+							logger.ShardGroup(g.ID),
+							zap.String("downsample", ds.Name),
+							zap.Error(err))
+						retryNeeded = true
+						continue
+					}
+
+					log.Info("Downsampled shard group",
+						logger.Database(d.Name),
+						logger.RetentionPolicy(r.Name),
+						logger.ShardGroup(g.ID),
+						zap.String("downsample", ds.Name),
+						zap.String("target_policy", ds.TargetPolicy),
+						zap.Int64("written", n))
+				}
+			}
+		}
+	}
+
+	if retryNeeded {
+		log.Info("One or more errors occurred during downsampling and will be retried on the next check", logger.DurationLiteral("check_interval", time.Duration(s.config.CheckInterval)))
+	}
+}
+
+// pending returns true if the shard group is cold and has either never been
+// downsampled or has been written to since it was last downsampled.
+func (s *Service) pending(ds *meta.DownsampleInfo, g *meta.ShardGroupInfo, now time.Time) bool {
+	if g.Deleted() || g.EndTime.Add(time.Duration(s.config.ColdAfter)).After(now) {
+		return false
+	}
+
+	p := ds.ShardGroupProgress(g.ID)
+	if p == nil {
+		return true
+	}
+
+	for _, sh := range g.Shards {
+		if shard := s.TSDBStore.Shard(sh.ID); shard != nil && shard.LastModified().After(p.CompletedAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// downsampleShardGroup aggregates every measurement of the shard group into
+// the target policy of the downsample. It returns the number of points written.
+func (s *Service) downsampleShardGroup(database, policy string, ds *meta.DownsampleInfo, g *meta.ShardGroupInfo) (int64, error) {
+	ids := make([]uint64, len(g.Shards))
+	for i, sh := range g.Shards {
+		ids[i] = sh.ID
+	}
+	sg := s.TSDBStore.ShardGroup(ids)
+
+	names := sg.MeasurementsByRegex(matchAll)
+	sort.Strings(names)
+
+	var written int64
+	for _, name := range names {
+		fields, _, err := sg.FieldDimensions([]string{name})
+		if err != nil {
+			return written, err
+		}
+
+		stmt := SelectStatement(database, policy, name, ds, fields)
+		if stmt == nil {
+			continue
+		}
+		if err := stmt.SetTimeRange(g.StartTime, g.EndTime); err != nil {
+			return written, err
+		}
+
+		n, err := s.execute(database, stmt)
+		written += n
+		if err != nil {
+			return written, fmt.Errorf("measurement %s: %s", name, err)
+		}
+	}
+	return written, nil
+}
+
+// execute runs a SELECT ... INTO statement and returns the number of points written.
+func (s *Service) execute(database string, stmt *influxql.SelectStatement) (int64, error) {
+	closing := make(chan struct{})
+	defer close(closing)
+
+	q := &influxql.Query{Statements: influxql.Statements{stmt}}
+	var written int64
+	for res := range s.QueryExecutor.ExecuteQuery(q, query.ExecutionOptions{Database: database}, closing) {
+		if res.Err != nil {
+			return written, res.Err
+		}
+		if len(res.Series) == 1 && len(res.Series[0].Values) == 1 {
+			if n, ok := res.Series[0].Values[0][1].(int64); ok {
+				written += n
+			}
+		}
+	}
+	return written, nil
+}
+
+// SelectStatement returns the statement that downsamples a measurement of
+// policy into the target policy of ds. Fields keep their names and are
+// aggregated with the function configured for their type, and all tags are
+// preserved. It returns nil if none of the fields are downsampled.
+func SelectStatement(database, policy, name string, ds *meta.DownsampleInfo, fields map[string]influxql.DataType) *influxql.SelectStatement {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	stmt := &influxql.SelectStatement{
+		Target: &influxql.Target{
+			Measurement: &influxql.Measurement{Database: database, RetentionPolicy: ds.TargetPolicy, Name: name},
+		},
+		Sources: influxql.Sources{
+			&influxql.Measurement{Database: database, RetentionPolicy: policy, Name: name},
+		},
+		Dimensions: influxql.Dimensions{
+			{Expr: &influxql.Call{Name: "time", Args: []influxql.Expr{&influxql.DurationLiteral{Val: ds.Interval}}}},
+			{Expr: &influxql.Wildcard{}},
+		},
+		Fill: influxql.NoFill,
+	}
+	for _, k := range keys {
+		agg := ds.Aggregate(fields[k])
+		if agg == "" {
+			continue
+		}
+		stmt.Fields = append(stmt.Fields, &influxql.Field{
+			Expr:  &influxql.Call{Name: agg, Args: []influxql.Expr{&influxql.VarRef{Val: k, Type: fields[k]}}},
+			Alias: k,
+		})
+	}
+
+	if len(stmt.Fields) == 0 {
+		return nil
+	}
+	return stmt
+}
@@ -0,0 +1,249 @@
+package downsample_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/internal"
+	"github.com/influxdata/influxdb/logger"
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/services/downsample"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxql"
+)
+
+func TestService_OpenClose(t *testing.T) {
+	s := NewService(downsample.NewConfig())
+
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.LogBuf.String() == "" {
+		t.Fatal("service didn't log anything on open")
+	}
+
+	// Reopening is a no-op
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Re-closing is a no-op
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestService_Run(t *testing.T) {
+	now := time.Date(2019, 1, 10, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	shardGroup := func(id uint64, start time.Time) meta.ShardGroupInfo {
+		return meta.ShardGroupInfo{
+			ID:        id,
+			StartTime: start,
+			EndTime:   start.Add(day),
+			Shards:    []meta.ShardInfo{{ID: id}},
+		}
+	}
+
+	deleted := shardGroup(4, now.Add(-4*day))
+	deleted.DeletedAt = now.Add(-time.Hour)
+
+	s := NewService(downsample.NewConfig())
+	s.MetaClient.DatabasesFn = func() []meta.DatabaseInfo {
+		return []meta.DatabaseInfo{{
+			Name: "db0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{{
+				Name:               "autogen",
+				ShardGroupDuration: day,
+				ShardGroups: []meta.ShardGroupInfo{
+					shardGroup(1, now.Add(-2*day)), // cold
+					shardGroup(2, now.Add(-day)),   // still hot
+					shardGroup(3, now.Add(-3*day)), // already downsampled
+					deleted,
+				},
+				Downsamples: []meta.DownsampleInfo{{
+					Name:              "hourly",
+					TargetPolicy:      "rollup",
+					Interval:          time.Hour,
+					FloatAggregate:    "mean",
+					IntegerAggregate:  "max",
+					UnsignedAggregate: "mean",
+					BooleanAggregate:  "last",
+					StringAggregate:   "last",
+					Progress:          []meta.DownsampleProgressInfo{{ShardGroupID: 3, CompletedAt: now.Add(-day)}},
+				}},
+			}},
+		}}
+	}
+
+	var progress []uint64
+	s.MetaClient.SetDownsampleProgressFn = func(database, policy, name string, shardGroupID uint64, t time.Time) error {
+		if database != "db0" || policy != "autogen" || name != "hourly" {
+			return errors.New("unexpected downsample")
+		}
+		progress = append(progress, shardGroupID)
+		return nil
+	}
+
+	s.TSDBStore.ShardFn = func(id uint64) *tsdb.Shard { return nil }
+	s.TSDBStore.ShardGroupFn = func(ids []uint64) tsdb.ShardGroup {
+		if !reflect.DeepEqual(ids, []uint64{1}) {
+			t.Errorf("unexpected shard ids: %v", ids)
+		}
+		return &ShardGroup{Fields: map[string]map[string]influxql.DataType{
+			"mem": {"used": influxql.Integer, "ok": influxql.Boolean},
+			"cpu": {"value": influxql.Float, "host_name": influxql.String},
+		}}
+	}
+
+	var stmts []string
+	s.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, opt query.ExecutionOptions, closing chan struct{}) <-chan *query.Result {
+		if opt.Database != "db0" {
+			t.Errorf("unexpected database: %s", opt.Database)
+		}
+		stmts = append(stmts, q.String())
+		return results(&query.Result{})
+	}
+
+	s.Run(now)
+
+	if exp := []string{
+		`SELECT last(host_name::string) AS host_name, mean(value::float) AS value INTO db0.rollup.cpu FROM db0.autogen.cpu WHERE time >= '2019-01-08T00:00:00Z' AND time < '2019-01-09T00:00:00Z' GROUP BY time(1h), * fill(none)`,
+		`SELECT last(ok::boolean) AS ok, max(used::integer) AS used INTO db0.rollup.mem FROM db0.autogen.mem WHERE time >= '2019-01-08T00:00:00Z' AND time < '2019-01-09T00:00:00Z' GROUP BY time(1h), * fill(none)`,
+	}; !reflect.DeepEqual(stmts, exp) {
+		t.Fatalf("unexpected statements:\n\ngot=%s\n\nexp=%s", strings.Join(stmts, "\n"), strings.Join(exp, "\n"))
+	}
+
+	if exp := []uint64{1}; !reflect.DeepEqual(progress, exp) {
+		t.Fatalf("unexpected progress: got %v, exp %v", progress, exp)
+	}
+}
+
+func TestService_Run_QueryError(t *testing.T) {
+	now := time.Date(2019, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	s := NewService(downsample.NewConfig())
+	s.MetaClient.DatabasesFn = func() []meta.DatabaseInfo {
+		return []meta.DatabaseInfo{{
+			Name: "db0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{{
+				Name: "autogen",
+				ShardGroups: []meta.ShardGroupInfo{{
+					ID:        1,
+					StartTime: now.Add(-48 * time.Hour),
+					EndTime:   now.Add(-24 * time.Hour),
+					Shards:    []meta.ShardInfo{{ID: 1}},
+				}},
+				Downsamples: []meta.DownsampleInfo{{Name: "hourly", TargetPolicy: "rollup", Interval: time.Hour, FloatAggregate: "mean"}},
+			}},
+		}}
+	}
+	s.MetaClient.SetDownsampleProgressFn = func(database, policy, name string, shardGroupID uint64, t time.Time) error {
+		return errors.New("progress must not be recorded for a failed downsample")
+	}
+	s.TSDBStore.ShardGroupFn = func(ids []uint64) tsdb.ShardGroup {
+		return &ShardGroup{Fields: map[string]map[string]influxql.DataType{
+			"cpu": {"value": influxql.Float},
+		}}
+	}
+	s.QueryExecutor.ExecuteQueryFn = func(q *influxql.Query, opt query.ExecutionOptions, closing chan struct{}) <-chan *query.Result {
+		return results(&query.Result{Err: errors.New("marker")})
+	}
+
+	s.Run(now)
+
+	if got := s.LogBuf.String(); !strings.Contains(got, "Failed to downsample shard group") || !strings.Contains(got, "marker") {
+		t.Fatalf("expected failure to be logged, got %q", got)
+	} else if strings.Contains(got, "Failed to record downsample progress") {
+		t.Fatalf("unexpected progress recorded: %q", got)
+	}
+}
+
+func TestSelectStatement_NoFields(t *testing.T) {
+	ds := &meta.DownsampleInfo{Name: "hourly", TargetPolicy: "rollup", Interval: time.Hour, FloatAggregate: "mean"}
+	if stmt := downsample.SelectStatement("db0", "autogen", "cpu", ds, map[string]influxql.DataType{"host": influxql.String}); stmt != nil {
+		t.Fatalf("expected nil statement, got %s", stmt)
+	}
+}
+
+type Service struct {
+	MetaClient    *internal.MetaClientMock
+	TSDBStore     *internal.TSDBStoreMock
+	QueryExecutor *QueryExecutor
+
+	LogBuf bytes.Buffer
+	*downsample.Service
+}
+
+func NewService(c downsample.Config) *Service {
+	s := &Service{
+		MetaClient:    &internal.MetaClientMock{},
+		TSDBStore:     &internal.TSDBStoreMock{},
+		QueryExecutor: &QueryExecutor{},
+		Service:       downsample.NewService(c),
+	}
+
+	l := logger.New(&s.LogBuf)
+	s.WithLogger(l)
+
+	s.Service.MetaClient = s.MetaClient
+	s.Service.TSDBStore = s.TSDBStore
+	s.Service.QueryExecutor = s.QueryExecutor
+	return s
+}
+
+// QueryExecutor is a mockable query executor.
+type QueryExecutor struct {
+	ExecuteQueryFn func(q *influxql.Query, opt query.ExecutionOptions, closing chan struct{}) <-chan *query.Result
+}
+
+func (e *QueryExecutor) ExecuteQuery(q *influxql.Query, opt query.ExecutionOptions, closing chan struct{}) <-chan *query.Result {
+	return e.ExecuteQueryFn(q, opt, closing)
+}
+
+// ShardGroup is a shard group holding the given measurements and fields.
+type ShardGroup struct {
+	tsdb.ShardGroup
+	Fields map[string]map[string]influxql.DataType
+}
+
+func (sg *ShardGroup) MeasurementsByRegex(re *regexp.Regexp) []string {
+	var names []string
+	for name := range sg.Fields {
+		if re.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (sg *ShardGroup) FieldDimensions(measurements []string) (map[string]influxql.DataType, map[string]struct{}, error) {
+	fields := make(map[string]influxql.DataType)
+	for _, name := range measurements {
+		for k, typ := range sg.Fields[name] {
+			fields[k] = typ
+		}
+	}
+	return fields, nil, nil
+}
+
+// results returns a closed channel holding the given results.
+func results(a ...*query.Result) <-chan *query.Result {
+	ch := make(chan *query.Result, len(a))
+	for _, r := range a {
+		ch <- r
+	}
+	close(ch)
+	return ch
+}
@@ -11,6 +11,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/logger"
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/monitor/diagnostics"
@@ -30,6 +31,7 @@ const (
 	statBatchesTransmitted  = "batchesTx"
 	statPointsTransmitted   = "pointsTx"
 	statBatchesTransmitFail = "batchesTxFail"
+	statPointsRateLimited   = "pointsRateLimited"
 	statConnectionsActive   = "connsActive"
 	statConnectionsHandled  = "connsHandled"
 )
@@ -281,6 +283,7 @@ type Statistics struct {
 	BatchesTransmitted  int64
 	PointsTransmitted   int64
 	BatchesTransmitFail int64
+	PointsRateLimited   int64
 	ActiveConnections   int64
 	HandledConnections  int64
 }
@@ -298,6 +301,7 @@ func (s *Service) Statistics(tags map[string]string) []models.Statistic {
 			statBatchesTransmitted:  atomic.LoadInt64(&s.stats.BatchesTransmitted),
 			statPointsTransmitted:   atomic.LoadInt64(&s.stats.PointsTransmitted),
 			statBatchesTransmitFail: atomic.LoadInt64(&s.stats.BatchesTransmitFail),
+			statPointsRateLimited:   atomic.LoadInt64(&s.stats.PointsRateLimited),
 			statConnectionsActive:   atomic.LoadInt64(&s.stats.ActiveConnections),
 			statConnectionsHandled:  atomic.LoadInt64(&s.stats.HandledConnections),
 		},
@@ -461,6 +465,10 @@ func (s *Service) processBatches(batcher *tsdb.PointBatcher) {
 			if err := s.PointsWriter.WritePointsPrivileged(s.database, s.retentionPolicy, models.ConsistencyLevelAny, batch); err == nil {
 				atomic.AddInt64(&s.stats.BatchesTransmitted, 1)
 				atomic.AddInt64(&s.stats.PointsTransmitted, int64(len(batch)))
+			} else if _, ok := err.(influxdb.RateLimitError); ok {
+				// Drop the batch rather than blocking the listener.
+				atomic.AddInt64(&s.stats.BatchesTransmitFail, 1)
+				atomic.AddInt64(&s.stats.PointsRateLimited, int64(len(batch)))
 			} else {
 				s.logger.Info("Failed to write point batch to database",
 					logger.Database(s.database), zap.Error(err))
@@ -28,6 +28,7 @@ import (
 	"github.com/influxdata/flux/lang"
 	"github.com/influxdata/flux/mock"
 	"github.com/influxdata/flux/repl"
+	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/flux/client"
 	"github.com/influxdata/influxdb/internal"
 	"github.com/influxdata/influxdb/logger"
@@ -129,6 +130,13 @@ func TestHandler_Query_Auth(t *testing.T) {
 		return h.MetaClient.User(u)
 	}
 
+	h.MetaClient.AuthenticateTokenFn = func(token string) (meta.User, error) {
+		if token != "0123abcd" {
+			return nil, meta.ErrAuthenticate
+		}
+		return h.MetaClient.User("user1")
+	}
+
 	// Set mock query authorizer for handler to use.
 	h.QueryAuthorizer.AuthorizeQueryFn = func(u meta.User, query *influxql.Query, database string) error {
 		return nil
@@ -166,6 +174,26 @@ func TestHandler_Query_Auth(t *testing.T) {
 		t.Fatalf("unexpected body: %s", body)
 	}
 
+	// Test the handler with a valid API token.
+	w = httptest.NewRecorder()
+	r = MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil)
+	r.Header.Set("Authorization", "Token 0123abcd")
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	} else if body := strings.TrimSpace(w.Body.String()); body != `{"results":[{"statement_id":1,"series":[{"name":"series0"}]},{"statement_id":2,"series":[{"name":"series1"}]}]}` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+
+	// Test the handler with an unknown API token.
+	w = httptest.NewRecorder()
+	r = MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil)
+	r.Header.Set("Authorization", "Token 4567efgh")
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+
 	// Test the handler with valid JWT bearer token.
 	req := MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil)
 	// Create a signed JWT token string and add it to the request header.
@@ -266,6 +294,120 @@ func TestHandler_Query_Auth(t *testing.T) {
 	}
 }
 
+// Ensure authentication attempts are recorded with the auditor.
+func TestHandler_Query_Auth_Audit(t *testing.T) {
+	h := NewHandler(true)
+	h.MetaClient.AdminUserExistsFn = func() bool { return true }
+	h.MetaClient.AuthenticateFn = func(u, p string) (meta.User, error) {
+		if u != "user1" || p != "abcd" {
+			return nil, meta.ErrAuthenticate
+		}
+		return &meta.UserInfo{Name: "user1", Admin: true}, nil
+	}
+	h.QueryAuthorizer.AuthorizeQueryFn = func(u meta.User, query *influxql.Query, database string) error {
+		return nil
+	}
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx *query.ExecutionContext) error {
+		if ctx.UserID != "user1" {
+			t.Fatalf("unexpected user: %s", ctx.UserID)
+		}
+		ctx.Results <- &query.Result{StatementID: 1}
+		return nil
+	}
+
+	auditor := &HandlerAuditor{}
+	h.Handler.Auditor = auditor
+
+	req := MustNewJSONRequest("GET", "/query?u=user1&p=abcd&db=foo&q=SELECT+*+FROM+bar", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+
+	req = MustNewJSONRequest("GET", "/query?u=user1&p=wrong&db=foo&q=SELECT+*+FROM+bar", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+
+	if exp := []string{
+		"user1 password 127.0.0.1:1234 <nil>",
+		"user1 password 127.0.0.1:1234 authorization failed",
+	}; !reflect.DeepEqual(auditor.Events, exp) {
+		t.Fatalf("unexpected events:\n\nexp=%v\n\ngot=%v", exp, auditor.Events)
+	}
+}
+
+// Ensure the handler audits the statements rejected by the query authorizer.
+func TestHandler_Query_Unauthorized_Audit(t *testing.T) {
+	h := NewHandler(true)
+	h.MetaClient.AdminUserExistsFn = func() bool { return true }
+	h.MetaClient.AuthenticateFn = func(u, p string) (meta.User, error) {
+		return &meta.UserInfo{Name: "user1"}, nil
+	}
+	h.QueryAuthorizer.AuthorizeQueryFn = func(u meta.User, query *influxql.Query, database string) error {
+		return errors.New("marker")
+	}
+
+	auditor := &HandlerAuditor{}
+	h.Handler.Auditor = auditor
+
+	req := MustNewJSONRequest("GET", "/query?u=user1&p=abcd&db=foo&q=DROP+DATABASE+foo", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+
+	if exp := []string{
+		"user1 password 127.0.0.1:1234 <nil>",
+		"DROP DATABASE foo user1 foo marker",
+	}; !reflect.DeepEqual(auditor.Events, exp) {
+		t.Fatalf("unexpected events:\n\nexp=%v\n\ngot=%v", exp, auditor.Events)
+	}
+}
+
+// Ensure the handler rejects queries exceeding a quota with a 429.
+func TestHandler_Query_QuotaExceeded(t *testing.T) {
+	h := NewHandler(false)
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx *query.ExecutionContext) error {
+		ctx.Results <- &query.Result{StatementID: 0, Series: models.Rows([]*models.Row{{Name: "series0"}})}
+		return nil
+	}
+
+	quotas := query.NewQuotas()
+	quotas.DatabaseLimits.MaxBytesReturned = 1
+	h.QueryExecutor.TaskManager.Quotas = quotas
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	} else if w.Header().Get("Retry-After") == "" {
+		t.Fatal("expected Retry-After header")
+	} else if body := w.Body.String(); !strings.Contains(body, "max-bytes-returned") {
+		t.Fatalf("unexpected body: %s", body)
+	}
+
+	// Other databases are unaffected.
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=bar&q=SELECT+*+FROM+bar", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+}
+
 // Ensure the handler returns results from a query (including nil results).
 func TestHandler_QueryRegex(t *testing.T) {
 	h := NewHandler(false)
@@ -375,6 +517,277 @@ func TestHandler_Query_Chunked(t *testing.T) {
 	}
 }
 
+// Ensure the handler writes CSV results as they are received when chunking
+// isn't requested.
+func TestHandler_Query_CSV_Streamed(t *testing.T) {
+	h := NewHandler(false)
+	h.Config.MaxRowLimit = 3
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx *query.ExecutionContext) error {
+		for i := 0; i < 3; i++ {
+			ctx.Results <- &query.Result{StatementID: 1, Series: models.Rows([]*models.Row{{
+				Name:    "series0",
+				Columns: []string{"value"},
+				Values:  [][]interface{}{{int64(2 * i)}, {int64(2*i + 1)}},
+			}})}
+		}
+		return nil
+	}
+
+	w := httptest.NewRecorder()
+	req := MustNewRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil)
+	req.Header.Set("Accept", "text/csv")
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if w.Body.String() != `name,tags,value
+series0,,0
+series0,,1
+series0,,2
+` {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
+
+// Ensure the handler limits the number of rows of chunked responses.
+func TestHandler_Query_Chunked_MaxRowLimit(t *testing.T) {
+	h := NewHandler(false)
+	h.Config.MaxRowLimit = 3
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx *query.ExecutionContext) error {
+		for i := 0; i < 3; i++ {
+			ctx.Results <- &query.Result{StatementID: 1, Series: models.Rows([]*models.Row{{
+				Name:    "series0",
+				Columns: []string{"value"},
+				Values:  [][]interface{}{{int64(2 * i)}, {int64(2*i + 1)}},
+				Partial: true,
+			}}), Partial: i < 2}
+		}
+		return nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar&chunked=true&chunk_size=2", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if w.Body.String() != `{"results":[{"statement_id":1,"series":[{"name":"series0","columns":["value"],"values":[[0],[1]],"partial":true}],"partial":true}]}
+{"results":[{"statement_id":1,"series":[{"name":"series0","columns":["value"],"values":[[2]],"partial":true}]}]}
+` {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
+
+// Ensure the handler writes the results of all statements of an Arrow
+// response to a single stream.
+func TestHandler_Query_Arrow(t *testing.T) {
+	h := NewHandler(false)
+	var statementID int
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx *query.ExecutionContext) error {
+		defer func() { statementID++ }()
+		switch statementID {
+		case 0:
+			ctx.Results <- &query.Result{StatementID: 0, Series: models.Rows([]*models.Row{{
+				Name:    "cpu",
+				Columns: []string{"time", "value"},
+				Values:  [][]interface{}{{time.Unix(0, 10), nil}},
+			}})}
+			ctx.Results <- &query.Result{StatementID: 0, Series: models.Rows([]*models.Row{{
+				Name:    "cpu",
+				Columns: []string{"time", "value"},
+				Values:  [][]interface{}{{time.Unix(0, 20), float64(2.5)}},
+			}})}
+		case 1:
+			ctx.Results <- &query.Result{StatementID: 1, Series: models.Rows([]*models.Row{{
+				Name:    "mem",
+				Columns: []string{"time", "free"},
+				Values:  [][]interface{}{{time.Unix(0, 10), int64(8)}},
+			}})}
+		}
+		return nil
+	}
+
+	w := httptest.NewRecorder()
+	req := MustNewRequest("GET", "/query?db=foo&q=SELECT+*+FROM+cpu%3BSELECT+*+FROM+mem", nil)
+	req.Header.Set("Accept", "application/vnd.apache.arrow.stream")
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	// The body is one stream with a schema and an end of stream marker.
+	body := w.Body.Bytes()
+	eos := []byte{0xFF, 0xFF, 0xFF, 0xFF, 0, 0, 0, 0}
+	if n := bytes.Count(body, eos); n != 1 || !bytes.HasSuffix(body, eos) {
+		t.Fatalf("expected a single stream, found %d end of stream markers", n)
+	} else if n := bytes.Count(body, []byte("statement_id")); n != 1 {
+		t.Fatalf("expected a single schema, found %d", n)
+	}
+
+	// The series of the second statement don't match the schema of the
+	// first, so they are written as an error.
+	if !bytes.Contains(body, []byte(`column "free" of series "mem" is not in the schema of the response`)) {
+		t.Fatalf("expected an error in the body: %q", body)
+	}
+}
+
+// Ensure the handler returns the id of chunked queries in a header.
+func TestHandler_Query_Chunked_QueryID(t *testing.T) {
+	h := NewHandler(false)
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx *query.ExecutionContext) error {
+		ctx.Results <- &query.Result{StatementID: 1, Series: models.Rows([]*models.Row{{Name: "series0"}})}
+		return nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar&chunked=true", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if got, want := w.Header().Get("X-Influxdb-Query-Id"), "1"; got != want {
+		t.Fatalf("unexpected query id: got=%q want=%q", got, want)
+	}
+}
+
+// Ensure the progress of a running query can be retrieved and the query
+// cancelled over HTTP.
+func TestHandler_QueryProgress_Kill(t *testing.T) {
+	h := NewHandler(false)
+	started := make(chan uint64)
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx *query.ExecutionContext) error {
+		p := query.ProgressFromContext(ctx)
+		p.AddShards(2)
+		p.CompleteShard()
+		p.AddPointsScanned(5)
+
+		started <- ctx.QueryID
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar&chunked=true", nil))
+		done <- w
+	}()
+	qid := <-started
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", fmt.Sprintf("/query/%d", qid), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+	var info query.QueryInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if info.ID != qid || info.Query != "SELECT * FROM bar" || info.Database != "foo" {
+		t.Fatalf("unexpected query: %s", w.Body.String())
+	} else if info.ShardsCompleted != 1 || info.ShardN != 2 || info.PointN != 5 {
+		t.Fatalf("unexpected progress: %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("DELETE", fmt.Sprintf("/query/%d", qid), nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := <-done; !strings.Contains(w.Body.String(), query.ErrQueryInterrupted.Error()) {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", fmt.Sprintf("/query/%d", qid), nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// Ensure only admins can cancel the queries of other users.
+func TestHandler_KillQuery_Auth(t *testing.T) {
+	h := NewHandler(true)
+	h.MetaClient.AdminUserExistsFn = func() bool { return true }
+	h.MetaClient.AuthenticateFn = func(u, p string) (meta.User, error) {
+		if u != p {
+			return nil, meta.ErrAuthenticate
+		}
+		return &meta.UserInfo{Name: u, Admin: u == "admin"}, nil
+	}
+	h.QueryAuthorizer.AuthorizeQueryFn = func(u meta.User, query *influxql.Query, database string) error {
+		return nil
+	}
+	started := make(chan uint64)
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx *query.ExecutionContext) error {
+		started <- ctx.QueryID
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.ServeHTTP(httptest.NewRecorder(), MustNewJSONRequest("GET", "/query?u=user1&p=user1&db=foo&q=SELECT+*+FROM+bar", nil))
+	}()
+	qid := <-started
+
+	for _, tt := range []struct {
+		user string
+		code int
+	}{
+		{user: "user2", code: http.StatusForbidden},
+		{user: "user1", code: http.StatusOK},
+		{user: "admin", code: http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, MustNewJSONRequest("GET", fmt.Sprintf("/query/%d?u=%s&p=%s", qid, tt.user, tt.user), nil))
+		if w.Code != tt.code {
+			t.Fatalf("unexpected status for %s: %d: %s", tt.user, w.Code, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("DELETE", fmt.Sprintf("/query/%d?u=user2&p=user2", qid), nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("DELETE", fmt.Sprintf("/query/%d?u=admin&p=admin", qid), nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+	<-done
+}
+
+// Ensure the handler returns the profile of each statement when requested.
+func TestHandler_Query_Profile(t *testing.T) {
+	h := NewHandler(false)
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx *query.ExecutionContext) error {
+		if !ctx.Profile {
+			t.Error("expected the statement to be profiled")
+		}
+		return ctx.Send(&query.Result{Series: models.Rows([]*models.Row{{Name: "series0"}})})
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar&profile=true", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	var resp httpd.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if len(resp.Results) != 1 {
+		t.Fatalf("unexpected number of results: %d", len(resp.Results))
+	}
+	result := resp.Results[0]
+	if len(result.Series) != 1 || result.Series[0].Name != "series0" {
+		t.Fatalf("unexpected series: %s", w.Body.String())
+	} else if result.Profile == nil || result.Profile.Name != "statement" {
+		t.Fatalf("unexpected profile: %s", w.Body.String())
+	} else if _, ok := result.Profile.Fields["total_time"]; !ok {
+		t.Fatalf("expected the total time in the profile: %s", w.Body.String())
+	}
+}
+
 // Ensure the handler can accept an async query.
 func TestHandler_Query_Async(t *testing.T) {
 	done := make(chan struct{})
@@ -1354,6 +1767,64 @@ func TestHandler_Write_EntityTooLarge_ContentLength(t *testing.T) {
 	}
 }
 
+// Ensure the handler rejects writes exceeding a rate limit with a 429.
+func TestHandler_Write_RateLimited(t *testing.T) {
+	h := NewHandler(false)
+	h.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+		return &meta.DatabaseInfo{}
+	}
+	h.PointsWriter.WritePointsFn = func(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error {
+		return influxdb.RateLimitError{Reason: `database "foo" writes more than 1 points per second`, RetryAfter: 1500 * time.Millisecond}
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write?db=foo", strings.NewReader("cpu value=1")))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if got := w.Header().Get("Retry-After"); got != "2" {
+		t.Fatalf("unexpected Retry-After: %q", got)
+	}
+}
+
+// Ensure the handler bulk loads writes with mode=bulk and rejects unknown modes.
+func TestHandler_Write_Bulk(t *testing.T) {
+	h := NewHandler(false)
+	h.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+		return &meta.DatabaseInfo{}
+	}
+	h.PointsWriter.WritePointsFn = func(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error {
+		t.Error("unexpected write")
+		return nil
+	}
+
+	var called bool
+	h.PointsWriter.WritePointsBulkFn = func(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error {
+		if database != "foo" || retentionPolicy != "bar" {
+			t.Fatalf("unexpected target: %s.%s", database, retentionPolicy)
+		} else if len(points) != 2 {
+			t.Fatalf("unexpected points: %v", points)
+		}
+		called = true
+		return nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write?db=foo&rp=bar&mode=bulk", strings.NewReader("cpu value=1 1\ncpu value=2 2")))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if !called {
+		t.Fatal("expected bulk write")
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write?db=foo&mode=sorted", strings.NewReader("cpu value=1")))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if body := strings.TrimSpace(w.Body.String()); body != `{"error":"invalid mode \"sorted\""}` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
+
 func TestHandler_Write_SuppressLog(t *testing.T) {
 	var buf bytes.Buffer
 	c := httpd.NewConfig()
@@ -1685,6 +2156,19 @@ func NewHandlerWithConfig(config httpd.Config) *Handler {
 	return h
 }
 
+// HandlerAuditor records the authentication attempts and statements audited by a handler.
+type HandlerAuditor struct {
+	Events []string
+}
+
+func (a *HandlerAuditor) AuditAuthentication(username, method, remoteAddr string, err error) {
+	a.Events = append(a.Events, fmt.Sprintf("%s %s %s %v", username, method, remoteAddr, err))
+}
+
+func (a *HandlerAuditor) AuditStatement(stmt influxql.Statement, user, database string, err error) {
+	a.Events = append(a.Events, fmt.Sprintf("%s %s %s %v", stmt, user, database, err))
+}
+
 // HandlerStatementExecutor is a mock implementation of Handler.StatementExecutor.
 type HandlerStatementExecutor struct {
 	ExecuteStatementFn func(stmt influxql.Statement, ctx *query.ExecutionContext) error
@@ -1704,13 +2188,18 @@ func (a *HandlerQueryAuthorizer) AuthorizeQuery(u meta.User, query *influxql.Que
 }
 
 type HandlerPointsWriter struct {
-	WritePointsFn func(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error
+	WritePointsFn     func(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error
+	WritePointsBulkFn func(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error
 }
 
 func (h *HandlerPointsWriter) WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error {
 	return h.WritePointsFn(database, retentionPolicy, consistencyLevel, user, points)
 }
 
+func (h *HandlerPointsWriter) WritePointsBulk(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error {
+	return h.WritePointsBulkFn(database, retentionPolicy, consistencyLevel, user, points)
+}
+
 // MustNewRequest returns a new HTTP request. Panic on error.
 func MustNewRequest(method, urlStr string, body io.Reader) *http.Request {
 	r, err := http.NewRequest(method, urlStr, body)
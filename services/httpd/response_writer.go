@@ -3,12 +3,17 @@ package httpd
 import (
 	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"sort"
 	"strconv"
 	"time"
 
 	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/arrowipc"
+	"github.com/influxdata/influxdb/query"
 	"github.com/tinylib/msgp/msgp"
 )
 
@@ -32,6 +37,9 @@ func NewResponseWriter(w http.ResponseWriter, r *http.Request) ResponseWriter {
 	case "application/x-msgpack":
 		w.Header().Add("Content-Type", "application/x-msgpack")
 		rw.formatter = &msgpackFormatter{}
+	case "application/vnd.apache.arrow.stream":
+		w.Header().Add("Content-Type", "application/vnd.apache.arrow.stream")
+		rw.formatter = &arrowFormatter{epoch: r.FormValue("epoch")}
 	case "application/json":
 		fallthrough
 	default:
@@ -72,6 +80,38 @@ func (w *responseWriter) WriteResponse(resp Response) (int, error) {
 	return writer.n, err
 }
 
+// streamsResults returns true if the results of a statement do not need to
+// be merged before being written, so that they can be written as they are
+// received instead of being buffered in memory.
+func streamsResults(w ResponseWriter) bool {
+	rw, ok := w.(*responseWriter)
+	if !ok {
+		return false
+	}
+	switch rw.formatter.(type) {
+	case *csvFormatter, *arrowFormatter:
+		return true
+	}
+	return false
+}
+
+// closeResponse ends a response whose results were written as they were
+// received. Formats which write all results of a response as one stream,
+// like Arrow, end the stream after the last result.
+func closeResponse(w ResponseWriter) (int, error) {
+	rw, ok := w.(*responseWriter)
+	if !ok {
+		return 0, nil
+	}
+	f, ok := rw.formatter.(*arrowFormatter)
+	if !ok {
+		return 0, nil
+	}
+	writer := bytesCountWriter{w: rw.ResponseWriter}
+	err := f.finish(&writer)
+	return writer.n, err
+}
+
 // Flush flushes the ResponseWriter if it has a Flush() method.
 func (w *responseWriter) Flush() {
 	if w, ok := w.ResponseWriter.(http.Flusher); ok {
@@ -112,6 +152,7 @@ func (f *jsonFormatter) WriteResponse(w io.Writer, resp Response) (err error) {
 
 type csvFormatter struct {
 	statementID int
+	header      []string
 	columns     []string
 }
 
@@ -146,17 +187,20 @@ func (f *csvFormatter) WriteResponse(w io.Writer, resp Response) (err error) {
 			f.statementID = result.StatementID
 
 			// Print out the column headers from the first series.
-			f.columns = make([]string, 2+len(result.Series[0].Columns))
+			f.header = result.Series[0].Columns
+			f.columns = make([]string, 2+len(f.header))
 			f.columns[0] = "name"
 			f.columns[1] = "tags"
-			copy(f.columns[2:], result.Series[0].Columns)
+			copy(f.columns[2:], f.header)
 			if err := csv.Write(f.columns); err != nil {
 				return err
 			}
 		}
 
-		for i, row := range result.Series {
-			if i > 0 && !stringsEqual(result.Series[i-1].Columns, row.Columns) {
+		for _, row := range result.Series {
+			// Results of a statement can be written in several chunks, so
+			// compare with the header instead of the previous series.
+			if !stringsEqual(f.header, row.Columns) {
 				// The columns have changed. Print a newline and reprint the header.
 				csv.Flush()
 				if err := csv.Error(); err != nil {
@@ -167,6 +211,7 @@ func (f *csvFormatter) WriteResponse(w io.Writer, resp Response) (err error) {
 					return err
 				}
 
+				f.header = row.Columns
 				f.columns = make([]string, 2+len(row.Columns))
 				f.columns[0] = "name"
 				f.columns[1] = "tags"
@@ -320,6 +365,356 @@ func (f *msgpackFormatter) WriteResponse(w io.Writer, resp Response) (err error)
 	return nil
 }
 
+// arrowFormatter writes the results of a response as a single Apache Arrow
+// IPC stream, so that they can be read with any stream reader. The schema of
+// the stream is taken from the first result with series: a column for the
+// statement id, a column for errors, a column for the measurement name, a
+// dictionary encoded column for each tag and a column for each column of the
+// series. Series of later results are written to the columns with the same
+// names and errors are written as rows with only a statement id and an
+// error. Since the schema of a stream can't change, series with tags or
+// columns missing from the schema, or with values which can't be converted
+// to the types of their columns, are written as errors.
+type arrowFormatter struct {
+	epoch string
+
+	// Writer of the current response, which the stream writes to.
+	w      io.Writer
+	stream *arrowipc.StreamWriter
+
+	// Indexes of the fields of the tags and columns of the stream.
+	tags    map[string]int
+	columns map[string]int
+
+	// Errors which haven't been written to the stream yet.
+	errors []arrowError
+	closed bool
+}
+
+// arrowError is an error of a statement, or of the response if it has no
+// statement id.
+type arrowError struct {
+	statementID interface{}
+	err         string
+}
+
+// Indexes of the fields every stream starts with.
+const (
+	arrowStatementIDField = iota
+	arrowErrorField
+	arrowNameField
+)
+
+// Write writes to the writer of the current response.
+func (f *arrowFormatter) Write(p []byte) (int, error) {
+	return f.w.Write(p)
+}
+
+func (f *arrowFormatter) WriteResponse(w io.Writer, resp Response) error {
+	f.w = w
+	if f.closed {
+		return errors.New("arrow stream of the response is closed")
+	}
+
+	// An error of the response ends it.
+	if resp.Err != nil {
+		f.errors = append(f.errors, arrowError{err: resp.Err.Error()})
+		return f.finish(w)
+	}
+
+	for _, result := range resp.Results {
+		if result.Err != nil {
+			f.errors = append(f.errors, arrowError{statementID: int64(result.StatementID), err: result.Err.Error()})
+			continue
+		}
+
+		// If there are no series in the result, skip past this result.
+		if len(result.Series) == 0 {
+			continue
+		}
+
+		if f.stream == nil {
+			f.start(result.Series)
+		}
+		if err := f.writeErrors(); err != nil {
+			return err
+		}
+		if err := f.writeResult(result); err != nil {
+			return err
+		}
+	}
+	return f.writeErrors()
+}
+
+// finish ends the stream after the last result of the response.
+func (f *arrowFormatter) finish(w io.Writer) error {
+	f.w = w
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+
+	if f.stream == nil {
+		f.start(nil)
+	}
+	if err := f.writeErrors(); err != nil {
+		return err
+	}
+	return f.stream.Close()
+}
+
+// start starts the stream with the fields needed to write the series of a
+// result. Columns mixing numeric types are written as floats and columns
+// mixing other types are written as strings. Columns without values are
+// written as strings, which values of any type can be converted to.
+func (f *arrowFormatter) start(series models.Rows) {
+	fields := []arrowipc.Field{
+		arrowStatementIDField: {Name: "statement_id", Type: arrowipc.Int64},
+		arrowErrorField:       {Name: "error", Type: arrowipc.String},
+		arrowNameField:        {Name: "name", Type: arrowipc.String, Dictionary: true},
+	}
+	f.tags = make(map[string]int)
+	f.columns = make(map[string]int)
+
+	var keys []string
+	for _, row := range series {
+		for k := range row.Tags {
+			if _, ok := f.tags[k]; !ok {
+				f.tags[k] = 0
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		f.tags[k] = len(fields)
+		fields = append(fields, arrowipc.Field{Name: k, Type: arrowipc.String, Dictionary: true})
+	}
+
+	for _, row := range series {
+		for i, col := range row.Columns {
+			typ := arrowColumnType(row.Values, i)
+			if idx, ok := f.columns[col]; ok {
+				fields[idx].Type = mergeArrowTypes(fields[idx].Type, typ)
+				continue
+			}
+			f.columns[col] = len(fields)
+			fields = append(fields, arrowipc.Field{Name: col, Type: typ})
+		}
+	}
+	for col, idx := range f.columns {
+		if fields[idx].Type == arrowipc.Null {
+			fields[idx].Type = arrowipc.String
+		} else if col == "time" {
+			f.setTimeUnit(&fields[idx])
+		}
+	}
+
+	f.stream = arrowipc.NewStreamWriter(f, fields)
+}
+
+// writeResult writes the series of a result as a record batch.
+func (f *arrowFormatter) writeResult(result *query.Result) error {
+	columns := make([][]interface{}, len(f.stream.Fields()))
+	for _, row := range result.Series {
+		var err error
+		if columns, err = f.appendSeries(columns, int64(result.StatementID), row); err != nil {
+			f.errors = append(f.errors, arrowError{statementID: int64(result.StatementID), err: err.Error()})
+		}
+	}
+	if len(columns[arrowStatementIDField]) == 0 {
+		return nil
+	}
+	return f.stream.WriteRecord(columns)
+}
+
+// appendSeries appends the rows of a series to the columns of a record
+// batch. If the series doesn't fit the schema of the stream, an error is
+// returned and nothing is appended.
+func (f *arrowFormatter) appendSeries(columns [][]interface{}, statementID int64, row *models.Row) ([][]interface{}, error) {
+	for k := range row.Tags {
+		if _, ok := f.tags[k]; !ok {
+			return columns, fmt.Errorf("tag %q of series %q is not in the schema of the response", k, row.Name)
+		}
+	}
+
+	fields := f.stream.Fields()
+	indexes := make([]int, len(row.Columns))
+	for i, col := range row.Columns {
+		idx, ok := f.columns[col]
+		if !ok {
+			return columns, fmt.Errorf("column %q of series %q is not in the schema of the response", col, row.Name)
+		}
+		indexes[i] = idx
+	}
+
+	records := make([][]interface{}, len(row.Values))
+	for j, values := range row.Values {
+		record := make([]interface{}, len(fields))
+		record[arrowStatementIDField] = statementID
+		record[arrowNameField] = row.Name
+		for k, v := range row.Tags {
+			record[f.tags[k]] = v
+		}
+		for i, v := range values {
+			field := fields[indexes[i]]
+			value, ok := arrowValue(field.Type, v)
+			if !ok {
+				return columns, fmt.Errorf("%T value of column %q of series %q can't be written as %s", v, field.Name, row.Name, field.Type)
+			}
+			record[indexes[i]] = value
+		}
+		records[j] = record
+	}
+
+	for _, record := range records {
+		columns = appendArrowRecord(columns, record)
+	}
+	return columns, nil
+}
+
+// writeErrors writes the errors which haven't been written yet as a record
+// batch. Errors are kept until the stream is started.
+func (f *arrowFormatter) writeErrors() error {
+	if f.stream == nil || len(f.errors) == 0 {
+		return nil
+	}
+	columns := make([][]interface{}, len(f.stream.Fields()))
+	for _, e := range f.errors {
+		record := make([]interface{}, len(columns))
+		record[arrowStatementIDField] = e.statementID
+		record[arrowErrorField] = e.err
+		columns = appendArrowRecord(columns, record)
+	}
+	f.errors = f.errors[:0]
+	return f.stream.WriteRecord(columns)
+}
+
+// setTimeUnit sets the unit of the time column. Times converted to an epoch
+// which isn't supported by Arrow are left as integers.
+func (f *arrowFormatter) setTimeUnit(field *arrowipc.Field) {
+	switch field.Type {
+	case arrowipc.Timestamp:
+		field.Unit = arrowipc.Nanosecond
+	case arrowipc.Int64:
+		switch f.epoch {
+		case "", "n", "ns":
+			field.Unit = arrowipc.Nanosecond
+		case "u":
+			field.Unit = arrowipc.Microsecond
+		case "ms":
+			field.Unit = arrowipc.Millisecond
+		case "s":
+			field.Unit = arrowipc.Second
+		default:
+			return
+		}
+		field.Type = arrowipc.Timestamp
+	default:
+		return
+	}
+	field.Timezone = "UTC"
+}
+
+// arrowColumnType returns the type of the values of a column.
+func arrowColumnType(values [][]interface{}, i int) arrowipc.Type {
+	typ := arrowipc.Null
+	for _, v := range values {
+		var t arrowipc.Type
+		switch v[i].(type) {
+		case float64:
+			t = arrowipc.Float64
+		case int64:
+			t = arrowipc.Int64
+		case uint64:
+			t = arrowipc.Uint64
+		case bool:
+			t = arrowipc.Bool
+		case string:
+			t = arrowipc.String
+		case time.Time:
+			t = arrowipc.Timestamp
+		default:
+			continue
+		}
+		typ = mergeArrowTypes(typ, t)
+	}
+	return typ
+}
+
+// mergeArrowTypes returns the type of a column with values of the types a
+// and b. Numeric types are merged into floats and other types into strings.
+func mergeArrowTypes(a, b arrowipc.Type) arrowipc.Type {
+	switch {
+	case a == arrowipc.Null:
+		return b
+	case b == arrowipc.Null, a == b:
+		return a
+	case isArrowNumeric(a) && isArrowNumeric(b):
+		return arrowipc.Float64
+	}
+	return arrowipc.String
+}
+
+func isArrowNumeric(t arrowipc.Type) bool {
+	return t == arrowipc.Float64 || t == arrowipc.Int64 || t == arrowipc.Uint64
+}
+
+// appendArrowRecord appends the values of a record to the columns of a
+// record batch.
+func appendArrowRecord(columns [][]interface{}, record []interface{}) [][]interface{} {
+	for i, v := range record {
+		columns[i] = append(columns[i], v)
+	}
+	return columns
+}
+
+// arrowValue converts a value to the type of its field. It returns false if
+// the value can't be converted.
+func arrowValue(typ arrowipc.Type, v interface{}) (interface{}, bool) {
+	if v == nil {
+		return nil, true
+	}
+	switch typ {
+	case arrowipc.Float64:
+		switch v := v.(type) {
+		case float64:
+			return v, true
+		case int64:
+			return float64(v), true
+		case uint64:
+			return float64(v), true
+		}
+	case arrowipc.Int64:
+		if v, ok := v.(int64); ok {
+			return v, true
+		}
+	case arrowipc.Uint64:
+		if v, ok := v.(uint64); ok {
+			return v, true
+		}
+	case arrowipc.Bool:
+		if v, ok := v.(bool); ok {
+			return v, true
+		}
+	case arrowipc.Timestamp:
+		switch v := v.(type) {
+		case time.Time:
+			return v.UnixNano(), true
+		case int64:
+			return v, true
+		}
+	case arrowipc.String:
+		switch v := v.(type) {
+		case string:
+			return v, true
+		case float64, int64, uint64, bool, time.Time:
+			return fmt.Sprint(v), true
+		}
+	}
+	return nil, false
+}
+
 func stringsEqual(a, b []string) bool {
 	if len(a) != len(b) {
 		return false
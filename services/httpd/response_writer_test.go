@@ -230,3 +230,85 @@ runtime,,amd64,8,darwin,go1.14.2
 		t.Errorf("unexpected output:\n\ngot=%v\nwant=%s", got, want)
 	}
 }
+
+func TestResponseWriter_CSV_Chunks(t *testing.T) {
+	header := make(http.Header)
+	header.Set("Accept", "text/csv")
+	r := &http.Request{
+		Header: header,
+		URL:    &url.URL{},
+	}
+	w := httptest.NewRecorder()
+
+	writer := httpd.NewResponseWriter(w, r)
+	for _, row := range []*models.Row{
+		{Name: "cpu", Columns: []string{"time", "value"}, Values: [][]interface{}{{time.Unix(0, 10), 1.5}}},
+		{Name: "cpu", Columns: []string{"time", "value"}, Values: [][]interface{}{{time.Unix(0, 20), 2.5}}},
+		{Name: "mem", Columns: []string{"time", "free"}, Values: [][]interface{}{{time.Unix(0, 10), int64(8)}}},
+	} {
+		if _, err := writer.WriteResponse(httpd.Response{
+			Results: []*query.Result{{StatementID: 0, Series: []*models.Row{row}}},
+		}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	if got, want := w.Body.String(), `name,tags,time,value
+cpu,,10,1.5
+cpu,,20,2.5
+
+name,tags,time,free
+mem,,10,8
+`; got != want {
+		t.Errorf("unexpected output:\n\ngot=%v\nwant=%s", got, want)
+	}
+}
+
+func TestResponseWriter_Arrow(t *testing.T) {
+	header := make(http.Header)
+	header.Set("Accept", "application/vnd.apache.arrow.stream")
+	r := &http.Request{
+		Header: header,
+		URL:    &url.URL{},
+	}
+	w := httptest.NewRecorder()
+
+	writer := httpd.NewResponseWriter(w, r)
+	if _, err := writer.WriteResponse(httpd.Response{
+		Results: []*query.Result{
+			{
+				StatementID: 0,
+				Series: []*models.Row{
+					{
+						Name:    "cpu",
+						Tags:    map[string]string{"host": "server01"},
+						Columns: []string{"time", "value", "count", "ok", "msg", "big"},
+						Values: [][]interface{}{
+							{time.Unix(0, 10), float64(2.5), int64(5), true, "foobar", uint64(math.MaxInt64 + 1)},
+							{time.Unix(0, 20), nil, nil, nil, nil, nil},
+						},
+					},
+				},
+			},
+			{
+				StatementID: 1,
+				Err:         fmt.Errorf("statement failed"),
+			},
+		},
+	}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if got, want := w.Header().Get("Content-Type"), "application/vnd.apache.arrow.stream"; got != want {
+		t.Fatalf("unexpected content type: got=%s want=%s", got, want)
+	}
+	body := w.Body.Bytes()
+	if !bytes.HasPrefix(body, []byte{0xFF, 0xFF, 0xFF, 0xFF}) {
+		t.Fatalf("expected the body to start with a message: %v", body)
+	}
+	for _, s := range []string{"host", "server01", "value", "foobar", "error", "statement failed"} {
+		if !bytes.Contains(body, []byte(s)) {
+			t.Errorf("expected %q in the body", s)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"bytes"
 	crand "crypto/rand"
 	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"io"
 	"io/ioutil"
@@ -20,6 +21,7 @@ import (
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/logger"
 	"github.com/influxdata/influxdb/pkg/file"
+	"github.com/influxdata/influxdb/query"
 	"github.com/influxdata/influxql"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
@@ -356,6 +358,96 @@ func (c *Client) UpdateRetentionPolicy(database, name string, rpu *RetentionPoli
 	return nil
 }
 
+// CreateRetentionRule adds a retention rule to a retention policy.
+func (c *Client) CreateRetentionRule(database, policy string, rule *RetentionRuleInfo) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.cacheData.Clone()
+
+	if err := data.CreateRetentionRule(database, policy, rule); err != nil {
+		return err
+	}
+
+	if err := c.commit(data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DropRetentionRule removes a retention rule from a retention policy.
+func (c *Client) DropRetentionRule(database, policy, name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.cacheData.Clone()
+
+	if err := data.DropRetentionRule(database, policy, name); err != nil {
+		return err
+	}
+
+	if err := c.commit(data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CreateDownsample adds a downsample to a retention policy.
+func (c *Client) CreateDownsample(database, policy string, ds *DownsampleInfo) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.cacheData.Clone()
+
+	if err := data.CreateDownsample(database, policy, ds); err != nil {
+		return err
+	}
+
+	if err := c.commit(data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DropDownsample removes a downsample from a retention policy.
+func (c *Client) DropDownsample(database, policy, name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.cacheData.Clone()
+
+	if err := data.DropDownsample(database, policy, name); err != nil {
+		return err
+	}
+
+	if err := c.commit(data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetDownsampleProgress records that a downsample finished processing a shard group at t.
+func (c *Client) SetDownsampleProgress(database, policy, name string, shardGroupID uint64, t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.cacheData.Clone()
+
+	if err := data.SetDownsampleProgress(database, policy, name, shardGroupID, t); err != nil {
+		return err
+	}
+
+	if err := c.commit(data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // Users returns a slice of UserInfo representing the currently known users.
 func (c *Client) Users() []UserInfo {
 	c.mu.RLock()
@@ -374,10 +466,8 @@ func (c *Client) User(name string) (User, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	for _, u := range c.cacheData.Users {
-		if u.Name == name {
-			return &u, nil
-		}
+	if u := c.cacheData.user(name); u != nil {
+		return c.cacheData.resolveUser(u), nil
 	}
 
 	return nil, ErrUserNotFound
@@ -550,7 +640,8 @@ func (c *Client) AdminUserExists() bool {
 func (c *Client) Authenticate(username, password string) (User, error) {
 	// Find user.
 	c.mu.RLock()
-	userInfo := c.cacheData.user(username)
+	data := c.cacheData
+	userInfo := data.user(username)
 	c.mu.RUnlock()
 	if userInfo == nil {
 		return nil, ErrUserNotFound
@@ -563,7 +654,7 @@ func (c *Client) Authenticate(username, password string) (User, error) {
 	if ok {
 		// verify the password using the cached salt and hash
 		if bytes.Equal(c.hashWithSalt(au.salt, password), au.hash) {
-			return userInfo, nil
+			return data.resolveUser(userInfo), nil
 		}
 
 		// fall through to requiring a full bcrypt hash for invalid passwords
@@ -582,7 +673,324 @@ func (c *Client) Authenticate(username, password string) (User, error) {
 	c.mu.Lock()
 	c.authCache[username] = authUser{salt: salt, hash: hashed, bhash: userInfo.Hash}
 	c.mu.Unlock()
-	return userInfo, nil
+	return data.resolveUser(userInfo), nil
+}
+
+// AuthenticateToken returns the user a token acts on behalf of, restricted to
+// read privileges.
+func (c *Client) AuthenticateToken(token string) (User, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ti := c.cacheData.tokenByHash(hashToken(token))
+	if ti == nil {
+		return nil, ErrAuthenticate
+	}
+
+	ui := c.cacheData.user(ti.User)
+	if ui == nil {
+		return nil, ErrAuthenticate
+	}
+
+	u := c.cacheData.resolveUser(ui)
+	u.readOnly = true
+	return u, nil
+}
+
+// hashToken returns the hash a token is stored as.
+func hashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
+// TokenBytes is the number of random bytes of a generated token.
+const TokenBytes = 32
+
+// CreateToken generates a new token acting on behalf of a user and returns
+// it. The token can't be retrieved afterwards.
+func (c *Client) CreateToken(name, username string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.cacheData.Clone()
+
+	b := make([]byte, TokenBytes)
+	if _, err := io.ReadFull(crand.Reader, b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	if err := data.CreateToken(name, username, hashToken(token)); err != nil {
+		return "", err
+	}
+
+	if err := c.commit(data); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// DropToken removes the token with the given name.
+func (c *Client) DropToken(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.cacheData.Clone()
+
+	if err := data.DropToken(name); err != nil {
+		return err
+	}
+
+	if err := c.commit(data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Tokens returns the tokens of all users.
+func (c *Client) Tokens() []TokenInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	tokens := c.cacheData.Tokens
+
+	if tokens == nil {
+		return []TokenInfo{}
+	}
+	return tokens
+}
+
+// SetQuota sets the query quota of a user or a database.
+func (c *Client) SetQuota(qi QuotaInfo) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.cacheData.Clone()
+
+	if err := data.SetQuota(qi); err != nil {
+		return err
+	}
+
+	if err := c.commit(data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DropQuota removes the query quota of a user or a database.
+func (c *Client) DropQuota(kind, name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.cacheData.Clone()
+
+	if err := data.DropQuota(kind, name); err != nil {
+		return err
+	}
+
+	if err := c.commit(data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Quotas returns the query quotas of all users and databases.
+func (c *Client) Quotas() []QuotaInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	quotas := c.cacheData.Quotas
+
+	if quotas == nil {
+		return []QuotaInfo{}
+	}
+	return quotas
+}
+
+// QueryQuota returns the limits of the query quota of a user or a database,
+// if one is set.
+func (c *Client) QueryQuota(kind, name string) (query.QuotaLimits, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	qi := c.cacheData.Quota(kind, name)
+	if qi == nil {
+		return query.QuotaLimits{}, false
+	}
+	return qi.Limits(), true
+}
+
+// Roles returns the known roles.
+func (c *Client) Roles() []RoleInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	roles := c.cacheData.Roles
+
+	if roles == nil {
+		return []RoleInfo{}
+	}
+	return roles
+}
+
+// Role returns the role with the given name, or ErrRoleNotFound.
+func (c *Client) Role(name string) (*RoleInfo, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ri := c.cacheData.Role(name)
+	if ri == nil {
+		return nil, ErrRoleNotFound
+	}
+	return ri, nil
+}
+
+// CreateRole adds a role with the given name.
+func (c *Client) CreateRole(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.cacheData.Clone()
+
+	if err := data.CreateRole(name); err != nil {
+		return err
+	}
+
+	if err := c.commit(data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DropRole removes the role with the given name.
+func (c *Client) DropRole(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.cacheData.Clone()
+
+	if err := data.DropRole(name); err != nil {
+		return err
+	}
+
+	if err := c.commit(data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GrantRole gives a role to a user.
+func (c *Client) GrantRole(role, username string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.cacheData.Clone()
+
+	if err := data.GrantRole(role, username); err != nil {
+		return err
+	}
+
+	if err := c.commit(data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RevokeRole takes a role away from a user.
+func (c *Client) RevokeRole(role, username string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.cacheData.Clone()
+
+	if err := data.RevokeRole(role, username); err != nil {
+		return err
+	}
+
+	if err := c.commit(data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GrantUserMeasurement grants a privilege on a set of measurements to a user.
+func (c *Client) GrantUserMeasurement(username string, gi *GrantInfo) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.cacheData.Clone()
+
+	if err := data.GrantUserMeasurement(username, gi); err != nil {
+		return err
+	}
+
+	if err := c.commit(data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RevokeUserMeasurement revokes a privilege on a set of measurements from a user.
+func (c *Client) RevokeUserMeasurement(username string, gi *GrantInfo) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.cacheData.Clone()
+
+	if err := data.RevokeUserMeasurement(username, gi); err != nil {
+		return err
+	}
+
+	if err := c.commit(data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GrantRoleMeasurement grants a privilege on a set of measurements to a role.
+func (c *Client) GrantRoleMeasurement(role string, gi *GrantInfo) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.cacheData.Clone()
+
+	if err := data.GrantRoleMeasurement(role, gi); err != nil {
+		return err
+	}
+
+	if err := c.commit(data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RevokeRoleMeasurement revokes a privilege on a set of measurements from a role.
+func (c *Client) RevokeRoleMeasurement(role string, gi *GrantInfo) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.cacheData.Clone()
+
+	if err := data.RevokeRoleMeasurement(role, gi); err != nil {
+		return err
+	}
+
+	if err := c.commit(data); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 // UserCount returns the number of users stored.
@@ -12,6 +12,7 @@ import (
 
 	"github.com/influxdata/influxdb"
 
+	"github.com/influxdata/influxdb/query"
 	"github.com/influxdata/influxdb/services/meta"
 	"github.com/influxdata/influxql"
 )
@@ -758,6 +759,134 @@ func TestMetaClient_UpdateUser(t *testing.T) {
 	}
 }
 
+func TestMetaClient_Tokens(t *testing.T) {
+	t.Parallel()
+
+	d, c := newClient()
+	defer os.RemoveAll(d)
+	defer c.Close()
+
+	if _, err := c.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.CreateUser("fred", "supersecure", false); err != nil {
+		t.Fatal(err)
+	} else if err := c.CreateRole("readers"); err != nil {
+		t.Fatal(err)
+	} else if err := c.GrantRoleMeasurement("readers", &meta.GrantInfo{Database: "db0", Measurement: "^cpu$", Privilege: influxql.AllPrivileges}); err != nil {
+		t.Fatal(err)
+	} else if err := c.GrantRole("readers", "fred"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The user gets the grants of its roles.
+	u, err := c.Authenticate("fred", "supersecure")
+	if err != nil {
+		t.Fatal(err)
+	} else if !u.AuthorizeMeasurement(influxql.WritePrivilege, "db0", "autogen", []byte("cpu")) {
+		t.Fatal("expected user to be authorized to write cpu")
+	} else if u.AuthorizeMeasurement(influxql.ReadPrivilege, "db0", "autogen", []byte("mem")) {
+		t.Fatal("expected user not to be authorized to read mem")
+	}
+
+	token, err := c.CreateToken("grafana", "fred")
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := c.CreateToken("grafana", "fred"); err != meta.ErrTokenExists {
+		t.Fatalf("got %v, expected %v", err, meta.ErrTokenExists)
+	}
+
+	// Only the hash of the token is stored.
+	if tokens := c.Tokens(); len(tokens) != 1 || tokens[0].Hash == token {
+		t.Fatalf("unexpected tokens: %v", tokens)
+	}
+
+	// Tokens only allow reading.
+	u, err = c.AuthenticateToken(token)
+	if err != nil {
+		t.Fatal(err)
+	} else if got, exp := u.ID(), "fred"; got != exp {
+		t.Fatalf("got %s, expected %s", got, exp)
+	} else if !u.AuthorizeMeasurement(influxql.ReadPrivilege, "db0", "autogen", []byte("cpu")) {
+		t.Fatal("expected token to be authorized to read cpu")
+	} else if u.AuthorizeMeasurement(influxql.WritePrivilege, "db0", "autogen", []byte("cpu")) {
+		t.Fatal("expected token not to be authorized to write cpu")
+	}
+
+	if _, err := c.AuthenticateToken(token + "0"); err != meta.ErrAuthenticate {
+		t.Fatalf("got %v, expected %v", err, meta.ErrAuthenticate)
+	}
+
+	if err := c.DropToken("grafana"); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.AuthenticateToken(token); err != meta.ErrAuthenticate {
+		t.Fatalf("got %v, expected %v", err, meta.ErrAuthenticate)
+	}
+}
+
+func TestMetaClient_AuthorizeQuery_Grants(t *testing.T) {
+	t.Parallel()
+
+	d, c := newClient()
+	defer os.RemoveAll(d)
+	defer c.Close()
+
+	if _, err := c.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.CreateUser("fred", "supersecure", false); err != nil {
+		t.Fatal(err)
+	} else if err := c.GrantUserMeasurement("fred", &meta.GrantInfo{Database: "db0", RetentionPolicy: "autogen", Measurement: "^cpu$", Privilege: influxql.ReadPrivilege}); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := c.User("fred")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		s          string
+		authorized bool
+	}{
+		{s: `SELECT value FROM cpu`, authorized: true},
+		{s: `SELECT value FROM autogen.cpu`, authorized: true},
+		{s: `SELECT value FROM /c/`, authorized: true},
+		{s: `SHOW MEASUREMENTS`, authorized: true},
+		{s: `SHOW FIELD KEYS FROM cpu`, authorized: true},
+		{s: `SELECT value FROM mem`, authorized: false},
+		{s: `SELECT value FROM cpu, mem`, authorized: false},
+		{s: `SELECT value INTO cpu_copy FROM cpu`, authorized: false},
+		{s: `SHOW FIELD KEYS`, authorized: false},
+		{s: `DROP MEASUREMENT cpu`, authorized: false},
+		{s: `SELECT value FROM db1.autogen.cpu`, authorized: false},
+	} {
+		q, err := influxql.ParseQuery(tt.s)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := u.AuthorizeQuery("db0", q); tt.authorized && err != nil {
+			t.Errorf("%s: unexpected error: %s", tt.s, err)
+		} else if !tt.authorized && err == nil {
+			t.Errorf("%s: expected an authorization error", tt.s)
+		}
+	}
+
+	// A grant on some measurements doesn't authorize the whole database.
+	if u.AuthorizeDatabase(influxql.ReadPrivilege, "db0") {
+		t.Error("expected the database to be unauthorized")
+	} else if !u.AuthorizeMeasurement(influxql.ReadPrivilege, "db0", "", nil) {
+		t.Error("expected a measurement of the database to be authorized")
+	}
+
+	// Series are authorized against the grants on their retention policy, the
+	// default retention policy when it isn't given.
+	if !u.AuthorizeSeriesRead("db0", []byte("cpu"), nil) {
+		t.Error("expected series of the default retention policy to be authorized")
+	} else if query.AuthorizerForRetentionPolicy(u, "rp1").AuthorizeSeriesRead("db0", []byte("cpu"), nil) {
+		t.Error("expected series of another retention policy to be unauthorized")
+	}
+}
+
 func TestMetaClient_ContinuousQueries(t *testing.T) {
 	t.Parallel()
 
@@ -1,9 +1,11 @@
 package meta
 
 import (
+	"crypto/subtle"
 	"errors"
 	"net"
 	"net/url"
+	"regexp"
 	"sort"
 	"strings"
 	"sync"
@@ -34,6 +36,14 @@ const (
 
 	// MinRetentionPolicyDuration represents the minimum duration for a policy.
 	MinRetentionPolicyDuration = time.Hour
+
+	// DefaultDownsampleNumericAggregate is the default aggregate applied to
+	// float, integer and unsigned fields by a downsample.
+	DefaultDownsampleNumericAggregate = "mean"
+
+	// DefaultDownsampleAggregate is the default aggregate applied to boolean
+	// and string fields by a downsample.
+	DefaultDownsampleAggregate = "last"
 )
 
 // Data represents the top level collection of all metadata.
@@ -43,6 +53,9 @@ type Data struct {
 	ClusterID uint64
 	Databases []DatabaseInfo
 	Users     []UserInfo
+	Roles     []RoleInfo
+	Tokens    []TokenInfo
+	Quotas    []QuotaInfo
 
 	// adminUserExists provides a constant time mechanism for determining
 	// if there is at least one admin user.
@@ -100,6 +113,7 @@ func (data *Data) DropDatabase(name string) error {
 			for i := range data.Users {
 				delete(data.Users[i].Privileges, name)
 			}
+			data.DropQuota(query.DatabaseQuota, name)
 			break
 		}
 	}
@@ -263,6 +277,192 @@ func (data *Data) UpdateRetentionPolicy(database, name string, rpu *RetentionPol
 	return nil
 }
 
+// CreateRetentionRule adds a retention rule to a retention policy.
+// It returns an error if the rule is invalid or if a different rule with the
+// same name already exists on the policy.
+func (data *Data) CreateRetentionRule(database, policy string, rule *RetentionRuleInfo) error {
+	if rule == nil || rule.Name == "" {
+		return ErrRetentionRuleNameRequired
+	} else if rule.Measurement == "" && rule.TagKey == "" {
+		return ErrRetentionRuleFilterRequired
+	} else if rule.TagKey != "" && rule.TagPrefix == "" {
+		return ErrRetentionRuleTagPrefixRequired
+	} else if rule.Duration < MinRetentionPolicyDuration {
+		return ErrRetentionRuleDurationTooLow
+	} else if _, err := rule.MeasurementRegex(); err != nil {
+		return err
+	}
+
+	di := data.Database(database)
+	if di == nil {
+		return influxdb.ErrDatabaseNotFound(database)
+	}
+
+	rpi := di.RetentionPolicy(policy)
+	if rpi == nil {
+		return influxdb.ErrRetentionPolicyNotFound(policy)
+	}
+
+	// A rule that keeps data at least as long as the policy never deletes anything.
+	if rpi.Duration != 0 && rule.Duration >= rpi.Duration {
+		return ErrRetentionRuleDurationTooHigh
+	}
+
+	for i := range rpi.RetentionRules {
+		if rpi.RetentionRules[i].Name == rule.Name {
+			// Silently succeed if the rule is identical, otherwise assume the
+			// user is trying to overwrite an existing rule.
+			if rpi.RetentionRules[i] == *rule {
+				return nil
+			}
+			return ErrRetentionRuleExists
+		}
+	}
+
+	rpi.RetentionRules = append(rpi.RetentionRules, *rule)
+	return nil
+}
+
+// DropRetentionRule removes a retention rule from a retention policy.
+func (data *Data) DropRetentionRule(database, policy, name string) error {
+	di := data.Database(database)
+	if di == nil {
+		return influxdb.ErrDatabaseNotFound(database)
+	}
+
+	rpi := di.RetentionPolicy(policy)
+	if rpi == nil {
+		return influxdb.ErrRetentionPolicyNotFound(policy)
+	}
+
+	for i := range rpi.RetentionRules {
+		if rpi.RetentionRules[i].Name == name {
+			rpi.RetentionRules = append(rpi.RetentionRules[:i], rpi.RetentionRules[i+1:]...)
+			return nil
+		}
+	}
+	return ErrRetentionRuleNotFound
+}
+
+// CreateDownsample adds a downsample to a retention policy. Unset aggregates
+// are replaced with their defaults. It returns an error if the downsample is
+// invalid or if a different downsample with the same name already exists on
+// the policy.
+func (data *Data) CreateDownsample(database, policy string, ds *DownsampleInfo) error {
+	if ds == nil || ds.Name == "" {
+		return ErrDownsampleNameRequired
+	} else if ds.Interval <= 0 {
+		return ErrDownsampleIntervalRequired
+	} else if ds.TargetPolicy == policy {
+		return ErrDownsampleTargetInvalid
+	}
+
+	di := data.Database(database)
+	if di == nil {
+		return influxdb.ErrDatabaseNotFound(database)
+	}
+
+	rpi := di.RetentionPolicy(policy)
+	if rpi == nil {
+		return influxdb.ErrRetentionPolicyNotFound(policy)
+	} else if di.RetentionPolicy(ds.TargetPolicy) == nil {
+		return influxdb.ErrRetentionPolicyNotFound(ds.TargetPolicy)
+	}
+
+	// Windows must not straddle shard groups since each group is downsampled
+	// on its own.
+	if rpi.ShardGroupDuration%ds.Interval != 0 {
+		return ErrDownsampleIntervalInvalid
+	}
+
+	other := ds.clone()
+	other.Progress = nil
+	for _, typ := range downsampleFieldTypes {
+		agg := other.aggregate(typ)
+		if *agg == "" {
+			*agg = defaultDownsampleAggregate(typ)
+		}
+		if !validDownsampleAggregate(*agg, typ) {
+			return ErrInvalidDownsampleAggregate(*agg, typ)
+		}
+	}
+
+	for i := range rpi.Downsamples {
+		if rpi.Downsamples[i].Name == other.Name {
+			// Silently succeed if the spec is identical, otherwise assume the
+			// user is trying to overwrite an existing downsample.
+			if rpi.Downsamples[i].sameSpec(&other) {
+				return nil
+			}
+			return ErrDownsampleExists
+		}
+	}
+
+	rpi.Downsamples = append(rpi.Downsamples, other)
+	return nil
+}
+
+// DropDownsample removes a downsample from a retention policy.
+func (data *Data) DropDownsample(database, policy, name string) error {
+	di := data.Database(database)
+	if di == nil {
+		return influxdb.ErrDatabaseNotFound(database)
+	}
+
+	rpi := di.RetentionPolicy(policy)
+	if rpi == nil {
+		return influxdb.ErrRetentionPolicyNotFound(policy)
+	}
+
+	for i := range rpi.Downsamples {
+		if rpi.Downsamples[i].Name == name {
+			rpi.Downsamples = append(rpi.Downsamples[:i], rpi.Downsamples[i+1:]...)
+			return nil
+		}
+	}
+	return ErrDownsampleNotFound
+}
+
+// SetDownsampleProgress records that a downsample has processed the shard
+// group with the given ID as of t. Progress recorded for shard groups that
+// no longer exist is discarded.
+func (data *Data) SetDownsampleProgress(database, policy, name string, shardGroupID uint64, t time.Time) error {
+	di := data.Database(database)
+	if di == nil {
+		return influxdb.ErrDatabaseNotFound(database)
+	}
+
+	rpi := di.RetentionPolicy(policy)
+	if rpi == nil {
+		return influxdb.ErrRetentionPolicyNotFound(policy)
+	}
+
+	ds := rpi.Downsample(name)
+	if ds == nil {
+		return ErrDownsampleNotFound
+	}
+
+	sgi := rpi.shardGroupByID(shardGroupID)
+	if sgi == nil || sgi.Deleted() {
+		return ErrShardGroupNotFound
+	}
+
+	progress := ds.Progress[:0]
+	for _, p := range ds.Progress {
+		if p.ShardGroupID == shardGroupID {
+			continue
+		}
+		if sgi := rpi.shardGroupByID(p.ShardGroupID); sgi != nil && !sgi.Deleted() {
+			progress = append(progress, p)
+		}
+	}
+	ds.Progress = append(progress, DownsampleProgressInfo{
+		ShardGroupID: shardGroupID,
+		CompletedAt:  t.UTC(),
+	})
+	return nil
+}
+
 // DropShard removes a shard by ID.
 //
 // DropShard won't return an error if the shard can't be found, which
@@ -572,6 +772,16 @@ func (data *Data) DropUser(name string) error {
 			wasAdmin := data.Users[i].Admin
 			data.Users = append(data.Users[:i], data.Users[i+1:]...)
 
+			// Tokens can't outlive the user they act on behalf of.
+			tokens := data.Tokens[:0]
+			for _, ti := range data.Tokens {
+				if ti.User != name {
+					tokens = append(tokens, ti)
+				}
+			}
+			data.Tokens = tokens
+			data.DropQuota(query.UserQuota, name)
+
 			// Maybe we dropped the only admin user?
 			if wasAdmin {
 				data.adminUserExists = data.hasAdminUser()
@@ -672,12 +882,325 @@ func (data *Data) UserPrivilege(name, database string) (*influxql.Privilege, err
 	return influxql.NewPrivilege(influxql.NoPrivileges), nil
 }
 
+// resolveUser returns a copy of the user carrying the grants of its roles, as
+// needed to authorize it.
+func (data *Data) resolveUser(ui *UserInfo) *UserInfo {
+	other := ui.clone()
+	for _, name := range ui.Roles {
+		if ri := data.role(name); ri != nil {
+			other.roleGrants = append(other.roleGrants, ri.Grants...)
+		}
+	}
+	other.data = data
+	return &other
+}
+
+func (data *Data) role(name string) *RoleInfo {
+	for i := range data.Roles {
+		if data.Roles[i].Name == name {
+			return &data.Roles[i]
+		}
+	}
+	return nil
+}
+
+// Role returns a role by name.
+func (data *Data) Role(name string) *RoleInfo {
+	return data.role(name)
+}
+
+// CreateRole creates a new role.
+func (data *Data) CreateRole(name string) error {
+	if name == "" {
+		return ErrRoleNameRequired
+	} else if data.role(name) != nil {
+		return ErrRoleExists
+	}
+
+	data.Roles = append(data.Roles, RoleInfo{Name: name})
+	return nil
+}
+
+// DropRole removes a role and revokes it from every user.
+func (data *Data) DropRole(name string) error {
+	for i := range data.Roles {
+		if data.Roles[i].Name == name {
+			data.Roles = append(data.Roles[:i], data.Roles[i+1:]...)
+
+			for j := range data.Users {
+				data.Users[j].Roles = removeString(data.Users[j].Roles, name)
+			}
+			return nil
+		}
+	}
+	return ErrRoleNotFound
+}
+
+// CloneRoles returns a copy of the role infos.
+func (data *Data) CloneRoles() []RoleInfo {
+	if data.Roles == nil {
+		return nil
+	}
+	roles := make([]RoleInfo, len(data.Roles))
+	for i := range data.Roles {
+		roles[i] = data.Roles[i].clone()
+	}
+	return roles
+}
+
+// GrantRole adds a role to a user.
+func (data *Data) GrantRole(role, username string) error {
+	ui := data.user(username)
+	if ui == nil {
+		return ErrUserNotFound
+	} else if data.role(role) == nil {
+		return ErrRoleNotFound
+	}
+
+	for _, name := range ui.Roles {
+		if name == role {
+			return nil
+		}
+	}
+	ui.Roles = append(ui.Roles, role)
+	return nil
+}
+
+// RevokeRole removes a role from a user.
+func (data *Data) RevokeRole(role, username string) error {
+	ui := data.user(username)
+	if ui == nil {
+		return ErrUserNotFound
+	} else if data.role(role) == nil {
+		return ErrRoleNotFound
+	}
+
+	ui.Roles = removeString(ui.Roles, role)
+	return nil
+}
+
+// GrantUserMeasurement adds the privilege of a grant to the measurements it
+// covers for a user.
+func (data *Data) GrantUserMeasurement(username string, gi *GrantInfo) error {
+	ui := data.user(username)
+	if ui == nil {
+		return ErrUserNotFound
+	}
+
+	grants, err := data.addGrant(ui.Grants, gi)
+	if err != nil {
+		return err
+	}
+	ui.Grants = grants
+	return nil
+}
+
+// RevokeUserMeasurement removes the privilege of a grant from the measurements
+// it covers for a user.
+func (data *Data) RevokeUserMeasurement(username string, gi *GrantInfo) error {
+	ui := data.user(username)
+	if ui == nil {
+		return ErrUserNotFound
+	}
+
+	grants, err := revokeGrant(ui.Grants, gi)
+	if err != nil {
+		return err
+	}
+	ui.Grants = grants
+	return nil
+}
+
+// GrantRoleMeasurement adds the privilege of a grant to the measurements it
+// covers for a role.
+func (data *Data) GrantRoleMeasurement(role string, gi *GrantInfo) error {
+	ri := data.role(role)
+	if ri == nil {
+		return ErrRoleNotFound
+	}
+
+	grants, err := data.addGrant(ri.Grants, gi)
+	if err != nil {
+		return err
+	}
+	ri.Grants = grants
+	return nil
+}
+
+// RevokeRoleMeasurement removes the privilege of a grant from the measurements
+// it covers for a role.
+func (data *Data) RevokeRoleMeasurement(role string, gi *GrantInfo) error {
+	ri := data.role(role)
+	if ri == nil {
+		return ErrRoleNotFound
+	}
+
+	grants, err := revokeGrant(ri.Grants, gi)
+	if err != nil {
+		return err
+	}
+	ri.Grants = grants
+	return nil
+}
+
+// addGrant merges a grant into grants. The privileges of a grant covering the
+// same measurements are combined.
+func (data *Data) addGrant(grants []GrantInfo, gi *GrantInfo) ([]GrantInfo, error) {
+	if gi.Database == "" {
+		return nil, ErrGrantDatabaseRequired
+	}
+
+	di := data.Database(gi.Database)
+	if di == nil {
+		return nil, influxdb.ErrDatabaseNotFound(gi.Database)
+	} else if gi.RetentionPolicy != "" && di.RetentionPolicy(gi.RetentionPolicy) == nil {
+		return nil, influxdb.ErrRetentionPolicyNotFound(gi.RetentionPolicy)
+	}
+
+	if err := gi.compile(); err != nil {
+		return nil, err
+	}
+
+	for i := range grants {
+		if grants[i].sameScope(gi) {
+			grants[i].Privilege |= gi.Privilege
+			return grants, nil
+		}
+	}
+	return append(grants, *gi), nil
+}
+
+// revokeGrant removes the privilege of a grant from the grant of grants
+// covering the same measurements. The grant is removed once no privilege is
+// left.
+func revokeGrant(grants []GrantInfo, gi *GrantInfo) ([]GrantInfo, error) {
+	for i := range grants {
+		if !grants[i].sameScope(gi) {
+			continue
+		}
+
+		grants[i].Privilege &^= gi.Privilege
+		if grants[i].Privilege == influxql.NoPrivileges {
+			grants = append(grants[:i], grants[i+1:]...)
+		}
+		return grants, nil
+	}
+	return nil, ErrGrantNotFound
+}
+
+// removeString returns a without the occurrences of s.
+func removeString(a []string, s string) []string {
+	other := a[:0]
+	for _, x := range a {
+		if x != s {
+			other = append(other, x)
+		}
+	}
+	if len(other) == 0 {
+		return nil
+	}
+	return other
+}
+
+// CreateToken creates a new token acting on behalf of a user. Only the hash
+// of the token is stored.
+func (data *Data) CreateToken(name, username, hash string) error {
+	if name == "" {
+		return ErrTokenNameRequired
+	} else if data.user(username) == nil {
+		return ErrUserNotFound
+	}
+
+	for i := range data.Tokens {
+		if data.Tokens[i].Name == name {
+			return ErrTokenExists
+		}
+	}
+
+	data.Tokens = append(data.Tokens, TokenInfo{Name: name, User: username, Hash: hash})
+	return nil
+}
+
+// DropToken removes a token.
+func (data *Data) DropToken(name string) error {
+	for i := range data.Tokens {
+		if data.Tokens[i].Name == name {
+			data.Tokens = append(data.Tokens[:i], data.Tokens[i+1:]...)
+			return nil
+		}
+	}
+	return ErrTokenNotFound
+}
+
+// tokenByHash returns the token with the given hash.
+func (data *Data) tokenByHash(hash string) *TokenInfo {
+	for i := range data.Tokens {
+		if subtle.ConstantTimeCompare([]byte(data.Tokens[i].Hash), []byte(hash)) == 1 {
+			return &data.Tokens[i]
+		}
+	}
+	return nil
+}
+
+// Quota returns the quota of a user or a database.
+func (data *Data) Quota(kind, name string) *QuotaInfo {
+	for i := range data.Quotas {
+		if data.Quotas[i].Kind == kind && data.Quotas[i].Name == name {
+			return &data.Quotas[i]
+		}
+	}
+	return nil
+}
+
+// SetQuota sets the quota of a user or a database, replacing the previous one.
+func (data *Data) SetQuota(qi QuotaInfo) error {
+	switch qi.Kind {
+	case query.UserQuota:
+		if data.user(qi.Name) == nil {
+			return ErrUserNotFound
+		}
+	case query.DatabaseQuota:
+		if data.Database(qi.Name) == nil {
+			return influxdb.ErrDatabaseNotFound(qi.Name)
+		}
+	default:
+		return ErrInvalidQuotaKind
+	}
+
+	if other := data.Quota(qi.Kind, qi.Name); other != nil {
+		*other = qi
+		return nil
+	}
+	data.Quotas = append(data.Quotas, qi)
+	return nil
+}
+
+// DropQuota removes the quota of a user or a database.
+func (data *Data) DropQuota(kind, name string) error {
+	for i := range data.Quotas {
+		if data.Quotas[i].Kind == kind && data.Quotas[i].Name == name {
+			data.Quotas = append(data.Quotas[:i], data.Quotas[i+1:]...)
+			return nil
+		}
+	}
+	return ErrQuotaNotFound
+}
+
 // Clone returns a copy of data with a new version.
 func (data *Data) Clone() *Data {
 	other := *data
 
 	other.Databases = data.CloneDatabases()
 	other.Users = data.CloneUsers()
+	other.Roles = data.CloneRoles()
+	if data.Tokens != nil {
+		other.Tokens = make([]TokenInfo, len(data.Tokens))
+		copy(other.Tokens, data.Tokens)
+	}
+	if data.Quotas != nil {
+		other.Quotas = make([]QuotaInfo, len(data.Quotas))
+		copy(other.Quotas, data.Quotas)
+	}
 
 	return &other
 }
@@ -706,6 +1229,21 @@ func (data *Data) marshal() *internal.Data {
 		pb.Users[i] = data.Users[i].marshal()
 	}
 
+	pb.Roles = make([]*internal.RoleInfo, len(data.Roles))
+	for i := range data.Roles {
+		pb.Roles[i] = data.Roles[i].marshal()
+	}
+
+	pb.Tokens = make([]*internal.TokenInfo, len(data.Tokens))
+	for i := range data.Tokens {
+		pb.Tokens[i] = data.Tokens[i].marshal()
+	}
+
+	pb.Quotas = make([]*internal.QuotaInfo, len(data.Quotas))
+	for i := range data.Quotas {
+		pb.Quotas[i] = data.Quotas[i].marshal()
+	}
+
 	return pb
 }
 
@@ -728,6 +1266,30 @@ func (data *Data) unmarshal(pb *internal.Data) {
 		data.Users[i].unmarshal(x)
 	}
 
+	data.Roles = nil
+	if len(pb.GetRoles()) > 0 {
+		data.Roles = make([]RoleInfo, len(pb.GetRoles()))
+		for i, x := range pb.GetRoles() {
+			data.Roles[i].unmarshal(x)
+		}
+	}
+
+	data.Tokens = nil
+	if len(pb.GetTokens()) > 0 {
+		data.Tokens = make([]TokenInfo, len(pb.GetTokens()))
+		for i, x := range pb.GetTokens() {
+			data.Tokens[i].unmarshal(x)
+		}
+	}
+
+	data.Quotas = nil
+	if len(pb.GetQuotas()) > 0 {
+		data.Quotas = make([]QuotaInfo, len(pb.GetQuotas()))
+		for i, x := range pb.GetQuotas() {
+			data.Quotas[i].unmarshal(x)
+		}
+	}
+
 	// Exhaustively determine if there is an admin user. The marshalled cache
 	// value may not be correct.
 	data.adminUserExists = data.hasAdminUser()
@@ -1106,6 +1668,8 @@ type RetentionPolicyInfo struct {
 	ShardGroupDuration time.Duration
 	ShardGroups        []ShardGroupInfo
 	Subscriptions      []SubscriptionInfo
+	RetentionRules     []RetentionRuleInfo
+	Downsamples        []DownsampleInfo
 }
 
 // NewRetentionPolicyInfo returns a new instance of RetentionPolicyInfo
@@ -1172,6 +1736,27 @@ func (rpi *RetentionPolicyInfo) ExpiredShardGroups(t time.Time) []*ShardGroupInf
 	return groups
 }
 
+// Downsample returns the downsample by name, or nil if it doesn't exist.
+func (rpi *RetentionPolicyInfo) Downsample(name string) *DownsampleInfo {
+	for i := range rpi.Downsamples {
+		if rpi.Downsamples[i].Name == name {
+			return &rpi.Downsamples[i]
+		}
+	}
+	return nil
+}
+
+// shardGroupByID returns the shard group with the given ID, or nil if it
+// doesn't exist.
+func (rpi *RetentionPolicyInfo) shardGroupByID(id uint64) *ShardGroupInfo {
+	for i := range rpi.ShardGroups {
+		if rpi.ShardGroups[i].ID == id {
+			return &rpi.ShardGroups[i]
+		}
+	}
+	return nil
+}
+
 // DeletedShardGroups returns the Shard Groups which are marked as deleted.
 func (rpi *RetentionPolicyInfo) DeletedShardGroups() []*ShardGroupInfo {
 	var groups = make([]*ShardGroupInfo, 0)
@@ -1202,6 +1787,16 @@ func (rpi *RetentionPolicyInfo) marshal() *internal.RetentionPolicyInfo {
 		pb.Subscriptions[i] = sub.marshal()
 	}
 
+	pb.RetentionRules = make([]*internal.RetentionRuleInfo, len(rpi.RetentionRules))
+	for i, rule := range rpi.RetentionRules {
+		pb.RetentionRules[i] = rule.marshal()
+	}
+
+	pb.Downsamples = make([]*internal.DownsampleInfo, len(rpi.Downsamples))
+	for i := range rpi.Downsamples {
+		pb.Downsamples[i] = rpi.Downsamples[i].marshal()
+	}
+
 	return pb
 }
 
@@ -1224,6 +1819,18 @@ func (rpi *RetentionPolicyInfo) unmarshal(pb *internal.RetentionPolicyInfo) {
 			rpi.Subscriptions[i].unmarshal(x)
 		}
 	}
+	if len(pb.GetRetentionRules()) > 0 {
+		rpi.RetentionRules = make([]RetentionRuleInfo, len(pb.GetRetentionRules()))
+		for i, x := range pb.GetRetentionRules() {
+			rpi.RetentionRules[i].unmarshal(x)
+		}
+	}
+	if len(pb.GetDownsamples()) > 0 {
+		rpi.Downsamples = make([]DownsampleInfo, len(pb.GetDownsamples()))
+		for i, x := range pb.GetDownsamples() {
+			rpi.Downsamples[i].unmarshal(x)
+		}
+	}
 }
 
 // clone returns a deep copy of rpi.
@@ -1237,6 +1844,18 @@ func (rpi RetentionPolicyInfo) clone() RetentionPolicyInfo {
 		}
 	}
 
+	if rpi.RetentionRules != nil {
+		other.RetentionRules = make([]RetentionRuleInfo, len(rpi.RetentionRules))
+		copy(other.RetentionRules, rpi.RetentionRules)
+	}
+
+	if rpi.Downsamples != nil {
+		other.Downsamples = make([]DownsampleInfo, len(rpi.Downsamples))
+		for i := range rpi.Downsamples {
+			other.Downsamples[i] = rpi.Downsamples[i].clone()
+		}
+	}
+
 	return other
 }
 
@@ -1255,6 +1874,227 @@ func (rpi *RetentionPolicyInfo) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
+// RetentionRuleInfo represents a rule that expires a subset of the series in a
+// retention policy sooner than the policy's own duration. A series is matched
+// when its measurement matches the Measurement glob pattern and, if TagKey is
+// set, the value of TagKey starts with TagPrefix.
+type RetentionRuleInfo struct {
+	Name        string
+	Measurement string
+	TagKey      string
+	TagPrefix   string
+	Duration    time.Duration
+}
+
+// MeasurementRegex returns the measurement pattern of the rule as a regular
+// expression. The pattern supports the '*' and '?' wildcards. A nil regex is
+// returned if the rule matches every measurement.
+func (rri RetentionRuleInfo) MeasurementRegex() (*regexp.Regexp, error) {
+	if rri.Measurement == "" {
+		return nil, nil
+	}
+
+	var buf strings.Builder
+	buf.WriteString("^")
+	for _, r := range rri.Measurement {
+		switch r {
+		case '*':
+			buf.WriteString(".*")
+		case '?':
+			buf.WriteString(".")
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	buf.WriteString("$")
+	return regexp.Compile(buf.String())
+}
+
+// marshal serializes to a protobuf representation.
+func (rri RetentionRuleInfo) marshal() *internal.RetentionRuleInfo {
+	pb := &internal.RetentionRuleInfo{
+		Name:     proto.String(rri.Name),
+		Duration: proto.Int64(int64(rri.Duration)),
+	}
+	if rri.Measurement != "" {
+		pb.Measurement = proto.String(rri.Measurement)
+	}
+	if rri.TagKey != "" {
+		pb.TagKey = proto.String(rri.TagKey)
+		pb.TagPrefix = proto.String(rri.TagPrefix)
+	}
+	return pb
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (rri *RetentionRuleInfo) unmarshal(pb *internal.RetentionRuleInfo) {
+	rri.Name = pb.GetName()
+	rri.Measurement = pb.GetMeasurement()
+	rri.TagKey = pb.GetTagKey()
+	rri.TagPrefix = pb.GetTagPrefix()
+	rri.Duration = time.Duration(pb.GetDuration())
+}
+
+// downsampleFieldTypes lists the field types a downsample aggregates.
+var downsampleFieldTypes = []influxql.DataType{
+	influxql.Float,
+	influxql.Integer,
+	influxql.Unsigned,
+	influxql.Boolean,
+	influxql.String,
+}
+
+// DownsampleInfo represents a downsampling tier. Once a shard group of the
+// retention policy holding the downsample goes cold, its data is aggregated
+// into windows of Interval and written to TargetPolicy. Each field is
+// aggregated with the function configured for its type.
+type DownsampleInfo struct {
+	Name              string
+	TargetPolicy      string
+	Interval          time.Duration
+	FloatAggregate    string
+	IntegerAggregate  string
+	UnsignedAggregate string
+	BooleanAggregate  string
+	StringAggregate   string
+	Progress          []DownsampleProgressInfo
+}
+
+// Aggregate returns the aggregate applied to fields of the given type, or an
+// empty string if fields of that type are not downsampled.
+func (dsi *DownsampleInfo) Aggregate(typ influxql.DataType) string {
+	if agg := dsi.aggregate(typ); agg != nil {
+		return *agg
+	}
+	return ""
+}
+
+// ShardGroupProgress returns the progress recorded for a shard group, or nil
+// if the shard group has not been downsampled yet.
+func (dsi *DownsampleInfo) ShardGroupProgress(id uint64) *DownsampleProgressInfo {
+	for i := range dsi.Progress {
+		if dsi.Progress[i].ShardGroupID == id {
+			return &dsi.Progress[i]
+		}
+	}
+	return nil
+}
+
+func (dsi *DownsampleInfo) aggregate(typ influxql.DataType) *string {
+	switch typ {
+	case influxql.Float:
+		return &dsi.FloatAggregate
+	case influxql.Integer:
+		return &dsi.IntegerAggregate
+	case influxql.Unsigned:
+		return &dsi.UnsignedAggregate
+	case influxql.Boolean:
+		return &dsi.BooleanAggregate
+	case influxql.String:
+		return &dsi.StringAggregate
+	}
+	return nil
+}
+
+// sameSpec returns true if dsi and other describe the same downsample,
+// ignoring progress.
+func (dsi *DownsampleInfo) sameSpec(other *DownsampleInfo) bool {
+	return dsi.Name == other.Name &&
+		dsi.TargetPolicy == other.TargetPolicy &&
+		dsi.Interval == other.Interval &&
+		dsi.FloatAggregate == other.FloatAggregate &&
+		dsi.IntegerAggregate == other.IntegerAggregate &&
+		dsi.UnsignedAggregate == other.UnsignedAggregate &&
+		dsi.BooleanAggregate == other.BooleanAggregate &&
+		dsi.StringAggregate == other.StringAggregate
+}
+
+// clone returns a deep copy of dsi.
+func (dsi DownsampleInfo) clone() DownsampleInfo {
+	other := dsi
+	if dsi.Progress != nil {
+		other.Progress = make([]DownsampleProgressInfo, len(dsi.Progress))
+		copy(other.Progress, dsi.Progress)
+	}
+	return other
+}
+
+// marshal serializes to a protobuf representation.
+func (dsi *DownsampleInfo) marshal() *internal.DownsampleInfo {
+	pb := &internal.DownsampleInfo{
+		Name:              proto.String(dsi.Name),
+		TargetPolicy:      proto.String(dsi.TargetPolicy),
+		Interval:          proto.Int64(int64(dsi.Interval)),
+		FloatAggregate:    proto.String(dsi.FloatAggregate),
+		IntegerAggregate:  proto.String(dsi.IntegerAggregate),
+		UnsignedAggregate: proto.String(dsi.UnsignedAggregate),
+		BooleanAggregate:  proto.String(dsi.BooleanAggregate),
+		StringAggregate:   proto.String(dsi.StringAggregate),
+	}
+
+	pb.Progress = make([]*internal.DownsampleProgressInfo, len(dsi.Progress))
+	for i, p := range dsi.Progress {
+		pb.Progress[i] = &internal.DownsampleProgressInfo{
+			ShardGroupID: proto.Uint64(p.ShardGroupID),
+			CompletedAt:  proto.Int64(p.CompletedAt.UnixNano()),
+		}
+	}
+	return pb
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (dsi *DownsampleInfo) unmarshal(pb *internal.DownsampleInfo) {
+	dsi.Name = pb.GetName()
+	dsi.TargetPolicy = pb.GetTargetPolicy()
+	dsi.Interval = time.Duration(pb.GetInterval())
+	dsi.FloatAggregate = pb.GetFloatAggregate()
+	dsi.IntegerAggregate = pb.GetIntegerAggregate()
+	dsi.UnsignedAggregate = pb.GetUnsignedAggregate()
+	dsi.BooleanAggregate = pb.GetBooleanAggregate()
+	dsi.StringAggregate = pb.GetStringAggregate()
+
+	if len(pb.GetProgress()) > 0 {
+		dsi.Progress = make([]DownsampleProgressInfo, len(pb.GetProgress()))
+		for i, x := range pb.GetProgress() {
+			dsi.Progress[i] = DownsampleProgressInfo{
+				ShardGroupID: x.GetShardGroupID(),
+				CompletedAt:  time.Unix(0, x.GetCompletedAt()).UTC(),
+			}
+		}
+	}
+}
+
+// DownsampleProgressInfo records when a downsample last finished processing a
+// shard group. A shard group written to after CompletedAt is processed again.
+type DownsampleProgressInfo struct {
+	ShardGroupID uint64
+	CompletedAt  time.Time
+}
+
+// defaultDownsampleAggregate returns the aggregate used for fields of the
+// given type when a downsample does not specify one.
+func defaultDownsampleAggregate(typ influxql.DataType) string {
+	switch typ {
+	case influxql.Float, influxql.Integer, influxql.Unsigned:
+		return DefaultDownsampleNumericAggregate
+	}
+	return DefaultDownsampleAggregate
+}
+
+// validDownsampleAggregate returns true if the aggregate can be applied to
+// fields of the given type.
+func validDownsampleAggregate(aggregate string, typ influxql.DataType) bool {
+	switch aggregate {
+	case "count", "first", "last", "mode":
+		return true
+	case "min", "max":
+		return typ != influxql.String
+	case "mean", "median", "sum", "spread", "stddev":
+		return typ == influxql.Float || typ == influxql.Integer || typ == influxql.Unsigned
+	}
+	return false
+}
+
 // shardGroupDuration returns the default duration for a shard group based on a policy duration.
 func shardGroupDuration(d time.Duration) time.Duration {
 	if d >= 180*24*time.Hour || d == 0 { // 6 months or 0
@@ -1574,12 +2414,33 @@ type UserInfo struct {
 
 	// Map of database name to granted privilege.
 	Privileges map[string]influxql.Privilege
+
+	// Names of the roles granted to the user.
+	Roles []string
+
+	// Privileges granted on a subset of the measurements of a database.
+	Grants []GrantInfo
+
+	// Grants of the user's roles, resolved when looking the user up.
+	roleGrants []GrantInfo
+
+	// Whether the user was authenticated with a token, which only allows
+	// reading.
+	readOnly bool
+
+	// Retention policy whose grants authorize series reads and writes. The
+	// default retention policy of the database is used when empty.
+	retentionPolicy string
+
+	// Snapshot of the meta data the user was resolved from.
+	data *Data
 }
 
 type User interface {
 	query.Authorizer
 	ID() string
 	AuthorizeUnrestricted() bool
+	AuthorizeMeasurement(privilege influxql.Privilege, database, retentionPolicy string, measurement []byte) bool
 }
 
 func (u *UserInfo) ID() string {
@@ -1587,27 +2448,98 @@ func (u *UserInfo) ID() string {
 }
 
 // AuthorizeDatabase returns true if the user is authorized for the given privilege on the given database.
+// Grants on a subset of the measurements of the database don't authorize it, use AuthorizeMeasurement
+// with a nil measurement to check for any grant on the database.
 func (ui *UserInfo) AuthorizeDatabase(privilege influxql.Privilege, database string) bool {
+	return ui.authorizeDatabase(privilege, database)
+}
+
+// AuthorizeMeasurement returns true if the user is authorized for the given privilege on a measurement
+// of the given retention policy. An empty retention policy matches grants on any retention policy, and
+// a nil measurement matches grants on any measurement.
+func (ui *UserInfo) AuthorizeMeasurement(privilege influxql.Privilege, database, retentionPolicy string, measurement []byte) bool {
+	if ui.authorizeDatabase(privilege, database) {
+		return true
+	} else if ui.readOnly && privilege&influxql.WritePrivilege != 0 {
+		return false
+	}
+
+	for _, grants := range [][]GrantInfo{ui.Grants, ui.roleGrants} {
+		for i := range grants {
+			if grants[i].matches(privilege, database, retentionPolicy, measurement) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// authorizeDatabase returns true if the user holds the given privilege on every measurement of the
+// given database.
+func (ui *UserInfo) authorizeDatabase(privilege influxql.Privilege, database string) bool {
+	if ui.readOnly && privilege&influxql.WritePrivilege != 0 {
+		return false
+	}
 	if ui.Admin || privilege == influxql.NoPrivileges {
 		return true
 	}
 	p, ok := ui.Privileges[database]
-	return ok && (p == privilege || p == influxql.AllPrivileges)
+	if ok && (p == privilege || p == influxql.AllPrivileges) {
+		return true
+	}
+
+	for _, grants := range [][]GrantInfo{ui.Grants, ui.roleGrants} {
+		for i := range grants {
+			if grants[i].RetentionPolicy == "" && grants[i].Measurement == "" && grants[i].matches(privilege, database, "", nil) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
-// AuthorizeSeriesRead is used to limit access per-series (enterprise only)
+// AuthorizeSeriesRead is used to limit access per-series.
 func (u *UserInfo) AuthorizeSeriesRead(database string, measurement []byte, tags models.Tags) bool {
-	return true
+	return u.AuthorizeMeasurement(influxql.ReadPrivilege, database, u.seriesRetentionPolicy(database), measurement)
 }
 
-// AuthorizeSeriesWrite is used to limit access per-series (enterprise only)
+// AuthorizeSeriesWrite is used to limit access per-series.
 func (u *UserInfo) AuthorizeSeriesWrite(database string, measurement []byte, tags models.Tags) bool {
-	return true
+	return u.AuthorizeMeasurement(influxql.WritePrivilege, database, u.seriesRetentionPolicy(database), measurement)
+}
+
+// ForRetentionPolicy returns a copy of the user whose series authorization
+// checks the grants on the given retention policy.
+func (u *UserInfo) ForRetentionPolicy(retentionPolicy string) query.Authorizer {
+	other := *u
+	other.retentionPolicy = retentionPolicy
+	return &other
+}
+
+// seriesRetentionPolicy returns the retention policy holding the series
+// authorized by the user.
+func (u *UserInfo) seriesRetentionPolicy(database string) string {
+	if u.retentionPolicy != "" {
+		return u.retentionPolicy
+	}
+	return u.defaultRetentionPolicy(database)
 }
 
 // AuthorizeUnrestricted allows admins to shortcut access checks.
 func (u *UserInfo) AuthorizeUnrestricted() bool {
-	return u.Admin
+	return u.Admin && !u.readOnly
+}
+
+// defaultRetentionPolicy returns the default retention policy of a database
+// as of the snapshot the user was resolved from.
+func (ui *UserInfo) defaultRetentionPolicy(database string) string {
+	if ui.data == nil {
+		return ""
+	}
+	if di := ui.data.Database(database); di != nil {
+		return di.DefaultRetentionPolicy
+	}
+	return ""
 }
 
 // clone returns a deep copy of si.
@@ -1621,6 +2553,16 @@ func (ui UserInfo) clone() UserInfo {
 		}
 	}
 
+	if ui.Roles != nil {
+		other.Roles = make([]string, len(ui.Roles))
+		copy(other.Roles, ui.Roles)
+	}
+
+	if ui.Grants != nil {
+		other.Grants = make([]GrantInfo, len(ui.Grants))
+		copy(other.Grants, ui.Grants)
+	}
+
 	return other
 }
 
@@ -1630,6 +2572,7 @@ func (ui UserInfo) marshal() *internal.UserInfo {
 		Name:  proto.String(ui.Name),
 		Hash:  proto.String(ui.Hash),
 		Admin: proto.Bool(ui.Admin),
+		Roles: ui.Roles,
 	}
 
 	for database, privilege := range ui.Privileges {
@@ -1639,6 +2582,10 @@ func (ui UserInfo) marshal() *internal.UserInfo {
 		})
 	}
 
+	for _, gi := range ui.Grants {
+		pb.Grants = append(pb.Grants, gi.marshal())
+	}
+
 	return pb
 }
 
@@ -1652,6 +2599,199 @@ func (ui *UserInfo) unmarshal(pb *internal.UserInfo) {
 	for _, p := range pb.GetPrivileges() {
 		ui.Privileges[p.GetDatabase()] = influxql.Privilege(p.GetPrivilege())
 	}
+
+	ui.Roles = nil
+	if len(pb.GetRoles()) > 0 {
+		ui.Roles = make([]string, len(pb.GetRoles()))
+		copy(ui.Roles, pb.GetRoles())
+	}
+
+	ui.Grants = unmarshalGrants(pb.GetGrants())
+}
+
+// RoleInfo represents a named set of grants that can be given to users.
+type RoleInfo struct {
+	Name   string
+	Grants []GrantInfo
+}
+
+// clone returns a deep copy of ri.
+func (ri RoleInfo) clone() RoleInfo {
+	other := ri
+	if ri.Grants != nil {
+		other.Grants = make([]GrantInfo, len(ri.Grants))
+		copy(other.Grants, ri.Grants)
+	}
+	return other
+}
+
+// marshal serializes to a protobuf representation.
+func (ri RoleInfo) marshal() *internal.RoleInfo {
+	pb := &internal.RoleInfo{
+		Name: proto.String(ri.Name),
+	}
+	for _, gi := range ri.Grants {
+		pb.Grants = append(pb.Grants, gi.marshal())
+	}
+	return pb
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (ri *RoleInfo) unmarshal(pb *internal.RoleInfo) {
+	ri.Name = pb.GetName()
+	ri.Grants = unmarshalGrants(pb.GetGrants())
+}
+
+// GrantInfo represents a privilege on the measurements of a database matching
+// a regular expression. Empty RetentionPolicy and Measurement match any
+// retention policy and any measurement.
+type GrantInfo struct {
+	Database        string
+	RetentionPolicy string
+	Measurement     string
+	Privilege       influxql.Privilege
+
+	re *regexp.Regexp
+}
+
+// compile compiles the measurement regular expression of the grant.
+func (gi *GrantInfo) compile() error {
+	if gi.Measurement == "" {
+		gi.re = nil
+		return nil
+	}
+
+	re, err := regexp.Compile(gi.Measurement)
+	if err != nil {
+		return fmt.Errorf("invalid grant measurement: %s", err)
+	}
+	gi.re = re
+	return nil
+}
+
+// sameScope returns true if both grants cover the same measurements.
+func (gi *GrantInfo) sameScope(other *GrantInfo) bool {
+	return gi.Database == other.Database &&
+		gi.RetentionPolicy == other.RetentionPolicy &&
+		gi.Measurement == other.Measurement
+}
+
+// matches returns true if the grant gives privilege on the measurement of the
+// retention policy. An empty retention policy or a nil measurement match any.
+func (gi *GrantInfo) matches(privilege influxql.Privilege, database, retentionPolicy string, measurement []byte) bool {
+	if gi.Database != database || gi.Privilege&privilege != privilege {
+		return false
+	} else if retentionPolicy != "" && gi.RetentionPolicy != "" && gi.RetentionPolicy != retentionPolicy {
+		return false
+	}
+	return measurement == nil || gi.Measurement == "" || (gi.re != nil && gi.re.Match(measurement))
+}
+
+// marshal serializes to a protobuf representation.
+func (gi GrantInfo) marshal() *internal.GrantInfo {
+	pb := &internal.GrantInfo{
+		Database:  proto.String(gi.Database),
+		Privilege: proto.Int32(int32(gi.Privilege)),
+	}
+	if gi.RetentionPolicy != "" {
+		pb.RetentionPolicy = proto.String(gi.RetentionPolicy)
+	}
+	if gi.Measurement != "" {
+		pb.Measurement = proto.String(gi.Measurement)
+	}
+	return pb
+}
+
+// unmarshal deserializes from a protobuf representation. A grant whose
+// measurement doesn't compile matches no measurement.
+func (gi *GrantInfo) unmarshal(pb *internal.GrantInfo) {
+	gi.Database = pb.GetDatabase()
+	gi.RetentionPolicy = pb.GetRetentionPolicy()
+	gi.Measurement = pb.GetMeasurement()
+	gi.Privilege = influxql.Privilege(pb.GetPrivilege())
+	gi.compile()
+}
+
+func unmarshalGrants(pbs []*internal.GrantInfo) []GrantInfo {
+	if len(pbs) == 0 {
+		return nil
+	}
+	grants := make([]GrantInfo, len(pbs))
+	for i, x := range pbs {
+		grants[i].unmarshal(x)
+	}
+	return grants
+}
+
+// TokenInfo represents an API token. A token authenticates as its user with
+// read privileges only.
+type TokenInfo struct {
+	Name string
+	User string
+
+	// Hex-encoded SHA-256 hash of the token.
+	Hash string
+}
+
+// marshal serializes to a protobuf representation.
+func (ti TokenInfo) marshal() *internal.TokenInfo {
+	return &internal.TokenInfo{
+		Name: proto.String(ti.Name),
+		User: proto.String(ti.User),
+		Hash: proto.String(ti.Hash),
+	}
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (ti *TokenInfo) unmarshal(pb *internal.TokenInfo) {
+	ti.Name = pb.GetName()
+	ti.User = pb.GetUser()
+	ti.Hash = pb.GetHash()
+}
+
+// QuotaInfo represents the query quota of a user or a database. It replaces
+// the default limits configured for users or databases. A zero limit is
+// unlimited.
+type QuotaInfo struct {
+	Kind string
+	Name string
+
+	MaxConcurrentQueries int
+	MaxQueriesPerMinute  int
+	MaxPointsScanned     int64
+	MaxBytesReturned     int64
+}
+
+// Limits returns the limits of the quota.
+func (qi QuotaInfo) Limits() query.QuotaLimits {
+	return query.QuotaLimits{
+		MaxConcurrentQueries: qi.MaxConcurrentQueries,
+		MaxQueriesPerMinute:  qi.MaxQueriesPerMinute,
+		MaxPointsScanned:     qi.MaxPointsScanned,
+		MaxBytesReturned:     qi.MaxBytesReturned,
+	}
+}
+
+// marshal serializes to a protobuf representation.
+func (qi QuotaInfo) marshal() *internal.QuotaInfo {
+	return &internal.QuotaInfo{
+		Kind:                 proto.String(qi.Kind),
+		Name:                 proto.String(qi.Name),
+		MaxConcurrentQueries: proto.Int64(int64(qi.MaxConcurrentQueries)),
+		MaxQueriesPerMinute:  proto.Int64(int64(qi.MaxQueriesPerMinute)),
+		MaxPointsScanned:     proto.Int64(qi.MaxPointsScanned),
+		MaxBytesReturned:     proto.Int64(qi.MaxBytesReturned),
+	}
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (qi *QuotaInfo) unmarshal(pb *internal.QuotaInfo) {
+	qi.Kind = pb.GetKind()
+	qi.Name = pb.GetName()
+	qi.MaxConcurrentQueries = int(pb.GetMaxConcurrentQueries())
+	qi.MaxQueriesPerMinute = int(pb.GetMaxQueriesPerMinute())
+	qi.MaxPointsScanned = pb.GetMaxPointsScanned()
+	qi.MaxBytesReturned = pb.GetMaxBytesReturned()
 }
 
 // Lease represents a lease held on a resource.
@@ -113,6 +113,227 @@ func Test_Data_CreateRetentionPolicy(t *testing.T) {
 	}
 }
 
+func TestData_CreateRetentionRule(t *testing.T) {
+	data := meta.Data{}
+	if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateRetentionPolicy("db0", &meta.RetentionPolicyInfo{
+		Name:     "rp0",
+		ReplicaN: 1,
+		Duration: 30 * 24 * time.Hour,
+	}, true); err != nil {
+		t.Fatal(err)
+	}
+
+	rule := meta.RetentionRuleInfo{Name: "debug", Measurement: "debug_*", Duration: 72 * time.Hour}
+	if err := data.CreateRetentionRule("db0", "rp0", &rule); err != nil {
+		t.Fatal(err)
+	}
+
+	// Creating an identical rule is a no-op.
+	if err := data.CreateRetentionRule("db0", "rp0", &rule); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name string
+		rule meta.RetentionRuleInfo
+		err  error
+	}{
+		{name: "exists", rule: meta.RetentionRuleInfo{Name: "debug", Measurement: "debug_*", Duration: 24 * time.Hour}, err: meta.ErrRetentionRuleExists},
+		{name: "no name", rule: meta.RetentionRuleInfo{Measurement: "tmp", Duration: 24 * time.Hour}, err: meta.ErrRetentionRuleNameRequired},
+		{name: "no filter", rule: meta.RetentionRuleInfo{Name: "all", Duration: 24 * time.Hour}, err: meta.ErrRetentionRuleFilterRequired},
+		{name: "no tag prefix", rule: meta.RetentionRuleInfo{Name: "tmp", TagKey: "host", Duration: 24 * time.Hour}, err: meta.ErrRetentionRuleTagPrefixRequired},
+		{name: "too short", rule: meta.RetentionRuleInfo{Name: "tmp", Measurement: "tmp", Duration: time.Minute}, err: meta.ErrRetentionRuleDurationTooLow},
+		{name: "too long", rule: meta.RetentionRuleInfo{Name: "tmp", Measurement: "tmp", Duration: 30 * 24 * time.Hour}, err: meta.ErrRetentionRuleDurationTooHigh},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, exp := data.CreateRetentionRule("db0", "rp0", &tt.rule), tt.err; got != exp {
+				t.Fatalf("unexpected error: got %v, exp %v", got, exp)
+			}
+		})
+	}
+
+	rule2 := meta.RetentionRuleInfo{Name: "tmp-hosts", TagKey: "host", TagPrefix: "tmp-", Duration: 24 * time.Hour}
+	if err := data.CreateRetentionRule("db0", "rp0", &rule2); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ensure rules survive an encoding round trip.
+	buf, err := data.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var other meta.Data
+	if err := other.UnmarshalBinary(buf); err != nil {
+		t.Fatal(err)
+	}
+	rpi, err := other.RetentionPolicy("db0", "rp0")
+	if err != nil {
+		t.Fatal(err)
+	} else if got, exp := rpi.RetentionRules, []meta.RetentionRuleInfo{rule, rule2}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected rules: got %#v, exp %#v", got, exp)
+	}
+
+	if err := other.DropRetentionRule("db0", "rp0", "debug"); err != nil {
+		t.Fatal(err)
+	} else if err := other.DropRetentionRule("db0", "rp0", "debug"); err != meta.ErrRetentionRuleNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	} else if rpi, _ := other.RetentionPolicy("db0", "rp0"); len(rpi.RetentionRules) != 1 {
+		t.Fatalf("unexpected rules: %#v", rpi.RetentionRules)
+	}
+}
+
+func TestRetentionRuleInfo_MeasurementRegex(t *testing.T) {
+	rule := meta.RetentionRuleInfo{Measurement: "debug_*.v?"}
+	re, err := rule.MeasurementRegex()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, exp := range map[string]bool{
+		"debug_cpu.v1": true,
+		"debug_.v2":    true,
+		"debug_cpu_v1": false,
+		"xdebug_.v1":   false,
+		"debug_cpu.v":  false,
+	} {
+		if got := re.MatchString(name); got != exp {
+			t.Errorf("%s: got %v, exp %v", name, got, exp)
+		}
+	}
+}
+
+func TestData_CreateDownsample(t *testing.T) {
+	data := meta.Data{}
+	if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"rp0", "rollup"} {
+		if err := data.CreateRetentionPolicy("db0", &meta.RetentionPolicyInfo{
+			Name:               name,
+			ReplicaN:           1,
+			ShardGroupDuration: 24 * time.Hour,
+		}, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ds := meta.DownsampleInfo{Name: "hourly", TargetPolicy: "rollup", Interval: time.Hour, IntegerAggregate: "max"}
+	if err := data.CreateDownsample("db0", "rp0", &ds); err != nil {
+		t.Fatal(err)
+	}
+
+	// Creating an identical downsample is a no-op.
+	if err := data.CreateDownsample("db0", "rp0", &ds); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name string
+		ds   meta.DownsampleInfo
+		err  string
+	}{
+		{name: "exists", ds: meta.DownsampleInfo{Name: "hourly", TargetPolicy: "rollup", Interval: 2 * time.Hour}, err: meta.ErrDownsampleExists.Error()},
+		{name: "no name", ds: meta.DownsampleInfo{TargetPolicy: "rollup", Interval: time.Hour}, err: meta.ErrDownsampleNameRequired.Error()},
+		{name: "no interval", ds: meta.DownsampleInfo{Name: "ds", TargetPolicy: "rollup"}, err: meta.ErrDownsampleIntervalRequired.Error()},
+		{name: "uneven interval", ds: meta.DownsampleInfo{Name: "ds", TargetPolicy: "rollup", Interval: 7 * time.Hour}, err: meta.ErrDownsampleIntervalInvalid.Error()},
+		{name: "same target", ds: meta.DownsampleInfo{Name: "ds", TargetPolicy: "rp0", Interval: time.Hour}, err: meta.ErrDownsampleTargetInvalid.Error()},
+		{name: "unknown target", ds: meta.DownsampleInfo{Name: "ds", TargetPolicy: "rp1", Interval: time.Hour}, err: "retention policy not found: rp1"},
+		{name: "bad aggregate", ds: meta.DownsampleInfo{Name: "ds", TargetPolicy: "rollup", Interval: time.Hour, StringAggregate: "mean"}, err: `invalid downsample aggregate "mean" for string fields`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := data.CreateDownsample("db0", "rp0", &tt.ds); err == nil || err.Error() != tt.err {
+				t.Fatalf("unexpected error: got %v, exp %s", err, tt.err)
+			}
+		})
+	}
+
+	// Ensure unset aggregates are defaulted.
+	rpi, err := data.RetentionPolicy("db0", "rp0")
+	if err != nil {
+		t.Fatal(err)
+	} else if got, exp := rpi.Downsamples, []meta.DownsampleInfo{{
+		Name:              "hourly",
+		TargetPolicy:      "rollup",
+		Interval:          time.Hour,
+		FloatAggregate:    "mean",
+		IntegerAggregate:  "max",
+		UnsignedAggregate: "mean",
+		BooleanAggregate:  "last",
+		StringAggregate:   "last",
+	}}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected downsamples: got %#v, exp %#v", got, exp)
+	}
+
+	if err := data.DropDownsample("db0", "rp0", "hourly"); err != nil {
+		t.Fatal(err)
+	} else if err := data.DropDownsample("db0", "rp0", "hourly"); err != meta.ErrDownsampleNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestData_SetDownsampleProgress(t *testing.T) {
+	data := meta.Data{}
+	if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"rp0", "rollup"} {
+		if err := data.CreateRetentionPolicy("db0", &meta.RetentionPolicyInfo{
+			Name:               name,
+			ReplicaN:           1,
+			ShardGroupDuration: 24 * time.Hour,
+		}, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := data.CreateDownsample("db0", "rp0", &meta.DownsampleInfo{Name: "hourly", TargetPolicy: "rollup", Interval: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+
+	t0 := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, ts := range []time.Time{t0, t0.Add(24 * time.Hour)} {
+		if err := data.CreateShardGroup("db0", "rp0", ts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rpi, _ := data.RetentionPolicy("db0", "rp0")
+	sg0, sg1 := rpi.ShardGroups[0].ID, rpi.ShardGroups[1].ID
+
+	if err := data.SetDownsampleProgress("db0", "rp0", "hourly", sg0, t0.Add(25*time.Hour)); err != nil {
+		t.Fatal(err)
+	} else if err := data.SetDownsampleProgress("db0", "rp0", "hourly", sg1, t0.Add(49*time.Hour)); err != nil {
+		t.Fatal(err)
+	} else if err := data.SetDownsampleProgress("db0", "rp0", "hourly", sg1, t0.Add(50*time.Hour)); err != nil {
+		t.Fatal(err)
+	} else if err := data.SetDownsampleProgress("db0", "rp0", "missing", sg1, t0); err != meta.ErrDownsampleNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := data.SetDownsampleProgress("db0", "rp0", "hourly", 1000, t0); err != meta.ErrShardGroupNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Progress of deleted shard groups is dropped on the next update.
+	if err := data.DeleteShardGroup("db0", "rp0", sg0); err != nil {
+		t.Fatal(err)
+	} else if err := data.SetDownsampleProgress("db0", "rp0", "hourly", sg1, t0.Add(51*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ensure progress survives an encoding round trip.
+	buf, err := data.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var other meta.Data
+	if err := other.UnmarshalBinary(buf); err != nil {
+		t.Fatal(err)
+	}
+	rpi, _ = other.RetentionPolicy("db0", "rp0")
+	if got, exp := rpi.Downsample("hourly").Progress, []meta.DownsampleProgressInfo{{ShardGroupID: sg1, CompletedAt: t0.Add(51 * time.Hour)}}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected progress: got %#v, exp %#v", got, exp)
+	}
+}
+
 func TestData_AdminUserExists(t *testing.T) {
 	data := meta.Data{}
 
@@ -217,6 +438,134 @@ func TestData_SetPrivilege(t *testing.T) {
 	}
 }
 
+func TestData_GrantMeasurement(t *testing.T) {
+	data := meta.Data{}
+	if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateUser("user1", "", false); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateRole("role1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := data.CreateRole("role1"), meta.ErrRoleExists; got != exp {
+		t.Fatalf("got %v, expected %v", got, exp)
+	}
+	if got, exp := data.GrantRole("role2", "user1"), meta.ErrRoleNotFound; got != exp {
+		t.Fatalf("got %v, expected %v", got, exp)
+	}
+	if got, exp := data.GrantUserMeasurement("user1", &meta.GrantInfo{Database: "db1", Privilege: influxql.ReadPrivilege}), influxdb.ErrDatabaseNotFound("db1"); got == nil || got.Error() != exp.Error() {
+		t.Fatalf("got %v, expected %v", got, exp)
+	}
+	if err := data.GrantUserMeasurement("user1", &meta.GrantInfo{Database: "db0", Measurement: "(", Privilege: influxql.ReadPrivilege}); err == nil {
+		t.Fatal("expected an invalid measurement regex to be rejected")
+	}
+
+	// Privileges granted on the same measurements are merged.
+	gi := meta.GrantInfo{Database: "db0", Measurement: "^cpu$"}
+	for _, p := range []influxql.Privilege{influxql.ReadPrivilege, influxql.WritePrivilege} {
+		gi.Privilege = p
+		if err := data.GrantRoleMeasurement("role1", &gi); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, exp := data.Role("role1").Grants, 1; len(got) != exp || got[0].Privilege != influxql.AllPrivileges {
+		t.Fatalf("unexpected grants: %v", got)
+	}
+
+	// Revoking a privilege keeps the others.
+	gi.Privilege = influxql.WritePrivilege
+	if err := data.RevokeRoleMeasurement("role1", &gi); err != nil {
+		t.Fatal(err)
+	} else if got := data.Role("role1").Grants; len(got) != 1 || got[0].Privilege != influxql.ReadPrivilege {
+		t.Fatalf("unexpected grants: %v", got)
+	}
+
+	if err := data.GrantRole("role1", "user1"); err != nil {
+		t.Fatal(err)
+	} else if got, exp := data.Users[0].Roles, []string{"role1"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("got %v, expected %v", got, exp)
+	}
+
+	// Dropping a role revokes it from its users.
+	if err := data.DropRole("role1"); err != nil {
+		t.Fatal(err)
+	} else if got := data.Users[0].Roles; len(got) != 0 {
+		t.Fatalf("unexpected roles: %v", got)
+	}
+}
+
+func TestData_DropUser_Tokens(t *testing.T) {
+	data := meta.Data{}
+	if err := data.CreateUser("user1", "", false); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateUser("user2", "", false); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := data.CreateToken("token0", "user3", "hash"), meta.ErrUserNotFound; got != exp {
+		t.Fatalf("got %v, expected %v", got, exp)
+	}
+	if err := data.CreateToken("token1", "user1", "hash1"); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateToken("token2", "user2", "hash2"); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := data.CreateToken("token1", "user2", "hash3"), meta.ErrTokenExists; got != exp {
+		t.Fatalf("got %v, expected %v", got, exp)
+	}
+
+	if err := data.DropUser("user1"); err != nil {
+		t.Fatal(err)
+	} else if got, exp := data.Tokens, []meta.TokenInfo{{Name: "token2", User: "user2", Hash: "hash2"}}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("got %v, expected %v", got, exp)
+	}
+}
+
+func TestData_SetQuota(t *testing.T) {
+	data := meta.Data{}
+	if err := data.CreateUser("user1", "", false); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := data.SetQuota(meta.QuotaInfo{Kind: "user", Name: "user2"}), meta.ErrUserNotFound; got != exp {
+		t.Fatalf("got %v, expected %v", got, exp)
+	} else if got, exp := data.SetQuota(meta.QuotaInfo{Kind: "host", Name: "user1"}), meta.ErrInvalidQuotaKind; got != exp {
+		t.Fatalf("got %v, expected %v", got, exp)
+	}
+
+	if err := data.SetQuota(meta.QuotaInfo{Kind: "user", Name: "user1", MaxConcurrentQueries: 1}); err != nil {
+		t.Fatal(err)
+	} else if err := data.SetQuota(meta.QuotaInfo{Kind: "user", Name: "user1", MaxQueriesPerMinute: 10}); err != nil {
+		t.Fatal(err)
+	} else if err := data.SetQuota(meta.QuotaInfo{Kind: "database", Name: "db0", MaxPointsScanned: 1000}); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []meta.QuotaInfo{
+		{Kind: "user", Name: "user1", MaxQueriesPerMinute: 10},
+		{Kind: "database", Name: "db0", MaxPointsScanned: 1000},
+	}
+	if !reflect.DeepEqual(data.Quotas, exp) {
+		t.Fatalf("got %v, expected %v", data.Quotas, exp)
+	}
+
+	// Quotas are removed along with their user or database.
+	if err := data.DropUser("user1"); err != nil {
+		t.Fatal(err)
+	} else if err := data.DropDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if len(data.Quotas) != 0 {
+		t.Fatalf("unexpected quotas: %v", data.Quotas)
+	}
+
+	if got, exp := data.DropQuota("user", "user1"), meta.ErrQuotaNotFound; got != exp {
+		t.Fatalf("got %v, expected %v", got, exp)
+	}
+}
+
 func TestData_TruncateShardGroups(t *testing.T) {
 	data := &meta.Data{}
 
@@ -330,6 +679,49 @@ func TestUserInfo_AuthorizeDatabase(t *testing.T) {
 	}
 }
 
+func TestUserInfo_AuthorizeMeasurement(t *testing.T) {
+	data := meta.Data{}
+	if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateRetentionPolicy("db0", &meta.RetentionPolicyInfo{Name: "rp0", ReplicaN: 1, Duration: time.Hour, ShardGroupDuration: time.Hour}, false); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateUser("user1", "", false); err != nil {
+		t.Fatal(err)
+	} else if err := data.GrantUserMeasurement("user1", &meta.GrantInfo{Database: "db0", RetentionPolicy: "rp0", Measurement: "^cpu", Privilege: influxql.AllPrivileges}); err != nil {
+		t.Fatal(err)
+	}
+	ui := &data.Users[0]
+
+	for _, tt := range []struct {
+		privilege   influxql.Privilege
+		database    string
+		policy      string
+		measurement string
+		exp         bool
+	}{
+		{influxql.ReadPrivilege, "db0", "rp0", "cpu", true},
+		{influxql.WritePrivilege, "db0", "rp0", "cpu_load", true},
+		{influxql.ReadPrivilege, "db0", "", "cpu", true},
+		{influxql.ReadPrivilege, "db0", "autogen", "cpu", false},
+		{influxql.ReadPrivilege, "db0", "rp0", "mem", false},
+		{influxql.ReadPrivilege, "db1", "rp0", "cpu", false},
+	} {
+		if got := ui.AuthorizeMeasurement(tt.privilege, tt.database, tt.policy, []byte(tt.measurement)); got != tt.exp {
+			t.Errorf("AuthorizeMeasurement(%s, %s, %s, %s) = %v, expected %v", tt.privilege, tt.database, tt.policy, tt.measurement, got, tt.exp)
+		}
+	}
+
+	if !ui.AuthorizeMeasurement(influxql.ReadPrivilege, "db0", "", nil) {
+		t.Fatal("expected a grant on the database to authorize some of its measurements")
+	}
+	if ui.AuthorizeDatabase(influxql.ReadPrivilege, "db0") {
+		t.Fatal("expected a grant on some measurements not to authorize the database")
+	}
+	if ui.AuthorizeMeasurement(influxql.ReadPrivilege, "db1", "", nil) {
+		t.Fatal("expected a database without grants to be unauthorized")
+	}
+}
+
 func TestShardGroupInfo_Contains(t *testing.T) {
 	sgi := &meta.ShardGroupInfo{StartTime: time.Unix(10, 0), EndTime: time.Unix(20, 0)}
 
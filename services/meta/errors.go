@@ -3,6 +3,8 @@ package meta
 import (
 	"errors"
 	"fmt"
+
+	"github.com/influxdata/influxql"
 )
 
 var (
@@ -67,6 +69,62 @@ var (
 	ErrReplicationFactorTooLow = errors.New("replication factor must be greater than 0")
 )
 
+var (
+	// ErrRetentionRuleExists is returned when creating an already existing retention rule.
+	ErrRetentionRuleExists = errors.New("retention rule already exists")
+
+	// ErrRetentionRuleNotFound is returned when removing a retention rule that doesn't exist.
+	ErrRetentionRuleNotFound = errors.New("retention rule not found")
+
+	// ErrRetentionRuleNameRequired is returned when creating a retention rule without a name.
+	ErrRetentionRuleNameRequired = errors.New("retention rule name required")
+
+	// ErrRetentionRuleFilterRequired is returned when creating a retention rule
+	// that matches neither a measurement nor a tag.
+	ErrRetentionRuleFilterRequired = errors.New("retention rule requires a measurement or tag filter")
+
+	// ErrRetentionRuleTagPrefixRequired is returned when creating a retention rule
+	// that filters on a tag key without a tag value prefix.
+	ErrRetentionRuleTagPrefixRequired = errors.New("retention rule tag prefix required")
+
+	// ErrRetentionRuleDurationTooLow is returned when creating a retention rule
+	// that has a duration lower than the allowed minimum.
+	ErrRetentionRuleDurationTooLow = fmt.Errorf("retention rule duration must be at least %s", MinRetentionPolicyDuration)
+
+	// ErrRetentionRuleDurationTooHigh is returned when creating a retention rule
+	// that keeps data at least as long as its retention policy.
+	ErrRetentionRuleDurationTooHigh = errors.New("retention rule duration must be lower than the retention policy duration")
+)
+
+var (
+	// ErrDownsampleExists is returned when creating an already existing downsample.
+	ErrDownsampleExists = errors.New("downsample already exists")
+
+	// ErrDownsampleNotFound is returned when mutating a downsample that doesn't exist.
+	ErrDownsampleNotFound = errors.New("downsample not found")
+
+	// ErrDownsampleNameRequired is returned when creating a downsample without a name.
+	ErrDownsampleNameRequired = errors.New("downsample name required")
+
+	// ErrDownsampleIntervalRequired is returned when creating a downsample
+	// without a positive interval.
+	ErrDownsampleIntervalRequired = errors.New("downsample interval must be greater than 0")
+
+	// ErrDownsampleIntervalInvalid is returned when the downsample interval
+	// does not evenly divide the shard group duration of the source policy.
+	ErrDownsampleIntervalInvalid = errors.New("downsample interval must evenly divide the shard group duration")
+
+	// ErrDownsampleTargetInvalid is returned when a downsample targets its own
+	// retention policy.
+	ErrDownsampleTargetInvalid = errors.New("downsample target policy must differ from the source policy")
+)
+
+// ErrInvalidDownsampleAggregate is returned when a downsample uses an
+// aggregate that cannot be applied to fields of the given type.
+func ErrInvalidDownsampleAggregate(aggregate string, typ influxql.DataType) error {
+	return fmt.Errorf("invalid downsample aggregate %q for %s fields", aggregate, typ)
+}
+
 var (
 	// ErrShardGroupExists is returned when creating an already existing shard group.
 	ErrShardGroupExists = errors.New("shard group already exists")
@@ -113,3 +171,36 @@ var (
 	// ErrAuthenticate is returned when authentication fails.
 	ErrAuthenticate = errors.New("authentication failed")
 )
+
+var (
+	// ErrRoleExists is returned when creating an already existing role.
+	ErrRoleExists = errors.New("role already exists")
+
+	// ErrRoleNotFound is returned when mutating a role that doesn't exist.
+	ErrRoleNotFound = errors.New("role not found")
+
+	// ErrRoleNameRequired is returned when creating a role without a name.
+	ErrRoleNameRequired = errors.New("role name required")
+
+	// ErrGrantDatabaseRequired is returned when granting a privilege without a database.
+	ErrGrantDatabaseRequired = errors.New("grant database required")
+
+	// ErrGrantNotFound is returned when revoking a grant that doesn't exist.
+	ErrGrantNotFound = errors.New("grant not found")
+
+	// ErrTokenExists is returned when creating an already existing token.
+	ErrTokenExists = errors.New("token already exists")
+
+	// ErrTokenNotFound is returned when dropping a token that doesn't exist.
+	ErrTokenNotFound = errors.New("token not found")
+
+	// ErrTokenNameRequired is returned when creating a token without a name.
+	ErrTokenNameRequired = errors.New("token name required")
+
+	// ErrInvalidQuotaKind is returned when setting a quota on something
+	// other than a user or a database.
+	ErrInvalidQuotaKind = errors.New("quota must be set on a user or a database")
+
+	// ErrQuotaNotFound is returned when dropping a quota that doesn't exist.
+	ErrQuotaNotFound = errors.New("quota not found")
+)
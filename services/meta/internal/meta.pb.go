@@ -14,6 +14,9 @@ It has these top-level messages:
 	DatabaseInfo
 	RetentionPolicySpec
 	RetentionPolicyInfo
+	RetentionRuleInfo
+	DownsampleInfo
+	DownsampleProgressInfo
 	ShardGroupInfo
 	ShardInfo
 	SubscriptionInfo
@@ -21,6 +24,10 @@ It has these top-level messages:
 	ContinuousQueryInfo
 	UserInfo
 	UserPrivilege
+	RoleInfo
+	GrantInfo
+	TokenInfo
+	QuotaInfo
 	Command
 	CreateNodeCommand
 	DeleteNodeCommand
@@ -196,9 +203,12 @@ type Data struct {
 	MaxShardGroupID *uint64         `protobuf:"varint,8,req,name=MaxShardGroupID" json:"MaxShardGroupID,omitempty"`
 	MaxShardID      *uint64         `protobuf:"varint,9,req,name=MaxShardID" json:"MaxShardID,omitempty"`
 	// added for 0.10.0
-	DataNodes        []*NodeInfo `protobuf:"bytes,10,rep,name=DataNodes" json:"DataNodes,omitempty"`
-	MetaNodes        []*NodeInfo `protobuf:"bytes,11,rep,name=MetaNodes" json:"MetaNodes,omitempty"`
-	XXX_unrecognized []byte      `json:"-"`
+	DataNodes        []*NodeInfo  `protobuf:"bytes,10,rep,name=DataNodes" json:"DataNodes,omitempty"`
+	MetaNodes        []*NodeInfo  `protobuf:"bytes,11,rep,name=MetaNodes" json:"MetaNodes,omitempty"`
+	Roles            []*RoleInfo  `protobuf:"bytes,12,rep,name=Roles" json:"Roles,omitempty"`
+	Tokens           []*TokenInfo `protobuf:"bytes,13,rep,name=Tokens" json:"Tokens,omitempty"`
+	Quotas           []*QuotaInfo `protobuf:"bytes,14,rep,name=Quotas" json:"Quotas,omitempty"`
+	XXX_unrecognized []byte       `json:"-"`
 }
 
 func (m *Data) Reset()                    { *m = Data{} }
@@ -283,6 +293,27 @@ func (m *Data) GetMetaNodes() []*NodeInfo {
 	return nil
 }
 
+func (m *Data) GetRoles() []*RoleInfo {
+	if m != nil {
+		return m.Roles
+	}
+	return nil
+}
+
+func (m *Data) GetTokens() []*TokenInfo {
+	if m != nil {
+		return m.Tokens
+	}
+	return nil
+}
+
+func (m *Data) GetQuotas() []*QuotaInfo {
+	if m != nil {
+		return m.Quotas
+	}
+	return nil
+}
+
 type NodeInfo struct {
 	ID               *uint64 `protobuf:"varint,1,req,name=ID" json:"ID,omitempty"`
 	Host             *string `protobuf:"bytes,2,req,name=Host" json:"Host,omitempty"`
@@ -399,13 +430,15 @@ func (m *RetentionPolicySpec) GetReplicaN() uint32 {
 }
 
 type RetentionPolicyInfo struct {
-	Name               *string             `protobuf:"bytes,1,req,name=Name" json:"Name,omitempty"`
-	Duration           *int64              `protobuf:"varint,2,req,name=Duration" json:"Duration,omitempty"`
-	ShardGroupDuration *int64              `protobuf:"varint,3,req,name=ShardGroupDuration" json:"ShardGroupDuration,omitempty"`
-	ReplicaN           *uint32             `protobuf:"varint,4,req,name=ReplicaN" json:"ReplicaN,omitempty"`
-	ShardGroups        []*ShardGroupInfo   `protobuf:"bytes,5,rep,name=ShardGroups" json:"ShardGroups,omitempty"`
-	Subscriptions      []*SubscriptionInfo `protobuf:"bytes,6,rep,name=Subscriptions" json:"Subscriptions,omitempty"`
-	XXX_unrecognized   []byte              `json:"-"`
+	Name               *string              `protobuf:"bytes,1,req,name=Name" json:"Name,omitempty"`
+	Duration           *int64               `protobuf:"varint,2,req,name=Duration" json:"Duration,omitempty"`
+	ShardGroupDuration *int64               `protobuf:"varint,3,req,name=ShardGroupDuration" json:"ShardGroupDuration,omitempty"`
+	ReplicaN           *uint32              `protobuf:"varint,4,req,name=ReplicaN" json:"ReplicaN,omitempty"`
+	ShardGroups        []*ShardGroupInfo    `protobuf:"bytes,5,rep,name=ShardGroups" json:"ShardGroups,omitempty"`
+	Subscriptions      []*SubscriptionInfo  `protobuf:"bytes,6,rep,name=Subscriptions" json:"Subscriptions,omitempty"`
+	RetentionRules     []*RetentionRuleInfo `protobuf:"bytes,7,rep,name=RetentionRules" json:"RetentionRules,omitempty"`
+	Downsamples        []*DownsampleInfo    `protobuf:"bytes,8,rep,name=Downsamples" json:"Downsamples,omitempty"`
+	XXX_unrecognized   []byte               `json:"-"`
 }
 
 func (m *RetentionPolicyInfo) Reset()                    { *m = RetentionPolicyInfo{} }
@@ -455,6 +488,172 @@ func (m *RetentionPolicyInfo) GetSubscriptions() []*SubscriptionInfo {
 	return nil
 }
 
+func (m *RetentionPolicyInfo) GetRetentionRules() []*RetentionRuleInfo {
+	if m != nil {
+		return m.RetentionRules
+	}
+	return nil
+}
+
+func (m *RetentionPolicyInfo) GetDownsamples() []*DownsampleInfo {
+	if m != nil {
+		return m.Downsamples
+	}
+	return nil
+}
+
+type RetentionRuleInfo struct {
+	Name             *string `protobuf:"bytes,1,req,name=Name" json:"Name,omitempty"`
+	Measurement      *string `protobuf:"bytes,2,opt,name=Measurement" json:"Measurement,omitempty"`
+	TagKey           *string `protobuf:"bytes,3,opt,name=TagKey" json:"TagKey,omitempty"`
+	TagPrefix        *string `protobuf:"bytes,4,opt,name=TagPrefix" json:"TagPrefix,omitempty"`
+	Duration         *int64  `protobuf:"varint,5,req,name=Duration" json:"Duration,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *RetentionRuleInfo) Reset()         { *m = RetentionRuleInfo{} }
+func (m *RetentionRuleInfo) String() string { return proto.CompactTextString(m) }
+func (*RetentionRuleInfo) ProtoMessage()    {}
+
+func (m *RetentionRuleInfo) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *RetentionRuleInfo) GetMeasurement() string {
+	if m != nil && m.Measurement != nil {
+		return *m.Measurement
+	}
+	return ""
+}
+
+func (m *RetentionRuleInfo) GetTagKey() string {
+	if m != nil && m.TagKey != nil {
+		return *m.TagKey
+	}
+	return ""
+}
+
+func (m *RetentionRuleInfo) GetTagPrefix() string {
+	if m != nil && m.TagPrefix != nil {
+		return *m.TagPrefix
+	}
+	return ""
+}
+
+func (m *RetentionRuleInfo) GetDuration() int64 {
+	if m != nil && m.Duration != nil {
+		return *m.Duration
+	}
+	return 0
+}
+
+type DownsampleInfo struct {
+	Name              *string                   `protobuf:"bytes,1,req,name=Name" json:"Name,omitempty"`
+	TargetPolicy      *string                   `protobuf:"bytes,2,req,name=TargetPolicy" json:"TargetPolicy,omitempty"`
+	Interval          *int64                    `protobuf:"varint,3,req,name=Interval" json:"Interval,omitempty"`
+	FloatAggregate    *string                   `protobuf:"bytes,4,opt,name=FloatAggregate" json:"FloatAggregate,omitempty"`
+	IntegerAggregate  *string                   `protobuf:"bytes,5,opt,name=IntegerAggregate" json:"IntegerAggregate,omitempty"`
+	UnsignedAggregate *string                   `protobuf:"bytes,6,opt,name=UnsignedAggregate" json:"UnsignedAggregate,omitempty"`
+	BooleanAggregate  *string                   `protobuf:"bytes,7,opt,name=BooleanAggregate" json:"BooleanAggregate,omitempty"`
+	StringAggregate   *string                   `protobuf:"bytes,8,opt,name=StringAggregate" json:"StringAggregate,omitempty"`
+	Progress          []*DownsampleProgressInfo `protobuf:"bytes,9,rep,name=Progress" json:"Progress,omitempty"`
+	XXX_unrecognized  []byte                    `json:"-"`
+}
+
+func (m *DownsampleInfo) Reset()         { *m = DownsampleInfo{} }
+func (m *DownsampleInfo) String() string { return proto.CompactTextString(m) }
+func (*DownsampleInfo) ProtoMessage()    {}
+
+func (m *DownsampleInfo) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *DownsampleInfo) GetTargetPolicy() string {
+	if m != nil && m.TargetPolicy != nil {
+		return *m.TargetPolicy
+	}
+	return ""
+}
+
+func (m *DownsampleInfo) GetInterval() int64 {
+	if m != nil && m.Interval != nil {
+		return *m.Interval
+	}
+	return 0
+}
+
+func (m *DownsampleInfo) GetFloatAggregate() string {
+	if m != nil && m.FloatAggregate != nil {
+		return *m.FloatAggregate
+	}
+	return ""
+}
+
+func (m *DownsampleInfo) GetIntegerAggregate() string {
+	if m != nil && m.IntegerAggregate != nil {
+		return *m.IntegerAggregate
+	}
+	return ""
+}
+
+func (m *DownsampleInfo) GetUnsignedAggregate() string {
+	if m != nil && m.UnsignedAggregate != nil {
+		return *m.UnsignedAggregate
+	}
+	return ""
+}
+
+func (m *DownsampleInfo) GetBooleanAggregate() string {
+	if m != nil && m.BooleanAggregate != nil {
+		return *m.BooleanAggregate
+	}
+	return ""
+}
+
+func (m *DownsampleInfo) GetStringAggregate() string {
+	if m != nil && m.StringAggregate != nil {
+		return *m.StringAggregate
+	}
+	return ""
+}
+
+func (m *DownsampleInfo) GetProgress() []*DownsampleProgressInfo {
+	if m != nil {
+		return m.Progress
+	}
+	return nil
+}
+
+type DownsampleProgressInfo struct {
+	ShardGroupID     *uint64 `protobuf:"varint,1,req,name=ShardGroupID" json:"ShardGroupID,omitempty"`
+	CompletedAt      *int64  `protobuf:"varint,2,req,name=CompletedAt" json:"CompletedAt,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *DownsampleProgressInfo) Reset()         { *m = DownsampleProgressInfo{} }
+func (m *DownsampleProgressInfo) String() string { return proto.CompactTextString(m) }
+func (*DownsampleProgressInfo) ProtoMessage()    {}
+
+func (m *DownsampleProgressInfo) GetShardGroupID() uint64 {
+	if m != nil && m.ShardGroupID != nil {
+		return *m.ShardGroupID
+	}
+	return 0
+}
+
+func (m *DownsampleProgressInfo) GetCompletedAt() int64 {
+	if m != nil && m.CompletedAt != nil {
+		return *m.CompletedAt
+	}
+	return 0
+}
+
 type ShardGroupInfo struct {
 	ID               *uint64      `protobuf:"varint,1,req,name=ID" json:"ID,omitempty"`
 	StartTime        *int64       `protobuf:"varint,2,req,name=StartTime" json:"StartTime,omitempty"`
@@ -625,6 +824,8 @@ type UserInfo struct {
 	Hash             *string          `protobuf:"bytes,2,req,name=Hash" json:"Hash,omitempty"`
 	Admin            *bool            `protobuf:"varint,3,req,name=Admin" json:"Admin,omitempty"`
 	Privileges       []*UserPrivilege `protobuf:"bytes,4,rep,name=Privileges" json:"Privileges,omitempty"`
+	Roles            []string         `protobuf:"bytes,5,rep,name=Roles" json:"Roles,omitempty"`
+	Grants           []*GrantInfo     `protobuf:"bytes,6,rep,name=Grants" json:"Grants,omitempty"`
 	XXX_unrecognized []byte           `json:"-"`
 }
 
@@ -661,6 +862,20 @@ func (m *UserInfo) GetPrivileges() []*UserPrivilege {
 	return nil
 }
 
+func (m *UserInfo) GetRoles() []string {
+	if m != nil {
+		return m.Roles
+	}
+	return nil
+}
+
+func (m *UserInfo) GetGrants() []*GrantInfo {
+	if m != nil {
+		return m.Grants
+	}
+	return nil
+}
+
 type UserPrivilege struct {
 	Database         *string `protobuf:"bytes,1,req,name=Database" json:"Database,omitempty"`
 	Privilege        *int32  `protobuf:"varint,2,req,name=Privilege" json:"Privilege,omitempty"`
@@ -686,6 +901,158 @@ func (m *UserPrivilege) GetPrivilege() int32 {
 	return 0
 }
 
+type RoleInfo struct {
+	Name             *string      `protobuf:"bytes,1,req,name=Name" json:"Name,omitempty"`
+	Grants           []*GrantInfo `protobuf:"bytes,2,rep,name=Grants" json:"Grants,omitempty"`
+	XXX_unrecognized []byte       `json:"-"`
+}
+
+func (m *RoleInfo) Reset()         { *m = RoleInfo{} }
+func (m *RoleInfo) String() string { return proto.CompactTextString(m) }
+func (*RoleInfo) ProtoMessage()    {}
+
+func (m *RoleInfo) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *RoleInfo) GetGrants() []*GrantInfo {
+	if m != nil {
+		return m.Grants
+	}
+	return nil
+}
+
+type GrantInfo struct {
+	Database         *string `protobuf:"bytes,1,req,name=Database" json:"Database,omitempty"`
+	RetentionPolicy  *string `protobuf:"bytes,2,opt,name=RetentionPolicy" json:"RetentionPolicy,omitempty"`
+	Measurement      *string `protobuf:"bytes,3,opt,name=Measurement" json:"Measurement,omitempty"`
+	Privilege        *int32  `protobuf:"varint,4,req,name=Privilege" json:"Privilege,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *GrantInfo) Reset()         { *m = GrantInfo{} }
+func (m *GrantInfo) String() string { return proto.CompactTextString(m) }
+func (*GrantInfo) ProtoMessage()    {}
+
+func (m *GrantInfo) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *GrantInfo) GetRetentionPolicy() string {
+	if m != nil && m.RetentionPolicy != nil {
+		return *m.RetentionPolicy
+	}
+	return ""
+}
+
+func (m *GrantInfo) GetMeasurement() string {
+	if m != nil && m.Measurement != nil {
+		return *m.Measurement
+	}
+	return ""
+}
+
+func (m *GrantInfo) GetPrivilege() int32 {
+	if m != nil && m.Privilege != nil {
+		return *m.Privilege
+	}
+	return 0
+}
+
+type TokenInfo struct {
+	Name             *string `protobuf:"bytes,1,req,name=Name" json:"Name,omitempty"`
+	User             *string `protobuf:"bytes,2,req,name=User" json:"User,omitempty"`
+	Hash             *string `protobuf:"bytes,3,req,name=Hash" json:"Hash,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *TokenInfo) Reset()         { *m = TokenInfo{} }
+func (m *TokenInfo) String() string { return proto.CompactTextString(m) }
+func (*TokenInfo) ProtoMessage()    {}
+
+func (m *TokenInfo) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *TokenInfo) GetUser() string {
+	if m != nil && m.User != nil {
+		return *m.User
+	}
+	return ""
+}
+
+func (m *TokenInfo) GetHash() string {
+	if m != nil && m.Hash != nil {
+		return *m.Hash
+	}
+	return ""
+}
+
+type QuotaInfo struct {
+	Kind                 *string `protobuf:"bytes,1,req,name=Kind" json:"Kind,omitempty"`
+	Name                 *string `protobuf:"bytes,2,req,name=Name" json:"Name,omitempty"`
+	MaxConcurrentQueries *int64  `protobuf:"varint,3,opt,name=MaxConcurrentQueries" json:"MaxConcurrentQueries,omitempty"`
+	MaxQueriesPerMinute  *int64  `protobuf:"varint,4,opt,name=MaxQueriesPerMinute" json:"MaxQueriesPerMinute,omitempty"`
+	MaxPointsScanned     *int64  `protobuf:"varint,5,opt,name=MaxPointsScanned" json:"MaxPointsScanned,omitempty"`
+	MaxBytesReturned     *int64  `protobuf:"varint,6,opt,name=MaxBytesReturned" json:"MaxBytesReturned,omitempty"`
+	XXX_unrecognized     []byte  `json:"-"`
+}
+
+func (m *QuotaInfo) Reset()         { *m = QuotaInfo{} }
+func (m *QuotaInfo) String() string { return proto.CompactTextString(m) }
+func (*QuotaInfo) ProtoMessage()    {}
+
+func (m *QuotaInfo) GetKind() string {
+	if m != nil && m.Kind != nil {
+		return *m.Kind
+	}
+	return ""
+}
+
+func (m *QuotaInfo) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *QuotaInfo) GetMaxConcurrentQueries() int64 {
+	if m != nil && m.MaxConcurrentQueries != nil {
+		return *m.MaxConcurrentQueries
+	}
+	return 0
+}
+
+func (m *QuotaInfo) GetMaxQueriesPerMinute() int64 {
+	if m != nil && m.MaxQueriesPerMinute != nil {
+		return *m.MaxQueriesPerMinute
+	}
+	return 0
+}
+
+func (m *QuotaInfo) GetMaxPointsScanned() int64 {
+	if m != nil && m.MaxPointsScanned != nil {
+		return *m.MaxPointsScanned
+	}
+	return 0
+}
+
+func (m *QuotaInfo) GetMaxBytesReturned() int64 {
+	if m != nil && m.MaxBytesReturned != nil {
+		return *m.MaxBytesReturned
+	}
+	return 0
+}
+
 type Command struct {
 	Type                         *Command_Type `protobuf:"varint,1,req,name=type,enum=meta.Command_Type" json:"type,omitempty"`
 	proto.XXX_InternalExtensions `json:"-"`
@@ -1821,6 +2188,9 @@ func init() {
 	proto.RegisterType((*DatabaseInfo)(nil), "meta.DatabaseInfo")
 	proto.RegisterType((*RetentionPolicySpec)(nil), "meta.RetentionPolicySpec")
 	proto.RegisterType((*RetentionPolicyInfo)(nil), "meta.RetentionPolicyInfo")
+	proto.RegisterType((*RetentionRuleInfo)(nil), "meta.RetentionRuleInfo")
+	proto.RegisterType((*DownsampleInfo)(nil), "meta.DownsampleInfo")
+	proto.RegisterType((*DownsampleProgressInfo)(nil), "meta.DownsampleProgressInfo")
 	proto.RegisterType((*ShardGroupInfo)(nil), "meta.ShardGroupInfo")
 	proto.RegisterType((*ShardInfo)(nil), "meta.ShardInfo")
 	proto.RegisterType((*SubscriptionInfo)(nil), "meta.SubscriptionInfo")
@@ -1828,6 +2198,10 @@ func init() {
 	proto.RegisterType((*ContinuousQueryInfo)(nil), "meta.ContinuousQueryInfo")
 	proto.RegisterType((*UserInfo)(nil), "meta.UserInfo")
 	proto.RegisterType((*UserPrivilege)(nil), "meta.UserPrivilege")
+	proto.RegisterType((*RoleInfo)(nil), "meta.RoleInfo")
+	proto.RegisterType((*GrantInfo)(nil), "meta.GrantInfo")
+	proto.RegisterType((*TokenInfo)(nil), "meta.TokenInfo")
+	proto.RegisterType((*QuotaInfo)(nil), "meta.QuotaInfo")
 	proto.RegisterType((*Command)(nil), "meta.Command")
 	proto.RegisterType((*CreateNodeCommand)(nil), "meta.CreateNodeCommand")
 	proto.RegisterType((*DeleteNodeCommand)(nil), "meta.DeleteNodeCommand")
@@ -72,7 +72,7 @@ func (a *QueryAuthorizer) AuthorizeDatabase(u User, priv influxql.Privilege, dat
 func (u *UserInfo) AuthorizeQuery(database string, query *influxql.Query) error {
 
 	// Admin privilege allows the user to execute all statements.
-	if u.Admin {
+	if u.AuthorizeUnrestricted() {
 		return nil
 	}
 
@@ -105,19 +105,86 @@ func (u *UserInfo) AuthorizeQuery(database string, query *influxql.Query) error
 			if db == "" {
 				db = database
 			}
-			if !u.AuthorizeDatabase(p.Privilege, db) {
-				return &ErrAuthorize{
-					Query:    query,
-					User:     u.Name,
-					Database: database,
-					Message:  fmt.Sprintf("statement '%s', requires %s on %s", stmt, p.Privilege.String(), db),
-				}
+			if u.authorizeDatabase(p.Privilege, db) {
+				continue
+			}
+
+			// Grants on a subset of the measurements of the database allow
+			// statements reading the measurements they cover.
+			if p.Privilege == influxql.ReadPrivilege && u.authorizeSources(stmt, db) {
+				continue
+			}
+
+			return &ErrAuthorize{
+				Query:    query,
+				User:     u.Name,
+				Database: database,
+				Message:  fmt.Sprintf("statement '%s', requires %s on %s", stmt, p.Privilege.String(), db),
 			}
 		}
 	}
 	return nil
 }
 
+// authorizeSources returns true if the user's grants allow reading every
+// measurement of database that stmt reads from. Series of measurements matched
+// by a regular expression are filtered by AuthorizeSeriesRead instead.
+func (u *UserInfo) authorizeSources(stmt influxql.Statement, database string) bool {
+	switch stmt := stmt.(type) {
+	case *influxql.SelectStatement,
+		*influxql.ShowMeasurementsStatement,
+		*influxql.ShowSeriesStatement,
+		*influxql.ShowTagKeysStatement,
+		*influxql.ShowTagValuesStatement,
+		*influxql.ShowRetentionPoliciesStatement:
+	case *influxql.ShowFieldKeysStatement:
+		// Field keys aren't filtered per series, so the measurements
+		// must be named.
+		if len(stmt.Sources) == 0 {
+			return false
+		}
+		for _, m := range stmt.Sources.Measurements() {
+			if m.Regex != nil {
+				return false
+			}
+		}
+	default:
+		return false
+	}
+
+	if !u.AuthorizeMeasurement(influxql.ReadPrivilege, database, "", nil) {
+		return false
+	}
+
+	authorized := true
+	influxql.WalkFunc(stmt, func(n influxql.Node) {
+		m, ok := n.(*influxql.Measurement)
+		if !ok || m.IsTarget || !authorized {
+			return
+		}
+
+		db := m.Database
+		if db == "" {
+			db = database
+		}
+		if db != database {
+			return
+		}
+
+		rp := m.RetentionPolicy
+		if rp == "" {
+			rp = u.defaultRetentionPolicy(db)
+		}
+
+		var name []byte
+		if m.Regex == nil {
+			name = []byte(m.Name)
+		}
+		authorized = u.AuthorizeMeasurement(influxql.ReadPrivilege, db, rp, name)
+	})
+	return authorized
+}
+
 // ErrAuthorize represents an authorization error.
 type ErrAuthorize struct {
 	Query    *influxql.Query
@@ -133,3 +200,8 @@ func (e ErrAuthorize) Error() string {
 	}
 	return fmt.Sprintf("%s not authorized to execute %s", e.User, e.Message)
 }
+
+// AuthorizationFailed returns true to mark the error as an authorization failure.
+func (e ErrAuthorize) AuthorizationFailed() bool {
+	return true
+}
@@ -16,10 +16,11 @@ func NewWriteAuthorizer(c *Client) *WriteAuthorizer {
 	return &WriteAuthorizer{Client: c}
 }
 
-// AuthorizeWrite returns nil if the user has permission to write to the database.
+// AuthorizeWrite returns nil if the user has permission to write to the database, or to some of
+// its measurements. Writes to measurements the user isn't granted are rejected by the points writer.
 func (a WriteAuthorizer) AuthorizeWrite(username, database string) error {
 	u, err := a.Client.User(username)
-	if err != nil || u == nil || !u.AuthorizeDatabase(influxql.WritePrivilege, database) {
+	if err != nil || u == nil || !u.AuthorizeMeasurement(influxql.WritePrivilege, database, "", nil) {
 		return &ErrAuthorize{
 			Database: database,
 			Message:  fmt.Sprintf("%s not authorized to write to %s", username, database),
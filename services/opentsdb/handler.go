@@ -6,8 +6,10 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"math"
 	"net"
 	"net/http"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -129,6 +131,10 @@ func (h *Handler) servePut(w http.ResponseWriter, r *http.Request) {
 		h.Logger.Info("Write series error", zap.Error(err))
 		http.Error(w, "write series error: "+err.Error(), http.StatusBadRequest)
 		return
+	} else if rerr, ok := err.(influxdb.RateLimitError); ok {
+		w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(rerr.RetryAfter.Seconds())), 10))
+		http.Error(w, "write series error: "+err.Error(), http.StatusTooManyRequests)
+		return
 	} else if err != nil {
 		h.Logger.Info("Write series error", zap.Error(err))
 		http.Error(w, "write series error: "+err.Error(), http.StatusInternalServerError)
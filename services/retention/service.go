@@ -2,11 +2,14 @@
 package retention // import "github.com/influxdata/influxdb/services/retention"
 
 import (
+	"regexp"
 	"sync"
 	"time"
 
 	"github.com/influxdata/influxdb/logger"
 	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxql"
 	"go.uber.org/zap"
 )
 
@@ -20,12 +23,23 @@ type Service struct {
 	TSDBStore interface {
 		ShardIDs() []uint64
 		DeleteShard(shardID uint64) error
+		DeleteSeriesInShards(database string, shardIDs []uint64, sources []influxql.Source, condition influxql.Expr) error
+		ShardTier(id uint64) string
+		MoveShardToColdTier(id uint64) error
 	}
 
+	// ColdShardAge is how long after its shard group ends a shard is moved to
+	// the cold tier. Shards are never moved when it is zero.
+	ColdShardAge time.Duration
+
 	config Config
 	wg     sync.WaitGroup
 	done   chan struct{}
 
+	// The retention rules already enforced on shards whose data is all
+	// older than the cutoff of the rule. Only accessed by the run goroutine.
+	enforced map[enforcedRule]struct{}
+
 	logger *zap.Logger
 }
 
@@ -82,10 +96,6 @@ func (s *Service) run() {
 		case <-ticker.C:
 			log, logEnd := logger.NewOperation(s.logger, "Retention policy deletion check", "retention_delete_check")
 
-			type deletionInfo struct {
-				db string
-				rp string
-			}
 			deletedShardIDs := make(map[uint64]deletionInfo)
 
 			// Mark down if an error occurred during this function so we can inform the
@@ -147,6 +157,16 @@ func (s *Service) run() {
 				}
 			}
 
+			// Expire the series matched by retention rules from the remaining shards.
+			if s.enforceRetentionRules(log, dbs, deletedShardIDs) {
+				retryNeeded = true
+			}
+
+			// Move the shards of old shard groups to the cold tier.
+			if s.moveColdShards(log, dbs, deletedShardIDs) {
+				retryNeeded = true
+			}
+
 			if err := s.MetaClient.PruneShardGroups(); err != nil {
 				log.Info("Problem pruning shard groups", zap.Error(err))
 				retryNeeded = true
@@ -160,3 +180,172 @@ func (s *Service) run() {
 		}
 	}
 }
+
+// moveColdShards moves the local shards of shard groups which ended more than
+// ColdShardAge ago to the cold tier. It returns true if any shard could not be
+// moved and should be retried.
+func (s *Service) moveColdShards(log *zap.Logger, dbs []meta.DatabaseInfo, deletedShardIDs map[uint64]deletionInfo) bool {
+	if s.ColdShardAge <= 0 {
+		return false
+	}
+
+	var retryNeeded bool
+	now := time.Now().UTC()
+	for _, d := range dbs {
+		for _, r := range d.RetentionPolicies {
+			for _, g := range r.ShardGroups {
+				if g.Deleted() || !g.EndTime.Add(s.ColdShardAge).Before(now) {
+					continue
+				}
+
+				for _, sh := range g.Shards {
+					if _, ok := deletedShardIDs[sh.ID]; ok {
+						continue
+					}
+
+					// Skip shards which are not stored locally or already moved.
+					if s.TSDBStore.ShardTier(sh.ID) != tsdb.HotTier {
+						continue
+					}
+
+					if err := s.TSDBStore.MoveShardToColdTier(sh.ID); err == tsdb.ErrShardNotIdle {
+						// The shard is moved on a later check, once fully compacted.
+						continue
+					} else if err != nil {
+						log.Info("Failed to move shard to cold tier",
+							logger.Database(d.Name),
+							logger.Shard(sh.ID),
+							logger.RetentionPolicy(r.Name),
+							zap.Error(err))
+						retryNeeded = true
+						continue
+					}
+
+					log.Info("Moved shard to cold tier",
+						logger.Database(d.Name),
+						logger.Shard(sh.ID),
+						logger.RetentionPolicy(r.Name))
+				}
+			}
+		}
+	}
+	return retryNeeded
+}
+
+type deletionInfo struct {
+	db string
+	rp string
+}
+
+// enforcedRule identifies a retention rule enforced on a shard.
+type enforcedRule struct {
+	database string
+	policy   string
+	rule     meta.RetentionRuleInfo
+	shardID  uint64
+}
+
+// enforceRetentionRules deletes the data expired by each retention rule from
+// the shards of its retention policy which are still alive. Shards whose data
+// was all expired by a previous check are skipped. It returns true if any rule
+// could not be enforced and should be retried.
+func (s *Service) enforceRetentionRules(log *zap.Logger, dbs []meta.DatabaseInfo, deletedShardIDs map[uint64]deletionInfo) bool {
+	var retryNeeded bool
+	now := time.Now().UTC()
+
+	// Rebuilt on each check so that deleted shards and rules are dropped.
+	enforced := make(map[enforcedRule]struct{})
+	defer func() { s.enforced = enforced }()
+
+	for _, d := range dbs {
+		for _, r := range d.RetentionPolicies {
+			for _, rule := range r.RetentionRules {
+				cutoff := now.Add(-rule.Duration)
+
+				// Only shard groups starting before the cutoff can hold expired data.
+				var shardIDs []uint64
+				var expired []enforcedRule
+				for _, g := range r.ShardGroups {
+					if g.Deleted() || !g.StartTime.Before(cutoff) {
+						continue
+					}
+					for _, sh := range g.Shards {
+						if _, ok := deletedShardIDs[sh.ID]; ok {
+							continue
+						}
+
+						// Shards ending before the cutoff expire as a whole, so the
+						// rule only needs to be enforced on them once.
+						if !g.EndTime.After(cutoff) {
+							key := enforcedRule{database: d.Name, policy: r.Name, rule: rule, shardID: sh.ID}
+							if _, ok := s.enforced[key]; ok {
+								enforced[key] = struct{}{}
+								continue
+							}
+							expired = append(expired, key)
+						}
+						shardIDs = append(shardIDs, sh.ID)
+					}
+				}
+				if len(shardIDs) == 0 {
+					continue
+				}
+
+				sources, cond, err := retentionRuleFilter(rule, cutoff)
+				if err == nil {
+					err = s.TSDBStore.DeleteSeriesInShards(d.Name, shardIDs, sources, cond)
+				}
+				if err != nil {
+					log.Info("Failed to enforce retention rule",
+						logger.Database(d.Name),
+						logger.RetentionPolicy(r.Name),
+						zap.String("retention_rule", rule.Name),
+						zap.Error(err))
+					retryNeeded = true
+					continue
+				}
+
+				for _, key := range expired {
+					enforced[key] = struct{}{}
+				}
+
+				log.Info("Enforced retention rule",
+					logger.Database(d.Name),
+					logger.RetentionPolicy(r.Name),
+					zap.String("retention_rule", rule.Name),
+					zap.Int("shards", len(shardIDs)))
+			}
+		}
+	}
+	return retryNeeded
+}
+
+// retentionRuleFilter returns the sources and condition selecting the series
+// matched by rule and their points older than cutoff.
+func retentionRuleFilter(rule meta.RetentionRuleInfo, cutoff time.Time) (influxql.Sources, influxql.Expr, error) {
+	var sources influxql.Sources
+	re, err := rule.MeasurementRegex()
+	if err != nil {
+		return nil, nil, err
+	} else if re != nil {
+		sources = influxql.Sources{&influxql.Measurement{Regex: &influxql.RegexLiteral{Val: re}}}
+	}
+
+	var cond influxql.Expr = &influxql.BinaryExpr{
+		Op:  influxql.LT,
+		LHS: &influxql.VarRef{Val: "time"},
+		RHS: &influxql.TimeLiteral{Val: cutoff},
+	}
+	if rule.TagKey != "" {
+		cond = &influxql.BinaryExpr{
+			Op: influxql.AND,
+			LHS: &influxql.BinaryExpr{
+				Op:  influxql.EQREGEX,
+				LHS: &influxql.VarRef{Val: rule.TagKey},
+				RHS: &influxql.RegexLiteral{Val: regexp.MustCompile("^" + regexp.QuoteMeta(rule.TagPrefix))},
+			},
+			RHS: cond,
+		}
+	}
+	return sources, cond, nil
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -13,6 +14,8 @@ import (
 	"github.com/influxdata/influxdb/services/meta"
 	"github.com/influxdata/influxdb/services/retention"
 	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxql"
 )
 
 func TestService_OpenDisabled(t *testing.T) {
@@ -206,6 +209,277 @@ func TestService_CheckShards(t *testing.T) {
 	}
 }
 
+func TestService_EnforceRetentionRules(t *testing.T) {
+	now := time.Now().UTC().Truncate(24 * time.Hour)
+	data := []meta.DatabaseInfo{
+		{
+			Name: "db0",
+
+			DefaultRetentionPolicy: "rp0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{
+				{
+					Name:               "rp0",
+					ReplicaN:           1,
+					Duration:           30 * 24 * time.Hour,
+					ShardGroupDuration: 24 * time.Hour,
+					ShardGroups: []meta.ShardGroupInfo{
+						{
+							ID:        1,
+							StartTime: now.Add(-10 * 24 * time.Hour),
+							EndTime:   now.Add(-9 * 24 * time.Hour),
+							Shards:    []meta.ShardInfo{{ID: 2}},
+						},
+						{
+							ID:        3,
+							StartTime: now.Add(-11 * 24 * time.Hour),
+							EndTime:   now.Add(-10 * 24 * time.Hour),
+							DeletedAt: now,
+							Shards:    []meta.ShardInfo{{ID: 4}},
+						},
+						{
+							ID:        5,
+							StartTime: now,
+							EndTime:   now.Add(24 * time.Hour),
+							Shards:    []meta.ShardInfo{{ID: 6}},
+						},
+					},
+					RetentionRules: []meta.RetentionRuleInfo{
+						{Name: "debug", Measurement: "debug_*", TagKey: "host", TagPrefix: "tmp-", Duration: 3 * 24 * time.Hour},
+					},
+				},
+			},
+		},
+	}
+
+	config := retention.NewConfig()
+	config.CheckInterval = toml.Duration(10 * time.Millisecond)
+	s := NewService(config)
+	s.MetaClient.DatabasesFn = func() []meta.DatabaseInfo { return data }
+	s.MetaClient.DeleteShardGroupFn = func(database, policy string, id uint64) error { return nil }
+	s.MetaClient.PruneShardGroupsFn = func() error { return nil }
+	s.TSDBStore.ShardIDsFn = func() []uint64 { return []uint64{2, 6} }
+	s.TSDBStore.DeleteShardFn = func(shardID uint64) error { return nil }
+
+	type deleteRequest struct {
+		database  string
+		shardIDs  []uint64
+		sources   string
+		condition string
+	}
+	deleted := make(chan deleteRequest, 1)
+	s.TSDBStore.DeleteSeriesInShardsFn = func(database string, shardIDs []uint64, sources []influxql.Source, condition influxql.Expr) error {
+		select {
+		case deleted <- deleteRequest{
+			database:  database,
+			shardIDs:  shardIDs,
+			sources:   influxql.Sources(sources).String(),
+			condition: condition.String(),
+		}:
+		default:
+		}
+		return nil
+	}
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("unexpected open error: %s", err)
+	}
+	defer s.Close()
+
+	select {
+	case req := <-deleted:
+		if got, exp := req.database, "db0"; got != exp {
+			t.Errorf("unexpected database: got %s, exp %s", got, exp)
+		}
+		if got, exp := req.shardIDs, []uint64{2}; !reflect.DeepEqual(got, exp) {
+			t.Errorf("unexpected shards: got %v, exp %v", got, exp)
+		}
+		if got, exp := req.sources, "/^debug_.*$/"; got != exp {
+			t.Errorf("unexpected sources: got %s, exp %s", got, exp)
+		}
+		if got, exp := req.condition, "host =~ /^tmp-/ AND time < "; !strings.HasPrefix(got, exp) {
+			t.Errorf("unexpected condition: got %s, exp prefix %s", got, exp)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for retention rule to be enforced")
+	}
+}
+
+// Ensure a retention rule is enforced once on shards whose data is all older
+// than its cutoff, and on every check on shards holding newer data.
+func TestService_EnforceRetentionRules_Once(t *testing.T) {
+	now := time.Now().UTC().Truncate(24 * time.Hour)
+	data := []meta.DatabaseInfo{
+		{
+			Name: "db0",
+
+			DefaultRetentionPolicy: "rp0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{
+				{
+					Name:               "rp0",
+					ReplicaN:           1,
+					Duration:           30 * 24 * time.Hour,
+					ShardGroupDuration: 7 * 24 * time.Hour,
+					ShardGroups: []meta.ShardGroupInfo{
+						{
+							ID:        1,
+							StartTime: now.Add(-14 * 24 * time.Hour),
+							EndTime:   now.Add(-7 * 24 * time.Hour),
+							Shards:    []meta.ShardInfo{{ID: 2}},
+						},
+						{
+							ID:        3,
+							StartTime: now.Add(-7 * 24 * time.Hour),
+							EndTime:   now,
+							Shards:    []meta.ShardInfo{{ID: 4}},
+						},
+					},
+					RetentionRules: []meta.RetentionRuleInfo{
+						{Name: "debug", Measurement: "debug_*", Duration: 3 * 24 * time.Hour},
+					},
+				},
+			},
+		},
+	}
+
+	config := retention.NewConfig()
+	config.CheckInterval = toml.Duration(10 * time.Millisecond)
+	s := NewService(config)
+	s.MetaClient.DatabasesFn = func() []meta.DatabaseInfo { return data }
+	s.MetaClient.DeleteShardGroupFn = func(database, policy string, id uint64) error { return nil }
+	s.MetaClient.PruneShardGroupsFn = func() error { return nil }
+	s.TSDBStore.ShardIDsFn = func() []uint64 { return []uint64{2, 4} }
+	s.TSDBStore.DeleteShardFn = func(shardID uint64) error { return nil }
+
+	deleted := make(chan []uint64, 3)
+	s.TSDBStore.DeleteSeriesInShardsFn = func(database string, shardIDs []uint64, sources []influxql.Source, condition influxql.Expr) error {
+		select {
+		case deleted <- shardIDs:
+		default:
+		}
+		return nil
+	}
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("unexpected open error: %s", err)
+	}
+	defer s.Close()
+
+	for i, exp := range [][]uint64{{2, 4}, {4}, {4}} {
+		select {
+		case got := <-deleted:
+			if !reflect.DeepEqual(got, exp) {
+				t.Fatalf("unexpected shards of check %d: got %v, exp %v", i, got, exp)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for retention rule to be enforced")
+		}
+	}
+}
+
+func TestService_MoveColdShards(t *testing.T) {
+	now := time.Now().UTC()
+	day := 24 * time.Hour
+	data := []meta.DatabaseInfo{
+		{
+			Name: "db0",
+
+			DefaultRetentionPolicy: "rp0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{
+				{
+					Name:               "rp0",
+					ReplicaN:           1,
+					Duration:           30 * day,
+					ShardGroupDuration: day,
+					ShardGroups: []meta.ShardGroupInfo{
+						{
+							ID:        1,
+							StartTime: now.Add(-10 * day),
+							EndTime:   now.Add(-9 * day),
+							Shards:    []meta.ShardInfo{{ID: 2}, {ID: 3}, {ID: 4}, {ID: 5}},
+						},
+						{
+							ID:        6,
+							StartTime: now.Add(-11 * day),
+							EndTime:   now.Add(-10 * day),
+							DeletedAt: now,
+							Shards:    []meta.ShardInfo{{ID: 7}},
+						},
+						{
+							ID:        8,
+							StartTime: now.Add(-day),
+							EndTime:   now,
+							Shards:    []meta.ShardInfo{{ID: 9}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	config := retention.NewConfig()
+	config.CheckInterval = toml.Duration(10 * time.Millisecond)
+	s := NewService(config)
+	s.ColdShardAge = 7 * day
+	s.MetaClient.DatabasesFn = func() []meta.DatabaseInfo { return data }
+	s.MetaClient.DeleteShardGroupFn = func(database, policy string, id uint64) error { return nil }
+	s.MetaClient.PruneShardGroupsFn = func() error { return nil }
+	s.TSDBStore.ShardIDsFn = func() []uint64 { return []uint64{2, 4, 5, 7, 9} }
+	s.TSDBStore.DeleteShardFn = func(shardID uint64) error { return nil }
+	s.TSDBStore.ShardTierFn = func(id uint64) string {
+		switch id {
+		case 3:
+			return "" // not stored on this server
+		case 4:
+			return tsdb.ColdTier
+		default:
+			return tsdb.HotTier
+		}
+	}
+
+	var mu sync.Mutex
+	var requested []uint64
+	moved := make(chan uint64, 1)
+	s.TSDBStore.MoveShardToColdTierFn = func(id uint64) error {
+		mu.Lock()
+		requested = append(requested, id)
+		mu.Unlock()
+
+		if id == 5 {
+			return tsdb.ErrShardNotIdle
+		}
+		select {
+		case moved <- id:
+		default:
+		}
+		return nil
+	}
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("unexpected open error: %s", err)
+	}
+
+	select {
+	case id := <-moved:
+		if id != 2 {
+			t.Errorf("unexpected shard moved: %d", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for shard to be moved")
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected close error: %s", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, id := range requested {
+		if id != 2 && id != 5 {
+			t.Errorf("unexpected move of shard %d", id)
+		}
+	}
+}
+
 // This reproduces https://github.com/influxdata/influxdb/issues/8819
 func TestService_8819_repro(t *testing.T) {
 	for i := 0; i < 1000; i++ {
@@ -49,6 +49,12 @@ type Service struct {
 		CreateShard(database, retentionPolicy string, shardID uint64, enabled bool) error
 	}
 
+	// BackupService backs up to a blob store. Blob store backups are not
+	// available when it is nil.
+	BackupService interface {
+		Backup(database, retentionPolicy string) (string, error)
+	}
+
 	Listener net.Listener
 	Logger   *zap.Logger
 }
@@ -149,6 +155,8 @@ func (s *Service) handleConn(conn net.Conn) error {
 		return s.writeRetentionPolicyInfo(conn, r.BackupDatabase, r.BackupRetentionPolicy)
 	case RequestMetaStoreUpdate:
 		return s.updateMetaStore(conn, bytes, r.BackupDatabase, r.RestoreDatabase, r.BackupRetentionPolicy, r.RestoreRetentionPolicy)
+	case RequestBlobBackup:
+		return s.writeBlobBackup(conn, r.BackupDatabase, r.BackupRetentionPolicy)
 	default:
 		return fmt.Errorf("request type unknown: %v", r.Type)
 	}
@@ -374,6 +382,24 @@ func (s *Service) writeRetentionPolicyInfo(conn net.Conn, database, retentionPol
 	return nil
 }
 
+// writeBlobBackup backs up the database and retention policy to the blob
+// store and writes the key of the manifest of the backup into the connection.
+func (s *Service) writeBlobBackup(conn net.Conn, database, retentionPolicy string) error {
+	res := Response{}
+	if s.BackupService == nil {
+		res.Err = "blob store backups are not enabled"
+	} else if key, err := s.BackupService.Backup(database, retentionPolicy); err != nil {
+		res.Err = err.Error()
+	} else {
+		res.Paths = []string{key}
+	}
+
+	if err := json.NewEncoder(conn).Encode(res); err != nil {
+		return fmt.Errorf("encode response: %s", err.Error())
+	}
+	return nil
+}
+
 // readRequest unmarshals a request object from the conn.
 func (s *Service) readRequest(conn net.Conn) (Request, []byte, error) {
 	var r Request
@@ -440,6 +466,11 @@ const (
 	// RequestShardUpdate will initiate the upload of a shard data tar file
 	// and have the engine import the data.
 	RequestShardUpdate
+
+	// RequestBlobBackup represents a request to back up to the blob store
+	// configured on the server. The response holds the key of the manifest
+	// of the backup.
+	RequestBlobBackup
 )
 
 // Request represents a request for a specific backup or for information
@@ -461,4 +492,5 @@ type Request struct {
 // that are in the requested database or retention policy.
 type Response struct {
 	Paths []string
+	Err   string `json:",omitempty"`
 }
@@ -379,6 +379,78 @@ func TestSnapshotter_RequestRetentionPolicyInfo(t *testing.T) {
 	}
 }
 
+func TestSnapshotter_RequestBlobBackup(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		backup *BackupService
+		exp    []string
+		expErr string
+	}{
+		{
+			name:   "ok",
+			backup: &BackupService{Key: "manifests/20190101T000000Z.00.manifest"},
+			exp:    []string{"manifests/20190101T000000Z.00.manifest"},
+		},
+		{
+			name:   "error",
+			backup: &BackupService{Err: fmt.Errorf("marker")},
+			expErr: "marker",
+		},
+		{
+			name:   "disabled",
+			expErr: "blob store backups are not enabled",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s, l, err := NewTestService()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+
+			if tt.backup != nil {
+				s.BackupService = tt.backup
+			}
+			if err := s.Open(); err != nil {
+				t.Fatalf("unexpected open error: %s", err)
+			}
+			defer s.Close()
+
+			conn, err := net.Dial("tcp", l.Addr().String())
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			defer conn.Close()
+
+			req := snapshotter.Request{
+				Type:                  snapshotter.RequestBlobBackup,
+				BackupDatabase:        "db0",
+				BackupRetentionPolicy: "rp0",
+			}
+			conn.Write([]byte{snapshotter.MuxHeader})
+			conn.Write([]byte{byte(req.Type)})
+			if err := json.NewEncoder(conn).Encode(&req); err != nil {
+				t.Fatalf("unable to encode request: %s", err)
+			}
+
+			var resp snapshotter.Response
+			if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+				t.Fatalf("error decoding response: %s", err)
+			}
+
+			if got, want := resp.Err, tt.expErr; got != want {
+				t.Errorf("unexpected error: got=%q want=%q", got, want)
+			}
+			if got, want := resp.Paths, tt.exp; !reflect.DeepEqual(got, want) {
+				t.Errorf("unexpected paths: got=%#v want=%#v", got, want)
+			}
+			if tt.backup != nil && (tt.backup.database != "db0" || tt.backup.retentionPolicy != "rp0") {
+				t.Errorf("unexpected backup of %s.%s", tt.backup.database, tt.backup.retentionPolicy)
+			}
+		})
+	}
+}
+
 func TestSnapshotter_InvalidRequest(t *testing.T) {
 	s, l, err := NewTestService()
 	if err != nil {
@@ -447,3 +519,16 @@ func (m *MetaClient) Database(name string) *meta.DatabaseInfo {
 	}
 	return nil
 }
+
+// BackupService is a mockable blob store backup service.
+type BackupService struct {
+	Key string
+	Err error
+
+	database, retentionPolicy string
+}
+
+func (s *BackupService) Backup(database, retentionPolicy string) (string, error) {
+	s.database, s.retentionPolicy = database, retentionPolicy
+	return s.Key, s.Err
+}
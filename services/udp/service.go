@@ -8,6 +8,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/logger"
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/services/meta"
@@ -32,6 +33,7 @@ const (
 	statBatchesTransmitted  = "batchesTx"
 	statPointsTransmitted   = "pointsTx"
 	statBatchesTransmitFail = "batchesTxFail"
+	statPointsRateLimited   = "pointsRateLimited"
 )
 
 // Service is a UDP service that will listen for incoming packets of line protocol.
@@ -134,6 +136,7 @@ type Statistics struct {
 	BatchesTransmitted  int64
 	PointsTransmitted   int64
 	BatchesTransmitFail int64
+	PointsRateLimited   int64
 }
 
 // Statistics returns statistics for periodic monitoring.
@@ -149,6 +152,7 @@ func (s *Service) Statistics(tags map[string]string) []models.Statistic {
 			statBatchesTransmitted:  atomic.LoadInt64(&s.stats.BatchesTransmitted),
 			statPointsTransmitted:   atomic.LoadInt64(&s.stats.PointsTransmitted),
 			statBatchesTransmitFail: atomic.LoadInt64(&s.stats.BatchesTransmitFail),
+			statPointsRateLimited:   atomic.LoadInt64(&s.stats.PointsRateLimited),
 		},
 	}}
 }
@@ -169,6 +173,10 @@ func (s *Service) writer() {
 			if err := s.PointsWriter.WritePointsPrivileged(s.config.Database, s.config.RetentionPolicy, models.ConsistencyLevelAny, batch); err == nil {
 				atomic.AddInt64(&s.stats.BatchesTransmitted, 1)
 				atomic.AddInt64(&s.stats.PointsTransmitted, int64(len(batch)))
+			} else if _, ok := err.(influxdb.RateLimitError); ok {
+				// Drop the batch rather than blocking the listener.
+				atomic.AddInt64(&s.stats.BatchesTransmitFail, 1)
+				atomic.AddInt64(&s.stats.PointsRateLimited, int64(len(batch)))
 			} else {
 				s.Logger.Info("Failed to write point batch to database",
 					logger.Database(s.config.Database), zap.Error(err))
@@ -345,6 +345,115 @@ func TestServer_RetentionPolicyCommands(t *testing.T) {
 	}
 }
 
+// Ensure retention rules can be created, listed and dropped.
+func TestServer_RetentionRuleCommands(t *testing.T) {
+	t.Parallel()
+	s := OpenServer(NewConfig())
+	defer s.Close()
+
+	if _, err := s.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+
+	test := Test{
+		queries: []*Query{
+			&Query{
+				name:    "create measurement rule",
+				command: `CREATE RETENTION RULE debug ON db0.autogen MEASUREMENT "debug_*" DURATION 1d`,
+				exp:     `{"results":[{"statement_id":0}]}`,
+			},
+			&Query{
+				name:    "create tag rule on default policy",
+				command: `CREATE RETENTION RULE test_hosts ON db0 TAG host PREFIX 'test-' DURATION 2h`,
+				exp:     `{"results":[{"statement_id":0}]}`,
+			},
+			&Query{
+				name:    "show rules",
+				command: `SHOW RETENTION RULES ON db0`,
+				exp:     `{"results":[{"statement_id":0,"series":[{"columns":["retention_policy","name","measurement","tag_key","tag_prefix","duration"],"values":[["autogen","debug","debug_*","","","24h0m0s"],["autogen","test_hosts","","host","test-","2h0m0s"]]}]}]}`,
+			},
+			&Query{
+				name:    "drop rule",
+				command: `DROP RETENTION RULE debug ON db0.autogen`,
+				exp:     `{"results":[{"statement_id":0}]}`,
+			},
+			&Query{
+				name:    "show rules after drop",
+				command: `SHOW RETENTION RULES`,
+				params:  url.Values{"db": []string{"db0"}},
+				exp:     `{"results":[{"statement_id":0,"series":[{"columns":["retention_policy","name","measurement","tag_key","tag_prefix","duration"],"values":[["autogen","test_hosts","","host","test-","2h0m0s"]]}]}]}`,
+			},
+		},
+	}
+
+	for _, query := range test.queries {
+		t.Run(query.name, func(t *testing.T) {
+			if query.skip {
+				t.Skipf("SKIP:: %s", query.name)
+			}
+			if err := query.Execute(s); err != nil {
+				t.Error(query.Error(err))
+			} else if !query.success() {
+				t.Error(query.failureMessage())
+			}
+		})
+	}
+}
+
+// Ensure downsamples can be created, listed and dropped.
+func TestServer_DownsampleCommands(t *testing.T) {
+	t.Parallel()
+	s := OpenServer(NewConfig())
+	defer s.Close()
+
+	if _, err := s.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+
+	test := Test{
+		queries: []*Query{
+			&Query{
+				name:    "create target policy",
+				command: `CREATE RETENTION POLICY rp_1h ON db0 DURATION INF REPLICATION 1`,
+				exp:     `{"results":[{"statement_id":0}]}`,
+			},
+			&Query{
+				name:    "create downsample",
+				command: `CREATE DOWNSAMPLE d1h ON db0.autogen INTO rp_1h EVERY 1h WITH float max, boolean first`,
+				exp:     `{"results":[{"statement_id":0}]}`,
+			},
+			&Query{
+				name:    "show downsamples",
+				command: `SHOW DOWNSAMPLES ON db0`,
+				exp:     `{"results":[{"statement_id":0,"series":[{"columns":["retention_policy","name","target_policy","interval","float","integer","unsigned","boolean","string","completed_shard_groups","pending_shard_groups","last_completed"],"values":[["autogen","d1h","rp_1h","1h0m0s","max","mean","mean","first","last",0,0,null]]}]}]}`,
+			},
+			&Query{
+				name:    "drop downsample",
+				command: `DROP DOWNSAMPLE d1h ON db0`,
+				exp:     `{"results":[{"statement_id":0}]}`,
+			},
+			&Query{
+				name:    "show downsamples after drop",
+				command: `SHOW DOWNSAMPLES ON db0`,
+				exp:     `{"results":[{"statement_id":0,"series":[{"columns":["retention_policy","name","target_policy","interval","float","integer","unsigned","boolean","string","completed_shard_groups","pending_shard_groups","last_completed"]}]}]}`,
+			},
+		},
+	}
+
+	for _, query := range test.queries {
+		t.Run(query.name, func(t *testing.T) {
+			if query.skip {
+				t.Skipf("SKIP:: %s", query.name)
+			}
+			if err := query.Execute(s); err != nil {
+				t.Error(query.Error(err))
+			} else if !query.success() {
+				t.Error(query.failureMessage())
+			}
+		})
+	}
+}
+
 // Ensure the autocreation of retention policy works.
 func TestServer_DatabaseRetentionPolicyAutoCreate(t *testing.T) {
 	t.Parallel()
@@ -367,6 +476,123 @@ func TestServer_DatabaseRetentionPolicyAutoCreate(t *testing.T) {
 	}
 }
 
+// Ensure roles can be created, granted measurement privileges and assigned to users.
+func TestServer_RoleCommands(t *testing.T) {
+	t.Parallel()
+	s := OpenServer(NewConfig())
+	defer s.Close()
+
+	if _, err := s.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+
+	test := Test{
+		queries: []*Query{
+			&Query{
+				name:    "create user",
+				command: `CREATE USER bob WITH PASSWORD 'secret'`,
+				exp:     `{"results":[{"statement_id":0}]}`,
+			},
+			&Query{
+				name:    "create role",
+				command: `CREATE ROLE readers`,
+				exp:     `{"results":[{"statement_id":0}]}`,
+			},
+			&Query{
+				name:    "grant measurement to role",
+				command: `GRANT READ ON MEASUREMENT cpu IN db0.autogen TO ROLE readers`,
+				exp:     `{"results":[{"statement_id":0}]}`,
+			},
+			&Query{
+				name:    "grant role to user",
+				command: `GRANT ROLE readers TO bob`,
+				exp:     `{"results":[{"statement_id":0}]}`,
+			},
+			&Query{
+				name:    "show roles",
+				command: `SHOW ROLES`,
+				exp:     `{"results":[{"statement_id":0,"series":[{"columns":["role","users"],"values":[["readers","bob"]]}]}]}`,
+			},
+			&Query{
+				name:    "show grants for role",
+				command: `SHOW GRANTS FOR ROLE readers`,
+				exp:     `{"results":[{"statement_id":0,"series":[{"columns":["database","retention_policy","measurement","privilege"],"values":[["db0","autogen","^cpu$","READ"]]}]}]}`,
+			},
+			&Query{
+				name:    "revoke role from user",
+				command: `REVOKE ROLE readers FROM bob`,
+				exp:     `{"results":[{"statement_id":0}]}`,
+			},
+			&Query{
+				name:    "drop role",
+				command: `DROP ROLE readers`,
+				exp:     `{"results":[{"statement_id":0}]}`,
+			},
+			&Query{
+				name:    "show roles after drop",
+				command: `SHOW ROLES`,
+				exp:     `{"results":[{"statement_id":0,"series":[{"columns":["role","users"]}]}]}`,
+			},
+		},
+	}
+
+	for _, query := range test.queries {
+		t.Run(query.name, func(t *testing.T) {
+			if err := query.Execute(s); err != nil {
+				t.Error(query.Error(err))
+			} else if !query.success() {
+				t.Error(query.failureMessage())
+			}
+		})
+	}
+}
+
+// Ensure query quotas can be set, listed and dropped.
+func TestServer_QuotaCommands(t *testing.T) {
+	t.Parallel()
+	s := OpenServer(NewConfig())
+	defer s.Close()
+
+	if _, err := s.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+
+	test := Test{
+		queries: []*Query{
+			&Query{
+				name:    "set database quota",
+				command: `SET QUOTA FOR DATABASE db0 CONCURRENT 2 POINTS 1000000`,
+				exp:     `{"results":[{"statement_id":0}]}`,
+			},
+			&Query{
+				name:    "show quotas",
+				command: `SHOW QUOTAS`,
+				exp:     `{"results":[{"statement_id":0,"series":[{"columns":["kind","name","max_concurrent_queries","max_queries_per_minute","max_points_scanned","max_bytes_returned"],"values":[["database","db0",2,0,1000000,0]]}]}]}`,
+			},
+			&Query{
+				name:    "drop database quota",
+				command: `DROP QUOTA FOR DATABASE db0`,
+				exp:     `{"results":[{"statement_id":0}]}`,
+			},
+			&Query{
+				name:    "show quotas after drop",
+				command: `SHOW QUOTAS`,
+				exp:     `{"results":[{"statement_id":0,"series":[{"columns":["kind","name","max_concurrent_queries","max_queries_per_minute","max_points_scanned","max_bytes_returned"]}]}]}`,
+			},
+		},
+	}
+
+	for _, query := range test.queries {
+		t.Run(query.name, func(t *testing.T) {
+			if err := query.Execute(s); err != nil {
+				t.Error(query.Error(err))
+			} else if !query.success() {
+				t.Error(query.failureMessage())
+			}
+		})
+	}
+}
+
 func TestServer_ShowDatabases_NoAuth(t *testing.T) {
 	t.Parallel()
 	s := OpenServer(NewConfig())
@@ -0,0 +1,370 @@
+package tsdb
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/bytesutil"
+	"github.com/influxdata/influxdb/pkg/estimator/hll"
+)
+
+// SeriesLimiter limits the number of series created in a database per minute.
+// It is shared by all shards of a database.
+type SeriesLimiter struct {
+	// Maximum number of series created per minute. A value of 0 disables the
+	// limit.
+	MaxPerMinute int
+
+	mu      sync.Mutex
+	window  time.Time
+	created int
+
+	// now returns the current time. Used for testing.
+	now func() time.Time
+}
+
+// NewSeriesLimiter returns a new instance of SeriesLimiter.
+func NewSeriesLimiter(maxPerMinute int) *SeriesLimiter {
+	return &SeriesLimiter{
+		MaxPerMinute: maxPerMinute,
+		now:          time.Now,
+	}
+}
+
+// enabled returns true if the limiter limits anything.
+func (l *SeriesLimiter) enabled() bool {
+	return l != nil && l.MaxPerMinute > 0
+}
+
+// take reserves the creation of a new series, or returns false if the limit
+// of the current minute has been reached.
+func (l *SeriesLimiter) take() bool {
+	if !l.enabled() {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if window := l.now().Truncate(time.Minute); !window.Equal(l.window) {
+		l.window, l.created = window, 0
+	}
+	if l.created >= l.MaxPerMinute {
+		return false
+	}
+	l.created++
+	return true
+}
+
+// measurementSeriesCounts caches the number of series of each measurement
+// in a shard. Counts are loaded from the index on first use and reset when
+// series are deleted.
+type measurementSeriesCounts struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// reset discards the cached counts.
+func (c *measurementSeriesCounts) reset() {
+	c.mu.Lock()
+	c.counts = nil
+	c.mu.Unlock()
+}
+
+// count returns the number of series of the measurement in the shard index.
+// c.mu must be held.
+func (c *measurementSeriesCounts) count(index Index, name []byte) (int, error) {
+	if n, ok := c.counts[string(name)]; ok {
+		return n, nil
+	}
+
+	n, err := shardMeasurementSeriesN(index, name)
+	if err != nil {
+		return 0, err
+	}
+	if c.counts == nil {
+		c.counts = make(map[string]int)
+	}
+	c.counts[string(name)] = n
+	return n, nil
+}
+
+// shardMeasurementSeriesN returns the number of series of a measurement in
+// a shard index. Indexes shared by the shards of a database are filtered by
+// the series of the shard.
+func shardMeasurementSeriesN(index Index, name []byte) (int, error) {
+	itr, err := index.MeasurementSeriesIDIterator(name)
+	if err != nil {
+		return 0, err
+	} else if itr == nil {
+		return 0, nil
+	}
+	defer itr.Close()
+
+	set := index.SeriesIDSet()
+	var n int
+	for {
+		e, err := itr.Next()
+		if err != nil {
+			return 0, err
+		} else if e.SeriesID == 0 {
+			return n, nil
+		}
+		if set.Contains(e.SeriesID) {
+			n++
+		}
+	}
+}
+
+// add counts the new series created by a write in the cached counts of their
+// measurement. newSeries maps the key of each new series to the name of its
+// measurement; the series dropped by the index are not counted. c.mu must be
+// held.
+func (c *measurementSeriesCounts) add(newSeries map[string][]byte, droppedKeys [][]byte) {
+	for key, name := range newSeries {
+		if len(droppedKeys) > 0 && bytesutil.Contains(droppedKeys, []byte(key)) {
+			continue
+		}
+		// Uncached counts are loaded from the index, which holds the series.
+		if n, ok := c.counts[string(name)]; ok {
+			c.counts[string(name)] = n + 1
+		}
+	}
+}
+
+// seriesLimited returns whether the shard limits the creation of new series.
+func (s *Shard) seriesLimited() bool {
+	return s.options.Config.MaxSeriesPerMeasurement > 0 || s.options.SeriesLimiter.enabled()
+}
+
+// limitNewSeries drops points creating new series beyond the series limits
+// of the shard. It returns the remaining points, the new series they create
+// by key, and the number of points dropped along with the reason the first
+// one was dropped. The new series are not counted until they are created.
+// s.mu and s.measurementSeries.mu must be held.
+func (s *Shard) limitNewSeries(points []models.Point, keys, names [][]byte, tagsSlice []models.Tags) ([]models.Point, [][]byte, [][]byte, []models.Tags, map[string][]byte, int, string, error) {
+	if !s.seriesLimited() {
+		return points, keys, names, tagsSlice, nil, 0, "", nil
+	}
+	maxSeries := s.options.Config.MaxSeriesPerMeasurement
+	limiter := s.options.SeriesLimiter
+
+	set := s.index.SeriesIDSet()
+
+	var (
+		dropped int
+		reason  string
+		buf     []byte
+		// Whether new series of the batch were admitted, so points of the
+		// same series are treated alike.
+		admitted  = make(map[string]bool)
+		newSeries = make(map[string][]byte)
+		// The number of new series admitted for each measurement.
+		pending = make(map[string]int)
+	)
+
+	var j int
+	for i := range points {
+		ok, seen := admitted[string(keys[i])]
+		if !seen {
+			ok = true
+			if id := s.sfile.SeriesID(names[i], tagsSlice[i], buf); id == 0 || !set.Contains(id) {
+				n, err := s.measurementSeries.count(s.index, names[i])
+				if err != nil {
+					return nil, nil, nil, nil, nil, 0, "", err
+				}
+				n += pending[string(names[i])]
+
+				// Series already in the series file were created by another
+				// shard of the database, such as the previous shard group, so
+				// they aren't new series of the database.
+				if maxSeries > 0 && n >= maxSeries {
+					ok = false
+					if reason == "" {
+						reason = fmt.Sprintf("max-series-per-measurement limit exceeded: measurement %q has %d series, limit %d", names[i], n, maxSeries)
+					}
+				} else if id == 0 && !limiter.take() {
+					ok = false
+					if reason == "" {
+						reason = fmt.Sprintf("max-new-series-per-minute limit exceeded: limit %d", limiter.MaxPerMinute)
+					}
+				} else {
+					pending[string(names[i])]++
+					newSeries[string(keys[i])] = names[i]
+				}
+			}
+			admitted[string(keys[i])] = ok
+		}
+
+		if !ok {
+			dropped++
+			continue
+		}
+		points[j], keys[j], names[j], tagsSlice[j] = points[i], keys[i], names[i], tagsSlice[i]
+		j++
+	}
+	return points[:j], keys[:j], names[:j], tagsSlice[:j], newSeries, dropped, reason, nil
+}
+
+// MeasurementSeriesIDSets returns the IDs of the series of each measurement
+// in the given shards.
+func (s *Store) MeasurementSeriesIDSets(shardIDs []uint64) (map[string]*SeriesIDSet, error) {
+	sets := make(map[string]*SeriesIDSet)
+	for _, sh := range s.Shards(shardIDs) {
+		index, err := sh.Index()
+		if err != nil {
+			return nil, err
+		}
+		shardSet := index.SeriesIDSet()
+
+		if err := index.ForEachMeasurementName(func(name []byte) error {
+			itr, err := index.MeasurementSeriesIDIterator(name)
+			if err != nil {
+				return err
+			} else if itr == nil {
+				return nil
+			}
+			defer itr.Close()
+
+			set := sets[string(name)]
+			if set == nil {
+				set = NewSeriesIDSet()
+				sets[string(name)] = set
+			}
+			for {
+				e, err := itr.Next()
+				if err != nil {
+					return err
+				} else if e.SeriesID == 0 {
+					return nil
+				}
+				if shardSet.Contains(e.SeriesID) {
+					set.Add(e.SeriesID)
+				}
+			}
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return sets, nil
+}
+
+// TagKeyCardinality holds the cardinality of a tag key of a measurement.
+type TagKeyCardinality struct {
+	Key string
+
+	// Estimated number of distinct values.
+	ValuesN int64
+
+	// Number of series with the tag key.
+	SeriesN int64
+}
+
+// TagKeyCardinalities returns the cardinality of the tag keys of a measurement
+// in the given shards, sorted by decreasing number of values. Values are
+// counted with HyperLogLog sketches.
+func (s *Store) TagKeyCardinalities(shardIDs []uint64, name []byte) ([]TagKeyCardinality, error) {
+	type tagKey struct {
+		values *hll.Plus
+		series *SeriesIDSet
+	}
+	keys := make(map[string]*tagKey)
+
+	for _, sh := range s.Shards(shardIDs) {
+		index, err := sh.Index()
+		if err != nil {
+			return nil, err
+		}
+		shardSet := index.SeriesIDSet()
+
+		kitr, err := index.TagKeyIterator(name)
+		if err != nil {
+			return nil, err
+		} else if kitr == nil {
+			continue
+		}
+
+		for {
+			key, err := kitr.Next()
+			if err != nil {
+				kitr.Close()
+				return nil, err
+			} else if key == nil {
+				break
+			}
+
+			k := keys[string(key)]
+			if k == nil {
+				k = &tagKey{values: hll.NewDefaultPlus(), series: NewSeriesIDSet()}
+				keys[string(key)] = k
+			}
+
+			if err := addTagValues(index, name, key, k.values); err != nil {
+				kitr.Close()
+				return nil, err
+			}
+
+			sitr, err := index.TagKeySeriesIDIterator(name, key)
+			if err != nil {
+				kitr.Close()
+				return nil, err
+			} else if sitr != nil {
+				for {
+					e, err := sitr.Next()
+					if err != nil {
+						sitr.Close()
+						kitr.Close()
+						return nil, err
+					} else if e.SeriesID == 0 {
+						break
+					}
+					if shardSet.Contains(e.SeriesID) {
+						k.series.Add(e.SeriesID)
+					}
+				}
+				sitr.Close()
+			}
+		}
+		kitr.Close()
+	}
+
+	a := make([]TagKeyCardinality, 0, len(keys))
+	for key, k := range keys {
+		a = append(a, TagKeyCardinality{
+			Key:     key,
+			ValuesN: int64(k.values.Count()),
+			SeriesN: int64(k.series.Cardinality()),
+		})
+	}
+	sort.Slice(a, func(i, j int) bool {
+		if a[i].ValuesN != a[j].ValuesN {
+			return a[i].ValuesN > a[j].ValuesN
+		}
+		return a[i].Key < a[j].Key
+	})
+	return a, nil
+}
+
+// addTagValues adds the values of a tag key to a sketch.
+func addTagValues(index Index, name, key []byte, sketch *hll.Plus) error {
+	itr, err := index.TagValueIterator(name, key)
+	if err != nil {
+		return err
+	} else if itr == nil {
+		return nil
+	}
+	defer itr.Close()
+
+	for {
+		value, err := itr.Next()
+		if err != nil {
+			return err
+		} else if value == nil {
+			return nil
+		}
+		sketch.Add(value)
+	}
+}
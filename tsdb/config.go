@@ -3,6 +3,9 @@ package tsdb
 import (
 	"errors"
 	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/influxdata/influxdb/monitor/diagnostics"
@@ -48,6 +51,18 @@ const (
 	// will be set to equal the normal throughput
 	DefaultCompactThroughputBurst = 48 * 1024 * 1024
 
+	// DefaultCompactPlanner is the default planner deciding which TSM files of
+	// a shard are compacted together.
+	DefaultCompactPlanner = "default"
+
+	// DefaultBulkLoadBufferSize is the size of the points a shard buffers in
+	// memory during a bulk load before spilling them to disk.
+	DefaultBulkLoadBufferSize = 64 * 1024 * 1024 // 64MB
+
+	// DefaultBulkLoadCommitInterval is the length of time without bulk writes
+	// after which a shard commits the points of a bulk load to TSM files.
+	DefaultBulkLoadCommitInterval = 10 * time.Second
+
 	// DefaultMaxPointsPerBlock is the maximum number of points in an encoded
 	// block in a TSM file
 	DefaultMaxPointsPerBlock = 1000
@@ -69,6 +84,20 @@ const (
 
 	// DefaultSeriesIDSetCacheSize is the default number of series ID sets to cache in the TSI index.
 	DefaultSeriesIDSetCacheSize = 100
+
+	// DefaultColdShardAge is the default time after its shard group ends that
+	// a shard is moved to the cold data directory.
+	DefaultColdShardAge = 7 * 24 * time.Hour
+
+	// DefaultWALArchiveRetention is the default time archived WAL segments
+	// are kept after their last write.
+	DefaultWALArchiveRetention = 7 * 24 * time.Hour
+
+	// DefaultTSMStringCodec is the default codec of the values of string blocks.
+	DefaultTSMStringCodec = "snappy"
+
+	// DefaultTSMFloatCodec is the default codec of the values of float blocks.
+	DefaultTSMFloatCodec = "gorilla"
 )
 
 // Config holds the configuration for the tsbd package.
@@ -80,11 +109,26 @@ type Config struct {
 	// General WAL configuration options
 	WALDir string `toml:"wal-dir"`
 
+	// ColdDir is the directory fully compacted shards are moved to once their
+	// shard group has ended more than ColdShardAge ago. The WAL of moved shards
+	// stays in WALDir. Tiered storage is disabled when ColdDir is empty.
+	ColdDir      string        `toml:"cold-dir"`
+	ColdShardAge toml.Duration `toml:"cold-shard-age"`
+
 	// WALFsyncDelay is the amount of time that a write will wait before fsyncing.  A duration
 	// greater than 0 can be used to batch up multiple fsync calls.  This is useful for slower
 	// disks or when WAL write contention is seen.  A value of 0 fsyncs every write to the WAL.
 	WALFsyncDelay toml.Duration `toml:"wal-fsync-delay"`
 
+	// WALArchiveDir is the directory closed WAL segments are copied to before
+	// they are removed, for point-in-time restores. Segments are laid out like
+	// in WALDir. Archiving is disabled when WALArchiveDir is empty.
+	WALArchiveDir string `toml:"wal-archive-dir"`
+
+	// WALArchiveRetention is how long archived segments are kept after their
+	// last write. A value of 0 keeps them until their shard is deleted.
+	WALArchiveRetention toml.Duration `toml:"wal-archive-retention"`
+
 	// Enables unicode validation on series keys on write.
 	ValidateKeys bool `toml:"validate-keys"`
 
@@ -99,6 +143,23 @@ type Config struct {
 	CompactThroughput              toml.Size     `toml:"compact-throughput"`
 	CompactThroughputBurst         toml.Size     `toml:"compact-throughput-burst"`
 
+	// CompactPlanner selects the compaction planner: "default" plans on file counts and
+	// levels only, while "priority" also weighs how often a shard is read, how many of its
+	// TSM files overlap and how many of its keys are deleted, so that hot shards and shards
+	// with heavy deletes are compacted first.
+	CompactPlanner string `toml:"compact-planner"`
+
+	// Bulk loads write points to new TSM files without going through the cache and the
+	// WAL. Points are sorted in a buffer of BulkLoadBufferSize, spilled to disk when the
+	// buffer is full, and committed once no bulk write has been received for
+	// BulkLoadCommitInterval or the shard is closed. Points are neither queryable nor
+	// durable until they are committed, and points not yet committed are lost if the
+	// process stops abruptly. BulkLoadBackfill also bulk loads the writes whose points
+	// are all older than the newest TSM file of the shard.
+	BulkLoadBufferSize     toml.Size     `toml:"bulk-load-buffer-size"`
+	BulkLoadCommitInterval toml.Duration `toml:"bulk-load-commit-interval"`
+	BulkLoadBackfill       bool          `toml:"bulk-load-backfill"`
+
 	// Limits
 
 	// MaxSeriesPerDatabase is the maximum number of series a node can hold per database.
@@ -111,6 +172,16 @@ type Config struct {
 	// A value of 0 disables the limit.
 	MaxValuesPerTag int `toml:"max-values-per-tag"`
 
+	// MaxSeriesPerMeasurement is the maximum number of series a measurement can have within
+	// a shard.  Points creating series beyond the limit are dropped.
+	// A value of 0 disables the limit.
+	MaxSeriesPerMeasurement int `toml:"max-series-per-measurement"`
+
+	// MaxNewSeriesPerMinute is the maximum number of series that can be created in a database
+	// per minute.  Points creating series beyond the limit are dropped.
+	// A value of 0 disables the limit.
+	MaxNewSeriesPerMinute int `toml:"max-new-series-per-minute"`
+
 	// MaxConcurrentCompactions is the maximum number of concurrent level and full compactions
 	// that can be running at one time across all shards.  Compactions scheduled to run when the
 	// limit is reached are blocked until a running compaction completes.  Snapshot compactions are
@@ -135,6 +206,16 @@ type Config struct {
 	// been found to be problematic in some cases. It may help users who have
 	// slow disks.
 	TSMWillNeed bool `toml:"tsm-use-madv-willneed"`
+
+	// TSMStringCodec and TSMFloatCodec are the codecs compactions use for the values
+	// of string and float blocks: "snappy" or "zstd" for strings and "gorilla" or "zstd"
+	// for floats. Blocks written with another codec are re-encoded when compacted.
+	TSMStringCodec string `toml:"tsm-string-codec"`
+	TSMFloatCodec  string `toml:"tsm-float-codec"`
+
+	// TSMZstdDictionary enables training a zstd dictionary for each shard from the
+	// values of its first blocks compressed with zstd.
+	TSMZstdDictionary bool `toml:"tsm-zstd-dictionary"`
 }
 
 // NewConfig returns the default configuration for tsdb.
@@ -145,12 +226,20 @@ func NewConfig() Config {
 
 		QueryLogEnabled: true,
 
+		ColdShardAge: toml.Duration(DefaultColdShardAge),
+
+		WALArchiveRetention: toml.Duration(DefaultWALArchiveRetention),
+
 		CacheMaxMemorySize:             toml.Size(DefaultCacheMaxMemorySize),
 		CacheSnapshotMemorySize:        toml.Size(DefaultCacheSnapshotMemorySize),
 		CacheSnapshotWriteColdDuration: toml.Duration(DefaultCacheSnapshotWriteColdDuration),
 		CompactFullWriteColdDuration:   toml.Duration(DefaultCompactFullWriteColdDuration),
 		CompactThroughput:              toml.Size(DefaultCompactThroughput),
 		CompactThroughputBurst:         toml.Size(DefaultCompactThroughputBurst),
+		CompactPlanner:                 DefaultCompactPlanner,
+
+		BulkLoadBufferSize:     toml.Size(DefaultBulkLoadBufferSize),
+		BulkLoadCommitInterval: toml.Duration(DefaultBulkLoadCommitInterval),
 
 		MaxSeriesPerDatabase:     DefaultMaxSeriesPerDatabase,
 		MaxValuesPerTag:          DefaultMaxValuesPerTag,
@@ -161,6 +250,9 @@ func NewConfig() Config {
 
 		TraceLoggingEnabled: false,
 		TSMWillNeed:         false,
+
+		TSMStringCodec: DefaultTSMStringCodec,
+		TSMFloatCodec:  DefaultTSMFloatCodec,
 	}
 }
 
@@ -172,14 +264,58 @@ func (c *Config) Validate() error {
 		return errors.New("Data.WALDir must be specified")
 	}
 
+	if c.ColdDir != "" {
+		if filepath.Clean(c.ColdDir) == filepath.Clean(c.Dir) {
+			return errors.New("Data.ColdDir must differ from Data.Dir")
+		} else if c.ColdShardAge <= 0 {
+			return errors.New("cold-shard-age must be positive")
+		}
+	}
+
+	if c.WALArchiveDir != "" && filepath.Clean(c.WALArchiveDir) == filepath.Clean(c.WALDir) {
+		return errors.New("Data.WALArchiveDir must differ from Data.WALDir")
+	}
+
 	if c.MaxConcurrentCompactions < 0 {
 		return errors.New("max-concurrent-compactions must be non-negative")
 	}
 
+	if c.MaxSeriesPerMeasurement < 0 {
+		return errors.New("max-series-per-measurement must be non-negative")
+	}
+
+	if c.MaxNewSeriesPerMinute < 0 {
+		return errors.New("max-new-series-per-minute must be non-negative")
+	}
+
 	if c.SeriesIDSetCacheSize < 0 {
 		return errors.New("series-id-set-cache-size must be non-negative")
 	}
 
+	if c.BulkLoadBufferSize <= 0 {
+		return errors.New("bulk-load-buffer-size must be positive")
+	} else if c.BulkLoadCommitInterval <= 0 {
+		return errors.New("bulk-load-commit-interval must be positive")
+	}
+
+	switch c.CompactPlanner {
+	case "default", "priority":
+	default:
+		return fmt.Errorf("unrecognized compact-planner %s", c.CompactPlanner)
+	}
+
+	switch c.TSMStringCodec {
+	case "snappy", "zstd":
+	default:
+		return fmt.Errorf("unrecognized tsm-string-codec %s", c.TSMStringCodec)
+	}
+
+	switch c.TSMFloatCodec {
+	case "gorilla", "zstd":
+	default:
+		return fmt.Errorf("unrecognized tsm-float-codec %s", c.TSMFloatCodec)
+	}
+
 	valid := false
 	for _, e := range RegisteredEngines() {
 		if e == c.Engine {
@@ -205,20 +341,43 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// WALArchivePath returns the directory the segments of a shard's WAL are
+// archived to, which mirrors the layout of the WAL directory.
+func (c Config) WALArchivePath(walPath string, id uint64) string {
+	rel, err := filepath.Rel(c.WALDir, walPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		rel = strconv.FormatUint(id, 10)
+	}
+	return filepath.Join(c.WALArchiveDir, rel)
+}
+
 // Diagnostics returns a diagnostics representation of a subset of the Config.
 func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
 	return diagnostics.RowFromMap(map[string]interface{}{
 		"dir":                                c.Dir,
 		"wal-dir":                            c.WALDir,
 		"wal-fsync-delay":                    c.WALFsyncDelay,
+		"wal-archive-dir":                    c.WALArchiveDir,
+		"wal-archive-retention":              c.WALArchiveRetention,
+		"cold-dir":                           c.ColdDir,
+		"cold-shard-age":                     c.ColdShardAge,
 		"cache-max-memory-size":              c.CacheMaxMemorySize,
 		"cache-snapshot-memory-size":         c.CacheSnapshotMemorySize,
 		"cache-snapshot-write-cold-duration": c.CacheSnapshotWriteColdDuration,
 		"compact-full-write-cold-duration":   c.CompactFullWriteColdDuration,
+		"compact-planner":                    c.CompactPlanner,
+		"bulk-load-buffer-size":              c.BulkLoadBufferSize,
+		"bulk-load-commit-interval":          c.BulkLoadCommitInterval,
+		"bulk-load-backfill":                 c.BulkLoadBackfill,
 		"max-series-per-database":            c.MaxSeriesPerDatabase,
 		"max-values-per-tag":                 c.MaxValuesPerTag,
+		"max-series-per-measurement":         c.MaxSeriesPerMeasurement,
+		"max-new-series-per-minute":          c.MaxNewSeriesPerMinute,
 		"max-concurrent-compactions":         c.MaxConcurrentCompactions,
 		"max-index-log-file-size":            c.MaxIndexLogFileSize,
 		"series-id-set-cache-size":           c.SeriesIDSetCacheSize,
+		"tsm-string-codec":                   c.TSMStringCodec,
+		"tsm-float-codec":                    c.TSMFloatCodec,
+		"tsm-zstd-dictionary":                c.TSMZstdDictionary,
 	}), nil
 }
@@ -16,6 +16,13 @@ dir = "/var/lib/influxdb/data"
 wal-dir = "/var/lib/influxdb/wal"
 wal-fsync-delay = "10s"
 tsm-use-madv-willneed = true
+tsm-string-codec = "zstd"
+tsm-float-codec = "zstd"
+tsm-zstd-dictionary = true
+compact-planner = "priority"
+bulk-load-buffer-size = "16m"
+bulk-load-commit-interval = "1m"
+bulk-load-backfill = true
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -36,6 +43,27 @@ tsm-use-madv-willneed = true
 	if got, exp := c.TSMWillNeed, true; got != exp {
 		t.Errorf("unexpected tsm-madv-willneed:\n\nexp=%v\n\ngot=%v\n\n", exp, got)
 	}
+	if got, exp := c.TSMStringCodec, "zstd"; got != exp {
+		t.Errorf("unexpected tsm-string-codec:\n\nexp=%v\n\ngot=%v\n\n", exp, got)
+	}
+	if got, exp := c.TSMFloatCodec, "zstd"; got != exp {
+		t.Errorf("unexpected tsm-float-codec:\n\nexp=%v\n\ngot=%v\n\n", exp, got)
+	}
+	if got, exp := c.TSMZstdDictionary, true; got != exp {
+		t.Errorf("unexpected tsm-zstd-dictionary:\n\nexp=%v\n\ngot=%v\n\n", exp, got)
+	}
+	if got, exp := c.CompactPlanner, "priority"; got != exp {
+		t.Errorf("unexpected compact-planner:\n\nexp=%v\n\ngot=%v\n\n", exp, got)
+	}
+	if got, exp := c.BulkLoadBufferSize, uint64(16<<20); uint64(got) != exp {
+		t.Errorf("unexpected bulk-load-buffer-size:\n\nexp=%v\n\ngot=%v\n\n", exp, got)
+	}
+	if got, exp := c.BulkLoadCommitInterval, time.Minute; time.Duration(got) != exp {
+		t.Errorf("unexpected bulk-load-commit-interval:\n\nexp=%v\n\ngot=%v\n\n", exp, got)
+	}
+	if got, exp := c.BulkLoadBackfill, true; got != exp {
+		t.Errorf("unexpected bulk-load-backfill:\n\nexp=%v\n\ngot=%v\n\n", exp, got)
+	}
 }
 
 func TestConfig_Validate_Error(t *testing.T) {
@@ -75,6 +103,54 @@ func TestConfig_Validate_Error(t *testing.T) {
 	if err := c.Validate(); err == nil || err.Error() != "series-id-set-cache-size must be non-negative" {
 		t.Errorf("unexpected error: %s", err)
 	}
+
+	c.SeriesIDSetCacheSize = tsdb.DefaultSeriesIDSetCacheSize
+	c.ColdDir = c.Dir + "/"
+	if err := c.Validate(); err == nil || err.Error() != "Data.ColdDir must differ from Data.Dir" {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	c.ColdDir = "/mnt/cold/influxdb/data"
+	c.ColdShardAge = 0
+	if err := c.Validate(); err == nil || err.Error() != "cold-shard-age must be positive" {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	c.ColdDir = ""
+	c.WALArchiveDir = c.WALDir
+	if err := c.Validate(); err == nil || err.Error() != "Data.WALArchiveDir must differ from Data.WALDir" {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	c.WALArchiveDir = ""
+	c.TSMStringCodec = "gorilla"
+	if err := c.Validate(); err == nil || err.Error() != "unrecognized tsm-string-codec gorilla" {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	c.TSMStringCodec = "zstd"
+	c.TSMFloatCodec = "snappy"
+	if err := c.Validate(); err == nil || err.Error() != "unrecognized tsm-float-codec snappy" {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	c.TSMFloatCodec = "zstd"
+	c.CompactPlanner = "heat"
+	if err := c.Validate(); err == nil || err.Error() != "unrecognized compact-planner heat" {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	c.CompactPlanner = "priority"
+	c.BulkLoadBufferSize = 0
+	if err := c.Validate(); err == nil || err.Error() != "bulk-load-buffer-size must be positive" {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	c.BulkLoadBufferSize = tsdb.DefaultBulkLoadBufferSize
+	c.BulkLoadCommitInterval = 0
+	if err := c.Validate(); err == nil || err.Error() != "bulk-load-commit-interval must be positive" {
+		t.Errorf("unexpected error: %s", err)
+	}
 }
 
 func TestConfig_ByteSizes(t *testing.T) {
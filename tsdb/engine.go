@@ -9,6 +9,7 @@ import (
 	"regexp"
 	"runtime"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/influxdata/influxdb/models"
@@ -47,11 +48,14 @@ type Engine interface {
 	Restore(r io.Reader, basePath string) error
 	Import(r io.Reader, basePath string) error
 	Digest() (io.ReadCloser, int64, error)
+	Repair() ([]CorruptBlock, error)
 
 	CreateIterator(ctx context.Context, measurement string, opt query.IteratorOptions) (query.Iterator, error)
 	CreateCursorIterator(ctx context.Context) (CursorIterator, error)
 	IteratorCost(measurement string, opt query.IteratorOptions) (query.IteratorCost, error)
 	WritePoints(points []models.Point) error
+	WritePointsBulk(points []models.Point) error
+	IsBackfill(points []models.Point) bool
 
 	CreateSeriesIfNotExists(key, name []byte, tags models.Tags) error
 	CreateSeriesListIfNotExists(keys, names [][]byte, tags []models.Tags) error
@@ -84,6 +88,16 @@ type Engine interface {
 	io.WriterTo
 }
 
+// CorruptBlock describes a block of data whose checksum does not match its
+// content.  The points of the block are lost.
+type CorruptBlock struct {
+	Path             string // file holding the block
+	Offset           int64  // offset of the block in the file
+	Series           []byte
+	Field            []byte
+	MinTime, MaxTime int64
+}
+
 // SeriesIDSets provides access to the total set of series IDs
 type SeriesIDSets interface {
 	ForEach(f func(ids *SeriesIDSet)) error
@@ -175,6 +189,10 @@ type EngineOptions struct {
 	WALEnabled                  bool
 	MonitorDisabled             bool
 
+	// CompactionPriorities ranks the shards sharing CompactionLimiter that are
+	// waiting to compact. Optional.
+	CompactionPriorities *CompactionPriorities
+
 	// DatabaseFilter is a predicate controlling which databases may be opened.
 	// If no function is set, all databases will be opened.
 	DatabaseFilter func(database string) bool
@@ -191,9 +209,19 @@ type EngineOptions struct {
 	SeriesIDSets   SeriesIDSets
 	FieldValidator FieldValidator
 
+	// SeriesLimiter limits the creation of series in the database. Optional.
+	SeriesLimiter *SeriesLimiter
+
 	OnNewEngine func(Engine)
 
 	FileStoreObserver FileStoreObserver
+
+	// ShardObserver is notified of points written to and deleted from shards. Optional.
+	ShardObserver ShardObserver
+
+	// BulkLoadCommitted is called with the time range of the points of a bulk load
+	// once they are committed.  It is set by the shard opening the engine. Optional.
+	BulkLoadCommitted func(min, max int64)
 }
 
 // NewEngineOptions constructs an EngineOptions object with safe default values.
@@ -213,6 +241,50 @@ var NewInmemIndex func(name string, sfile *SeriesFile) (interface{}, error)
 
 type CompactionPlannerCreator func(cfg Config) interface{}
 
+// CompactionPriorities tracks the compaction priority of the shards waiting to
+// compact so that shards with a higher priority take the compaction limiter first.
+type CompactionPriorities struct {
+	mu         sync.Mutex
+	priorities map[uint64]float64
+}
+
+// NewCompactionPriorities returns a new instance of CompactionPriorities.
+func NewCompactionPriorities() *CompactionPriorities {
+	return &CompactionPriorities{priorities: make(map[uint64]float64)}
+}
+
+// Set records that shard id is waiting to compact with the given priority.  A
+// priority of zero or less removes the shard.
+func (p *CompactionPriorities) Set(id uint64, priority float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if priority <= 0 {
+		delete(p.priorities, id)
+		return
+	}
+	p.priorities[id] = priority
+}
+
+// Remove removes shard id from the waiting shards.
+func (p *CompactionPriorities) Remove(id uint64) {
+	p.Set(id, 0)
+}
+
+// Ahead returns the number of waiting shards with a higher priority than shard id.
+func (p *CompactionPriorities) Ahead(id uint64) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	priority := p.priorities[id]
+
+	var n int
+	for other, v := range p.priorities {
+		if other != id && v > priority {
+			n++
+		}
+	}
+	return n
+}
+
 // FileStoreObserver is passed notifications before the file store adds or deletes files. In this way, it can
 // be sure to observe every file that is added or removed even in the presence of process death.
 type FileStoreObserver interface {
@@ -222,3 +294,14 @@ type FileStoreObserver interface {
 	// FileUnlinking is called before a file is unlinked.
 	FileUnlinking(path string) error
 }
+
+// ShardObserver is notified of changes to the points of shards.
+type ShardObserver interface {
+	// PointsWritten is called after points between min and max (inclusive)
+	// were written to a shard of the database.
+	PointsWritten(database string, min, max int64)
+
+	// PointsDeleted is called after points between min and max (inclusive)
+	// were deleted from a shard of the database.
+	PointsDeleted(database string, min, max int64)
+}
@@ -12,8 +12,9 @@ import (
 // FloatArrayEncodeAll encodes src into b, returning b and any error encountered.
 // The returned slice may be of a different length and capactity to b.
 //
-// Currently only the float compression scheme used in Facebook's Gorilla is
-// supported, so this method implements a batch oriented version of that.
+// The values are compressed with the float compression scheme used in Facebook's
+// Gorilla, so this method implements a batch oriented version of that.
+// FloatArrayEncodeZstd compresses them with zstd instead.
 func FloatArrayEncodeAll(src []float64, b []byte) ([]byte, error) {
 	if cap(b) < 9 {
 		b = make([]byte, 0, 9) // Enough room for the header and one value.
@@ -276,6 +277,14 @@ func init() {
 }
 
 func FloatArrayDecodeAll(b []byte, buf []float64) ([]float64, error) {
+	if len(b) > 0 && b[0]>>4 == floatCompressedZstd {
+		data, err := decodeZstd(b, nil)
+		if err != nil {
+			return []float64{}, fmt.Errorf("failed to decode float block: %v", err)
+		}
+		return unshuffleFloats(buf, data)
+	}
+
 	if len(b) < 9 {
 		return []float64{}, nil
 	}
@@ -286,7 +295,7 @@ func FloatArrayDecodeAll(b []byte, buf []float64) ([]float64, error) {
 		meaningfulN uint8  = 64 // meaningful bit count
 	)
 
-	// first byte is the compression type; always Gorilla
+	// first byte is the compression type; Gorilla as zstd is handled above
 	b = b[1:]
 
 	val = binary.BigEndian.Uint64(b)
@@ -22,7 +22,8 @@ var (
 // StringArrayEncodeAll encodes src into b, returning b and any error encountered.
 // The returned slice may be of a different length and capactity to b.
 //
-// Currently only the string compression scheme used snappy.
+// The strings are compressed with snappy. StringArrayEncodeZstd compresses
+// them with zstd instead.
 func StringArrayEncodeAll(src []string, b []byte) ([]byte, error) {
 	srcSz64 := int64(2 + len(src)*binary.MaxVarintLen32) // strings should't be longer than 64kb
 	for i := range src {
@@ -78,14 +79,17 @@ func StringArrayEncodeAll(src []string, b []byte) ([]byte, error) {
 }
 
 func StringArrayDecodeAll(b []byte, dst []string) ([]string, error) {
-	// First byte stores the encoding type, only have snappy format
-	// currently so ignore for now.
+	// First byte stores the encoding type.
 	if len(b) > 0 {
 		var err error
-		// it is important that to note that `snappy.Decode` always returns
-		// a newly allocated slice as the final strings reference this slice
-		// directly.
-		b, err = snappy.Decode(nil, b[1:])
+		// it is important that to note that `snappy.Decode` and
+		// `decodeZstd` always return a newly allocated slice as the final
+		// strings reference this slice directly.
+		if b[0]>>4 == stringCompressedZstd {
+			b, err = decodeZstd(b, nil)
+		} else {
+			b, err = snappy.Decode(nil, b[1:])
+		}
 		if err != nil {
 			return []string{}, fmt.Errorf("failed to decode string block: %v", err.Error())
 		}
@@ -0,0 +1,214 @@
+package tsm1
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/influxdb/logger"
+	"github.com/influxdata/influxdb/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// bulkLoadDir is the directory of the engine the buffer of a bulk load is
+	// spilled to.  Like other temporary directories, it is removed when the
+	// engine is opened, so a bulk load that was not committed is lost.
+	bulkLoadDir = "bulk." + TmpTSMFileExtension
+
+	// bulkLoadSequence is the sequence of the first TSM file of a committed bulk
+	// load.  The files are sorted and deduplicated like those of a full
+	// compaction, so they are written at level 4.
+	bulkLoadSequence = 4
+)
+
+// WritePointsBulk writes points to the bulk load of the engine instead of the
+// cache and the WAL.  Points are buffered sorted by key and spilled to disk as
+// sorted TSM files whenever the buffer exceeds BulkLoadBufferSize.
+//
+// Points become queryable and durable once the bulk load is committed to new
+// TSM files, which happens after BulkLoadCommitInterval without bulk writes,
+// before deletes and snapshots, and when the engine is closed.  Points not yet
+// committed are lost if the process stops abruptly.  Committing a bulk load
+// counts as writing its points: they replace the points with the same timestamp
+// written before the commit, including those still in the cache, and are
+// replaced by those written after it.
+func (e *Engine) WritePointsBulk(points []models.Point) error {
+	values, seriesErr, err := e.pointValues(points)
+	if err != nil {
+		return err
+	}
+
+	e.bulkMu.Lock()
+	defer e.bulkMu.Unlock()
+
+	if !e.bulkPendingNoLock() {
+		e.bulkMinTime, e.bulkMaxTime = math.MaxInt64, math.MinInt64
+	}
+	if e.bulk == nil {
+		e.bulk = NewCache(0)
+	}
+	if err := e.bulk.WriteMulti(values); err != nil {
+		return err
+	}
+	e.bulkLastWrite = time.Now()
+
+	for _, p := range points {
+		t := p.UnixNano()
+		if t < e.bulkMinTime {
+			e.bulkMinTime = t
+		}
+		if t > e.bulkMaxTime {
+			e.bulkMaxTime = t
+		}
+	}
+
+	if e.bulk.Size() > e.BulkLoadBufferSize {
+		if err := e.spillBulk(); err != nil {
+			return err
+		}
+	}
+	return seriesErr
+}
+
+// IsBackfill returns true if every point is older than the newest TSM file of
+// the engine.
+func (e *Engine) IsBackfill(points []models.Point) bool {
+	if len(points) == 0 {
+		return false
+	}
+
+	minTime := e.FileStore.NewestMinTime()
+	if minTime == math.MinInt64 {
+		return false
+	}
+
+	for _, p := range points {
+		if p.UnixNano() >= minTime {
+			return false
+		}
+	}
+	return true
+}
+
+// ShouldCommitBulk returns true if there is a bulk load and it has not been
+// written to since BulkLoadCommitInterval before t.
+func (e *Engine) ShouldCommitBulk(t time.Time) bool {
+	e.bulkMu.Lock()
+	defer e.bulkMu.Unlock()
+	return e.bulkPendingNoLock() && t.Sub(e.bulkLastWrite) > e.BulkLoadCommitInterval
+}
+
+// bulkPending returns true if there is a bulk load to commit.
+func (e *Engine) bulkPending() bool {
+	e.bulkMu.Lock()
+	defer e.bulkMu.Unlock()
+	return e.bulkPendingNoLock()
+}
+
+func (e *Engine) bulkPendingNoLock() bool {
+	return e.bulk != nil || len(e.bulkRuns) > 0
+}
+
+// bulkSize returns the size of the points buffered in memory by the bulk load.
+func (e *Engine) bulkSize() uint64 {
+	e.bulkMu.Lock()
+	defer e.bulkMu.Unlock()
+	if e.bulk == nil {
+		return 0
+	}
+	return e.bulk.Size()
+}
+
+// spillBulk writes the buffer of the bulk load to sorted TSM files in the bulk
+// load directory.  bulkMu must be held.
+func (e *Engine) spillBulk() error {
+	if e.bulk == nil {
+		return nil
+	}
+
+	dir := filepath.Join(e.path, bulkLoadDir)
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+
+	e.bulk.Deduplicate()
+	runs, err := e.Compactor.writeRuns(dir, len(e.bulkRuns), e.bulk)
+	if err != nil {
+		return err
+	}
+
+	e.bulkRuns = append(e.bulkRuns, runs...)
+	e.bulk = nil
+	atomic.AddInt64(&e.stats.BulkLoadSpills, 1)
+	return nil
+}
+
+// commitBulk writes the points of the bulk load to new TSM files and adds them
+// to the file store.
+func (e *Engine) commitBulk() (err error) {
+	e.bulkMu.Lock()
+	defer e.bulkMu.Unlock()
+
+	if !e.bulkPendingNoLock() {
+		return nil
+	}
+
+	start := time.Now()
+	log, logEnd := logger.NewOperation(e.logger, "Bulk load commit", "tsm1_bulk_load_commit")
+	defer func() {
+		atomic.AddInt64(&e.stats.BulkLoadCommitDuration, time.Since(start).Nanoseconds())
+		if err != nil && err != errCompactionsDisabled {
+			atomic.AddInt64(&e.stats.BulkLoadCommitErrors, 1)
+		}
+		logEnd()
+	}()
+
+	if err := e.spillBulk(); err != nil {
+		return err
+	}
+
+	// Points in the cache replace those of TSM files, so the cache is flushed
+	// to TSM files older than those of the bulk load for the bulk load to
+	// replace them.
+	if e.Cache.Size() > 0 {
+		if err := e.flushCache(); err != nil {
+			return err
+		}
+	}
+
+	newFiles, err := e.Compactor.WriteBulk(e.bulkRuns)
+	if err != nil {
+		return err
+	}
+
+	e.mu.RLock()
+	err = e.FileStore.Replace(nil, newFiles)
+	e.mu.RUnlock()
+	if err != nil {
+		log.Info("Error adding new TSM files from bulk load. Removing temp files.", zap.Error(err))
+
+		// Remove the new files.  The runs are kept, so we will try again.
+		for _, file := range newFiles {
+			if err := os.Remove(file); err != nil {
+				log.Info("Unable to remove file", zap.String("path", file), zap.Error(err))
+			}
+		}
+		return err
+	}
+
+	if err := os.RemoveAll(filepath.Join(e.path, bulkLoadDir)); err != nil {
+		log.Info("Unable to remove bulk load directory", zap.Error(err))
+	}
+	e.bulkRuns = nil
+
+	if e.bulkCommitted != nil {
+		e.bulkCommitted(e.bulkMinTime, e.bulkMaxTime)
+	}
+
+	atomic.AddInt64(&e.stats.BulkLoadCommits, 1)
+	log.Info("Bulk load committed", zap.Strings("files", newFiles), zap.Duration("duration", time.Since(start)))
+	return nil
+}
@@ -29,6 +29,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/golang/snappy"
 	"github.com/influxdata/influxdb/pkg/limiter"
 	"github.com/influxdata/influxdb/tsdb"
 )
@@ -126,6 +127,7 @@ type DefaultPlanner struct {
 
 type fileStore interface {
 	Stats() []FileStat
+	Reads() int64
 	LastModified() time.Time
 	BlockCount(path string, idx int) int
 	ParseFileName(path string) (int, int, error)
@@ -314,6 +316,14 @@ func (c *DefaultPlanner) PlanLevel(level int) []CompactionGroup {
 // to optimize the index across TSM files.  Each returned compaction group can be
 // compacted concurrently.
 func (c *DefaultPlanner) PlanOptimize() []CompactionGroup {
+	return c.planOptimize(func(group tsmGenerations) bool {
+		return len(group) >= 4 || group.hasTombstones()
+	})
+}
+
+// planOptimize returns the groups of level 4 generations for which worthwhile
+// returns true.
+func (c *DefaultPlanner) planOptimize(worthwhile func(group tsmGenerations) bool) []CompactionGroup {
 	// If a full plan has been requested, don't plan any levels which will prevent
 	// the full plan from acquiring them.
 	c.mu.RLock()
@@ -381,7 +391,7 @@ func (c *DefaultPlanner) PlanOptimize() []CompactionGroup {
 	var cGroups []CompactionGroup
 	for _, group := range levelGroups {
 		// Skip the group if it's not worthwhile to optimize it
-		if len(group) < 4 && !group.hasTombstones() {
+		if !worthwhile(group) {
 			continue
 		}
 
@@ -696,6 +706,16 @@ type Compactor struct {
 	// RateLimit is the limit for disk writes for all concurrent compactions.
 	RateLimit limiter.Rate
 
+	// StringCodec and FloatCodec are the codecs of the values of the string
+	// and float blocks written by the compactor.  Blocks encoded with another
+	// codec are re-encoded.  An empty codec keeps blocks as they are.
+	StringCodec string
+	FloatCodec  string
+
+	// ZstdDictionary enables training a zstd dictionary for the shard from the
+	// first blocks compressed with zstd.  Subsequent blocks are compressed with it.
+	ZstdDictionary bool
+
 	formatFileName FormatFileNameFunc
 	parseFileName  ParseFileNameFunc
 
@@ -714,6 +734,10 @@ type Compactor struct {
 	compactionsInterrupt chan struct{}
 
 	files map[string]struct{}
+
+	zstdMu         sync.Mutex
+	zstdDictLoaded bool
+	zstdDict       *zstdDict
 }
 
 // NewCompactor returns a new instance of Compactor.
@@ -1011,6 +1035,86 @@ func (c *Compactor) CompactFast(tsmFiles []string) ([]string, error) {
 
 }
 
+// WriteBulk writes the sorted TSM files spilled by a bulk load into new TSM files of
+// the next generation.  The values of later runs replace those of earlier runs with
+// the same timestamp.  A single run is already sorted and deduplicated, so it is
+// linked instead of rewritten.  The new files start at bulkLoadSequence, so level
+// compactions leave them be.
+func (c *Compactor) WriteBulk(runs []string) ([]string, error) {
+	c.mu.RLock()
+	enabled := c.compactionsEnabled
+	intC := c.compactionsInterrupt
+	c.mu.RUnlock()
+
+	if !enabled {
+		return nil, errCompactionsDisabled
+	} else if len(runs) == 0 {
+		return nil, nil
+	}
+
+	generation := c.FileStore.NextGeneration()
+	if len(runs) == 1 {
+		fileName := filepath.Join(c.Dir, c.formatFileName(generation, bulkLoadSequence)+"."+TSMFileExtension+"."+TmpTSMFileExtension)
+		if err := os.Link(runs[0], fileName); err != nil {
+			return nil, err
+		}
+		return []string{fileName}, nil
+	}
+
+	var trs []*TSMReader
+	for _, run := range runs {
+		f, err := os.Open(run)
+		if err != nil {
+			return nil, err
+		}
+
+		tr, err := NewTSMReader(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("error opening bulk load run %s: %v", run, err)
+		}
+		defer tr.Close()
+		trs = append(trs, tr)
+	}
+
+	size := c.Size
+	if size <= 0 {
+		size = tsdb.DefaultMaxPointsPerBlock
+	}
+
+	iter, err := NewTSMBatchKeyIterator(size, false, intC, trs...)
+	if err != nil {
+		return nil, err
+	}
+	return c.writeNewFiles(generation, bulkLoadSequence-1, nil, iter, true)
+}
+
+// writeRuns writes the values of cache to sorted TSM files in dir, named after
+// their position in the runs of a bulk load starting at seq.
+func (c *Compactor) writeRuns(dir string, seq int, cache *Cache) ([]string, error) {
+	var files []string
+	iter := NewCacheKeyIterator(cache, tsdb.DefaultMaxPointsPerBlock, nil)
+	for {
+		seq++
+		fileName := filepath.Join(dir, fmt.Sprintf("%09d.%s", seq, TSMFileExtension))
+
+		err := c.write(fileName, iter, false)
+		if err == errMaxFileExceeded || err == ErrMaxBlocksExceeded {
+			files = append(files, fileName)
+			continue
+		} else if err == ErrNoValues {
+			break
+		} else if err != nil {
+			c.removeTmpFiles(files)
+			return nil, err
+		}
+
+		files = append(files, fileName)
+		break
+	}
+	return files, nil
+}
+
 // removeTmpFiles is responsible for cleaning up a compaction that
 // was started, but then abandoned before the temporary files were dealt with.
 func (c *Compactor) removeTmpFiles(files []string) error {
@@ -1131,6 +1235,7 @@ func (c *Compactor) write(path string, iter KeyIterator, throttle bool) (err err
 		}
 	}()
 
+	var sampler zstdSampler
 	for iter.Next() {
 		c.mu.RLock()
 		enabled := c.snapshotsEnabled || c.compactionsEnabled
@@ -1151,8 +1256,25 @@ func (c *Compactor) write(path string, iter KeyIterator, throttle bool) (err err
 			return fmt.Errorf("invalid index entry for block. min=%d, max=%d", minTime, maxTime)
 		}
 
+		if block, err = c.encodeBlock(block, &sampler); err != nil {
+			return err
+		}
+
+		// Blocks copied as is keep the summary from their file, so that
+		// they don't have to be decoded to summarize them again.
+		var summary BlockSummary
+		var summarized bool
+		if si, ok := iter.(blockSummaryIterator); ok {
+			summary, summarized = si.BlockSummary()
+		}
+
 		// Write the key and value
-		if err := w.WriteBlock(key, minTime, maxTime, block); err == ErrMaxBlocksExceeded {
+		if summarized {
+			err = w.WriteBlockSummary(key, minTime, maxTime, block, summary)
+		} else {
+			err = w.WriteBlock(key, minTime, maxTime, block)
+		}
+		if err == ErrMaxBlocksExceeded {
 			if err := w.WriteIndex(); err != nil {
 				return err
 			}
@@ -1184,6 +1306,145 @@ func (c *Compactor) write(path string, iter KeyIterator, throttle bool) (err err
 	return nil
 }
 
+// encodeBlock re-encodes the values of a float or string block if they are not
+// encoded with the configured codec.  The uncompressed values of the blocks it
+// compresses with zstd are sampled into s until a dictionary can be trained.
+func (c *Compactor) encodeBlock(block []byte, s *zstdSampler) ([]byte, error) {
+	var codec string
+	switch block[0] {
+	case BlockFloat64:
+		codec = c.FloatCodec
+	case BlockString:
+		codec = c.StringCodec
+	}
+	if codec == "" {
+		return block, nil
+	}
+
+	typ := block[0]
+	if current, err := BlockCodec(block); err != nil {
+		return nil, err
+	} else if current == codec || current == "" {
+		return block, nil
+	}
+
+	tb, vb, err := unpackBlock(block[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode the values to the payload compressed by zstd.
+	var payload []byte
+	switch typ {
+	case BlockFloat64:
+		values, err := FloatArrayDecodeAll(vb, nil)
+		if err != nil {
+			return nil, err
+		}
+		if codec == FloatCodecGorilla {
+			if vb, err = FloatArrayEncodeAll(values, nil); err != nil {
+				return nil, err
+			}
+			return packBlock(nil, typ, tb, vb), nil
+		}
+		if payload, err = shuffleFloats(nil, values); err != nil {
+			return nil, err
+		}
+	case BlockString:
+		if vb[0]>>4 == stringCompressedZstd {
+			payload, err = decodeZstd(vb, nil)
+		} else {
+			payload, err = snappy.Decode(nil, vb[1:])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode string block: %v", err)
+		}
+		if codec == StringCodecSnappy {
+			vb = append([]byte{stringCompressedSnappy << 4}, snappy.Encode(nil, payload)...)
+			return packBlock(nil, typ, tb, vb), nil
+		}
+	}
+
+	d, err := c.loadZstdDict()
+	if err != nil {
+		return nil, err
+	}
+	if d == nil && c.ZstdDictionary && s.add(payload) {
+		if d, err = c.trainZstdDict(s.samples); err != nil {
+			return nil, err
+		}
+		s.samples = nil
+	}
+
+	enc := zstdEncoder
+	if d != nil && c.ZstdDictionary {
+		enc = d.enc
+	}
+	return packBlock(nil, typ, tb, appendZstd(nil, typ, payload, enc)), nil
+}
+
+// loadZstdDict returns the zstd dictionary of the shard, loading it on first
+// use.  It returns nil if the shard has no dictionary.
+func (c *Compactor) loadZstdDict() (*zstdDict, error) {
+	c.zstdMu.Lock()
+	defer c.zstdMu.Unlock()
+
+	if !c.zstdDictLoaded && c.Dir != "" {
+		d, err := loadZstdDict(c.Dir)
+		if err != nil {
+			return nil, err
+		}
+		c.zstdDict, c.zstdDictLoaded = d, true
+	}
+	return c.zstdDict, nil
+}
+
+// trainZstdDict trains a zstd dictionary for the shard from samples and
+// stores it in the shard directory.  If the shard got a dictionary since it
+// was loaded, that one is returned instead.
+func (c *Compactor) trainZstdDict(samples [][]byte) (*zstdDict, error) {
+	c.zstdMu.Lock()
+	defer c.zstdMu.Unlock()
+
+	if c.zstdDict != nil {
+		return c.zstdDict, nil
+	}
+
+	// A dictionary may have been restored from a backup since it was loaded.
+	if d, err := loadZstdDict(c.Dir); err != nil {
+		return nil, err
+	} else if d != nil {
+		c.zstdDict = d
+		return d, nil
+	}
+
+	b, err := trainZstdDict(samples)
+	if err != nil {
+		return nil, err
+	}
+	d, err := newZstdDict(b)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeZstdDict(c.Dir, b); err != nil {
+		return nil, err
+	}
+	c.zstdDict = d
+	return d, nil
+}
+
+// releaseZstdDict unregisters the zstd dictionary of the shard if the
+// compactor loaded it.  It is loaded again on next use.
+func (c *Compactor) releaseZstdDict() {
+	c.zstdMu.Lock()
+	defer c.zstdMu.Unlock()
+
+	if c.zstdDict != nil {
+		c.zstdDict.release()
+	}
+	c.zstdDict, c.zstdDictLoaded = nil, false
+}
+
 func (c *Compactor) add(files []string) bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -1230,6 +1491,12 @@ type KeyIterator interface {
 	EstimatedIndexSize() int
 }
 
+// blockSummaryIterator is implemented by key iterators which can return the
+// summary of the block returned by Read.
+type blockSummaryIterator interface {
+	BlockSummary() (BlockSummary, bool)
+}
+
 // tsmKeyIterator implements the KeyIterator for set of TSMReaders.  Iteration produces
 // keys in sorted order and the values between the keys sorted and deduped.  If any of
 // the readers have associated tombstone entries, they are returned as part of iteration.
@@ -1291,6 +1558,10 @@ type block struct {
 	// readMin, readMax are the timestamps range of values have been
 	// read and encoded from this block.
 	readMin, readMax int64
+
+	// summary is the summary of the block in its file, if it has one.
+	summary    BlockSummary
+	summarized bool
 }
 
 func (b *block) overlapsTimeRange(min, max int64) bool {
@@ -1401,9 +1672,10 @@ RETRY:
 		if len(v) == 0 {
 			iter := k.iterators[i]
 			if iter.Next() {
-				key, minTime, maxTime, typ, _, b, err := iter.Read()
+				key, minTime, maxTime, typ, b, err := iter.readVerified()
 				if err != nil {
 					k.err = err
+					return false
 				}
 
 				// This block may have ranges of time removed from it that would
@@ -1430,13 +1702,15 @@ RETRY:
 				blk.tombstones = tombstones
 				blk.readMin = math.MaxInt64
 				blk.readMax = math.MinInt64
+				blk.summary, blk.summarized = iter.summary()
 
 				blockKey := key
 				for bytes.Equal(iter.PeekNext(), blockKey) {
 					iter.Next()
-					key, minTime, maxTime, typ, _, b, err := iter.Read()
+					key, minTime, maxTime, typ, b, err := iter.readVerified()
 					if err != nil {
 						k.err = err
+						return false
 					}
 
 					tombstones := iter.r.TombstoneRange(key)
@@ -1462,6 +1736,7 @@ RETRY:
 					blk.tombstones = tombstones
 					blk.readMin = math.MaxInt64
 					blk.readMax = math.MinInt64
+					blk.summary, blk.summarized = iter.summary()
 				}
 			}
 
@@ -1549,6 +1824,15 @@ func (k *tsmKeyIterator) Read() ([]byte, int64, int64, []byte, error) {
 	return block.key, block.minTime, block.maxTime, block.b, k.err
 }
 
+// BlockSummary returns the summary of the block returned by Read if it is
+// copied as is from a file with block summaries.
+func (k *tsmKeyIterator) BlockSummary() (BlockSummary, bool) {
+	if len(k.merged) == 0 {
+		return BlockSummary{}, false
+	}
+	return k.merged[0].summary, k.merged[0].summarized
+}
+
 func (k *tsmKeyIterator) Close() error {
 	k.values = nil
 	k.pos = nil
@@ -1693,9 +1977,10 @@ RETRY:
 
 		iter := k.iterators[i]
 		if iter.Next() {
-			key, minTime, maxTime, typ, _, b, err := iter.Read()
+			key, minTime, maxTime, typ, b, err := iter.readVerified()
 			if err != nil {
 				k.err = err
+				return false
 			}
 
 			// This block may have ranges of time removed from it that would
@@ -1722,13 +2007,15 @@ RETRY:
 			blk.tombstones = tombstones
 			blk.readMin = math.MaxInt64
 			blk.readMax = math.MinInt64
+			blk.summary, blk.summarized = iter.summary()
 
 			blockKey := key
 			for bytes.Equal(iter.PeekNext(), blockKey) {
 				iter.Next()
-				key, minTime, maxTime, typ, _, b, err := iter.Read()
+				key, minTime, maxTime, typ, b, err := iter.readVerified()
 				if err != nil {
 					k.err = err
+					return false
 				}
 
 				tombstones := iter.r.TombstoneRange(key)
@@ -1754,6 +2041,7 @@ RETRY:
 				blk.tombstones = tombstones
 				blk.readMin = math.MaxInt64
 				blk.readMax = math.MinInt64
+				blk.summary, blk.summarized = iter.summary()
 			}
 		}
 
@@ -1848,6 +2136,15 @@ func (k *tsmBatchKeyIterator) Read() ([]byte, int64, int64, []byte, error) {
 	return block.key, block.minTime, block.maxTime, block.b, k.err
 }
 
+// BlockSummary returns the summary of the block returned by Read if it is
+// copied as is from a file with block summaries.
+func (k *tsmBatchKeyIterator) BlockSummary() (BlockSummary, bool) {
+	if len(k.merged) == 0 {
+		return BlockSummary{}, false
+	}
+	return k.merged[0].summary, k.merged[0].summarized
+}
+
 func (k *tsmBatchKeyIterator) Close() error {
 	k.values = nil
 	k.pos = nil
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -140,6 +141,58 @@ func TestCompactor_CompactFullLastTimestamp(t *testing.T) {
 	}
 }
 
+// Ensures that blocks copied as is by a compaction keep their summary instead
+// of being summarized again.
+func TestCompactor_CompactFull_BlockSummaries(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	// The summary doesn't match the values of the block, so a summary
+	// computed from the block can be told apart from the copied one.
+	block, err := tsm1.Values{tsm1.NewIntegerValue(1, 1), tsm1.NewIntegerValue(2, 2)}.Encode(nil)
+	if err != nil {
+		t.Fatalf("unexpected error encoding: %v", err)
+	}
+	summary := tsm1.BlockSummary{Count: 42, Sum: 1, Min: 1, Max: 1}
+
+	w, f1 := MustTSMWriter(dir, 1)
+	if err := w.WriteBlockSummary([]byte("cpu,host=A#!~#value"), 1, 2, block, summary); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	} else if err := w.WriteIndex(); err != nil {
+		t.Fatalf("unexpected error writing index: %v", err)
+	} else if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+
+	f2 := MustWriteTSM(dir, 2, map[string][]tsm1.Value{
+		"cpu,host=B#!~#value": {tsm1.NewIntegerValue(1, 3)},
+	})
+
+	fs := &fakeFileStore{}
+	defer fs.Close()
+	compactor := tsm1.NewCompactor()
+	compactor.Dir = dir
+	compactor.FileStore = fs
+	compactor.Open()
+
+	files, err := compactor.CompactFull([]string{f1, f2})
+	if err != nil {
+		t.Fatalf("unexpected error compacting: %v", err)
+	}
+
+	r := MustOpenTSMReader(files[0])
+	defer r.Close()
+
+	entries := r.Entries([]byte("cpu,host=A#!~#value"))
+	if got, ok := r.BlockSummary(&entries[0]); !ok || got != summary {
+		t.Fatalf("unexpected summary of the copied block: %v", got)
+	}
+	entries = r.Entries([]byte("cpu,host=B#!~#value"))
+	if got, ok := r.BlockSummary(&entries[0]); !ok || got.Count != 1 {
+		t.Fatalf("unexpected summary: %v", got)
+	}
+}
+
 // Ensures that a compaction will properly merge multiple TSM files
 func TestCompactor_CompactFull(t *testing.T) {
 	dir := MustTempDir()
@@ -296,7 +349,7 @@ func TestCompactor_DecodeError(t *testing.T) {
 	compactor.Open()
 
 	files, err = compactor.CompactFull([]string{f1, f2, f3})
-	if err == nil || err.Error() != "decode error: unable to decompress block type float for key 'cpu,host=A#!~#value': unpackBlock: not enough data for timestamp" {
+	if err == nil || !strings.HasSuffix(err.Error(), `block of key "cpu,host=A#!~#value" at offset 5: block checksum mismatch`) {
 		t.Fatalf("expected error writing snapshot: %v", err)
 	}
 }
@@ -594,6 +647,46 @@ func TestCompactor_Compact_UnsortedBlocksOverlapping(t *testing.T) {
 	}
 }
 
+// Ensures that a compaction fails rather than write out a block whose checksum
+// does not match its content with a new checksum.
+func TestCompactor_CompactFull_CorruptBlock(t *testing.T) {
+	for _, overlap := range []bool{false, true} {
+		t.Run(fmt.Sprintf("overlap=%v", overlap), func(t *testing.T) {
+			dir := MustTempDir()
+			defer os.RemoveAll(dir)
+
+			f1 := MustWriteTSM(dir, 1, map[string][]tsm1.Value{
+				"cpu,host=A#!~#value": {tsm1.NewValue(1, 1.1)},
+			})
+			key := "cpu,host=B#!~#value"
+			if overlap {
+				key = "cpu,host=A#!~#value"
+			}
+			f2 := MustWriteTSM(dir, 2, map[string][]tsm1.Value{
+				key: {tsm1.NewValue(2, 2.1)},
+			})
+
+			// The only block of the file follows the header and its checksum.
+			MustCorruptFile(f2, 5+4+1)
+
+			fs := &fakeFileStore{}
+			defer fs.Close()
+			compactor := tsm1.NewCompactor()
+			compactor.Dir = dir
+			compactor.FileStore = fs
+			compactor.Open()
+
+			files, err := compactor.CompactFull([]string{f1, f2})
+			if err == nil || !strings.Contains(err.Error(), "checksum") {
+				t.Fatalf("expected checksum error, got %v", err)
+			}
+			if len(files) > 0 {
+				t.Fatalf("no files should be compacted: got %v", files)
+			}
+		})
+	}
+}
+
 // Ensures that a compaction will properly merge multiple TSM files
 func TestCompactor_CompactFull_SkipFullBlocks(t *testing.T) {
 	dir := MustTempDir()
@@ -1086,6 +1179,158 @@ func TestCompactor_CompactFull_MaxKeys(t *testing.T) {
 }
 
 // Tests that a single TSM file can be read and iterated over
+// Ensures that compactions re-encode float and string blocks with the configured codecs.
+func TestCompactor_CompactFull_Codecs(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	var floats, msgs []tsm1.Value
+	for i := 0; i < 1500; i++ {
+		floats = append(floats, tsm1.NewValue(int64(i), 20+float64(i%7)/10))
+		msgs = append(msgs, tsm1.NewValue(int64(i), fmt.Sprintf("sensor %d ok", i%5)))
+	}
+	count := []tsm1.Value{tsm1.NewValue(0, int64(1))}
+
+	f1 := MustWriteTSM(dir, 1, map[string][]tsm1.Value{
+		"cpu,host=A#!~#count": count,
+		"cpu,host=A#!~#msg":   msgs[:1000],
+		"cpu,host=A#!~#value": floats[:1000],
+	})
+	f2 := MustWriteTSM(dir, 2, map[string][]tsm1.Value{
+		"cpu,host=A#!~#msg":   msgs[1000:],
+		"cpu,host=A#!~#value": floats[1000:],
+	})
+
+	fs := &fakeFileStore{}
+	defer fs.Close()
+	compactor := tsm1.NewCompactor()
+	compactor.Dir = dir
+	compactor.FileStore = fs
+	compactor.StringCodec = tsm1.CodecZstd
+	compactor.FloatCodec = tsm1.CodecZstd
+	compactor.Open()
+
+	files, err := compactor.CompactFull([]string{f1, f2})
+	if err != nil {
+		t.Fatalf("unexpected error compacting: %v", err)
+	}
+	assertBlockCodecs(t, files, map[string]string{
+		"cpu,host=A#!~#count": "",
+		"cpu,host=A#!~#msg":   tsm1.CodecZstd,
+		"cpu,host=A#!~#value": tsm1.CodecZstd,
+	})
+
+	compactor.StringCodec = tsm1.StringCodecSnappy
+	compactor.FloatCodec = tsm1.FloatCodecGorilla
+	files, err = compactor.CompactFull(files)
+	if err != nil {
+		t.Fatalf("unexpected error compacting: %v", err)
+	}
+	assertBlockCodecs(t, files, map[string]string{
+		"cpu,host=A#!~#count": "",
+		"cpu,host=A#!~#msg":   tsm1.StringCodecSnappy,
+		"cpu,host=A#!~#value": tsm1.FloatCodecGorilla,
+	})
+
+	r := MustOpenTSMReader(files[0])
+	defer r.Close()
+	for key, exp := range map[string][]tsm1.Value{
+		"cpu,host=A#!~#count": count,
+		"cpu,host=A#!~#msg":   msgs,
+		"cpu,host=A#!~#value": floats,
+	} {
+		values, err := r.ReadAll([]byte(key))
+		if err != nil {
+			t.Fatalf("unexpected error reading: %v", err)
+		}
+		if got, exp := len(values), len(exp); got != exp {
+			t.Fatalf("values length mismatch %s: got %v, exp %v", key, got, exp)
+		}
+		for i, point := range exp {
+			assertValueEqual(t, values[i], point)
+		}
+	}
+}
+
+// Ensures that compactions train a zstd dictionary for the shard and keep it in snapshots.
+func TestCompactor_CompactFull_ZstdDictionary(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	writes := make(map[string][]tsm1.Value)
+	for k := 0; k < 20; k++ {
+		key := fmt.Sprintf("logs,host=%d#!~#msg", k)
+		for i := 0; i < 1000; i++ {
+			writes[key] = append(writes[key], tsm1.NewValue(int64(i), fmt.Sprintf("level=info msg=\"request served\" path=/api/items/%d status=200", i)))
+		}
+	}
+	f1 := MustWriteTSM(dir, 1, writes)
+
+	fs := &fakeFileStore{}
+	defer fs.Close()
+	compactor := tsm1.NewCompactor()
+	compactor.Dir = dir
+	compactor.FileStore = fs
+	compactor.StringCodec = tsm1.CodecZstd
+	compactor.ZstdDictionary = true
+	compactor.Open()
+
+	files, err := compactor.CompactFull([]string{f1})
+	if err != nil {
+		t.Fatalf("unexpected error compacting: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, tsm1.ZstdDictFile)); err != nil {
+		t.Fatalf("expected zstd dictionary: %v", err)
+	}
+
+	// A new compactor compresses the blocks again with the existing dictionary
+	// of the shard.
+	compactor = tsm1.NewCompactor()
+	compactor.Dir = dir
+	compactor.FileStore = fs
+	compactor.StringCodec = tsm1.StringCodecSnappy
+	compactor.ZstdDictionary = true
+	compactor.Open()
+
+	files, err = compactor.CompactFull(files)
+	if err != nil {
+		t.Fatalf("unexpected error compacting: %v", err)
+	}
+	compactor.StringCodec = tsm1.CodecZstd
+	if files, err = compactor.CompactFull(files); err != nil {
+		t.Fatalf("unexpected error compacting: %v", err)
+	}
+
+	r := MustOpenTSMReader(files[0])
+	for key, exp := range writes {
+		values, err := r.ReadAll([]byte(key))
+		if err != nil {
+			t.Fatalf("unexpected error reading: %v", err)
+		}
+		if got, exp := len(values), len(exp); got != exp {
+			t.Fatalf("values length mismatch %s: got %v, exp %v", key, got, exp)
+		}
+		for i, point := range exp {
+			assertValueEqual(t, values[i], point)
+		}
+	}
+	r.Close()
+
+	store := tsm1.NewFileStore(dir)
+	if err := store.Replace(nil, files); err != nil {
+		t.Fatalf("unexpected error replacing files: %v", err)
+	}
+	defer store.Close()
+
+	s, err := store.CreateSnapshot()
+	if err != nil {
+		t.Fatalf("unexpected error creating snapshot: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(s, tsm1.ZstdDictFile)); err != nil {
+		t.Fatalf("expected zstd dictionary in snapshot: %v", err)
+	}
+}
+
 func TestTSMKeyIterator_Single(t *testing.T) {
 	dir := MustTempDir()
 	defer os.RemoveAll(dir)
@@ -2877,6 +3122,118 @@ func TestDefaultPlanner_Plan_ForceFull(t *testing.T) {
 
 }
 
+func TestPriorityPlanner_PlanLevel_Tombstones(t *testing.T) {
+	var data []tsm1.FileStat
+	for i := 1; i <= 8; i++ {
+		f := tsm1.FileStat{
+			Path:     fmt.Sprintf("%02d-02.tsm1", i),
+			Size:     1 * 1024 * 1024,
+			KeyCount: 100,
+		}
+		if i > 4 {
+			f.HasTombstone = true
+			f.TombstoneCount = 50
+		}
+		data = append(data, f)
+	}
+
+	cp := tsm1.NewPriorityPlanner(
+		&fakeFileStore{
+			PathsFn: func() []tsm1.FileStat {
+				return data
+			},
+		}, tsdb.DefaultCompactFullWriteColdDuration,
+	)
+
+	// The generations with the most deleted keys are compacted first.
+	tsm := cp.PlanLevel(2)
+	if exp, got := 2, len(tsm); exp != got {
+		t.Fatalf("group length mismatch: got %v, exp %v", got, exp)
+	}
+	for i, p := range data[4:] {
+		if got, exp := tsm[0][i], p.Path; got != exp {
+			t.Fatalf("tsm file mismatch: got %v, exp %v", got, exp)
+		}
+	}
+
+	cp.Plan(time.Now())
+	if got, exp := cp.Priority().TombstoneRatio, 200.0/1000; got != exp {
+		t.Fatalf("tombstone ratio mismatch: got %v, exp %v", got, exp)
+	}
+}
+
+func TestPriorityPlanner_PlanOptimize_Hot(t *testing.T) {
+	data := []tsm1.FileStat{
+		{
+			Path:    "01-04.tsm1",
+			Size:    1 * 1024 * 1024,
+			MinKey:  []byte("cpu"),
+			MaxKey:  []byte("mem"),
+			MinTime: 0,
+			MaxTime: 100,
+		},
+		{
+			Path:    "02-04.tsm1",
+			Size:    1 * 1024 * 1024,
+			MinKey:  []byte("cpu"),
+			MaxKey:  []byte("mem"),
+			MinTime: 50,
+			MaxTime: 150,
+		},
+	}
+
+	fs := &fakeFileStore{
+		PathsFn: func() []tsm1.FileStat {
+			return data
+		},
+	}
+	cp := tsm1.NewPriorityPlanner(fs, tsdb.DefaultCompactFullWriteColdDuration)
+
+	// Two generations are not worth optimizing while the shard is not read.
+	if tsm := cp.Plan(time.Now()); len(tsm) != 0 {
+		t.Fatalf("unexpected plan: %v", tsm)
+	}
+	if tsm := cp.PlanOptimize(); len(tsm) != 0 {
+		t.Fatalf("unexpected optimize plan: %v", tsm)
+	}
+
+	fs.reads += 10000
+	cp.Plan(time.Now())
+
+	priority := cp.Priority()
+	if priority.ReadHeat < cp.HotReadRate {
+		t.Fatalf("expected shard to be hot, got read heat %v", priority.ReadHeat)
+	}
+	if exp, got := 2, priority.OverlappingFiles; exp != got {
+		t.Fatalf("overlapping files mismatch: got %v, exp %v", got, exp)
+	}
+	if priority.Score <= 0 {
+		t.Fatalf("expected positive score, got %v", priority.Score)
+	}
+
+	tsm := cp.PlanOptimize()
+	if exp, got := 1, len(tsm); exp != got {
+		t.Fatalf("group length mismatch: got %v, exp %v", got, exp)
+	}
+	for i, p := range data {
+		if got, exp := tsm[0][i], p.Path; got != exp {
+			t.Fatalf("tsm file mismatch: got %v, exp %v", got, exp)
+		}
+	}
+	cp.Release(tsm)
+
+	// Generations that do not overlap are left alone however hot the shard is.
+	data[1].MinTime = 101
+	fs.lastModified = time.Now()
+	cp.Plan(time.Now())
+	if exp, got := 0, cp.Priority().OverlappingFiles; exp != got {
+		t.Fatalf("overlapping files mismatch: got %v, exp %v", got, exp)
+	}
+	if tsm := cp.PlanOptimize(); len(tsm) != 0 {
+		t.Fatalf("unexpected optimize plan: %v", tsm)
+	}
+}
+
 func assertValueEqual(t *testing.T, a, b tsm1.Value) {
 	if got, exp := a.UnixNano(), b.UnixNano(); got != exp {
 		t.Fatalf("time mismatch: got %v, exp %v", got, exp)
@@ -2886,6 +3243,29 @@ func assertValueEqual(t *testing.T, a, b tsm1.Value) {
 	}
 }
 
+// assertBlockCodecs checks the codec of every block of each key in files.
+func assertBlockCodecs(t *testing.T, files []string, exp map[string]string) {
+	t.Helper()
+	for _, f := range files {
+		r := MustOpenTSMReader(f)
+		iter := r.BlockIterator()
+		for iter.Next() {
+			key, _, _, _, _, buf, err := iter.Read()
+			if err != nil {
+				t.Fatalf("unexpected error reading block: %v", err)
+			}
+			codec, err := tsm1.BlockCodec(buf)
+			if err != nil {
+				t.Fatalf("unexpected error reading codec: %v", err)
+			}
+			if got, exp := codec, exp[string(key)]; got != exp {
+				t.Fatalf("codec mismatch %s: got %q, exp %q", key, got, exp)
+			}
+		}
+		r.Close()
+	}
+}
+
 func MustTSMWriter(dir string, gen int) (tsm1.TSMWriter, string) {
 	f := MustTempFile(dir)
 	oldName := f.Name()
@@ -2941,6 +3321,24 @@ func MustWriteTSM(dir string, gen int, values map[string][]tsm1.Value) string {
 	return name
 }
 
+// MustCorruptFile flips the bits of the byte at offset in the file at path.
+func MustCorruptFile(path string, offset int64) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0666)
+	if err != nil {
+		panic(fmt.Sprintf("open file: %v", err))
+	}
+	defer f.Close()
+
+	b := make([]byte, 1)
+	if _, err := f.ReadAt(b, offset); err != nil {
+		panic(fmt.Sprintf("read file: %v", err))
+	}
+	b[0] = ^b[0]
+	if _, err := f.WriteAt(b, offset); err != nil {
+		panic(fmt.Sprintf("write file: %v", err))
+	}
+}
+
 func MustTSMReader(dir string, gen int, values map[string][]tsm1.Value) *tsm1.TSMReader {
 	return MustOpenTSMReader(MustWriteTSM(dir, gen, values))
 }
@@ -2962,6 +3360,7 @@ type fakeFileStore struct {
 	PathsFn      func() []tsm1.FileStat
 	lastModified time.Time
 	blockCount   int
+	reads        int64
 	readers      []*tsm1.TSMReader
 }
 
@@ -2969,6 +3368,10 @@ func (w *fakeFileStore) Stats() []tsm1.FileStat {
 	return w.PathsFn()
 }
 
+func (w *fakeFileStore) Reads() int64 {
+	return w.reads
+}
+
 func (w *fakeFileStore) NextGeneration() int {
 	return 1
 }
@@ -233,6 +233,34 @@ func BlockType(block []byte) (byte, error) {
 	}
 }
 
+// BlockCodec returns the name of the codec of the values of a float or string
+// block.  It returns an empty string for other blocks and empty blocks.
+func BlockCodec(block []byte) (string, error) {
+	if len(block) <= encodedBlockHeaderSize {
+		return "", fmt.Errorf("codec of short block: got %v, exp %v", len(block), encodedBlockHeaderSize)
+	}
+	_, vb, err := unpackBlock(block[1:])
+	if err != nil {
+		return "", err
+	} else if len(vb) == 0 {
+		return "", nil
+	}
+
+	switch enc := vb[0] >> 4; {
+	case block[0] == BlockFloat64 && enc == floatCompressedGorilla:
+		return FloatCodecGorilla, nil
+	case block[0] == BlockFloat64 && enc == floatCompressedZstd:
+		return CodecZstd, nil
+	case block[0] == BlockString && enc == stringCompressedSnappy:
+		return StringCodecSnappy, nil
+	case block[0] == BlockString && enc == stringCompressedZstd:
+		return CodecZstd, nil
+	case block[0] == BlockFloat64 || block[0] == BlockString:
+		return "", fmt.Errorf("unknown encoding %d for block type %d", enc, block[0])
+	}
+	return "", nil
+}
+
 // BlockCount returns the number of timestamps encoded in block.
 func BlockCount(block []byte) int {
 	if len(block) <= encodedBlockHeaderSize {
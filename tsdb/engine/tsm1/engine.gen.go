@@ -9,6 +9,7 @@ package tsm1
 import (
 	"context"
 
+	"github.com/influxdata/influxdb/pkg/metrics"
 	"github.com/influxdata/influxdb/query"
 )
 
@@ -16,6 +17,9 @@ import (
 func (e *Engine) buildFloatCursor(ctx context.Context, measurement, seriesKey, field string, opt query.IteratorOptions) floatCursor {
 	key := SeriesFieldKeyBytes(seriesKey, field)
 	cacheValues := e.Cache.Values(key)
+	if col := metrics.GroupFromContext(ctx); col != nil {
+		col.GetCounter(cacheValuesReadCounter).Add(int64(len(cacheValues)))
+	}
 	keyCursor := e.KeyCursor(ctx, key, opt.SeekTime(), opt.Ascending)
 	return newFloatCursor(opt.SeekTime(), opt.Ascending, cacheValues, keyCursor)
 }
@@ -24,6 +28,9 @@ func (e *Engine) buildFloatCursor(ctx context.Context, measurement, seriesKey, f
 func (e *Engine) buildIntegerCursor(ctx context.Context, measurement, seriesKey, field string, opt query.IteratorOptions) integerCursor {
 	key := SeriesFieldKeyBytes(seriesKey, field)
 	cacheValues := e.Cache.Values(key)
+	if col := metrics.GroupFromContext(ctx); col != nil {
+		col.GetCounter(cacheValuesReadCounter).Add(int64(len(cacheValues)))
+	}
 	keyCursor := e.KeyCursor(ctx, key, opt.SeekTime(), opt.Ascending)
 	return newIntegerCursor(opt.SeekTime(), opt.Ascending, cacheValues, keyCursor)
 }
@@ -32,6 +39,9 @@ func (e *Engine) buildIntegerCursor(ctx context.Context, measurement, seriesKey,
 func (e *Engine) buildUnsignedCursor(ctx context.Context, measurement, seriesKey, field string, opt query.IteratorOptions) unsignedCursor {
 	key := SeriesFieldKeyBytes(seriesKey, field)
 	cacheValues := e.Cache.Values(key)
+	if col := metrics.GroupFromContext(ctx); col != nil {
+		col.GetCounter(cacheValuesReadCounter).Add(int64(len(cacheValues)))
+	}
 	keyCursor := e.KeyCursor(ctx, key, opt.SeekTime(), opt.Ascending)
 	return newUnsignedCursor(opt.SeekTime(), opt.Ascending, cacheValues, keyCursor)
 }
@@ -40,6 +50,9 @@ func (e *Engine) buildUnsignedCursor(ctx context.Context, measurement, seriesKey
 func (e *Engine) buildStringCursor(ctx context.Context, measurement, seriesKey, field string, opt query.IteratorOptions) stringCursor {
 	key := SeriesFieldKeyBytes(seriesKey, field)
 	cacheValues := e.Cache.Values(key)
+	if col := metrics.GroupFromContext(ctx); col != nil {
+		col.GetCounter(cacheValuesReadCounter).Add(int64(len(cacheValues)))
+	}
 	keyCursor := e.KeyCursor(ctx, key, opt.SeekTime(), opt.Ascending)
 	return newStringCursor(opt.SeekTime(), opt.Ascending, cacheValues, keyCursor)
 }
@@ -48,6 +61,9 @@ func (e *Engine) buildStringCursor(ctx context.Context, measurement, seriesKey,
 func (e *Engine) buildBooleanCursor(ctx context.Context, measurement, seriesKey, field string, opt query.IteratorOptions) booleanCursor {
 	key := SeriesFieldKeyBytes(seriesKey, field)
 	cacheValues := e.Cache.Values(key)
+	if col := metrics.GroupFromContext(ctx); col != nil {
+		col.GetCounter(cacheValuesReadCounter).Add(int64(len(cacheValues)))
+	}
 	keyCursor := e.KeyCursor(ctx, key, opt.SeekTime(), opt.Ascending)
 	return newBooleanCursor(opt.SeekTime(), opt.Ascending, cacheValues, keyCursor)
 }
@@ -14,6 +14,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -64,6 +65,7 @@ var (
 	numberOfRefCursorsCounter  = metrics.MustRegisterCounter("cursors_ref", metrics.WithGroup(tsmGroup))
 	numberOfAuxCursorsCounter  = metrics.MustRegisterCounter("cursors_aux", metrics.WithGroup(tsmGroup))
 	numberOfCondCursorsCounter = metrics.MustRegisterCounter("cursors_cond", metrics.WithGroup(tsmGroup))
+	cacheValuesReadCounter     = metrics.MustRegisterCounter("cache_values_read", metrics.WithGroup(tsmGroup))
 	planningTimer              = metrics.MustRegisterTimer("planning_time", metrics.WithGroup(tsmGroup))
 )
 
@@ -125,6 +127,18 @@ const (
 	statTSMFullCompactionError    = "tsmFullCompactionErr"
 	statTSMFullCompactionDuration = "tsmFullCompactionDuration"
 	statTSMFullCompactionQueue    = "tsmFullCompactionQueue"
+
+	statTSMCompactionsDeferred        = "tsmCompactionsDeferred"
+	statTSMCompactionPriority         = "tsmCompactionPriority"
+	statTSMCompactionReadHeat         = "tsmCompactionReadHeat"
+	statTSMCompactionOverlappingFiles = "tsmCompactionOverlappingFiles"
+	statTSMCompactionTombstoneRatio   = "tsmCompactionTombstoneRatio"
+
+	statBulkLoadSpills          = "bulkLoadSpills"
+	statBulkLoadCommits         = "bulkLoadCommits"
+	statBulkLoadCommitError     = "bulkLoadCommitErr"
+	statBulkLoadCommitDuration  = "bulkLoadCommitDuration"
+	statBulkLoadBufferSizeBytes = "bulkLoadBufferSizeBytes"
 )
 
 // Engine represents a storage engine with compressed blocks.
@@ -148,6 +162,23 @@ type Engine struct {
 	snapDone chan struct{}   // channel to signal snapshot compactions to stop
 	snapWG   *sync.WaitGroup // waitgroup for running snapshot compactions
 
+	// repairMu keeps deletes from adding tombstones to TSM files while Repair
+	// rewrites them.
+	repairMu sync.RWMutex
+
+	// The following group of fields holds the state of the bulk load of the
+	// engine: the buffer of points not spilled yet, the sorted TSM files the
+	// buffer was spilled to and the time of the last bulk write.
+	bulkMu        sync.Mutex
+	bulk          *Cache
+	bulkRuns      []string
+	bulkLastWrite time.Time
+
+	// bulkMinTime and bulkMaxTime are the time range of the points of the bulk
+	// load, which bulkCommitted is called with once they are committed.
+	bulkMinTime, bulkMaxTime int64
+	bulkCommitted            func(min, max int64)
+
 	id           uint64
 	path         string
 	sfile        *tsdb.SeriesFile
@@ -174,6 +205,14 @@ type Engine struct {
 	// a snapshot of the cache to a TSM file
 	CacheFlushWriteColdDuration time.Duration
 
+	// BulkLoadBufferSize is the size of the buffer of a bulk load above which
+	// its points are spilled to disk.
+	BulkLoadBufferSize uint64
+
+	// BulkLoadCommitInterval is the length of time without bulk writes after
+	// which the bulk load is committed to new TSM files.
+	BulkLoadCommitInterval time.Duration
+
 	// WALEnabled determines whether writes to the WAL are enabled.  If this is false,
 	// writes will only exist in the cache and can be lost if a snapshot has not occurred.
 	WALEnabled bool
@@ -204,6 +243,10 @@ func NewEngine(id uint64, idx tsdb.Index, path string, walPath string, sfile *ts
 	if opt.WALEnabled {
 		wal = NewWAL(walPath)
 		wal.syncDelay = time.Duration(opt.Config.WALFsyncDelay)
+		if opt.Config.WALArchiveDir != "" {
+			wal.archivePath = opt.Config.WALArchivePath(walPath, id)
+			wal.archiveRetention = time.Duration(opt.Config.WALArchiveRetention)
+		}
 	}
 
 	fs := NewFileStore(path)
@@ -219,8 +262,14 @@ func NewEngine(id uint64, idx tsdb.Index, path string, walPath string, sfile *ts
 	c.Dir = path
 	c.FileStore = fs
 	c.RateLimit = opt.CompactionThroughputLimiter
+	c.StringCodec = opt.Config.TSMStringCodec
+	c.FloatCodec = opt.Config.TSMFloatCodec
+	c.ZstdDictionary = opt.Config.TSMZstdDictionary
 
 	var planner CompactionPlanner = NewDefaultPlanner(fs, time.Duration(opt.Config.CompactFullWriteColdDuration))
+	if opt.Config.CompactPlanner == "priority" {
+		planner = NewPriorityPlanner(fs, time.Duration(opt.Config.CompactFullWriteColdDuration))
+	}
 	if opt.CompactionPlannerCreator != nil {
 		planner = opt.CompactionPlannerCreator(opt.Config).(CompactionPlanner)
 		planner.SetFileStore(fs)
@@ -246,6 +295,8 @@ func NewEngine(id uint64, idx tsdb.Index, path string, walPath string, sfile *ts
 
 		CacheFlushMemorySizeThreshold: uint64(opt.Config.CacheSnapshotMemorySize),
 		CacheFlushWriteColdDuration:   time.Duration(opt.Config.CacheSnapshotWriteColdDuration),
+		BulkLoadBufferSize:            uint64(opt.Config.BulkLoadBufferSize),
+		BulkLoadCommitInterval:        time.Duration(opt.Config.BulkLoadCommitInterval),
 		enableCompactionsOnOpen:       true,
 		WALEnabled:                    opt.WALEnabled,
 		formatFileName:                DefaultFormatFileName,
@@ -253,8 +304,13 @@ func NewEngine(id uint64, idx tsdb.Index, path string, walPath string, sfile *ts
 		compactionLimiter:             opt.CompactionLimiter,
 		scheduler:                     newScheduler(stats, opt.CompactionLimiter.Capacity()),
 		seriesIDSets:                  opt.SeriesIDSets,
+		bulkCommitted:                 opt.BulkLoadCommitted,
 	}
 
+	e.scheduler.id = id
+	e.scheduler.priorities = opt.CompactionPriorities
+	e.scheduler.limiter = opt.CompactionLimiter
+
 	// Feature flag to enable per-series type checking, by default this is off and
 	// e.seriesTypeMap will be nil.
 	if os.Getenv("INFLUXDB_SERIES_TYPE_CHECK_ENABLED") != "" {
@@ -647,6 +703,13 @@ type EngineStatistics struct {
 	TSMFullCompactionErrors   int64 // Counter of full compactions that have failed due to error.
 	TSMFullCompactionDuration int64 // Counter of number of wall nanoseconds spent in full compactions.
 	TSMFullCompactionsQueue   int64 // Gauge of full compactions queue.
+
+	TSMCompactionsDeferred int64 // Counter of compactions deferred to shards with a higher priority.
+
+	BulkLoadSpills         int64 // Counter of bulk load buffers spilled to disk.
+	BulkLoadCommits        int64 // Counter of bulk loads committed to TSM files.
+	BulkLoadCommitErrors   int64 // Counter of bulk load commits that have failed due to error.
+	BulkLoadCommitDuration int64 // Counter of number of wall nanoseconds spent committing bulk loads.
 }
 
 // Statistics returns statistics for periodic monitoring.
@@ -690,9 +753,26 @@ func (e *Engine) Statistics(tags map[string]string) []models.Statistic {
 			statTSMFullCompactionError:    atomic.LoadInt64(&e.stats.TSMFullCompactionErrors),
 			statTSMFullCompactionDuration: atomic.LoadInt64(&e.stats.TSMFullCompactionDuration),
 			statTSMFullCompactionQueue:    atomic.LoadInt64(&e.stats.TSMFullCompactionsQueue),
+
+			statTSMCompactionsDeferred: atomic.LoadInt64(&e.stats.TSMCompactionsDeferred),
+
+			statBulkLoadSpills:          atomic.LoadInt64(&e.stats.BulkLoadSpills),
+			statBulkLoadCommits:         atomic.LoadInt64(&e.stats.BulkLoadCommits),
+			statBulkLoadCommitError:     atomic.LoadInt64(&e.stats.BulkLoadCommitErrors),
+			statBulkLoadCommitDuration:  atomic.LoadInt64(&e.stats.BulkLoadCommitDuration),
+			statBulkLoadBufferSizeBytes: int64(e.bulkSize()),
 		},
 	})
 
+	if p, ok := e.CompactionPlan.(CompactionPrioritizer); ok {
+		priority := p.Priority()
+		values := statistics[0].Values
+		values[statTSMCompactionPriority] = priority.Score
+		values[statTSMCompactionReadHeat] = priority.ReadHeat
+		values[statTSMCompactionOverlappingFiles] = int64(priority.OverlappingFiles)
+		values[statTSMCompactionTombstoneRatio] = priority.TombstoneRatio
+	}
+
 	statistics = append(statistics, e.Cache.Statistics(tags)...)
 	statistics = append(statistics, e.FileStore.Statistics(tags)...)
 	if e.WALEnabled {
@@ -758,6 +838,11 @@ func (e *Engine) Open() error {
 
 // Close closes the engine. Subsequent calls to Close are a nop.
 func (e *Engine) Close() error {
+	// Commit the bulk load while compactions are still enabled.
+	if err := e.commitBulk(); err != nil {
+		e.logger.Info("Error committing bulk load", zap.Error(err))
+	}
+
 	e.SetCompactionsEnabled(false)
 
 	// Lock now and close everything else down.
@@ -768,6 +853,7 @@ func (e *Engine) Close() error {
 	if err := e.FileStore.Close(); err != nil {
 		return err
 	}
+	e.Compactor.releaseZstdDict()
 	if e.WALEnabled {
 		return e.WAL.Close()
 	}
@@ -877,10 +963,10 @@ func (e *Engine) LoadMetadataIndex(shardID uint64, index tsdb.Index) error {
 	return nil
 }
 
-// IsIdle returns true if the cache is empty, there are no running compactions and the
-// shard is fully compacted.
+// IsIdle returns true if the cache is empty, there is no bulk load to commit, there are
+// no running compactions and the shard is fully compacted.
 func (e *Engine) IsIdle() bool {
-	cacheEmpty := e.Cache.Size() == 0
+	cacheEmpty := e.Cache.Size() == 0 && !e.bulkPending()
 
 	runningCompactions := atomic.LoadInt64(&e.stats.CacheCompactionsActive)
 	runningCompactions += atomic.LoadInt64(&e.stats.TSMCompactionsActive[0])
@@ -1169,12 +1255,16 @@ func (e *Engine) readFileFromBackup(tr *tar.Reader, shardRelativePath string, as
 		return "", err
 	}
 
+	nativeFileName := filepath.FromSlash(hdr.Name)
+	if filepath.Base(nativeFileName) == ZstdDictFile && strings.HasPrefix(nativeFileName, shardRelativePath) {
+		return "", e.restoreZstdDict(tr)
+	}
+
 	if !strings.HasSuffix(hdr.Name, TSMFileExtension) {
 		// This isn't a .tsm file.
 		return "", nil
 	}
 
-	nativeFileName := filepath.FromSlash(hdr.Name)
 	// Skip file if it does not have a matching prefix.
 	if !strings.HasPrefix(nativeFileName, shardRelativePath) {
 		return "", nil
@@ -1217,6 +1307,38 @@ func (e *Engine) readFileFromBackup(tr *tar.Reader, shardRelativePath string, as
 	return tmp, nil
 }
 
+// restoreZstdDict installs the zstd dictionary read from a backup archive, so
+// the restored blocks compressed with it can be decoded.  A shard only has one
+// dictionary, so restoring fails if the shard already has a different one.
+func (e *Engine) restoreZstdDict(r io.Reader) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	if cur, err := ioutil.ReadFile(filepath.Join(e.path, ZstdDictFile)); err == nil {
+		if !bytes.Equal(cur, b) {
+			return fmt.Errorf("shard %d already has a different zstd dictionary", e.id)
+		}
+		return nil
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	// Registering the dictionary checks it before it is written. The file
+	// store keeps it registered from then on, until the shard is closed.
+	id, err := registerZstdDict(b)
+	if err != nil {
+		return err
+	}
+	defer unregisterZstdDict(id)
+
+	if err := writeZstdDict(e.path, b); err != nil {
+		return err
+	}
+	return e.FileStore.reloadZstdDict()
+}
+
 // addToIndexFromKey will pull the measurement names, series keys, and field
 // names from composite keys, and add them to the database index and measurement
 // fields.
@@ -1256,11 +1378,41 @@ func (e *Engine) addToIndexFromKey(keys [][]byte, fieldTypes []influxql.DataType
 // It returns an error if new points are added to an existing key.
 // Entry of write
 func (e *Engine) WritePoints(points []models.Point) error {
-	values := make(map[string][]Value, len(points))
+	values, seriesErr, err := e.pointValues(points)
+	if err != nil {
+		return err
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	// first try to write to the cache
+	// write to the cache
+	// one shard one cache, there are so risks of memory overflow if you backfill points cross many shards
+	// 写入tsm cache中
+	if err := e.Cache.WriteMulti(values); err != nil {
+		return err
+	}
+
+	if e.WALEnabled {
+		// write to the wal
+		// 写入tsm wal中
+		if _, err := e.WAL.WriteMulti(values); err != nil {
+			return err
+		}
+	}
+	// 至此完成了写操作。
+	// 写操作主要有两个步骤：写cache、写wal。前者写内存，后者顺序写磁盘，耗时小。这就是为什么写的速度非常快
+	return seriesErr
+}
+
+// pointValues returns the values of the fields of points keyed by series and
+// field.  seriesErr is set if fields were dropped because of a type conflict.
+func (e *Engine) pointValues(points []models.Point) (values map[string][]Value, seriesErr error, err error) {
+	values = make(map[string][]Value, len(points))
 	var (
-		keyBuf    []byte
-		baseLen   int
-		seriesErr error
+		keyBuf  []byte
+		baseLen int
 	)
 
 	for _, p := range points {
@@ -1312,19 +1464,19 @@ func (e *Engine) WritePoints(points []models.Point) error {
 			case models.Float:
 				fv, err := iter.FloatValue()
 				if err != nil {
-					return err
+					return nil, nil, err
 				}
 				v = NewFloatValue(t, fv)
 			case models.Integer:
 				iv, err := iter.IntegerValue()
 				if err != nil {
-					return err
+					return nil, nil, err
 				}
 				v = NewIntegerValue(t, iv)
 			case models.Unsigned:
 				iv, err := iter.UnsignedValue()
 				if err != nil {
-					return err
+					return nil, nil, err
 				}
 				v = NewUnsignedValue(t, iv)
 			case models.String:
@@ -1332,37 +1484,16 @@ func (e *Engine) WritePoints(points []models.Point) error {
 			case models.Boolean:
 				bv, err := iter.BooleanValue()
 				if err != nil {
-					return err
+					return nil, nil, err
 				}
 				v = NewBooleanValue(t, bv)
 			default:
-				return fmt.Errorf("unknown field type for %s: %s", string(iter.FieldKey()), p.String())
+				return nil, nil, fmt.Errorf("unknown field type for %s: %s", string(iter.FieldKey()), p.String())
 			}
 			values[string(keyBuf)] = append(values[string(keyBuf)], v)
 		}
 	}
-
-	e.mu.RLock()
-	defer e.mu.RUnlock()
-
-	// first try to write to the cache
-	// write to the cache
-	// one shard one cache, there are so risks of memory overflow if you backfill points cross many shards
-	// 写入tsm cache中
-	if err := e.Cache.WriteMulti(values); err != nil {
-		return err
-	}
-
-	if e.WALEnabled {
-		// write to the wal
-		// 写入tsm wal中
-		if _, err := e.WAL.WriteMulti(values); err != nil {
-			return err
-		}
-	}
-	// 至此完成了写操作。
-	// 写操作主要有两个步骤：写cache、写wal。前者写内存，后者顺序写磁盘，耗时小。这就是为什么写的速度非常快
-	return seriesErr
+	return values, seriesErr, nil
 }
 
 // DeleteSeriesRange removes the values between min and max (inclusive) from all series
@@ -1377,6 +1508,11 @@ func (e *Engine) DeleteSeriesRange(itr tsdb.SeriesIterator, min, max int64) erro
 func (e *Engine) DeleteSeriesRangeWithPredicate(itr tsdb.SeriesIterator, predicate func(name []byte, tags models.Tags) (int64, int64, bool)) error {
 	var disableOnce bool
 
+	// Commit the bulk load so that the delete applies to its points as well.
+	if err := e.commitBulk(); err != nil {
+		return err
+	}
+
 	// Ensure that the index does not compact away the measurement or series we're
 	// going to delete before we're done with them.
 	if tsiIndex, ok := e.index.(*tsi1.Index); ok {
@@ -1496,6 +1632,9 @@ func (e *Engine) deleteSeriesRange(seriesKeys [][]byte, min, max int64) error {
 		return nil
 	}
 
+	e.repairMu.RLock()
+	defer e.repairMu.RUnlock()
+
 	// Ensure keys are sorted since lower layers require them to be.
 	// 排序
 	if !bytesutil.IsSorted(seriesKeys) {
@@ -1510,6 +1649,12 @@ func (e *Engine) deleteSeriesRange(seriesKeys [][]byte, min, max int64) error {
 		max = math.MaxInt64
 	}
 
+	// Keys deleted from TSM files are also logged to an archived WAL so
+	// that point-in-time restores replay the delete.
+	var archiveMu sync.Mutex
+	var archiveKeys [][]byte
+	archiving := e.WALEnabled && e.WAL.archivePath != ""
+
 	// Run the delete on each TSM file in parallel
 	// delete from tombstone
 	// 遍历所有的tsm文件
@@ -1552,6 +1697,11 @@ func (e *Engine) deleteSeriesRange(seriesKeys [][]byte, min, max int64) error {
 					batch.Rollback()
 					return err
 				}
+				if archiving {
+					archiveMu.Lock()
+					archiveKeys = append(archiveKeys, append([]byte(nil), indexKey...))
+					archiveMu.Unlock()
+				}
 			}
 		}
 
@@ -1593,8 +1743,13 @@ func (e *Engine) deleteSeriesRange(seriesKeys [][]byte, min, max int64) error {
 
 	// delete from the WAL
 	if e.WALEnabled {
+		walKeys := deleteKeys
+		if len(archiveKeys) > 0 {
+			walKeys = bytesutil.SortDedup(append(archiveKeys, deleteKeys...))
+		}
+
 		// 记录删除操作
-		if _, err := e.WAL.DeleteRange(deleteKeys, min, max); err != nil {
+		if _, err := e.WAL.DeleteRange(walKeys, min, max); err != nil {
 			return err
 		}
 	}
@@ -1893,9 +2048,14 @@ func (e *Engine) WriteSnapshot() (err error) {
 }
 
 // CreateSnapshot will create a temp directory that holds
-// temporary hardlinks to the underylyng shard files.
+// temporary hardlinks to the underylyng shard files. The cache and the WAL
+// are flushed to TSM files first, so the snapshot holds every write made
+// before it was created.
 func (e *Engine) CreateSnapshot() (string, error) {
-	if err := e.WriteSnapshot(); err != nil {
+	if err := e.commitBulk(); err != nil {
+		return "", err
+	}
+	if err := e.flushCache(); err != nil {
 		return "", err
 	}
 
@@ -1910,6 +2070,18 @@ func (e *Engine) CreateSnapshot() (string, error) {
 	return path, nil
 }
 
+// flushCache writes the cache to a TSM file and removes the closed WAL
+// segments. A cache snapshot already in progress is waited for, since the
+// writes it holds would otherwise only be in the WAL.
+func (e *Engine) flushCache() error {
+	for {
+		if err := e.WriteSnapshot(); err != ErrSnapshotInProgress {
+			return err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
 // writeSnapshotAndCommit will write the passed cache to a new TSM file and remove the closed WAL segments.
 func (e *Engine) writeSnapshotAndCommit(log *zap.Logger, closedFiles []string, snapshot *Cache) (err error) {
 	defer func() {
@@ -1955,7 +2127,8 @@ func (e *Engine) writeSnapshotAndCommit(log *zap.Logger, closedFiles []string, s
 	return nil
 }
 
-// compactCache continually checks if the WAL cache should be written to disk.
+// compactCache continually checks if the WAL cache should be written to disk and
+// if the bulk load should be committed.
 // compact immutable memory
 func (e *Engine) compactCache() {
 	t := time.NewTicker(time.Second)
@@ -1984,6 +2157,12 @@ func (e *Engine) compactCache() {
 				}
 				atomic.AddInt64(&e.stats.CacheCompactionDuration, time.Since(start).Nanoseconds())
 			}
+
+			if e.ShouldCommitBulk(time.Now()) {
+				if err := e.commitBulk(); err != nil && err != errCompactionsDisabled {
+					e.logger.Info("Error committing bulk load", zap.Error(err))
+				}
+			}
 		}
 	}
 }
@@ -2007,6 +2186,7 @@ func (e *Engine) ShouldCompactCache(t time.Time) bool {
 func (e *Engine) compact(wg *sync.WaitGroup) {
 	t := time.NewTicker(time.Second)
 	defer t.Stop()
+	defer e.scheduler.removePriority()
 
 	for {
 		e.mu.RLock()
@@ -2042,27 +2222,37 @@ func (e *Engine) compact(wg *sync.WaitGroup) {
 			e.scheduler.setDepth(2, len(level2Groups))
 			e.scheduler.setDepth(3, len(level3Groups))
 			e.scheduler.setDepth(4, len(level4Groups))
+			if p, ok := e.CompactionPlan.(CompactionPrioritizer); ok {
+				e.scheduler.setPriority(p.Priority().Score)
+			}
 
 			// Find the next compaction that can run and try to kick it off
 			if level, runnable := e.scheduler.next(); runnable {
+				var started bool
 				switch level {
 				case 1:
-					if e.compactHiPriorityLevel(level1Groups[0], 1, false, wg) {
+					if started = e.compactHiPriorityLevel(level1Groups[0], 1, false, wg); started {
 						level1Groups = level1Groups[1:]
 					}
 				case 2:
-					if e.compactHiPriorityLevel(level2Groups[0], 2, false, wg) {
+					if started = e.compactHiPriorityLevel(level2Groups[0], 2, false, wg); started {
 						level2Groups = level2Groups[1:]
 					}
 				case 3:
-					if e.compactLoPriorityLevel(level3Groups[0], 3, true, wg) {
+					if started = e.compactLoPriorityLevel(level3Groups[0], 3, true, wg); started {
 						level3Groups = level3Groups[1:]
 					}
 				case 4:
-					if e.compactFull(level4Groups[0], wg) {
+					if started = e.compactFull(level4Groups[0], wg); started {
 						level4Groups = level4Groups[1:]
 					}
 				}
+
+				// A shard holding a compaction is no longer waiting for one, so
+				// it must not be counted against the available compactions.
+				if started {
+					e.scheduler.removePriority()
+				}
 			}
 
 			// Release all the plans we didn't start.
@@ -2453,6 +2643,7 @@ func (e *Engine) createCallIterator(ctx context.Context, measurement string, cal
 			default:
 			}
 
+			// Each series is wrapped in a call iterator as it is created.
 			inputs, err := e.createTagSetIterators(ctx, ref, measurement, t, opt)
 			if err != nil {
 				return err
@@ -2460,20 +2651,6 @@ func (e *Engine) createCallIterator(ctx context.Context, measurement string, cal
 				continue
 			}
 
-			// Wrap each series in a call iterator.
-			for i, input := range inputs {
-				if opt.InterruptCh != nil {
-					input = query.NewInterruptIterator(input, opt.InterruptCh)
-				}
-
-				itr, err := query.NewCallIterator(input, opt)
-				if err != nil {
-					query.Iterators(inputs).Close()
-					return err
-				}
-				inputs[i] = itr
-			}
-
 			itr := query.NewParallelMergeIterator(inputs, opt, runtime.GOMAXPROCS(0))
 			itrs = append(itrs, itr)
 		}
@@ -2651,7 +2828,13 @@ func (e *Engine) createTagSetGroupIterators(ctx context.Context, ref *influxql.V
 			conditionFields = influxql.ExprNames(filters[i])
 		}
 
-		itr, err := e.createVarRefSeriesIterator(ctx, ref, name, seriesKey, t, filters[i], conditionFields, opt)
+		var itr query.Iterator
+		var err error
+		if call, ok := opt.Expr.(*influxql.Call); ok {
+			itr, err = e.createCallSeriesIterator(ctx, call, ref, name, seriesKey, t, filters[i], conditionFields, opt)
+		} else {
+			itr, err = e.createVarRefSeriesIterator(ctx, ref, name, seriesKey, t, filters[i], conditionFields, opt)
+		}
 		if err != nil {
 			return itrs, err
 		} else if itr == nil {
@@ -2677,6 +2860,137 @@ func (e *Engine) createTagSetGroupIterators(ctx context.Context, ref *influxql.V
 	return itrs, nil
 }
 
+// createCallSeriesIterator creates an iterator for a call for a series.  Blocks
+// answered by their summaries are merged into the result of the call instead
+// of being decoded.
+func (e *Engine) createCallSeriesIterator(ctx context.Context, call *influxql.Call, ref *influxql.VarRef, name string, seriesKey string, t *query.TagSet, filter influxql.Expr, conditionFields []influxql.VarRef, opt query.IteratorOptions) (query.Iterator, error) {
+	var input, summaries query.Iterator
+	if typ := e.summaryFieldType(call, ref, name, filter, opt); typ != influxql.Unknown {
+		input, summaries = e.createSummarySeriesIterators(ctx, call, ref.Val, typ, name, seriesKey, opt)
+	} else {
+		itr, err := e.createVarRefSeriesIterator(ctx, ref, name, seriesKey, t, filter, conditionFields, opt)
+		if err != nil || itr == nil {
+			return nil, err
+		}
+		input = itr
+	}
+
+	if opt.InterruptCh != nil {
+		input = query.NewInterruptIterator(input, opt.InterruptCh)
+	}
+
+	itr, err := query.NewCallIterator(input, opt)
+	if err != nil {
+		input.Close()
+		if summaries != nil {
+			summaries.Close()
+		}
+		return nil, err
+	} else if summaries == nil {
+		return itr, nil
+	}
+
+	merged, err := query.Iterators{itr, summaries}.Merge(opt)
+	if err != nil {
+		query.Iterators{itr, summaries}.Close()
+		return nil, err
+	}
+	return merged, nil
+}
+
+// summaryFieldType returns the type of the field of call if the call can be
+// answered from block summaries for a series, and influxql.Unknown otherwise.
+func (e *Engine) summaryFieldType(call *influxql.Call, ref *influxql.VarRef, measurement string, filter influxql.Expr, opt query.IteratorOptions) influxql.DataType {
+	switch call.Name {
+	case "count", "sum":
+	case "min", "max":
+		// Without an interval, the selected point keeps its own time which
+		// the summaries do not record.
+		if opt.Interval.IsZero() {
+			return influxql.Unknown
+		}
+	default:
+		return influxql.Unknown
+	}
+	if ref == nil || filter != nil || len(opt.Aux) > 0 {
+		return influxql.Unknown
+	}
+
+	mf := e.fieldset.FieldsByString(measurement)
+	if mf == nil {
+		return influxql.Unknown
+	}
+	f := mf.Field(ref.Val)
+	if f == nil {
+		return influxql.Unknown
+	} else if ref.Type != influxql.Unknown && ref.Type != influxql.AnyField && ref.Type != f.Type {
+		return influxql.Unknown
+	}
+
+	switch f.Type {
+	case influxql.Float, influxql.Integer, influxql.Unsigned:
+		return f.Type
+	}
+	return influxql.Unknown
+}
+
+// createSummarySeriesIterators creates an iterator over the values of a field
+// of a series that leaves out the blocks answered by their summaries, and an
+// iterator of the partial results of call for those blocks.
+func (e *Engine) createSummarySeriesIterators(ctx context.Context, call *influxql.Call, field string, typ influxql.DataType, measurement, seriesKey string, opt query.IteratorOptions) (query.Iterator, query.Iterator) {
+	key := SeriesFieldKeyBytes(seriesKey, field)
+	cacheValues := e.Cache.Values(key)
+	if col := metrics.GroupFromContext(ctx); col != nil {
+		col.GetCounter(cacheValuesReadCounter).Add(int64(len(cacheValues)))
+		col.GetCounter(numberOfRefCursorsCounter).Add(1)
+	}
+
+	// A block is answered by its summary if it lies within the time range
+	// and a single window, and no cached value overwrites its values.
+	keyCursor, blocks := e.FileStore.SummaryKeyCursor(ctx, key, opt.SeekTime(), opt.Ascending, func(min, max int64) bool {
+		if min < opt.StartTime || max > opt.EndTime {
+			return false
+		}
+		minStart, _ := opt.Window(min)
+		maxStart, _ := opt.Window(max)
+		if minStart != maxStart {
+			return false
+		}
+		i := sort.Search(len(cacheValues), func(i int) bool { return cacheValues[i].UnixNano() >= min })
+		return i == len(cacheValues) || cacheValues[i].UnixNano() > max
+	})
+	if col := metrics.GroupFromContext(ctx); col != nil {
+		col.GetCounter(blocksSummarizedCounter).Add(int64(len(blocks)))
+	}
+
+	_, tfs := models.ParseKey([]byte(seriesKey))
+	tags := query.NewTags(tfs.Map())
+	tags = tags.Subset(opt.GetDimensions())
+
+	name := measurement
+	if opt.StripName {
+		name = ""
+	}
+
+	// The series only has tag conditions, which were already matched.
+	itrOpt := opt
+	itrOpt.Condition = nil
+
+	switch typ {
+	case influxql.Float:
+		cur := newFloatCursor(opt.SeekTime(), opt.Ascending, cacheValues, keyCursor)
+		return newFloatIterator(name, tags, itrOpt, cur, nil, nil, nil), newSummaryIterator(call.Name, typ, name, tags, blocks)
+	case influxql.Integer:
+		cur := newIntegerCursor(opt.SeekTime(), opt.Ascending, cacheValues, keyCursor)
+		return newIntegerIterator(name, tags, itrOpt, cur, nil, nil, nil), newSummaryIterator(call.Name, typ, name, tags, blocks)
+	case influxql.Unsigned:
+		cur := newUnsignedCursor(opt.SeekTime(), opt.Ascending, cacheValues, keyCursor)
+		return newUnsignedIterator(name, tags, itrOpt, cur, nil, nil, nil), newSummaryIterator(call.Name, typ, name, tags, blocks)
+	default:
+		panic("unreachable")
+	}
+}
+
 // createVarRefSeriesIterator creates an iterator for a variable reference for a series.
 func (e *Engine) createVarRefSeriesIterator(ctx context.Context, ref *influxql.VarRef, name string, seriesKey string, t *query.TagSet, filter influxql.Expr, conditionFields []influxql.VarRef, opt query.IteratorOptions) (query.Iterator, error) {
 	_, tfs := models.ParseKey([]byte(seriesKey))
@@ -322,6 +322,38 @@ func TestEngine_Backup(t *testing.T) {
 	}
 }
 
+// Ensure a snapshot waits for a cache snapshot in progress and includes its writes.
+func TestEngine_CreateSnapshot_SnapshotInProgress(t *testing.T) {
+	e := MustOpenEngine(inmem.IndexName)
+	defer e.Close()
+
+	if err := e.WritePointsString(`cpu,host=A value=1.1 1000000000`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Start a cache snapshot, as the background snapshotter would, and fail it
+	// while the engine snapshot waits for it.
+	if _, err := e.Cache.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		dir, err := e.CreateSnapshot()
+		os.RemoveAll(dir)
+		done <- err
+	}()
+	e.Cache.ClearSnapshot(false)
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	} else if got, exp := e.FileStore.Count(), 1; got != exp {
+		t.Fatalf("got %d TSM files, expected %d", got, exp)
+	} else if got, exp := e.Cache.Size(), uint64(0); got != exp {
+		t.Fatalf("got cache size %d, expected %d", got, exp)
+	}
+}
+
 func TestEngine_Export(t *testing.T) {
 	// Generate temporary file.
 	f, _ := ioutil.TempFile("", "tsm")
@@ -816,6 +848,102 @@ func TestEngine_CreateIterator_TSM_Descending(t *testing.T) {
 	}
 }
 
+// Ensure engine answers aggregates from block summaries only where no other
+// block or cached value overlaps the block.
+func TestEngine_CreateIterator_Summaries(t *testing.T) {
+	t.Parallel()
+
+	for _, index := range tsdb.RegisteredIndexes() {
+		t.Run(index, func(t *testing.T) {
+			e := MustOpenEngine(index)
+			defer e.Close()
+
+			e.MeasurementFields([]byte("cpu")).CreateFieldIfNotExists([]byte("value"), influxql.Float)
+			e.CreateSeriesIfNotExists([]byte("cpu,host=A"), []byte("cpu"), models.NewTags(map[string]string{"host": "A"}))
+
+			for _, points := range [][]string{
+				// A block within a single window.
+				{`cpu,host=A value=1 1000000000`, `cpu,host=A value=2 2000000000`, `cpu,host=A value=3 3000000000`},
+				// A block that is partly overwritten by the cache.
+				{`cpu,host=A value=10 11000000000`, `cpu,host=A value=20 12000000000`},
+				// Blocks that overlap each other.
+				{`cpu,host=A value=5 21000000000`, `cpu,host=A value=6 25000000000`},
+				{`cpu,host=A value=7 22000000000`},
+				// A block that spans two windows.
+				{`cpu,host=A value=8 38000000000`, `cpu,host=A value=9 41000000000`},
+			} {
+				if err := e.WritePointsString(points...); err != nil {
+					t.Fatalf("failed to write points: %s", err.Error())
+				}
+				e.MustWriteSnapshot()
+			}
+			if err := e.WritePointsString(`cpu,host=A value=25 12000000000`); err != nil {
+				t.Fatalf("failed to write points: %s", err.Error())
+			}
+
+			for _, tt := range []struct {
+				expr string
+				exp  []interface{}
+			}{
+				{expr: `count(value)`, exp: []interface{}{int64(3), int64(2), int64(3), int64(1), int64(1)}},
+				{expr: `sum(value)`, exp: []interface{}{float64(6), float64(35), float64(18), float64(8), float64(9)}},
+				{expr: `min(value)`, exp: []interface{}{float64(1), float64(10), float64(5), float64(8), float64(9)}},
+				{expr: `max(value)`, exp: []interface{}{float64(3), float64(25), float64(7), float64(8), float64(9)}},
+			} {
+				for _, ascending := range []bool{true, false} {
+					itr, err := e.CreateIterator(context.Background(), "cpu", query.IteratorOptions{
+						Expr:       influxql.MustParseExpr(tt.expr),
+						Dimensions: []string{"host"},
+						Interval:   query.Interval{Duration: 10 * time.Second},
+						StartTime:  0,
+						EndTime:    50000000000 - 1,
+						Ascending:  ascending,
+					})
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					var got []interface{}
+					switch itr := itr.(type) {
+					case query.FloatIterator:
+						for {
+							p, err := itr.Next()
+							if err != nil {
+								t.Fatal(err)
+							} else if p == nil {
+								break
+							}
+							got = append(got, p.Value)
+						}
+					case query.IntegerIterator:
+						for {
+							p, err := itr.Next()
+							if err != nil {
+								t.Fatal(err)
+							} else if p == nil {
+								break
+							}
+							got = append(got, p.Value)
+						}
+					}
+					itr.Close()
+
+					exp := tt.exp
+					if !ascending {
+						exp = make([]interface{}, len(tt.exp))
+						for i, v := range tt.exp {
+							exp[len(exp)-1-i] = v
+						}
+					}
+					if diff := cmp.Diff(exp, got); diff != "" {
+						t.Fatalf("unexpected values for %s (ascending=%v):\n%s", tt.expr, ascending, diff)
+					}
+				}
+			}
+		})
+	}
+}
+
 // Ensure engine can create an iterator with auxilary fields.
 func TestEngine_CreateIterator_Aux(t *testing.T) {
 	t.Parallel()
@@ -1086,6 +1214,325 @@ func TestEngine_DeleteSeries(t *testing.T) {
 	}
 }
 
+// Ensures deletes of data already written to TSM files are logged to an
+// archived WAL.
+func TestEngine_DeleteSeriesRange_WALArchive(t *testing.T) {
+	for _, index := range tsdb.RegisteredIndexes() {
+		t.Run(index, func(t *testing.T) {
+			e, err := NewEngine(index, func(opt *tsdb.EngineOptions, root string) {
+				opt.Config.WALDir = root
+				opt.Config.WALArchiveDir = filepath.Join(root, "archive")
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			e.CompactionPlan = &mockPlanner{}
+			if err := e.Open(); err != nil {
+				t.Fatal(err)
+			}
+			defer e.Close()
+
+			p1 := MustParsePointString("cpu,host=A value=1.1 1000000000")
+			p2 := MustParsePointString("cpu,host=B value=1.2 2000000000")
+			for _, p := range []models.Point{p1, p2} {
+				if err := e.CreateSeriesIfNotExists(p.Key(), p.Name(), p.Tags()); err != nil {
+					t.Fatalf("create series index error: %v", err)
+				}
+			}
+			if err := e.WritePoints([]models.Point{p1, p2}); err != nil {
+				t.Fatalf("failed to write points: %s", err.Error())
+			} else if err := e.WriteSnapshot(); err != nil {
+				t.Fatalf("failed to snapshot: %s", err.Error())
+			}
+
+			// Segments are archived once the cache holds new writes to snapshot.
+			itr := &seriesIterator{keys: [][]byte{[]byte("cpu,host=A")}}
+			if err := e.DeleteSeriesRange(itr, math.MinInt64, math.MaxInt64); err != nil {
+				t.Fatalf("failed to delete series: %v", err)
+			} else if err := e.WritePoints([]models.Point{p2}); err != nil {
+				t.Fatalf("failed to write points: %s", err.Error())
+			} else if err := e.WriteSnapshot(); err != nil {
+				t.Fatalf("failed to snapshot: %s", err.Error())
+			}
+
+			var deleted []string
+			if err := tsm1.ReplayWALArchive(filepath.Join(e.root, "archive", "wal"), time.Time{}, time.Now(), func(entry tsm1.WALEntry, _ time.Time) error {
+				if d, ok := entry.(*tsm1.DeleteRangeWALEntry); ok {
+					for _, k := range d.Keys {
+						deleted = append(deleted, string(k))
+					}
+				}
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			if exp := []string{"cpu,host=A#!~#value"}; !reflect.DeepEqual(deleted, exp) {
+				t.Fatalf("unexpected deleted keys: got %v, exp %v", deleted, exp)
+			}
+		})
+	}
+}
+
+// Ensure the engine rewrites files without their corrupt blocks and reports them.
+func TestEngine_Repair(t *testing.T) {
+	e, err := NewEngine(tsdb.DefaultIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// mock the planner so compactions don't run during the test
+	e.CompactionPlan = &mockPlanner{}
+	if err := e.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	if err := e.WritePointsString(
+		"cpu,host=A value=1.1 1000000000",
+		"cpu,host=A value=1.2 2000000000",
+		"cpu,host=B value=2.1 1000000000",
+		"cpu,host=B value=2.2 2000000000",
+		"cpu,host=B value=2.3 3000000000",
+	); err != nil {
+		t.Fatalf("failed to write points: %s", err.Error())
+	}
+	if err := e.WriteSnapshot(); err != nil {
+		t.Fatalf("failed to snapshot: %s", err.Error())
+	}
+
+	keyA, keyB := []byte("cpu,host=A#!~#value"), []byte("cpu,host=B#!~#value")
+	path := e.FileStore.Files()[0].Path()
+	entry := e.FileStore.Files()[0].Entries(keyA)[0]
+	MustCorruptFile(path, entry.Offset+4+1)
+
+	// Deletes must carry over to the repaired file.
+	if err := e.FileStore.DeleteRange([][]byte{keyB}, 2000000000, 2000000000); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+
+	read := func(key []byte) []float64 {
+		buf := make([]tsm1.FloatValue, 1000)
+		c := e.FileStore.KeyCursor(context.Background(), key, 0, true)
+		defer c.Close()
+
+		var got []float64
+		for {
+			values, err := c.ReadFloatBlock(&buf)
+			if err != nil {
+				t.Fatalf("unexpected error reading values: %v", err)
+			} else if len(values) == 0 {
+				return got
+			}
+			for _, v := range values {
+				got = append(got, v.Value().(float64))
+			}
+			c.Next()
+		}
+	}
+
+	if got := read(keyA); len(got) != 0 {
+		t.Fatalf("unexpected values read from corrupt block: %v", got)
+	}
+	if got := len(e.FileStore.Quarantined()); got != 1 {
+		t.Fatalf("unexpected quarantined block count: got %d, exp 1", got)
+	}
+
+	blocks, err := e.Repair()
+	if err != nil {
+		t.Fatalf("failed to repair: %v", err)
+	}
+	exp := []tsdb.CorruptBlock{{
+		Path:    path,
+		Offset:  entry.Offset,
+		Series:  []byte("cpu,host=A"),
+		Field:   []byte("value"),
+		MinTime: 1000000000,
+		MaxTime: 2000000000,
+	}}
+	if !reflect.DeepEqual(blocks, exp) {
+		t.Fatalf("unexpected corrupt blocks: got %v, exp %v", blocks, exp)
+	}
+
+	// The repaired file is the next sequence of the generation.
+	files := e.FileStore.Files()
+	if len(files) != 1 {
+		t.Fatalf("unexpected file count: got %d, exp 1", len(files))
+	}
+	gen, seq, _ := tsm1.DefaultParseFileName(path)
+	if gotGen, gotSeq, _ := tsm1.DefaultParseFileName(files[0].Path()); gotGen != gen || gotSeq != seq+1 {
+		t.Fatalf("unexpected repaired file: %s", files[0].Path())
+	}
+
+	if got := e.FileStore.Quarantined(); len(got) != 0 {
+		t.Fatalf("unexpected quarantined blocks: %v", got)
+	}
+	if got, exp := read(keyB), []float64{2.1, 2.3}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected values: got %v, exp %v", got, exp)
+	}
+	if keys := e.FileStore.Keys(); len(keys) != 1 {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+
+	// There is nothing left to repair.
+	if blocks, err := e.Repair(); err != nil || len(blocks) != 0 {
+		t.Fatalf("unexpected repair result: %v, %v", blocks, err)
+	}
+}
+
+func TestEngine_WritePointsBulk(t *testing.T) {
+	e, err := NewEngine(tsdb.DefaultIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// mock the planner so compactions don't run during the test
+	e.CompactionPlan = &mockPlanner{}
+	if err := e.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	if err := e.WritePointsString("cpu,host=A value=1.5 5000000000"); err != nil {
+		t.Fatalf("failed to write points: %s", err.Error())
+	}
+	if err := e.WriteSnapshot(); err != nil {
+		t.Fatalf("failed to snapshot: %s", err.Error())
+	}
+
+	writeBulk := func(ptstr ...string) {
+		points, err := models.ParsePointsString(strings.Join(ptstr, "\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range points {
+			if err := e.CreateSeriesIfNotExists(p.Key(), p.Name(), p.Tags()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !e.IsBackfill(points) {
+			t.Fatalf("points not backfill: %v", points)
+		}
+		if err := e.WritePointsBulk(points); err != nil {
+			t.Fatalf("failed to write points: %s", err.Error())
+		}
+	}
+
+	if points := MustParsePointsString("cpu,host=A value=1.6 6000000000"); e.IsBackfill(points) {
+		t.Fatal("unexpected backfill of points newer than the newest file")
+	}
+
+	// Spill every write so the commit merges several runs.
+	e.BulkLoadBufferSize = 1
+	writeBulk(
+		"cpu,host=A value=1.1 1000000000",
+		"cpu,host=A value=1.2 2000000000",
+		"cpu,host=B value=2.1 1000000000",
+	)
+	writeBulk(
+		"cpu,host=A value=1.3 2000000000",
+		"cpu,host=B value=2.2 2000000000",
+	)
+
+	read := func(key string) []float64 {
+		buf := make([]tsm1.FloatValue, 1000)
+		c := e.FileStore.KeyCursor(context.Background(), []byte(key), 0, true)
+		defer c.Close()
+
+		var got []float64
+		for {
+			values, err := c.ReadFloatBlock(&buf)
+			if err != nil {
+				t.Fatalf("unexpected error reading values: %v", err)
+			} else if len(values) == 0 {
+				return got
+			}
+			for _, v := range values {
+				got = append(got, v.Value().(float64))
+			}
+			c.Next()
+		}
+	}
+
+	// Points are not queryable until the bulk load is committed.
+	if got := read("cpu,host=B#!~#value"); len(got) != 0 {
+		t.Fatalf("unexpected values before commit: %v", got)
+	}
+	if !e.ShouldCommitBulk(time.Now().Add(time.Hour)) {
+		t.Fatal("expected bulk load to be committed")
+	}
+
+	// Closing the engine commits the bulk load.
+	if err := e.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := read("cpu,host=A#!~#value"), []float64{1.1, 1.3, 1.5}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected values: got %v, exp %v", got, exp)
+	}
+	if got, exp := read("cpu,host=B#!~#value"), []float64{2.1, 2.2}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected values: got %v, exp %v", got, exp)
+	}
+
+	// The bulk load is written as a single fully compacted file.
+	files := e.FileStore.Files()
+	if len(files) != 2 {
+		t.Fatalf("unexpected file count: got %d, exp 2", len(files))
+	}
+	if _, seq, _ := tsm1.DefaultParseFileName(files[1].Path()); seq != 4 {
+		t.Fatalf("unexpected bulk load file: %s", files[1].Path())
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(files[1].Path()), "bulk.tmp")); !os.IsNotExist(err) {
+		t.Fatalf("unexpected bulk load directory: %v", err)
+	}
+	if e.ShouldCommitBulk(time.Now().Add(time.Hour)) {
+		t.Fatal("unexpected bulk load after commit")
+	}
+}
+
+// Ensure committed bulk points replace the points still in the cache.
+func TestEngine_WritePointsBulk_Cache(t *testing.T) {
+	e, err := NewEngine(tsdb.DefaultIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// mock the planner so compactions don't run during the test
+	e.CompactionPlan = &mockPlanner{}
+	if err := e.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	if err := e.WritePointsString("cpu,host=A value=1.1 1000000000"); err != nil {
+		t.Fatalf("failed to write points: %s", err.Error())
+	}
+	if err := e.WritePointsBulk(MustParsePointsString("cpu,host=A value=2.1 1000000000")); err != nil {
+		t.Fatalf("failed to write points: %s", err.Error())
+	}
+
+	// Closing the engine commits the bulk load.
+	if err := e.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+
+	key := []byte("cpu,host=A#!~#value")
+	if values := e.Cache.Values(key); len(values) != 0 {
+		t.Fatalf("unexpected values in cache: %v", values)
+	}
+
+	buf := make([]tsm1.FloatValue, 1000)
+	c := e.FileStore.KeyCursor(context.Background(), key, 0, true)
+	defer c.Close()
+	values, err := c.ReadFloatBlock(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error reading values: %v", err)
+	} else if len(values) != 1 || values[0].Value() != 2.1 {
+		t.Fatalf("unexpected values: %v", values)
+	}
+}
+
 func TestEngine_DeleteSeriesRange(t *testing.T) {
 	for _, index := range tsdb.RegisteredIndexes() {
 		t.Run(index, func(t *testing.T) {
@@ -2363,8 +2810,9 @@ type Engine struct {
 	sfile     *tsdb.SeriesFile
 }
 
-// NewEngine returns a new instance of Engine at a temporary location.
-func NewEngine(index string) (*Engine, error) {
+// NewEngine returns a new instance of Engine at a temporary location. The
+// engine options can be modified by passing functions.
+func NewEngine(index string, fns ...func(opt *tsdb.EngineOptions, root string)) (*Engine, error) {
 	root, err := ioutil.TempDir("", "tsm1-")
 	if err != nil {
 		panic(err)
@@ -2389,6 +2837,9 @@ func NewEngine(index string) (*Engine, error) {
 	if index == tsdb.InmemIndexName {
 		opt.InmemIndex = inmem.NewIndex(db, sfile)
 	}
+	for _, fn := range fns {
+		fn(&opt, root)
+	}
 	// Initialise series id sets. Need to do this as it's normally done at the
 	// store level.
 	seriesIDs := tsdb.NewSeriesIDSet()
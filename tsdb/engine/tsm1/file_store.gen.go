@@ -18,6 +18,7 @@ LOOP:
 	if err != nil {
 		return nil, err
 	}
+	c.checkQuarantined(first)
 	if c.col != nil {
 		c.col.GetCounter(floatBlocksDecodedCounter).Add(1)
 		c.col.GetCounter(floatBlocksSizeCounter).Add(int64(first.entry.Size))
@@ -92,6 +93,7 @@ LOOP:
 			if err != nil {
 				return nil, err
 			}
+			c.checkQuarantined(cur)
 			if c.col != nil {
 				c.col.GetCounter(floatBlocksDecodedCounter).Add(1)
 				c.col.GetCounter(floatBlocksSizeCounter).Add(int64(cur.entry.Size))
@@ -155,6 +157,7 @@ LOOP:
 			if err != nil {
 				return nil, err
 			}
+			c.checkQuarantined(cur)
 			if c.col != nil {
 				c.col.GetCounter(floatBlocksDecodedCounter).Add(1)
 				c.col.GetCounter(floatBlocksSizeCounter).Add(int64(cur.entry.Size))
@@ -205,6 +208,7 @@ LOOP:
 	if err != nil {
 		return nil, err
 	}
+	c.checkQuarantined(first)
 	if c.col != nil {
 		c.col.GetCounter(integerBlocksDecodedCounter).Add(1)
 		c.col.GetCounter(integerBlocksSizeCounter).Add(int64(first.entry.Size))
@@ -278,6 +282,7 @@ LOOP:
 			if err != nil {
 				return nil, err
 			}
+			c.checkQuarantined(cur)
 			if c.col != nil {
 				c.col.GetCounter(integerBlocksDecodedCounter).Add(1)
 				c.col.GetCounter(integerBlocksSizeCounter).Add(int64(cur.entry.Size))
@@ -341,6 +346,7 @@ LOOP:
 			if err != nil {
 				return nil, err
 			}
+			c.checkQuarantined(cur)
 			if c.col != nil {
 				c.col.GetCounter(integerBlocksDecodedCounter).Add(1)
 				c.col.GetCounter(integerBlocksSizeCounter).Add(int64(cur.entry.Size))
@@ -391,6 +397,7 @@ LOOP:
 	if err != nil {
 		return nil, err
 	}
+	c.checkQuarantined(first)
 	if c.col != nil {
 		c.col.GetCounter(unsignedBlocksDecodedCounter).Add(1)
 		c.col.GetCounter(unsignedBlocksSizeCounter).Add(int64(first.entry.Size))
@@ -464,6 +471,7 @@ LOOP:
 			if err != nil {
 				return nil, err
 			}
+			c.checkQuarantined(cur)
 			if c.col != nil {
 				c.col.GetCounter(unsignedBlocksDecodedCounter).Add(1)
 				c.col.GetCounter(unsignedBlocksSizeCounter).Add(int64(cur.entry.Size))
@@ -527,6 +535,7 @@ LOOP:
 			if err != nil {
 				return nil, err
 			}
+			c.checkQuarantined(cur)
 			if c.col != nil {
 				c.col.GetCounter(unsignedBlocksDecodedCounter).Add(1)
 				c.col.GetCounter(unsignedBlocksSizeCounter).Add(int64(cur.entry.Size))
@@ -577,6 +586,7 @@ LOOP:
 	if err != nil {
 		return nil, err
 	}
+	c.checkQuarantined(first)
 	if c.col != nil {
 		c.col.GetCounter(stringBlocksDecodedCounter).Add(1)
 		c.col.GetCounter(stringBlocksSizeCounter).Add(int64(first.entry.Size))
@@ -650,6 +660,7 @@ LOOP:
 			if err != nil {
 				return nil, err
 			}
+			c.checkQuarantined(cur)
 			if c.col != nil {
 				c.col.GetCounter(stringBlocksDecodedCounter).Add(1)
 				c.col.GetCounter(stringBlocksSizeCounter).Add(int64(cur.entry.Size))
@@ -713,6 +724,7 @@ LOOP:
 			if err != nil {
 				return nil, err
 			}
+			c.checkQuarantined(cur)
 			if c.col != nil {
 				c.col.GetCounter(stringBlocksDecodedCounter).Add(1)
 				c.col.GetCounter(stringBlocksSizeCounter).Add(int64(cur.entry.Size))
@@ -763,6 +775,7 @@ LOOP:
 	if err != nil {
 		return nil, err
 	}
+	c.checkQuarantined(first)
 	if c.col != nil {
 		c.col.GetCounter(booleanBlocksDecodedCounter).Add(1)
 		c.col.GetCounter(booleanBlocksSizeCounter).Add(int64(first.entry.Size))
@@ -836,6 +849,7 @@ LOOP:
 			if err != nil {
 				return nil, err
 			}
+			c.checkQuarantined(cur)
 			if c.col != nil {
 				c.col.GetCounter(booleanBlocksDecodedCounter).Add(1)
 				c.col.GetCounter(booleanBlocksSizeCounter).Add(int64(cur.entry.Size))
@@ -899,6 +913,7 @@ LOOP:
 			if err != nil {
 				return nil, err
 			}
+			c.checkQuarantined(cur)
 			if c.col != nil {
 				c.col.GetCounter(booleanBlocksDecodedCounter).Add(1)
 				c.col.GetCounter(booleanBlocksSizeCounter).Add(int64(cur.entry.Size))
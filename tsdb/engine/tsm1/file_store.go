@@ -60,6 +60,10 @@ type TSMFile interface {
 	Entries(key []byte) []IndexEntry
 	ReadEntries(key []byte, entries *[]IndexEntry) []IndexEntry
 
+	// BlockSummary returns the summary of the block identified by entry, if
+	// the file has one.
+	BlockSummary(entry *IndexEntry) (BlockSummary, bool)
+
 	// Returns true if the TSMFile may contain a value with the specified
 	// key and time.
 	ContainsValue(key []byte, t int64) bool
@@ -149,8 +153,10 @@ type TSMFile interface {
 
 // Statistics gathered by the FileStore.
 const (
-	statFileStoreBytes = "diskBytes"
-	statFileStoreCount = "numFiles"
+	statFileStoreBytes             = "diskBytes"
+	statFileStoreCount             = "numFiles"
+	statFileStoreReads             = "reads"
+	statFileStoreQuarantinedBlocks = "quarantinedBlocks"
 )
 
 var (
@@ -164,6 +170,7 @@ var (
 	stringBlocksSizeCounter      = metrics.MustRegisterCounter("string_blocks_size_bytes", metrics.WithGroup(tsmGroup))
 	booleanBlocksDecodedCounter  = metrics.MustRegisterCounter("boolean_blocks_decoded", metrics.WithGroup(tsmGroup))
 	booleanBlocksSizeCounter     = metrics.MustRegisterCounter("boolean_blocks_size_bytes", metrics.WithGroup(tsmGroup))
+	blocksSummarizedCounter      = metrics.MustRegisterCounter("blocks_summarized", metrics.WithGroup(tsmGroup))
 )
 
 // FileStore is an abstraction around multiple TSM files.
@@ -174,6 +181,9 @@ type FileStore struct {
 	// Most recently known file stats. If nil then stats will need to be
 	// recalculated
 	lastFileStats []FileStat
+	// Min time of the newest TSM file, valid until the files change.
+	lastNewestMinTime      int64
+	lastNewestMinTimeValid bool
 
 	currentGeneration int
 	dir               string
@@ -194,6 +204,10 @@ type FileStore struct {
 	parseFileName ParseFileNameFunc
 
 	obs tsdb.FileStoreObserver
+
+	// zstdDictID is the ID of the registered zstd dictionary of the shard,
+	// or 0 if it has none.
+	zstdDictID uint32
 }
 
 // FileStat holds information about a TSM file on disk.
@@ -204,6 +218,11 @@ type FileStat struct {
 	LastModified     int64
 	MinTime, MaxTime int64
 	MinKey, MaxKey   []byte
+
+	// KeyCount is the number of keys in the file that are not fully deleted
+	// and TombstoneCount the number of tombstones applied to the file.
+	KeyCount       int
+	TombstoneCount int
 }
 
 // OverlapsTimeRange returns true if the time range of the file intersect min and max.
@@ -277,6 +296,7 @@ func (f *FileStore) WithLogger(log *zap.Logger) {
 type FileStoreStatistics struct {
 	DiskBytes int64
 	FileCount int64
+	Reads     int64
 }
 
 // Statistics returns statistics for periodic monitoring.
@@ -285,12 +305,44 @@ func (f *FileStore) Statistics(tags map[string]string) []models.Statistic {
 		Name: "tsm1_filestore",
 		Tags: tags,
 		Values: map[string]interface{}{
-			statFileStoreBytes: atomic.LoadInt64(&f.stats.DiskBytes),
-			statFileStoreCount: atomic.LoadInt64(&f.stats.FileCount),
+			statFileStoreBytes:             atomic.LoadInt64(&f.stats.DiskBytes),
+			statFileStoreCount:             atomic.LoadInt64(&f.stats.FileCount),
+			statFileStoreReads:             atomic.LoadInt64(&f.stats.Reads),
+			statFileStoreQuarantinedBlocks: int64(len(f.Quarantined())),
 		},
 	}}
 }
 
+// Reads returns the number of times keys have been read from the FileStore.
+func (f *FileStore) Reads() int64 {
+	return atomic.LoadInt64(&f.stats.Reads)
+}
+
+// Quarantined returns the corrupt blocks found when reading the TSM files.
+func (f *FileStore) Quarantined() []tsdb.CorruptBlock {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var blocks []tsdb.CorruptBlock
+	for _, file := range f.files {
+		if r, ok := file.(*TSMReader); ok {
+			blocks = append(blocks, r.Quarantined()...)
+		}
+	}
+	return blocks
+}
+
+// quarantined logs a corrupt block found when reading a TSM file.
+func (f *FileStore) quarantined(b tsdb.CorruptBlock) {
+	f.logger.Warn("Quarantined corrupt block",
+		zap.String("path", b.Path),
+		zap.Int64("offset", b.Offset),
+		zap.ByteString("series", b.Series),
+		zap.ByteString("field", b.Field),
+		zap.Int64("min_time", b.MinTime),
+		zap.Int64("max_time", b.MaxTime))
+}
+
 // Count returns the number of TSM files currently loaded.
 func (f *FileStore) Count() int {
 	f.mu.RLock()
@@ -424,6 +476,7 @@ func (f *FileStore) Apply(fn func(r TSMFile) error) error {
 	f.mu.Lock()
 	f.lastModified = time.Now().UTC()
 	f.lastFileStats = nil
+	f.lastNewestMinTimeValid = false
 	f.mu.Unlock()
 
 	return applyErr
@@ -460,6 +513,7 @@ func (f *FileStore) DeleteRange(keys [][]byte, min, max int64) error {
 	f.mu.Lock()
 	f.lastModified = time.Now().UTC()
 	f.lastFileStats = nil
+	f.lastNewestMinTimeValid = false
 	f.mu.Unlock()
 	return nil
 }
@@ -478,6 +532,11 @@ func (f *FileStore) Open() error {
 		return errors.New("cannot open FileStore without an OpenLimiter (is EngineOptions.OpenLimiter set?)")
 	}
 
+	// Register the zstd dictionary of the shard before any block is decoded.
+	if err := f.loadZstdDict(); err != nil {
+		return err
+	}
+
 	// find the current max ID for temp directories
 	tmpfiles, err := ioutil.ReadDir(f.dir)
 	if err != nil {
@@ -533,7 +592,7 @@ func (f *FileStore) Open() error {
 			defer f.openLimiter.Release()
 
 			start := time.Now()
-			df, err := NewTSMReader(file, WithMadviseWillNeed(f.tsmMMAPWillNeed))
+			df, err := NewTSMReader(file, WithMadviseWillNeed(f.tsmMMAPWillNeed), WithQuarantineFunc(f.quarantined))
 			f.logger.Info("Opened file",
 				zap.String("path", file.Name()),
 				zap.Int("id", idx),
@@ -583,6 +642,7 @@ func (f *FileStore) Open() error {
 	close(readerC)
 
 	sort.Sort(tsmReaders(f.files))
+	f.lastNewestMinTimeValid = false
 	atomic.StoreInt64(&f.stats.FileCount, int64(len(f.files)))
 	return nil
 }
@@ -595,9 +655,13 @@ func (f *FileStore) Close() error {
 	files := f.files
 
 	f.lastFileStats = nil
+	f.lastNewestMinTimeValid = false
 	f.files = nil
 	atomic.StoreInt64(&f.stats.FileCount, 0)
 
+	UnloadZstdDict(f.zstdDictID)
+	f.zstdDictID = 0
+
 	// Let other methods access this closed object while we do the actual closing.
 	f.mu.Unlock()
 
@@ -611,6 +675,32 @@ func (f *FileStore) Close() error {
 	return nil
 }
 
+// loadZstdDict registers the zstd dictionary of the shard, unless it is
+// already registered.  The dictionary stays registered until the FileStore
+// is closed.  f.mu must be held.
+func (f *FileStore) loadZstdDict() error {
+	if f.zstdDictID != 0 {
+		return nil
+	}
+	id, err := LoadZstdDict(f.dir)
+	if err != nil {
+		return err
+	}
+	f.zstdDictID = id
+	return nil
+}
+
+// reloadZstdDict registers the zstd dictionary of the shard if the shard got
+// one since the FileStore was opened, as when it is restored from a backup.
+func (f *FileStore) reloadZstdDict() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.dir == "" {
+		return nil
+	}
+	return f.loadZstdDict()
+}
+
 func (f *FileStore) DiskSizeBytes() int64 {
 	return atomic.LoadInt64(&f.stats.DiskBytes)
 }
@@ -618,6 +708,8 @@ func (f *FileStore) DiskSizeBytes() int64 {
 // Read returns the slice of values for the given key and the given timestamp,
 // if any file matches those constraints.
 func (f *FileStore) Read(key []byte, t int64) ([]Value, error) {
+	atomic.AddInt64(&f.stats.Reads, 1)
+
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 
@@ -663,11 +755,28 @@ func (f *FileStore) TSMReader(path string) *TSMReader {
 
 // KeyCursor returns a KeyCursor for key and t across the files in the FileStore.
 func (f *FileStore) KeyCursor(ctx context.Context, key []byte, t int64, ascending bool) *KeyCursor {
+	atomic.AddInt64(&f.stats.Reads, 1)
+
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 	return newKeyCursor(ctx, f, key, t, ascending)
 }
 
+// SummaryKeyCursor returns a KeyCursor for key and t across the files in the
+// FileStore that leaves out the blocks answered by their summaries, along with
+// those summaries in the order of the cursor.  A block is answered by its summary
+// if no other block or tombstone overlaps it and fn returns true for its time range.
+func (f *FileStore) SummaryKeyCursor(ctx context.Context, key []byte, t int64, ascending bool, fn func(min, max int64) bool) (*KeyCursor, []SummarizedBlock) {
+	atomic.AddInt64(&f.stats.Reads, 1)
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	c := newKeyCursor(ctx, f, key, t, ascending)
+	blocks := c.takeSummaries(fn)
+	c.seek(t)
+	return c, blocks
+}
+
 // Stats returns the stats of the underlying files, preferring the cached version if it is still valid.
 func (f *FileStore) Stats() []FileStat {
 	f.mu.RLock()
@@ -698,6 +807,34 @@ func (f *FileStore) Stats() []FileStat {
 	return f.lastFileStats
 }
 
+// NewestMinTime returns the minimum time of the newest TSM file, the first file
+// of the newest generation, or math.MinInt64 if there are no TSM files.
+func (f *FileStore) NewestMinTime() int64 {
+	f.mu.RLock()
+	if f.lastNewestMinTimeValid {
+		defer f.mu.RUnlock()
+		return f.lastNewestMinTime
+	}
+	f.mu.RUnlock()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.lastNewestMinTimeValid {
+		return f.lastNewestMinTime
+	}
+
+	newest, minTime := -1, int64(math.MinInt64)
+	for _, fd := range f.files {
+		if generation, _, err := f.parseFileName(fd.Path()); err == nil && generation > newest {
+			newest = generation
+			minTime, _ = fd.TimeRange()
+		}
+	}
+	f.lastNewestMinTime, f.lastNewestMinTimeValid = minTime, true
+	return minTime
+}
+
 // ReplaceWithCallback replaces oldFiles with newFiles and calls updatedFn with the files to be added the FileStore.
 func (f *FileStore) ReplaceWithCallback(oldFiles, newFiles []string, updatedFn func(r []TSMFile)) error {
 	return f.replace(oldFiles, newFiles, updatedFn)
@@ -761,7 +898,7 @@ func (f *FileStore) replace(oldFiles, newFiles []string, updatedFn func(r []TSMF
 			}
 		}
 
-		tsm, err := NewTSMReader(fd, WithMadviseWillNeed(f.tsmMMAPWillNeed))
+		tsm, err := NewTSMReader(fd, WithMadviseWillNeed(f.tsmMMAPWillNeed), WithQuarantineFunc(f.quarantined))
 		if err != nil {
 			if newName != oldName {
 				if err1 := os.Rename(newName, oldName); err1 != nil {
@@ -876,6 +1013,7 @@ func (f *FileStore) replace(oldFiles, newFiles []string, updatedFn func(r []TSMF
 	f.lastModified = maxTime.UTC()
 
 	f.lastFileStats = nil
+	f.lastNewestMinTimeValid = false
 	f.files = active
 	sort.Sort(tsmReaders(f.files))
 	atomic.StoreInt64(&f.stats.FileCount, int64(len(f.files)))
@@ -1080,6 +1218,11 @@ func (f *FileStore) CreateSnapshot() (string, error) {
 		}
 	}
 
+	// Blocks compressed with the zstd dictionary of the shard cannot be read without it.
+	if err := os.Link(filepath.Join(f.dir, ZstdDictFile), filepath.Join(tmpPath, ZstdDictFile)); err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("error creating zstd dictionary hard link: %q", err)
+	}
+
 	return tmpPath, nil
 }
 
@@ -1139,6 +1282,10 @@ type KeyCursor struct {
 	ctx context.Context
 	col *metrics.Group
 
+	// warnings returns the corrupt blocks skipped by the cursor to the
+	// statement reading it.
+	warnings *query.Warnings
+
 	// pos is the index within seeks.  Based on ascending, it will increment or
 	// decrement through the size of seeks slice.
 	pos       int
@@ -1198,6 +1345,7 @@ func newKeyCursor(ctx context.Context, fs *FileStore, key []byte, t int64, ascen
 		seeks:     fs.locations(key, t, ascending),
 		ctx:       ctx,
 		col:       metrics.GroupFromContext(ctx),
+		warnings:  query.WarningsFromContext(ctx),
 		ascending: ascending,
 	}
 
@@ -1216,6 +1364,87 @@ func newKeyCursor(ctx context.Context, fs *FileStore, key []byte, t int64, ascen
 	return c
 }
 
+// takeSummaries removes the blocks answered by their summaries from the cursor
+// and returns the summaries.  The cursor must be seeked again afterwards.
+func (c *KeyCursor) takeSummaries(fn func(min, max int64) bool) []SummarizedBlock {
+	// Order the blocks by min time so a block overlaps another block if one
+	// of the blocks before it ends after it starts, or the next block starts
+	// before it ends.
+	order := make([]int, len(c.seeks))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return c.seeks[order[i]].entry.MinTime < c.seeks[order[j]].entry.MinTime
+	})
+
+	overlaps := make([]bool, len(c.seeks))
+	maxTime := int64(math.MinInt64)
+	for i, j := range order {
+		e := c.seeks[j].entry
+		if i > 0 && maxTime >= e.MinTime {
+			overlaps[j] = true
+		}
+		if i < len(order)-1 && c.seeks[order[i+1]].entry.MinTime <= e.MaxTime {
+			overlaps[j] = true
+		}
+		if e.MaxTime > maxTime {
+			maxTime = e.MaxTime
+		}
+	}
+
+	var blocks []SummarizedBlock
+	seeks := c.seeks[:0]
+LOOP:
+	for i, l := range c.seeks {
+		if overlaps[i] || !fn(l.entry.MinTime, l.entry.MaxTime) {
+			seeks = append(seeks, l)
+			continue
+		}
+		for _, t := range l.r.TombstoneRange(c.key) {
+			if t.Overlaps(l.entry.MinTime, l.entry.MaxTime) {
+				seeks = append(seeks, l)
+				continue LOOP
+			}
+		}
+
+		summary, ok := l.r.BlockSummary(&l.entry)
+		if !ok {
+			seeks = append(seeks, l)
+			continue
+		}
+		blocks = append(blocks, SummarizedBlock{
+			MinTime:      l.entry.MinTime,
+			MaxTime:      l.entry.MaxTime,
+			BlockSummary: summary,
+		})
+		l.r.Unref()
+	}
+	c.seeks = seeks
+	return blocks
+}
+
+// checkQuarantined warns the statement reading the cursor that the points of
+// the block at l are missing from its results if the block is corrupt.
+func (c *KeyCursor) checkQuarantined(l *location) {
+	if c.warnings == nil {
+		return
+	}
+	r, ok := l.r.(*TSMReader)
+	if !ok {
+		return
+	}
+	b, ok := r.QuarantinedBlock(&l.entry)
+	if !ok {
+		return
+	}
+
+	c.warnings.Add(fmt.Sprintf("skipped corrupt block of series %q field %q in %s: points between %s and %s are missing, run REPAIR SHARD to remove the block",
+		b.Series, b.Field, b.Path,
+		time.Unix(0, b.MinTime).UTC().Format(time.RFC3339Nano),
+		time.Unix(0, b.MaxTime).UTC().Format(time.RFC3339Nano)))
+}
+
 // Close removes all references on the cursor.
 func (c *KeyCursor) Close() {
 	// Remove all of our in-use references since we're done
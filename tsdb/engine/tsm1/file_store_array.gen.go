@@ -22,6 +22,7 @@ LOOP:
 	if err != nil {
 		return nil, err
 	}
+	c.checkQuarantined(first)
 	if c.col != nil {
 		c.col.GetCounter(floatBlocksDecodedCounter).Add(1)
 		c.col.GetCounter(floatBlocksSizeCounter).Add(int64(first.entry.Size))
@@ -95,6 +96,7 @@ LOOP:
 			if err != nil {
 				return nil, err
 			}
+			c.checkQuarantined(cur)
 			if c.col != nil {
 				c.col.GetCounter(floatBlocksDecodedCounter).Add(1)
 				c.col.GetCounter(floatBlocksSizeCounter).Add(int64(cur.entry.Size))
@@ -157,6 +159,7 @@ LOOP:
 			if err != nil {
 				return nil, err
 			}
+			c.checkQuarantined(cur)
 			if c.col != nil {
 				c.col.GetCounter(floatBlocksDecodedCounter).Add(1)
 				c.col.GetCounter(floatBlocksSizeCounter).Add(int64(cur.entry.Size))
@@ -207,6 +210,7 @@ LOOP:
 	if err != nil {
 		return nil, err
 	}
+	c.checkQuarantined(first)
 	if c.col != nil {
 		c.col.GetCounter(integerBlocksDecodedCounter).Add(1)
 		c.col.GetCounter(integerBlocksSizeCounter).Add(int64(first.entry.Size))
@@ -279,6 +283,7 @@ LOOP:
 			if err != nil {
 				return nil, err
 			}
+			c.checkQuarantined(cur)
 			if c.col != nil {
 				c.col.GetCounter(integerBlocksDecodedCounter).Add(1)
 				c.col.GetCounter(integerBlocksSizeCounter).Add(int64(cur.entry.Size))
@@ -341,6 +346,7 @@ LOOP:
 			if err != nil {
 				return nil, err
 			}
+			c.checkQuarantined(cur)
 			if c.col != nil {
 				c.col.GetCounter(integerBlocksDecodedCounter).Add(1)
 				c.col.GetCounter(integerBlocksSizeCounter).Add(int64(cur.entry.Size))
@@ -391,6 +397,7 @@ LOOP:
 	if err != nil {
 		return nil, err
 	}
+	c.checkQuarantined(first)
 	if c.col != nil {
 		c.col.GetCounter(unsignedBlocksDecodedCounter).Add(1)
 		c.col.GetCounter(unsignedBlocksSizeCounter).Add(int64(first.entry.Size))
@@ -463,6 +470,7 @@ LOOP:
 			if err != nil {
 				return nil, err
 			}
+			c.checkQuarantined(cur)
 			if c.col != nil {
 				c.col.GetCounter(unsignedBlocksDecodedCounter).Add(1)
 				c.col.GetCounter(unsignedBlocksSizeCounter).Add(int64(cur.entry.Size))
@@ -525,6 +533,7 @@ LOOP:
 			if err != nil {
 				return nil, err
 			}
+			c.checkQuarantined(cur)
 			if c.col != nil {
 				c.col.GetCounter(unsignedBlocksDecodedCounter).Add(1)
 				c.col.GetCounter(unsignedBlocksSizeCounter).Add(int64(cur.entry.Size))
@@ -575,6 +584,7 @@ LOOP:
 	if err != nil {
 		return nil, err
 	}
+	c.checkQuarantined(first)
 	if c.col != nil {
 		c.col.GetCounter(stringBlocksDecodedCounter).Add(1)
 		c.col.GetCounter(stringBlocksSizeCounter).Add(int64(first.entry.Size))
@@ -647,6 +657,7 @@ LOOP:
 			if err != nil {
 				return nil, err
 			}
+			c.checkQuarantined(cur)
 			if c.col != nil {
 				c.col.GetCounter(stringBlocksDecodedCounter).Add(1)
 				c.col.GetCounter(stringBlocksSizeCounter).Add(int64(cur.entry.Size))
@@ -709,6 +720,7 @@ LOOP:
 			if err != nil {
 				return nil, err
 			}
+			c.checkQuarantined(cur)
 			if c.col != nil {
 				c.col.GetCounter(stringBlocksDecodedCounter).Add(1)
 				c.col.GetCounter(stringBlocksSizeCounter).Add(int64(cur.entry.Size))
@@ -759,6 +771,7 @@ LOOP:
 	if err != nil {
 		return nil, err
 	}
+	c.checkQuarantined(first)
 	if c.col != nil {
 		c.col.GetCounter(booleanBlocksDecodedCounter).Add(1)
 		c.col.GetCounter(booleanBlocksSizeCounter).Add(int64(first.entry.Size))
@@ -831,6 +844,7 @@ LOOP:
 			if err != nil {
 				return nil, err
 			}
+			c.checkQuarantined(cur)
 			if c.col != nil {
 				c.col.GetCounter(booleanBlocksDecodedCounter).Add(1)
 				c.col.GetCounter(booleanBlocksSizeCounter).Add(int64(cur.entry.Size))
@@ -893,6 +907,7 @@ LOOP:
 			if err != nil {
 				return nil, err
 			}
+			c.checkQuarantined(cur)
 			if c.col != nil {
 				c.col.GetCounter(booleanBlocksDecodedCounter).Add(1)
 				c.col.GetCounter(booleanBlocksSizeCounter).Add(int64(cur.entry.Size))
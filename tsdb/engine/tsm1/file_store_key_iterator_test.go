@@ -156,6 +156,9 @@ func (*mockTSMFile) Entries(key []byte) []IndexEntry                           {
 func (*mockTSMFile) ReadEntries(key []byte, entries *[]IndexEntry) []IndexEntry {
 	panic("implement me")
 }
+func (*mockTSMFile) BlockSummary(entry *IndexEntry) (BlockSummary, bool) {
+	panic("implement me")
+}
 func (*mockTSMFile) ContainsValue(key []byte, t int64) bool          { panic("implement me") }
 func (*mockTSMFile) Contains(key []byte) bool                        { panic("implement me") }
 func (*mockTSMFile) OverlapsTimeRange(min, max int64) bool           { panic("implement me") }
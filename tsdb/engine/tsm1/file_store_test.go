@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -13,6 +14,7 @@ import (
 	"time"
 
 	"github.com/influxdata/influxdb/logger"
+	"github.com/influxdata/influxdb/query"
 	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
 )
 
@@ -92,6 +94,111 @@ func TestFileStore_SeekToAsc_FromStart(t *testing.T) {
 	}
 }
 
+// Ensures that reads skip a block whose checksum does not match its content
+// and that the block is quarantined.
+func TestFileStore_SeekToAsc_CorruptBlock(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+	fs := tsm1.NewFileStore(dir)
+
+	// Setup 3 files
+	data := []keyValues{
+		keyValues{"cpu", []tsm1.Value{tsm1.NewValue(0, 1.0)}},
+		keyValues{"cpu", []tsm1.Value{tsm1.NewValue(1, 2.0)}},
+		keyValues{"cpu", []tsm1.Value{tsm1.NewValue(2, 3.0)}},
+	}
+
+	files, err := newFiles(dir, data...)
+	if err != nil {
+		t.Fatalf("unexpected error creating files: %v", err)
+	}
+
+	// The only block of the file follows the header and its checksum.
+	MustCorruptFile(files[1], 5+4+1)
+
+	fs.Replace(nil, files)
+
+	buf := make([]tsm1.FloatValue, 1000)
+	c := fs.KeyCursor(context.Background(), []byte("cpu"), 0, true)
+	var got []float64
+	for {
+		values, err := c.ReadFloatBlock(&buf)
+		if err != nil {
+			t.Fatalf("unexpected error reading values: %v", err)
+		} else if len(values) == 0 {
+			break
+		}
+		for _, v := range values {
+			got = append(got, v.Value().(float64))
+		}
+		c.Next()
+	}
+	c.Close()
+
+	if exp := []float64{1.0, 3.0}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected values: got %v, exp %v", got, exp)
+	}
+
+	blocks := fs.Quarantined()
+	if len(blocks) != 1 {
+		t.Fatalf("unexpected quarantined blocks: %v", blocks)
+	} else if blocks[0].Path != files[1] || string(blocks[0].Series) != "cpu" || blocks[0].MinTime != 1 || blocks[0].MaxTime != 1 {
+		t.Fatalf("unexpected quarantined block: %+v", blocks[0])
+	}
+}
+
+// Ensures that a corrupt block is not answered by its summary and that
+// the statement reading it is warned about the missing points.
+func TestFileStore_SummaryKeyCursor_CorruptBlock(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+	fs := tsm1.NewFileStore(dir)
+
+	// Setup 3 files
+	data := []keyValues{
+		keyValues{"cpu", []tsm1.Value{tsm1.NewValue(0, 1.0)}},
+		keyValues{"cpu", []tsm1.Value{tsm1.NewValue(1, 2.0)}},
+		keyValues{"cpu", []tsm1.Value{tsm1.NewValue(2, 3.0)}},
+	}
+
+	files, err := newFiles(dir, data...)
+	if err != nil {
+		t.Fatalf("unexpected error creating files: %v", err)
+	}
+
+	// The only block of the file follows the header and its checksum.
+	MustCorruptFile(files[1], 5+4+1)
+
+	fs.Replace(nil, files)
+
+	results := make(chan *query.Result, 1)
+	ctx := &query.ExecutionContext{Context: context.Background(), Results: results}
+
+	c, blocks := fs.SummaryKeyCursor(ctx, []byte("cpu"), 0, true, func(min, max int64) bool { return true })
+	if len(blocks) != 2 || blocks[0].MinTime != 0 || blocks[1].MinTime != 2 {
+		t.Fatalf("unexpected summarized blocks: %v", blocks)
+	}
+	if got := len(fs.Quarantined()); got != 1 {
+		t.Fatalf("unexpected quarantined block count: got %d, exp 1", got)
+	}
+
+	buf := make([]tsm1.FloatValue, 1000)
+	if values, err := c.ReadFloatBlock(&buf); err != nil {
+		t.Fatalf("unexpected error reading values: %v", err)
+	} else if len(values) != 0 {
+		t.Fatalf("unexpected values read from corrupt block: %v", values)
+	}
+	c.Close()
+
+	if err := ctx.Send(&query.Result{}); err != nil {
+		t.Fatal(err)
+	}
+	msgs := (<-results).Messages
+	if len(msgs) != 1 || !strings.Contains(msgs[0].Text, "skipped corrupt block of series \"cpu\"") || !strings.Contains(msgs[0].Text, files[1]) {
+		t.Fatalf("unexpected messages: %v", msgs)
+	}
+}
+
 func TestFileStore_SeekToAsc_Duplicate(t *testing.T) {
 	dir := MustTempDir()
 	defer os.RemoveAll(dir)
@@ -2695,6 +2802,115 @@ func TestFileStore_Stats(t *testing.T) {
 	}
 }
 
+// Ensure the min time of the newest file is cached until the files change.
+func TestFileStore_NewestMinTime(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	data := []keyValues{
+		keyValues{"cpu", []tsm1.Value{tsm1.NewValue(5, 1.0)}},
+		keyValues{"cpu", []tsm1.Value{tsm1.NewValue(3, 2.0)}},
+		keyValues{"mem", []tsm1.Value{tsm1.NewValue(2, 1.0), tsm1.NewValue(8, 1.0)}},
+	}
+
+	files, err := newFileDir(dir, data...)
+	if err != nil {
+		fatal(t, "creating test files", err)
+	}
+
+	fs := tsm1.NewFileStore(dir)
+	if err := fs.Open(); err != nil {
+		fatal(t, "opening file store", err)
+	}
+	defer fs.Close()
+
+	if got, exp := fs.NewestMinTime(), int64(2); got != exp {
+		t.Fatalf("min time mismatch: got %v, exp %v", got, exp)
+	}
+
+	// Removing the newest file should invalidate the cache.
+	if err := fs.Replace(files[2:], nil); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	if got, exp := fs.NewestMinTime(), int64(3); got != exp {
+		t.Fatalf("min time mismatch: got %v, exp %v", got, exp)
+	}
+
+	newFile := MustWriteTSM(dir, 4, map[string][]tsm1.Value{
+		"mem": []tsm1.Value{tsm1.NewValue(7, 1.0)},
+	})
+	replacement := fmt.Sprintf("%s.%s", newFile, tsm1.TmpTSMFileExtension)
+	if err := os.Rename(newFile, replacement); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if err := fs.Replace(nil, []string{replacement}); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	if got, exp := fs.NewestMinTime(), int64(7); got != exp {
+		t.Fatalf("min time mismatch: got %v, exp %v", got, exp)
+	}
+
+	if err := fs.Replace([]string{files[0], files[1], newFile}, nil); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	if got, exp := fs.NewestMinTime(), int64(math.MinInt64); got != exp {
+		t.Fatalf("min time mismatch: got %v, exp %v", got, exp)
+	}
+}
+
+func TestFileStore_Stats_Tombstones(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	data := []keyValues{
+		keyValues{"cpu", []tsm1.Value{tsm1.NewValue(0, 1.0), tsm1.NewValue(1, 2.0)}},
+	}
+	if _, err := newFileDir(dir, data...); err != nil {
+		fatal(t, "creating test files", err)
+	}
+	w, _ := MustTSMWriter(dir, 2)
+	for _, key := range []string{"disk", "mem"} {
+		if err := w.Write([]byte(key), []tsm1.Value{tsm1.NewValue(0, 1.0)}); err != nil {
+			t.Fatalf("unexpected error writing: %v", err)
+		}
+	}
+	if err := w.WriteIndex(); err != nil {
+		t.Fatalf("unexpected error writing index: %v", err)
+	}
+	w.Close()
+
+	fs := tsm1.NewFileStore(dir)
+	if err := fs.Open(); err != nil {
+		fatal(t, "opening file store", err)
+	}
+	defer fs.Close()
+
+	if err := fs.DeleteRange([][]byte{[]byte("cpu")}, 0, 0); err != nil {
+		t.Fatalf("unexpected error deleting: %v", err)
+	}
+	if err := fs.Delete([][]byte{[]byte("mem")}); err != nil {
+		t.Fatalf("unexpected error deleting: %v", err)
+	}
+
+	stats := fs.Stats()
+	if got, exp := len(stats), 2; got != exp {
+		t.Fatalf("file count mismatch: got %v, exp %v", got, exp)
+	}
+	for i, exp := range []tsm1.FileStat{{KeyCount: 1, TombstoneCount: 1}, {KeyCount: 1, TombstoneCount: 1}} {
+		if got := stats[i]; got.KeyCount != exp.KeyCount || got.TombstoneCount != exp.TombstoneCount {
+			t.Fatalf("stat mismatch for %s: got %d keys and %d tombstones, exp %d and %d", got.Path, got.KeyCount, got.TombstoneCount, exp.KeyCount, exp.TombstoneCount)
+		}
+	}
+
+	buf := make([]tsm1.FloatValue, 1000)
+	c := fs.KeyCursor(context.Background(), []byte("cpu"), 0, true)
+	c.ReadFloatBlock(&buf)
+	c.Close()
+	if got, exp := fs.Reads(), int64(1); got != exp {
+		t.Fatalf("reads mismatch: got %v, exp %v", got, exp)
+	}
+}
+
 func TestFileStore_CreateSnapshot(t *testing.T) {
 	dir := MustTempDir()
 	defer os.RemoveAll(dir)
@@ -19,8 +19,13 @@ import (
 )
 
 // Note: an uncompressed format is not yet implemented.
-// floatCompressedGorilla is a compressed format using the gorilla paper encoding
-const floatCompressedGorilla = 1
+const (
+	// floatCompressedGorilla is a compressed format using the gorilla paper encoding
+	floatCompressedGorilla = 1
+
+	// floatCompressedZstd is a compressed format using zstd compression
+	floatCompressedZstd = 2
+)
 
 // uvnan is the constant returned from math.NaN().
 const uvnan = 0x7FF8000000000001
@@ -151,17 +156,32 @@ type FloatDecoder struct {
 	first    bool
 	finished bool
 
+	// zstd is true if b is zstd compressed, in which case the values are
+	// decoded up front into vals and i is the index of the next value.
+	zstd bool
+	buf  []byte
+	vals []float64
+	i    int
+
 	err error
 }
 
 // SetBytes initializes the decoder with b. Must call before calling Next().
 func (it *FloatDecoder) SetBytes(b []byte) error {
 	var v uint64
+	it.zstd = len(b) > 0 && b[0]>>4 == floatCompressedZstd
 	if len(b) == 0 {
 		v = uvnan
+	} else if it.zstd {
+		var err error
+		if it.buf, err = decodeZstd(b, it.buf); err != nil {
+			return fmt.Errorf("failed to decode float block: %v", err)
+		}
+		if it.vals, err = unshuffleFloats(it.vals, it.buf); err != nil {
+			return err
+		}
 	} else {
 		// first byte is the compression type.
-		// we currently just have gorilla compression.
 		it.br.Reset(b[1:])
 
 		var err error
@@ -178,6 +198,7 @@ func (it *FloatDecoder) SetBytes(b []byte) error {
 	it.b = b
 	it.first = true
 	it.finished = false
+	it.i = 0
 	it.err = nil
 
 	return nil
@@ -189,6 +210,16 @@ func (it *FloatDecoder) Next() bool {
 		return false
 	}
 
+	if it.zstd {
+		if it.i >= len(it.vals) {
+			it.finished = true
+			return false
+		}
+		it.val = math.Float64bits(it.vals[it.i])
+		it.i++
+		return true
+	}
+
 	if it.first {
 		it.first = false
 
@@ -0,0 +1,226 @@
+package tsm1
+
+import (
+	"bytes"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultHotReadRate is the default rate of reads per second above which a
+	// shard is considered hot.
+	DefaultHotReadRate = 10.0
+
+	// readHeatHalfLife is the time it takes for reads to count half as much
+	// towards the read heat of a shard.
+	readHeatHalfLife = 5 * time.Minute
+)
+
+// CompactionPriority holds the heuristics a shard is ranked by against the other
+// shards waiting to compact.
+type CompactionPriority struct {
+	// ReadHeat is the decaying average rate of reads per second.
+	ReadHeat float64
+
+	// OverlappingFiles is the number of TSM files whose keys and time range
+	// overlap those of another generation.
+	OverlappingFiles int
+
+	// TombstoneRatio is the share of the keys of the TSM files that have been
+	// deleted by tombstones.
+	TombstoneRatio float64
+
+	// Score ranks the shard against other shards.  Shards with a higher score
+	// are compacted first.
+	Score float64
+}
+
+// CompactionPrioritizer is implemented by compaction planners that rank their
+// shard against the other shards waiting to compact.
+type CompactionPrioritizer interface {
+	Priority() CompactionPriority
+}
+
+// PriorityPlanner implements CompactionPlanner by extending DefaultPlanner with
+// heuristics ranking its shard against other shards: how often the shard is
+// read, how many of its TSM files overlap and how many of its keys are deleted.
+// The groups of each plan are ordered by their share of deleted keys, and hot
+// shards optimize overlapping level 4 generations without waiting for enough
+// of them to accumulate.
+//
+// The heuristics are updated each time Plan is called.
+type PriorityPlanner struct {
+	*DefaultPlanner
+
+	// HotReadRate is the read heat above which a shard is hot.
+	HotReadRate float64
+
+	mu        sync.RWMutex
+	lastReads int64
+	lastCheck time.Time
+	priority  CompactionPriority
+}
+
+// NewPriorityPlanner returns a new instance of PriorityPlanner.
+func NewPriorityPlanner(fs fileStore, writeColdDuration time.Duration) *PriorityPlanner {
+	return &PriorityPlanner{
+		DefaultPlanner: NewDefaultPlanner(fs, writeColdDuration),
+		HotReadRate:    DefaultHotReadRate,
+	}
+}
+
+// Priority returns the heuristics computed by the last call to Plan.
+func (p *PriorityPlanner) Priority() CompactionPriority {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.priority
+}
+
+// PlanLevel returns a set of TSM files to rewrite for a specific level.
+func (p *PriorityPlanner) PlanLevel(level int) []CompactionGroup {
+	return p.order(p.DefaultPlanner.PlanLevel(level))
+}
+
+// Plan updates the heuristics of the shard and returns a set of TSM files to
+// rewrite for level 4 or higher.
+func (p *PriorityPlanner) Plan(lastWrite time.Time) []CompactionGroup {
+	p.update(time.Now())
+	return p.order(p.DefaultPlanner.Plan(lastWrite))
+}
+
+// PlanOptimize returns the groups of level 4 generations to optimize.  Groups
+// with overlapping generations are optimized regardless of their size if the
+// shard is hot.
+func (p *PriorityPlanner) PlanOptimize() []CompactionGroup {
+	hot := p.Priority().ReadHeat >= p.HotReadRate
+	return p.order(p.planOptimize(func(group tsmGenerations) bool {
+		return len(group) >= 4 || group.hasTombstones() || hot && group.overlappingFiles() > 0
+	}))
+}
+
+// update recomputes the heuristics of the shard at time now.
+func (p *PriorityPlanner) update(now time.Time) {
+	reads := p.FileStore.Reads()
+	stats := p.FileStore.Stats()
+	generations := p.findGenerations(false)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.lastCheck.IsZero() {
+		if elapsed := now.Sub(p.lastCheck); elapsed > 0 {
+			rate := float64(reads-p.lastReads) / elapsed.Seconds()
+			decay := math.Pow(0.5, elapsed.Seconds()/readHeatHalfLife.Seconds())
+			p.priority.ReadHeat = p.priority.ReadHeat*decay + rate*(1-decay)
+		}
+	}
+	p.lastReads, p.lastCheck = reads, now
+
+	p.priority.OverlappingFiles = generations.overlappingFiles()
+	p.priority.TombstoneRatio = tombstoneRatio(stats)
+
+	// A shard with nothing to gain from compacting has no priority however
+	// often it is read.
+	p.priority.Score = (float64(p.priority.OverlappingFiles) + p.priority.TombstoneRatio*float64(len(stats))) * (1 + p.priority.ReadHeat)
+}
+
+// order sorts groups by their share of deleted keys, highest first.
+func (p *PriorityPlanner) order(groups []CompactionGroup) []CompactionGroup {
+	if len(groups) <= 1 {
+		return groups
+	}
+
+	stats := make(map[string]FileStat)
+	for _, f := range p.FileStore.Stats() {
+		stats[f.Path] = f
+	}
+
+	ratios := make([]float64, len(groups))
+	for i, group := range groups {
+		files := make([]FileStat, 0, len(group))
+		for _, path := range group {
+			files = append(files, stats[path])
+		}
+		ratios[i] = tombstoneRatio(files)
+	}
+	sort.Stable(groupsByRatio{groups: groups, ratios: ratios})
+	return groups
+}
+
+// groupsByRatio sorts compaction groups by descending ratios.
+type groupsByRatio struct {
+	groups []CompactionGroup
+	ratios []float64
+}
+
+func (a groupsByRatio) Len() int           { return len(a.groups) }
+func (a groupsByRatio) Less(i, j int) bool { return a.ratios[i] > a.ratios[j] }
+func (a groupsByRatio) Swap(i, j int) {
+	a.groups[i], a.groups[j] = a.groups[j], a.groups[i]
+	a.ratios[i], a.ratios[j] = a.ratios[j], a.ratios[i]
+}
+
+// tombstoneRatio returns the share of the keys of files that have been deleted.
+// Keys deleted entirely are no longer counted by the files, so each tombstone
+// is counted as a key as well.
+func tombstoneRatio(files []FileStat) float64 {
+	var keys, tombstones int
+	for _, f := range files {
+		keys += f.KeyCount
+		tombstones += f.TombstoneCount
+	}
+	if tombstones == 0 {
+		return 0
+	}
+	return float64(tombstones) / float64(keys+tombstones)
+}
+
+// bounds returns the key and time range of the files in the generation.
+func (t *tsmGeneration) bounds() (minKey, maxKey []byte, minTime, maxTime int64) {
+	minTime, maxTime = math.MaxInt64, math.MinInt64
+	for _, f := range t.files {
+		if minKey == nil || bytes.Compare(f.MinKey, minKey) < 0 {
+			minKey = f.MinKey
+		}
+		if maxKey == nil || bytes.Compare(f.MaxKey, maxKey) > 0 {
+			maxKey = f.MaxKey
+		}
+		if f.MinTime < minTime {
+			minTime = f.MinTime
+		}
+		if f.MaxTime > maxTime {
+			maxTime = f.MaxTime
+		}
+	}
+	return minKey, maxKey, minTime, maxTime
+}
+
+// overlappingFiles returns the number of files in generations whose keys and
+// time range overlap those of another generation.
+func (a tsmGenerations) overlappingFiles() int {
+	type bounds struct {
+		minKey, maxKey   []byte
+		minTime, maxTime int64
+	}
+	b := make([]bounds, len(a))
+	for i, g := range a {
+		b[i].minKey, b[i].maxKey, b[i].minTime, b[i].maxTime = g.bounds()
+	}
+
+	var n int
+	for i, g := range a {
+		for j := range a {
+			if i == j {
+				continue
+			}
+			if b[i].minTime <= b[j].maxTime && b[i].maxTime >= b[j].minTime &&
+				bytes.Compare(b[i].minKey, b[j].maxKey) <= 0 && bytes.Compare(b[i].maxKey, b[j].minKey) >= 0 {
+				n += g.count()
+				break
+			}
+		}
+	}
+	return n
+}
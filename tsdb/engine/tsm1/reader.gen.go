@@ -15,6 +15,10 @@ func (t *TSMReader) ReadFloatBlockAt(entry *IndexEntry, vals *[]FloatValue) ([]F
 	t.mu.RLock()
 	v, err := t.accessor.readFloatBlock(entry, vals)
 	t.mu.RUnlock()
+	if err == errBlockChecksum {
+		t.quarantine(entry)
+		return nil, nil
+	}
 	return v, err
 }
 
@@ -23,6 +27,12 @@ func (t *TSMReader) ReadFloatArrayBlockAt(entry *IndexEntry, vals *tsdb.FloatArr
 	t.mu.RLock()
 	err := t.accessor.readFloatArrayBlock(entry, vals)
 	t.mu.RUnlock()
+	if err == errBlockChecksum {
+		t.quarantine(entry)
+		vals.Timestamps = vals.Timestamps[:0]
+		vals.Values = vals.Values[:0]
+		return nil
+	}
 	return err
 }
 
@@ -31,6 +41,10 @@ func (t *TSMReader) ReadIntegerBlockAt(entry *IndexEntry, vals *[]IntegerValue)
 	t.mu.RLock()
 	v, err := t.accessor.readIntegerBlock(entry, vals)
 	t.mu.RUnlock()
+	if err == errBlockChecksum {
+		t.quarantine(entry)
+		return nil, nil
+	}
 	return v, err
 }
 
@@ -39,6 +53,12 @@ func (t *TSMReader) ReadIntegerArrayBlockAt(entry *IndexEntry, vals *tsdb.Intege
 	t.mu.RLock()
 	err := t.accessor.readIntegerArrayBlock(entry, vals)
 	t.mu.RUnlock()
+	if err == errBlockChecksum {
+		t.quarantine(entry)
+		vals.Timestamps = vals.Timestamps[:0]
+		vals.Values = vals.Values[:0]
+		return nil
+	}
 	return err
 }
 
@@ -47,6 +67,10 @@ func (t *TSMReader) ReadUnsignedBlockAt(entry *IndexEntry, vals *[]UnsignedValue
 	t.mu.RLock()
 	v, err := t.accessor.readUnsignedBlock(entry, vals)
 	t.mu.RUnlock()
+	if err == errBlockChecksum {
+		t.quarantine(entry)
+		return nil, nil
+	}
 	return v, err
 }
 
@@ -55,6 +79,12 @@ func (t *TSMReader) ReadUnsignedArrayBlockAt(entry *IndexEntry, vals *tsdb.Unsig
 	t.mu.RLock()
 	err := t.accessor.readUnsignedArrayBlock(entry, vals)
 	t.mu.RUnlock()
+	if err == errBlockChecksum {
+		t.quarantine(entry)
+		vals.Timestamps = vals.Timestamps[:0]
+		vals.Values = vals.Values[:0]
+		return nil
+	}
 	return err
 }
 
@@ -63,6 +93,10 @@ func (t *TSMReader) ReadStringBlockAt(entry *IndexEntry, vals *[]StringValue) ([
 	t.mu.RLock()
 	v, err := t.accessor.readStringBlock(entry, vals)
 	t.mu.RUnlock()
+	if err == errBlockChecksum {
+		t.quarantine(entry)
+		return nil, nil
+	}
 	return v, err
 }
 
@@ -71,6 +105,12 @@ func (t *TSMReader) ReadStringArrayBlockAt(entry *IndexEntry, vals *tsdb.StringA
 	t.mu.RLock()
 	err := t.accessor.readStringArrayBlock(entry, vals)
 	t.mu.RUnlock()
+	if err == errBlockChecksum {
+		t.quarantine(entry)
+		vals.Timestamps = vals.Timestamps[:0]
+		vals.Values = vals.Values[:0]
+		return nil
+	}
 	return err
 }
 
@@ -79,6 +119,10 @@ func (t *TSMReader) ReadBooleanBlockAt(entry *IndexEntry, vals *[]BooleanValue)
 	t.mu.RLock()
 	v, err := t.accessor.readBooleanBlock(entry, vals)
 	t.mu.RUnlock()
+	if err == errBlockChecksum {
+		t.quarantine(entry)
+		return nil, nil
+	}
 	return v, err
 }
 
@@ -87,6 +131,12 @@ func (t *TSMReader) ReadBooleanArrayBlockAt(entry *IndexEntry, vals *tsdb.Boolea
 	t.mu.RLock()
 	err := t.accessor.readBooleanArrayBlock(entry, vals)
 	t.mu.RUnlock()
+	if err == errBlockChecksum {
+		t.quarantine(entry)
+		vals.Timestamps = vals.Timestamps[:0]
+		vals.Values = vals.Values[:0]
+		return nil
+	}
 	return err
 }
 
@@ -108,6 +158,8 @@ type blockAccessor interface {
 	readBooleanBlock(entry *IndexEntry, values *[]BooleanValue) ([]BooleanValue, error)
 	readBooleanArrayBlock(entry *IndexEntry, values *tsdb.BooleanArray) error
 	readBytes(entry *IndexEntry, buf []byte) (uint32, []byte, error)
+	summary(entry *IndexEntry) (BlockSummary, bool)
+	verify(entry *IndexEntry) error
 	rename(path string) error
 	path() string
 	close() error
@@ -118,12 +170,13 @@ func (m *mmapAccessor) readFloatBlock(entry *IndexEntry, values *[]FloatValue) (
 	m.incAccess()
 
 	m.mu.RLock()
-	if int64(len(m.b)) < entry.Offset+int64(entry.Size) {
+	b, err := m.block(entry)
+	if err != nil {
 		m.mu.RUnlock()
-		return nil, ErrTSMClosed
+		return nil, err
 	}
 
-	a, err := DecodeFloatBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+	a, err := DecodeFloatBlock(b, values)
 	m.mu.RUnlock()
 
 	if err != nil {
@@ -137,12 +190,13 @@ func (m *mmapAccessor) readFloatArrayBlock(entry *IndexEntry, values *tsdb.Float
 	m.incAccess()
 
 	m.mu.RLock()
-	if int64(len(m.b)) < entry.Offset+int64(entry.Size) {
+	b, err := m.block(entry)
+	if err != nil {
 		m.mu.RUnlock()
-		return ErrTSMClosed
+		return err
 	}
 
-	err := DecodeFloatArrayBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+	err = DecodeFloatArrayBlock(b, values)
 	m.mu.RUnlock()
 
 	return err
@@ -152,12 +206,13 @@ func (m *mmapAccessor) readIntegerBlock(entry *IndexEntry, values *[]IntegerValu
 	m.incAccess()
 
 	m.mu.RLock()
-	if int64(len(m.b)) < entry.Offset+int64(entry.Size) {
+	b, err := m.block(entry)
+	if err != nil {
 		m.mu.RUnlock()
-		return nil, ErrTSMClosed
+		return nil, err
 	}
 
-	a, err := DecodeIntegerBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+	a, err := DecodeIntegerBlock(b, values)
 	m.mu.RUnlock()
 
 	if err != nil {
@@ -171,12 +226,13 @@ func (m *mmapAccessor) readIntegerArrayBlock(entry *IndexEntry, values *tsdb.Int
 	m.incAccess()
 
 	m.mu.RLock()
-	if int64(len(m.b)) < entry.Offset+int64(entry.Size) {
+	b, err := m.block(entry)
+	if err != nil {
 		m.mu.RUnlock()
-		return ErrTSMClosed
+		return err
 	}
 
-	err := DecodeIntegerArrayBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+	err = DecodeIntegerArrayBlock(b, values)
 	m.mu.RUnlock()
 
 	return err
@@ -186,12 +242,13 @@ func (m *mmapAccessor) readUnsignedBlock(entry *IndexEntry, values *[]UnsignedVa
 	m.incAccess()
 
 	m.mu.RLock()
-	if int64(len(m.b)) < entry.Offset+int64(entry.Size) {
+	b, err := m.block(entry)
+	if err != nil {
 		m.mu.RUnlock()
-		return nil, ErrTSMClosed
+		return nil, err
 	}
 
-	a, err := DecodeUnsignedBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+	a, err := DecodeUnsignedBlock(b, values)
 	m.mu.RUnlock()
 
 	if err != nil {
@@ -205,12 +262,13 @@ func (m *mmapAccessor) readUnsignedArrayBlock(entry *IndexEntry, values *tsdb.Un
 	m.incAccess()
 
 	m.mu.RLock()
-	if int64(len(m.b)) < entry.Offset+int64(entry.Size) {
+	b, err := m.block(entry)
+	if err != nil {
 		m.mu.RUnlock()
-		return ErrTSMClosed
+		return err
 	}
 
-	err := DecodeUnsignedArrayBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+	err = DecodeUnsignedArrayBlock(b, values)
 	m.mu.RUnlock()
 
 	return err
@@ -220,12 +278,13 @@ func (m *mmapAccessor) readStringBlock(entry *IndexEntry, values *[]StringValue)
 	m.incAccess()
 
 	m.mu.RLock()
-	if int64(len(m.b)) < entry.Offset+int64(entry.Size) {
+	b, err := m.block(entry)
+	if err != nil {
 		m.mu.RUnlock()
-		return nil, ErrTSMClosed
+		return nil, err
 	}
 
-	a, err := DecodeStringBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+	a, err := DecodeStringBlock(b, values)
 	m.mu.RUnlock()
 
 	if err != nil {
@@ -239,12 +298,13 @@ func (m *mmapAccessor) readStringArrayBlock(entry *IndexEntry, values *tsdb.Stri
 	m.incAccess()
 
 	m.mu.RLock()
-	if int64(len(m.b)) < entry.Offset+int64(entry.Size) {
+	b, err := m.block(entry)
+	if err != nil {
 		m.mu.RUnlock()
-		return ErrTSMClosed
+		return err
 	}
 
-	err := DecodeStringArrayBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+	err = DecodeStringArrayBlock(b, values)
 	m.mu.RUnlock()
 
 	return err
@@ -254,12 +314,13 @@ func (m *mmapAccessor) readBooleanBlock(entry *IndexEntry, values *[]BooleanValu
 	m.incAccess()
 
 	m.mu.RLock()
-	if int64(len(m.b)) < entry.Offset+int64(entry.Size) {
+	b, err := m.block(entry)
+	if err != nil {
 		m.mu.RUnlock()
-		return nil, ErrTSMClosed
+		return nil, err
 	}
 
-	a, err := DecodeBooleanBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+	a, err := DecodeBooleanBlock(b, values)
 	m.mu.RUnlock()
 
 	if err != nil {
@@ -273,12 +334,13 @@ func (m *mmapAccessor) readBooleanArrayBlock(entry *IndexEntry, values *tsdb.Boo
 	m.incAccess()
 
 	m.mu.RLock()
-	if int64(len(m.b)) < entry.Offset+int64(entry.Size) {
+	b, err := m.block(entry)
+	if err != nil {
 		m.mu.RUnlock()
-		return ErrTSMClosed
+		return err
 	}
 
-	err := DecodeBooleanArrayBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+	err = DecodeBooleanArrayBlock(b, values)
 	m.mu.RUnlock()
 
 	return err
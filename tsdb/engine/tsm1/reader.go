@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"math"
 	"os"
@@ -20,6 +21,9 @@ import (
 // ErrFileInUse is returned when attempting to remove or close a TSM file that is still being used.
 var ErrFileInUse = fmt.Errorf("file still in use")
 
+// errBlockChecksum is returned when the checksum of a block does not match its content.
+var errBlockChecksum = fmt.Errorf("block checksum mismatch")
+
 // nilOffset is the value written to the offsets to indicate that position is deleted.  The value is the max
 // uint32 which is an invalid position.  We don't use 0 as 0 is actually a valid position.
 var nilOffset = []byte{255, 255, 255, 255}
@@ -59,6 +63,12 @@ type TSMReader struct {
 
 	// deleteMu limits concurrent deletes
 	deleteMu sync.Mutex
+
+	// quarantined holds the blocks found corrupt when read, by offset.
+	quarantineMu sync.Mutex
+	quarantined  map[int64]tsdb.CorruptBlock
+	quarantinedN int32 // len(quarantined), read without quarantineMu
+	onQuarantine func(tsdb.CorruptBlock)
 }
 
 // TSMIndex represent the index section of a TSM file.  The index records all
@@ -212,6 +222,24 @@ func (b *BlockIterator) Read() (key []byte, minTime int64, maxTime int64, typ by
 	return b.key, b.entries[0].MinTime, b.entries[0].MaxTime, b.typ, checksum, buf, err
 }
 
+// readVerified reads information about the next block like Read, but returns an
+// error if the checksum of the block does not match its content.
+func (b *BlockIterator) readVerified() ([]byte, int64, int64, byte, []byte, error) {
+	key, minTime, maxTime, typ, checksum, buf, err := b.Read()
+	if err != nil {
+		return nil, 0, 0, 0, nil, err
+	} else if crc32.ChecksumIEEE(buf) != checksum {
+		return nil, 0, 0, 0, nil, fmt.Errorf("%s: block of key %q at offset %d: %v", b.r.Path(), key, b.entries[0].Offset, errBlockChecksum)
+	}
+	return key, minTime, maxTime, typ, buf, nil
+}
+
+// summary returns the summary of the block to be iterated, if the file has
+// one for it.
+func (b *BlockIterator) summary() (BlockSummary, bool) {
+	return b.r.BlockSummary(&b.entries[0])
+}
+
 // Err returns any errors encounter during iteration.
 func (b *BlockIterator) Err() error {
 	return b.err
@@ -226,6 +254,14 @@ var WithMadviseWillNeed = func(willNeed bool) tsmReaderOption {
 	}
 }
 
+// WithQuarantineFunc is an option for specifying a function called with each
+// corrupt block the reader quarantines.
+var WithQuarantineFunc = func(fn func(tsdb.CorruptBlock)) tsmReaderOption {
+	return func(r *TSMReader) {
+		r.onQuarantine = fn
+	}
+}
+
 // NewTSMReader returns a new TSMReader from the given file.
 func NewTSMReader(f *os.File, options ...tsmReaderOption) (*TSMReader, error) {
 	t := &TSMReader{}
@@ -339,6 +375,10 @@ func (t *TSMReader) ReadAt(entry *IndexEntry, vals []Value) ([]Value, error) {
 	t.mu.RLock()
 	v, err := t.accessor.readBlock(entry, vals)
 	t.mu.RUnlock()
+	if err == errBlockChecksum {
+		t.quarantine(entry)
+		return vals[:0], nil
+	}
 	return v, err
 }
 
@@ -365,6 +405,110 @@ func (t *TSMReader) ReadBytes(e *IndexEntry, b []byte) (uint32, []byte, error) {
 	return n, v, err
 }
 
+// BlockSummary returns the summary of the block identified by entry.  A corrupt
+// block is quarantined and has no summary, so it is read like any other block
+// without one.
+func (t *TSMReader) BlockSummary(entry *IndexEntry) (BlockSummary, bool) {
+	t.mu.RLock()
+	s, ok := t.accessor.summary(entry)
+	var err error
+	if ok {
+		err = t.accessor.verify(entry)
+	}
+	t.mu.RUnlock()
+	if err != nil {
+		if err == errBlockChecksum {
+			t.quarantine(entry)
+		}
+		return BlockSummary{}, false
+	}
+	return s, ok
+}
+
+// quarantine records the block identified by entry as corrupt.  Reads of a
+// corrupt block return no values so the rest of the file can still be served.
+func (t *TSMReader) quarantine(entry *IndexEntry) {
+	t.quarantineMu.Lock()
+	defer t.quarantineMu.Unlock()
+	if _, ok := t.quarantined[entry.Offset]; ok {
+		return
+	}
+
+	b := tsdb.CorruptBlock{
+		Path:    t.Path(),
+		Offset:  entry.Offset,
+		MinTime: entry.MinTime,
+		MaxTime: entry.MaxTime,
+	}
+	if key := t.blockKey(entry.Offset); key != nil {
+		series, field := SeriesAndFieldFromCompositeKey(key)
+		b.Series = append([]byte(nil), series...)
+		b.Field = append([]byte(nil), field...)
+	}
+
+	if t.quarantined == nil {
+		t.quarantined = make(map[int64]tsdb.CorruptBlock)
+	}
+	t.quarantined[entry.Offset] = b
+	atomic.StoreInt32(&t.quarantinedN, int32(len(t.quarantined)))
+	if t.onQuarantine != nil {
+		t.onQuarantine(b)
+	}
+}
+
+// QuarantinedBlock returns the block identified by entry if it was found
+// corrupt.
+func (t *TSMReader) QuarantinedBlock(entry *IndexEntry) (tsdb.CorruptBlock, bool) {
+	if atomic.LoadInt32(&t.quarantinedN) == 0 {
+		return tsdb.CorruptBlock{}, false
+	}
+
+	t.quarantineMu.Lock()
+	defer t.quarantineMu.Unlock()
+	b, ok := t.quarantined[entry.Offset]
+	return b, ok
+}
+
+// Quarantined returns the corrupt blocks found when reading the file, ordered
+// by offset.
+func (t *TSMReader) Quarantined() []tsdb.CorruptBlock {
+	t.quarantineMu.Lock()
+	defer t.quarantineMu.Unlock()
+	if len(t.quarantined) == 0 {
+		return nil
+	}
+
+	blocks := make([]tsdb.CorruptBlock, 0, len(t.quarantined))
+	for _, b := range t.quarantined {
+		blocks = append(blocks, b)
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Offset < blocks[j].Offset })
+	return blocks
+}
+
+// blockKey returns the key of the block at offset, or nil if there is no
+// block at offset.
+func (t *TSMReader) blockKey(offset int64) []byte {
+	// Blocks are written in key order, so the keys are sorted by the offset
+	// of their first block as well.
+	var entries []IndexEntry
+	i := sort.Search(t.index.KeyCount(), func(i int) bool {
+		_, _, e := t.index.Key(i, &entries)
+		return len(e) > 0 && e[0].Offset > offset
+	}) - 1
+	if i < 0 {
+		return nil
+	}
+
+	key, _, e := t.index.Key(i, &entries)
+	for _, entry := range e {
+		if entry.Offset == offset {
+			return key
+		}
+	}
+	return nil
+}
+
 // Type returns the type of values stored at the given key.
 func (t *TSMReader) Type(key []byte) (byte, error) {
 	return t.index.Type(key)
@@ -583,6 +727,9 @@ func (t *TSMReader) Stats() FileStat {
 		MinKey:       minKey,
 		MaxKey:       maxKey,
 		HasTombstone: t.tombstoner.HasTombstones(),
+
+		KeyCount:       t.index.KeyCount(),
+		TombstoneCount: t.tombstoner.Count(),
 	}
 }
 
@@ -1344,18 +1491,25 @@ type mmapAccessor struct {
 	f  *os.File
 
 	index *indirectIndex
+
+	// summaryStart and summaryEnd are the bounds of the block summaries in b.
+	summaryStart, summaryEnd int
+
+	// verified holds the offsets of the blocks whose checksum matched, so the
+	// checksum of a block is only computed the first time it is read.
+	verifiedMu sync.RWMutex
+	verified   map[int64]struct{}
 }
 
 func (m *mmapAccessor) init() (*indirectIndex, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if err := verifyVersion(m.f); err != nil {
+	version, err := verifyVersion(m.f)
+	if err != nil {
 		return nil, err
 	}
 
-	var err error
-
 	if _, err := m.f.Seek(0, 0); err != nil {
 		return nil, err
 	}
@@ -1388,8 +1542,23 @@ func (m *mmapAccessor) init() (*indirectIndex, error) {
 		return nil, fmt.Errorf("mmapAccessor: invalid indexStart")
 	}
 
+	// Version 2 files store the block summaries between the index and the footer.
+	indexEnd := indexOfsPos
+	if version != version1 {
+		if indexOfsPos < 8 {
+			return nil, fmt.Errorf("mmapAccessor: byte slice too small for block summaries")
+		}
+		summaryOfsPos := indexOfsPos - 8
+		summaryStart := binary.BigEndian.Uint64(m.b[summaryOfsPos : summaryOfsPos+8])
+		if summaryStart < indexStart || summaryStart > uint64(summaryOfsPos) || (uint64(summaryOfsPos)-summaryStart)%blockSummarySize != 0 {
+			return nil, fmt.Errorf("mmapAccessor: invalid summaryStart")
+		}
+		m.summaryStart, m.summaryEnd = int(summaryStart), summaryOfsPos
+		indexEnd = int(summaryStart)
+	}
+
 	m.index = NewIndirectIndex()
-	if err := m.index.UnmarshalBinary(m.b[indexStart:indexOfsPos]); err != nil {
+	if err := m.index.UnmarshalBinary(m.b[indexStart:indexEnd]); err != nil {
 		return nil, err
 	}
 
@@ -1492,12 +1661,12 @@ func (m *mmapAccessor) readBlock(entry *IndexEntry, values []Value) ([]Value, er
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	if int64(len(m.b)) < entry.Offset+int64(entry.Size) {
-		return nil, ErrTSMClosed
+	b, err := m.block(entry)
+	if err != nil {
+		return nil, err
 	}
-	//TODO: Validate checksum
-	var err error
-	values, err = DecodeBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+
+	values, err = DecodeBlock(b, values)
 	if err != nil {
 		return nil, err
 	}
@@ -1505,6 +1674,43 @@ func (m *mmapAccessor) readBlock(entry *IndexEntry, values []Value) ([]Value, er
 	return values, nil
 }
 
+// block returns the block identified by entry without its checksum.  It returns
+// errBlockChecksum if the checksum does not match the block.  m.mu must be held.
+func (m *mmapAccessor) block(entry *IndexEntry) ([]byte, error) {
+	if int64(len(m.b)) < entry.Offset+int64(entry.Size) {
+		return nil, ErrTSMClosed
+	}
+
+	b := m.b[entry.Offset : entry.Offset+int64(entry.Size)]
+	m.verifiedMu.RLock()
+	_, ok := m.verified[entry.Offset]
+	m.verifiedMu.RUnlock()
+	if ok {
+		return b[4:], nil
+	}
+
+	if crc32.ChecksumIEEE(b[4:]) != binary.BigEndian.Uint32(b[:4]) {
+		return nil, errBlockChecksum
+	}
+
+	m.verifiedMu.Lock()
+	if m.verified == nil {
+		m.verified = make(map[int64]struct{})
+	}
+	m.verified[entry.Offset] = struct{}{}
+	m.verifiedMu.Unlock()
+	return b[4:], nil
+}
+
+// verify returns errBlockChecksum if the checksum of the block identified by
+// entry does not match the block.
+func (m *mmapAccessor) verify(entry *IndexEntry) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, err := m.block(entry)
+	return err
+}
+
 func (m *mmapAccessor) readBytes(entry *IndexEntry, b []byte) (uint32, []byte, error) {
 	m.incAccess()
 
@@ -1521,6 +1727,19 @@ func (m *mmapAccessor) readBytes(entry *IndexEntry, b []byte) (uint32, []byte, e
 	return crc, block, nil
 }
 
+// summary returns the summary of the block identified by entry.  Files written
+// before block summaries were added have none.
+func (m *mmapAccessor) summary(entry *IndexEntry) (BlockSummary, bool) {
+	m.incAccess()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.b) < m.summaryEnd {
+		return BlockSummary{}, false
+	}
+	return searchBlockSummary(m.b[m.summaryStart:m.summaryEnd], entry.Offset)
+}
+
 // readAll returns all values for a key in all blocks.
 func (m *mmapAccessor) readAll(key []byte) ([]Value, error) {
 	m.incAccess()
@@ -1536,7 +1755,6 @@ func (m *mmapAccessor) readAll(key []byte) ([]Value, error) {
 	defer m.mu.RUnlock()
 
 	var temp []Value
-	var err error
 	var values []Value
 	for _, block := range blocks {
 		var skip bool
@@ -1551,10 +1769,14 @@ func (m *mmapAccessor) readAll(key []byte) ([]Value, error) {
 		if skip {
 			continue
 		}
-		//TODO: Validate checksum
+
+		b, err := m.block(&block)
+		if err != nil {
+			return nil, err
+		}
+
 		temp = temp[:0]
-		// The +4 is the 4 byte checksum length
-		temp, err = DecodeBlock(m.b[block.Offset+4:block.Offset+int64(block.Size)], temp)
+		temp, err = DecodeBlock(b, temp)
 		if err != nil {
 			return nil, err
 		}
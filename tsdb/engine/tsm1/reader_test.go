@@ -1,13 +1,18 @@
 package tsm1
 
 import (
+	"encoding/binary"
 	"fmt"
 	"io/ioutil"
 	"math"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sort"
+	"strings"
 	"testing"
+
+	"github.com/influxdata/influxdb/tsdb"
 )
 
 func fatal(t *testing.T, msg string, err error) {
@@ -56,6 +61,220 @@ func TestTSMReader_Type(t *testing.T) {
 	}
 }
 
+func TestTSMReader_BlockSummary(t *testing.T) {
+	dir := mustTempDir()
+	defer os.RemoveAll(dir)
+	f := mustTempFile(dir)
+
+	w, err := NewTSMWriter(f)
+	if err != nil {
+		t.Fatalf("unexpected error creating writer: %v", err)
+	}
+
+	if err := w.Write([]byte("cpu"), []Value{NewValue(1, 1.5), NewValue(2, -2.0), NewValue(3, 4.0)}); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	}
+
+	block, err := Values{NewValue(1, int64(7)), NewValue(2, int64(-3))}.Encode(nil)
+	if err != nil {
+		t.Fatalf("unexpected error encoding: %v", err)
+	}
+	if err := w.WriteBlock([]byte("mem"), 1, 2, block); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	}
+
+	if err := w.Write([]byte("name"), []Value{NewValue(1, "server01")}); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	}
+
+	if err := w.WriteIndex(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+
+	f, err = os.Open(f.Name())
+	if err != nil {
+		t.Fatalf("unexpected error opening: %v", err)
+	}
+	r, err := NewTSMReader(f)
+	if err != nil {
+		t.Fatalf("unexpected error created reader: %v", err)
+	}
+	defer r.Close()
+
+	entry := r.Entries([]byte("cpu"))[0]
+	if got, ok := r.BlockSummary(&entry); !ok {
+		t.Fatalf("expected summary for float block")
+	} else if exp := (BlockSummary{Count: 3, Sum: math.Float64bits(3.5), Min: math.Float64bits(-2), Max: math.Float64bits(4)}); got != exp {
+		t.Fatalf("summary mismatch: got %v, exp %v", got, exp)
+	}
+
+	entry = r.Entries([]byte("mem"))[0]
+	if got, ok := r.BlockSummary(&entry); !ok {
+		t.Fatalf("expected summary for integer block")
+	} else if got.Count != 2 || int64(got.Sum) != 4 || int64(got.Min) != -3 || int64(got.Max) != 7 {
+		t.Fatalf("summary mismatch: got %v", got)
+	}
+
+	entry = r.Entries([]byte("name"))[0]
+	if _, ok := r.BlockSummary(&entry); ok {
+		t.Fatalf("unexpected summary for string block")
+	}
+}
+
+func TestTSMReader_Quarantine(t *testing.T) {
+	dir := mustTempDir()
+	defer os.RemoveAll(dir)
+	f := mustTempFile(dir)
+
+	w, err := NewTSMWriter(f)
+	if err != nil {
+		t.Fatalf("unexpected error creating writer: %v", err)
+	}
+	if err := w.Write([]byte("cpu,host=A#!~#value"), []Value{NewValue(1, 1.0), NewValue(2, 2.0)}); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	}
+	if err := w.Write([]byte("cpu,host=B#!~#value"), []Value{NewValue(3, 3.0)}); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	}
+	if err := w.WriteIndex(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+
+	// Flip a byte of the first block, which follows the header and its checksum.
+	f, err = os.OpenFile(f.Name(), os.O_RDWR, 0666)
+	if err != nil {
+		t.Fatalf("unexpected error opening: %v", err)
+	}
+	if _, err := f.WriteAt([]byte{0xff}, 5+4+2); err != nil {
+		t.Fatalf("unexpected error corrupting block: %v", err)
+	}
+
+	var quarantined []tsdb.CorruptBlock
+	r, err := NewTSMReader(f, WithQuarantineFunc(func(b tsdb.CorruptBlock) {
+		quarantined = append(quarantined, b)
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error created reader: %v", err)
+	}
+	defer r.Close()
+
+	// Reads of the corrupt block return no values, however many times it is read.
+	entry := r.Entries([]byte("cpu,host=A#!~#value"))[0]
+	for i := 0; i < 2; i++ {
+		var buf []FloatValue
+		if values, err := r.ReadFloatBlockAt(&entry, &buf); err != nil {
+			t.Fatalf("unexpected error reading: %v", err)
+		} else if len(values) != 0 {
+			t.Fatalf("unexpected values read from corrupt block: %v", values)
+		}
+
+		var a tsdb.FloatArray
+		if err := r.ReadFloatArrayBlockAt(&entry, &a); err != nil {
+			t.Fatalf("unexpected error reading: %v", err)
+		} else if a.Len() != 0 {
+			t.Fatalf("unexpected values read from corrupt block: %v", a.Timestamps)
+		}
+	}
+
+	// The rest of the file is served.
+	entry = r.Entries([]byte("cpu,host=B#!~#value"))[0]
+	var buf []FloatValue
+	if values, err := r.ReadFloatBlockAt(&entry, &buf); err != nil {
+		t.Fatalf("unexpected error reading: %v", err)
+	} else if len(values) != 1 || values[0].value != 3.0 {
+		t.Fatalf("unexpected values: %v", values)
+	}
+
+	exp := []tsdb.CorruptBlock{{
+		Path:    f.Name(),
+		Offset:  5,
+		Series:  []byte("cpu,host=A"),
+		Field:   []byte("value"),
+		MinTime: 1,
+		MaxTime: 2,
+	}}
+	if got := r.Quarantined(); !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected quarantined blocks: got %v, exp %v", got, exp)
+	}
+	if !reflect.DeepEqual(quarantined, exp) {
+		t.Fatalf("unexpected blocks passed to quarantine func: got %v, exp %v", quarantined, exp)
+	}
+
+	if _, err := r.ReadAll([]byte("cpu,host=A#!~#value")); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Fatalf("expected checksum error reading all values, got %v", err)
+	}
+}
+
+// Ensure files written before block summaries were added can still be read.
+func TestTSMReader_Version1(t *testing.T) {
+	dir := mustTempDir()
+	defer os.RemoveAll(dir)
+	f := mustTempFile(dir)
+
+	w, err := NewTSMWriter(f)
+	if err != nil {
+		t.Fatalf("unexpected error creating writer: %v", err)
+	}
+
+	values := []Value{NewValue(1, 1.5), NewValue(2, 2.5)}
+	if err := w.Write([]byte("cpu"), values); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	}
+	if err := w.WriteIndex(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+
+	// Rewrite the file in the version 1 format by removing the summaries.
+	b, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("unexpected error reading: %v", err)
+	}
+	summaryStart := binary.BigEndian.Uint64(b[len(b)-16 : len(b)-8])
+	b = append(b[:summaryStart], b[len(b)-8:]...)
+	b[4] = version1
+	if err := ioutil.WriteFile(f.Name(), b, 0666); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	}
+
+	f, err = os.Open(f.Name())
+	if err != nil {
+		t.Fatalf("unexpected error opening: %v", err)
+	}
+	r, err := NewTSMReader(f)
+	if err != nil {
+		t.Fatalf("unexpected error created reader: %v", err)
+	}
+	defer r.Close()
+
+	readValues, err := r.ReadAll([]byte("cpu"))
+	if err != nil {
+		t.Fatalf("unexpected error reading: %v", err)
+	}
+	if got, exp := len(readValues), len(values); got != exp {
+		t.Fatalf("values length mismatch: got %v, exp %v", got, exp)
+	}
+	for i, v := range values {
+		if v.Value() != readValues[i].Value() {
+			t.Fatalf("read value mismatch(%d): got %v, exp %v", i, readValues[i].Value(), v.Value())
+		}
+	}
+
+	entry := r.Entries([]byte("cpu"))[0]
+	if _, ok := r.BlockSummary(&entry); ok {
+		t.Fatalf("unexpected summary in version 1 file")
+	}
+}
+
 func TestTSMReader_MMAP_ReadAll(t *testing.T) {
 	dir := mustTempDir()
 	defer os.RemoveAll(dir)
@@ -0,0 +1,185 @@
+package tsm1
+
+import (
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+
+	"github.com/influxdata/influxdb/tsdb"
+	"go.uber.org/zap"
+)
+
+// VerifyTSM returns the blocks of r whose checksum does not match their content.
+func VerifyTSM(r *TSMReader) ([]tsdb.CorruptBlock, error) {
+	var corrupt []tsdb.CorruptBlock
+	if err := walkBlocks(r, func(key []byte, entry *IndexEntry) error {
+		checksum, b, err := r.ReadBytes(entry, nil)
+		if err != nil {
+			return err
+		}
+		if crc32.ChecksumIEEE(b) == checksum {
+			return nil
+		}
+
+		series, field := SeriesAndFieldFromCompositeKey(key)
+		corrupt = append(corrupt, tsdb.CorruptBlock{
+			Path:    r.Path(),
+			Offset:  entry.Offset,
+			Series:  append([]byte(nil), series...),
+			Field:   append([]byte(nil), field...),
+			MinTime: entry.MinTime,
+			MaxTime: entry.MaxTime,
+		})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return corrupt, nil
+}
+
+// RepairTSM writes the blocks of r to w, except for the corrupt blocks, and
+// writes the index.  Blocks are copied as is, so the tombstones of r apply to
+// the new file as well.  ErrNoValues is returned if every block is corrupt.
+func RepairTSM(r *TSMReader, w TSMWriter, corrupt []tsdb.CorruptBlock) error {
+	skip := make(map[int64]struct{}, len(corrupt))
+	for _, b := range corrupt {
+		skip[b.Offset] = struct{}{}
+	}
+
+	if err := walkBlocks(r, func(key []byte, entry *IndexEntry) error {
+		if _, ok := skip[entry.Offset]; ok {
+			return nil
+		}
+
+		_, b, err := r.ReadBytes(entry, nil)
+		if err != nil {
+			return err
+		}
+		if summary, ok := r.BlockSummary(entry); ok {
+			return w.WriteBlockSummary(key, entry.MinTime, entry.MaxTime, b, summary)
+		}
+		return w.WriteBlock(key, entry.MinTime, entry.MaxTime, b)
+	}); err != nil {
+		return err
+	}
+	return w.WriteIndex()
+}
+
+// walkBlocks calls fn with each block of r in file order.
+func walkBlocks(r *TSMReader, fn func(key []byte, entry *IndexEntry) error) error {
+	var entries []IndexEntry
+	for i, n := 0, r.KeyCount(); i < n; i++ {
+		key, _, e := r.Key(i, &entries)
+		for j := range e {
+			if err := fn(key, &e[j]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Repair rewrites the TSM files of the engine without the blocks whose
+// checksum does not match their content and returns the blocks it removed.
+// Level compactions and deletes wait for the repair to complete.
+func (e *Engine) Repair() ([]tsdb.CorruptBlock, error) {
+	e.disableLevelCompactions(true)
+	defer e.enableLevelCompactions(true)
+
+	e.repairMu.Lock()
+	defer e.repairMu.Unlock()
+
+	var removed []tsdb.CorruptBlock
+	for _, f := range e.FileStore.Stats() {
+		blocks, err := e.repairFile(f.Path)
+		if err != nil {
+			return removed, err
+		}
+		removed = append(removed, blocks...)
+	}
+	return removed, nil
+}
+
+// repairFile rewrites the TSM file at path without its corrupt blocks and
+// returns them.  The repaired file takes the next sequence of the generation,
+// as if the file had been compacted.
+func (e *Engine) repairFile(path string) ([]tsdb.CorruptBlock, error) {
+	r := e.FileStore.TSMReader(path)
+	if r == nil {
+		return nil, nil
+	}
+	defer r.Unref()
+
+	corrupt, err := VerifyTSM(r)
+	if err != nil || len(corrupt) == 0 {
+		return nil, err
+	}
+
+	generation, sequence, err := e.FileStore.ParseFileName(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range e.FileStore.Stats() {
+		if g, s, err := e.FileStore.ParseFileName(f.Path); err == nil && g == generation && s > sequence {
+			sequence = s
+		}
+	}
+
+	newPath := filepath.Join(e.path, e.formatFileName(generation, sequence+1)+"."+TSMFileExtension)
+	tmpPath := newPath + "." + TmpTSMFileExtension
+	newFiles, err := e.writeRepairedFile(r, tmpPath, newPath, corrupt)
+	if err == nil {
+		err = e.FileStore.Replace([]string{path}, newFiles)
+	}
+	if err != nil {
+		os.RemoveAll(tmpPath)
+		os.RemoveAll((&Tombstoner{Path: newPath}).tombstonePath())
+		return nil, err
+	}
+
+	for _, b := range corrupt {
+		e.logger.Warn("Removed corrupt block",
+			zap.String("path", b.Path),
+			zap.Int64("offset", b.Offset),
+			zap.ByteString("series", b.Series),
+			zap.ByteString("field", b.Field),
+			zap.Int64("min_time", b.MinTime),
+			zap.Int64("max_time", b.MaxTime))
+	}
+	e.logger.Info("Repaired TSM file", zap.String("path", path), zap.Strings("new_files", newFiles), zap.Int("removed_blocks", len(corrupt)))
+	return corrupt, nil
+}
+
+// writeRepairedFile writes the blocks of r, except for the corrupt blocks, to
+// tmpPath and copies the tombstones of r to the tombstone file of newPath.  It
+// returns the files to replace r with, which are none if every block is corrupt.
+func (e *Engine) writeRepairedFile(r *TSMReader, tmpPath, newPath string, corrupt []tsdb.CorruptBlock) ([]string, error) {
+	fd, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_RDWR|os.O_EXCL, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := NewTSMWriter(fd)
+	if err != nil {
+		fd.Close()
+		return nil, err
+	}
+
+	if err := RepairTSM(r, w, corrupt); err == ErrNoValues {
+		w.Close()
+		return nil, os.Remove(tmpPath)
+	} else if err != nil {
+		w.Close()
+		return nil, fmt.Errorf("repair %s: %v", r.Path(), err)
+	} else if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	for _, f := range r.TombstoneFiles() {
+		if err := copyFileSync(f.Path, (&Tombstoner{Path: newPath}).tombstonePath()); err != nil {
+			return nil, err
+		}
+	}
+	return []string{tmpPath}, nil
+}
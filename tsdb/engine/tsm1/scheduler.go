@@ -2,6 +2,9 @@ package tsm1
 
 import (
 	"sync/atomic"
+
+	"github.com/influxdata/influxdb/pkg/limiter"
+	"github.com/influxdata/influxdb/tsdb"
 )
 
 var defaultWeights = [4]float64{0.4, 0.3, 0.2, 0.1}
@@ -13,6 +16,14 @@ type scheduler struct {
 	// queues is the depth of work pending for each compaction level
 	queues  [4]int
 	weights [4]float64
+
+	// priorities ranks the shards sharing limiter that are waiting to compact.
+	// If set, the scheduler leaves the available compactions to shards with a
+	// higher priority than its own.
+	id         uint64
+	priority   float64
+	priorities *tsdb.CompactionPriorities
+	limiter    limiter.Fixed
 }
 
 func newScheduler(stats *EngineStatistics, maxConcurrency int) *scheduler {
@@ -32,6 +43,18 @@ func (s *scheduler) setDepth(level, depth int) {
 	s.queues[level] = depth
 }
 
+// setPriority sets the priority of the shard against other shards.
+func (s *scheduler) setPriority(priority float64) {
+	s.priority = priority
+}
+
+// removePriority removes the shard from the shards waiting to compact.
+func (s *scheduler) removePriority() {
+	if s.priorities != nil {
+		s.priorities.Remove(s.id)
+	}
+}
+
 func (s *scheduler) next() (int, bool) {
 	level1Running := int(atomic.LoadInt64(&s.stats.TSMCompactionsActive[0]))
 	level2Running := int(atomic.LoadInt64(&s.stats.TSMCompactionsActive[1]))
@@ -39,6 +62,7 @@ func (s *scheduler) next() (int, bool) {
 	level4Running := int(atomic.LoadInt64(&s.stats.TSMFullCompactionsActive) + atomic.LoadInt64(&s.stats.TSMOptimizeCompactionsActive))
 
 	if level1Running+level2Running+level3Running+level4Running >= s.maxConcurrency {
+		s.removePriority()
 		return 0, false
 	}
 
@@ -61,6 +85,20 @@ func (s *scheduler) next() (int, bool) {
 			weight = float64(s.queues[i]) * s.weights[i]
 		}
 	}
+
+	if s.priorities == nil {
+		return level, runnable
+	} else if !runnable {
+		s.removePriority()
+		return level, runnable
+	}
+
+	// Wait if the shards with a higher priority can take all the available compactions.
+	s.priorities.Set(s.id, s.priority)
+	if s.priorities.Ahead(s.id) >= s.limiter.Available() {
+		atomic.AddInt64(&s.stats.TSMCompactionsDeferred, 1)
+		return 0, false
+	}
 	return level, runnable
 }
 
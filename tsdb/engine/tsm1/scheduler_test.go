@@ -1,6 +1,11 @@
 package tsm1
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/influxdata/influxdb/pkg/limiter"
+	"github.com/influxdata/influxdb/tsdb"
+)
 
 func TestScheduler_Runnable_Empty(t *testing.T) {
 	s := newScheduler(&EngineStatistics{}, 1)
@@ -72,3 +77,68 @@ func TestScheduler_Runnable_MaxConcurrency(t *testing.T) {
 		}
 	}
 }
+
+func TestScheduler_Runnable_Priorities(t *testing.T) {
+	priorities := tsdb.NewCompactionPriorities()
+	lim := limiter.NewFixed(1)
+
+	newPriorityScheduler := func(id uint64, priority float64) *scheduler {
+		s := newScheduler(&EngineStatistics{}, 1)
+		s.id, s.priorities, s.limiter = id, priorities, lim
+		s.setPriority(priority)
+		s.setDepth(1, 1)
+		return s
+	}
+	hot := newPriorityScheduler(1, 10)
+	cold := newPriorityScheduler(2, 1)
+
+	if _, runnable := hot.next(); !runnable {
+		t.Fatal("expected hot shard to be runnable")
+	}
+
+	// The only compaction available is left to the hot shard.
+	if _, runnable := cold.next(); runnable {
+		t.Fatal("expected cold shard to wait")
+	}
+	if exp, got := int64(1), cold.stats.TSMCompactionsDeferred; exp != got {
+		t.Fatalf("deferred mismatch: exp %v, got %v", exp, got)
+	}
+
+	// Once the hot shard has no work left, the cold shard can run.
+	hot.setDepth(1, 0)
+	if _, runnable := hot.next(); runnable {
+		t.Fatal("expected hot shard not to be runnable")
+	}
+	if _, runnable := cold.next(); !runnable {
+		t.Fatal("expected cold shard to be runnable")
+	}
+}
+
+func TestScheduler_Runnable_Priorities_Started(t *testing.T) {
+	priorities := tsdb.NewCompactionPriorities()
+	lim := limiter.NewFixed(2)
+
+	newPriorityScheduler := func(id uint64, priority float64) *scheduler {
+		s := newScheduler(&EngineStatistics{}, 2)
+		s.id, s.priorities, s.limiter = id, priorities, lim
+		s.setPriority(priority)
+		s.setDepth(1, 2)
+		return s
+	}
+	hot := newPriorityScheduler(1, 10)
+	cold := newPriorityScheduler(2, 1)
+
+	// The hot shard takes a compaction and still has work queued.
+	if _, runnable := hot.next(); !runnable {
+		t.Fatal("expected hot shard to be runnable")
+	} else if !lim.TryTake() {
+		t.Fatal("expected a compaction to be available")
+	}
+	hot.removePriority()
+
+	// The hot shard is not waiting, so the remaining compaction goes to the
+	// cold shard.
+	if _, runnable := cold.next(); !runnable {
+		t.Fatal("expected cold shard to be runnable")
+	}
+}
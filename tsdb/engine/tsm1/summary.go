@@ -0,0 +1,276 @@
+package tsm1
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxql"
+)
+
+// BlockSummary holds the count, sum, min and max of the values of a float,
+// integer or unsigned block. Sum, Min and Max hold the bits of the float64,
+// int64 or uint64 values depending on the type of the block.
+type BlockSummary struct {
+	Count         int64
+	Sum, Min, Max uint64
+}
+
+// SummarizedBlock is the summary of a block along with the time range of its values.
+type SummarizedBlock struct {
+	MinTime, MaxTime int64
+	BlockSummary
+}
+
+// appendTo appends the encoded summary of the block at offset to b.
+func (s *BlockSummary) appendTo(b []byte, offset int64) []byte {
+	var buf [blockSummarySize]byte
+	binary.BigEndian.PutUint64(buf[0:8], uint64(offset))
+	binary.BigEndian.PutUint64(buf[8:16], uint64(s.Count))
+	binary.BigEndian.PutUint64(buf[16:24], s.Sum)
+	binary.BigEndian.PutUint64(buf[24:32], s.Min)
+	binary.BigEndian.PutUint64(buf[32:40], s.Max)
+	return append(b, buf[:]...)
+}
+
+// unmarshalBinary decodes a BlockSummary from b.
+func (s *BlockSummary) unmarshalBinary(b []byte) {
+	s.Count = int64(binary.BigEndian.Uint64(b[8:16]))
+	s.Sum = binary.BigEndian.Uint64(b[16:24])
+	s.Min = binary.BigEndian.Uint64(b[24:32])
+	s.Max = binary.BigEndian.Uint64(b[32:40])
+}
+
+// searchBlockSummary returns the summary of the block at offset from the
+// encoded summaries in b.
+func searchBlockSummary(b []byte, offset int64) (BlockSummary, bool) {
+	n := len(b) / blockSummarySize
+	i := sort.Search(n, func(i int) bool {
+		return int64(binary.BigEndian.Uint64(b[i*blockSummarySize:])) >= offset
+	})
+	if i == n || int64(binary.BigEndian.Uint64(b[i*blockSummarySize:])) != offset {
+		return BlockSummary{}, false
+	}
+
+	var s BlockSummary
+	s.unmarshalBinary(b[i*blockSummarySize : (i+1)*blockSummarySize])
+	return s, true
+}
+
+// summarizeValues returns the summary of values if they are floats, integers
+// or unsigned integers.
+func summarizeValues(values Values) (BlockSummary, bool) {
+	switch values[0].(type) {
+	case FloatValue:
+		a := make([]float64, len(values))
+		for i, v := range values {
+			a[i] = v.(FloatValue).value
+		}
+		return summarizeFloats(a), true
+	case IntegerValue:
+		a := make([]int64, len(values))
+		for i, v := range values {
+			a[i] = v.(IntegerValue).value
+		}
+		return summarizeIntegers(a), true
+	case UnsignedValue:
+		a := make([]uint64, len(values))
+		for i, v := range values {
+			a[i] = v.(UnsignedValue).value
+		}
+		return summarizeUnsigneds(a), true
+	}
+	return BlockSummary{}, false
+}
+
+// summarizeBlock decodes block and returns its summary if it is a float,
+// integer or unsigned block.
+func summarizeBlock(blockType byte, block []byte) (BlockSummary, bool, error) {
+	switch blockType {
+	case BlockFloat64:
+		var a tsdb.FloatArray
+		if err := DecodeFloatArrayBlock(block, &a); err != nil {
+			return BlockSummary{}, false, err
+		}
+		return summarizeFloats(a.Values), len(a.Values) > 0, nil
+	case BlockInteger:
+		var a tsdb.IntegerArray
+		if err := DecodeIntegerArrayBlock(block, &a); err != nil {
+			return BlockSummary{}, false, err
+		}
+		return summarizeIntegers(a.Values), len(a.Values) > 0, nil
+	case BlockUnsigned:
+		var a tsdb.UnsignedArray
+		if err := DecodeUnsignedArrayBlock(block, &a); err != nil {
+			return BlockSummary{}, false, err
+		}
+		return summarizeUnsigneds(a.Values), len(a.Values) > 0, nil
+	}
+	return BlockSummary{}, false, nil
+}
+
+func summarizeFloats(a []float64) BlockSummary {
+	if len(a) == 0 {
+		return BlockSummary{}
+	}
+	sum, min, max := 0.0, a[0], a[0]
+	for _, v := range a {
+		sum += v
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+	}
+	return BlockSummary{
+		Count: int64(len(a)),
+		Sum:   math.Float64bits(sum),
+		Min:   math.Float64bits(min),
+		Max:   math.Float64bits(max),
+	}
+}
+
+func summarizeIntegers(a []int64) BlockSummary {
+	if len(a) == 0 {
+		return BlockSummary{}
+	}
+	sum, min, max := int64(0), a[0], a[0]
+	for _, v := range a {
+		sum += v
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+	}
+	return BlockSummary{
+		Count: int64(len(a)),
+		Sum:   uint64(sum),
+		Min:   uint64(min),
+		Max:   uint64(max),
+	}
+}
+
+func summarizeUnsigneds(a []uint64) BlockSummary {
+	if len(a) == 0 {
+		return BlockSummary{}
+	}
+	sum, min, max := uint64(0), a[0], a[0]
+	for _, v := range a {
+		sum += v
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+	}
+	return BlockSummary{
+		Count: int64(len(a)),
+		Sum:   sum,
+		Min:   min,
+		Max:   max,
+	}
+}
+
+// newSummaryIterator returns an iterator of the partial results of the call
+// named name for each of blocks, or nil if there are no blocks.
+func newSummaryIterator(name string, typ influxql.DataType, measurement string, tags query.Tags, blocks []SummarizedBlock) query.Iterator {
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	value := func(b *SummarizedBlock) uint64 {
+		switch name {
+		case "sum":
+			return b.Sum
+		case "min":
+			return b.Min
+		default:
+			return b.Max
+		}
+	}
+
+	switch {
+	case name == "count":
+		points := make([]query.IntegerPoint, len(blocks))
+		for i, b := range blocks {
+			points[i] = query.IntegerPoint{Name: measurement, Tags: tags, Time: b.MinTime, Value: b.Count}
+		}
+		return &integerSummaryIterator{points: points}
+	case typ == influxql.Float:
+		points := make([]query.FloatPoint, len(blocks))
+		for i := range blocks {
+			points[i] = query.FloatPoint{Name: measurement, Tags: tags, Time: blocks[i].MinTime, Value: math.Float64frombits(value(&blocks[i]))}
+		}
+		return &floatSummaryIterator{points: points}
+	case typ == influxql.Integer:
+		points := make([]query.IntegerPoint, len(blocks))
+		for i := range blocks {
+			points[i] = query.IntegerPoint{Name: measurement, Tags: tags, Time: blocks[i].MinTime, Value: int64(value(&blocks[i]))}
+		}
+		return &integerSummaryIterator{points: points}
+	default:
+		points := make([]query.UnsignedPoint, len(blocks))
+		for i := range blocks {
+			points[i] = query.UnsignedPoint{Name: measurement, Tags: tags, Time: blocks[i].MinTime, Value: value(&blocks[i])}
+		}
+		return &unsignedSummaryIterator{points: points}
+	}
+}
+
+// floatSummaryIterator emits the partial results of a call over float blocks.
+type floatSummaryIterator struct {
+	points []query.FloatPoint
+}
+
+func (itr *floatSummaryIterator) Stats() query.IteratorStats { return query.IteratorStats{} }
+func (itr *floatSummaryIterator) Close() error               { return nil }
+
+func (itr *floatSummaryIterator) Next() (*query.FloatPoint, error) {
+	if len(itr.points) == 0 {
+		return nil, nil
+	}
+	p := &itr.points[0]
+	itr.points = itr.points[1:]
+	return p, nil
+}
+
+// integerSummaryIterator emits the partial results of a call over integer
+// blocks, or the counts of any numeric blocks.
+type integerSummaryIterator struct {
+	points []query.IntegerPoint
+}
+
+func (itr *integerSummaryIterator) Stats() query.IteratorStats { return query.IteratorStats{} }
+func (itr *integerSummaryIterator) Close() error               { return nil }
+
+func (itr *integerSummaryIterator) Next() (*query.IntegerPoint, error) {
+	if len(itr.points) == 0 {
+		return nil, nil
+	}
+	p := &itr.points[0]
+	itr.points = itr.points[1:]
+	return p, nil
+}
+
+// unsignedSummaryIterator emits the partial results of a call over unsigned blocks.
+type unsignedSummaryIterator struct {
+	points []query.UnsignedPoint
+}
+
+func (itr *unsignedSummaryIterator) Stats() query.IteratorStats { return query.IteratorStats{} }
+func (itr *unsignedSummaryIterator) Close() error               { return nil }
+
+func (itr *unsignedSummaryIterator) Next() (*query.UnsignedPoint, error) {
+	if len(itr.points) == 0 {
+		return nil, nil
+	}
+	p := &itr.points[0]
+	itr.points = itr.points[1:]
+	return p, nil
+}
@@ -55,6 +55,9 @@ type Tombstoner struct {
 	tmp               [8]byte
 	lastAppliedOffset int64
 
+	// count is the number of tombstones read by Walk.
+	count int
+
 	// Optional observer for when tombstone files are written.
 	obs tsdb.FileStoreObserver
 }
@@ -177,6 +180,7 @@ func (t *Tombstoner) Delete() error {
 	}
 	t.statsLoaded = false
 	t.lastAppliedOffset = 0
+	t.count = 0
 
 	return nil
 }
@@ -225,6 +229,13 @@ func (t *Tombstoner) TombstoneFiles() []FileStat {
 	return stats
 }
 
+// Count returns the number of tombstones read by the last calls to Walk.
+func (t *Tombstoner) Count() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.count
+}
+
 // Walk calls fn for every Tombstone under the Tombstoner.
 func (t *Tombstoner) Walk(fn func(t Tombstone) error) error {
 	t.mu.Lock()
@@ -232,12 +243,24 @@ func (t *Tombstoner) Walk(fn func(t Tombstone) error) error {
 
 	f, err := os.Open(t.tombstonePath())
 	if os.IsNotExist(err) {
+		t.count = 0
 		return nil
 	} else if err != nil {
 		return err
 	}
 	defer f.Close()
 
+	// Only the tombstones added since the last walk of a v4 file are read, so
+	// the count restarts whenever the file is read from the beginning.
+	if t.lastAppliedOffset == 0 {
+		t.count = 0
+	}
+	walkFn := fn
+	fn = func(ts Tombstone) error {
+		t.count++
+		return walkFn(ts)
+	}
+
 	var b [4]byte
 	if _, err := f.Read(b[:]); err != nil {
 		// Might be a zero length file which should not exist, but
@@ -280,6 +280,44 @@ func TestTombstoner_Delete(t *testing.T) {
 	}
 }
 
+func TestTombstoner_Count(t *testing.T) {
+	dir := MustTempDir()
+	defer func() { os.RemoveAll(dir) }()
+
+	f := MustTempFile(dir)
+	ts := tsm1.NewTombstoner(f.Name(), nil)
+
+	ts.Add([][]byte{[]byte("foo"), []byte("bar")})
+	if err := ts.Flush(); err != nil {
+		t.Fatalf("unexpected error flushing: %v", err)
+	}
+
+	mustReadAll(ts)
+	if got, exp := ts.Count(), 2; got != exp {
+		t.Fatalf("count mismatch: got %v, exp %v", got, exp)
+	}
+
+	// Walking again only reads the tombstones added since the last walk.
+	ts.AddRange([][]byte{[]byte("baz")}, 10, 20)
+	if err := ts.Flush(); err != nil {
+		t.Fatalf("unexpected error flushing: %v", err)
+	}
+
+	if got, exp := len(mustReadAll(ts)), 1; got != exp {
+		t.Fatalf("length mismatch: got %v, exp %v", got, exp)
+	}
+	if got, exp := ts.Count(), 3; got != exp {
+		t.Fatalf("count mismatch: got %v, exp %v", got, exp)
+	}
+
+	if err := ts.Delete(); err != nil {
+		fatal(t, "delete tombstone", err)
+	}
+	if got, exp := ts.Count(), 0; got != exp {
+		t.Fatalf("count mismatch: got %v, exp %v", got, exp)
+	}
+}
+
 func TestTombstoner_ReadV1(t *testing.T) {
 	dir := MustTempDir()
 	defer func() { os.RemoveAll(dir) }()
@@ -94,6 +94,20 @@ type WAL struct {
 	currentSegmentID     int
 	currentSegmentWriter *WALSegmentWriter
 
+	// archivePath is the directory closed segments are copied to before they
+	// are removed. Segments are not archived when it is empty. This must be
+	// set before the WAL is opened.
+	archivePath      string
+	archiveSegmentID int
+
+	// archiveRetention is how long archived segments are kept after their
+	// last write. They are kept forever when it is zero.
+	archiveRetention time.Duration
+
+	// currentTimesWriter records the time each entry of the current segment
+	// was written at when archiving is enabled.
+	currentTimesWriter *walTimesWriter
+
 	// cache and flush variables
 	once    sync.Once
 	closing chan struct{}
@@ -191,6 +205,12 @@ func (l *WAL) Open() error {
 		return err
 	}
 
+	if l.archivePath != "" {
+		if err := l.openArchive(); err != nil {
+			return fmt.Errorf("open WAL archive: %v", err)
+		}
+	}
+
 	segments, err := segmentFileNames(l.path)
 	if err != nil {
 		return err
@@ -211,6 +231,7 @@ func (l *WAL) Open() error {
 
 		if stat.Size() == 0 {
 			os.Remove(lastSegment)
+			os.Remove(walTimesPath(lastSegment))
 			segments = segments[:len(segments)-1]
 		} else {
 			fd, err := os.OpenFile(lastSegment, os.O_RDWR, 0666)
@@ -222,6 +243,12 @@ func (l *WAL) Open() error {
 			}
 			l.currentSegmentWriter = NewWALSegmentWriter(fd)
 
+			if l.archivePath != "" {
+				if l.currentTimesWriter, err = openWALTimesWriter(lastSegment); err != nil {
+					return err
+				}
+			}
+
 			// Reset the current segment size stat
 			atomic.StoreInt64(&l.stats.CurrentBytes, stat.Size())
 		}
@@ -298,6 +325,9 @@ func (l *WAL) scheduleSync() {
 // a write lock on the WAL is obtained before calling sync.
 func (l *WAL) sync() {
 	err := l.currentSegmentWriter.sync()
+	if err == nil && l.currentTimesWriter != nil {
+		err = l.currentTimesWriter.sync()
+	}
 	for len(l.syncWaiters) > 0 {
 		errC := <-l.syncWaiters
 		errC <- err
@@ -355,12 +385,26 @@ func (l *WAL) ClosedSegments() ([]string, error) {
 }
 
 // Remove deletes the given segment file paths from disk and cleans up any associated objects.
+// Segments are copied to the archive directory first when archiving is enabled.
 func (l *WAL) Remove(files []string) error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	for _, fn := range files {
+		if l.archivePath != "" {
+			if err := l.archiveSegment(fn); err != nil {
+				return fmt.Errorf("error archiving WAL segment: %v", err)
+			}
+		}
+
 		l.traceLogger.Info("Removing WAL file", zap.String("path", fn))
 		os.RemoveAll(fn)
+		os.RemoveAll(walTimesPath(fn))
+	}
+
+	if l.archivePath != "" {
+		if err := l.pruneArchive(time.Now()); err != nil {
+			return fmt.Errorf("error pruning WAL archive: %v", err)
+		}
 	}
 
 	// Refresh the on-disk size stats
@@ -434,6 +478,13 @@ func (l *WAL) writeToLog(entry WALEntry) (int, error) {
 			return -1, fmt.Errorf("error writing WAL entry: %v", err)
 		}
 
+		now := time.Now().UTC()
+		if l.currentTimesWriter != nil {
+			if err := l.currentTimesWriter.write(now.UnixNano()); err != nil {
+				return -1, fmt.Errorf("error writing WAL entry time: %v", err)
+			}
+		}
+
 		select {
 		case l.syncWaiters <- syncErr:
 		default:
@@ -444,7 +495,7 @@ func (l *WAL) writeToLog(entry WALEntry) (int, error) {
 		// Update stats for current segment size
 		atomic.StoreInt64(&l.stats.CurrentBytes, int64(l.currentSegmentWriter.size))
 
-		l.lastWriteTime = time.Now().UTC()
+		l.lastWriteTime = now
 
 		return l.currentSegmentID, nil
 
@@ -540,6 +591,10 @@ func (l *WAL) Close() error {
 			l.currentSegmentWriter.close()
 			l.currentSegmentWriter = nil
 		}
+		if l.currentTimesWriter != nil {
+			l.currentTimesWriter.close()
+			l.currentTimesWriter = nil
+		}
 	})
 
 	return nil
@@ -566,6 +621,12 @@ func (l *WAL) newSegmentFile() error {
 		}
 		atomic.StoreInt64(&l.stats.OldBytes, int64(l.currentSegmentWriter.size))
 	}
+	if l.currentTimesWriter != nil {
+		if err := l.currentTimesWriter.close(); err != nil {
+			return err
+		}
+		l.currentTimesWriter = nil
+	}
 
 	fileName := filepath.Join(l.path, fmt.Sprintf("%s%05d.%s", WALFilePrefix, l.currentSegmentID, WALFileExtension))
 	fd, err := os.OpenFile(fileName, os.O_CREATE|os.O_RDWR, 0666)
@@ -574,6 +635,12 @@ func (l *WAL) newSegmentFile() error {
 	}
 	l.currentSegmentWriter = NewWALSegmentWriter(fd)
 
+	if l.archivePath != "" {
+		if l.currentTimesWriter, err = openWALTimesWriter(fileName); err != nil {
+			return err
+		}
+	}
+
 	// Reset the current segment size stat
 	atomic.StoreInt64(&l.stats.CurrentBytes, 0)
 
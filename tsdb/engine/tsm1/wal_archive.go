@@ -0,0 +1,345 @@
+package tsm1
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// WALTimesFileExtension is the extension of the files recording the wall-clock
+// time at which each entry of a WAL segment was written. They are only written
+// when WAL archiving is enabled, next to the segment they describe.
+const WALTimesFileExtension = "times"
+
+// walTimesPath returns the path of the times file of a segment.
+func walTimesPath(segment string) string {
+	return segment + "." + WALTimesFileExtension
+}
+
+// walTimesWriter appends the write time of each entry of a segment as a
+// big-endian unix nanosecond timestamp.
+type walTimesWriter struct {
+	f *os.File
+	w *bufio.Writer
+}
+
+// openWALTimesWriter opens the times file of a segment for appending. Times
+// are added or dropped so that the file holds exactly one time per valid
+// entry of the segment, in case the server crashed between the two writes.
+func openWALTimesWriter(segment string) (*walTimesWriter, error) {
+	stat, err := os.Stat(segment)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := countWALEntries(segment)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(walTimesPath(segment), os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	have := fi.Size() / 8
+	if have > n {
+		have = n
+	}
+	if err := f.Truncate(have * 8); err != nil {
+		f.Close()
+		return nil, err
+	} else if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	w := &walTimesWriter{f: f, w: bufio.NewWriter(f)}
+	for ; have < n; have++ {
+		if err := w.write(stat.ModTime().UnixNano()); err != nil {
+			w.close()
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+func (w *walTimesWriter) write(t int64) error {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(t))
+	_, err := w.w.Write(buf[:])
+	return err
+}
+
+func (w *walTimesWriter) sync() error {
+	if err := w.w.Flush(); err != nil {
+		return err
+	}
+	return w.f.Sync()
+}
+
+func (w *walTimesWriter) close() error {
+	if err := w.sync(); err != nil {
+		w.f.Close()
+		return err
+	}
+	return w.f.Close()
+}
+
+// readWALTimes returns the times recorded for the entries of a segment.
+func readWALTimes(segment string) ([]int64, error) {
+	b, err := ioutil.ReadFile(walTimesPath(segment))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	times := make([]int64, len(b)/8)
+	for i := range times {
+		times[i] = int64(binary.BigEndian.Uint64(b[i*8:]))
+	}
+	return times, nil
+}
+
+// countWALEntries returns the number of valid entries at the start of a segment.
+func countWALEntries(segment string) (int64, error) {
+	f, err := os.Open(segment)
+	if err != nil {
+		return 0, err
+	}
+
+	r := NewWALSegmentReader(f)
+	defer r.Close()
+
+	var n int64
+	for r.Next() {
+		if _, err := r.Read(); err != nil {
+			break
+		}
+		n++
+	}
+	return n, nil
+}
+
+// openArchive prepares the archive directory and finds the last archived
+// segment ID. Callers must hold the write lock.
+func (l *WAL) openArchive() error {
+	if err := os.MkdirAll(l.archivePath, 0777); err != nil {
+		return err
+	}
+
+	segments, err := ArchivedWALSegments(l.archivePath)
+	if err != nil {
+		return err
+	}
+
+	l.archiveSegmentID = 0
+	if len(segments) > 0 {
+		id, err := idFromFileName(segments[len(segments)-1])
+		if err != nil {
+			return err
+		}
+		l.archiveSegmentID = id
+	}
+	return nil
+}
+
+// archiveSegment copies a closed segment and the times of its entries to the
+// archive directory. Segment IDs restart when a WAL is emptied, so archived
+// segments are numbered independently.
+func (l *WAL) archiveSegment(segment string) error {
+	dst := filepath.Join(l.archivePath, fmt.Sprintf("%s%05d.%s", WALFilePrefix, l.archiveSegmentID+1, WALFileExtension))
+
+	// Copy the times first so an archived segment is never missing them.
+	if err := copyFileSync(walTimesPath(segment), walTimesPath(dst)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := copyFileSync(segment, dst); err != nil {
+		return err
+	}
+
+	l.archiveSegmentID++
+	l.traceLogger.Info("Archived WAL file", zap.String("path", segment), zap.String("archive", dst))
+	return nil
+}
+
+// pruneArchive removes the archived segments whose last entry was written
+// more than the archive retention before now. Callers must hold the write
+// lock.
+func (l *WAL) pruneArchive(now time.Time) error {
+	if l.archiveRetention <= 0 {
+		return nil
+	}
+
+	segments, err := ArchivedWALSegments(l.archivePath)
+	if err != nil {
+		return err
+	}
+
+	cutoff := now.Add(-l.archiveRetention)
+	for _, segment := range segments {
+		t, err := lastWALTime(segment)
+		if err != nil {
+			return err
+		} else if !t.Before(cutoff) {
+			// Segments are archived in the order they were written.
+			return nil
+		}
+
+		// Remove the segment first so a segment is never missing its times.
+		if err := os.Remove(segment); err != nil {
+			return err
+		} else if err := os.Remove(walTimesPath(segment)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		l.traceLogger.Info("Pruned archived WAL file", zap.String("path", segment))
+	}
+	return nil
+}
+
+// lastWALTime returns the time the last entry of an archived segment was
+// written at.
+func lastWALTime(segment string) (time.Time, error) {
+	times, err := readWALTimes(segment)
+	if err != nil {
+		return time.Time{}, err
+	} else if len(times) > 0 {
+		return time.Unix(0, times[len(times)-1]), nil
+	}
+
+	stat, err := os.Stat(segment)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return stat.ModTime(), nil
+}
+
+// copyFileSync copies src to dst through a temporary file which is synced
+// before being renamed into place.
+func copyFileSync(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := dst + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	} else if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	} else if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// ArchivedWALSegments returns the paths of the segments archived in dir,
+// ordered by ID.
+func ArchivedWALSegments(dir string) ([]string, error) {
+	names, err := segmentFileNames(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]int, len(names))
+	for _, name := range names {
+		id, err := idFromFileName(name)
+		if err != nil {
+			return nil, err
+		}
+		ids[name] = id
+	}
+	sort.Slice(names, func(i, j int) bool { return ids[names[i]] < ids[names[j]] })
+	return names, nil
+}
+
+// ReplayWALArchive calls fn with each entry archived in dir, in the order the
+// entries were written, along with the time they were written at. Entries
+// written at or before since are skipped, and the replay stops at the first
+// entry written after until. Entries of segments archived without their
+// times are assumed to have been written when the segment was last modified.
+func ReplayWALArchive(dir string, since, until time.Time, fn func(entry WALEntry, t time.Time) error) error {
+	segments, err := ArchivedWALSegments(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, segment := range segments {
+		done, err := replayWALSegment(segment, since, until, fn)
+		if err != nil {
+			return fmt.Errorf("replay %s: %s", segment, err)
+		} else if done {
+			return nil
+		}
+	}
+	return nil
+}
+
+// replayWALSegment replays the entries of an archived segment. It returns
+// true once an entry written after until is reached.
+func replayWALSegment(segment string, since, until time.Time, fn func(entry WALEntry, t time.Time) error) (bool, error) {
+	times, err := readWALTimes(segment)
+	if err != nil {
+		return false, err
+	}
+
+	f, err := os.Open(segment)
+	if err != nil {
+		return false, err
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return false, err
+	}
+
+	r := NewWALSegmentReader(f)
+	defer r.Close()
+
+	for i := 0; r.Next(); i++ {
+		entry, err := r.Read()
+		if err != nil {
+			return false, err
+		}
+
+		t := stat.ModTime()
+		if i < len(times) {
+			t = time.Unix(0, times[i])
+		}
+
+		if t.After(until) {
+			return true, nil
+		} else if !t.After(since) {
+			continue
+		}
+
+		if err := fn(entry, t.UTC()); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
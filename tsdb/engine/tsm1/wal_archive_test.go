@@ -0,0 +1,208 @@
+package tsm1
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWAL_Archive(t *testing.T) {
+	dir := mustTempDir()
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, "archive")
+
+	w := NewWAL(filepath.Join(dir, "wal"))
+	w.archivePath = archive
+	if err := w.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if _, err := w.WriteMulti(map[string][]Value{"cpu,host=A#!~#value": {NewValue(1, 1.0)}}); err != nil {
+		t.Fatal(err)
+	} else if err := w.CloseSegment(); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	mid := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	if _, err := w.WriteMulti(map[string][]Value{"cpu,host=B#!~#value": {NewValue(2, 2.0)}}); err != nil {
+		t.Fatal(err)
+	} else if _, err := w.DeleteRange([][]byte{[]byte("cpu,host=A#!~#value")}, 0, 10); err != nil {
+		t.Fatal(err)
+	} else if err := w.CloseSegment(); err != nil {
+		t.Fatal(err)
+	}
+
+	closed, err := w.ClosedSegments()
+	if err != nil {
+		t.Fatal(err)
+	} else if err := w.Remove(closed); err != nil {
+		t.Fatal(err)
+	}
+
+	// The removed segments and their times are in the archive.
+	segments, err := ArchivedWALSegments(archive)
+	if err != nil {
+		t.Fatal(err)
+	} else if got, exp := len(segments), 2; got != exp {
+		t.Fatalf("unexpected number of archived segments: got %d, exp %d", got, exp)
+	}
+	for _, segment := range segments {
+		if _, err := os.Stat(walTimesPath(segment)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, segment := range closed {
+		if _, err := os.Stat(walTimesPath(segment)); !os.IsNotExist(err) {
+			t.Fatalf("times of removed segment still present: %v", err)
+		}
+	}
+
+	replay := func(since, until time.Time) []WalEntryType {
+		var types []WalEntryType
+		if err := ReplayWALArchive(archive, since, until, func(entry WALEntry, _ time.Time) error {
+			types = append(types, entry.Type())
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return types
+	}
+
+	if got, exp := replay(time.Time{}, mid), []WalEntryType{WriteWALEntryType}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected entries before %s: got %v, exp %v", mid, got, exp)
+	}
+	if got, exp := replay(mid, time.Now()), []WalEntryType{WriteWALEntryType, DeleteRangeWALEntryType}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected entries after %s: got %v, exp %v", mid, got, exp)
+	}
+
+	// Archived segments keep being numbered after a restart.
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	w = NewWAL(filepath.Join(dir, "wal"))
+	w.archivePath = archive
+	if err := w.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if _, err := w.WriteMulti(map[string][]Value{"cpu,host=C#!~#value": {NewValue(3, 3.0)}}); err != nil {
+		t.Fatal(err)
+	} else if err := w.CloseSegment(); err != nil {
+		t.Fatal(err)
+	}
+	if closed, err := w.ClosedSegments(); err != nil {
+		t.Fatal(err)
+	} else if err := w.Remove(closed); err != nil {
+		t.Fatal(err)
+	}
+
+	if segments, err := ArchivedWALSegments(archive); err != nil {
+		t.Fatal(err)
+	} else if got, exp := filepath.Base(segments[len(segments)-1]), "_00003.wal"; len(segments) != 3 || got != exp {
+		t.Fatalf("unexpected archived segments: %v", segments)
+	}
+}
+
+func TestWAL_Archive_RecoverTimes(t *testing.T) {
+	dir := mustTempDir()
+	defer os.RemoveAll(dir)
+
+	w := NewWAL(dir)
+	w.archivePath = filepath.Join(dir, "archive")
+	if err := w.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := w.WriteMulti(map[string][]Value{"cpu#!~#value": {NewValue(int64(i), float64(i))}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	segment := w.currentSegmentWriter.path()
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a crash before the time of the last entry was written.
+	if err := os.Truncate(walTimesPath(segment), 8); err != nil {
+		t.Fatal(err)
+	}
+
+	w = NewWAL(dir)
+	w.archivePath = filepath.Join(dir, "archive")
+	if err := w.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.WriteMulti(map[string][]Value{"cpu#!~#value": {NewValue(2, 2.0)}}); err != nil {
+		t.Fatal(err)
+	} else if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if times, err := readWALTimes(segment); err != nil {
+		t.Fatal(err)
+	} else if got, exp := len(times), 3; got != exp {
+		t.Fatalf("unexpected number of times: got %d, exp %d", got, exp)
+	}
+}
+
+func TestWAL_Archive_Prune(t *testing.T) {
+	dir := mustTempDir()
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, "archive")
+
+	w := NewWAL(filepath.Join(dir, "wal"))
+	w.archivePath = archive
+	w.archiveRetention = time.Hour
+	if err := w.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	archiveSegment := func() {
+		t.Helper()
+		if _, err := w.WriteMulti(map[string][]Value{"cpu#!~#value": {NewValue(1, 1.0)}}); err != nil {
+			t.Fatal(err)
+		} else if err := w.CloseSegment(); err != nil {
+			t.Fatal(err)
+		}
+		if closed, err := w.ClosedSegments(); err != nil {
+			t.Fatal(err)
+		} else if err := w.Remove(closed); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archiveSegment()
+	archiveSegment()
+	segments, err := ArchivedWALSegments(archive)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(segments) != 2 {
+		t.Fatalf("unexpected archived segments: %v", segments)
+	}
+
+	// Make the first segment older than the retention. Its times file is
+	// removed to check the segment's modification time is used instead.
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Remove(walTimesPath(segments[0])); err != nil {
+		t.Fatal(err)
+	} else if err := os.Chtimes(segments[0], old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	archiveSegment()
+	if got, err := ArchivedWALSegments(archive); err != nil {
+		t.Fatal(err)
+	} else if exp := []string{segments[1], filepath.Join(archive, "_00003.wal")}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected archived segments: got %v, exp %v", got, exp)
+	}
+}
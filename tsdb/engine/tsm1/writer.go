@@ -52,14 +52,28 @@ file, we can use the size to determine how much to read in a given IO.
 │ 2 bytes │ N bytes │1 byte│2 bytes│ 8 bytes │ 8 bytes │8 bytes │4 bytes │   │
 └─────────┴─────────┴──────┴───────┴─────────┴─────────┴────────┴────────┴───┘
 
-The last section is the footer that stores the offset of the start of the index.
+Version 2 files follow the index with a summary section.  It holds the count,
+sum, min and max of the values of every float, integer and unsigned block,
+ordered by the offset of the block, so aggregates over whole blocks can be
+answered without decoding them.  Sum, Min and Max hold the bits of the float64,
+int64 or uint64 values depending on the type of the block.
+
+┌─────────────────────────────────────────────────────────┐
+│                        Summaries                        │
+├─────────┬─────────┬─────────┬─────────┬─────────┬───────┤
+│ Offset  │  Count  │   Sum   │   Min   │   Max   │  ...  │
+│ 8 bytes │ 8 bytes │ 8 bytes │ 8 bytes │ 8 bytes │       │
+└─────────┴─────────┴─────────┴─────────┴─────────┴───────┘
 
-┌─────────┐
-│ Footer  │
-├─────────┤
-│Index Ofs│
-│ 8 bytes │
-└─────────┘
+The last section is the footer that stores the offset of the start of the index.
+Version 2 files also store the offset of the start of the summaries.
+
+┌─────────┐   ┌───────────────────────┐
+│ Footer  │   │   Footer (version 2)  │
+├─────────┤   ├───────────┬───────────┤
+│Index Ofs│   │Summary Ofs│ Index Ofs │
+│ 8 bytes │   │  8 bytes  │  8 bytes  │
+└─────────┘   └───────────┴───────────┘
 */
 
 import (
@@ -80,8 +94,12 @@ const (
 	// identify the file as a tsm1 formatted file
 	MagicNumber uint32 = 0x16D116D1
 
-	// Version indicates the version of the TSM file format.
-	Version byte = 1
+	// Version indicates the version of the TSM file format.  Releases
+	// before version 2 can't read version 2 files.
+	Version byte = 2
+
+	// version1 is the version of TSM files written without block summaries.
+	version1 byte = 1
 
 	// Size in bytes of an index entry
 	indexEntrySize = 28
@@ -92,6 +110,9 @@ const (
 	// Size in bytes used to store the type of block encoded
 	indexTypeSize = 1
 
+	// Size in bytes of a block summary
+	blockSummarySize = 40
+
 	// Max number of blocks for a given key that can exist in a single file
 	maxIndexEntries = (1 << (indexCountSize * 8)) - 1
 
@@ -136,6 +157,11 @@ type TSMWriter interface {
 	// timestamp values are used as the minimum and maximum values for the index entry.
 	WriteBlock(key []byte, minTime, maxTime int64, block []byte) error
 
+	// WriteBlockSummary writes a block like WriteBlock, using summary as the
+	// summary of the block instead of decoding the block to summarize it.
+	// It is used to copy blocks along with their summary from other files.
+	WriteBlockSummary(key []byte, minTime, maxTime int64, block []byte, summary BlockSummary) error
+
 	// WriteIndex finishes the TSM write streams and writes the index.
 	WriteIndex() error
 
@@ -547,6 +573,9 @@ type tsmWriter struct {
 	index   IndexWriter
 	n       int64
 
+	// summaries holds the encoded summaries of the blocks written so far.
+	summaries []byte
+
 	// The bytes written count of when we last fsync'd
 	lastSync int64
 }
@@ -634,6 +663,10 @@ func (t *tsmWriter) Write(key []byte, values Values) error {
 	// Record this block in index
 	t.index.Add(key, blockType, values[0].UnixNano(), values[len(values)-1].UnixNano(), t.n, uint32(n))
 
+	if summary, ok := summarizeValues(values); ok {
+		t.summaries = summary.appendTo(t.summaries, t.n)
+	}
+
 	// Increment file position pointer
 	t.n += int64(n)
 
@@ -648,6 +681,18 @@ func (t *tsmWriter) Write(key []byte, values Values) error {
 // exceeds max entries for a given key, ErrMaxBlocksExceeded is returned.  This indicates
 // that the index is now full for this key and no future writes to this key will succeed.
 func (t *tsmWriter) WriteBlock(key []byte, minTime, maxTime int64, block []byte) error {
+	return t.writeBlock(key, minTime, maxTime, block, nil)
+}
+
+// WriteBlockSummary writes block for the given key and time range to the TSM file along
+// with its summary.
+func (t *tsmWriter) WriteBlockSummary(key []byte, minTime, maxTime int64, block []byte, summary BlockSummary) error {
+	return t.writeBlock(key, minTime, maxTime, block, &summary)
+}
+
+// writeBlock writes block to the TSM file.  The block is decoded to summarize it if
+// summary is nil.
+func (t *tsmWriter) writeBlock(key []byte, minTime, maxTime int64, block []byte, summary *BlockSummary) error {
 	if len(key) > maxKeyLength {
 		return ErrMaxKeyLengthExceeded
 	}
@@ -686,6 +731,20 @@ func (t *tsmWriter) WriteBlock(key []byte, minTime, maxTime int64, block []byte)
 	// Record this block in index
 	t.index.Add(key, blockType, minTime, maxTime, t.n, uint32(n))
 
+	// Blocks copied from files without a summary for them are decoded to
+	// summarize them.
+	if summary == nil {
+		s, ok, err := summarizeBlock(blockType, block)
+		if err != nil {
+			return err
+		} else if ok {
+			summary = &s
+		}
+	}
+	if summary != nil {
+		t.summaries = summary.appendTo(t.summaries, t.n)
+	}
+
 	// Increment file position pointer (checksum + block len)
 	t.n += int64(n)
 
@@ -720,15 +779,23 @@ func (t *tsmWriter) WriteIndex() error {
 	}
 
 	// Write the index
-	if _, err := t.index.WriteTo(t.w); err != nil {
+	n, err := t.index.WriteTo(t.w)
+	if err != nil {
 		return err
 	}
+	summaryPos := indexPos + n
 
-	var buf [8]byte
-	binary.BigEndian.PutUint64(buf[:], uint64(indexPos))
+	// Write the block summaries
+	if _, err := t.w.Write(t.summaries); err != nil {
+		return err
+	}
 
-	// Write the index index position
-	_, err := t.w.Write(buf[:])
+	var buf [16]byte
+	binary.BigEndian.PutUint64(buf[:8], uint64(summaryPos))
+	binary.BigEndian.PutUint64(buf[8:], uint64(indexPos))
+
+	// Write the summary and index positions
+	_, err = t.w.Write(buf[:])
 	return err
 }
 
@@ -795,31 +862,31 @@ func (t *tsmWriter) Remove() error {
 }
 
 func (t *tsmWriter) Size() uint32 {
-	return uint32(t.n) + t.index.Size()
+	return uint32(t.n) + t.index.Size() + uint32(len(t.summaries))
 }
 
 // verifyVersion verifies that the reader's bytes are a TSM byte
-// stream of the correct version (1)
-func verifyVersion(r io.ReadSeeker) error {
+// stream of a supported version (1 or 2) and returns the version.
+func verifyVersion(r io.ReadSeeker) (byte, error) {
 	_, err := r.Seek(0, 0)
 	if err != nil {
-		return fmt.Errorf("init: failed to seek: %v", err)
+		return 0, fmt.Errorf("init: failed to seek: %v", err)
 	}
 	var b [4]byte
 	_, err = io.ReadFull(r, b[:])
 	if err != nil {
-		return fmt.Errorf("init: error reading magic number of file: %v", err)
+		return 0, fmt.Errorf("init: error reading magic number of file: %v", err)
 	}
 	if binary.BigEndian.Uint32(b[:]) != MagicNumber {
-		return fmt.Errorf("can only read from tsm file")
+		return 0, fmt.Errorf("can only read from tsm file")
 	}
 	_, err = io.ReadFull(r, b[:1])
 	if err != nil {
-		return fmt.Errorf("init: error reading version: %v", err)
+		return 0, fmt.Errorf("init: error reading version: %v", err)
 	}
-	if b[0] != Version {
-		return fmt.Errorf("init: file is version %b. expected %b", b[0], Version)
+	if b[0] != Version && b[0] != version1 {
+		return 0, fmt.Errorf("init: file is version %b. expected %b", b[0], Version)
 	}
 
-	return nil
+	return b[0], nil
 }
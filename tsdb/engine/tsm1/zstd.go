@@ -0,0 +1,399 @@
+package tsm1
+
+// Zstd encoding compresses the values of string and float blocks with zstd.
+// String blocks compress the same buffer of variable byte length prefixed
+// strings that the snappy encoding uses.  Float blocks compress the 8 bytes of
+// each value grouped by significance: the most significant byte of every value
+// comes first, followed by the next most significant byte of every value and so
+// on, so the sign and exponent bytes, which rarely change, end up next to each
+// other.  A 1 byte header indicates the type of encoding in both cases.
+//
+// A shard may have a dictionary trained from the values of its own blocks, which
+// is stored in the shard directory.  Frames compressed with a dictionary record
+// the ID of the dictionary, so the dictionary must be registered with
+// LoadZstdDict before these blocks can be decoded.  Each dictionary has its
+// own decoder, which is chosen by the ID recorded in the frame.
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/cespare/xxhash"
+	"github.com/influxdata/influxdb/pkg/file"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// ZstdDictFile is the name of the file holding the zstd dictionary of a shard.
+	ZstdDictFile = "zstd.dict"
+
+	// zstdDictMagic is the magic number of zstd dictionaries.
+	zstdDictMagic = 0xEC30A437
+
+	// zstdDictSampleSize is the amount of uncompressed block data sampled
+	// before a dictionary is trained.
+	zstdDictSampleSize = 1 << 20
+
+	// zstdDictSize is the maximum size of the content of a trained dictionary.
+	zstdDictSize = 64 << 10
+
+	// zstdDictMinID is the lowest dictionary ID that is not reserved by zstd.
+	zstdDictMinID = 1 << 15
+)
+
+// Names of the codecs of the values of string and float blocks.
+const (
+	StringCodecSnappy = "snappy"
+	FloatCodecGorilla = "gorilla"
+	CodecZstd         = "zstd"
+)
+
+// zstdEncoder compresses blocks without a dictionary.
+var zstdEncoder, _ = zstd.NewWriter(nil)
+
+// zstdDecoder decompresses blocks without a dictionary.
+var zstdDecoder, _ = zstd.NewReader(nil)
+
+// zstdDicts holds the registered dictionaries by ID.  Every user of the
+// dictionary of a shard registers it, and it is removed once the last of them
+// unregisters it when the shard is closed or deleted.
+var zstdDicts = struct {
+	mu    sync.RWMutex
+	dicts map[uint32]*registeredZstdDict
+}{dicts: make(map[uint32]*registeredZstdDict)}
+
+// registeredZstdDict is a registered dictionary along with a decoder using it.
+type registeredZstdDict struct {
+	b    []byte
+	dec  *zstd.Decoder
+	refs int
+}
+
+// zstdFrameDecoder returns a decoder for the frame in b, using the dictionary
+// the frame was compressed with.
+func zstdFrameDecoder(b []byte) (*zstd.Decoder, error) {
+	var h zstd.Header
+	if err := h.Decode(b); err != nil {
+		return nil, err
+	} else if h.DictionaryID == 0 {
+		return zstdDecoder, nil
+	}
+
+	zstdDicts.mu.RLock()
+	d := zstdDicts.dicts[h.DictionaryID]
+	zstdDicts.mu.RUnlock()
+	if d == nil {
+		return nil, fmt.Errorf("zstd dictionary %d is not registered", h.DictionaryID)
+	}
+	return d.dec, nil
+}
+
+// zstdDictID returns the ID of the dictionary in b.
+func zstdDictID(b []byte) (uint32, error) {
+	if len(b) < 8 || binary.LittleEndian.Uint32(b[:4]) != zstdDictMagic {
+		return 0, fmt.Errorf("invalid zstd dictionary")
+	}
+	return binary.LittleEndian.Uint32(b[4:8]), nil
+}
+
+// registerZstdDict makes the dictionary in b available to the decoder and
+// returns its ID.  Registering the same dictionary again only counts one more
+// reference to it, which must be released with unregisterZstdDict.
+func registerZstdDict(b []byte) (uint32, error) {
+	id, err := zstdDictID(b)
+	if err != nil {
+		return 0, err
+	}
+
+	zstdDicts.mu.Lock()
+	defer zstdDicts.mu.Unlock()
+
+	if d, ok := zstdDicts.dicts[id]; ok {
+		if !bytes.Equal(d.b, b) {
+			return 0, fmt.Errorf("zstd dictionary %d is already registered with different content", id)
+		}
+		d.refs++
+		return id, nil
+	}
+
+	dec, err := zstd.NewReader(nil, zstd.WithDecoderDicts(b))
+	if err != nil {
+		return 0, err
+	}
+	zstdDicts.dicts[id] = &registeredZstdDict{b: b, dec: dec, refs: 1}
+	return id, nil
+}
+
+// unregisterZstdDict releases a reference to the dictionary with the given
+// ID and removes the dictionary once it has no references left.
+func unregisterZstdDict(id uint32) {
+	zstdDicts.mu.Lock()
+	defer zstdDicts.mu.Unlock()
+
+	d, ok := zstdDicts.dicts[id]
+	if !ok {
+		return
+	}
+	if d.refs--; d.refs == 0 {
+		// The decoder is only used with DecodeAll, which starts no
+		// goroutines, so it is left to the garbage collector rather than
+		// closed while a block may still be decoded with it.
+		delete(zstdDicts.dicts, id)
+	}
+}
+
+// zstdDict is a registered dictionary along with an encoder using it.
+type zstdDict struct {
+	id  uint32
+	enc *zstd.Encoder
+}
+
+// newZstdDict registers the dictionary in b and returns an encoder using it.
+// The dictionary must be released once it is no longer used.
+func newZstdDict(b []byte) (*zstdDict, error) {
+	id, err := registerZstdDict(b)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderDict(b))
+	if err != nil {
+		unregisterZstdDict(id)
+		return nil, err
+	}
+	return &zstdDict{id: id, enc: enc}, nil
+}
+
+// release unregisters the dictionary.
+func (d *zstdDict) release() {
+	unregisterZstdDict(d.id)
+}
+
+// LoadZstdDict registers the zstd dictionary of the shard in dir, if it has
+// one, so the blocks compressed with it can be decoded.  It returns the ID of
+// the dictionary, or 0 if the shard has none, which must be passed to
+// UnloadZstdDict once the shard is closed.
+func LoadZstdDict(dir string) (uint32, error) {
+	b, err := readZstdDict(dir)
+	if err != nil || b == nil {
+		return 0, err
+	}
+
+	id, err := registerZstdDict(b)
+	if err != nil {
+		return 0, fmt.Errorf("cannot load %s: %v", filepath.Join(dir, ZstdDictFile), err)
+	}
+	return id, nil
+}
+
+// UnloadZstdDict unregisters a dictionary registered by LoadZstdDict.
+func UnloadZstdDict(id uint32) {
+	if id != 0 {
+		unregisterZstdDict(id)
+	}
+}
+
+// loadZstdDict registers the zstd dictionary of the shard in dir and returns
+// an encoder using it.  It returns nil if the shard has no dictionary.
+func loadZstdDict(dir string) (*zstdDict, error) {
+	b, err := readZstdDict(dir)
+	if err != nil || b == nil {
+		return nil, err
+	}
+
+	d, err := newZstdDict(b)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load %s: %v", filepath.Join(dir, ZstdDictFile), err)
+	}
+	return d, nil
+}
+
+// readZstdDict returns the zstd dictionary of the shard in dir, or nil if the
+// shard has none.
+func readZstdDict(dir string) ([]byte, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, ZstdDictFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return b, err
+}
+
+// writeZstdDict atomically writes the dictionary in b to the shard in dir.  It
+// fails if the shard already has a dictionary, as blocks may be compressed with it.
+func writeZstdDict(dir string, b []byte) error {
+	path := filepath.Join(dir, ZstdDictFile)
+	tmp := path + "." + TmpTSMFileExtension
+
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	// Link rather than rename the file into place so an existing dictionary
+	// is never replaced.
+	err = os.Link(tmp, path)
+	os.Remove(tmp)
+	if err != nil {
+		return err
+	}
+	return file.SyncDir(dir)
+}
+
+// trainZstdDict returns a dictionary trained from samples of uncompressed
+// block data.  The content of the dictionary is made of an equal share of each
+// sample and its ID is derived from that content.
+func trainZstdDict(samples [][]byte) ([]byte, error) {
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no samples to train zstd dictionary")
+	}
+
+	share := zstdDictSize / len(samples)
+	if share == 0 {
+		share = 1
+	}
+	var hist []byte
+	for _, s := range samples {
+		if len(hist)+share > zstdDictSize {
+			break
+		}
+		if len(s) > share {
+			s = s[:share]
+		}
+		hist = append(hist, s...)
+	}
+
+	id := zstdDictMinID + uint32(xxhash.Sum64(hist)%(math.MaxInt32-zstdDictMinID))
+	return zstd.BuildDict(zstd.BuildDictOptions{
+		ID:       id,
+		Contents: samples,
+		History:  hist,
+		Offsets:  [3]int{1, 4, 8},
+	})
+}
+
+// zstdSampler collects uncompressed block data for training a dictionary.
+type zstdSampler struct {
+	samples [][]byte
+	size    int
+}
+
+// add adds a copy of b to the samples and returns true once enough data has
+// been sampled to train a dictionary.
+func (s *zstdSampler) add(b []byte) bool {
+	if len(b) > 0 {
+		s.samples = append(s.samples, append([]byte(nil), b...))
+		s.size += len(b)
+	}
+	return s.size >= zstdDictSampleSize
+}
+
+// appendZstd appends the zstd compressed payload of a block of type typ to b.
+func appendZstd(b []byte, typ byte, payload []byte, enc *zstd.Encoder) []byte {
+	switch typ {
+	case BlockFloat64:
+		b = append(b, floatCompressedZstd<<4)
+	case BlockString:
+		b = append(b, stringCompressedZstd<<4)
+	}
+	return enc.EncodeAll(payload, b)
+}
+
+// decodeZstd decompresses the values of a zstd encoded block, skipping the
+// header byte.
+func decodeZstd(b []byte, dst []byte) ([]byte, error) {
+	if len(b) < 1 {
+		return nil, fmt.Errorf("zstd block too short")
+	}
+	dec, err := zstdFrameDecoder(b[1:])
+	if err != nil {
+		return nil, err
+	}
+	return dec.DecodeAll(b[1:], dst[:0])
+}
+
+// shuffleFloats appends the bytes of the values of src to dst grouped by
+// significance.
+func shuffleFloats(dst []byte, src []float64) ([]byte, error) {
+	n := len(src)
+	sz := len(dst)
+	if cap(dst)-sz < 8*n {
+		b := make([]byte, sz, sz+8*n)
+		copy(b, dst)
+		dst = b
+	}
+	dst = dst[:sz+8*n]
+	planes := dst[sz:]
+
+	for i, v := range src {
+		if math.IsNaN(v) {
+			return nil, fmt.Errorf("unsupported value: NaN")
+		}
+		u := math.Float64bits(v)
+		for j := 0; j < 8; j++ {
+			planes[j*n+i] = byte(u >> uint(56-8*j))
+		}
+	}
+	return dst, nil
+}
+
+// unshuffleFloats decodes the values grouped by significance in src into dst.
+func unshuffleFloats(dst []float64, src []byte) ([]float64, error) {
+	if len(src)%8 != 0 {
+		return nil, fmt.Errorf("invalid zstd float block length: %d", len(src))
+	}
+
+	n := len(src) / 8
+	if cap(dst) < n {
+		dst = make([]float64, n)
+	} else {
+		dst = dst[:n]
+	}
+
+	for i := range dst {
+		var u uint64
+		for j := 0; j < 8; j++ {
+			u = u<<8 | uint64(src[j*n+i])
+		}
+		dst[i] = math.Float64frombits(u)
+	}
+	return dst, nil
+}
+
+// FloatArrayEncodeZstd encodes src into b with zstd, returning b and any error
+// encountered.
+func FloatArrayEncodeZstd(src []float64, b []byte) ([]byte, error) {
+	payload, err := shuffleFloats(nil, src)
+	if err != nil {
+		return nil, err
+	}
+	return appendZstd(b[:0], BlockFloat64, payload, zstdEncoder), nil
+}
+
+// StringArrayEncodeZstd encodes src into b with zstd, returning b and any error
+// encountered.
+func StringArrayEncodeZstd(src []string, b []byte) ([]byte, error) {
+	var payload []byte
+	var buf [binary.MaxVarintLen64]byte
+	for _, s := range src {
+		n := binary.PutUvarint(buf[:], uint64(len(s)))
+		payload = append(payload, buf[:n]...)
+		payload = append(payload, s...)
+	}
+	return appendZstd(b[:0], BlockString, payload, zstdEncoder), nil
+}
@@ -0,0 +1,172 @@
+package tsm1
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func Test_FloatArrayEncodeZstd_Quick(t *testing.T) {
+	quick.Check(func(values []float64) bool {
+		var exp []float64
+		for _, v := range values {
+			if !math.IsNaN(v) {
+				exp = append(exp, v)
+			}
+		}
+
+		b, err := FloatArrayEncodeZstd(exp, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := b[0] >> 4; got != floatCompressedZstd {
+			t.Fatalf("unexpected encoding: got %d, exp %d", got, floatCompressedZstd)
+		}
+
+		got, err := FloatArrayDecodeAll(b, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(got) != len(exp) || (len(exp) > 0 && !reflect.DeepEqual(got, exp)) {
+			t.Fatalf("unexpected values: got %v, exp %v", got, exp)
+		}
+
+		var dec FloatDecoder
+		if err := dec.SetBytes(b); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got = got[:0]
+		for dec.Next() {
+			got = append(got, dec.Values())
+		}
+		if err := dec.Error(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(got) != len(exp) || (len(exp) > 0 && !reflect.DeepEqual(got, exp)) {
+			t.Fatalf("unexpected values: got %v, exp %v", got, exp)
+		}
+		return true
+	}, nil)
+}
+
+func TestFloatArrayEncodeZstd_NaN(t *testing.T) {
+	if _, err := FloatArrayEncodeZstd([]float64{1.0, math.NaN()}, nil); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func Test_StringArrayEncodeZstd_Quick(t *testing.T) {
+	quick.Check(func(values []string) bool {
+		b, err := StringArrayEncodeZstd(values, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := b[0] >> 4; got != stringCompressedZstd {
+			t.Fatalf("unexpected encoding: got %d, exp %d", got, stringCompressedZstd)
+		}
+
+		got, err := StringArrayDecodeAll(b, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(got) != len(values) || (len(values) > 0 && !reflect.DeepEqual(got, values)) {
+			t.Fatalf("unexpected values: got %q, exp %q", got, values)
+		}
+
+		var dec StringDecoder
+		if err := dec.SetBytes(b); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got = got[:0]
+		for dec.Next() {
+			got = append(got, dec.Read())
+		}
+		if err := dec.Error(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(got) != len(values) || (len(values) > 0 && !reflect.DeepEqual(got, values)) {
+			t.Fatalf("unexpected values: got %q, exp %q", got, values)
+		}
+		return true
+	}, nil)
+}
+
+func TestZstdDict(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tsm1-zstd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var s zstdSampler
+	var payloads [][]byte
+	for i := 0; ; i++ {
+		var payload []byte
+		for j := 0; j < 100; j++ {
+			payload = append(payload, fmt.Sprintf("level=info msg=\"request served\" path=/api/v%d/items/%d status=200\n", i%3, i*100+j)...)
+		}
+		payloads = append(payloads, payload)
+		if s.add(payload) {
+			break
+		}
+	}
+
+	b, err := trainZstdDict(s.samples)
+	if err != nil {
+		t.Fatalf("unexpected error training dictionary: %v", err)
+	}
+	if err := writeZstdDict(dir, b); err != nil {
+		t.Fatalf("unexpected error writing dictionary: %v", err)
+	}
+	if err := writeZstdDict(dir, b); !os.IsExist(err) {
+		t.Fatalf("expected error replacing dictionary, got %v", err)
+	}
+
+	d, err := loadZstdDict(dir)
+	if err != nil {
+		t.Fatalf("unexpected error loading dictionary: %v", err)
+	}
+
+	block := appendZstd(nil, BlockString, payloads[0], d.enc)
+	var h zstd.Header
+	if err := h.Decode(block[1:]); err != nil {
+		t.Fatalf("unexpected error decoding frame header: %v", err)
+	}
+	if exp, _ := zstdDictID(b); h.DictionaryID != exp {
+		t.Fatalf("unexpected dictionary ID: got %d, exp %d", h.DictionaryID, exp)
+	}
+	if n := len(appendZstd(nil, BlockString, payloads[0], zstdEncoder)); len(block) >= n {
+		t.Fatalf("dictionary did not improve compression: got %d bytes, %d without dictionary", len(block), n)
+	}
+
+	got, err := decodeZstd(block, nil)
+	if err != nil {
+		t.Fatalf("unexpected error decoding: %v", err)
+	}
+	if !reflect.DeepEqual(got, payloads[0]) {
+		t.Fatalf("unexpected payload: got %q, exp %q", got, payloads[0])
+	}
+
+	// The dictionary stays registered until every user released it.
+	id, err := LoadZstdDict(dir)
+	if err != nil {
+		t.Fatalf("unexpected error loading dictionary: %v", err)
+	}
+	d.release()
+	if _, err := decodeZstd(block, nil); err != nil {
+		t.Fatalf("unexpected error decoding: %v", err)
+	}
+	UnloadZstdDict(id)
+	if _, err := decodeZstd(block, nil); err == nil {
+		t.Fatal("expected error decoding with an unregistered dictionary")
+	}
+	if _, ok := zstdDicts.dicts[id]; ok {
+		t.Fatal("expected dictionary to be removed")
+	}
+}
@@ -40,6 +40,7 @@ const (
 	statWritePointsErr     = "writePointsErr"
 	statWritePointsDropped = "writePointsDropped"
 	statWritePointsOK      = "writePointsOk"
+	statWritePointsBulk    = "writePointsBulk"
 	statWriteBytes         = "writeBytes"
 	statDiskBytes          = "diskBytes"
 )
@@ -140,6 +141,9 @@ type Shard struct {
 	index   Index
 	enabled bool
 
+	// Cached series counts enforcing max-series-per-measurement.
+	measurementSeries measurementSeriesCounts
+
 	// expvar-based stats.
 	stats       *ShardStatistics
 	defaultTags models.StatisticTags
@@ -246,6 +250,7 @@ type ShardStatistics struct {
 	WritePointsErr     int64
 	WritePointsDropped int64
 	WritePointsOK      int64
+	WritePointsBulk    int64
 	BytesWritten       int64
 	DiskBytes          int64
 }
@@ -283,6 +288,7 @@ func (s *Shard) Statistics(tags map[string]string) []models.Statistic {
 			statWritePointsErr:     atomic.LoadInt64(&s.stats.WritePointsErr),
 			statWritePointsDropped: atomic.LoadInt64(&s.stats.WritePointsDropped),
 			statWritePointsOK:      atomic.LoadInt64(&s.stats.WritePointsOK),
+			statWritePointsBulk:    atomic.LoadInt64(&s.stats.WritePointsBulk),
 			statWriteBytes:         atomic.LoadInt64(&s.stats.BytesWritten),
 			statDiskBytes:          atomic.LoadInt64(&s.stats.DiskBytes),
 		},
@@ -325,7 +331,9 @@ func (s *Shard) Open() error {
 		s.index = idx
 
 		// Initialize underlying engine.
-		e, err := NewEngine(s.id, idx, s.path, s.walPath, s.sfile, s.options)
+		opt := s.options
+		opt.BulkLoadCommitted = s.bulkLoadCommitted
+		e, err := NewEngine(s.id, idx, s.path, s.walPath, s.sfile, opt)
 		if err != nil {
 			return err
 		}
@@ -493,8 +501,21 @@ type FieldCreate struct {
 }
 
 // WritePoints will write the raw data points and any new metadata to the index in the shard.
+// Points are bulk loaded if bulk-load-backfill is enabled and they are all older than the
+// newest TSM file of the shard.
 // write to shard
 func (s *Shard) WritePoints(points []models.Point) error {
+	return s.writePoints(points, false)
+}
+
+// WritePointsBulk writes the raw data points like WritePoints, except that the points are
+// bulk loaded into new TSM files instead of going through the cache and the WAL.  The points
+// are neither queryable nor durable until the bulk load is committed; see Config.
+func (s *Shard) WritePointsBulk(points []models.Point) error {
+	return s.writePoints(points, true)
+}
+
+func (s *Shard) writePoints(points []models.Point, bulk bool) error {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -527,13 +548,26 @@ func (s *Shard) WritePoints(points []models.Point) error {
 
 	// Write to the engine.
 	// 写入tsm engine
-	if err := engine.WritePoints(points); err != nil {
+	write := engine.WritePoints
+	if !bulk && s.options.Config.BulkLoadBackfill {
+		bulk = engine.IsBackfill(points)
+	}
+	if bulk {
+		write = engine.WritePointsBulk
+	}
+	if err := write(points); err != nil {
 		atomic.AddInt64(&s.stats.WritePointsErr, int64(len(points)))
 		atomic.AddInt64(&s.stats.WriteReqErr, 1)
 		return fmt.Errorf("engine: %s", err)
 	}
 	atomic.AddInt64(&s.stats.WritePointsOK, int64(len(points)))
 	atomic.AddInt64(&s.stats.WriteReqOK, 1)
+	if bulk {
+		atomic.AddInt64(&s.stats.WritePointsBulk, int64(len(points)))
+	} else {
+		// Bulk loaded points are reported by bulkLoadCommitted.
+		s.pointsWritten(points)
+	}
 
 	return writeError
 }
@@ -592,6 +626,27 @@ func (s *Shard) validateSeriesAndFields(points []models.Point) ([]models.Point,
 		return nil, nil, err
 	}
 
+	// Drop points creating series beyond the series limits. The cached series
+	// counts stay locked until the admitted series are created, so that only
+	// the series accepted by the index are counted.
+	if s.seriesLimited() {
+		s.measurementSeries.mu.Lock()
+		defer s.measurementSeries.mu.Unlock()
+	}
+	var limited int
+	var limitReason string
+	var newSeries map[string][]byte
+	points, keys, names, tagsSlice, newSeries, limited, limitReason, err = s.limitNewSeries(points, keys, names, tagsSlice)
+	if err != nil {
+		return nil, nil, err
+	} else if limited > 0 {
+		dropped += limited
+		if reason == "" {
+			reason = limitReason
+		}
+		atomic.AddInt64(&s.stats.WritePointsDropped, int64(limited))
+	}
+
 	// Add new series. Check for partial writes.
 	var droppedKeys [][]byte
 	// create series if not exists
@@ -609,6 +664,7 @@ func (s *Shard) validateSeriesAndFields(points []models.Point) ([]models.Point,
 			return nil, nil, err
 		}
 	}
+	s.measurementSeries.add(newSeries, droppedKeys)
 
 	j = 0
 	for i, p := range points {
@@ -729,7 +785,12 @@ func (s *Shard) DeleteSeriesRange(itr SeriesIterator, min, max int64) error {
 	if err != nil {
 		return err
 	}
-	return engine.DeleteSeriesRange(itr, min, max)
+	defer s.measurementSeries.reset()
+	if err := engine.DeleteSeriesRange(itr, min, max); err != nil {
+		return err
+	}
+	s.pointsDeleted(min, max)
+	return nil
 }
 
 // DeleteSeriesRangeWithPredicate deletes all values from for seriesKeys between min and max (inclusive)
@@ -739,7 +800,12 @@ func (s *Shard) DeleteSeriesRangeWithPredicate(itr SeriesIterator, predicate fun
 	if err != nil {
 		return err
 	}
-	return engine.DeleteSeriesRangeWithPredicate(itr, predicate)
+	defer s.measurementSeries.reset()
+	if err := engine.DeleteSeriesRangeWithPredicate(itr, predicate); err != nil {
+		return err
+	}
+	s.pointsDeleted(influxql.MinTime, influxql.MaxTime)
+	return nil
 }
 
 // DeleteMeasurement deletes a measurement and all underlying series.
@@ -748,7 +814,47 @@ func (s *Shard) DeleteMeasurement(name []byte) error {
 	if err != nil {
 		return err
 	}
-	return engine.DeleteMeasurement(name)
+	defer s.measurementSeries.reset()
+	if err := engine.DeleteMeasurement(name); err != nil {
+		return err
+	}
+	s.pointsDeleted(influxql.MinTime, influxql.MaxTime)
+	return nil
+}
+
+// pointsWritten notifies the shard observer of the time range of points
+// written to the shard.
+func (s *Shard) pointsWritten(points []models.Point) {
+	obs := s.options.ShardObserver
+	if obs == nil || len(points) == 0 {
+		return
+	}
+
+	min, max := points[0].UnixNano(), points[0].UnixNano()
+	for _, p := range points[1:] {
+		if t := p.UnixNano(); t < min {
+			min = t
+		} else if t > max {
+			max = t
+		}
+	}
+	obs.PointsWritten(s.database, min, max)
+}
+
+// bulkLoadCommitted notifies the shard observer of the time range of points
+// bulk loaded into the shard, which only become queryable once committed.
+func (s *Shard) bulkLoadCommitted(min, max int64) {
+	if obs := s.options.ShardObserver; obs != nil {
+		obs.PointsWritten(s.database, min, max)
+	}
+}
+
+// pointsDeleted notifies the shard observer of the time range of points
+// deleted from the shard.
+func (s *Shard) pointsDeleted(min, max int64) {
+	if obs := s.options.ShardObserver; obs != nil {
+		obs.PointsDeleted(s.database, min, max)
+	}
 }
 
 // SeriesN returns the unique number of series in the shard.
@@ -1166,6 +1272,16 @@ func (s *Shard) Digest() (io.ReadCloser, int64, error) {
 	return engine.Digest()
 }
 
+// Repair rewrites the files of the shard without the blocks whose checksum
+// does not match their content and returns the blocks that were removed.
+func (s *Shard) Repair() ([]CorruptBlock, error) {
+	engine, err := s.Engine()
+	if err != nil {
+		return nil, err
+	}
+	return engine.Repair()
+}
+
 // engine safely (under an RLock) returns a reference to the shard's Engine, or
 // an error if the Engine is closed, or the shard is currently disabled.
 //
@@ -1308,6 +1424,12 @@ func (a Shards) CreateIterator(ctx context.Context, measurement *influxql.Measur
 		return a.createSeriesIterator(ctx, opt)
 	}
 
+	// Report the shards read to the query, if it is tracked.
+	progress := query.ProgressFromContext(ctx)
+	if progress != nil {
+		progress.AddShards(len(a))
+	}
+
 	itrs := make([]query.Iterator, 0, len(a))
 	for _, sh := range a {
 		itr, err := sh.CreateIterator(ctx, measurement, opt)
@@ -1315,8 +1437,14 @@ func (a Shards) CreateIterator(ctx context.Context, measurement *influxql.Measur
 			query.Iterators(itrs).Close()
 			return nil, err
 		} else if itr == nil {
+			if progress != nil {
+				progress.CompleteShard()
+			}
 			continue
 		}
+		if progress != nil {
+			itr = query.NewProgressIterator(itr, progress)
+		}
 		itrs = append(itrs, itr)
 
 		select {
@@ -265,6 +265,240 @@ func TestShard_MaxTagValuesLimit(t *testing.T) {
 	sh.Close()
 }
 
+func TestShard_MaxSeriesPerMeasurementLimit(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(tmpDir)
+	tmpShard := filepath.Join(tmpDir, "db", "rp", "1")
+	tmpWal := filepath.Join(tmpDir, "wal")
+
+	sfile := MustOpenSeriesFile()
+	defer sfile.Close()
+
+	opts := tsdb.NewEngineOptions()
+	opts.Config.WALDir = filepath.Join(tmpDir, "wal")
+	opts.Config.MaxSeriesPerMeasurement = 10
+	opts.InmemIndex = inmem.NewIndex(filepath.Base(tmpDir), sfile.SeriesFile)
+
+	sh := tsdb.NewShard(1, tmpShard, tmpWal, sfile.SeriesFile, opts)
+
+	if err := sh.Open(); err != nil {
+		t.Fatalf("error opening shard: %s", err.Error())
+	}
+	defer sh.Close()
+
+	newPoint := func(name string, i int) models.Point {
+		return models.MustNewPoint(
+			name,
+			models.Tags{{Key: []byte("host"), Value: []byte(fmt.Sprintf("server%d", i))}},
+			map[string]interface{}{"value": 1.0},
+			time.Unix(1, 2),
+		)
+	}
+
+	// Writing 10 series of a measurement should succeed.
+	var points []models.Point
+	for i := 0; i < 10; i++ {
+		points = append(points, newPoint("cpu", i))
+	}
+	if err := sh.WritePoints(points); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the point creating an 11th series of cpu is dropped.
+	err := sh.WritePoints([]models.Point{newPoint("cpu", 0), newPoint("cpu", 10), newPoint("mem", 0)})
+	if err == nil {
+		t.Fatal("expected error")
+	} else if exp, got := `partial write: max-series-per-measurement limit exceeded: measurement "cpu" has 10 series, limit 10 dropped=1`, err.Error(); exp != got {
+		t.Fatalf("unexpected error message:\n\texp = %s\n\tgot = %s", exp, got)
+	}
+
+	if got, exp := sh.SeriesN(), int64(11); got != exp {
+		t.Fatalf("got %d series, exp %d series in index", got, exp)
+	}
+}
+
+// Ensure series dropped by the index after passing the series limits don't
+// count towards the limit.
+func TestShard_MaxSeriesPerMeasurementLimit_DroppedSeries(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(tmpDir)
+	tmpShard := filepath.Join(tmpDir, "db", "rp", "1")
+	tmpWal := filepath.Join(tmpDir, "wal")
+
+	sfile := MustOpenSeriesFile()
+	defer sfile.Close()
+
+	opts := tsdb.NewEngineOptions()
+	opts.Config.WALDir = filepath.Join(tmpDir, "wal")
+	opts.Config.MaxSeriesPerMeasurement = 2
+	opts.Config.MaxValuesPerTag = 1
+	opts.InmemIndex = inmem.NewIndex(filepath.Base(tmpDir), sfile.SeriesFile)
+
+	sh := tsdb.NewShard(1, tmpShard, tmpWal, sfile.SeriesFile, opts)
+
+	if err := sh.Open(); err != nil {
+		t.Fatalf("error opening shard: %s", err.Error())
+	}
+	defer sh.Close()
+
+	newPoint := func(key, value string) models.Point {
+		return models.MustNewPoint(
+			"cpu",
+			models.Tags{{Key: []byte(key), Value: []byte(value)}},
+			map[string]interface{}{"value": 1.0},
+			time.Unix(1, 2),
+		)
+	}
+
+	if err := sh.WritePoints([]models.Point{newPoint("host", "server0")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The second value of host is dropped by the index.
+	if err := sh.WritePoints([]models.Point{newPoint("host", "server1")}); err == nil || !strings.Contains(err.Error(), "max-values-per-tag limit exceeded") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The measurement still has room for a second series.
+	if err := sh.WritePoints([]models.Point{newPoint("region", "west")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := sh.SeriesN(), int64(2); got != exp {
+		t.Fatalf("got %d series, exp %d series in index", got, exp)
+	}
+}
+
+func TestShard_MaxNewSeriesPerMinuteLimit(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(tmpDir)
+	tmpShard := filepath.Join(tmpDir, "db", "rp", "1")
+	tmpWal := filepath.Join(tmpDir, "wal")
+
+	sfile := MustOpenSeriesFile()
+	defer sfile.Close()
+
+	opts := tsdb.NewEngineOptions()
+	opts.Config.WALDir = filepath.Join(tmpDir, "wal")
+	opts.SeriesLimiter = tsdb.NewSeriesLimiter(5)
+	opts.InmemIndex = inmem.NewIndex(filepath.Base(tmpDir), sfile.SeriesFile)
+
+	sh := tsdb.NewShard(1, tmpShard, tmpWal, sfile.SeriesFile, opts)
+
+	if err := sh.Open(); err != nil {
+		t.Fatalf("error opening shard: %s", err.Error())
+	}
+	defer sh.Close()
+
+	// Points of a dropped series are all dropped.
+	var points []models.Point
+	for i := 0; i < 7; i++ {
+		for j := 0; j < 2; j++ {
+			points = append(points, models.MustNewPoint(
+				"cpu",
+				models.Tags{{Key: []byte("host"), Value: []byte(fmt.Sprintf("server%d", i))}},
+				map[string]interface{}{"value": 1.0},
+				time.Unix(int64(j), 0),
+			))
+		}
+	}
+
+	err := sh.WritePoints(points)
+	if err == nil {
+		t.Fatal("expected error")
+	} else if exp, got := `partial write: max-new-series-per-minute limit exceeded: limit 5 dropped=4`, err.Error(); exp != got {
+		t.Fatalf("unexpected error message:\n\texp = %s\n\tgot = %s", exp, got)
+	}
+
+	if got, exp := sh.SeriesN(), int64(5); got != exp {
+		t.Fatalf("got %d series, exp %d series in index", got, exp)
+	}
+}
+
+// Ensure the shard observer is notified of points written and deleted.
+func TestShard_ShardObserver(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(tmpDir)
+	tmpShard := filepath.Join(tmpDir, "db", "rp", "1")
+	tmpWal := filepath.Join(tmpDir, "wal")
+
+	sfile := MustOpenSeriesFile()
+	defer sfile.Close()
+
+	obs := &ShardObserver{}
+	opts := tsdb.NewEngineOptions()
+	opts.Config.WALDir = filepath.Join(tmpDir, "wal")
+	opts.ShardObserver = obs
+	opts.SeriesIDSets = seriesIDSets([]*tsdb.SeriesIDSet{})
+	opts.InmemIndex = inmem.NewIndex(filepath.Base(tmpDir), sfile.SeriesFile)
+
+	sh := tsdb.NewShard(1, tmpShard, tmpWal, sfile.SeriesFile, opts)
+	if err := sh.Open(); err != nil {
+		t.Fatalf("error opening shard: %s", err.Error())
+	}
+	defer sh.Close()
+
+	if err := sh.WritePoints([]models.Point{
+		models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "serverA"}), map[string]interface{}{"value": 1.0}, time.Unix(0, 30)),
+		models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "serverA"}), map[string]interface{}{"value": 1.0}, time.Unix(0, 10)),
+		models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "serverA"}), map[string]interface{}{"value": 1.0}, time.Unix(0, 20)),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	sitr := &seriesIterator{keys: [][]byte{[]byte("cpu,host=serverA")}}
+	if err := sh.DeleteSeriesRange(sitr, 15, 25); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp := []string{"write db 10 30", "delete db 15 25"}; !reflect.DeepEqual(obs.Changes, exp) {
+		t.Fatalf("unexpected changes: %v", obs.Changes)
+	}
+}
+
+// Ensure the shard observer is notified of bulk loaded points once they are
+// committed.
+func TestShard_ShardObserver_Bulk(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(tmpDir)
+	tmpShard := filepath.Join(tmpDir, "db", "rp", "1")
+	tmpWal := filepath.Join(tmpDir, "wal")
+
+	sfile := MustOpenSeriesFile()
+	defer sfile.Close()
+
+	obs := &ShardObserver{}
+	opts := tsdb.NewEngineOptions()
+	opts.Config.WALDir = filepath.Join(tmpDir, "wal")
+	opts.ShardObserver = obs
+	opts.SeriesIDSets = seriesIDSets([]*tsdb.SeriesIDSet{})
+	opts.InmemIndex = inmem.NewIndex(filepath.Base(tmpDir), sfile.SeriesFile)
+
+	sh := tsdb.NewShard(1, tmpShard, tmpWal, sfile.SeriesFile, opts)
+	if err := sh.Open(); err != nil {
+		t.Fatalf("error opening shard: %s", err.Error())
+	}
+	defer sh.Close()
+
+	if err := sh.WritePointsBulk([]models.Point{
+		models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "serverA"}), map[string]interface{}{"value": 1.0}, time.Unix(0, 30)),
+		models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "serverA"}), map[string]interface{}{"value": 1.0}, time.Unix(0, 10)),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(obs.Changes) != 0 {
+		t.Fatalf("unexpected changes before commit: %v", obs.Changes)
+	}
+
+	// Closing the shard commits the bulk load.
+	if err := sh.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if exp := []string{"write db 10 30"}; !reflect.DeepEqual(obs.Changes, exp) {
+		t.Fatalf("unexpected changes: %v", obs.Changes)
+	}
+}
+
 func TestWriteTimeTag(t *testing.T) {
 	tmpDir, _ := ioutil.TempDir("", "shard_test")
 	defer os.RemoveAll(tmpDir)
@@ -2160,6 +2394,19 @@ func MustNewOpenShard(index string) *Shard {
 	return sh
 }
 
+// ShardObserver records the changes notified by shards.
+type ShardObserver struct {
+	Changes []string
+}
+
+func (o *ShardObserver) PointsWritten(database string, min, max int64) {
+	o.Changes = append(o.Changes, fmt.Sprintf("write %s %d %d", database, min, max))
+}
+
+func (o *ShardObserver) PointsDeleted(database string, min, max int64) {
+	o.Changes = append(o.Changes, fmt.Sprintf("delete %s %d %d", database, min, max))
+}
+
 // Close closes the shard and removes all underlying data.
 func (sh *Shard) Close() error {
 	// Will remove temp series file data.
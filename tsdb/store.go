@@ -84,6 +84,9 @@ type Store struct {
 	// shared per-database indexes, only if using "inmem".
 	indexes map[string]interface{}
 
+	// shared per-database limits on the creation of series.
+	seriesLimiters map[string]*SeriesLimiter
+
 	// Maintains a set of shards that are in the process of deletion.
 	// This prevents new shards from being created while old ones are being deleted.
 	pendingShardDeletes map[uint64]struct{}
@@ -111,6 +114,7 @@ func NewStore(path string) *Store {
 		path:                path,
 		sfiles:              make(map[string]*SeriesFile),
 		indexes:             make(map[string]interface{}),
+		seriesLimiters:      make(map[string]*SeriesLimiter),
 		pendingShardDeletes: make(map[uint64]struct{}),
 		epochs:              make(map[uint64]*epochTracker),
 		EngineOptions:       NewEngineOptions(),
@@ -163,7 +167,7 @@ func (s *Store) Statistics(tags map[string]string) []models.Statistic {
 
 	// Gather all statistics for all shards.
 	for _, shard := range shards {
-		statistics = append(statistics, shard.Statistics(tags)...)
+		statistics = append(statistics, shard.Statistics(models.StatisticTags{"tier": s.shardTier(shard)}.Merge(tags))...)
 	}
 	return statistics
 }
@@ -261,6 +265,7 @@ func (s *Store) loadShards() error {
 	}
 
 	s.EngineOptions.CompactionLimiter = limiter.NewFixed(lim)
+	s.EngineOptions.CompactionPriorities = NewCompactionPriorities()
 
 	compactionSettings := []zapcore.Field{zap.Int("max_concurrent_compactions", lim)}
 	throughput := int(s.EngineOptions.Config.CompactThroughput)
@@ -289,6 +294,11 @@ func (s *Store) loadShards() error {
 	log, logEnd := logger.NewOperation(s.Logger, "Open store", "tsdb_open")
 	defer logEnd()
 
+	// Finish or roll back shard moves to the cold tier interrupted by a crash.
+	if err := s.recoverShardMoves(log); err != nil {
+		return err
+	}
+
 	t := limiter.NewFixed(runtime.GOMAXPROCS(0))
 	resC := make(chan *res)
 	var n int
@@ -323,6 +333,9 @@ func (s *Store) loadShards() error {
 			return err
 		}
 
+		// Retrieve database series limiter.
+		seriesLimiter := s.seriesLimiter(db.Name())
+
 		// Load each retention policy within the database directory.
 		rpDirs, err := ioutil.ReadDir(dbPath)
 		if err != nil {
@@ -330,7 +343,6 @@ func (s *Store) loadShards() error {
 		}
 
 		for _, rp := range rpDirs {
-			rpPath := filepath.Join(s.path, db.Name(), rp.Name())
 			if !rp.IsDir() {
 				log.Info("Skipping retention policy dir", zap.String("name", rp.Name()), zap.String("reason", "not a directory"))
 				continue
@@ -346,25 +358,26 @@ func (s *Store) loadShards() error {
 				continue
 			}
 
-			shardDirs, err := ioutil.ReadDir(rpPath)
+			// Shards are loaded from both the hot and the cold tier.
+			shardDirs, err := s.shardDirs(db.Name(), rp.Name())
 			if err != nil {
 				return err
 			}
 
 			for _, sh := range shardDirs {
 				// Series file should not be in a retention policy but skip just in case.
-				if sh.Name() == SeriesFileDirectory {
-					log.Warn("Skipping series file in retention policy dir", zap.String("path", filepath.Join(s.path, db.Name(), rp.Name())))
+				if sh.name == SeriesFileDirectory {
+					log.Warn("Skipping series file in retention policy dir", zap.String("path", filepath.Join(sh.root, db.Name(), rp.Name())))
 					continue
 				}
 
 				n++
-				go func(db, rp, sh string) {
+				go func(root, db, rp, sh string) {
 					t.Take()
 					defer t.Release()
 
 					start := time.Now()
-					path := filepath.Join(s.path, db, rp, sh)
+					path := filepath.Join(root, db, rp, sh)
 					walPath := filepath.Join(s.EngineOptions.Config.WALDir, db, rp, sh)
 
 					// Shard file names are numeric shardIDs
@@ -387,6 +400,7 @@ func (s *Store) loadShards() error {
 
 					// Provide an implementation of the ShardIDSets
 					opt.SeriesIDSets = shardSet{store: s, db: db}
+					opt.SeriesLimiter = seriesLimiter
 
 					// Existing shards should continue to use inmem index.
 					if _, err := os.Stat(filepath.Join(path, "index")); os.IsNotExist(err) {
@@ -410,7 +424,7 @@ func (s *Store) loadShards() error {
 
 					resC <- &res{s: shard}
 					log.Info("Opened shard", zap.String("index_version", shard.IndexType()), zap.String("path", path), zap.Duration("duration", time.Since(start)))
-				}(db.Name(), rp.Name(), sh.Name())
+				}(sh.root, db.Name(), rp.Name(), sh.name)
 			}
 		}
 	}
@@ -486,6 +500,7 @@ func (s *Store) Close() error {
 	s.databases = make(map[string]*databaseState)
 	s.sfiles = map[string]*SeriesFile{}
 	s.indexes = make(map[string]interface{})
+	s.seriesLimiters = make(map[string]*SeriesLimiter)
 	s.pendingShardDeletes = make(map[uint64]struct{})
 	s.shards = nil
 	s.opened = false // Store may now be opened again.
@@ -547,6 +562,21 @@ func (s *Store) createIndexIfNotExists(name string) (interface{}, error) {
 	return idx, nil
 }
 
+// seriesLimiter returns the limiter of series creation shared by the shards
+// of a database, or nil if series creation isn't limited. It must be called
+// under a full lock.
+func (s *Store) seriesLimiter(name string) *SeriesLimiter {
+	if s.EngineOptions.Config.MaxNewSeriesPerMinute <= 0 {
+		return nil
+	}
+	if l := s.seriesLimiters[name]; l != nil {
+		return l
+	}
+	l := NewSeriesLimiter(s.EngineOptions.Config.MaxNewSeriesPerMinute)
+	s.seriesLimiters[name] = l
+	return l
+}
+
 // Shard returns a shard by id.
 func (s *Store) Shard(id uint64) *Shard {
 	s.mu.RLock()
@@ -595,6 +625,18 @@ func (s *Store) ShardDigest(id uint64) (io.ReadCloser, int64, error) {
 	return sh.Digest()
 }
 
+// RepairShard rewrites the files of the shard without the blocks whose
+// checksum does not match their content and returns the blocks that were
+// removed.
+func (s *Store) RepairShard(id uint64) ([]CorruptBlock, error) {
+	sh := s.Shard(id)
+	if sh == nil {
+		return nil, ErrShardNotFound
+	}
+
+	return sh.Repair()
+}
+
 // CreateShard creates a shard with the given id and retention policy on a database.
 func (s *Store) CreateShard(database, retentionPolicy string, shardID uint64, enabled bool) error {
 	s.mu.Lock()
@@ -644,6 +686,7 @@ func (s *Store) CreateShard(database, retentionPolicy string, shardID uint64, en
 	opt := s.EngineOptions
 	opt.InmemIndex = idx
 	opt.SeriesIDSets = shardSet{store: s, db: database}
+	opt.SeriesLimiter = s.seriesLimiter(database)
 
 	path := filepath.Join(s.path, database, retentionPolicy, strconv.FormatUint(shardID, 10))
 	shard := NewShard(shardID, path, walPath, sfile, opt)
@@ -791,11 +834,20 @@ func (s *Store) DeleteShard(shardID uint64) error {
 		return err
 	}
 
+	sh.pointsDeleted(influxql.MinTime, influxql.MaxTime)
+
 	// Remove the on-disk shard data.
 	if err := os.RemoveAll(sh.path); err != nil {
 		return err
 	}
 
+	// Remove the archived WAL of the shard.
+	if s.EngineOptions.Config.WALArchiveDir != "" {
+		if err := os.RemoveAll(s.EngineOptions.Config.WALArchivePath(sh.walPath, sh.id)); err != nil {
+			return err
+		}
+	}
+
 	return os.RemoveAll(sh.walPath)
 }
 
@@ -817,7 +869,11 @@ func (s *Store) DeleteDatabase(name string) error {
 			return nil
 		}
 
-		return sh.Close()
+		if err := sh.Close(); err != nil {
+			return err
+		}
+		sh.pointsDeleted(influxql.MinTime, influxql.MaxTime)
+		return nil
 	}); err != nil {
 		return err
 	}
@@ -849,6 +905,16 @@ func (s *Store) DeleteDatabase(name string) error {
 	if err := os.RemoveAll(filepath.Join(s.EngineOptions.Config.WALDir, name)); err != nil {
 		return err
 	}
+	if coldDir := s.EngineOptions.Config.ColdDir; coldDir != "" {
+		if err := os.RemoveAll(filepath.Join(coldDir, name)); err != nil {
+			return err
+		}
+	}
+	if archiveDir := s.EngineOptions.Config.WALArchiveDir; archiveDir != "" {
+		if err := os.RemoveAll(filepath.Join(archiveDir, name)); err != nil {
+			return err
+		}
+	}
 
 	for _, sh := range shards {
 		delete(s.shards, sh.id)
@@ -860,6 +926,7 @@ func (s *Store) DeleteDatabase(name string) error {
 
 	// Remove shared index for database if using inmem index.
 	delete(s.indexes, name)
+	delete(s.seriesLimiters, name)
 
 	return nil
 }
@@ -886,7 +953,11 @@ func (s *Store) DeleteRetentionPolicy(database, name string) error {
 			return nil
 		}
 
-		return sh.Close()
+		if err := sh.Close(); err != nil {
+			return err
+		}
+		sh.pointsDeleted(influxql.MinTime, influxql.MaxTime)
+		return nil
 	}); err != nil {
 		return err
 	}
@@ -909,6 +980,20 @@ func (s *Store) DeleteRetentionPolicy(database, name string) error {
 		return err
 	}
 
+	// Remove the retention policy folder from the cold tier.
+	if coldDir := s.EngineOptions.Config.ColdDir; coldDir != "" {
+		if err := os.RemoveAll(filepath.Join(coldDir, database, name)); err != nil {
+			return err
+		}
+	}
+
+	// Remove the retention policy folder from the WAL archive.
+	if archiveDir := s.EngineOptions.Config.WALArchiveDir; archiveDir != "" {
+		if err := os.RemoveAll(filepath.Join(archiveDir, database, name)); err != nil {
+			return err
+		}
+	}
+
 	s.mu.Lock()
 	state := s.databases[database]
 	for _, sh := range shards {
@@ -1192,7 +1277,7 @@ func (s *Store) BackupShard(id uint64, since time.Time, w io.Writer) error {
 		return fmt.Errorf("shard %d doesn't exist on this server", id)
 	}
 
-	path, err := relativePath(s.path, shard.path)
+	path, err := relativePath(s.shardRoot(shard), shard.path)
 	if err != nil {
 		return err
 	}
@@ -1206,7 +1291,7 @@ func (s *Store) ExportShard(id uint64, start time.Time, end time.Time, w io.Writ
 		return fmt.Errorf("shard %d doesn't exist on this server", id)
 	}
 
-	path, err := relativePath(s.path, shard.path)
+	path, err := relativePath(s.shardRoot(shard), shard.path)
 	if err != nil {
 		return err
 	}
@@ -1222,7 +1307,7 @@ func (s *Store) RestoreShard(id uint64, r io.Reader) error {
 		return fmt.Errorf("shard %d doesn't exist on this server", id)
 	}
 
-	path, err := relativePath(s.path, shard.path)
+	path, err := relativePath(s.shardRoot(shard), shard.path)
 	if err != nil {
 		return err
 	}
@@ -1240,7 +1325,7 @@ func (s *Store) ImportShard(id uint64, r io.Reader) error {
 		return fmt.Errorf("shard %d doesn't exist on this server", id)
 	}
 
-	path, err := relativePath(s.path, shard.path)
+	path, err := relativePath(s.shardRoot(shard), shard.path)
 	if err != nil {
 		return err
 	}
@@ -1255,13 +1340,32 @@ func (s *Store) ShardRelativePath(id uint64) (string, error) {
 	if shard == nil {
 		return "", fmt.Errorf("shard %d doesn't exist on this server", id)
 	}
-	return relativePath(s.path, shard.path)
+	return relativePath(s.shardRoot(shard), shard.path)
 }
 
 // DeleteSeries loops through the local shards and deletes the series data for
 // the passed in series keys.
 // 删除series
 func (s *Store) DeleteSeries(database string, sources []influxql.Source, condition influxql.Expr) error {
+	return s.deleteSeries(database, byDatabase(database), sources, condition)
+}
+
+// DeleteSeriesInShards deletes the series data matching sources and condition
+// from the given local shards of a database only. Shard IDs which are not
+// stored locally are ignored.
+func (s *Store) DeleteSeriesInShards(database string, shardIDs []uint64, sources []influxql.Source, condition influxql.Expr) error {
+	ids := make(map[uint64]struct{}, len(shardIDs))
+	for _, id := range shardIDs {
+		ids[id] = struct{}{}
+	}
+
+	return s.deleteSeries(database, func(sh *Shard) bool {
+		_, ok := ids[sh.id]
+		return ok && sh.database == database
+	}, sources, condition)
+}
+
+func (s *Store) deleteSeries(database string, fn func(sh *Shard) bool, sources []influxql.Source, condition influxql.Expr) error {
 	// Expand regex expressions in the FROM clause.
 	a, err := s.ExpandSources(sources)
 	if err != nil {
@@ -1301,7 +1405,7 @@ func (s *Store) DeleteSeries(database string, sources []influxql.Source, conditi
 		// No series file means nothing has been written to this DB and thus nothing to delete.
 		return nil
 	}
-	shards := s.filterShards(byDatabase(database))
+	shards := s.filterShards(fn)
 	epochs := s.epochsForShards(shards)
 	s.mu.RUnlock()
 
@@ -1373,6 +1477,16 @@ func (s *Store) ExpandSources(sources influxql.Sources) (influxql.Sources, error
 
 // WriteToShard writes a list of points to a shard identified by its ID.
 func (s *Store) WriteToShard(shardID uint64, points []models.Point) error {
+	return s.writeToShard(shardID, points, false)
+}
+
+// WriteToShardBulk writes a list of points to a shard identified by its ID like
+// WriteToShard, except that the points are bulk loaded.  See Shard.WritePointsBulk.
+func (s *Store) WriteToShardBulk(shardID uint64, points []models.Point) error {
+	return s.writeToShard(shardID, points, true)
+}
+
+func (s *Store) writeToShard(shardID uint64, points []models.Point, bulk bool) error {
 	s.mu.RLock()
 
 	select {
@@ -1414,6 +1528,9 @@ func (s *Store) WriteToShard(shardID uint64, points []models.Point) error {
 	}
 
 	// 写入shard
+	if bulk {
+		return sh.WritePointsBulk(points)
+	}
 	return sh.WritePoints(points)
 }
 
@@ -161,6 +161,212 @@ func TestStore_DeleteSeries_NonExistentDB(t *testing.T) {
 	}
 }
 
+// Ensure the store only deletes series from the requested shards.
+func TestStore_DeleteSeriesInShards(t *testing.T) {
+	t.Parallel()
+
+	test := func(index string) error {
+		s := MustOpenStore(index)
+		defer s.Close()
+
+		s.MustCreateShardWithData("db0", "rp0", 0, `debug_cpu,host=serverA value=1 0`, `cpu,host=serverA value=1 0`)
+		s.MustCreateShardWithData("db0", "rp0", 1, `debug_cpu,host=serverA value=1 0`)
+
+		sources := influxql.Sources{&influxql.Measurement{Regex: &influxql.RegexLiteral{Val: regexp.MustCompile(`^debug_`)}}}
+		if err := s.DeleteSeriesInShards("db0", []uint64{0, 100}, sources, nil); err != nil {
+			return err
+		}
+
+		for _, tt := range []struct {
+			shardID uint64
+			name    string
+			exp     bool
+		}{
+			{shardID: 0, name: "debug_cpu", exp: false},
+			{shardID: 0, name: "cpu", exp: true},
+			{shardID: 1, name: "debug_cpu", exp: true},
+		} {
+			itr, err := s.Shard(tt.shardID).CreateIterator(context.Background(), &influxql.Measurement{Name: tt.name}, query.IteratorOptions{
+				Expr:      influxql.MustParseExpr(`value`),
+				Ascending: true,
+				StartTime: influxql.MinTime,
+				EndTime:   influxql.MaxTime,
+			})
+			if err != nil {
+				return err
+			}
+
+			var got bool
+			if itr != nil {
+				p, err := itr.(query.FloatIterator).Next()
+				itr.Close()
+				if err != nil {
+					return err
+				}
+				got = p != nil
+			}
+			if got != tt.exp {
+				return fmt.Errorf("shard %d: measurement %s has data=%v, expected %v", tt.shardID, tt.name, got, tt.exp)
+			}
+		}
+		return nil
+	}
+
+	for _, index := range tsdb.RegisteredIndexes() {
+		t.Run(index, func(t *testing.T) {
+			if err := test(index); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// Ensure a fully compacted shard can be moved to the cold tier and reopened from it.
+func TestStore_MoveShardToColdTier(t *testing.T) {
+	t.Parallel()
+
+	test := func(index string) error {
+		coldDir, err := ioutil.TempDir("", "influxdb-tsdb-cold-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(coldDir)
+
+		s := NewStore(index)
+		s.EngineOptions.Config.ColdDir = coldDir
+		if err := s.Open(); err != nil {
+			return err
+		}
+		defer s.Close()
+
+		s.MustCreateShardWithData("db0", "rp0", 1, `cpu,host=serverA value=1 0`)
+		if got, exp := s.ShardTier(1), tsdb.HotTier; got != exp {
+			return fmt.Errorf("got tier %q, expected %q", got, exp)
+		}
+
+		// The cache holds the write, so the shard can't be moved yet.
+		if err := s.MoveShardToColdTier(1); err != tsdb.ErrShardNotIdle {
+			return fmt.Errorf("got error %v, expected %v", err, tsdb.ErrShardNotIdle)
+		}
+
+		// Snapshotting the shard flushes the cache to a single TSM file.
+		dir, err := s.Shard(1).CreateSnapshot()
+		if err != nil {
+			return err
+		}
+		os.RemoveAll(dir)
+
+		if err := s.MoveShardToColdTier(1); err != nil {
+			return err
+		} else if got, exp := s.ShardTier(1), tsdb.ColdTier; got != exp {
+			return fmt.Errorf("got tier %q, expected %q", got, exp)
+		} else if dirExists(filepath.Join(s.Path(), "db0", "rp0", "1")) {
+			return errors.New("shard still exists in the hot tier")
+		} else if !dirExists(filepath.Join(coldDir, "db0", "rp0", "1")) {
+			return errors.New("shard doesn't exist in the cold tier")
+		}
+
+		// The store must return the shard opened from the cold tier.
+		if err := s.WriteToShard(1, []models.Point{models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "serverA"}), map[string]interface{}{"value": 2.0}, time.Unix(0, 1))}); err != nil {
+			return err
+		}
+
+		// Moving a shard already on the cold tier is a no-op.
+		if err := s.MoveShardToColdTier(1); err != nil {
+			return err
+		}
+
+		for i := 0; i < 2; i++ {
+			itr, err := s.Shard(1).CreateIterator(context.Background(), &influxql.Measurement{Name: "cpu"}, query.IteratorOptions{
+				Expr:      influxql.MustParseExpr(`value`),
+				Ascending: true,
+				StartTime: influxql.MinTime,
+				EndTime:   influxql.MaxTime,
+			})
+			if err != nil {
+				return err
+			}
+			p, err := itr.(query.FloatIterator).Next()
+			itr.Close()
+			if err != nil {
+				return err
+			} else if p == nil || p.Value != 1 {
+				return fmt.Errorf("unexpected point: %v", p)
+			}
+
+			// The shard must be loaded from the cold tier on reopen.
+			if err := s.Reopen(); err != nil {
+				return err
+			} else if got, exp := s.ShardTier(1), tsdb.ColdTier; got != exp {
+				return fmt.Errorf("got tier %q after reopen, expected %q", got, exp)
+			}
+		}
+		return nil
+	}
+
+	for _, index := range tsdb.RegisteredIndexes() {
+		t.Run(index, func(t *testing.T) {
+			if err := test(index); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// Ensure an interrupted move to the cold tier is rolled back when the store is opened.
+func TestStore_MoveShardToColdTier_Recover(t *testing.T) {
+	t.Parallel()
+
+	test := func(index string) error {
+		coldDir, err := ioutil.TempDir("", "influxdb-tsdb-cold-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(coldDir)
+
+		s := NewStore(index)
+		s.EngineOptions.Config.ColdDir = coldDir
+		if err := s.Open(); err != nil {
+			return err
+		}
+		defer s.Close()
+
+		s.MustCreateShardWithData("db0", "rp0", 1, `cpu,host=serverA value=1 0`)
+
+		// Simulate a crash in the middle of copying the shard.
+		source := filepath.Join(s.Path(), "db0", "rp0", "1")
+		target := filepath.Join(coldDir, "db0", "rp0", "1")
+		if err := os.MkdirAll(target+".tmp", 0777); err != nil {
+			return err
+		} else if err := os.MkdirAll(filepath.Join(coldDir, tsdb.ShardMoveDirectory), 0777); err != nil {
+			return err
+		}
+		manifest := fmt.Sprintf(`{"shardID":1,"source":%q,"target":%q,"state":"copying"}`, source, target)
+		if err := ioutil.WriteFile(filepath.Join(coldDir, tsdb.ShardMoveDirectory, "1.json"), []byte(manifest), 0666); err != nil {
+			return err
+		}
+
+		if err := s.Reopen(); err != nil {
+			return err
+		} else if got, exp := s.ShardTier(1), tsdb.HotTier; got != exp {
+			return fmt.Errorf("got tier %q, expected %q", got, exp)
+		} else if dirExists(target + ".tmp") {
+			return errors.New("partial copy not removed")
+		} else if _, err := os.Stat(filepath.Join(coldDir, tsdb.ShardMoveDirectory, "1.json")); !os.IsNotExist(err) {
+			return fmt.Errorf("manifest not removed: %v", err)
+		}
+		return nil
+	}
+
+	for _, index := range tsdb.RegisteredIndexes() {
+		t.Run(index, func(t *testing.T) {
+			if err := test(index); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
 // Ensure the store can delete an existing shard.
 func TestStore_DeleteShard(t *testing.T) {
 	t.Parallel()
@@ -250,6 +456,54 @@ func TestStore_DeleteShard(t *testing.T) {
 	}
 }
 
+// Ensure the archived WAL of shards is removed along with the shards.
+func TestStore_DeleteShard_WALArchive(t *testing.T) {
+	t.Parallel()
+
+	test := func(index string) error {
+		s := NewStore(index)
+		archiveDir := filepath.Join(s.Path(), "archive")
+		s.EngineOptions.Config.WALArchiveDir = archiveDir
+		if err := s.Open(); err != nil {
+			return err
+		}
+		defer s.Close()
+
+		s.MustCreateShardWithData("db0", "rp0", 1, `cpu v=1`)
+		s.MustCreateShardWithData("db0", "rp0", 2, `cpu v=1`)
+		s.MustCreateShardWithData("db0", "rp1", 3, `cpu v=1`)
+		s.MustCreateShardWithData("db1", "rp0", 4, `cpu v=1`)
+		for _, path := range []string{"db0/rp0/1", "db0/rp0/2", "db0/rp1/3", "db1/rp0/4"} {
+			if !dirExists(filepath.Join(archiveDir, path)) {
+				return fmt.Errorf("WAL archive %s doesn't exist", path)
+			}
+		}
+
+		if err := s.DeleteShard(1); err != nil {
+			return err
+		} else if err := s.DeleteRetentionPolicy("db0", "rp1"); err != nil {
+			return err
+		} else if err := s.DeleteDatabase("db1"); err != nil {
+			return err
+		}
+
+		for path, exp := range map[string]bool{"db0/rp0/1": false, "db0/rp0/2": true, "db0/rp1": false, "db1": false} {
+			if got := dirExists(filepath.Join(archiveDir, path)); got != exp {
+				return fmt.Errorf("WAL archive %s exists: got %v, expected %v", path, got, exp)
+			}
+		}
+		return nil
+	}
+
+	for _, index := range tsdb.RegisteredIndexes() {
+		t.Run(index, func(t *testing.T) {
+			if err := test(index); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
 // Ensure the store can create a snapshot to a shard.
 func TestStore_CreateShardSnapShot(t *testing.T) {
 	t.Parallel()
@@ -704,6 +958,108 @@ func testStoreCardinalityTombstoning(t *testing.T, store *Store) {
 	}
 }
 
+// Ensure the store limits the creation of series across the shards of a database.
+func TestStore_MaxNewSeriesPerMinute(t *testing.T) {
+	t.Parallel()
+
+	test := func(index string) {
+		s := NewStore(index)
+		s.EngineOptions.Config.MaxNewSeriesPerMinute = 3
+		if err := s.Open(); err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		s.MustCreateShardWithData("db0", "rp0", 0, `cpu,host=serverA value=1 0`, `cpu,host=serverB value=1 0`)
+		if err := s.CreateShard("db0", "rp0", 1, true); err != nil {
+			t.Fatal(err)
+		}
+
+		pts := []models.Point{
+			models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "serverC"}), map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+			models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "serverD"}), map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+		}
+		if err, ok := s.WriteToShard(1, pts).(tsdb.PartialWriteError); !ok || err.Dropped != 1 {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// Series created by another shard of the database aren't new.
+		pts = []models.Point{
+			models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "serverA"}), map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+			models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "serverB"}), map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+		}
+		if err := s.WriteToShard(1, pts); err != nil {
+			t.Fatal(err)
+		}
+
+		// Other databases have their own limit.
+		s.MustCreateShardWithData("db1", "rp0", 2, `cpu,host=serverA value=1 0`)
+	}
+
+	for _, index := range tsdb.RegisteredIndexes() {
+		t.Run(index, func(t *testing.T) { test(index) })
+	}
+}
+
+// Ensure the store returns the series of each measurement and the cardinality of tag keys.
+func TestStore_MeasurementSeriesIDSets(t *testing.T) {
+	t.Parallel()
+
+	test := func(index string) {
+		s := MustOpenStore(index)
+		defer s.Close()
+
+		s.MustCreateShardWithData("db0", "rp0", 0,
+			`cpu,host=serverA,region=east value=1 0`,
+			`cpu,host=serverB,region=east value=1 0`,
+			`mem,host=serverA value=1 0`,
+		)
+		s.MustCreateShardWithData("db0", "rp0", 1,
+			`cpu,host=serverB,region=east value=1 10`,
+			`cpu,host=serverC,region=west value=1 10`,
+		)
+		s.MustCreateShardWithData("db1", "rp0", 2, `disk,host=serverA value=1 0`)
+
+		sets, err := s.MeasurementSeriesIDSets([]uint64{0, 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, exp := len(sets), 2; got != exp {
+			t.Fatalf("got %d measurements, exp %d", got, exp)
+		} else if got, exp := sets["cpu"].Cardinality(), uint64(3); got != exp {
+			t.Fatalf("got %d cpu series, exp %d", got, exp)
+		} else if got, exp := sets["mem"].Cardinality(), uint64(1); got != exp {
+			t.Fatalf("got %d mem series, exp %d", got, exp)
+		}
+
+		// Only series of the given shards are returned.
+		sets, err = s.MeasurementSeriesIDSets([]uint64{1})
+		if err != nil {
+			t.Fatal(err)
+		} else if got, exp := sets["cpu"].Cardinality(), uint64(2); got != exp {
+			t.Fatalf("got %d cpu series, exp %d", got, exp)
+		} else if sets["mem"] != nil && sets["mem"].Cardinality() != 0 {
+			t.Fatalf("unexpected mem series: %d", sets["mem"].Cardinality())
+		}
+
+		keys, err := s.TagKeyCardinalities([]uint64{0, 1}, []byte("cpu"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp := []tsdb.TagKeyCardinality{
+			{Key: "host", ValuesN: 3, SeriesN: 3},
+			{Key: "region", ValuesN: 2, SeriesN: 3},
+		}
+		if !reflect.DeepEqual(keys, exp) {
+			t.Fatalf("got %v, exp %v", keys, exp)
+		}
+	}
+
+	for _, index := range tsdb.RegisteredIndexes() {
+		t.Run(index, func(t *testing.T) { test(index) })
+	}
+}
+
 func TestStore_Cardinality_Tombstoning(t *testing.T) {
 	t.Parallel()
 
@@ -2058,9 +2414,11 @@ func (s *Store) Reopen() error {
 		return err
 	}
 
+	coldDir := s.EngineOptions.Config.ColdDir
 	s.Store = tsdb.NewStore(s.Path())
 	s.EngineOptions.IndexVersion = s.index
 	s.EngineOptions.Config.WALDir = filepath.Join(s.Path(), "wal")
+	s.EngineOptions.Config.ColdDir = coldDir
 	s.EngineOptions.Config.TraceLoggingEnabled = true
 
 	if testing.Verbose() {
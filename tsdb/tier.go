@@ -0,0 +1,455 @@
+package tsdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/influxdata/influxdb/logger"
+	"github.com/influxdata/influxdb/pkg/file"
+	"go.uber.org/zap"
+)
+
+// Storage tiers a shard can be stored on.
+const (
+	// HotTier holds the shards stored under the data directory.
+	HotTier = "hot"
+
+	// ColdTier holds the shards moved to the cold data directory.
+	ColdTier = "cold"
+)
+
+// ShardMoveDirectory is the name of the directory, under the cold data
+// directory, holding the manifests of shards being moved to the cold tier.
+const ShardMoveDirectory = "_moves"
+
+// ErrColdTierDisabled is returned when moving a shard to the cold tier while
+// no cold data directory is configured.
+var ErrColdTierDisabled = errors.New("cold tier is not configured")
+
+// Shard move states recorded in a shard move manifest.
+const (
+	// shardMoveCopying means the shard is being copied to the cold tier. The
+	// copy may be incomplete and the shard in the hot tier is authoritative.
+	shardMoveCopying = "copying"
+
+	// shardMoveCopied means the copy in the cold tier is complete and
+	// authoritative. Only the shard in the hot tier remains to be removed.
+	shardMoveCopied = "copied"
+)
+
+// shardMove is the manifest of a shard being moved to the cold tier. It is
+// written before any file is copied and removed once the move completes, so
+// that a move interrupted by a crash can be rolled back or finished when the
+// store is next opened.
+type shardMove struct {
+	ShardID uint64 `json:"shardID"`
+	Source  string `json:"source"`
+	Target  string `json:"target"`
+	State   string `json:"state"`
+}
+
+// ShardTier returns the storage tier holding the shard, or an empty string if
+// the shard doesn't exist on this server.
+func (s *Store) ShardTier(id uint64) string {
+	sh := s.Shard(id)
+	if sh == nil {
+		return ""
+	}
+	return s.shardTier(sh)
+}
+
+// shardTier returns the storage tier holding sh.
+func (s *Store) shardTier(sh *Shard) string {
+	if s.isColdPath(sh.path) {
+		return ColdTier
+	}
+	return HotTier
+}
+
+// shardRoot returns the root directory of the tier holding sh.
+func (s *Store) shardRoot(sh *Shard) string {
+	if s.isColdPath(sh.path) {
+		return s.EngineOptions.Config.ColdDir
+	}
+	return s.path
+}
+
+// isColdPath returns true if path is within the cold data directory.
+func (s *Store) isColdPath(path string) bool {
+	coldDir := s.EngineOptions.Config.ColdDir
+	if coldDir == "" {
+		return false
+	}
+	return strings.HasPrefix(filepath.Clean(path), filepath.Clean(coldDir)+string(filepath.Separator))
+}
+
+// MoveShardToColdTier relocates the TSM files, tombstones and index of a
+// shard to the cold data directory and reopens the shard from there. The WAL
+// of the shard stays in place. Only fully compacted shards can be moved;
+// ErrShardNotIdle is returned otherwise. Moving a shard already on the cold
+// tier is a no-op.
+//
+// The shard stays live while a snapshot of it is copied. It is then closed
+// only while the files changed since the snapshot are copied and its copy is
+// opened from the cold tier. Writes to the shard fail with ErrEngineClosed
+// meanwhile. The store lock is only held to swap the shard for its copy.
+func (s *Store) MoveShardToColdTier(id uint64) error {
+	coldDir := s.EngineOptions.Config.ColdDir
+	if coldDir == "" {
+		return ErrColdTierDisabled
+	}
+
+	sh := s.Shard(id)
+	if sh == nil {
+		return ErrShardNotFound
+	} else if s.shardTier(sh) == ColdTier {
+		return nil
+	} else if !sh.IsIdle() {
+		return ErrShardNotIdle
+	}
+
+	rel, err := relativePath(s.path, sh.path)
+	if err != nil {
+		return err
+	}
+
+	m := &shardMove{
+		ShardID: id,
+		Source:  sh.path,
+		Target:  filepath.Join(coldDir, rel),
+		State:   shardMoveCopying,
+	}
+	if err := s.writeShardMove(m); err != nil {
+		return err
+	}
+
+	if err := copyShardSnapshot(sh, m.Target+".tmp"); err != nil {
+		s.rollbackShardMove(m)
+		return err
+	}
+
+	// Close the shard without holding the store lock, so that the other
+	// shards stay available while the shard is moved.
+	if s.Shard(id) != sh {
+		// The shard was deleted while it was being copied.
+		s.rollbackShardMove(m)
+		return ErrShardNotFound
+	} else if err := sh.Close(); err != nil {
+		s.rollbackShardMove(m)
+		return err
+	}
+
+	// If the copy cannot be opened, fall back to the original files.
+	moved, err := s.openMovedShard(sh, m)
+	if err != nil {
+		s.rollbackShardMove(m)
+		if e := sh.Open(); e != nil {
+			return fmt.Errorf("open moved shard: %s; reopen shard: %s", err, e)
+		}
+		return err
+	}
+
+	// Only swap the shard for its copy under the store lock.
+	s.mu.Lock()
+	if s.shards[id] != sh {
+		// The shard was deleted while it was being moved.
+		s.mu.Unlock()
+		moved.Close()
+		s.rollbackShardMove(m)
+		return ErrShardNotFound
+	}
+	s.shards[id] = moved
+	s.mu.Unlock()
+
+	if err := os.RemoveAll(m.Source); err != nil {
+		return err
+	}
+	return s.removeShardMove(id)
+}
+
+// openMovedShard completes the copy of the closed shard sh to the cold tier,
+// makes the copy authoritative and opens it.
+func (s *Store) openMovedShard(sh *Shard, m *shardMove) (*Shard, error) {
+	tmp := m.Target + ".tmp"
+	if err := syncShardDir(m.Source, tmp); err != nil {
+		return nil, err
+	} else if err := file.RenameFile(tmp, m.Target); err != nil {
+		return nil, err
+	} else if err := file.SyncDir(filepath.Dir(m.Target)); err != nil {
+		return nil, err
+	}
+
+	m.State = shardMoveCopied
+	if err := s.writeShardMove(m); err != nil {
+		return nil, err
+	}
+
+	moved := NewShard(sh.id, m.Target, sh.walPath, sh.sfile, sh.options)
+	moved.CompactionDisabled = sh.CompactionDisabled
+	moved.WithLogger(s.baseLogger)
+	if err := moved.Open(); err != nil {
+		return nil, err
+	}
+	return moved, nil
+}
+
+// shardDir is a shard directory found in one of the storage tiers.
+type shardDir struct {
+	root string // root directory of the tier
+	name string
+}
+
+// shardDirs returns the shard directories of a retention policy in the hot
+// tier and, when configured, in the cold tier. A shard found in both tiers is
+// only returned from the hot tier.
+func (s *Store) shardDirs(db, rp string) ([]shardDir, error) {
+	fis, err := ioutil.ReadDir(filepath.Join(s.path, db, rp))
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make([]shardDir, 0, len(fis))
+	hot := make(map[string]struct{}, len(fis))
+	for _, fi := range fis {
+		dirs = append(dirs, shardDir{root: s.path, name: fi.Name()})
+		hot[fi.Name()] = struct{}{}
+	}
+
+	coldDir := s.EngineOptions.Config.ColdDir
+	if coldDir == "" {
+		return dirs, nil
+	}
+
+	fis, err = ioutil.ReadDir(filepath.Join(coldDir, db, rp))
+	if os.IsNotExist(err) {
+		return dirs, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, fi := range fis {
+		if _, ok := hot[fi.Name()]; ok || !fi.IsDir() {
+			continue
+		}
+		dirs = append(dirs, shardDir{root: coldDir, name: fi.Name()})
+	}
+	return dirs, nil
+}
+
+// recoverShardMoves finishes or rolls back the shard moves that were
+// interrupted before the store was last closed.
+func (s *Store) recoverShardMoves(log *zap.Logger) error {
+	coldDir := s.EngineOptions.Config.ColdDir
+	if coldDir == "" {
+		return nil
+	}
+
+	fis, err := ioutil.ReadDir(filepath.Join(coldDir, ShardMoveDirectory))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, fi := range fis {
+		if filepath.Ext(fi.Name()) != ".json" {
+			continue
+		}
+
+		buf, err := ioutil.ReadFile(filepath.Join(coldDir, ShardMoveDirectory, fi.Name()))
+		if err != nil {
+			return err
+		}
+
+		var m shardMove
+		if err := json.Unmarshal(buf, &m); err != nil {
+			return fmt.Errorf("invalid shard move manifest %s: %s", fi.Name(), err)
+		}
+
+		switch m.State {
+		case shardMoveCopied:
+			log.Info("Finishing interrupted shard move", logger.Shard(m.ShardID), zap.String("path", m.Target))
+			if err := os.RemoveAll(m.Source); err != nil {
+				return err
+			} else if err := s.removeShardMove(m.ShardID); err != nil {
+				return err
+			}
+		default:
+			log.Info("Rolling back interrupted shard move", logger.Shard(m.ShardID), zap.String("path", m.Source))
+			if err := s.rollbackShardMove(&m); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rollbackShardMove removes the cold tier copy of a shard and its manifest.
+func (s *Store) rollbackShardMove(m *shardMove) error {
+	if err := os.RemoveAll(m.Target + ".tmp"); err != nil {
+		return err
+	} else if err := os.RemoveAll(m.Target); err != nil {
+		return err
+	}
+	return s.removeShardMove(m.ShardID)
+}
+
+// shardMovePath returns the path of the manifest of a shard move.
+func (s *Store) shardMovePath(id uint64) string {
+	return filepath.Join(s.EngineOptions.Config.ColdDir, ShardMoveDirectory, fmt.Sprintf("%d.json", id))
+}
+
+// writeShardMove atomically writes the manifest of a shard move.
+func (s *Store) writeShardMove(m *shardMove) error {
+	path := s.shardMovePath(m.ShardID)
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+
+	buf, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		return err
+	} else if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	} else if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := file.RenameFile(path+".tmp", path); err != nil {
+		return err
+	}
+	return file.SyncDir(filepath.Dir(path))
+}
+
+// removeShardMove removes the manifest of a shard move.
+func (s *Store) removeShardMove(id uint64) error {
+	path := s.shardMovePath(id)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return file.SyncDir(filepath.Dir(path))
+}
+
+// copyShardSnapshot copies a snapshot of the TSM files and tombstones of the
+// live shard sh to dst.
+func copyShardSnapshot(sh *Shard, dst string) error {
+	dir, err := sh.CreateSnapshot()
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.RemoveAll(dst); err != nil {
+		return err
+	}
+
+	return filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if fi.IsDir() {
+			return os.MkdirAll(target, 0777)
+		}
+		return copyFile(path, target)
+	})
+}
+
+// syncShardDir makes dst a copy of the shard directory src. Only the files
+// whose size or modification time differ from their copy in dst are copied,
+// and the files of dst missing from src are removed.
+func syncShardDir(src, dst string) error {
+	if err := filepath.Walk(src, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if fi.IsDir() {
+			return os.MkdirAll(target, 0777)
+		} else if tfi, err := os.Stat(target); err == nil && tfi.Size() == fi.Size() && tfi.ModTime().Equal(fi.ModTime()) {
+			return nil
+		}
+		return copyFile(path, target)
+	}); err != nil {
+		return err
+	}
+
+	return filepath.Walk(dst, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dst, path)
+		if err != nil {
+			return err
+		}
+
+		if _, err := os.Stat(filepath.Join(src, rel)); !os.IsNotExist(err) {
+			return err
+		} else if err := os.RemoveAll(path); err != nil {
+			return err
+		} else if fi.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+// copyFile copies the file at src to dst and syncs it to disk. The copy keeps
+// the modification time of src.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	} else if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	} else if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, fi.ModTime(), fi.ModTime())
+}